@@ -123,10 +123,14 @@ type Message struct {
 // ContentField handles both string and array content formats
 type ContentField struct {
 	Value string
+	// Raw is the field as received, before array content is flattened to text
+	Raw json.RawMessage
 }
 
 // UnmarshalJSON implements custom unmarshaling to handle both string and array formats
 func (c *ContentField) UnmarshalJSON(data []byte) error {
+	c.Raw = append(json.RawMessage(nil), data...)
+
 	// First try to unmarshal as string
 	var str string
 	if err := json.Unmarshal(data, &str); err == nil {
@@ -169,6 +173,7 @@ type Content struct {
 	Input     map[string]interface{} `json:"input,omitempty"`
 	ToolUseID string                 `json:"tool_use_id,omitempty"`
 	Content   ContentField           `json:"content,omitempty"`
+	IsError   bool                   `json:"is_error,omitempty"`
 }
 
 // ServerToolUse tracks server-side tool usage
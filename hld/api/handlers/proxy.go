@@ -21,13 +21,76 @@ type ProxyHandler struct {
 	sessionManager session.SessionManager
 	store          store.ConversationStore
 	httpClient     *http.Client
+	overload       *session.OverloadMonitor
 }
 
 func NewProxyHandler(sessionManager session.SessionManager, store store.ConversationStore) *ProxyHandler {
 	return &ProxyHandler{
 		sessionManager: sessionManager,
 		store:          store,
-		httpClient:     &http.Client{},
+		httpClient:     &http.Client{Transport: NewProviderTransport(DefaultProviderPoolSize, DefaultProviderIdleTimeout)},
+	}
+}
+
+const (
+	// DefaultProviderPoolSize is the number of idle connections kept per provider host
+	DefaultProviderPoolSize = 16
+	// DefaultProviderIdleTimeout is how long an idle provider connection is kept open
+	DefaultProviderIdleTimeout = 90 * time.Second
+)
+
+// NewProviderTransport creates the transport used for upstream provider
+// requests. Up to poolSize idle connections are kept per host for idleTimeout,
+// so consecutive turns, within a session or across sessions, reuse warm
+// connections instead of paying for a new TCP and TLS handshake each time.
+// A poolSize of zero disables keep-alive.
+func NewProviderTransport(poolSize int, idleTimeout time.Duration) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if poolSize <= 0 {
+		transport.DisableKeepAlives = true
+		return transport
+	}
+	transport.MaxIdleConns = poolSize * 4
+	transport.MaxIdleConnsPerHost = poolSize
+	transport.IdleConnTimeout = idleTimeout
+	return transport
+}
+
+// SetConnectionPool replaces the upstream transport with one keeping poolSize
+// idle connections per provider host. Concurrency is still governed by the
+// launch scheduler and overload monitor; the pool only decides which requests
+// can skip connection setup.
+func (h *ProxyHandler) SetConnectionPool(poolSize int, idleTimeout time.Duration) {
+	if old, ok := h.httpClient.Transport.(*http.Transport); ok {
+		old.CloseIdleConnections()
+	}
+	h.httpClient = &http.Client{Transport: NewProviderTransport(poolSize, idleTimeout)}
+}
+
+// SetOverloadMonitor sets the monitor notified of upstream overload responses
+func (h *ProxyHandler) SetOverloadMonitor(monitor *session.OverloadMonitor) {
+	h.overload = monitor
+}
+
+// recordUpstreamStatus reports upstream overload and recovery to the overload
+// monitor and passes any Retry-After hint through to the client
+func (h *ProxyHandler) recordUpstreamStatus(c *gin.Context, sessionID string, resp *http.Response) {
+	if session.IsOverloadStatus(resp.StatusCode) {
+		retryAfter := resp.Header.Get("Retry-After")
+		if retryAfter != "" {
+			c.Header("Retry-After", retryAfter)
+		}
+		if h.overload != nil {
+			backoff := h.overload.ReportOverload(session.ParseRetryAfter(retryAfter))
+			slog.Warn("upstream provider overloaded",
+				"session_id", sessionID,
+				"retry_after", retryAfter,
+				"backoff", backoff)
+		}
+		return
+	}
+	if h.overload != nil && resp.StatusCode < 300 {
+		h.overload.ReportSuccess()
 	}
 }
 
@@ -267,6 +330,7 @@ func (h *ProxyHandler) handleNonStreamingProxy(c *gin.Context, sessionID string,
 		"status_code", resp.StatusCode,
 		"duration_ms", time.Since(requestStart).Milliseconds(),
 		"content_length", resp.ContentLength)
+	h.recordUpstreamStatus(c, sessionID, resp)
 
 	// Read response
 	readStart := time.Now()
@@ -370,6 +434,7 @@ func (h *ProxyHandler) handleStreamingProxy(c *gin.Context, sessionID string, ur
 		"session_id", sessionID,
 		"status_code", resp.StatusCode,
 		"initial_response_ms", time.Since(requestStart).Milliseconds())
+	h.recordUpstreamStatus(c, sessionID, resp)
 
 	// Set SSE headers
 	c.Header("Content-Type", "text/event-stream")
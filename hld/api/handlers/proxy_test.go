@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newProviderServer starts a TLS server standing in for a provider and
+// counts the connections opened to it
+func newProviderServer(t testing.TB) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var conns atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"type":"message"}`)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server, &conns
+}
+
+// providerClient returns a client using NewProviderTransport that trusts server
+func providerClient(server *httptest.Server, poolSize int) *http.Client {
+	transport := NewProviderTransport(poolSize, DefaultProviderIdleTimeout)
+	transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+	return &http.Client{Transport: transport}
+}
+
+func doTurn(t testing.TB, client *http.Client, url string) {
+	resp, err := client.Post(url, "application/json", nil)
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
+
+func TestProviderTransport(t *testing.T) {
+	t.Run("reuses connections across turns", func(t *testing.T) {
+		server, conns := newProviderServer(t)
+		client := providerClient(server, DefaultProviderPoolSize)
+		for i := 0; i < 5; i++ {
+			doTurn(t, client, server.URL)
+		}
+		assert.Equal(t, int64(1), conns.Load())
+	})
+
+	t.Run("pool size zero disables keep-alive", func(t *testing.T) {
+		server, conns := newProviderServer(t)
+		client := providerClient(server, 0)
+		for i := 0; i < 3; i++ {
+			doTurn(t, client, server.URL)
+		}
+		assert.Equal(t, int64(3), conns.Load())
+	})
+
+	t.Run("applies pool settings", func(t *testing.T) {
+		transport := NewProviderTransport(4, time.Minute)
+		assert.Equal(t, 4, transport.MaxIdleConnsPerHost)
+		assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+		assert.False(t, transport.DisableKeepAlives)
+	})
+}
+
+// BenchmarkProviderTransport compares per-turn latency with and without
+// connection pooling. Unpooled turns pay for a TCP and TLS handshake each time.
+func BenchmarkProviderTransport(b *testing.B) {
+	for _, bc := range []struct {
+		name     string
+		poolSize int
+	}{
+		{"pooled", DefaultProviderPoolSize},
+		{"unpooled", 0},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			server, _ := newProviderServer(b)
+			client := providerClient(server, bc.poolSize)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				doTurn(b, client, server.URL)
+			}
+		})
+	}
+}
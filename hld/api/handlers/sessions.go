@@ -263,6 +263,17 @@ func (h *SessionHandlers) CreateSession(ctx context.Context, req api.CreateSessi
 				RequiresCreation: true,
 			}, nil
 		}
+		var unavailableTools *session.UnavailableToolsError
+		if errors.As(err, &unavailableTools) {
+			return api.CreateSession400JSONResponse{
+				BadRequestJSONResponse: api.BadRequestJSONResponse{
+					Error: api.ErrorDetail{
+						Code:    "HLD-3001",
+						Message: unavailableTools.Error(),
+					},
+				},
+			}, nil
+		}
 		slog.Error("Failed to launch session",
 			"error", fmt.Sprintf("%v", err),
 			"query", config.Query,
@@ -1191,7 +1202,7 @@ func (h *SessionHandlers) InterruptSession(ctx context.Context, req api.Interrup
 
 // GetSessionMessages retrieves conversation history for a session
 func (h *SessionHandlers) GetSessionMessages(ctx context.Context, req api.GetSessionMessagesRequestObject) (api.GetSessionMessagesResponseObject, error) {
-	events, err := h.store.GetSessionConversation(ctx, string(req.Id))
+	events, err := h.store.GetSessionConversation(ctx, string(req.Id), store.ConversationPage{})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return api.GetSessionMessages404JSONResponse{
@@ -1566,7 +1577,11 @@ func (h *SessionHandlers) GetDebugInfo(ctx context.Context, req api.GetDebugInfo
 	}
 
 	// Get table counts from the store
-	if sqliteStore, ok := h.store.(*store.SQLiteStore); ok {
+	baseStore := h.store
+	if cached, ok := baseStore.(*store.CachedConversationStore); ok {
+		baseStore = cached.Unwrap()
+	}
+	if sqliteStore, ok := baseStore.(*store.SQLiteStore); ok {
 		// Get session count
 		if count, err := sqliteStore.GetSessionCount(ctx); err == nil {
 			stats["sessions"] = int64(count)
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -49,8 +50,16 @@ func (m *MockStore) GetSessionByRunID(ctx context.Context, runID string) (*store
 	return args.Get(0).(*store.Session), args.Error(1)
 }
 
-func (m *MockStore) ListSessions(ctx context.Context) ([]*store.Session, error) {
-	args := m.Called(ctx)
+func (m *MockStore) GetSessionsByRunID(ctx context.Context, runID string) ([]*store.Session, error) {
+	args := m.Called(ctx, runID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*store.Session), args.Error(1)
+}
+
+func (m *MockStore) ListSessions(ctx context.Context, filter store.ListSessionsFilter) ([]*store.Session, error) {
+	args := m.Called(ctx, filter)
 	return args.Get(0).([]*store.Session), args.Error(1)
 }
 
@@ -62,26 +71,65 @@ func (m *MockStore) SearchSessionsByTitle(ctx context.Context, query string, lim
 	return args.Get(0).([]*store.Session), args.Error(1)
 }
 
+func (m *MockStore) ListToolCalls(ctx context.Context, filter store.ToolCallFilter) ([]*store.ToolCallSummary, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*store.ToolCallSummary), args.Error(1)
+}
+
+func (m *MockStore) SearchEvents(ctx context.Context, query string, filter store.SearchFilter) ([]*store.ConversationEvent, error) {
+	args := m.Called(ctx, query, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*store.ConversationEvent), args.Error(1)
+}
+
 func (m *MockStore) GetExpiredDangerousPermissionsSessions(ctx context.Context) ([]*store.Session, error) {
 	args := m.Called(ctx)
 	return args.Get(0).([]*store.Session), args.Error(1)
 }
 
+func (m *MockStore) ForkSession(ctx context.Context, sourceSessionID string, atSequence int, fork *store.Session) (int, error) {
+	args := m.Called(ctx, sourceSessionID, atSequence, fork)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockStore) AddConversationEvent(ctx context.Context, event *store.ConversationEvent) error {
 	args := m.Called(ctx, event)
 	return args.Error(0)
 }
 
-func (m *MockStore) GetConversation(ctx context.Context, claudeSessionID string) ([]*store.ConversationEvent, error) {
-	args := m.Called(ctx, claudeSessionID)
+func (m *MockStore) AppendConversationEvents(ctx context.Context, events []*store.ConversationEvent) error {
+	args := m.Called(ctx, events)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetConversation(ctx context.Context, claudeSessionID string, page store.ConversationPage) ([]*store.ConversationEvent, error) {
+	args := m.Called(ctx, claudeSessionID, page)
 	return args.Get(0).([]*store.ConversationEvent), args.Error(1)
 }
 
-func (m *MockStore) GetSessionConversation(ctx context.Context, sessionID string) ([]*store.ConversationEvent, error) {
-	args := m.Called(ctx, sessionID)
+func (m *MockStore) GetSessionConversation(ctx context.Context, sessionID string, page store.ConversationPage) ([]*store.ConversationEvent, error) {
+	args := m.Called(ctx, sessionID, page)
 	return args.Get(0).([]*store.ConversationEvent), args.Error(1)
 }
 
+func (m *MockStore) GetConversationRange(ctx context.Context, sessionID string, fromSeq, toSeq int) ([]*store.ConversationEvent, error) {
+	args := m.Called(ctx, sessionID, fromSeq, toSeq)
+	return args.Get(0).([]*store.ConversationEvent), args.Error(1)
+}
+
+func (m *MockStore) SubscribeToSession(ctx context.Context, sessionID string) (<-chan *store.ConversationEvent, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(<-chan *store.ConversationEvent), args.Error(1)
+}
+
 func (m *MockStore) GetPendingToolCall(ctx context.Context, sessionID string, toolName string) (*store.ConversationEvent, error) {
 	args := m.Called(ctx, sessionID, toolName)
 	if args.Get(0) == nil {
@@ -164,8 +212,13 @@ func (m *MockStore) GetPendingApprovals(ctx context.Context, sessionID string) (
 	return args.Get(0).([]*store.Approval), args.Error(1)
 }
 
-func (m *MockStore) UpdateApprovalResponse(ctx context.Context, id string, status store.ApprovalStatus, comment string) error {
-	args := m.Called(ctx, id, status, comment)
+func (m *MockStore) GetSessionApprovals(ctx context.Context, sessionID string) ([]*store.Approval, error) {
+	args := m.Called(ctx, sessionID)
+	return args.Get(0).([]*store.Approval), args.Error(1)
+}
+
+func (m *MockStore) UpdateApprovalResponse(ctx context.Context, id string, status store.ApprovalStatus, comment, respondedBy string) error {
+	args := m.Called(ctx, id, status, comment, respondedBy)
 	return args.Error(0)
 }
 
@@ -179,6 +232,19 @@ func (m *MockStore) GetFileSnapshots(ctx context.Context, sessionID string) ([]s
 	return args.Get(0).([]store.FileSnapshot), args.Error(1)
 }
 
+func (m *MockStore) SetSessionTags(ctx context.Context, sessionID string, tags map[string]string) error {
+	args := m.Called(ctx, sessionID, tags)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetSessionTags(ctx context.Context, sessionID string) (map[string]string, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
 func (m *MockStore) GetRecentWorkingDirs(ctx context.Context, limit int) ([]store.RecentPath, error) {
 	args := m.Called(ctx, limit)
 	return args.Get(0).([]store.RecentPath), args.Error(1)
@@ -197,6 +263,280 @@ func (m *MockStore) UpdateUserSettings(ctx context.Context, settings store.UserS
 	return args.Error(0)
 }
 
+func (m *MockStore) RecordTurnUsage(ctx context.Context, turn *store.TurnUsage) error {
+	args := m.Called(ctx, turn)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetSessionThroughput(ctx context.Context, sessionID string) (*store.SessionThroughput, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*store.SessionThroughput), args.Error(1)
+}
+
+func (m *MockStore) GetSessionTurns(ctx context.Context, sessionID string) ([]*store.TurnUsage, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*store.TurnUsage), args.Error(1)
+}
+
+func (m *MockStore) GetToolOutputStats(ctx context.Context, sessionID string) ([]*store.ToolOutputStats, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*store.ToolOutputStats), args.Error(1)
+}
+
+func (m *MockStore) GetEventByPermalink(ctx context.Context, permalink string) (*store.ConversationEvent, error) {
+	args := m.Called(ctx, permalink)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*store.ConversationEvent), args.Error(1)
+}
+
+func (m *MockStore) FindEventContentHashes(ctx context.Context, hashes []string) (map[string]string, error) {
+	args := m.Called(ctx, hashes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+func (m *MockStore) GetLastEventContentHash(ctx context.Context, sessionID string) (string, error) {
+	args := m.Called(ctx, sessionID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockStore) MarkEventTruncated(ctx context.Context, eventID int64) error {
+	args := m.Called(ctx, eventID)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetConversationStats(ctx context.Context, sessionID string) (*store.ConversationStats, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*store.ConversationStats), args.Error(1)
+}
+
+func (m *MockStore) GetConversationMetrics(ctx context.Context, sessionID string) (*store.ConversationMetrics, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*store.ConversationMetrics), args.Error(1)
+}
+
+func (m *MockStore) GetSessionMetrics(ctx context.Context, sessionID string) (*store.SessionMetrics, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*store.SessionMetrics), args.Error(1)
+}
+
+func (m *MockStore) AddAnnotation(ctx context.Context, eventID int64, author, note string) (*store.Annotation, error) {
+	args := m.Called(ctx, eventID, author, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*store.Annotation), args.Error(1)
+}
+
+func (m *MockStore) GetAnnotationsForSession(ctx context.Context, sessionID string) ([]*store.Annotation, error) {
+	args := m.Called(ctx, sessionID)
+	return args.Get(0).([]*store.Annotation), args.Error(1)
+}
+
+func (m *MockStore) CreateWebhook(ctx context.Context, webhook *store.Webhook) error {
+	args := m.Called(ctx, webhook)
+	return args.Error(0)
+}
+
+func (m *MockStore) DeleteWebhook(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockStore) ListWebhooks(ctx context.Context) ([]*store.Webhook, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*store.Webhook), args.Error(1)
+}
+
+func (m *MockStore) CreateAttachment(ctx context.Context, attachment *store.Attachment) error {
+	args := m.Called(ctx, attachment)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetAttachment(ctx context.Context, id string) (*store.Attachment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*store.Attachment), args.Error(1)
+}
+
+func (m *MockStore) ListSessionAttachments(ctx context.Context, sessionID string) ([]*store.Attachment, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*store.Attachment), args.Error(1)
+}
+
+func (m *MockStore) GetSessionUsage(ctx context.Context, filter store.SessionUsageFilter) ([]*store.SessionUsage, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*store.SessionUsage), args.Error(1)
+}
+
+func (m *MockStore) AggregateCostByRun(ctx context.Context, runID string) (store.RunCostSummary, error) {
+	args := m.Called(ctx, runID)
+	return args.Get(0).(store.RunCostSummary), args.Error(1)
+}
+
+func (m *MockStore) GetSessionCostStats(ctx context.Context, runID string) (store.SessionCostStats, error) {
+	args := m.Called(ctx, runID)
+	return args.Get(0).(store.SessionCostStats), args.Error(1)
+}
+
+func (m *MockStore) AggregateCostByModel(ctx context.Context, since time.Time) ([]store.ModelCostSummary, error) {
+	args := m.Called(ctx, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]store.ModelCostSummary), args.Error(1)
+}
+
+func (m *MockStore) GetSessionsByModel(ctx context.Context, model string, limit int) ([]*store.Session, error) {
+	args := m.Called(ctx, model, limit)
+	return args.Get(0).([]*store.Session), args.Error(1)
+}
+
+func (m *MockStore) GetEvictableSessionIDs(ctx context.Context, maxSessions int) ([]string, error) {
+	args := m.Called(ctx, maxSessions)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockStore) GetSessionIncludingDeleted(ctx context.Context, sessionID string) (*store.Session, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*store.Session), args.Error(1)
+}
+
+func (m *MockStore) SoftDeleteSession(ctx context.Context, sessionID string) error {
+	args := m.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockStore) ArchiveSession(ctx context.Context, sessionID string) error {
+	args := m.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockStore) RestoreArchivedSession(ctx context.Context, sessionID string) error {
+	args := m.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockStore) CountEventsByType(ctx context.Context, sessionID string) (map[string]int, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+func (m *MockStore) GetExpiredSessionIDs(ctx context.Context, olderThan time.Time) ([]string, error) {
+	args := m.Called(ctx, olderThan)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockStore) IncrementalVacuum(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockStore) CountSessions(ctx context.Context, filter store.ListSessionsFilter) (int, error) {
+	args := m.Called(ctx, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStore) CountConversation(ctx context.Context, claudeSessionID string) (int, error) {
+	args := m.Called(ctx, claudeSessionID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStore) CountSessionConversation(ctx context.Context, sessionID string) (int, error) {
+	args := m.Called(ctx, sessionID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStore) RestoreSession(ctx context.Context, sessionID string) error {
+	args := m.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockStore) ListQueuedSessions(ctx context.Context) ([]*store.Session, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*store.Session), args.Error(1)
+}
+
+func (m *MockStore) DeleteSessionData(ctx context.Context, sessionID string) error {
+	args := m.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockStore) VacuumOldEvents(ctx context.Context, olderThan time.Time, keepTerminalSessions bool) (int64, int64, error) {
+	args := m.Called(ctx, olderThan, keepTerminalSessions)
+	return args.Get(0).(int64), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockStore) CreateAuditEntry(ctx context.Context, entry *store.AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockStore) CompleteAuditEntry(ctx context.Context, id int64, outcome string, errorMessage string) error {
+	args := m.Called(ctx, id, outcome, errorMessage)
+	return args.Error(0)
+}
+
+func (m *MockStore) ListAuditEntries(ctx context.Context, filter store.AuditLogFilter) ([]*store.AuditEntry, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*store.AuditEntry), args.Error(1)
+}
+
+func (m *MockStore) PruneAuditEntries(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStore) Ping(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 func (m *MockStore) Close() error {
 	args := m.Called()
 	return args.Error(0)
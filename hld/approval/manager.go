@@ -155,7 +155,7 @@ func (m *manager) ApproveToolCall(ctx context.Context, id string, comment string
 	}
 
 	// Update approval status
-	if err := m.store.UpdateApprovalResponse(ctx, id, store.ApprovalStatusLocalApproved, comment); err != nil {
+	if err := m.store.UpdateApprovalResponse(ctx, id, store.ApprovalStatusLocalApproved, comment, ApproverFromContext(ctx)); err != nil {
 		return fmt.Errorf("failed to update approval: %w", err)
 	}
 
@@ -192,7 +192,7 @@ func (m *manager) DenyToolCall(ctx context.Context, id string, reason string) er
 	}
 
 	// Update approval status
-	if err := m.store.UpdateApprovalResponse(ctx, id, store.ApprovalStatusLocalDenied, reason); err != nil {
+	if err := m.store.UpdateApprovalResponse(ctx, id, store.ApprovalStatusLocalDenied, reason, ApproverFromContext(ctx)); err != nil {
 		return fmt.Errorf("failed to update approval: %w", err)
 	}
 
@@ -148,7 +148,7 @@ func TestManager_ApproveToolCall(t *testing.T) {
 	mockStore.EXPECT().GetApproval(ctx, approvalID).Return(approval, nil)
 
 	// Mock updating approval response
-	mockStore.EXPECT().UpdateApprovalResponse(ctx, approvalID, store.ApprovalStatusLocalApproved, comment).Return(nil)
+	mockStore.EXPECT().UpdateApprovalResponse(ctx, approvalID, store.ApprovalStatusLocalApproved, comment, "").Return(nil)
 
 	// Mock updating approval status in conversation events
 	mockStore.EXPECT().UpdateApprovalStatus(ctx, approvalID, store.ApprovalStatusApproved).Return(nil)
@@ -178,7 +178,7 @@ func TestManager_DenyToolCall(t *testing.T) {
 
 	manager := NewManager(mockStore, mockEventBus)
 
-	ctx := context.Background()
+	ctx := WithApprover(context.Background(), "reviewer")
 	approvalID := "local-approval-123"
 	sessionID := "test-session-456"
 	reason := "Not safe to execute"
@@ -194,7 +194,7 @@ func TestManager_DenyToolCall(t *testing.T) {
 	mockStore.EXPECT().GetApproval(ctx, approvalID).Return(approval, nil)
 
 	// Mock updating approval response
-	mockStore.EXPECT().UpdateApprovalResponse(ctx, approvalID, store.ApprovalStatusLocalDenied, reason).Return(nil)
+	mockStore.EXPECT().UpdateApprovalResponse(ctx, approvalID, store.ApprovalStatusLocalDenied, reason, "reviewer").Return(nil)
 
 	// Mock updating approval status in conversation events
 	mockStore.EXPECT().UpdateApprovalStatus(ctx, approvalID, store.ApprovalStatusDenied).Return(nil)
@@ -7,6 +7,21 @@ import (
 	"github.com/humanlayer/humanlayer/hld/store"
 )
 
+type approverKey struct{}
+
+// WithApprover returns a context naming who is deciding an approval, for
+// ApproveToolCall and DenyToolCall to record. Decisions made without one,
+// such as auto-approvals, have no approver.
+func WithApprover(ctx context.Context, approver string) context.Context {
+	return context.WithValue(ctx, approverKey{}, approver)
+}
+
+// ApproverFromContext returns the approver set by WithApprover, or ""
+func ApproverFromContext(ctx context.Context) string {
+	approver, _ := ctx.Value(approverKey{}).(string)
+	return approver
+}
+
 // Manager defines the interface for managing local approvals
 type Manager interface {
 	// Create a new approval
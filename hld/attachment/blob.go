@@ -0,0 +1,60 @@
+// Package attachment stores binary attachments outside the relational store.
+// Blobs live in a BlobStore backend and the conversation store only keeps a
+// reference to them, so large files don't bloat the database or its backups.
+package attachment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Backend names, recorded with each attachment so reads go to the backend
+// that holds the blob
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// ErrNotFound is returned when a blob doesn't exist in the backend
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore is a backend for attachment content. Keys are slash-separated
+// paths; implementations must treat them as opaque beyond that.
+type BlobStore interface {
+	// Backend returns the backend name, BackendLocal or BackendS3
+	Backend() string
+	// Put stores size bytes read from r under key, replacing any existing blob
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get opens the blob stored under key. It returns ErrNotFound if there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix removes every blob whose key starts with prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// SessionPrefix is the key prefix of every attachment belonging to a session
+func SessionPrefix(sessionID string) string {
+	return "sessions/" + sessionID + "/"
+}
+
+// Key is the blob key for an attachment
+func Key(sessionID, attachmentID string) string {
+	return SessionPrefix(sessionID) + attachmentID
+}
+
+// validateKey rejects keys that could escape the backend's namespace
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(strings.TrimSuffix(key, "/"), "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
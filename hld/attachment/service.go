@@ -0,0 +1,90 @@
+package attachment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// Service stores attachment content in a blob store and references to it in
+// the conversation store
+type Service struct {
+	blobs BlobStore
+	store store.ConversationStore
+}
+
+// NewService creates an attachment service writing to blobs
+func NewService(blobs BlobStore, store store.ConversationStore) *Service {
+	return &Service{blobs: blobs, store: store}
+}
+
+// Backend returns the name of the configured blob store
+func (s *Service) Backend() string {
+	return s.blobs.Backend()
+}
+
+// Add stores size bytes read from r as an attachment of a session
+func (s *Service) Add(ctx context.Context, sessionID, name, contentType string, r io.Reader, size int64) (*store.Attachment, error) {
+	if _, err := s.store.GetSession(ctx, sessionID); err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	key := Key(sessionID, id)
+	hash := sha256.New()
+	if err := s.blobs.Put(ctx, key, io.TeeReader(r, hash), size); err != nil {
+		return nil, fmt.Errorf("failed to store attachment content: %w", err)
+	}
+
+	attachment := &store.Attachment{
+		ID:          id,
+		SessionID:   sessionID,
+		Name:        name,
+		ContentType: contentType,
+		SizeBytes:   size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		Backend:     s.blobs.Backend(),
+		StorageKey:  key,
+	}
+	if err := s.store.CreateAttachment(ctx, attachment); err != nil {
+		// Don't leave an unreferenced blob behind
+		if deleteErr := s.blobs.Delete(ctx, key); deleteErr != nil {
+			slog.Warn("failed to clean up attachment blob", "key", key, "error", deleteErr)
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// Open returns an attachment's reference and a reader for its content. The
+// caller must close the reader.
+func (s *Service) Open(ctx context.Context, id string) (*store.Attachment, io.ReadCloser, error) {
+	attachment, err := s.store.GetAttachment(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment.Backend != s.blobs.Backend() {
+		return nil, nil, fmt.Errorf("attachment %s is stored in the %s backend but the daemon is configured for %s",
+			id, attachment.Backend, s.blobs.Backend())
+	}
+	content, err := s.blobs.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read attachment content: %w", err)
+	}
+	return attachment, content, nil
+}
+
+// DeleteSessionAttachments removes the content of every attachment of a
+// session from the blob store. The references are removed with the session.
+func (s *Service) DeleteSessionAttachments(ctx context.Context, sessionID string) error {
+	if err := s.blobs.DeletePrefix(ctx, SessionPrefix(sessionID)); err != nil {
+		return fmt.Errorf("failed to delete attachments of session %s: %w", sessionID, err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 )
@@ -16,11 +17,22 @@ type eventBus struct {
 	bufferSize  int
 }
 
+// DefaultBufferSize is the number of events NewEventBus buffers per
+// subscriber before dropping events for a slow one
+const DefaultBufferSize = 100
+
 // NewEventBus creates a new event bus
 func NewEventBus() EventBus {
+	return NewEventBusWithBufferSize(DefaultBufferSize)
+}
+
+// NewEventBusWithBufferSize creates an event bus that buffers up to size
+// events per subscriber. Publish never blocks, so events for a subscriber
+// whose buffer is full are dropped.
+func NewEventBusWithBufferSize(size int) EventBus {
 	return &eventBus{
 		subscribers: make(map[string]*Subscriber),
-		bufferSize:  100, // Buffer up to 100 events per subscriber
+		bufferSize:  max(size, 0),
 	}
 }
 
@@ -171,6 +183,14 @@ func (eb *eventBus) matchesFilter(event Event, filter EventFilter) bool {
 		}
 	}
 
+	// Check status filter
+	if len(filter.Statuses) > 0 {
+		newStatus, _ := event.Data["new_status"].(string)
+		if event.Type != EventSessionStatusChanged || !slices.Contains(filter.Statuses, newStatus) {
+			return false
+		}
+	}
+
 	slog.Debug("event matches filter",
 		"event_type", event.Type,
 		"filter", filter,
@@ -185,6 +205,22 @@ func (eb *eventBus) GetSubscriberCount() int {
 	return len(eb.subscribers)
 }
 
+// GetSessionSubscriberCount returns the number of subscribers scoped to a
+// session. Unfiltered subscribers also receive the session's events but are
+// not counted, since they aren't tied to any one session.
+func (eb *eventBus) GetSessionSubscriberCount(sessionID, runID string) int {
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+	count := 0
+	for _, sub := range eb.subscribers {
+		if (sessionID != "" && sub.Filter.SessionID == sessionID) ||
+			(runID != "" && sub.Filter.RunID == runID) {
+			count++
+		}
+	}
+	return count
+}
+
 // generateSubscriberID creates a unique subscriber ID
 func generateSubscriberID() string {
 	// Use crypto/rand for proper randomness
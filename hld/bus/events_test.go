@@ -288,3 +288,84 @@ func TestEventBus_ContextCancellation(t *testing.T) {
 		// Channel might be empty but should be closed
 	}
 }
+
+func TestEventBus_GetSessionSubscriberCount(t *testing.T) {
+	eb := NewEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub := eb.Subscribe(ctx, EventFilter{SessionID: "sess-1"})
+	eb.Subscribe(ctx, EventFilter{RunID: "run-1"})
+	eb.Subscribe(ctx, EventFilter{SessionID: "sess-2"})
+	eb.Subscribe(ctx, EventFilter{})
+
+	if count := eb.GetSessionSubscriberCount("sess-1", "run-1"); count != 2 {
+		t.Errorf("expected 2 subscribers for sess-1, got %d", count)
+	}
+	if count := eb.GetSessionSubscriberCount("sess-3", "run-3"); count != 0 {
+		t.Errorf("expected 0 subscribers for sess-3, got %d", count)
+	}
+
+	eb.Unsubscribe(sub.ID)
+	if count := eb.GetSessionSubscriberCount("sess-1", "run-1"); count != 1 {
+		t.Errorf("expected 1 subscriber for sess-1 after unsubscribe, got %d", count)
+	}
+}
+
+func TestEventBus_StatusFilter(t *testing.T) {
+	eb := NewEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	completed := eb.Subscribe(ctx, EventFilter{Statuses: []string{"completed", "failed"}})
+
+	status := func(newStatus string) Event {
+		return Event{Type: EventSessionStatusChanged, Data: map[string]interface{}{
+			"session_id": "sess-1", "old_status": "running", "new_status": newStatus,
+		}}
+	}
+	start := time.Now()
+	eb.Publish(status("completed"))
+	select {
+	case received := <-completed.Channel:
+		if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+			t.Errorf("event took %s to arrive", elapsed)
+		}
+		if received.Data["new_status"] != "completed" {
+			t.Errorf("expected completed, got %v", received.Data["new_status"])
+		}
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("event not received within 10ms")
+	}
+
+	// Other transitions and other event types carrying a status are filtered out
+	eb.Publish(status("waiting_input"))
+	eb.Publish(Event{Type: EventConversationUpdated, Data: map[string]interface{}{"new_status": "completed"}})
+	select {
+	case received := <-completed.Channel:
+		t.Errorf("unexpected event %v", received)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	eb.Unsubscribe(completed.ID)
+	eb.Publish(status("failed"))
+	if _, ok := <-completed.Channel; ok {
+		t.Error("expected no deliveries after unsubscribe")
+	}
+}
+
+func TestNewEventBusWithBufferSize(t *testing.T) {
+	eb := NewEventBusWithBufferSize(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := eb.Subscribe(ctx, EventFilter{})
+
+	for i := 0; i < 5; i++ {
+		eb.Publish(Event{Type: EventNewApproval, Data: map[string]interface{}{"i": i}})
+	}
+	if got := len(sub.Channel); got != 2 {
+		t.Errorf("expected 2 buffered events, got %d", got)
+	}
+	if first := <-sub.Channel; first.Data["i"] != 0 {
+		t.Errorf("expected the oldest event to be kept, got %v", first.Data["i"])
+	}
+}
@@ -16,11 +16,22 @@ const (
 	// EventSessionStatusChanged indicates a session status has changed
 	EventSessionStatusChanged EventType = "session_status_changed"
 	// EventConversationUpdated indicates new conversation content has been added to a session
+	// Streaming fragments of a message set partial=true and carry only the new content
 	EventConversationUpdated EventType = "conversation_updated"
 	// EventSessionSettingsChanged indicates session settings have been updated
 	// Data includes: session_id, run_id, changed settings, and optional "reason" field
 	// For dangerous skip permissions expiry: reason="expired", expired_at=timestamp
 	EventSessionSettingsChanged EventType = "session_settings_changed"
+	// EventToolQuotaExceeded indicates a tool call was denied by the session's tool quota
+	// Data includes: session_id, run_id, tool_name, tool_use_id and the feedback message
+	EventToolQuotaExceeded EventType = "tool_quota_exceeded"
+	// EventSessionBudgetExceeded indicates a session was stopped and failed
+	// because it spent its cost budget
+	// Data includes: session_id, run_id, cost_usd, max_cost_usd and the error message
+	EventSessionBudgetExceeded EventType = "session_budget_exceeded"
+	// EventSessionDeleted indicates a session was permanently deleted
+	// Data includes: session_id and run_id
+	EventSessionDeleted EventType = "session_deleted"
 )
 
 // SessionSettingsChangeReason represents reasons for session settings changes
@@ -43,6 +54,11 @@ type EventFilter struct {
 	Types     []EventType // Empty means all types
 	SessionID string      // Empty means all sessions
 	RunID     string      // Empty means all run IDs
+	// Statuses restricts the filter to status changes into any of these
+	// session statuses, such as "completed", so a subscriber can wait for
+	// one lifecycle transition without inspecting payloads. Empty means all
+	// events.
+	Statuses []string
 }
 
 // Subscriber represents a client subscribed to events
@@ -64,4 +80,7 @@ type EventBus interface {
 	Publish(event Event)
 	// GetSubscriberCount returns the current number of subscribers
 	GetSubscriberCount() int
+	// GetSessionSubscriberCount returns the number of subscribers scoped to a
+	// session, either by session ID or by its run ID
+	GetSessionSubscriberCount(sessionID, runID string) int
 }
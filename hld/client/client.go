@@ -2,6 +2,7 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
@@ -15,12 +16,14 @@ import (
 // client provides a JSON-RPC 2.0 client for communicating with the HumanLayer daemon
 type client struct {
 	socketPath string
+	token      string // Bearer token sent with every call, if set
 	conn       net.Conn
 	mu         sync.Mutex
 	id         int64
 	// Track subscription connections to close them when client closes
 	subConns []net.Conn
 	subMu    sync.Mutex
+	closed   bool // Set by Close so handed-off subscriptions stop reconnecting
 }
 
 // New creates a new client that connects to the daemon's Unix socket
@@ -36,57 +39,37 @@ func New(socketPath string) (Client, error) {
 	}, nil
 }
 
-// Subscribe subscribes to events from the daemon
-func (c *client) Subscribe(req rpc.SubscribeRequest) (<-chan rpc.EventNotification, error) {
-	// Create a separate connection for subscription
-	conn, err := net.Dial("unix", c.socketPath)
+// NewWithToken creates a client that authenticates every call with token, for
+// daemons started with rpc_token_file
+func NewWithToken(socketPath, token string) (Client, error) {
+	c, err := New(socketPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create subscription connection: %w", err)
+		return nil, err
 	}
+	c.(*client).token = token
+	return c, nil
+}
 
-	// Track this subscription connection
-	c.subMu.Lock()
-	c.subConns = append(c.subConns, conn)
-	c.subMu.Unlock()
-
-	// Send subscribe request
-	encoder := json.NewEncoder(conn)
-	jsonReq := jsonRPCRequest{
-		JSONRPC: "2.0",
-		Method:  "Subscribe",
-		Params:  req,
-		ID:      atomic.AddInt64(&c.id, 1),
-	}
-	if err := encoder.Encode(jsonReq); err != nil {
-		_ = conn.Close()
-		return nil, fmt.Errorf("failed to send subscribe request: %w", err)
+// Subscribe subscribes to events from the daemon. If the daemon hands the
+// subscription off while shutting down, the client reconnects with the resume
+// token once the new instance is accepting connections.
+func (c *client) Subscribe(req rpc.SubscribeRequest) (<-chan rpc.EventNotification, error) {
+	conn, decoder, err := c.dialSubscription(req)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create channel for events
 	eventChan := make(chan rpc.EventNotification, 100)
 
-	// Create a channel to signal when subscription is confirmed
-	ready := make(chan struct{})
-
 	// Start goroutine to read events
 	go func() {
 		defer close(eventChan)
-		defer func() { _ = conn.Close() }()
 		defer func() {
-			// Remove this connection from tracked subscriptions
-			c.subMu.Lock()
-			for i, subConn := range c.subConns {
-				if subConn == conn {
-					c.subConns = append(c.subConns[:i], c.subConns[i+1:]...)
-					break
-				}
-			}
-			c.subMu.Unlock()
+			_ = conn.Close()
+			c.untrackSubConn(conn)
 		}()
 
-		decoder := json.NewDecoder(conn)
-		subscriptionConfirmed := false
-
 		for {
 			var resp jsonRPCResponse
 			if err := decoder.Decode(&resp); err != nil {
@@ -99,24 +82,42 @@ func (c *client) Subscribe(req rpc.SubscribeRequest) (<-chan rpc.EventNotificati
 				continue
 			}
 
-			// First check if it's a subscription response
-			if !subscriptionConfirmed {
-				var subResp rpc.SubscribeResponse
-				if err := json.Unmarshal(resp.Result, &subResp); err == nil && subResp.SubscriptionID != "" {
-					// This is the initial subscription confirmation
-					subscriptionConfirmed = true
-					close(ready)
-					continue
+			// Check if it's a heartbeat or control frame
+			var control map[string]interface{}
+			if err := json.Unmarshal(resp.Result, &control); err == nil {
+				if controlType, ok := control["type"].(string); ok {
+					if controlType == "heartbeat" {
+						// Skip heartbeats
+						continue
+					}
+					if controlType == "reconnect" {
+						var handoff rpc.ReconnectNotification
+						if err := json.Unmarshal(resp.Result, &handoff); err != nil {
+							return
+						}
+						_ = conn.Close()
+						c.untrackSubConn(conn)
+						var resumeErr error
+						conn, decoder, resumeErr = c.resumeSubscription(handoff)
+						if resumeErr != nil {
+							return
+						}
+						continue
+					}
 				}
 			}
 
-			// Check if it's a heartbeat
-			var heartbeat map[string]interface{}
-			if err := json.Unmarshal(resp.Result, &heartbeat); err == nil {
-				if hbType, ok := heartbeat["type"].(string); ok && hbType == "heartbeat" {
-					// Skip heartbeats
-					continue
+			// Batched subscriptions deliver several events per frame
+			var batch rpc.EventBatchNotification
+			if err := json.Unmarshal(resp.Result, &batch); err == nil && len(batch.Events) > 0 {
+				for _, event := range batch.Events {
+					select {
+					case eventChan <- rpc.EventNotification{Event: event}:
+					default:
+						// Channel full, drop event
+					}
 				}
+				continue
 			}
 
 			// Try to decode as event notification
@@ -131,15 +132,117 @@ func (c *client) Subscribe(req rpc.SubscribeRequest) (<-chan rpc.EventNotificati
 		}
 	}()
 
-	// Wait for subscription confirmation with timeout
-	select {
-	case <-ready:
-		// Subscription confirmed
-		return eventChan, nil
-	case <-time.After(5 * time.Second):
+	return eventChan, nil
+}
+
+// subscriptionResumeTimeout bounds how long a handed-off subscription waits
+// for the daemon to come back
+const subscriptionResumeTimeout = 30 * time.Second
+
+// resumeSubscription reconnects a handed-off subscription, retrying until the
+// new daemon instance accepts it or the resume timeout elapses
+func (c *client) resumeSubscription(handoff rpc.ReconnectNotification) (net.Conn, *json.Decoder, error) {
+	retryAfter := time.Duration(handoff.RetryAfterMS) * time.Millisecond
+	if retryAfter <= 0 {
+		retryAfter = 500 * time.Millisecond
+	}
+	deadline := time.Now().Add(subscriptionResumeTimeout)
+	req := rpc.SubscribeRequest{ResumeToken: handoff.ResumeToken}
+
+	for {
+		time.Sleep(retryAfter)
+		if c.isClosed() {
+			return nil, nil, fmt.Errorf("client closed")
+		}
+		conn, decoder, err := c.dialSubscription(req)
+		if err == nil {
+			return conn, decoder, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, nil, fmt.Errorf("failed to resume subscription: %w", err)
+		}
+	}
+}
+
+// dialSubscription opens a subscription connection and waits for the daemon
+// to confirm it
+func (c *client) dialSubscription(req rpc.SubscribeRequest) (net.Conn, *json.Decoder, error) {
+	// Create a separate connection for subscription
+	conn, err := net.Dial("unix", c.socketPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create subscription connection: %w", err)
+	}
+
+	// Track this subscription connection
+	c.subMu.Lock()
+	if c.closed {
+		c.subMu.Unlock()
 		_ = conn.Close()
-		return nil, fmt.Errorf("timeout waiting for subscription confirmation")
+		return nil, nil, fmt.Errorf("client closed")
+	}
+	c.subConns = append(c.subConns, conn)
+	c.subMu.Unlock()
+
+	fail := func(err error) (net.Conn, *json.Decoder, error) {
+		_ = conn.Close()
+		c.untrackSubConn(conn)
+		return nil, nil, err
+	}
+
+	// Send subscribe request
+	encoder := json.NewEncoder(conn)
+	jsonReq := jsonRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "Subscribe",
+		Params:  c.authParams(req),
+		ID:      atomic.AddInt64(&c.id, 1),
 	}
+	if err := encoder.Encode(jsonReq); err != nil {
+		return fail(fmt.Errorf("failed to send subscribe request: %w", err))
+	}
+
+	// Wait for subscription confirmation with timeout
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	decoder := json.NewDecoder(conn)
+	for {
+		var resp jsonRPCResponse
+		if err := decoder.Decode(&resp); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return fail(fmt.Errorf("timeout waiting for subscription confirmation"))
+			}
+			return fail(fmt.Errorf("subscription connection closed: %w", err))
+		}
+		if resp.Error != nil {
+			return fail(fmt.Errorf("subscription rejected: %s", resp.Error.Message))
+		}
+		var subResp rpc.SubscribeResponse
+		if err := json.Unmarshal(resp.Result, &subResp); err == nil && subResp.SubscriptionID != "" {
+			break
+		}
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	return conn, decoder, nil
+}
+
+// untrackSubConn removes a subscription connection from the tracked set
+func (c *client) untrackSubConn(conn net.Conn) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	for i, subConn := range c.subConns {
+		if subConn == conn {
+			c.subConns = append(c.subConns[:i], c.subConns[i+1:]...)
+			break
+		}
+	}
+}
+
+// isClosed reports whether Close has been called
+func (c *client) isClosed() bool {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	return c.closed
 }
 
 // Close closes the connection to the daemon
@@ -153,6 +256,7 @@ func (c *client) Close() error {
 		_ = conn.Close()
 	}
 	c.subConns = nil
+	c.closed = true
 	c.subMu.Unlock()
 
 	// Close main connection
@@ -178,6 +282,21 @@ type jsonRPCResponse struct {
 	ID      interface{}     `json:"id,omitempty"` // Can be number, string, or null for notifications
 }
 
+// authEnvelope wraps the params of a call to a daemon that requires a token
+type authEnvelope struct {
+	Authorization string      `json:"authorization"`
+	Params        interface{} `json:"params,omitempty"`
+}
+
+// authParams returns params as sent to the daemon, wrapped in the
+// authentication envelope when the client has a token
+func (c *client) authParams(params interface{}) interface{} {
+	if c.token == "" {
+		return params
+	}
+	return authEnvelope{Authorization: "Bearer " + c.token, Params: params}
+}
+
 // call sends an RPC request and waits for the response
 func (c *client) call(method string, params interface{}, result interface{}) error {
 	c.mu.Lock()
@@ -194,7 +313,7 @@ func (c *client) call(method string, params interface{}, result interface{}) err
 	req := jsonRPCRequest{
 		JSONRPC: "2.0",
 		Method:  method,
-		Params:  params,
+		Params:  c.authParams(params),
 		ID:      id,
 	}
 
@@ -206,14 +325,18 @@ func (c *client) call(method string, params interface{}, result interface{}) err
 
 	// Read response
 	decoder := json.NewDecoder(c.conn)
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
 	var resp jsonRPCResponse
-	if err := decoder.Decode(&resp); err != nil {
+	if err := json.Unmarshal(raw, &resp); err != nil {
 		return fmt.Errorf("failed to read response: %w", err)
 	}
 
-	// Check for error
+	// Check for error, keeping its class so callers can match it with errors.Is
 	if resp.Error != nil {
-		return fmt.Errorf("RPC error %d: %s", resp.Error.Code, resp.Error.Message)
+		return fmt.Errorf("RPC error %d: %w", resp.Error.Code, rpc.DecodeError(raw))
 	}
 
 	// Unmarshal result if provided
@@ -374,10 +497,17 @@ func (c *client) Reconnect() error {
 
 // Connect attempts to connect to the daemon with retries
 func Connect(socketPath string, maxRetries int, retryDelay time.Duration) (Client, error) {
+	return ConnectWithToken(socketPath, "", maxRetries, retryDelay)
+}
+
+// ConnectWithToken attempts to connect to the daemon with retries, using a
+// client that authenticates every call with token. An empty token sends
+// calls unauthenticated.
+func ConnectWithToken(socketPath, token string, maxRetries int, retryDelay time.Duration) (Client, error) {
 	var lastErr error
 
 	for i := 0; i <= maxRetries; i++ {
-		client, err := New(socketPath)
+		client, err := NewWithToken(socketPath, token)
 		if err == nil {
 			// Test the connection
 			if err := client.Health(); err == nil {
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/humanlayer/humanlayer/hld/bus"
 	"github.com/humanlayer/humanlayer/hld/internal/testutil"
 	"github.com/humanlayer/humanlayer/hld/rpc"
 	"github.com/humanlayer/humanlayer/hld/store"
@@ -282,3 +284,109 @@ func TestClient_InterruptSession(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "session_id required")
 }
+
+func TestClient_WithToken(t *testing.T) {
+	socketPath := testutil.CreateTestSocket(t)
+	_ = os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	server := rpc.NewServer()
+	server.SetTokenProvider(rpc.StaticTokenProvider{"secret"})
+	server.Register("interruptSession", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var req rpc.InterruptSessionRequest
+		if err := json.Unmarshal(params, &req); err != nil || req.SessionID != "sess-1" {
+			return nil, fmt.Errorf("%w: unexpected params %s", rpc.ErrInvalidRequest, params)
+		}
+		return struct{}{}, nil
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() { _ = server.ServeConn(context.Background(), conn) }()
+		}
+	}()
+
+	authed, err := NewWithToken(socketPath, "secret")
+	require.NoError(t, err)
+	defer func() { _ = authed.Close() }()
+	assert.NoError(t, authed.Health())
+	assert.NoError(t, authed.InterruptSession("sess-1"), "the handler gets the unwrapped params")
+
+	anonymous, err := New(socketPath)
+	require.NoError(t, err)
+	defer func() { _ = anonymous.Close() }()
+	assert.NoError(t, anonymous.Health(), "health needs no token")
+	assert.ErrorIs(t, anonymous.InterruptSession("sess-1"), rpc.ErrUnauthorized)
+
+	wrong, err := ConnectWithToken(socketPath, "wrong", 0, 0)
+	require.NoError(t, err)
+	defer func() { _ = wrong.Close() }()
+	assert.ErrorIs(t, wrong.InterruptSession("sess-1"), rpc.ErrUnauthorized)
+}
+
+func TestClient_SubscribeResumesAfterHandoff(t *testing.T) {
+	socketPath := testutil.CreateTestSocket(t)
+	_ = os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	resumeTokens := make(chan string, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				var req struct {
+					Method string               `json:"method"`
+					Params rpc.SubscribeRequest `json:"params"`
+				}
+				if err := json.NewDecoder(conn).Decode(&req); err != nil || req.Method != "Subscribe" {
+					return
+				}
+				resumeTokens <- req.Params.ResumeToken
+
+				encoder := json.NewEncoder(conn)
+				_ = encoder.Encode(rpc.Response{JSONRPC: "2.0", Result: rpc.SubscribeResponse{SubscriptionID: "sub"}})
+				if req.Params.ResumeToken == "" {
+					// Simulate the daemon shutting down
+					_ = encoder.Encode(rpc.Response{JSONRPC: "2.0", Result: rpc.ReconnectNotification{
+						Type:         "reconnect",
+						Reason:       "daemon_shutdown",
+						ResumeToken:  "tok",
+						RetryAfterMS: 10,
+					}})
+					return
+				}
+				_ = encoder.Encode(rpc.Response{JSONRPC: "2.0", Result: rpc.EventNotification{
+					Event: bus.Event{Type: bus.EventNewApproval},
+				}})
+				time.Sleep(time.Second)
+			}()
+		}
+	}()
+
+	c, err := New(socketPath)
+	require.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	events, err := c.Subscribe(rpc.SubscribeRequest{SessionID: "sess-1"})
+	require.NoError(t, err)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, bus.EventNewApproval, event.Event.Type)
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscription did not resume")
+	}
+	assert.Equal(t, "", <-resumeTokens)
+	assert.Equal(t, "tok", <-resumeTokens)
+}
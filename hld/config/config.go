@@ -2,9 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -27,6 +30,12 @@ type Config struct {
 	// Database configuration
 	DatabasePath string `mapstructure:"database_path"`
 
+	// DatabaseKey enables encryption at rest for conversation content, session
+	// prompts and secrets, approval inputs and file snapshots. It is
+	// a secret, so it is only read from the environment or the config file
+	// and never written back by Save.
+	DatabaseKey string `mapstructure:"database_key"`
+
 	// API configuration (for future phases)
 	APIKey     string `mapstructure:"api_key"`
 	APIBaseURL string `mapstructure:"api_base_url"`
@@ -43,6 +52,86 @@ type Config struct {
 
 	// Claude configuration
 	ClaudePath string `mapstructure:"claude_path"`
+
+	// Session storage cap (0 disables eviction)
+	MaxStoredSessions  int    `mapstructure:"max_stored_sessions"`
+	EvictionArchiveDir string `mapstructure:"eviction_archive_dir"`
+
+	// Days finished sessions are kept after their last activity, and how many
+	// sessions are kept at most before the least recently active finished
+	// ones are pruned (0 disables either limit)
+	RetentionDays        int `mapstructure:"retention_days"`
+	RetentionMaxSessions int `mapstructure:"retention_max_sessions"`
+
+	// Launch concurrency cap (0 disables) and how queued launches are ordered
+	MaxConcurrentSessions int    `mapstructure:"max_concurrent_sessions"`
+	SchedulingPolicy      string `mapstructure:"scheduling_policy"` // "fair" or "fifo"
+
+	// Concurrency allowed while the provider reports overload, and the longest
+	// backoff applied after repeated overloads
+	OverloadMaxConcurrent int           `mapstructure:"overload_max_concurrent"`
+	OverloadMaxBackoff    time.Duration `mapstructure:"overload_max_backoff"`
+
+	// Tools whose results may be reused for identical calls within the TTL
+	CacheableTools []string      `mapstructure:"cacheable_tools"`
+	ToolCacheTTL   time.Duration `mapstructure:"tool_cache_ttl"`
+
+	// How long audit log entries are kept (0 keeps them forever). Independent
+	// of session eviction, which never removes audit entries.
+	AuditRetention time.Duration `mapstructure:"audit_retention"`
+
+	// How long a running session may go without activity before it is
+	// marked failed (0 disables)
+	SessionInactivityTimeout time.Duration `mapstructure:"session_inactivity_timeout"`
+
+	// Sessions kept in the in-memory GetSession cache (0 disables it) and how
+	// long each is served before being re-read from the database
+	SessionCacheSize int           `mapstructure:"session_cache_size"`
+	SessionCacheTTL  time.Duration `mapstructure:"session_cache_ttl"`
+
+	// Idle connections kept per provider host for proxied requests (0 disables
+	// keep-alive) and how long they stay open
+	ProviderPoolSize    int           `mapstructure:"provider_pool_size"`
+	ProviderIdleTimeout time.Duration `mapstructure:"provider_idle_timeout"`
+
+	// Where attachment content is stored: "local" (default) or "s3". Local
+	// attachments go in AttachmentDir, which defaults to an attachments
+	// directory next to the database.
+	AttachmentBackend string `mapstructure:"attachment_backend"`
+	AttachmentDir     string `mapstructure:"attachment_dir"`
+
+	// S3-compatible object store used by the "s3" attachment backend. The
+	// secret access key is never written back by Save.
+	AttachmentS3Endpoint        string `mapstructure:"attachment_s3_endpoint"`
+	AttachmentS3Bucket          string `mapstructure:"attachment_s3_bucket"`
+	AttachmentS3Region          string `mapstructure:"attachment_s3_region"`
+	AttachmentS3AccessKeyID     string `mapstructure:"attachment_s3_access_key_id"`
+	AttachmentS3SecretAccessKey string `mapstructure:"attachment_s3_secret_access_key"`
+
+	// Extra regular expressions redacted as secrets from exports, on top of
+	// the built-in credential patterns
+	RedactionPatterns []string `mapstructure:"redaction_patterns"`
+
+	// Translation provider for getConversation's translate_to, a
+	// LibreTranslate-compatible endpoint. Translation is unavailable if unset.
+	TranslationEndpoint string `mapstructure:"translation_endpoint"`
+	TranslationAPIKey   string `mapstructure:"translation_api_key"` // Not saved to the config file
+
+	// File of bearer tokens, one per line, that every RPC call except
+	// health must carry. RPCs are unauthenticated if unset.
+	RPCTokenFile string `mapstructure:"rpc_token_file"`
+
+	// Calls per second allowed for each RPC method, shared by all clients,
+	// with "*" applying to methods not listed. Methods are unlimited if
+	// neither is set. RateLimitBurst is how many calls may exceed the rate
+	// at once.
+	RateLimits     map[string]float64 `mapstructure:"rate_limits"`
+	RateLimitBurst int                `mapstructure:"rate_limit_burst"`
+
+	// Feature flags gate specific behaviors. Owner flags override the global
+	// value for launches and calls made by that owner. Reloaded on SIGHUP.
+	FeatureFlags      map[string]bool            `mapstructure:"feature_flags"`
+	OwnerFeatureFlags map[string]map[string]bool `mapstructure:"owner_feature_flags"`
 }
 
 // Load loads configuration with priority: flags > env vars > config file > defaults
@@ -65,6 +154,7 @@ func Load() (*Config, error) {
 	// Map environment variables to config keys
 	_ = v.BindEnv("socket_path", "HUMANLAYER_DAEMON_SOCKET")
 	_ = v.BindEnv("database_path", "HUMANLAYER_DATABASE_PATH")
+	_ = v.BindEnv("database_key", "HUMANLAYER_DATABASE_KEY")
 	_ = v.BindEnv("api_key", "HUMANLAYER_API_KEY")
 	_ = v.BindEnv("api_base_url", "HUMANLAYER_API_BASE_URL", "HUMANLAYER_API_BASE")
 	_ = v.BindEnv("log_level", "HUMANLAYER_LOG_LEVEL")
@@ -72,6 +162,34 @@ func Load() (*Config, error) {
 	_ = v.BindEnv("http_port", "HUMANLAYER_DAEMON_HTTP_PORT")
 	_ = v.BindEnv("http_host", "HUMANLAYER_DAEMON_HTTP_HOST")
 	_ = v.BindEnv("claude_path", "HUMANLAYER_CLAUDE_PATH")
+	_ = v.BindEnv("max_stored_sessions", "HUMANLAYER_MAX_STORED_SESSIONS")
+	_ = v.BindEnv("eviction_archive_dir", "HUMANLAYER_EVICTION_ARCHIVE_DIR")
+	_ = v.BindEnv("retention_days", "HUMANLAYER_RETENTION_DAYS")
+	_ = v.BindEnv("retention_max_sessions", "HUMANLAYER_RETENTION_MAX_SESSIONS")
+	_ = v.BindEnv("max_concurrent_sessions", "HUMANLAYER_MAX_CONCURRENT_SESSIONS")
+	_ = v.BindEnv("scheduling_policy", "HUMANLAYER_SCHEDULING_POLICY")
+	_ = v.BindEnv("overload_max_concurrent", "HUMANLAYER_OVERLOAD_MAX_CONCURRENT")
+	_ = v.BindEnv("overload_max_backoff", "HUMANLAYER_OVERLOAD_MAX_BACKOFF")
+	_ = v.BindEnv("cacheable_tools", "HUMANLAYER_CACHEABLE_TOOLS")
+	_ = v.BindEnv("tool_cache_ttl", "HUMANLAYER_TOOL_CACHE_TTL")
+	_ = v.BindEnv("audit_retention", "HUMANLAYER_AUDIT_RETENTION")
+	_ = v.BindEnv("session_inactivity_timeout", "HUMANLAYER_SESSION_INACTIVITY_TIMEOUT")
+	_ = v.BindEnv("session_cache_size", "HUMANLAYER_SESSION_CACHE_SIZE")
+	_ = v.BindEnv("session_cache_ttl", "HUMANLAYER_SESSION_CACHE_TTL")
+	_ = v.BindEnv("provider_pool_size", "HUMANLAYER_PROVIDER_POOL_SIZE")
+	_ = v.BindEnv("provider_idle_timeout", "HUMANLAYER_PROVIDER_IDLE_TIMEOUT")
+	_ = v.BindEnv("attachment_backend", "HUMANLAYER_ATTACHMENT_BACKEND")
+	_ = v.BindEnv("attachment_dir", "HUMANLAYER_ATTACHMENT_DIR")
+	_ = v.BindEnv("attachment_s3_endpoint", "HUMANLAYER_ATTACHMENT_S3_ENDPOINT")
+	_ = v.BindEnv("attachment_s3_bucket", "HUMANLAYER_ATTACHMENT_S3_BUCKET")
+	_ = v.BindEnv("attachment_s3_region", "HUMANLAYER_ATTACHMENT_S3_REGION")
+	_ = v.BindEnv("attachment_s3_access_key_id", "HUMANLAYER_ATTACHMENT_S3_ACCESS_KEY_ID")
+	_ = v.BindEnv("attachment_s3_secret_access_key", "HUMANLAYER_ATTACHMENT_S3_SECRET_ACCESS_KEY")
+	_ = v.BindEnv("redaction_patterns", "HUMANLAYER_REDACTION_PATTERNS")
+	_ = v.BindEnv("translation_endpoint", "HUMANLAYER_TRANSLATION_ENDPOINT")
+	_ = v.BindEnv("translation_api_key", "HUMANLAYER_TRANSLATION_API_KEY")
+	_ = v.BindEnv("rpc_token_file", "HUMANLAYER_RPC_TOKEN_FILE")
+	_ = v.BindEnv("rate_limit_burst", "HUMANLAYER_RATE_LIMIT_BURST")
 
 	// Set defaults
 	setDefaults(v)
@@ -93,6 +211,9 @@ func Load() (*Config, error) {
 	config.SocketPath = expandHome(config.SocketPath)
 	config.DatabasePath = expandHome(config.DatabasePath)
 	config.ClaudePath = expandHome(config.ClaudePath)
+	config.EvictionArchiveDir = expandHome(config.EvictionArchiveDir)
+	config.AttachmentDir = expandHome(config.AttachmentDir)
+	config.RPCTokenFile = expandHome(config.RPCTokenFile)
 
 	return &config, nil
 }
@@ -112,6 +233,18 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("http_port", port)
 	v.SetDefault("http_host", "127.0.0.1")
 	v.SetDefault("claude_path", DefaultClaudePath)
+	v.SetDefault("scheduling_policy", "fair")
+	v.SetDefault("overload_max_concurrent", 1)
+	v.SetDefault("overload_max_backoff", "5m")
+	v.SetDefault("tool_cache_ttl", "10m")
+	v.SetDefault("audit_retention", "0s")
+	v.SetDefault("session_inactivity_timeout", "0s")
+	v.SetDefault("session_cache_size", 256)
+	v.SetDefault("session_cache_ttl", "5s")
+	v.SetDefault("provider_pool_size", 16)
+	v.SetDefault("provider_idle_timeout", "90s")
+	v.SetDefault("attachment_backend", "local")
+	v.SetDefault("rate_limit_burst", 10)
 }
 
 // getDefaultConfigDir returns the default configuration directory
@@ -148,6 +281,77 @@ func (c *Config) Validate() error {
 	if c.SocketPath == "" {
 		return fmt.Errorf("socket path cannot be empty")
 	}
+	if c.MaxStoredSessions < 0 {
+		return fmt.Errorf("max stored sessions cannot be negative")
+	}
+	if c.MaxConcurrentSessions < 0 {
+		return fmt.Errorf("max concurrent sessions cannot be negative")
+	}
+	switch c.SchedulingPolicy {
+	case "", "fair", "fifo":
+	default:
+		return fmt.Errorf("unknown scheduling policy %q (must be fair or fifo)", c.SchedulingPolicy)
+	}
+	if c.OverloadMaxConcurrent < 0 {
+		return fmt.Errorf("overload max concurrent cannot be negative")
+	}
+	if c.OverloadMaxBackoff < 0 {
+		return fmt.Errorf("overload max backoff cannot be negative")
+	}
+	if c.ToolCacheTTL < 0 {
+		return fmt.Errorf("tool cache TTL cannot be negative")
+	}
+	if c.RetentionDays < 0 {
+		return fmt.Errorf("retention days cannot be negative")
+	}
+	if c.RetentionMaxSessions < 0 {
+		return fmt.Errorf("retention max sessions cannot be negative")
+	}
+	if c.AuditRetention < 0 {
+		return fmt.Errorf("audit retention cannot be negative")
+	}
+	if c.SessionInactivityTimeout < 0 {
+		return fmt.Errorf("session inactivity timeout cannot be negative")
+	}
+	if c.SessionCacheSize < 0 {
+		return fmt.Errorf("session cache size cannot be negative")
+	}
+	if c.SessionCacheTTL < 0 {
+		return fmt.Errorf("session cache TTL cannot be negative")
+	}
+	for method, limit := range c.RateLimits {
+		if limit <= 0 {
+			return fmt.Errorf("rate limit for %q must be positive", method)
+		}
+	}
+	if c.RateLimitBurst < 0 {
+		return fmt.Errorf("rate limit burst cannot be negative")
+	}
+	if c.ProviderPoolSize < 0 {
+		return fmt.Errorf("provider pool size cannot be negative")
+	}
+	if c.ProviderIdleTimeout < 0 {
+		return fmt.Errorf("provider idle timeout cannot be negative")
+	}
+	switch c.AttachmentBackend {
+	case "", "local":
+	case "s3":
+		if c.AttachmentS3Endpoint == "" || c.AttachmentS3Bucket == "" {
+			return fmt.Errorf("s3 attachment backend requires an endpoint and bucket")
+		}
+	default:
+		return fmt.Errorf("unknown attachment backend %q (must be local or s3)", c.AttachmentBackend)
+	}
+	if c.TranslationEndpoint != "" {
+		if u, err := url.Parse(c.TranslationEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("translation endpoint must be an http or https URL")
+		}
+	}
+	for _, pattern := range c.RedactionPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+	}
 	return nil
 }
 
@@ -178,6 +382,35 @@ func Save(cfg *Config) error {
 	v.Set("http_port", cfg.HTTPPort)
 	v.Set("http_host", cfg.HTTPHost)
 	v.Set("claude_path", cfg.ClaudePath)
+	v.Set("max_stored_sessions", cfg.MaxStoredSessions)
+	v.Set("eviction_archive_dir", cfg.EvictionArchiveDir)
+	v.Set("retention_days", cfg.RetentionDays)
+	v.Set("retention_max_sessions", cfg.RetentionMaxSessions)
+	v.Set("max_concurrent_sessions", cfg.MaxConcurrentSessions)
+	v.Set("scheduling_policy", cfg.SchedulingPolicy)
+	v.Set("overload_max_concurrent", cfg.OverloadMaxConcurrent)
+	v.Set("overload_max_backoff", cfg.OverloadMaxBackoff.String())
+	v.Set("cacheable_tools", cfg.CacheableTools)
+	v.Set("tool_cache_ttl", cfg.ToolCacheTTL.String())
+	v.Set("audit_retention", cfg.AuditRetention.String())
+	v.Set("session_inactivity_timeout", cfg.SessionInactivityTimeout.String())
+	v.Set("session_cache_size", cfg.SessionCacheSize)
+	v.Set("session_cache_ttl", cfg.SessionCacheTTL.String())
+	v.Set("provider_pool_size", cfg.ProviderPoolSize)
+	v.Set("provider_idle_timeout", cfg.ProviderIdleTimeout.String())
+	v.Set("attachment_backend", cfg.AttachmentBackend)
+	v.Set("attachment_dir", cfg.AttachmentDir)
+	v.Set("attachment_s3_endpoint", cfg.AttachmentS3Endpoint)
+	v.Set("attachment_s3_bucket", cfg.AttachmentS3Bucket)
+	v.Set("attachment_s3_region", cfg.AttachmentS3Region)
+	v.Set("attachment_s3_access_key_id", cfg.AttachmentS3AccessKeyID)
+	v.Set("redaction_patterns", cfg.RedactionPatterns)
+	v.Set("translation_endpoint", cfg.TranslationEndpoint)
+	v.Set("rpc_token_file", cfg.RPCTokenFile)
+	v.Set("rate_limits", cfg.RateLimits)
+	v.Set("rate_limit_burst", cfg.RateLimitBurst)
+	v.Set("feature_flags", cfg.FeatureFlags)
+	v.Set("owner_feature_flags", cfg.OwnerFeatureFlags)
 
 	// Set config file path explicitly
 	configFile := filepath.Join(configDir, "humanlayer.json")
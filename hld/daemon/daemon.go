@@ -6,17 +6,26 @@ import (
 	"log/slog"
 	"net"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/humanlayer/humanlayer/hld/approval"
+	"github.com/humanlayer/humanlayer/hld/attachment"
 	"github.com/humanlayer/humanlayer/hld/bus"
 	"github.com/humanlayer/humanlayer/hld/config"
+	"github.com/humanlayer/humanlayer/hld/feature"
+	"github.com/humanlayer/humanlayer/hld/redact"
 	"github.com/humanlayer/humanlayer/hld/rpc"
 	"github.com/humanlayer/humanlayer/hld/session"
 	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/humanlayer/humanlayer/hld/translate"
+	"github.com/humanlayer/humanlayer/hld/webhook"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -55,12 +64,18 @@ type Daemon struct {
 	socketPath        string
 	listener          net.Listener
 	rpcServer         *rpc.Server
+	sessionHandlers   *rpc.SessionHandlers
 	httpServer        *HTTPServer
 	sessions          session.SessionManager
 	approvals         approval.Manager
 	eventBus          bus.EventBus
 	store             store.ConversationStore
 	permissionMonitor *session.PermissionMonitor
+	sessionEvictor    *session.SessionEvictor
+	launchScheduler   *session.LaunchScheduler
+	overloadMonitor   *session.OverloadMonitor
+	attachments       *attachment.Service
+	features          *feature.Flags
 }
 
 // New creates a new daemon instance
@@ -110,27 +125,83 @@ func New() (*Daemon, error) {
 	// Create event bus
 	eventBus := bus.NewEventBus()
 
-	// Initialize SQLite store
-	conversationStore, err := store.NewSQLiteStore(cfg.DatabasePath)
+	// Initialize SQLite store, encrypted at rest when a key is configured
+	var conversationStore *store.SQLiteStore
+	if cfg.DatabaseKey != "" {
+		conversationStore, err = store.NewEncryptedSQLiteStore(cfg.DatabasePath, cfg.DatabaseKey)
+	} else {
+		conversationStore, err = store.NewSQLiteStore(cfg.DatabasePath)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SQLite store: %w", err)
 	}
 
+	// Serve repeated session lookups, such as getSessionState on every UI
+	// refresh, from memory. Everything below uses the cached store, so its
+	// session writes invalidate the cache.
+	var sessionStore store.ConversationStore = conversationStore
+	if cfg.SessionCacheSize > 0 {
+		sessionStore = store.NewCachedConversationStore(conversationStore, cfg.SessionCacheSize, cfg.SessionCacheTTL)
+		slog.Info("session cache enabled",
+			"size", cfg.SessionCacheSize,
+			"ttl", cfg.SessionCacheTTL)
+	}
+
+	// Attachment content lives outside the database in the configured backend
+	blobStore, err := newAttachmentStore(cfg)
+	if err != nil {
+		_ = conversationStore.Close()
+		return nil, fmt.Errorf("failed to create attachment store: %w", err)
+	}
+	slog.Info("attachment storage configured", "backend", blobStore.Backend())
+
 	// Create session manager with store and config
-	sessionManager, err := session.NewManagerWithConfig(eventBus, conversationStore, cfg.SocketPath, cfg)
+	sessionManager, err := session.NewManagerWithConfig(eventBus, sessionStore, cfg.SocketPath, cfg)
 	if err != nil {
 		_ = conversationStore.Close()
 		return nil, fmt.Errorf("failed to create session manager: %w", err)
 	}
 
+	// The scheduler caps concurrently running sessions if configured, and is
+	// always present so provider overload can throttle launches
+	policy, err := session.ParseSchedulingPolicy(cfg.SchedulingPolicy)
+	if err != nil {
+		_ = conversationStore.Close()
+		return nil, fmt.Errorf("invalid scheduling policy: %w", err)
+	}
+	launchScheduler := session.NewLaunchScheduler(cfg.MaxConcurrentSessions, policy)
+	sessionManager.SetLaunchScheduler(launchScheduler)
+	if cfg.MaxConcurrentSessions > 0 {
+		slog.Info("session launch cap enabled",
+			"max_concurrent", cfg.MaxConcurrentSessions,
+			"policy", policy)
+	}
+
+	overloadMonitor := session.NewOverloadMonitor(launchScheduler, cfg.OverloadMaxConcurrent, cfg.OverloadMaxBackoff)
+	sessionManager.SetOverloadMonitor(overloadMonitor)
+
+	// Serve repeated calls to tools marked cacheable from a result cache
+	if len(cfg.CacheableTools) > 0 {
+		sessionManager.SetToolResultCache(session.NewToolResultCache(cfg.CacheableTools, cfg.ToolCacheTTL))
+		slog.Info("tool result cache enabled",
+			"tools", cfg.CacheableTools,
+			"ttl", cfg.ToolCacheTTL)
+	}
+
+	// Feature flags are shared by the session manager and RPC server so a
+	// reload applies everywhere at once
+	features := feature.New(cfg.FeatureFlags, cfg.OwnerFeatureFlags)
+	sessionManager.SetFeatureFlags(features)
+
 	// Always create local approval manager
 	slog.Info("creating local approval manager")
-	approvalManager := approval.NewManager(conversationStore, eventBus)
+	approvalManager := approval.NewManager(sessionStore, eventBus)
 	slog.Debug("local approval manager created successfully")
 
 	// Create HTTP server (always enabled, port 0 means dynamic allocation)
 	slog.Info("creating HTTP server", "port", cfg.HTTPPort)
-	httpServer := NewHTTPServer(cfg, sessionManager, approvalManager, conversationStore, eventBus)
+	httpServer := NewHTTPServer(cfg, sessionManager, approvalManager, sessionStore, eventBus)
+	httpServer.SetOverloadMonitor(overloadMonitor)
 
 	return &Daemon{
 		config:     cfg,
@@ -138,11 +209,36 @@ func New() (*Daemon, error) {
 		sessions:   sessionManager,
 		approvals:  approvalManager,
 		eventBus:   eventBus,
-		store:      conversationStore,
+		store:      sessionStore,
 		httpServer: httpServer,
+
+		launchScheduler: launchScheduler,
+		overloadMonitor: overloadMonitor,
+		attachments:     attachment.NewService(blobStore, sessionStore),
+		features:        features,
 	}, nil
 }
 
+// newAttachmentStore creates the blob store selected by the configuration
+func newAttachmentStore(cfg *config.Config) (attachment.BlobStore, error) {
+	switch cfg.AttachmentBackend {
+	case attachment.BackendS3:
+		return attachment.NewS3Store(attachment.S3Config{
+			Endpoint:        cfg.AttachmentS3Endpoint,
+			Bucket:          cfg.AttachmentS3Bucket,
+			Region:          cfg.AttachmentS3Region,
+			AccessKeyID:     cfg.AttachmentS3AccessKeyID,
+			SecretAccessKey: cfg.AttachmentS3SecretAccessKey,
+		}, nil)
+	default:
+		dir := cfg.AttachmentDir
+		if dir == "" {
+			dir = filepath.Join(filepath.Dir(cfg.DatabasePath), "attachments")
+		}
+		return attachment.NewLocalStore(dir)
+	}
+}
+
 // Run starts the daemon and blocks until ctx is cancelled
 func (d *Daemon) Run(ctx context.Context) error {
 	// Create Unix socket listener
@@ -196,6 +292,11 @@ func (d *Daemon) Run(ctx context.Context) error {
 		d.rpcServer = rpc.NewServer()
 	}
 
+	d.rpcServer.SetFeatureFlags(d.features)
+
+	// Reload feature flags from the config file on SIGHUP
+	go d.watchReload(ctx)
+
 	// Mark orphaned sessions as failed (from previous daemon run)
 	if err := d.markOrphanedSessionsAsFailed(ctx); err != nil {
 		slog.Warn("failed to mark orphaned sessions as failed", "error", err)
@@ -212,19 +313,116 @@ func (d *Daemon) Run(ctx context.Context) error {
 	}()
 	slog.Info("started dangerous skip permissions expiry monitor")
 
+	// Start session evictor if a storage cap is configured
+	if d.config.MaxStoredSessions > 0 {
+		d.sessionEvictor = session.NewSessionEvictor(d.store, d.config.MaxStoredSessions, d.config.EvictionArchiveDir, time.Minute)
+		if d.attachments != nil {
+			d.sessionEvictor.SetAttachmentPruner(d.attachments)
+		}
+		go func() {
+			d.sessionEvictor.Start(ctx)
+		}()
+	}
+
+	// Start session reaper if an inactivity timeout is configured
+	if d.config.SessionInactivityTimeout > 0 {
+		reaper := session.NewSessionReaper(d.store, d.eventBus, d.config.SessionInactivityTimeout, time.Minute)
+		go func() {
+			_ = reaper.Run(ctx)
+		}()
+	}
+
+	// Deliver session lifecycle events to registered webhooks
+	if d.eventBus != nil {
+		dispatcher := webhook.NewWebhookDispatcher(d.store, d.eventBus, nil)
+		go func() {
+			_ = dispatcher.Run(ctx)
+		}()
+	}
+
+	// Require a token on every RPC call except health
+	if d.config.RPCTokenFile != "" {
+		d.rpcServer.SetTokenProvider(rpc.NewFileTokenProvider(d.config.RPCTokenFile))
+		slog.Info("rpc authentication enabled", "token_file", d.config.RPCTokenFile)
+	}
+
+	// Record mutating RPC calls in the audit log
+	d.rpcServer.SetAuditLogger(rpc.NewAuditLogger(d.store))
+
+	// Start audit pruner if a retention period is configured
+	if d.config.AuditRetention > 0 {
+		auditPruner := rpc.NewAuditPruner(d.store, d.config.AuditRetention, time.Hour)
+		go func() {
+			auditPruner.Start(ctx)
+		}()
+	}
+
+	// Start session retention if a retention period or session cap is configured
+	var sessionRetention *rpc.SessionRetention
+	if d.config.RetentionDays > 0 || d.config.RetentionMaxSessions > 0 {
+		sessionRetention = rpc.NewSessionRetention(d.store, time.Duration(d.config.RetentionDays)*24*time.Hour,
+			d.config.RetentionMaxSessions, time.Hour)
+		if d.attachments != nil {
+			sessionRetention.SetAttachmentPruner(d.attachments)
+		}
+		go func() {
+			sessionRetention.Start(ctx)
+		}()
+	}
+
 	// Register subscription handlers
 	subscriptionHandlers := rpc.NewSubscriptionHandlers(d.eventBus)
 	d.rpcServer.SetSubscriptionHandlers(subscriptionHandlers)
 
 	// Register session handlers
-	sessionHandlers := rpc.NewSessionHandlers(d.sessions, d.store, d.approvals)
+	sessionHandlers := rpc.NewSessionHandlers(d.sessions, d.store, d.approvals, prometheus.DefaultRegisterer, slog.Default())
 	sessionHandlers.SetEventBus(d.eventBus)
+	sessionHandlers.SetFeatureFlags(d.features)
+	if d.attachments != nil {
+		sessionHandlers.SetAttachmentPruner(d.attachments)
+	}
+	sessionHandlers.SetSessionRetention(sessionRetention)
+	if d.config.TranslationEndpoint != "" {
+		provider := translate.NewHTTPProvider(d.config.TranslationEndpoint, d.config.TranslationAPIKey, nil)
+		sessionHandlers.SetTranslator(translate.New(provider, 0))
+		slog.Info("conversation translation enabled", "endpoint", d.config.TranslationEndpoint)
+	}
+	if redactor, err := redact.New(d.config.RedactionPatterns); err != nil {
+		slog.Warn("ignoring invalid redaction patterns", "error", err)
+	} else {
+		sessionHandlers.SetRedactor(redactor)
+	}
+	if len(d.config.RateLimits) > 0 {
+		limits := make(map[string]rate.Limit, len(d.config.RateLimits))
+		for method, limit := range d.config.RateLimits {
+			limits[method] = rate.Limit(limit)
+		}
+		sessionHandlers.Use(rpc.RateLimitMiddleware(limits, d.config.RateLimitBurst))
+		slog.Info("rpc rate limits enabled", "limits", d.config.RateLimits, "burst", d.config.RateLimitBurst)
+	}
 	sessionHandlers.Register(d.rpcServer)
+	d.sessionHandlers = sessionHandlers
 
 	// Register local approval handlers
 	approvalHandlers := rpc.NewApprovalHandlers(d.approvals, d.sessions)
 	approvalHandlers.Register(d.rpcServer)
 
+	// Register audit log handlers
+	auditHandlers := rpc.NewAuditHandlers(d.store)
+	auditHandlers.Register(d.rpcServer)
+
+	// Register attachment handlers
+	attachmentHandlers := rpc.NewAttachmentHandlers(d.attachments)
+	attachmentHandlers.Register(d.rpcServer)
+
+	// Register launch scheduler handlers
+	schedulerHandlers := rpc.NewSchedulerHandlers(d.launchScheduler, d.store)
+	schedulerHandlers.Register(d.rpcServer)
+
+	// Register provider status handlers
+	providerHandlers := rpc.NewProviderHandlers(d.overloadMonitor)
+	providerHandlers.Register(d.rpcServer)
+
 	// Start HTTP server if enabled
 	if d.httpServer != nil {
 		httpCtx, httpCancel := context.WithCancel(ctx)
@@ -258,6 +456,19 @@ func (d *Daemon) Run(ctx context.Context) error {
 	var wg sync.WaitGroup
 	var sessionErr, httpErr error
 
+	// Let in-flight RPC calls finish so their responses aren't dropped
+	if d.sessionHandlers != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			drainCtx, cancel := context.WithTimeout(context.Background(), getShutdownTimeout())
+			defer cancel()
+			if err := d.sessionHandlers.Shutdown(drainCtx); err != nil {
+				slog.Warn("error draining rpc requests", "error", err)
+			}
+		}()
+	}
+
 	// Start session shutdown in goroutine
 	if d.sessions != nil {
 		wg.Add(1)
@@ -304,6 +515,43 @@ func (d *Daemon) Run(ctx context.Context) error {
 	return nil
 }
 
+// watchReload reloads the hot-reloadable settings each time the daemon
+// receives SIGHUP. Other settings still need a restart.
+func (d *Daemon) watchReload(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := d.reloadConfig(); err != nil {
+				slog.Error("failed to reload config, keeping current settings", "error", err)
+			}
+		}
+	}
+}
+
+// reloadConfig reloads the feature flags from the configuration
+func (d *Daemon) reloadConfig() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if d.features != nil {
+		d.features.Update(cfg.FeatureFlags, cfg.OwnerFeatureFlags)
+	}
+	slog.Info("reloaded config",
+		"feature_flags", cfg.FeatureFlags,
+		"owner_feature_flags", cfg.OwnerFeatureFlags)
+	return nil
+}
+
 // acceptConnections handles incoming client connections
 func (d *Daemon) acceptConnections(ctx context.Context) {
 	for {
@@ -347,19 +595,20 @@ func (d *Daemon) markOrphanedSessionsAsFailed(ctx context.Context) error {
 	}
 
 	// Get all sessions from the database
-	sessions, err := d.store.ListSessions(ctx)
+	sessions, err := d.store.ListSessions(ctx, store.ListSessionsFilter{})
 	if err != nil {
 		return fmt.Errorf("failed to list sessions: %w", err)
 	}
 
 	orphanedCount := 0
 	for _, session := range sessions {
-		// Mark only truly orphaned sessions as failed (running, waiting_input, starting).
+		// Mark only truly orphaned sessions as failed (running, waiting_input, starting, queued).
 		// Sessions with status interrupting, interrupted, completed, or failed are left as-is
 		// to allow interrupted sessions to be resumed after daemon restart.
 		if session.Status == store.SessionStatusRunning ||
 			session.Status == store.SessionStatusWaitingInput ||
-			session.Status == store.SessionStatusStarting {
+			session.Status == store.SessionStatusStarting ||
+			session.Status == store.SessionStatusQueued {
 			failedStatus := store.SessionStatusFailed
 			errorMsg := "daemon restarted while session was active"
 			now := time.Now()
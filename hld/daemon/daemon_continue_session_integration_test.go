@@ -47,7 +47,7 @@ func TestIntegrationContinueSession(t *testing.T) {
 
 	// Register RPC handlers
 	// Pass nil for approval manager since this test doesn't test approval functionality
-	sessionHandlers := rpc.NewSessionHandlers(sessionManager, sqliteStore, nil)
+	sessionHandlers := rpc.NewSessionHandlers(sessionManager, sqliteStore, nil, nil, nil)
 	sessionHandlers.Register(d.rpcServer)
 
 	// Start daemon
@@ -642,7 +642,7 @@ func TestIntegrationContinueSession(t *testing.T) {
 		}
 
 		// Get all sessions to find the child
-		sessions, err := d.store.ListSessions(ctx)
+		sessions, err := d.store.ListSessions(ctx, store.ListSessionsFilter{})
 		if err != nil {
 			t.Fatalf("Failed to list sessions: %v", err)
 		}
@@ -700,7 +700,7 @@ func TestIntegrationContinueSession(t *testing.T) {
 		}
 
 		// Find the child session
-		sessions, err := d.store.ListSessions(ctx)
+		sessions, err := d.store.ListSessions(ctx, store.ListSessionsFilter{})
 		if err != nil {
 			t.Fatalf("Failed to list sessions: %v", err)
 		}
@@ -47,7 +47,7 @@ func TestDaemon_MarkOrphanedSessions(t *testing.T) {
 	}
 
 	// Expect ListSessions to be called
-	mockStore.EXPECT().ListSessions(gomock.Any()).Return(sessions, nil)
+	mockStore.EXPECT().ListSessions(gomock.Any(), gomock.Any()).Return(sessions, nil)
 
 	// Expect UpdateSession for orphaned sessions only
 	for _, sess := range sessions {
@@ -47,7 +47,7 @@ func TestIntegrationResumeDuringRunning(t *testing.T) {
 
 	// Register RPC handlers
 	// Pass nil for approval manager since this test doesn't test approval functionality
-	sessionHandlers := rpc.NewSessionHandlers(sessionManager, sqliteStore, nil)
+	sessionHandlers := rpc.NewSessionHandlers(sessionManager, sqliteStore, nil, nil, nil)
 	sessionHandlers.Register(d.rpcServer)
 
 	// Start daemon
@@ -156,7 +156,7 @@ func TestDaemonSessionStateIntegration(t *testing.T) {
 		}
 
 		// 7. Check that approval was correlated with tool call
-		conversation, err := testStore.GetConversation(ctx, claudeSessionID)
+		conversation, err := testStore.GetConversation(ctx, claudeSessionID, store.ConversationPage{})
 		if err != nil {
 			t.Fatalf("failed to get conversation: %v", err)
 		}
@@ -206,7 +206,7 @@ func TestDaemonSessionStateIntegration(t *testing.T) {
 		}
 
 		// 11. Check that approval status was updated in conversation
-		finalConversation, err := testStore.GetConversation(ctx, claudeSessionID)
+		finalConversation, err := testStore.GetConversation(ctx, claudeSessionID, store.ConversationPage{})
 		if err != nil {
 			t.Fatalf("failed to get final conversation: %v", err)
 		}
@@ -93,6 +93,7 @@ func NewHTTPServer(
 	fileHandlers := handlers.NewFileHandlers()
 	sseHandler := handlers.NewSSEHandler(eventBus)
 	proxyHandler := handlers.NewProxyHandler(sessionManager, conversationStore)
+	proxyHandler.SetConnectionPool(cfg.ProviderPoolSize, cfg.ProviderIdleTimeout)
 	configHandler := handlers.NewConfigHandler()
 	settingsHandlers := handlers.NewSettingsHandlers(conversationStore)
 	agentHandlers := handlers.NewAgentHandlers()
@@ -114,6 +115,12 @@ func NewHTTPServer(
 	}
 }
 
+// SetOverloadMonitor sets the monitor notified when proxied provider requests
+// report overload
+func (s *HTTPServer) SetOverloadMonitor(monitor *session.OverloadMonitor) {
+	s.proxyHandler.SetOverloadMonitor(monitor)
+}
+
 // Start starts the HTTP server
 func (s *HTTPServer) Start(ctx context.Context) error {
 	// Create server implementation combining all handlers
@@ -139,6 +146,8 @@ func (s *HTTPServer) Start(ctx context.Context) error {
 
 	// MCP endpoint (Phase 5: with event-driven approvals)
 	mcpServer := mcp.NewMCPServer(s.approvalManager, s.eventBus)
+	mcpServer.SetToolResultCache(s.sessionManager)
+	mcpServer.SetToolQuotaChecker(s.sessionManager)
 	mcpServer.Start(ctx) // Start background processes with context
 	v1.Any("/mcp", func(c *gin.Context) {
 		mcpServer.ServeHTTP(c.Writer, c.Request)
@@ -0,0 +1,131 @@
+// Package export renders conversations as readable transcripts.
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Event types, matching the store's
+const (
+	EventMessage    = "message"
+	EventToolCall   = "tool_call"
+	EventToolResult = "tool_result"
+	EventThinking   = "thinking"
+)
+
+// Event is one conversation event to render
+type Event struct {
+	Type            string
+	Role            string
+	Content         string
+	ToolID          string
+	ToolName        string
+	ToolInputJSON   string
+	ToolResultForID string
+	ToolResult      string
+	ToolError       string // Set when the tool failed
+}
+
+// Markdown renders events as a Markdown transcript. Messages become
+// "**Role**: content" blocks and tool calls fenced JSON labelled with the
+// tool name, each followed by its result even when other events came in
+// between.
+func Markdown(title string, events []Event) string {
+	// Results are rendered with their calls; only orphans keep their place
+	results := map[string]Event{}
+	calls := map[string]bool{}
+	for _, event := range events {
+		switch event.Type {
+		case EventToolCall:
+			if event.ToolID != "" {
+				calls[event.ToolID] = true
+			}
+		case EventToolResult:
+			if _, seen := results[event.ToolResultForID]; !seen {
+				results[event.ToolResultForID] = event
+			}
+		}
+	}
+
+	var b strings.Builder
+	if title != "" {
+		fmt.Fprintf(&b, "# %s\n\n", title)
+	}
+	for _, event := range events {
+		switch event.Type {
+		case EventMessage:
+			fmt.Fprintf(&b, "**%s**: %s\n\n", roleName(event.Role), event.Content)
+		case EventThinking:
+			b.WriteString("> **Thinking**\n>\n")
+			for _, line := range strings.Split(event.Content, "\n") {
+				fmt.Fprintf(&b, "> %s\n", line)
+			}
+			b.WriteString("\n")
+		case EventToolCall:
+			fmt.Fprintf(&b, "**Tool call: %s**\n\n", event.ToolName)
+			writeFenced(&b, "json", indentJSON(event.ToolInputJSON))
+			if result, ok := results[event.ToolID]; ok && event.ToolID != "" {
+				writeResult(&b, result)
+				delete(results, event.ToolID)
+			}
+		case EventToolResult:
+			if !calls[event.ToolResultForID] {
+				writeResult(&b, event)
+			}
+		default:
+			if event.Content != "" {
+				fmt.Fprintf(&b, "_%s_\n\n", event.Content)
+			}
+		}
+	}
+	return b.String()
+}
+
+func writeResult(b *strings.Builder, event Event) {
+	switch {
+	case event.ToolError != "":
+		fmt.Fprintf(b, "**Tool error**: %s\n\n", event.ToolError)
+	case event.ToolResult == "":
+		b.WriteString("**Tool result**: _empty_\n\n")
+	default:
+		b.WriteString("**Tool result**:\n\n")
+	}
+	if event.ToolResult != "" {
+		writeFenced(b, "", event.ToolResult)
+	}
+}
+
+// writeFenced writes content in a code fence longer than any run of
+// backticks inside it, so the content can't close the fence early
+func writeFenced(b *strings.Builder, lang, content string) {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n\n", fence, lang, content, fence)
+}
+
+// indentJSON pretty-prints valid JSON and returns anything else unchanged
+func indentJSON(raw string) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(raw), "", "  "); err != nil {
+		return raw
+	}
+	return out.String()
+}
+
+func roleName(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "":
+		return "Message"
+	default:
+		return strings.ToUpper(role[:1]) + role[1:]
+	}
+}
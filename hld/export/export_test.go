@@ -0,0 +1,47 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdown(t *testing.T) {
+	events := []Event{
+		{Type: EventMessage, Role: "user", Content: "List the files and read the README"},
+		{Type: EventThinking, Role: "assistant", Content: "Two tools.\nRun them together."},
+		{Type: EventToolCall, ToolID: "t1", ToolName: "Bash", ToolInputJSON: `{"command":"ls"}`},
+		{Type: EventToolCall, ToolID: "t2", ToolName: "Read", ToolInputJSON: `{"file_path":"README.md"}`},
+		{Type: EventToolResult, ToolResultForID: "t2", ToolResult: "# Project\n```sh\nmake\n```"},
+		{Type: EventToolResult, ToolResultForID: "t1", ToolResult: "README.md\nmain.go"},
+		{Type: EventToolCall, ToolID: "t3", ToolName: "Bash", ToolInputJSON: `not json`},
+		{Type: EventToolResult, ToolResultForID: "t3", ToolResult: "", ToolError: "exit status 1"},
+		{Type: EventToolResult, ToolResultForID: "unknown", ToolResult: "orphan"},
+		{Type: EventToolCall, ToolID: "t4", ToolName: "Write", ToolInputJSON: `{}`},
+		{Type: EventToolResult, ToolResultForID: "t4"},
+		{Type: "system", Content: "Session resumed"},
+		{Type: EventMessage, Role: "assistant", Content: "There are two files."},
+	}
+
+	want := "# Survey\n\n" +
+		"**User**: List the files and read the README\n\n" +
+		"> **Thinking**\n>\n> Two tools.\n> Run them together.\n\n" +
+		"**Tool call: Bash**\n\n```json\n{\n  \"command\": \"ls\"\n}\n```\n\n" +
+		"**Tool result**:\n\n```\nREADME.md\nmain.go\n```\n\n" +
+		"**Tool call: Read**\n\n```json\n{\n  \"file_path\": \"README.md\"\n}\n```\n\n" +
+		"**Tool result**:\n\n````\n# Project\n```sh\nmake\n```\n````\n\n" +
+		"**Tool call: Bash**\n\n```json\nnot json\n```\n\n" +
+		"**Tool error**: exit status 1\n\n" +
+		"**Tool result**:\n\n```\norphan\n```\n\n" +
+		"**Tool call: Write**\n\n```json\n{}\n```\n\n" +
+		"**Tool result**: _empty_\n\n" +
+		"_Session resumed_\n\n" +
+		"**Assistant**: There are two files.\n\n"
+
+	assert.Equal(t, want, Markdown("Survey", events))
+}
+
+func TestMarkdownEmpty(t *testing.T) {
+	assert.Equal(t, "", Markdown("", nil))
+	assert.Equal(t, "# Untouched\n\n", Markdown("Untouched", nil))
+}
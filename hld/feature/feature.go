@@ -0,0 +1,103 @@
+// Package feature gates daemon behaviors behind flags that operators can turn
+// on globally or per owner, and change without restarting the daemon.
+package feature
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Flag names
+const (
+	// Attachments enables the attachment RPC methods
+	Attachments = "attachments"
+	// ConversationExport enables exportConversation
+	ConversationExport = "conversation_export"
+	// ToolAvailabilityCheck checks allowed tools are available before a
+	// session launches
+	ToolAvailabilityCheck = "tool_availability_check"
+)
+
+// Defaults is the value of each known flag when the configuration doesn't
+// set it. Flags missing from Defaults are unknown and default to off.
+var Defaults = map[string]bool{
+	Attachments:           true,
+	ConversationExport:    true,
+	ToolAvailabilityCheck: true,
+}
+
+// Flags holds the configured flag values. A nil *Flags reports the defaults.
+type Flags struct {
+	mu     sync.RWMutex
+	global map[string]bool
+	owners map[string]map[string]bool // owner -> flag -> enabled
+}
+
+// New creates flags from global values and per-owner overrides
+func New(global map[string]bool, owners map[string]map[string]bool) *Flags {
+	f := &Flags{}
+	f.Update(global, owners)
+	return f
+}
+
+// Update replaces the configured values, such as after a config reload.
+// Names are matched case-insensitively since the config loader lowercases
+// map keys.
+func (f *Flags) Update(global map[string]bool, owners map[string]map[string]bool) {
+	lower := func(flags map[string]bool) map[string]bool {
+		out := make(map[string]bool, len(flags))
+		for name, enabled := range flags {
+			out[strings.ToLower(name)] = enabled
+		}
+		return out
+	}
+	ownersCopy := make(map[string]map[string]bool, len(owners))
+	for owner, flags := range owners {
+		ownersCopy[strings.ToLower(owner)] = lower(flags)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.global = lower(global)
+	f.owners = ownersCopy
+}
+
+// Enabled reports whether a flag is on for owner. An owner override wins over
+// the global value, which wins over the default.
+func (f *Flags) Enabled(name, owner string) bool {
+	name, owner = strings.ToLower(name), strings.ToLower(owner)
+	if f != nil {
+		f.mu.RLock()
+		defer f.mu.RUnlock()
+		if enabled, ok := f.owners[owner][name]; ok {
+			return enabled
+		}
+		if enabled, ok := f.global[name]; ok {
+			return enabled
+		}
+	}
+	return Defaults[name]
+}
+
+// Snapshot returns the value of every known or configured flag for owner
+func (f *Flags) Snapshot(owner string) map[string]bool {
+	names := slices.Collect(maps.Keys(Defaults))
+	if f != nil {
+		f.mu.RLock()
+		for name := range f.global {
+			names = append(names, name)
+		}
+		for name := range f.owners[strings.ToLower(owner)] {
+			names = append(names, name)
+		}
+		f.mu.RUnlock()
+	}
+
+	snapshot := make(map[string]bool, len(names))
+	for _, name := range names {
+		snapshot[name] = f.Enabled(name, owner)
+	}
+	return snapshot
+}
@@ -0,0 +1,73 @@
+package feature
+
+import "testing"
+
+func TestEnabled(t *testing.T) {
+	flags := New(
+		map[string]bool{"beta_ui": true, Attachments: false},
+		map[string]map[string]bool{
+			"Alice": {"beta_ui": false, Attachments: true},
+		},
+	)
+
+	tests := []struct {
+		name  string
+		flag  string
+		owner string
+		want  bool
+	}{
+		{"global on", "beta_ui", "bob", true},
+		{"owner override off", "beta_ui", "alice", false},
+		{"owner match ignores case", "BETA_UI", "ALICE", false},
+		{"global turns a default off", Attachments, "bob", false},
+		{"owner turns it back on", Attachments, "alice", true},
+		{"unset known flag uses default", ConversationExport, "bob", true},
+		{"unknown flag is off", "no_such_flag", "alice", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flags.Enabled(tt.flag, tt.owner); got != tt.want {
+				t.Errorf("Enabled(%q, %q) = %v, want %v", tt.flag, tt.owner, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilFlagsUseDefaults(t *testing.T) {
+	var flags *Flags
+	for name, want := range Defaults {
+		if got := flags.Enabled(name, "local"); got != want {
+			t.Errorf("Enabled(%q) = %v, want default %v", name, got, want)
+		}
+	}
+	if flags.Enabled("no_such_flag", "local") {
+		t.Error("unknown flag enabled")
+	}
+	if got := flags.Snapshot("local"); len(got) != len(Defaults) {
+		t.Errorf("Snapshot() = %v, want the defaults", got)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	flags := New(nil, nil)
+	if flags.Enabled("beta_ui", "local") {
+		t.Fatal("unknown flag enabled before update")
+	}
+
+	flags.Update(map[string]bool{"beta_ui": true}, nil)
+	if !flags.Enabled("beta_ui", "local") {
+		t.Error("flag not enabled after update")
+	}
+	snapshot := flags.Snapshot("local")
+	if !snapshot["beta_ui"] || !snapshot[Attachments] {
+		t.Errorf("Snapshot() = %v, want configured and default flags", snapshot)
+	}
+
+	flags.Update(nil, map[string]map[string]bool{"local": {"beta_ui": true}})
+	if flags.Enabled("beta_ui", "other") {
+		t.Error("owner flag applied to another owner")
+	}
+	if !flags.Enabled("beta_ui", "local") {
+		t.Error("owner flag not applied")
+	}
+}
@@ -29,6 +29,16 @@ type ApprovalDecision struct {
 	Comment  string
 }
 
+// ToolResultCache answers repeated calls to cacheable tools without running them
+type ToolResultCache interface {
+	CachedToolResult(ctx context.Context, sessionID, toolUseID, toolName string, input json.RawMessage) (string, bool)
+}
+
+// ToolQuotaChecker denies tool calls beyond a session's tool call quota
+type ToolQuotaChecker interface {
+	CheckToolQuota(ctx context.Context, sessionID, toolUseID, toolName string) (string, bool)
+}
+
 // MCPServer wraps the mark3labs MCP server
 type MCPServer struct {
 	mcpServer        *server.MCPServer
@@ -36,6 +46,8 @@ type MCPServer struct {
 	approvalManager  approval.Manager
 	eventBus         bus.EventBus
 	autoDenyAll      bool
+	toolCache        ToolResultCache
+	toolQuota        ToolQuotaChecker
 	pendingApprovals sync.Map // map[string]chan ApprovalDecision
 }
 
@@ -86,6 +98,18 @@ func NewMCPServer(approvalManager approval.Manager, eventBus bus.EventBus) *MCPS
 	return s
 }
 
+// SetToolResultCache sets the cache consulted before requesting approval. A
+// cache hit denies the call with the cached result so the tool isn't re-run.
+func (s *MCPServer) SetToolResultCache(cache ToolResultCache) {
+	s.toolCache = cache
+}
+
+// SetToolQuotaChecker sets the quota consulted before requesting approval. A
+// call over quota is denied with the checker's message as feedback.
+func (s *MCPServer) SetToolQuotaChecker(checker ToolQuotaChecker) {
+	s.toolQuota = checker
+}
+
 // Start initializes the MCP server's background processes
 func (s *MCPServer) Start(ctx context.Context) {
 	if s.eventBus != nil {
@@ -135,6 +159,50 @@ func (s *MCPServer) handleRequestApproval(ctx context.Context, request mcp.CallT
 		return nil, fmt.Errorf("failed to marshal input: %w", err)
 	}
 
+	// Answer from the tool result cache when possible. Claude has no way to
+	// accept a substitute result, so the call is denied with the cached result
+	// as the message.
+	if s.toolCache != nil {
+		if result, ok := s.toolCache.CachedToolResult(ctx, sessionID, toolUseID, toolName, inputJSON); ok {
+			slog.Info("Answering tool call from cache", "tool_use_id", toolUseID, "tool_name", toolName)
+
+			responseData := map[string]interface{}{
+				"behavior": "deny",
+				"message":  "Not re-executed: an identical " + toolName + " call ran recently. Cached result:\n\n" + result,
+			}
+			responseJSON, _ := json.Marshal(responseData)
+
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: string(responseJSON),
+					},
+				},
+			}, nil
+		}
+	}
+
+	// Calls over the session's quota are denied before anyone is asked
+	if s.toolQuota != nil {
+		if message, denied := s.toolQuota.CheckToolQuota(ctx, sessionID, toolUseID, toolName); denied {
+			responseData := map[string]interface{}{
+				"behavior": "deny",
+				"message":  message,
+			}
+			responseJSON, _ := json.Marshal(responseData)
+
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: string(responseJSON),
+					},
+				},
+			}, nil
+		}
+	}
+
 	// Create approval with tool_use_id
 	approval, err := s.approvalManager.CreateApprovalWithToolUseID(ctx, sessionID, toolName, inputJSON, toolUseID)
 	if err != nil {
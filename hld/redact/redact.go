@@ -0,0 +1,103 @@
+// Package redact replaces secrets and personal data in text with placeholders.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Pattern is a named regular expression whose matches are redacted
+type Pattern struct {
+	Name string
+	Re   *regexp.Regexp
+	// Group, if non-zero, redacts only that capture group, keeping the rest
+	// of the match (such as a "password=" prefix) readable
+	Group int
+}
+
+// SecretPatterns match credentials that should never leave the machine
+var SecretPatterns = []Pattern{
+	{Name: "private_key", Re: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{Name: "aws_access_key", Re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "github_token", Re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{Name: "anthropic_key", Re: regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{Name: "openai_key", Re: regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9]{32,}\b`)},
+	{Name: "slack_token", Re: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{Name: "jwt", Re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{Name: "bearer_token", Re: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{16,}=*)`), Group: 1},
+	{Name: "secret_assignment", Re: regexp.MustCompile(`(?i)\b(?:password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token)\b["']?\s*[:=]\s*["']?([^\s"',;\[][^\s"',;]{5,})`), Group: 1},
+}
+
+// PIIPatterns match personal data, redacted only when asked for
+var PIIPatterns = []Pattern{
+	{Name: "email", Re: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{Name: "phone", Re: regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?\(?\b\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}\b`)},
+	{Name: "ssn", Re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{Name: "credit_card", Re: regexp.MustCompile(`\b(?:\d{4}[ -]?){3}\d{4}\b`)},
+	{Name: "ipv4", Re: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)},
+}
+
+// Redactor applies a set of patterns to text
+type Redactor struct {
+	secrets []Pattern
+	pii     []Pattern
+}
+
+// New creates a redactor using the built-in patterns plus custom regular
+// expressions, which are treated as secrets
+func New(custom []string) (*Redactor, error) {
+	r := &Redactor{
+		secrets: append([]Pattern(nil), SecretPatterns...),
+		pii:     PIIPatterns,
+	}
+	for i, expr := range custom {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", expr, err)
+		}
+		r.secrets = append(r.secrets, Pattern{Name: fmt.Sprintf("custom_%d", i+1), Re: re})
+	}
+	return r, nil
+}
+
+// Redact replaces secrets in text, and personal data too if pii is set. It
+// returns the redacted text and the number of replacements made.
+func (r *Redactor) Redact(text string, pii bool) (string, int) {
+	if text == "" {
+		return text, 0
+	}
+	count := 0
+	for _, p := range r.secrets {
+		text = apply(p, text, &count)
+	}
+	if pii {
+		for _, p := range r.pii {
+			text = apply(p, text, &count)
+		}
+	}
+	return text, count
+}
+
+// apply replaces each match of p, or of its group, with a placeholder
+func apply(p Pattern, text string, count *int) string {
+	placeholder := "[REDACTED:" + p.Name + "]"
+	matches := p.Re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	out := make([]byte, 0, len(text))
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if p.Group > 0 && len(m) > 2*p.Group+1 && m[2*p.Group] >= 0 {
+			start, end = m[2*p.Group], m[2*p.Group+1]
+		}
+		out = append(out, text[last:start]...)
+		out = append(out, placeholder...)
+		last = end
+		*count++
+	}
+	out = append(out, text[last:]...)
+	return string(out)
+}
@@ -0,0 +1,127 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// Annotation is a reviewer's note on a conversation event
+type Annotation struct {
+	ID        int64  `json:"id"`
+	EventID   int64  `json:"event_id"`
+	Author    string `json:"author"`
+	Note      string `json:"note"`
+	CreatedAt string `json:"created_at"`
+}
+
+// AddAnnotationRequest is the request for annotating a conversation event
+type AddAnnotationRequest struct {
+	EventID int64  `json:"event_id"`
+	Author  string `json:"author"`
+	Note    string `json:"note"`
+}
+
+// AddAnnotationResponse is the response for annotating a conversation event
+type AddAnnotationResponse struct {
+	Annotation Annotation `json:"annotation"`
+}
+
+// ListAnnotationsRequest is the request for a session's annotations
+type ListAnnotationsRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// ListAnnotationsResponse holds a session's annotations, oldest first
+type ListAnnotationsResponse struct {
+	SessionID   string       `json:"session_id"`
+	Annotations []Annotation `json:"annotations"`
+}
+
+// HandleAddAnnotation attaches a note to a conversation event
+func (h *SessionHandlers) HandleAddAnnotation(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req AddAnnotationRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.EventID == 0 {
+		return nil, fmt.Errorf("%w: event_id is required", ErrInvalidRequest)
+	}
+	if strings.TrimSpace(req.Author) == "" {
+		return nil, fmt.Errorf("%w: author is required", ErrInvalidRequest)
+	}
+	if strings.TrimSpace(req.Note) == "" {
+		return nil, fmt.Errorf("%w: note is required", ErrInvalidRequest)
+	}
+
+	annotation, err := h.store.AddAnnotation(ctx, req.EventID, req.Author, req.Note)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add annotation: %w", err)
+	}
+	return &AddAnnotationResponse{Annotation: annotationToRPC(annotation)}, nil
+}
+
+// HandleListAnnotations returns the annotations on a session's events
+func (h *SessionHandlers) HandleListAnnotations(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req ListAnnotationsRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+
+	// Tell an unannotated session apart from one that doesn't exist
+	if _, err := h.store.GetSession(ctx, req.SessionID); err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	annotations, err := h.store.GetAnnotationsForSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotations: %w", err)
+	}
+
+	resp := &ListAnnotationsResponse{SessionID: req.SessionID, Annotations: make([]Annotation, len(annotations))}
+	for i, annotation := range annotations {
+		resp.Annotations[i] = annotationToRPC(annotation)
+	}
+	return resp, nil
+}
+
+// eventAnnotations groups the annotations on events by event ID. Events may
+// come from several sessions of a parent chain, so each one is looked up.
+func (h *SessionHandlers) eventAnnotations(ctx context.Context, events []*store.ConversationEvent) (map[int64][]Annotation, error) {
+	byEvent := map[int64][]Annotation{}
+	seen := map[string]bool{}
+	for _, event := range events {
+		if seen[event.SessionID] {
+			continue
+		}
+		seen[event.SessionID] = true
+
+		annotations, err := h.store.GetAnnotationsForSession(ctx, event.SessionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get annotations: %w", err)
+		}
+		for _, annotation := range annotations {
+			byEvent[annotation.EventID] = append(byEvent[annotation.EventID], annotationToRPC(annotation))
+		}
+	}
+	return byEvent, nil
+}
+
+// annotationToRPC converts a store annotation to its RPC representation
+func annotationToRPC(a *store.Annotation) Annotation {
+	return Annotation{
+		ID:        a.ID,
+		EventID:   a.EventID,
+		Author:    a.Author,
+		Note:      a.Note,
+		CreatedAt: a.CreatedAt.Format(time.RFC3339),
+	}
+}
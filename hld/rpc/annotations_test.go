@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleAnnotations(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID: "sess-1", RunID: "run-sess-1", ClaudeSessionID: "claude-1", Status: store.SessionStatusCompleted,
+		CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+	var events []*store.ConversationEvent
+	for _, content := range []string{"first", "second"} {
+		event := &store.ConversationEvent{
+			SessionID: "sess-1", ClaudeSessionID: "claude-1",
+			EventType: store.EventTypeMessage, Role: "assistant", Content: content,
+		}
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, event))
+		events = append(events, event)
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	result, err := handlers.HandleAddAnnotation(ctx, json.RawMessage(
+		fmt.Sprintf(`{"event_id":%d,"author":"sam","note":"Check this"}`, events[1].ID)))
+	require.NoError(t, err)
+	added := result.(*AddAnnotationResponse).Annotation
+	assert.Equal(t, events[1].ID, added.EventID)
+	assert.Equal(t, "sam", added.Author)
+	assert.Equal(t, "Check this", added.Note)
+
+	result, err = handlers.HandleListAnnotations(ctx, json.RawMessage(`{"session_id":"sess-1"}`))
+	require.NoError(t, err)
+	assert.Equal(t, []Annotation{added}, result.(*ListAnnotationsResponse).Annotations)
+
+	result, err = handlers.HandleGetConversation(ctx, json.RawMessage(`{"session_id":"sess-1"}`))
+	require.NoError(t, err)
+	conversation := result.(*GetConversationResponse).Events
+	require.Len(t, conversation, 2)
+	assert.Empty(t, conversation[0].Annotations)
+	assert.Equal(t, []Annotation{added}, conversation[1].Annotations)
+
+	t.Run("errors", func(t *testing.T) {
+		var notFound *store.NotFoundError
+		_, err := handlers.HandleAddAnnotation(ctx, json.RawMessage(`{"event_id":9999,"author":"sam","note":"x"}`))
+		assert.ErrorAs(t, err, &notFound)
+		_, err = handlers.HandleListAnnotations(ctx, json.RawMessage(`{"session_id":"sess-unknown"}`))
+		assert.ErrorAs(t, err, &notFound)
+
+		for params, want := range map[string]string{
+			`{"author":"sam","note":"x"}`:              "invalid request: event_id is required",
+			`{"event_id":1,"note":"x"}`:                "invalid request: author is required",
+			`{"event_id":1,"author":"sam","note":" "}`: "invalid request: note is required",
+		} {
+			_, err := handlers.HandleAddAnnotation(ctx, json.RawMessage(params))
+			assert.EqualError(t, err, want, params)
+		}
+		_, err = handlers.HandleListAnnotations(ctx, json.RawMessage(`{}`))
+		assert.EqualError(t, err, "invalid request: session_id is required")
+	})
+}
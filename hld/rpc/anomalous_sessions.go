@@ -0,0 +1,247 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// Outlier detection methods
+const (
+	AnomalyMethodStdDev     = "stddev"     // Threshold is a number of standard deviations above the mean
+	AnomalyMethodPercentile = "percentile" // Threshold is a percentile, such as 95
+)
+
+// Metrics checked for outliers
+const (
+	AnomalyMetricCost      = "cost_usd"
+	AnomalyMetricDuration  = "duration_ms"
+	AnomalyMetricTurns     = "turns"
+	AnomalyMetricToolCalls = "tool_calls"
+)
+
+const (
+	defaultAnomalyStdDevs     = 3.0
+	defaultAnomalyPercentile  = 95.0
+	defaultAnomalyMinSessions = 10
+)
+
+// GetAnomalousSessionsRequest selects the sessions to compare and how far
+// from the rest a session must be to be flagged
+type GetAnomalousSessionsRequest struct {
+	Since  string `json:"since,omitempty"` // RFC3339; sessions created before it are left out
+	Status string `json:"status,omitempty"`
+	Model  string `json:"model,omitempty"`
+	Owner  string `json:"owner,omitempty"`
+
+	Method    string  `json:"method,omitempty"`    // AnomalyMethodStdDev (default) or AnomalyMethodPercentile
+	Threshold float64 `json:"threshold,omitempty"` // Defaults to 3 standard deviations or the 95th percentile
+	// MinSessions is the fewest sessions reporting a metric for it to be
+	// checked, since small populations flag noise (default 10)
+	MinSessions int `json:"min_sessions,omitempty"`
+}
+
+// AnomalyMetricSummary describes one metric across the compared sessions
+type AnomalyMetricSummary struct {
+	Count  int     `json:"count"` // Sessions reporting the metric
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	Cutoff float64 `json:"cutoff"`           // Values above this are flagged
+	Reason string  `json:"reason,omitempty"` // Why the metric wasn't checked
+}
+
+// AnomalyFlag is one metric that made a session an outlier
+type AnomalyFlag struct {
+	Metric string  `json:"metric"`
+	Value  float64 `json:"value"`
+	Cutoff float64 `json:"cutoff"`
+	ZScore float64 `json:"z_score"` // Standard deviations above the mean
+}
+
+// AnomalousSession is a session flagged on at least one metric
+type AnomalousSession struct {
+	SessionID string        `json:"session_id"`
+	Status    string        `json:"status"`
+	Model     string        `json:"model,omitempty"`
+	CreatedAt string        `json:"created_at"`
+	Flags     []AnomalyFlag `json:"flags"`
+}
+
+// GetAnomalousSessionsResponse lists the outliers, most flagged first
+type GetAnomalousSessionsResponse struct {
+	Sessions  int                             `json:"sessions"` // Sessions compared
+	Method    string                          `json:"method"`
+	Threshold float64                         `json:"threshold"`
+	Metrics   map[string]AnomalyMetricSummary `json:"metrics"`
+	Outliers  []AnomalousSession              `json:"outliers"`
+}
+
+// HandleGetAnomalousSessions flags sessions that are unusually expensive,
+// long or busy compared to the rest of the selected sessions. Only high
+// values are flagged; unusually cheap or short sessions aren't a concern.
+func (h *SessionHandlers) HandleGetAnomalousSessions(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetAnomalousSessionsRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+	}
+
+	filter := store.SessionUsageFilter{Status: req.Status, Model: req.Model, Owner: req.Owner}
+	if req.Since != "" {
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid since: %w", ErrInvalidRequest, err)
+		}
+		filter.Since = since
+	}
+	switch req.Method {
+	case "", AnomalyMethodStdDev:
+		req.Method = AnomalyMethodStdDev
+		if req.Threshold == 0 {
+			req.Threshold = defaultAnomalyStdDevs
+		}
+		if req.Threshold < 0 {
+			return nil, fmt.Errorf("%w: threshold must be positive", ErrInvalidRequest)
+		}
+	case AnomalyMethodPercentile:
+		if req.Threshold == 0 {
+			req.Threshold = defaultAnomalyPercentile
+		}
+		if req.Threshold <= 0 || req.Threshold >= 100 {
+			return nil, fmt.Errorf("%w: percentile threshold must be between 0 and 100", ErrInvalidRequest)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown method %q (must be %s or %s)", ErrInvalidRequest, req.Method, AnomalyMethodStdDev, AnomalyMethodPercentile)
+	}
+	if req.MinSessions <= 0 {
+		req.MinSessions = defaultAnomalyMinSessions
+	}
+
+	usage, err := h.store.GetSessionUsage(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session usage: %w", err)
+	}
+
+	metrics := []struct {
+		name  string
+		value func(u *store.SessionUsage) (float64, bool)
+	}{
+		{AnomalyMetricCost, func(u *store.SessionUsage) (float64, bool) {
+			if u.CostUSD == nil {
+				return 0, false
+			}
+			return *u.CostUSD, true
+		}},
+		{AnomalyMetricDuration, func(u *store.SessionUsage) (float64, bool) {
+			if u.DurationMS == nil {
+				return 0, false
+			}
+			return float64(*u.DurationMS), true
+		}},
+		{AnomalyMetricTurns, func(u *store.SessionUsage) (float64, bool) {
+			if u.Turns == nil {
+				return 0, false
+			}
+			return float64(*u.Turns), true
+		}},
+		{AnomalyMetricToolCalls, func(u *store.SessionUsage) (float64, bool) {
+			return float64(u.ToolCalls), true
+		}},
+	}
+
+	resp := &GetAnomalousSessionsResponse{
+		Sessions:  len(usage),
+		Method:    req.Method,
+		Threshold: req.Threshold,
+		Metrics:   make(map[string]AnomalyMetricSummary, len(metrics)),
+		Outliers:  []AnomalousSession{},
+	}
+	flags := make(map[string][]AnomalyFlag)
+	for _, metric := range metrics {
+		var values []float64
+		for _, u := range usage {
+			if v, ok := metric.value(u); ok {
+				values = append(values, v)
+			}
+		}
+		summary := summarizeForAnomalies(values, req.Method, req.Threshold)
+		if summary.Count < req.MinSessions {
+			summary.Reason = fmt.Sprintf("too few sessions (%d, need %d)", summary.Count, req.MinSessions)
+		}
+		resp.Metrics[metric.name] = summary
+		if summary.Reason != "" {
+			continue
+		}
+
+		for _, u := range usage {
+			v, ok := metric.value(u)
+			if !ok || v <= summary.Cutoff {
+				continue
+			}
+			var z float64
+			if summary.StdDev > 0 {
+				z = (v - summary.Mean) / summary.StdDev
+			}
+			flags[u.SessionID] = append(flags[u.SessionID], AnomalyFlag{
+				Metric: metric.name,
+				Value:  v,
+				Cutoff: summary.Cutoff,
+				ZScore: z,
+			})
+		}
+	}
+
+	for _, u := range usage {
+		if len(flags[u.SessionID]) == 0 {
+			continue
+		}
+		resp.Outliers = append(resp.Outliers, AnomalousSession{
+			SessionID: u.SessionID,
+			Status:    u.Status,
+			Model:     u.Model,
+			CreatedAt: u.CreatedAt.Format(time.RFC3339),
+			Flags:     flags[u.SessionID],
+		})
+	}
+	sort.SliceStable(resp.Outliers, func(i, j int) bool {
+		return len(resp.Outliers[i].Flags) > len(resp.Outliers[j].Flags)
+	})
+	return resp, nil
+}
+
+// summarizeForAnomalies computes the mean, population standard deviation and
+// the cutoff above which values are outliers
+func summarizeForAnomalies(values []float64, method string, threshold float64) AnomalyMetricSummary {
+	summary := AnomalyMetricSummary{Count: len(values)}
+	if len(values) == 0 {
+		return summary
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	summary.Mean = sum / float64(len(values))
+	var sq float64
+	for _, v := range values {
+		sq += (v - summary.Mean) * (v - summary.Mean)
+	}
+	summary.StdDev = math.Sqrt(sq / float64(len(values)))
+
+	switch method {
+	case AnomalyMethodPercentile:
+		sorted := append([]float64(nil), values...)
+		sort.Float64s(sorted)
+		// Nearest rank, as in summarizeLatency
+		rank := int(math.Ceil(threshold / 100 * float64(len(sorted))))
+		summary.Cutoff = sorted[max(rank, 1)-1]
+	default:
+		summary.Cutoff = summary.Mean + threshold*summary.StdDev
+	}
+	return summary
+}
@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetAnomalousSessions(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	// Nine ordinary sessions and one outlier on each of cost, duration and
+	// tool calls. Only a few sessions report turns.
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 12; i++ {
+		id := fmt.Sprintf("sess-%02d", i)
+		cost, duration, toolCalls := 1.0, 1000, 2
+		switch id {
+		case "sess-03":
+			cost = 50
+		case "sess-05":
+			duration = 100000
+		case "sess-07":
+			toolCalls = 40
+		}
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID:             id,
+			RunID:          "run-" + id,
+			Status:         store.SessionStatusCompleted,
+			Model:          "sonnet",
+			CreatedAt:      base.Add(time.Duration(i) * time.Minute),
+			LastActivityAt: base,
+		}))
+		update := store.SessionUpdate{CostUSD: &cost, DurationMS: &duration}
+		if i < 3 {
+			turns := 5
+			update.NumTurns = &turns
+		}
+		require.NoError(t, sqliteStore.UpdateSession(ctx, id, update))
+		for j := 0; j < toolCalls; j++ {
+			require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+				SessionID: id,
+				EventType: store.EventTypeToolCall,
+				ToolID:    fmt.Sprintf("%s-tool-%d", id, j),
+				ToolName:  "Bash",
+			}))
+		}
+	}
+
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+	get := func(t *testing.T, params string) *GetAnomalousSessionsResponse {
+		result, err := handlers.HandleGetAnomalousSessions(ctx, json.RawMessage(params))
+		require.NoError(t, err)
+		return result.(*GetAnomalousSessionsResponse)
+	}
+	flagged := func(resp *GetAnomalousSessionsResponse) map[string]string {
+		out := make(map[string]string)
+		for _, outlier := range resp.Outliers {
+			require.Len(t, outlier.Flags, 1)
+			out[outlier.SessionID] = outlier.Flags[0].Metric
+		}
+		return out
+	}
+
+	t.Run("standard deviations", func(t *testing.T) {
+		resp := get(t, `{}`)
+		assert.Equal(t, 12, resp.Sessions)
+		assert.Equal(t, AnomalyMethodStdDev, resp.Method)
+		assert.Equal(t, 3.0, resp.Threshold)
+		assert.Equal(t, map[string]string{
+			"sess-03": AnomalyMetricCost,
+			"sess-05": AnomalyMetricDuration,
+			"sess-07": AnomalyMetricToolCalls,
+		}, flagged(resp))
+		assert.Greater(t, resp.Outliers[0].Flags[0].ZScore, 3.0)
+
+		turns := resp.Metrics[AnomalyMetricTurns]
+		assert.Equal(t, 3, turns.Count)
+		assert.Contains(t, turns.Reason, "too few sessions")
+	})
+
+	t.Run("percentile", func(t *testing.T) {
+		resp := get(t, `{"method":"percentile","threshold":90}`)
+		assert.Len(t, flagged(resp), 3)
+		assert.Equal(t, 1.0, resp.Metrics[AnomalyMetricCost].Cutoff)
+	})
+
+	t.Run("higher threshold", func(t *testing.T) {
+		assert.Empty(t, get(t, `{"threshold":3.5}`).Outliers)
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		resp := get(t, fmt.Sprintf(`{"since":%q}`, base.Add(4*time.Minute).Format(time.RFC3339)))
+		assert.Equal(t, 8, resp.Sessions)
+		assert.Empty(t, resp.Outliers, "too few sessions left")
+
+		resp = get(t, fmt.Sprintf(`{"since":%q,"min_sessions":5,"threshold":2}`, base.Add(4*time.Minute).Format(time.RFC3339)))
+		assert.Equal(t, map[string]string{
+			"sess-05": AnomalyMetricDuration,
+			"sess-07": AnomalyMetricToolCalls,
+		}, flagged(resp))
+
+		assert.Zero(t, get(t, `{"model":"opus"}`).Sessions)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for _, params := range []string{
+			`{"method":"iqr"}`,
+			`{"method":"percentile","threshold":100}`,
+			`{"threshold":-1}`,
+			`{"since":"last week"}`,
+		} {
+			_, err := handlers.HandleGetAnomalousSessions(ctx, json.RawMessage(params))
+			assert.Error(t, err, params)
+		}
+	})
+}
@@ -41,18 +41,18 @@ type CreateApprovalResponse struct {
 func (h *ApprovalHandlers) HandleCreateApproval(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req CreateApprovalRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	// Validate required fields
 	if req.RunID == "" {
-		return nil, fmt.Errorf("run_id is required")
+		return nil, fmt.Errorf("%w: run_id is required", ErrInvalidRequest)
 	}
 	if req.ToolName == "" {
-		return nil, fmt.Errorf("tool_name is required")
+		return nil, fmt.Errorf("%w: tool_name is required", ErrInvalidRequest)
 	}
 	if req.ToolInput == nil {
-		return nil, fmt.Errorf("tool_input is required")
+		return nil, fmt.Errorf("%w: tool_input is required", ErrInvalidRequest)
 	}
 
 	// Create approval with or without tool use ID
@@ -93,7 +93,7 @@ func (h *ApprovalHandlers) HandleFetchApprovals(ctx context.Context, params json
 	var req FetchApprovalsRequest
 	if params != nil {
 		if err := json.Unmarshal(params, &req); err != nil {
-			return nil, fmt.Errorf("invalid request: %w", err)
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 		}
 	}
 
@@ -132,17 +132,20 @@ type SendDecisionResponse struct {
 func (h *ApprovalHandlers) HandleSendDecision(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req SendDecisionRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	// Validate required fields
 	if req.ApprovalID == "" {
-		return nil, fmt.Errorf("approval_id is required")
+		return nil, fmt.Errorf("%w: approval_id is required", ErrInvalidRequest)
 	}
 	if req.Decision == "" {
-		return nil, fmt.Errorf("decision is required")
+		return nil, fmt.Errorf("%w: decision is required", ErrInvalidRequest)
 	}
 
+	// Record the caller as the approver
+	ctx = approval.WithApprover(ctx, IdentityFromContext(ctx))
+
 	var err error
 
 	switch req.Decision {
@@ -150,11 +153,11 @@ func (h *ApprovalHandlers) HandleSendDecision(ctx context.Context, params json.R
 		err = h.approvals.ApproveToolCall(ctx, req.ApprovalID, req.Comment)
 	case "deny":
 		if req.Comment == "" {
-			return nil, fmt.Errorf("comment is required for denial")
+			return nil, fmt.Errorf("%w: comment is required for denial", ErrInvalidRequest)
 		}
 		err = h.approvals.DenyToolCall(ctx, req.ApprovalID, req.Comment)
 	default:
-		return nil, fmt.Errorf("invalid decision: %s (must be 'approve' or 'deny')", req.Decision)
+		return nil, fmt.Errorf("%w: invalid decision: %s (must be 'approve' or 'deny')", ErrInvalidRequest, req.Decision)
 	}
 
 	if err != nil {
@@ -183,12 +186,12 @@ type GetApprovalResponse struct {
 func (h *ApprovalHandlers) HandleGetApproval(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req GetApprovalRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	// Validate required fields
 	if req.ApprovalID == "" {
-		return nil, fmt.Errorf("approval_id is required")
+		return nil, fmt.Errorf("%w: approval_id is required", ErrInvalidRequest)
 	}
 
 	// Get the approval
@@ -204,8 +207,8 @@ func (h *ApprovalHandlers) HandleGetApproval(ctx context.Context, params json.Ra
 
 // Register registers all local approval handlers with the RPC server
 func (h *ApprovalHandlers) Register(server *Server) {
-	server.Register("createApproval", h.HandleCreateApproval)
+	server.RegisterMutating("createApproval", h.HandleCreateApproval)
 	server.Register("fetchApprovals", h.HandleFetchApprovals)
 	server.Register("getApproval", h.HandleGetApproval)
-	server.Register("sendDecision", h.HandleSendDecision)
+	server.RegisterMutating("sendDecision", h.HandleSendDecision)
 }
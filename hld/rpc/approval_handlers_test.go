@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/approval"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSendDecisionRecordsApprover(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID: "sess-1", RunID: "run-1", ClaudeSessionID: "claude-1", Query: "Clean up",
+		Status: store.SessionStatusRunning, CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+	require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+		SessionID: "sess-1", ClaudeSessionID: "claude-1", EventType: store.EventTypeToolCall,
+		ToolID: "toolu_1", ToolName: "Bash", ToolInputJSON: `{"command":"rm -rf build"}`,
+	}))
+
+	approvals := approval.NewManager(sqliteStore, nil)
+	pending, err := approvals.CreateApprovalWithToolUseID(ctx, "sess-1", "Bash", json.RawMessage(`{"command":"rm -rf build"}`), "toolu_1")
+	require.NoError(t, err)
+	handlers := NewApprovalHandlers(approvals, nil)
+
+	callerCtx := WithIdentity(ctx, "reviewer")
+	result, err := handlers.HandleSendDecision(callerCtx, json.RawMessage(`{"approval_id":"`+pending.ID+`","decision":"deny","comment":"Not the build dir"}`))
+	require.NoError(t, err)
+	require.True(t, result.(*SendDecisionResponse).Success)
+
+	denied, err := sqliteStore.GetApproval(ctx, pending.ID)
+	require.NoError(t, err)
+	assert.Equal(t, store.ApprovalStatusLocalDenied, denied.Status)
+	assert.Equal(t, "Not the build dir", denied.Comment)
+	assert.Equal(t, "reviewer", denied.RespondedBy)
+
+	// The tool call is marked denied and never gets a result
+	events, err := sqliteStore.GetConversation(ctx, "claude-1", store.ConversationPage{})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, store.ApprovalStatusDenied, events[0].ApprovalStatus)
+	assert.False(t, events[0].IsCompleted)
+
+	// A second decision is refused and leaves the first one alone
+	result, err = handlers.HandleSendDecision(callerCtx, json.RawMessage(`{"approval_id":"`+pending.ID+`","decision":"approve"}`))
+	require.NoError(t, err)
+	assert.False(t, result.(*SendDecisionResponse).Success)
+	denied, err = sqliteStore.GetApproval(ctx, pending.ID)
+	require.NoError(t, err)
+	assert.Equal(t, store.ApprovalStatusLocalDenied, denied.Status)
+}
@@ -0,0 +1,186 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/attachment"
+	"github.com/humanlayer/humanlayer/hld/feature"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// attachmentChunkSize is the number of content bytes per getAttachment frame
+const attachmentChunkSize = 256 * 1024
+
+// AttachmentHandlers provides RPC handlers for session attachments
+type AttachmentHandlers struct {
+	attachments *attachment.Service
+}
+
+// NewAttachmentHandlers creates new attachment RPC handlers
+func NewAttachmentHandlers(attachments *attachment.Service) *AttachmentHandlers {
+	return &AttachmentHandlers{attachments: attachments}
+}
+
+// Attachment is the RPC representation of an attachment reference
+type Attachment struct {
+	ID          string `json:"id"`
+	SessionID   string `json:"session_id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type,omitempty"`
+	SizeBytes   int64  `json:"size_bytes"`
+	SHA256      string `json:"sha256"`
+	Backend     string `json:"backend"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// AddAttachmentRequest is the request for attaching a file to a session
+type AddAttachmentRequest struct {
+	SessionID   string `json:"session_id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type,omitempty"`
+	Data        string `json:"data"` // Base64 encoded content
+}
+
+// AddAttachmentResponse is the response for attaching a file to a session
+type AddAttachmentResponse struct {
+	Attachment Attachment `json:"attachment"`
+}
+
+// GetAttachmentRequest is the request for reading an attachment
+type GetAttachmentRequest struct {
+	ID string `json:"id"`
+}
+
+// AttachmentFrame is one frame of a getAttachment stream. The first frame
+// has type "attachment" and carries the reference; the content follows in
+// "attachment_data" frames, the last of which is marked final.
+type AttachmentFrame struct {
+	Type       string      `json:"type"`
+	Attachment *Attachment `json:"attachment,omitempty"`
+	Data       string      `json:"data,omitempty"` // Base64 encoded content
+	Final      bool        `json:"final,omitempty"`
+}
+
+// HandleAddAttachment stores a file as an attachment of a session
+func (h *AttachmentHandlers) HandleAddAttachment(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req AddAttachmentRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidRequest)
+	}
+	data, err := base64.StdEncoding.DecodeString(req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid data: %w", ErrInvalidRequest, err)
+	}
+
+	stored, err := h.attachments.Add(ctx, req.SessionID, req.Name, req.ContentType, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to add attachment: %w", err)
+	}
+	return &AddAttachmentResponse{Attachment: attachmentToRPC(stored)}, nil
+}
+
+// GetAttachmentConn streams an attachment's content from the backend that
+// holds it, so large files are never buffered whole
+func (h *AttachmentHandlers) GetAttachmentConn(ctx context.Context, conn net.Conn, params json.RawMessage) error {
+	var req GetAttachmentRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return sendJSONResponse(conn, &Response{
+				JSONRPC: "2.0",
+				Error:   &Error{Code: InvalidParams, Message: fmt.Sprintf("invalid request: %v", err)},
+			})
+		}
+	}
+	if req.ID == "" {
+		return sendJSONResponse(conn, &Response{
+			JSONRPC: "2.0",
+			Error:   &Error{Code: InvalidParams, Message: "id is required"},
+		})
+	}
+
+	stored, content, err := h.attachments.Open(ctx, req.ID)
+	if err != nil {
+		code := InternalError
+		var notFound *store.NotFoundError
+		if errors.As(err, &notFound) {
+			code = InvalidParams
+		}
+		return sendJSONResponse(conn, &Response{
+			JSONRPC: "2.0",
+			Error:   &Error{Code: code, Message: fmt.Sprintf("failed to get attachment: %v", err)},
+		})
+	}
+	defer func() { _ = content.Close() }()
+
+	ref := attachmentToRPC(stored)
+	if err := sendJSONResponse(conn, &Response{
+		JSONRPC: "2.0",
+		Result:  &AttachmentFrame{Type: "attachment", Attachment: &ref},
+	}); err != nil {
+		return fmt.Errorf("failed to send attachment: %w", err)
+	}
+
+	buf := make([]byte, attachmentChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, readErr := io.ReadFull(content, buf)
+		final := readErr == io.EOF || readErr == io.ErrUnexpectedEOF
+		if readErr != nil && !final {
+			return sendJSONResponse(conn, &Response{
+				JSONRPC: "2.0",
+				Error:   &Error{Code: InternalError, Message: fmt.Sprintf("failed to read attachment: %v", readErr)},
+			})
+		}
+		if err := sendJSONResponse(conn, &Response{
+			JSONRPC: "2.0",
+			Result: &AttachmentFrame{
+				Type:  "attachment_data",
+				Data:  base64.StdEncoding.EncodeToString(buf[:n]),
+				Final: final,
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to send attachment data: %w", err)
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// attachmentToRPC converts a store attachment to its RPC representation
+func attachmentToRPC(a *store.Attachment) Attachment {
+	return Attachment{
+		ID:          a.ID,
+		SessionID:   a.SessionID,
+		Name:        a.Name,
+		ContentType: a.ContentType,
+		SizeBytes:   a.SizeBytes,
+		SHA256:      a.SHA256,
+		Backend:     a.Backend,
+		CreatedAt:   a.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// Register registers all attachment handlers with the RPC server
+func (h *AttachmentHandlers) Register(server *Server) {
+	server.RegisterMutating("addAttachment", h.HandleAddAttachment)
+	server.RegisterConnHandler("getAttachment", h.GetAttachmentConn)
+	server.GateMethod("addAttachment", feature.Attachments)
+	server.GateMethod("getAttachment", feature.Attachments)
+}
@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/attachment"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachmentHandlers(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID:             "sess-1",
+		RunID:          "run-1",
+		Status:         store.SessionStatusCompleted,
+		CreatedAt:      time.Now(),
+		LastActivityAt: time.Now(),
+	}))
+
+	blobs, err := attachment.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	handlers := NewAttachmentHandlers(attachment.NewService(blobs, sqliteStore))
+
+	// readFrames collects a getAttachment stream up to its final frame or error
+	readFrames := func(t *testing.T, params string) ([]AttachmentFrame, *Error) {
+		server, client := net.Pipe()
+		defer func() { _ = client.Close() }()
+		done := make(chan error, 1)
+		go func() {
+			done <- handlers.GetAttachmentConn(ctx, server, json.RawMessage(params))
+			_ = server.Close()
+		}()
+
+		reader := bufio.NewReader(client)
+		var frames []AttachmentFrame
+		for {
+			_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+			line, err := reader.ReadBytes('\n')
+			require.NoError(t, err)
+			var resp struct {
+				Result AttachmentFrame `json:"result"`
+				Error  *Error          `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(line, &resp))
+			if resp.Error != nil {
+				require.NoError(t, <-done)
+				return frames, resp.Error
+			}
+			frames = append(frames, resp.Result)
+			if resp.Result.Final {
+				require.NoError(t, <-done)
+				return frames, nil
+			}
+		}
+	}
+
+	t.Run("adds and streams an attachment", func(t *testing.T) {
+		content := bytes.Repeat([]byte{0, 1, 2, 0xff}, attachmentChunkSize/4+10)
+		result, err := handlers.HandleAddAttachment(ctx, json.RawMessage(fmt.Sprintf(
+			`{"session_id":"sess-1","name":"dump.bin","content_type":"application/octet-stream","data":%q}`,
+			base64.StdEncoding.EncodeToString(content))))
+		require.NoError(t, err)
+		added := result.(*AddAttachmentResponse).Attachment
+		assert.Equal(t, attachment.BackendLocal, added.Backend)
+		assert.Equal(t, int64(len(content)), added.SizeBytes)
+		assert.Len(t, added.SHA256, 64)
+
+		frames, rpcErr := readFrames(t, fmt.Sprintf(`{"id":%q}`, added.ID))
+		require.Nil(t, rpcErr)
+		require.Len(t, frames, 3)
+		assert.Equal(t, "attachment", frames[0].Type)
+		require.NotNil(t, frames[0].Attachment)
+		assert.Equal(t, "dump.bin", frames[0].Attachment.Name)
+
+		var streamed []byte
+		for _, frame := range frames[1:] {
+			assert.Equal(t, "attachment_data", frame.Type)
+			data, err := base64.StdEncoding.DecodeString(frame.Data)
+			require.NoError(t, err)
+			streamed = append(streamed, data...)
+		}
+		assert.Equal(t, content, streamed)
+	})
+
+	t.Run("rejects attachments to unknown sessions", func(t *testing.T) {
+		_, err := handlers.HandleAddAttachment(ctx, json.RawMessage(`{"session_id":"missing","name":"a.txt","data":""}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown attachment", func(t *testing.T) {
+		_, rpcErr := readFrames(t, `{"id":"missing"}`)
+		require.NotNil(t, rpcErr)
+		assert.Equal(t, InvalidParams, rpcErr.Code)
+	})
+
+	t.Run("attachment held by another backend", func(t *testing.T) {
+		require.NoError(t, sqliteStore.CreateAttachment(ctx, &store.Attachment{
+			ID:         "att-s3",
+			SessionID:  "sess-1",
+			Name:       "remote.txt",
+			SHA256:     "unused",
+			Backend:    attachment.BackendS3,
+			StorageKey: attachment.Key("sess-1", "att-s3"),
+		}))
+		_, rpcErr := readFrames(t, `{"id":"att-s3"}`)
+		require.NotNil(t, rpcErr)
+		assert.Contains(t, rpcErr.Message, "s3 backend")
+	})
+}
@@ -0,0 +1,38 @@
+package rpc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// stalledSessionThreshold is how long a running session can go without
+// activity before it is reported as stalled
+const stalledSessionThreshold = 10 * time.Minute
+
+// sessionAttention reports whether a session needs human action and why.
+// Every state view is built through this so all clients agree on which
+// sessions are actionable.
+func sessionAttention(session *store.Session, now time.Time) (bool, string) {
+	switch session.Status {
+	case store.SessionStatusWaitingInput:
+		return true, "waiting for tool approval"
+	case store.SessionStatusStarting, store.SessionStatusRunning:
+		if !session.LastActivityAt.IsZero() {
+			idle := now.Sub(session.LastActivityAt)
+			if idle > stalledSessionThreshold {
+				return true, fmt.Sprintf("stalled: no activity for %s", idle.Truncate(time.Minute))
+			}
+		}
+	case store.SessionStatusFailed:
+		// Failures with a Claude session behind them can be resumed
+		if session.ClaudeSessionID != "" && !session.Imported {
+			if session.ErrorMessage != "" {
+				return true, "failed and can be retried: " + session.ErrorMessage
+			}
+			return true, "failed and can be retried"
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,36 @@
+package rpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionAttention(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name    string
+		session store.Session
+		needs   bool
+		reason  string
+	}{
+		{"healthy running", store.Session{Status: store.SessionStatusRunning, LastActivityAt: now.Add(-time.Minute)}, false, ""},
+		{"completed", store.Session{Status: store.SessionStatusCompleted, LastActivityAt: now.Add(-time.Hour)}, false, ""},
+		{"pending approval", store.Session{Status: store.SessionStatusWaitingInput}, true, "waiting for tool approval"},
+		{"stalled", store.Session{Status: store.SessionStatusRunning, LastActivityAt: now.Add(-25 * time.Minute)}, true, "stalled: no activity for 25m0s"},
+		{"failed and retryable", store.Session{Status: store.SessionStatusFailed, ClaudeSessionID: "c1", ErrorMessage: "exit status 1"}, true, "failed and can be retried: exit status 1"},
+		{"failed before starting", store.Session{Status: store.SessionStatusFailed}, false, ""},
+		{"failed import", store.Session{Status: store.SessionStatusFailed, ClaudeSessionID: "imported-1", Imported: true}, false, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			needs, reason := sessionAttention(&tc.session, now)
+			assert.Equal(t, tc.needs, needs)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
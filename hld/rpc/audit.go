@@ -0,0 +1,98 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// DefaultIdentity is recorded for callers that have not been identified by
+// the transport or an authentication layer.
+const DefaultIdentity = "local"
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the caller identity for audit purposes
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the caller identity, or DefaultIdentity if unset
+func IdentityFromContext(ctx context.Context) string {
+	if identity, ok := ctx.Value(identityKey{}).(string); ok && identity != "" {
+		return identity
+	}
+	return DefaultIdentity
+}
+
+// AuditLogger records mutating RPC calls in the store
+type AuditLogger struct {
+	store store.ConversationStore
+}
+
+// NewAuditLogger creates a new audit logger
+func NewAuditLogger(store store.ConversationStore) *AuditLogger {
+	return &AuditLogger{store: store}
+}
+
+// Wrap returns a handler that records the call before executing it. If the
+// audit entry cannot be written the call is refused, so no state change can
+// happen without a matching audit record.
+func (a *AuditLogger) Wrap(method string, handler HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		entry := &store.AuditEntry{
+			Identity:  IdentityFromContext(ctx),
+			Method:    method,
+			SessionID: extractSessionID(params),
+			Outcome:   store.AuditOutcomePending,
+		}
+		if err := a.store.CreateAuditEntry(ctx, entry); err != nil {
+			slog.Error("failed to write audit entry, refusing call",
+				"method", method,
+				"error", err)
+			return nil, fmt.Errorf("%w: audit log unavailable: %w", ErrUnavailable, err)
+		}
+
+		result, err := handler(ctx, params)
+
+		outcome := store.AuditOutcomeSuccess
+		errorMessage := ""
+		if err != nil {
+			outcome = store.AuditOutcomeError
+			errorMessage = err.Error()
+		}
+
+		// The call has already taken effect, so a failure here is logged
+		// rather than surfaced; the entry remains as 'pending'.
+		if completeErr := a.store.CompleteAuditEntry(ctx, entry.ID, outcome, errorMessage); completeErr != nil {
+			slog.Error("failed to complete audit entry",
+				"audit_id", entry.ID,
+				"method", method,
+				"error", completeErr)
+		}
+
+		return result, err
+	}
+}
+
+// extractSessionID pulls a session_id out of request params if present,
+// looking inside the envelope of calls made through AuthMiddleware
+func extractSessionID(params json.RawMessage) string {
+	if len(params) == 0 {
+		return ""
+	}
+	var p struct {
+		SessionID string          `json:"session_id"`
+		Params    json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return ""
+	}
+	if p.SessionID == "" && len(p.Params) > 0 {
+		return extractSessionID(p.Params)
+	}
+	return p.SessionID
+}
@@ -0,0 +1,223 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+const (
+	defaultAuditLogLimit = 100
+	maxAuditLogLimit     = 1000
+
+	// defaultAuditStreamBatch is the number of entries per streamAuditLog batch
+	defaultAuditStreamBatch = 500
+)
+
+// AuditHandlers provides RPC handlers for querying the audit log
+type AuditHandlers struct {
+	store store.ConversationStore
+}
+
+// NewAuditHandlers creates new audit log RPC handlers
+func NewAuditHandlers(store store.ConversationStore) *AuditHandlers {
+	return &AuditHandlers{store: store}
+}
+
+// GetAuditLogRequest is the request for querying the audit log
+type GetAuditLogRequest struct {
+	Method    string `json:"method,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	Identity  string `json:"identity,omitempty"`
+	Outcome   string `json:"outcome,omitempty"`
+	Since     string `json:"since,omitempty"` // RFC3339
+	Until     string `json:"until,omitempty"` // RFC3339
+	Limit     int    `json:"limit,omitempty"`
+}
+
+// AuditEntry is the RPC representation of an audit log entry
+type AuditEntry struct {
+	ID           int64  `json:"id"`
+	Identity     string `json:"identity"`
+	Method       string `json:"method"`
+	SessionID    string `json:"session_id,omitempty"`
+	Outcome      string `json:"outcome"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	CreatedAt    string `json:"created_at"`
+	CompletedAt  string `json:"completed_at,omitempty"`
+	PrevHash     string `json:"prev_hash,omitempty"`
+	EntryHash    string `json:"entry_hash"`
+}
+
+// GetAuditLogResponse is the response for querying the audit log
+type GetAuditLogResponse struct {
+	Entries []AuditEntry `json:"entries"`
+}
+
+// AuditLogBatch is a single batch of entries sent by streamAuditLog
+type AuditLogBatch struct {
+	Type    string       `json:"type"` // Always "audit_entries"
+	Entries []AuditEntry `json:"entries"`
+	Final   bool         `json:"final"` // No more batches follow
+}
+
+// HandleGetAuditLog handles the GetAuditLog RPC method
+func (h *AuditHandlers) HandleGetAuditLog(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetAuditLogRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+	}
+
+	filter, err := auditLogFilter(req)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLogLimit
+	}
+	if filter.Limit > maxAuditLogLimit {
+		filter.Limit = maxAuditLogLimit
+	}
+
+	entries, err := h.store.ListAuditEntries(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+
+	return &GetAuditLogResponse{Entries: auditEntriesToRPC(entries)}, nil
+}
+
+// StreamAuditLogConn streams every audit entry matching the request, newest
+// first, in batches of Limit entries. It is meant for exports and other large
+// result sets that would not fit in a single getAuditLog response; the last
+// batch is marked final.
+func (h *AuditHandlers) StreamAuditLogConn(ctx context.Context, conn net.Conn, params json.RawMessage) error {
+	var req GetAuditLogRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return sendJSONResponse(conn, &Response{
+				JSONRPC: "2.0",
+				Error: &Error{
+					Code:    InvalidParams,
+					Message: fmt.Sprintf("invalid request: %v", err),
+				},
+			})
+		}
+	}
+	filter, err := auditLogFilter(req)
+	if err != nil {
+		return sendJSONResponse(conn, &Response{
+			JSONRPC: "2.0",
+			Error: &Error{
+				Code:    InvalidParams,
+				Message: err.Error(),
+			},
+		})
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditStreamBatch
+	}
+	if filter.Limit > maxAuditLogLimit {
+		filter.Limit = maxAuditLogLimit
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entries, err := h.store.ListAuditEntries(ctx, filter)
+		if err != nil {
+			return sendJSONResponse(conn, &Response{
+				JSONRPC: "2.0",
+				Error: &Error{
+					Code:    InternalError,
+					Message: fmt.Sprintf("failed to list audit entries: %v", err),
+				},
+			})
+		}
+
+		final := len(entries) < filter.Limit
+		if err := sendJSONResponse(conn, &Response{
+			JSONRPC: "2.0",
+			Result: &AuditLogBatch{
+				Type:    "audit_entries",
+				Entries: auditEntriesToRPC(entries),
+				Final:   final,
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to send audit entries: %w", err)
+		}
+		if final {
+			return nil
+		}
+		filter.BeforeID = entries[len(entries)-1].ID
+	}
+}
+
+// auditLogFilter validates a request and converts it to a store filter.
+// The limit is passed through unclamped.
+func auditLogFilter(req GetAuditLogRequest) (store.AuditLogFilter, error) {
+	switch req.Outcome {
+	case "", store.AuditOutcomePending, store.AuditOutcomeSuccess, store.AuditOutcomeError:
+	default:
+		return store.AuditLogFilter{}, fmt.Errorf("%w: invalid outcome: %s", ErrInvalidRequest, req.Outcome)
+	}
+
+	filter := store.AuditLogFilter{
+		Method:    req.Method,
+		SessionID: req.SessionID,
+		Identity:  req.Identity,
+		Outcome:   req.Outcome,
+		Limit:     req.Limit,
+	}
+	if req.Since != "" {
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return store.AuditLogFilter{}, fmt.Errorf("%w: invalid since: %w", ErrInvalidRequest, err)
+		}
+		filter.Since = &since
+	}
+	if req.Until != "" {
+		until, err := time.Parse(time.RFC3339, req.Until)
+		if err != nil {
+			return store.AuditLogFilter{}, fmt.Errorf("%w: invalid until: %w", ErrInvalidRequest, err)
+		}
+		filter.Until = &until
+	}
+	return filter, nil
+}
+
+// auditEntriesToRPC converts store audit entries to their RPC representation
+func auditEntriesToRPC(entries []*store.AuditEntry) []AuditEntry {
+	out := make([]AuditEntry, 0, len(entries))
+	for _, e := range entries {
+		entry := AuditEntry{
+			ID:           e.ID,
+			Identity:     e.Identity,
+			Method:       e.Method,
+			SessionID:    e.SessionID,
+			Outcome:      e.Outcome,
+			ErrorMessage: e.ErrorMessage,
+			CreatedAt:    e.CreatedAt.Format(time.RFC3339),
+			PrevHash:     e.PrevHash,
+			EntryHash:    e.EntryHash,
+		}
+		if e.CompletedAt != nil {
+			entry.CompletedAt = e.CompletedAt.Format(time.RFC3339)
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// Register registers all audit handlers with the RPC server
+func (h *AuditHandlers) Register(server *Server) {
+	server.Register("getAuditLog", h.HandleGetAuditLog)
+	server.RegisterConnHandler("streamAuditLog", h.StreamAuditLogConn)
+}
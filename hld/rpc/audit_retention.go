@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// AuditPruner deletes audit entries older than the retention period. It runs
+// separately from session eviction so audit history can outlive (or be kept
+// shorter than) the conversations it refers to.
+type AuditPruner struct {
+	store     store.ConversationStore
+	retention time.Duration
+	interval  time.Duration
+	now       func() time.Time
+}
+
+// NewAuditPruner creates a new audit pruner
+func NewAuditPruner(store store.ConversationStore, retention, interval time.Duration) *AuditPruner {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &AuditPruner{
+		store:     store,
+		retention: retention,
+		interval:  interval,
+		now:       time.Now,
+	}
+}
+
+// Start periodically prunes the audit log until ctx is cancelled
+func (p *AuditPruner) Start(ctx context.Context) {
+	slog.Info("starting audit pruner",
+		"retention", p.retention,
+		"interval", p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	// Do an initial pass immediately
+	p.PruneOnce(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("audit pruner shutting down")
+			return
+		case <-ticker.C:
+			p.PruneOnce(ctx)
+		}
+	}
+}
+
+// PruneOnce runs a single pruning pass and returns the number of entries removed
+func (p *AuditPruner) PruneOnce(ctx context.Context) int64 {
+	if p.store == nil || p.retention <= 0 {
+		return 0
+	}
+
+	pruned, err := p.store.PruneAuditEntries(ctx, p.now().Add(-p.retention))
+	if err != nil {
+		slog.Error("failed to prune audit log", "error", err)
+		return 0
+	}
+	if pruned > 0 {
+		slog.Info("pruned audit log", "entries", pruned, "retention", p.retention)
+	}
+	return pruned
+}
@@ -0,0 +1,178 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAuditLoggerWrap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	logger := NewAuditLogger(mockStore)
+	params := json.RawMessage(`{"session_id":"sess-1"}`)
+
+	t.Run("records successful call", func(t *testing.T) {
+		mockStore.EXPECT().
+			CreateAuditEntry(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, entry *store.AuditEntry) error {
+				assert.Equal(t, "token-a", entry.Identity)
+				assert.Equal(t, "interruptSession", entry.Method)
+				assert.Equal(t, "sess-1", entry.SessionID)
+				entry.ID = 7
+				return nil
+			})
+		mockStore.EXPECT().
+			CompleteAuditEntry(gomock.Any(), int64(7), store.AuditOutcomeSuccess, "").
+			Return(nil)
+
+		handler := logger.Wrap("interruptSession", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			return "ok", nil
+		})
+		result, err := handler(WithIdentity(context.Background(), "token-a"), params)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", result)
+	})
+
+	t.Run("records failed call", func(t *testing.T) {
+		mockStore.EXPECT().
+			CreateAuditEntry(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, entry *store.AuditEntry) error {
+				assert.Equal(t, DefaultIdentity, entry.Identity)
+				entry.ID = 8
+				return nil
+			})
+		mockStore.EXPECT().
+			CompleteAuditEntry(gomock.Any(), int64(8), store.AuditOutcomeError, "boom").
+			Return(nil)
+
+		handler := logger.Wrap("interruptSession", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			return nil, fmt.Errorf("boom")
+		})
+		_, err := handler(context.Background(), params)
+		assert.EqualError(t, err, "boom")
+	})
+
+	t.Run("refuses call when audit write fails", func(t *testing.T) {
+		mockStore.EXPECT().
+			CreateAuditEntry(gomock.Any(), gomock.Any()).
+			Return(fmt.Errorf("disk full"))
+
+		called := false
+		handler := logger.Wrap("interruptSession", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			called = true
+			return nil, nil
+		})
+		_, err := handler(context.Background(), params)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "audit log unavailable")
+		assert.False(t, called, "handler must not run without an audit record")
+	})
+}
+
+func TestServerAuditsOnlyMutatingMethods(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	server := NewServer()
+	server.SetAuditLogger(NewAuditLogger(mockStore))
+
+	noop := func(ctx context.Context, params json.RawMessage) (interface{}, error) { return "ok", nil }
+	server.Register("readThing", noop)
+	server.RegisterMutating("writeThing", noop)
+
+	// Only the mutating method touches the audit log
+	mockStore.EXPECT().CreateAuditEntry(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mockStore.EXPECT().CompleteAuditEntry(gomock.Any(), gomock.Any(), store.AuditOutcomeSuccess, "").Return(nil).Times(1)
+
+	resp := server.handleRequest(context.Background(), []byte(`{"jsonrpc":"2.0","method":"readThing","id":1}`))
+	require.Nil(t, resp.Error)
+	resp = server.handleRequest(context.Background(), []byte(`{"jsonrpc":"2.0","method":"writeThing","id":2}`))
+	require.Nil(t, resp.Error)
+}
+
+func TestStreamAuditLogConn(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, sqliteStore.CreateAuditEntry(ctx, &store.AuditEntry{
+			Identity: "local",
+			Method:   fmt.Sprintf("method-%d", i),
+		}))
+	}
+
+	handlers := NewAuditHandlers(sqliteStore)
+	server, client := net.Pipe()
+	defer func() { _ = client.Close() }()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handlers.StreamAuditLogConn(ctx, server, json.RawMessage(`{"limit":2}`))
+		_ = server.Close()
+	}()
+
+	reader := bufio.NewReader(client)
+	var batches []AuditLogBatch
+	for {
+		_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+		line, err := reader.ReadBytes('\n')
+		require.NoError(t, err)
+		var resp struct {
+			Result AuditLogBatch `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(line, &resp))
+		batches = append(batches, resp.Result)
+		if resp.Result.Final {
+			break
+		}
+	}
+	require.NoError(t, <-done)
+
+	require.Len(t, batches, 3)
+	var methods []string
+	for _, batch := range batches {
+		assert.Equal(t, "audit_entries", batch.Type)
+		for _, entry := range batch.Entries {
+			methods = append(methods, entry.Method)
+		}
+	}
+	assert.Equal(t, []string{"method-4", "method-3", "method-2", "method-1", "method-0"}, methods)
+}
+
+func TestAuditPruner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No store calls are expected when retention is disabled
+	mockStore := store.NewMockConversationStore(ctrl)
+	pruner := NewAuditPruner(mockStore, 0, time.Hour)
+	assert.Zero(t, pruner.PruneOnce(context.Background()))
+
+	cutoff := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	pruner = NewAuditPruner(mockStore, 24*time.Hour, time.Hour)
+	pruner.now = func() time.Time { return cutoff.Add(24 * time.Hour) }
+	mockStore.EXPECT().PruneAuditEntries(gomock.Any(), cutoff).Return(int64(3), nil)
+	assert.Equal(t, int64(3), pruner.PruneOnce(context.Background()))
+}
+
+func TestExtractSessionID(t *testing.T) {
+	assert.Equal(t, "sess-1", extractSessionID(json.RawMessage(`{"session_id":"sess-1"}`)))
+	assert.Equal(t, "sess-1", extractSessionID(json.RawMessage(`{"authorization":"Bearer t","params":{"session_id":"sess-1"}}`)))
+	assert.Equal(t, "", extractSessionID(json.RawMessage(`{"authorization":"Bearer t","params":{}}`)))
+	assert.Equal(t, "", extractSessionID(nil))
+}
@@ -0,0 +1,191 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/humanlayer/humanlayer/hld/approval"
+	"github.com/humanlayer/humanlayer/hld/bus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// failingTokenProvider is a TokenProvider that can't load its tokens
+type failingTokenProvider struct{}
+
+func (failingTokenProvider) Tokens() ([]string, error) {
+	return nil, errors.New("token store offline")
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	var received json.RawMessage
+	echo := func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		received = params
+		return "done", nil
+	}
+	handler := AuthMiddleware([]string{"alpha", "bravo"})(echo)
+
+	t.Run("valid token", func(t *testing.T) {
+		result, err := handler(context.Background(), json.RawMessage(`{"authorization":"Bearer bravo","params":{"session_id":"sess-1"}}`))
+		require.NoError(t, err)
+		assert.Equal(t, "done", result)
+		assert.JSONEq(t, `{"session_id":"sess-1"}`, string(received), "the handler gets the inner params")
+	})
+
+	for name, params := range map[string]string{
+		"no params":        ``,
+		"no envelope":      `{"session_id":"sess-1"}`,
+		"unknown token":    `{"authorization":"Bearer charlie","params":{}}`,
+		"token prefix":     `{"authorization":"Bearer alph","params":{}}`,
+		"missing scheme":   `{"authorization":"alpha","params":{}}`,
+		"empty token":      `{"authorization":"Bearer ","params":{}}`,
+		"not an object":    `["Bearer alpha"]`,
+		"wrong scheme":     `{"authorization":"Basic alpha","params":{}}`,
+		"lowercase bearer": `{"authorization":"bearer alpha","params":{}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			received = nil
+			_, err := handler(context.Background(), json.RawMessage(params))
+			assert.ErrorIs(t, err, ErrUnauthorized)
+			assert.Nil(t, received, "the handler isn't called")
+		})
+	}
+
+	t.Run("no valid tokens", func(t *testing.T) {
+		_, err := AuthMiddleware(nil)(echo)(context.Background(), json.RawMessage(`{"authorization":"Bearer alpha","params":{}}`))
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("failing provider", func(t *testing.T) {
+		_, err := TokenAuthMiddleware(failingTokenProvider{})(echo)(context.Background(), json.RawMessage(`{"authorization":"Bearer alpha","params":{}}`))
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+}
+
+func TestAuthMiddlewareWithHandlers(t *testing.T) {
+	handlers := NewSessionHandlers(nil, nil, nil, nil, nil)
+	handlers.Use(AuthMiddleware([]string{"secret"}))
+	server := NewServer()
+	handlers.Register(server)
+
+	resp := server.handleRequest(context.Background(), []byte(`{"jsonrpc":"2.0","method":"getSessionState","params":{},"id":1}`))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, "unauthorized", resp.Error.Message)
+
+	// Authorized calls reach the handler, which rejects the empty request
+	resp = server.handleRequest(context.Background(), []byte(`{"jsonrpc":"2.0","method":"getSessionState","params":{"authorization":"Bearer secret","params":{}},"id":2}`))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, "invalid request: session_id is required", resp.Error.Message)
+}
+
+func TestServerAuthentication(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	approvals := approval.NewMockManager(ctrl)
+	server := NewServer()
+	server.SetTokenProvider(StaticTokenProvider{"secret"})
+	NewApprovalHandlers(approvals, nil).Register(server)
+	server.SetSubscriptionHandlers(NewSubscriptionHandlers(bus.NewEventBus()))
+	server.RegisterConnHandler("streamThings", func(ctx context.Context, conn net.Conn, params json.RawMessage) error {
+		return sendJSONResponse(conn, &Response{JSONRPC: "2.0", Result: IdentityFromContext(ctx)})
+	})
+
+	call := func(t *testing.T, line string) *RPCError {
+		t.Helper()
+		data, err := json.Marshal(server.handleRequest(context.Background(), []byte(line)))
+		require.NoError(t, err)
+		return DecodeError(data)
+	}
+
+	t.Run("unauthenticated sendDecision is rejected", func(t *testing.T) {
+		// The approval manager isn't called
+		err := call(t, `{"jsonrpc":"2.0","method":"sendDecision","params":{"approval_id":"appr-1","decision":"approve"},"id":1}`)
+		require.NotNil(t, err)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		assert.Equal(t, "unauthorized", err.Message)
+
+		err = call(t, `{"jsonrpc":"2.0","method":"sendDecision","params":{"authorization":"Bearer wrong","params":{"approval_id":"appr-1","decision":"approve"}},"id":2}`)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("authenticated sendDecision records the token identity", func(t *testing.T) {
+		approvals.EXPECT().ApproveToolCall(gomock.Any(), "appr-1", "ok").
+			DoAndReturn(func(ctx context.Context, id, comment string) error {
+				assert.Equal(t, TokenIdentity("secret"), approval.ApproverFromContext(ctx))
+				return nil
+			})
+		err := call(t, `{"jsonrpc":"2.0","method":"sendDecision","params":{"authorization":"Bearer secret","params":{"approval_id":"appr-1","decision":"approve","comment":"ok"}},"id":3}`)
+		assert.Nil(t, err)
+	})
+
+	t.Run("builtins other than health require a token", func(t *testing.T) {
+		assert.Nil(t, call(t, `{"jsonrpc":"2.0","method":"health","id":4}`))
+		err := call(t, `{"jsonrpc":"2.0","method":"listMethods","id":5}`)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		// Unknown methods aren't revealed to unauthenticated callers
+		err = call(t, `{"jsonrpc":"2.0","method":"noSuchMethod","id":6}`)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	serve := func(t *testing.T, line string) map[string]interface{} {
+		t.Helper()
+		client, conn := net.Pipe()
+		defer func() { _ = client.Close() }()
+		go func() {
+			_ = server.ServeConn(context.Background(), conn)
+			_ = conn.Close()
+		}()
+		_, err := client.Write([]byte(line + "\n"))
+		require.NoError(t, err)
+		var resp map[string]interface{}
+		require.NoError(t, json.NewDecoder(client).Decode(&resp))
+		return resp
+	}
+
+	for _, method := range []string{"Subscribe", "streamThings"} {
+		t.Run(method+" requires a token", func(t *testing.T) {
+			resp := serve(t, `{"jsonrpc":"2.0","method":"`+method+`","params":{},"id":7}`)
+			require.Contains(t, resp, "error")
+			assert.Equal(t, "unauthorized", resp["error"].(map[string]interface{})["message"])
+		})
+	}
+
+	t.Run("connection handlers get the token identity", func(t *testing.T) {
+		resp := serve(t, `{"jsonrpc":"2.0","method":"streamThings","params":{"authorization":"Bearer secret","params":{}},"id":8}`)
+		assert.Equal(t, TokenIdentity("secret"), resp["result"])
+	})
+}
+
+func TestFileTokenProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(path, []byte("# daemon tokens\nalpha\n\n  bravo  \r\n"), 0600))
+
+	provider := NewFileTokenProvider(path)
+	tokens, err := provider.Tokens()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha", "bravo"}, tokens)
+
+	// Rotated tokens apply to the next call
+	require.NoError(t, os.WriteFile(path, []byte("charlie\n"), 0600))
+	handler := TokenAuthMiddleware(provider)(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return "done", nil
+	})
+	_, err = handler(context.Background(), json.RawMessage(`{"authorization":"Bearer alpha","params":{}}`))
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = handler(context.Background(), json.RawMessage(`{"authorization":"Bearer charlie","params":{}}`))
+	assert.NoError(t, err)
+
+	_, err = NewFileTokenProvider(filepath.Join(t.TempDir(), "missing")).Tokens()
+	assert.Error(t, err)
+}
+
+func TestStaticTokenProvider(t *testing.T) {
+	tokens, err := StaticTokenProvider{"alpha"}.Tokens()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha"}, tokens)
+}
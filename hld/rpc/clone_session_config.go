@@ -0,0 +1,101 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/humanlayer/humanlayer/hld/session"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// CloneSessionConfigRequest is the request for cloning a session's config
+type CloneSessionConfigRequest struct {
+	SourceSessionID string `json:"source_session_id"`
+	NewQuery        string `json:"new_query,omitempty"` // Defaults to the source's query
+}
+
+// CloneSessionConfigResponse is the response for cloning a session's config
+type CloneSessionConfigResponse struct {
+	SessionID string `json:"session_id"`
+	RunID     string `json:"run_id"`
+}
+
+// HandleCloneSessionConfig creates a draft session with the same launch
+// config and tags as the source, so it can be re-run without entering the
+// config again. No events are copied; launching the draft starts afresh.
+// Permission bypasses are not copied and must be granted again.
+func (h *SessionHandlers) HandleCloneSessionConfig(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req CloneSessionConfigRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.SourceSessionID == "" {
+		return nil, fmt.Errorf("%w: source_session_id is required", ErrInvalidRequest)
+	}
+
+	source, err := h.store.GetSession(ctx, req.SourceSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	tags, err := h.store.GetSessionTags(ctx, source.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session tags: %w", err)
+	}
+
+	query := req.NewQuery
+	if query == "" {
+		query = source.Query
+	}
+	now := time.Now()
+	clone := &store.Session{
+		ID:                    uuid.New().String(),
+		RunID:                 uuid.New().String(),
+		Query:                 query,
+		Summary:               session.CalculateSummary(query),
+		Title:                 source.Title,
+		Model:                 source.Model,
+		ModelID:               source.ModelID,
+		WorkingDir:            source.WorkingDir,
+		MaxTurns:              source.MaxTurns,
+		SystemPrompt:          source.SystemPrompt,
+		AppendSystemPrompt:    source.AppendSystemPrompt,
+		CustomInstructions:    source.CustomInstructions,
+		PermissionPromptTool:  source.PermissionPromptTool,
+		AllowedTools:          source.AllowedTools,
+		DisallowedTools:       source.DisallowedTools,
+		AdditionalDirectories: source.AdditionalDirectories,
+		AutoAcceptEdits:       source.AutoAcceptEdits,
+		ProxyEnabled:          source.ProxyEnabled,
+		ProxyBaseURL:          source.ProxyBaseURL,
+		ProxyModelOverride:    source.ProxyModelOverride,
+		ProxyAPIKey:           source.ProxyAPIKey,
+		Owner:                 source.Owner,
+		BypassToolCache:       source.BypassToolCache,
+		ToolQuota:             source.ToolQuota,
+		MaxCostUSD:            source.MaxCostUSD,
+		Priority:              source.Priority,
+		Status:                store.SessionStatusDraft,
+		CreatedAt:             now,
+		LastActivityAt:        now,
+	}
+
+	if err := h.store.CreateSession(ctx, clone); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	if len(tags) > 0 {
+		if err := h.store.SetSessionTags(ctx, clone.ID, tags); err != nil {
+			return nil, fmt.Errorf("failed to copy session tags: %w", err)
+		}
+	}
+
+	slog.Info("cloned session config",
+		"source_session_id", source.ID,
+		"session_id", clone.ID)
+
+	return &CloneSessionConfigResponse{SessionID: clone.ID, RunID: clone.RunID}, nil
+}
@@ -0,0 +1,79 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleCloneSessionConfig(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	budget := 2.5
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID: "source", RunID: "run-source", ClaudeSessionID: "claude-source", Query: "Fix the bug",
+		Model: "sonnet", WorkingDir: "/src", MaxTurns: 7, AllowedTools: `["Read"]`, MaxCostUSD: &budget,
+		DangerouslySkipPermissions: true, Status: store.SessionStatusCompleted,
+		CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+	require.NoError(t, sqliteStore.SetSessionTags(ctx, "source", map[string]string{"team": "infra", "ticket": "ENG-1"}))
+	for range 3 {
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+			SessionID: "source", ClaudeSessionID: "claude-source",
+			EventType: store.EventTypeMessage, Role: "assistant", Content: "done",
+		}))
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	result, err := handlers.HandleCloneSessionConfig(ctx, json.RawMessage(`{"source_session_id":"source"}`))
+	require.NoError(t, err)
+	resp := result.(*CloneSessionConfigResponse)
+	assert.NotEqual(t, "source", resp.SessionID)
+
+	clone, err := sqliteStore.GetSession(ctx, resp.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, store.SessionStatusDraft, clone.Status)
+	assert.Equal(t, resp.RunID, clone.RunID)
+	assert.Equal(t, "Fix the bug", clone.Query)
+	assert.Equal(t, "sonnet", clone.Model)
+	assert.Equal(t, "/src", clone.WorkingDir)
+	assert.Equal(t, 7, clone.MaxTurns)
+	assert.Equal(t, `["Read"]`, clone.AllowedTools)
+	require.NotNil(t, clone.MaxCostUSD)
+	assert.Equal(t, budget, *clone.MaxCostUSD)
+	assert.False(t, clone.DangerouslySkipPermissions, "permission bypasses aren't cloned")
+	assert.Empty(t, clone.ClaudeSessionID)
+	assert.Nil(t, clone.CostUSD)
+
+	tags, err := sqliteStore.GetSessionTags(ctx, resp.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "infra", "ticket": "ENG-1"}, tags)
+	events, err := sqliteStore.GetSessionConversation(ctx, resp.SessionID, store.ConversationPage{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	result, err = handlers.HandleCloneSessionConfig(ctx, json.RawMessage(`{"source_session_id":"source","new_query":"Fix it again"}`))
+	require.NoError(t, err)
+	second := result.(*CloneSessionConfigResponse)
+	assert.NotEqual(t, resp.SessionID, second.SessionID)
+	clone, err = sqliteStore.GetSession(ctx, second.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "Fix it again", clone.Query)
+	assert.Equal(t, "Fix it again", clone.Summary)
+
+	t.Run("errors", func(t *testing.T) {
+		var notFound *store.NotFoundError
+		_, err := handlers.HandleCloneSessionConfig(ctx, json.RawMessage(`{"source_session_id":"missing"}`))
+		assert.ErrorAs(t, err, &notFound)
+		_, err = handlers.HandleCloneSessionConfig(ctx, json.RawMessage(`{}`))
+		assert.EqualError(t, err, "invalid request: source_session_id is required")
+	})
+}
@@ -0,0 +1,113 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// CompareSessionCostsRequest selects the sessions to compare and how far
+// from the mean a cost must be to be flagged
+type CompareSessionCostsRequest struct {
+	RunID           string  `json:"run_id,omitempty"`           // Compares every session if empty
+	StdDevThreshold float64 `json:"stddev_threshold,omitempty"` // Standard deviations from the mean (default 3)
+}
+
+// SessionCostComparison is one session's cost relative to the others
+type SessionCostComparison struct {
+	SessionID string  `json:"session_id"`
+	RunID     string  `json:"run_id"`
+	Status    string  `json:"status"`
+	Model     string  `json:"model,omitempty"`
+	CostUSD   float64 `json:"cost_usd"`
+	ZScore    float64 `json:"z_score"` // Standard deviations from the mean; negative if cheaper
+	IsOutlier bool    `json:"is_outlier"`
+}
+
+// CompareSessionCostsResponse ranks the compared sessions, most expensive
+// first
+type CompareSessionCostsResponse struct {
+	RunID           string                  `json:"run_id,omitempty"`
+	SessionCount    int                     `json:"session_count"` // Sessions with a recorded cost
+	MeanCostUSD     float64                 `json:"mean_cost_usd"`
+	StdDevCostUSD   float64                 `json:"stddev_cost_usd"`
+	StdDevThreshold float64                 `json:"stddev_threshold"`
+	Sessions        []SessionCostComparison `json:"sessions"`
+}
+
+// HandleCompareSessionCosts ranks sessions by cost and flags those whose
+// cost is more than stddev_threshold standard deviations from the mean, in
+// either direction. Sessions without a recorded cost are left out.
+func (h *SessionHandlers) HandleCompareSessionCosts(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req CompareSessionCostsRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+	}
+
+	if req.StdDevThreshold == 0 {
+		req.StdDevThreshold = defaultAnomalyStdDevs
+	}
+	if req.StdDevThreshold < 0 {
+		return nil, fmt.Errorf("%w: stddev_threshold must be positive", ErrInvalidRequest)
+	}
+
+	sessions, err := h.store.ListSessions(ctx, store.ListSessionsFilter{RunID: req.RunID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	if req.RunID != "" && len(sessions) == 0 {
+		return nil, &store.NotFoundError{Type: "run", ID: req.RunID}
+	}
+
+	// The mean comes from the database; only the deviations are summed here
+	stats, err := h.store.GetSessionCostStats(ctx, req.RunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost stats: %w", err)
+	}
+
+	resp := &CompareSessionCostsResponse{
+		RunID:           req.RunID,
+		SessionCount:    stats.Count,
+		MeanCostUSD:     stats.MeanCostUSD,
+		StdDevThreshold: req.StdDevThreshold,
+		Sessions:        []SessionCostComparison{},
+	}
+	var sq float64
+	for _, sess := range sessions {
+		if sess.CostUSD == nil {
+			continue
+		}
+		d := *sess.CostUSD - stats.MeanCostUSD
+		sq += d * d
+		resp.Sessions = append(resp.Sessions, SessionCostComparison{
+			SessionID: sess.ID,
+			RunID:     sess.RunID,
+			Status:    sess.Status,
+			Model:     sess.Model,
+			CostUSD:   *sess.CostUSD,
+		})
+	}
+	if len(resp.Sessions) > 0 {
+		// Population standard deviation, as in summarizeForAnomalies
+		resp.StdDevCostUSD = math.Sqrt(sq / float64(len(resp.Sessions)))
+	}
+
+	for i := range resp.Sessions {
+		c := &resp.Sessions[i]
+		d := c.CostUSD - stats.MeanCostUSD
+		if resp.StdDevCostUSD > 0 {
+			c.ZScore = d / resp.StdDevCostUSD
+		}
+		c.IsOutlier = math.Abs(d) > req.StdDevThreshold*resp.StdDevCostUSD
+	}
+	sort.SliceStable(resp.Sessions, func(i, j int) bool {
+		return resp.Sessions[i].CostUSD > resp.Sessions[j].CostUSD
+	})
+	return resp, nil
+}
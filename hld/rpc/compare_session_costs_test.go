@@ -0,0 +1,91 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleCompareSessionCosts(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	// Costs 1 to 5 have a mean of 3 and a standard deviation of sqrt(2)
+	for i, cost := range []float64{3, 1, 5, 2, 4} {
+		id := fmt.Sprintf("sess-%d", int(cost))
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: id, RunID: "run-" + id, Query: "q", Status: store.SessionStatusCompleted,
+			Model: "sonnet", CreatedAt: time.Now().Add(time.Duration(i) * time.Minute), LastActivityAt: time.Now(),
+		}))
+		require.NoError(t, sqliteStore.UpdateSession(ctx, id, store.SessionUpdate{CostUSD: &cost}))
+	}
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID: "no-cost", RunID: "run-no-cost", Query: "q", Status: store.SessionStatusRunning,
+		CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+	compare := func(req CompareSessionCostsRequest) *CompareSessionCostsResponse {
+		params, _ := json.Marshal(req)
+		result, err := handlers.HandleCompareSessionCosts(ctx, params)
+		require.NoError(t, err)
+		return result.(*CompareSessionCostsResponse)
+	}
+
+	t.Run("flags both tails", func(t *testing.T) {
+		resp := compare(CompareSessionCostsRequest{StdDevThreshold: 1})
+		assert.Equal(t, 5, resp.SessionCount)
+		assert.InDelta(t, 3.0, resp.MeanCostUSD, 1e-9)
+		assert.InDelta(t, math.Sqrt2, resp.StdDevCostUSD, 1e-9)
+		assert.Equal(t, 1.0, resp.StdDevThreshold)
+
+		require.Len(t, resp.Sessions, 5, "sessions without a cost are left out")
+		var order []string
+		outliers := map[string]bool{}
+		for _, s := range resp.Sessions {
+			order = append(order, s.SessionID)
+			outliers[s.SessionID] = s.IsOutlier
+		}
+		assert.Equal(t, []string{"sess-5", "sess-4", "sess-3", "sess-2", "sess-1"}, order, "most expensive first")
+		assert.Equal(t, map[string]bool{
+			"sess-5": true, "sess-4": false, "sess-3": false, "sess-2": false, "sess-1": true,
+		}, outliers)
+		assert.InDelta(t, math.Sqrt2, resp.Sessions[0].ZScore, 1e-9)
+		assert.InDelta(t, -math.Sqrt2, resp.Sessions[4].ZScore, 1e-9)
+		assert.Equal(t, "sonnet", resp.Sessions[0].Model)
+	})
+
+	t.Run("default threshold", func(t *testing.T) {
+		resp := compare(CompareSessionCostsRequest{})
+		assert.Equal(t, defaultAnomalyStdDevs, resp.StdDevThreshold)
+		for _, s := range resp.Sessions {
+			assert.False(t, s.IsOutlier, s.SessionID)
+		}
+	})
+
+	t.Run("one run", func(t *testing.T) {
+		resp := compare(CompareSessionCostsRequest{RunID: "run-sess-5", StdDevThreshold: 1})
+		assert.Equal(t, 1, resp.SessionCount)
+		assert.Equal(t, 5.0, resp.MeanCostUSD)
+		require.Len(t, resp.Sessions, 1)
+		assert.False(t, resp.Sessions[0].IsOutlier, "a lone session is never an outlier")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := handlers.HandleCompareSessionCosts(ctx, json.RawMessage(`{"run_id":"missing"}`))
+		var notFound *store.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+
+		_, err = handlers.HandleCompareSessionCosts(ctx, json.RawMessage(`{"stddev_threshold":-1}`))
+		assert.EqualError(t, err, "invalid request: stddev_threshold must be positive")
+	})
+}
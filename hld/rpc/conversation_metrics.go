@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// readingWordsPerMinute is a typical adult silent reading speed
+const readingWordsPerMinute = 238
+
+// GetConversationMetricsRequest is the request for sizing a conversation
+type GetConversationMetricsRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// GetConversationMetricsResponse sizes a conversation without transferring
+// its content. Characters, words and reading time cover user and assistant
+// messages; thinking and tool output are excluded.
+type GetConversationMetricsResponse struct {
+	SessionID          string `json:"session_id"`
+	Messages           int    `json:"messages"`
+	Turns              int    `json:"turns"` // User messages
+	ToolCalls          int    `json:"tool_calls"`
+	Characters         int    `json:"characters"`
+	Words              int    `json:"words"`
+	ReadingTimeSeconds int    `json:"reading_time_seconds"`
+	ReadingTimeMinutes int    `json:"reading_time_minutes"` // Rounded up, for "12 min read" hints
+}
+
+// HandleGetConversationMetrics returns size metrics for a session's conversation
+func (h *SessionHandlers) HandleGetConversationMetrics(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetConversationMetricsRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+
+	if _, err := h.store.GetSession(ctx, req.SessionID); err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	metrics, err := h.store.GetConversationMetrics(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation metrics: %w", err)
+	}
+
+	seconds := readingTimeSeconds(metrics.Words)
+	return &GetConversationMetricsResponse{
+		SessionID:          req.SessionID,
+		Messages:           metrics.Messages,
+		Turns:              metrics.Turns,
+		ToolCalls:          metrics.ToolCalls,
+		Characters:         metrics.Characters,
+		Words:              metrics.Words,
+		ReadingTimeSeconds: seconds,
+		ReadingTimeMinutes: int(math.Ceil(float64(seconds) / 60)),
+	}, nil
+}
+
+// readingTimeSeconds estimates how long a person takes to read words
+func readingTimeSeconds(words int) int {
+	return int(math.Ceil(float64(words) * 60 / readingWordsPerMinute))
+}
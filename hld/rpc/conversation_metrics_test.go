@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetConversationMetrics(t *testing.T) {
+	ctx := context.Background()
+	plain, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = plain.Close() }()
+	encrypted, err := store.NewEncryptedSQLiteStore(":memory:", "secret")
+	require.NoError(t, err)
+	defer func() { _ = encrypted.Close() }()
+
+	for name, sqliteStore := range map[string]*store.SQLiteStore{"plaintext": plain, "encrypted": encrypted} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+				ID:              "sess-1",
+				RunID:           "run-1",
+				ClaudeSessionID: "claude-1",
+				Status:          store.SessionStatusCompleted,
+				CreatedAt:       time.Now(),
+				LastActivityAt:  time.Now(),
+			}))
+			for _, event := range []*store.ConversationEvent{
+				{EventType: store.EventTypeMessage, Role: "user", Content: "Summarise the café menu"},
+				{EventType: store.EventTypeThinking, Role: "assistant", Content: "not counted as reading"},
+				{EventType: store.EventTypeToolCall, ToolID: "t1", ToolName: "Read", ToolInputJSON: `{}`},
+				{EventType: store.EventTypeToolResult, ToolResultForID: "t1", ToolResultContent: strings.Repeat("menu ", 100)},
+				{EventType: store.EventTypeMessage, Role: "assistant", Content: strings.TrimSpace(strings.Repeat("word ", 500))},
+				{EventType: store.EventTypeMessage, Role: "user", Content: "thanks"},
+			} {
+				event.SessionID = "sess-1"
+				event.ClaudeSessionID = "claude-1"
+				require.NoError(t, sqliteStore.AddConversationEvent(ctx, event))
+			}
+
+			handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+			result, err := handlers.HandleGetConversationMetrics(ctx, json.RawMessage(`{"session_id": "sess-1"}`))
+			require.NoError(t, err)
+			metrics := result.(*GetConversationMetricsResponse)
+
+			assert.Equal(t, 3, metrics.Messages)
+			assert.Equal(t, 2, metrics.Turns)
+			assert.Equal(t, 1, metrics.ToolCalls)
+			assert.Equal(t, 4+500+1, metrics.Words)
+			// 23 runes (é is one), 2499 and 6
+			assert.Equal(t, 23+2499+6, metrics.Characters, "characters count runes, not bytes")
+			assert.Equal(t, 128, metrics.ReadingTimeSeconds) // 505 words at 238 wpm
+			assert.Equal(t, 3, metrics.ReadingTimeMinutes)
+		})
+	}
+
+	t.Run("requires a known session", func(t *testing.T) {
+		handlers := NewSessionHandlers(nil, plain, nil, nil, nil)
+		_, err := handlers.HandleGetConversationMetrics(ctx, json.RawMessage(`{}`))
+		assert.Error(t, err)
+		_, err = handlers.HandleGetConversationMetrics(ctx, json.RawMessage(`{"session_id": "missing"}`))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// GetConversationStatsRequest is the request for a conversation's event counts
+type GetConversationStatsRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// HourBucket counts the events recorded in one hour
+type HourBucket struct {
+	Hour       time.Time `json:"hour"` // Start of the hour, in UTC
+	EventCount int       `json:"event_count"`
+	TokenCount int       `json:"token_count"` // Estimated tool result tokens
+}
+
+// GetConversationStatsResponse counts a conversation's events by type and
+// hour. ByHour runs from the first event's hour to the last event's, with
+// empty hours included.
+type GetConversationStatsResponse struct {
+	SessionID   string         `json:"session_id"`
+	TotalEvents int            `json:"total_events"`
+	ByType      map[string]int `json:"by_type"`
+	ByHour      []HourBucket   `json:"by_hour"`
+}
+
+// HandleGetConversationStats returns a session's event counts per type and
+// an hourly time series of its activity
+func (h *SessionHandlers) HandleGetConversationStats(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetConversationStatsRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+
+	if _, err := h.store.GetSession(ctx, req.SessionID); err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	stats, err := h.store.GetConversationStats(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation stats: %w", err)
+	}
+
+	resp := &GetConversationStatsResponse{
+		SessionID: req.SessionID,
+		ByType:    stats.ByType,
+		ByHour:    []HourBucket{},
+	}
+	for _, count := range stats.ByType {
+		resp.TotalEvents += count
+	}
+
+	// Fill the hours without events so the series has no gaps
+	for _, bucket := range stats.ByHour {
+		if n := len(resp.ByHour); n > 0 {
+			for next := resp.ByHour[n-1].Hour.Add(time.Hour); next.Before(bucket.Hour); next = next.Add(time.Hour) {
+				resp.ByHour = append(resp.ByHour, HourBucket{Hour: next})
+			}
+		}
+		resp.ByHour = append(resp.ByHour, HourBucket{
+			Hour:       bucket.Hour,
+			EventCount: bucket.EventCount,
+			TokenCount: bucket.TokenCount,
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,81 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetConversationStats(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	for _, id := range []string{"sess-1", "empty"} {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: id, RunID: "run-" + id, ClaudeSessionID: "claude-" + id, Query: "q",
+			Status: store.SessionStatusCompleted, CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+	}
+
+	// Events at 10:00-10:59, 12:00-12:59 and 13:00-13:59, nothing at 11
+	seed := []struct {
+		at    string
+		event store.ConversationEvent
+	}{
+		{"2026-03-01 10:05:00", store.ConversationEvent{EventType: store.EventTypeMessage, Role: "user", Content: "List files"}},
+		{"2026-03-01 10:06:00", store.ConversationEvent{EventType: store.EventTypeToolCall, ToolID: "t1", ToolName: "Bash"}},
+		{"2026-03-01 10:59:59", store.ConversationEvent{EventType: store.EventTypeToolResult, ToolResultForID: "t1", ToolResultContent: "a.go b.go c.go d.go"}},
+		{"2026-03-01 12:00:00", store.ConversationEvent{EventType: store.EventTypeMessage, Role: "assistant", Content: "Four files"}},
+		{"2026-03-01 13:30:00", store.ConversationEvent{EventType: store.EventTypeMessage, Role: "user", Content: "Thanks"}},
+	}
+	for _, s := range seed {
+		event := s.event
+		event.SessionID, event.ClaudeSessionID = "sess-1", "claude-sess-1"
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, &event))
+		_, err := sqliteStore.GetDB().Exec(`UPDATE conversation_events SET created_at = ? WHERE id = ?`, s.at, event.ID)
+		require.NoError(t, err)
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	result, err := handlers.HandleGetConversationStats(ctx, json.RawMessage(`{"session_id":"sess-1"}`))
+	require.NoError(t, err)
+	resp := result.(*GetConversationStatsResponse)
+	assert.Equal(t, 5, resp.TotalEvents)
+	assert.Equal(t, map[string]int{"message": 3, "tool_call": 1, "tool_result": 1}, resp.ByType)
+
+	hour := func(h int) time.Time { return time.Date(2026, 3, 1, h, 0, 0, 0, time.UTC) }
+	require.Len(t, resp.ByHour, 4)
+	for i, want := range []struct {
+		hour   int
+		events int
+	}{{10, 3}, {11, 0}, {12, 1}, {13, 1}} {
+		assert.True(t, hour(want.hour).Equal(resp.ByHour[i].Hour), "bucket %d is %s", i, resp.ByHour[i].Hour)
+		assert.Equal(t, want.events, resp.ByHour[i].EventCount, "bucket %d", i)
+	}
+	assert.Equal(t, store.EstimateTokens("a.go b.go c.go d.go"), resp.ByHour[0].TokenCount)
+	assert.Zero(t, resp.ByHour[1].TokenCount)
+
+	t.Run("empty conversation", func(t *testing.T) {
+		result, err := handlers.HandleGetConversationStats(ctx, json.RawMessage(`{"session_id":"empty"}`))
+		require.NoError(t, err)
+		resp := result.(*GetConversationStatsResponse)
+		assert.Zero(t, resp.TotalEvents)
+		assert.Empty(t, resp.ByType)
+		assert.Empty(t, resp.ByHour)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := handlers.HandleGetConversationStats(ctx, json.RawMessage(`{}`))
+		assert.EqualError(t, err, "invalid request: session_id is required")
+		var notFound *store.NotFoundError
+		_, err = handlers.HandleGetConversationStats(ctx, json.RawMessage(`{"session_id":"missing"}`))
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
@@ -0,0 +1,114 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// StreamConversationRequest is the request for streaming a session's new
+// conversation events
+type StreamConversationRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// ConversationEventNotification is pushed to conversation stream subscribers
+type ConversationEventNotification struct {
+	Type  string             `json:"type"` // "conversation_event", or "end" when the stream is closed
+	Event *ConversationEvent `json:"event,omitempty"`
+}
+
+// HandleStreamConversation pushes each conversation event stored for a
+// session as a newline-delimited JSON frame, in sequence order. Events stored
+// before the call are not replayed; fetch them with getConversation. The
+// stream ends with an "end" frame when the session reaches a terminal status
+// or the subscriber falls too far behind, after which getConversation with
+// after_sequence catches up.
+func (h *SessionHandlers) HandleStreamConversation(ctx context.Context, conn net.Conn, params json.RawMessage) error {
+	var req StreamConversationRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return sendJSONResponse(conn, &Response{
+				JSONRPC: "2.0",
+				Error: &Error{
+					Code:    InvalidParams,
+					Message: fmt.Sprintf("invalid request: %v", err),
+				},
+			})
+		}
+	}
+	if req.SessionID == "" {
+		return sendJSONResponse(conn, &Response{
+			JSONRPC: "2.0",
+			Error: &Error{
+				Code:    InvalidParams,
+				Message: "session_id is required",
+			},
+		})
+	}
+
+	connCtx, connCancel := context.WithCancel(ctx)
+	defer connCancel()
+
+	events, err := h.store.SubscribeToSession(connCtx, req.SessionID)
+	if err != nil {
+		return sendJSONResponse(conn, &Response{
+			JSONRPC: "2.0",
+			Error: &Error{
+				Code:    InternalError,
+				Message: fmt.Sprintf("failed to subscribe to session: %v", err),
+			},
+		})
+	}
+
+	slog.Info("client streaming conversation", "session_id", req.SessionID)
+
+	go watchConnClose(connCtx, conn, connCancel, "session_id", req.SessionID)
+
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-connCtx.Done():
+			return connCtx.Err()
+
+		case event, ok := <-events:
+			if !ok {
+				return sendConversationNotification(conn, &ConversationEventNotification{Type: "end"})
+			}
+			rpcEvent := eventToRPC(event)
+			if err := sendConversationNotification(conn, &ConversationEventNotification{
+				Type:  "conversation_event",
+				Event: &rpcEvent,
+			}); err != nil {
+				return err
+			}
+
+		case <-heartbeat.C:
+			if err := sendJSONResponse(conn, &Response{
+				JSONRPC: "2.0",
+				Result: map[string]interface{}{
+					"type":    "heartbeat",
+					"message": "Connection alive",
+				},
+			}); err != nil {
+				return fmt.Errorf("failed to send heartbeat: %w", err)
+			}
+		}
+	}
+}
+
+// sendConversationNotification writes a conversation stream frame to the connection
+func sendConversationNotification(conn net.Conn, notification *ConversationEventNotification) error {
+	if err := sendJSONResponse(conn, &Response{
+		JSONRPC: "2.0",
+		Result:  notification,
+	}); err != nil {
+		return fmt.Errorf("failed to send conversation event: %w", err)
+	}
+	return nil
+}
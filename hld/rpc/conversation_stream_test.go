@@ -0,0 +1,67 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandleStreamConversation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+
+	events := make(chan *store.ConversationEvent, 2)
+	mockStore.EXPECT().SubscribeToSession(gomock.Any(), "sess-1").Return((<-chan *store.ConversationEvent)(events), nil)
+
+	server, client := net.Pipe()
+	defer func() { _ = client.Close() }()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handlers.HandleStreamConversation(context.Background(), server, json.RawMessage(`{"session_id":"sess-1"}`))
+		_ = server.Close()
+	}()
+
+	reader := bufio.NewReader(client)
+	readFrame := func() ConversationEventNotification {
+		_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+		line, err := reader.ReadBytes('\n')
+		require.NoError(t, err)
+		var resp struct {
+			Result ConversationEventNotification `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(line, &resp))
+		return resp.Result
+	}
+
+	events <- &store.ConversationEvent{ID: 1, SessionID: "sess-1", Sequence: 1, EventType: store.EventTypeMessage, Role: "assistant", Content: "first", CreatedAt: time.Now()}
+	events <- &store.ConversationEvent{ID: 2, SessionID: "sess-1", Sequence: 2, EventType: store.EventTypeMessage, Role: "assistant", Content: "second", CreatedAt: time.Now()}
+
+	for _, want := range []string{"first", "second"} {
+		frame := readFrame()
+		assert.Equal(t, "conversation_event", frame.Type)
+		require.NotNil(t, frame.Event)
+		assert.Equal(t, want, frame.Event.Content)
+	}
+
+	// The store closes the channel when the session finishes
+	close(events)
+	assert.Equal(t, "end", readFrame().Type)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not end")
+	}
+}
@@ -0,0 +1,31 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/humanlayer/humanlayer/hld/translate"
+)
+
+// SetTranslator sets the translator used for getConversation's translate_to
+func (h *SessionHandlers) SetTranslator(translator *translate.Translator) {
+	h.translator = translator
+}
+
+// translateMessages sets TranslatedContent on messages not already in the
+// target language
+func (h *SessionHandlers) translateMessages(ctx context.Context, events []ConversationEvent, target string) error {
+	for i := range events {
+		event := &events[i]
+		if event.EventType != store.EventTypeMessage || strings.EqualFold(event.Language, target) {
+			continue
+		}
+		translated, err := h.translator.TranslateEvent(ctx, event.ID, event.Content, target)
+		if err != nil {
+			return err
+		}
+		event.TranslatedContent = translated
+	}
+	return nil
+}
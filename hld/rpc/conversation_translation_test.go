@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/humanlayer/humanlayer/hld/translate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prefixProvider marks each text with its target language
+type prefixProvider struct{ texts []string }
+
+func (p *prefixProvider) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	p.texts = append(p.texts, texts...)
+	out := make([]string, len(texts))
+	for i, text := range texts {
+		out[i] = "[" + target + "] " + strings.TrimSpace(text)
+	}
+	return out, nil
+}
+
+func TestHandleGetConversationTranslation(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID:              "sess-es",
+		RunID:           "run-es",
+		ClaudeSessionID: "claude-es",
+		Status:          store.SessionStatusRunning,
+		CreatedAt:       time.Now(),
+		LastActivityAt:  time.Now(),
+	}))
+	for _, event := range []*store.ConversationEvent{
+		{EventType: store.EventTypeMessage, Role: "user", Content: "arregla las pruebas", Language: "es"},
+		{EventType: store.EventTypeToolCall, ToolID: "t1", ToolName: "Bash", ToolInputJSON: `{"command":"go test"}`},
+		{EventType: store.EventTypeMessage, Role: "assistant", Content: "Already in English", Language: "en"},
+	} {
+		event.SessionID = "sess-es"
+		event.ClaudeSessionID = "claude-es"
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, event))
+	}
+
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+	params := json.RawMessage(`{"session_id":"sess-es","translate_to":"en"}`)
+
+	_, err = handlers.HandleGetConversation(ctx, params)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "translation not available")
+
+	provider := &prefixProvider{}
+	handlers.SetTranslator(translate.New(provider, 0))
+	result, err := handlers.HandleGetConversation(ctx, params)
+	require.NoError(t, err)
+	events := result.(*GetConversationResponse).Events
+	require.Len(t, events, 3)
+
+	assert.Equal(t, "arregla las pruebas", events[0].Content, "the original is kept")
+	assert.Equal(t, "[en] arregla las pruebas", events[0].TranslatedContent)
+	assert.Empty(t, events[1].TranslatedContent, "tool calls aren't translated")
+	assert.Empty(t, events[2].TranslatedContent, "already in the target language")
+	assert.Equal(t, []string{"arregla las pruebas"}, provider.texts)
+
+	// Repeated reads come from the cache
+	_, err = handlers.HandleGetConversation(ctx, params)
+	require.NoError(t, err)
+	assert.Len(t, provider.texts, 1)
+}
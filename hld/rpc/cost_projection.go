@@ -0,0 +1,166 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+const (
+	// defaultProjectedTurns is used when the request doesn't say how many
+	// more turns to expect and the session has no turn limit
+	defaultProjectedTurns = 10
+	// minTurnsForProjection is the history below which projections are low confidence
+	minTurnsForProjection = 3
+	// highConfidenceTurns is the history at which projections are high confidence
+	highConfidenceTurns = 10
+)
+
+// Projection confidence levels
+const (
+	ConfidenceLow    = "low"
+	ConfidenceMedium = "medium"
+	ConfidenceHigh   = "high"
+)
+
+// ProjectSessionCostRequest is the request for projecting a session's further cost
+type ProjectSessionCostRequest struct {
+	SessionID string `json:"session_id"`
+	// RemainingTurns is how many more turns to project. Defaults to the turns
+	// left under the session's max_turns, or 10 without a limit.
+	RemainingTurns int `json:"remaining_turns,omitempty"`
+}
+
+// ProjectSessionCostResponse is a rough projection of what continuing a
+// session will cost, with a band around it. It is an estimate from the
+// session's own history, not a guarantee.
+type ProjectSessionCostResponse struct {
+	SessionID              string   `json:"session_id"`
+	CostSoFarUSD           float64  `json:"cost_so_far_usd"`
+	TurnsObserved          int      `json:"turns_observed"`
+	AverageTurnCostUSD     float64  `json:"average_turn_cost_usd"`
+	RemainingTurns         int      `json:"remaining_turns"`
+	ProjectedAdditionalUSD float64  `json:"projected_additional_usd"`
+	ProjectedTotalUSD      float64  `json:"projected_total_usd"`
+	LowTotalUSD            float64  `json:"low_total_usd"`
+	HighTotalUSD           float64  `json:"high_total_usd"`
+	Confidence             string   `json:"confidence"` // 'low', 'medium' or 'high'
+	Caveats                []string `json:"caveats"`
+}
+
+// HandleProjectSessionCost projects the additional cost of continuing a
+// session from its per-turn cost history
+func (h *SessionHandlers) HandleProjectSessionCost(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req ProjectSessionCostRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+	if req.RemainingTurns < 0 {
+		return nil, fmt.Errorf("%w: remaining_turns cannot be negative", ErrInvalidRequest)
+	}
+
+	session, err := h.store.GetSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	turns, err := h.store.GetSessionTurns(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session turns: %w", err)
+	}
+
+	remaining := req.RemainingTurns
+	if remaining == 0 {
+		remaining = defaultProjectedTurns
+		if session.MaxTurns > 0 && session.NumTurns != nil {
+			remaining = max(session.MaxTurns-*session.NumTurns, 0)
+		}
+	}
+
+	var costSoFar float64
+	if session.CostUSD != nil {
+		costSoFar = *session.CostUSD
+	}
+	return projectSessionCost(req.SessionID, costSoFar, turnCosts(costSoFar, turns), remaining), nil
+}
+
+// turnCosts splits a session's total cost across its recorded turns. Only the
+// total is reported by the provider, so each turn is charged in proportion to
+// its output tokens, or evenly if no tokens were recorded.
+func turnCosts(total float64, turns []*store.TurnUsage) []float64 {
+	if len(turns) == 0 || total <= 0 {
+		return nil
+	}
+	tokens := 0
+	for _, turn := range turns {
+		tokens += turn.OutputTokens
+	}
+	costs := make([]float64, len(turns))
+	for i, turn := range turns {
+		if tokens > 0 {
+			costs[i] = total * float64(turn.OutputTokens) / float64(tokens)
+		} else {
+			costs[i] = total / float64(len(turns))
+		}
+	}
+	return costs
+}
+
+// projectSessionCost projects remaining turns at the average observed turn
+// cost. The band is a 95% interval assuming turns vary independently around
+// the average; with too little history it is widened to span zero to twice
+// the projection.
+func projectSessionCost(sessionID string, costSoFar float64, costs []float64, remaining int) *ProjectSessionCostResponse {
+	resp := &ProjectSessionCostResponse{
+		SessionID:      sessionID,
+		CostSoFarUSD:   costSoFar,
+		TurnsObserved:  len(costs),
+		RemainingTurns: remaining,
+		Caveats: []string{
+			"projection is an estimate from this session's history, not a guarantee",
+			"turn costs are apportioned from the session total by output tokens",
+		},
+	}
+
+	var mean, variance float64
+	for _, c := range costs {
+		mean += c
+	}
+	if len(costs) > 0 {
+		mean /= float64(len(costs))
+	}
+	for _, c := range costs {
+		variance += (c - mean) * (c - mean)
+	}
+	if len(costs) > 1 {
+		variance /= float64(len(costs) - 1)
+	}
+
+	n := float64(remaining)
+	resp.AverageTurnCostUSD = mean
+	resp.ProjectedAdditionalUSD = mean * n
+	resp.ProjectedTotalUSD = costSoFar + resp.ProjectedAdditionalUSD
+
+	switch {
+	case len(costs) < minTurnsForProjection:
+		resp.Confidence = ConfidenceLow
+		resp.Caveats = append(resp.Caveats, fmt.Sprintf("only %d turns of history; at least %d are needed for a meaningful band", len(costs), minTurnsForProjection))
+		resp.LowTotalUSD = costSoFar
+		resp.HighTotalUSD = costSoFar + 2*resp.ProjectedAdditionalUSD
+		return resp
+	case len(costs) < highConfidenceTurns:
+		resp.Confidence = ConfidenceMedium
+	default:
+		resp.Confidence = ConfidenceHigh
+	}
+
+	halfWidth := 1.96 * math.Sqrt(variance) * math.Sqrt(n)
+	resp.LowTotalUSD = costSoFar + math.Max(resp.ProjectedAdditionalUSD-halfWidth, 0)
+	resp.HighTotalUSD = costSoFar + resp.ProjectedAdditionalUSD + halfWidth
+	return resp
+}
@@ -0,0 +1,90 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandleProjectSessionCost(t *testing.T) {
+	project := func(t *testing.T, session *store.Session, turns []*store.TurnUsage, params string) *ProjectSessionCostResponse {
+		t.Helper()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStore := store.NewMockConversationStore(ctrl)
+		mockStore.EXPECT().GetSession(gomock.Any(), session.ID).Return(session, nil)
+		mockStore.EXPECT().GetSessionTurns(gomock.Any(), session.ID).Return(turns, nil)
+		handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+
+		result, err := handlers.HandleProjectSessionCost(context.Background(), json.RawMessage(params))
+		require.NoError(t, err)
+		return result.(*ProjectSessionCostResponse)
+	}
+	turnsWithTokens := func(tokens ...int) []*store.TurnUsage {
+		var turns []*store.TurnUsage
+		for _, n := range tokens {
+			turns = append(turns, &store.TurnUsage{OutputTokens: n})
+		}
+		return turns
+	}
+
+	t.Run("projects from turn history", func(t *testing.T) {
+		cost := 1.0
+		// Ten turns averaging $0.10 with some spread
+		turns := turnsWithTokens(80, 120, 90, 110, 100, 100, 95, 105, 85, 115)
+		resp := project(t, &store.Session{ID: "sess-1", CostUSD: &cost}, turns,
+			`{"session_id":"sess-1","remaining_turns":5}`)
+
+		assert.Equal(t, 10, resp.TurnsObserved)
+		assert.Equal(t, 5, resp.RemainingTurns)
+		assert.InDelta(t, 0.1, resp.AverageTurnCostUSD, 1e-9)
+		assert.InDelta(t, 0.5, resp.ProjectedAdditionalUSD, 1e-9)
+		assert.InDelta(t, 1.5, resp.ProjectedTotalUSD, 1e-9)
+		assert.Equal(t, ConfidenceHigh, resp.Confidence)
+		assert.Less(t, resp.LowTotalUSD, resp.ProjectedTotalUSD)
+		assert.Greater(t, resp.HighTotalUSD, resp.ProjectedTotalUSD)
+		assert.GreaterOrEqual(t, resp.LowTotalUSD, cost)
+		assert.NotEmpty(t, resp.Caveats)
+	})
+
+	t.Run("defaults remaining turns to the turn limit", func(t *testing.T) {
+		cost := 0.4
+		numTurns := 4
+		resp := project(t, &store.Session{ID: "sess-2", CostUSD: &cost, MaxTurns: 10, NumTurns: &numTurns},
+			turnsWithTokens(10, 10, 10, 10), `{"session_id":"sess-2"}`)
+
+		assert.Equal(t, 6, resp.RemainingTurns)
+		assert.InDelta(t, 0.6, resp.ProjectedAdditionalUSD, 1e-9)
+		assert.Equal(t, ConfidenceMedium, resp.Confidence)
+	})
+
+	t.Run("few turns report low confidence", func(t *testing.T) {
+		cost := 0.2
+		resp := project(t, &store.Session{ID: "sess-3", CostUSD: &cost},
+			turnsWithTokens(50), `{"session_id":"sess-3","remaining_turns":2}`)
+
+		assert.Equal(t, ConfidenceLow, resp.Confidence)
+		assert.InDelta(t, 0.4, resp.ProjectedAdditionalUSD, 1e-9)
+		assert.InDelta(t, cost, resp.LowTotalUSD, 1e-9)
+		assert.InDelta(t, cost+0.8, resp.HighTotalUSD, 1e-9)
+	})
+
+	t.Run("session without history", func(t *testing.T) {
+		resp := project(t, &store.Session{ID: "sess-4"}, nil, `{"session_id":"sess-4"}`)
+
+		assert.Zero(t, resp.ProjectedAdditionalUSD)
+		assert.Equal(t, ConfidenceLow, resp.Confidence)
+	})
+
+	t.Run("requires session id", func(t *testing.T) {
+		handlers := NewSessionHandlers(nil, nil, nil, nil, nil)
+		_, err := handlers.HandleProjectSessionCost(context.Background(), json.RawMessage(`{}`))
+		assert.EqualError(t, err, "invalid request: session_id is required")
+	})
+}
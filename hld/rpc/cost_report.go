@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// GetCostReportRequest selects the sessions to total. With a run ID the
+// report covers that run; otherwise it covers every session created since
+// the given time.
+type GetCostReportRequest struct {
+	RunID string `json:"run_id,omitempty"`
+	Since string `json:"since,omitempty"` // RFC3339; ignored with run_id
+}
+
+// ModelCost totals the sessions that used one model
+type ModelCost struct {
+	Model            string  `json:"model"` // Empty for sessions without a recorded model
+	Sessions         int     `json:"sessions"`
+	CostUSD          float64 `json:"cost_usd"`
+	PromptTokens     int64   `json:"prompt_tokens"` // Input tokens, including cache reads and writes
+	CompletionTokens int64   `json:"completion_tokens"`
+}
+
+// GetCostReportResponse totals cost and tokens, most expensive model first
+type GetCostReportResponse struct {
+	RunID            string      `json:"run_id,omitempty"`
+	Sessions         int         `json:"sessions"`
+	CostUSD          float64     `json:"cost_usd"`
+	PromptTokens     int64       `json:"prompt_tokens"`
+	CompletionTokens int64       `json:"completion_tokens"`
+	Models           []ModelCost `json:"models"`
+}
+
+// HandleGetCostReport totals session cost for a run or, without one, for
+// every session since a time, broken down by model
+func (h *SessionHandlers) HandleGetCostReport(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetCostReportRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+	}
+
+	resp := &GetCostReportResponse{RunID: req.RunID}
+	var models []store.ModelCostSummary
+	if req.RunID != "" {
+		summary, err := h.store.AggregateCostByRun(ctx, req.RunID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to aggregate run cost: %w", err)
+		}
+		models = summary.Models
+		resp.Sessions = summary.SessionCount
+		resp.CostUSD = summary.CostUSD
+		resp.PromptTokens = summary.PromptTokens
+		resp.CompletionTokens = summary.CompletionTokens
+	} else {
+		var since time.Time
+		if req.Since != "" {
+			var err error
+			if since, err = time.Parse(time.RFC3339, req.Since); err != nil {
+				return nil, fmt.Errorf("%w: invalid since: %w", ErrInvalidRequest, err)
+			}
+			// Timestamps are stored in local time and compared as text
+			since = since.Local()
+		}
+		var err error
+		if models, err = h.store.AggregateCostByModel(ctx, since); err != nil {
+			return nil, fmt.Errorf("failed to aggregate cost by model: %w", err)
+		}
+		for _, m := range models {
+			resp.Sessions += m.SessionCount
+			resp.CostUSD += m.CostUSD
+			resp.PromptTokens += m.PromptTokens
+			resp.CompletionTokens += m.CompletionTokens
+		}
+	}
+
+	resp.Models = make([]ModelCost, len(models))
+	for i, m := range models {
+		resp.Models[i] = ModelCost{
+			Model:            m.Model,
+			Sessions:         m.SessionCount,
+			CostUSD:          m.CostUSD,
+			PromptTokens:     m.PromptTokens,
+			CompletionTokens: m.CompletionTokens,
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,80 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetCostReport(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	floatPtr := func(v float64) *float64 { return &v }
+	intPtr := func(v int) *int { return &v }
+	for _, sess := range []struct {
+		id, model string
+		age       time.Duration
+		update    store.SessionUpdate
+	}{
+		{"sess-a", "sonnet", time.Hour, store.SessionUpdate{CostUSD: floatPtr(1), InputTokens: intPtr(100), OutputTokens: intPtr(10)}},
+		{"sess-b", "opus", time.Hour, store.SessionUpdate{CostUSD: floatPtr(3), InputTokens: intPtr(200), CacheCreationInputTokens: intPtr(20), OutputTokens: intPtr(30)}},
+		{"sess-c", "opus", 72 * time.Hour, store.SessionUpdate{CostUSD: floatPtr(5)}},
+	} {
+		createdAt := time.Now().Add(-sess.age)
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: sess.id, RunID: "run-" + sess.id, Model: sess.model, Status: store.SessionStatusCompleted,
+			CreatedAt: createdAt, LastActivityAt: createdAt,
+		}))
+		require.NoError(t, sqliteStore.UpdateSession(ctx, sess.id, sess.update))
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	report := func(t *testing.T, params string) *GetCostReportResponse {
+		t.Helper()
+		result, err := handlers.HandleGetCostReport(ctx, json.RawMessage(params))
+		require.NoError(t, err)
+		return result.(*GetCostReportResponse)
+	}
+
+	t.Run("since", func(t *testing.T) {
+		since := time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+		resp := report(t, `{"since":"`+since+`"}`)
+		assert.Equal(t, 2, resp.Sessions)
+		assert.InDelta(t, 4, resp.CostUSD, 1e-9)
+		assert.Equal(t, int64(320), resp.PromptTokens)
+		assert.Equal(t, int64(40), resp.CompletionTokens)
+		require.Len(t, resp.Models, 2)
+		assert.Equal(t, "opus", resp.Models[0].Model, "most expensive first")
+	})
+
+	t.Run("all time", func(t *testing.T) {
+		resp := report(t, `{}`)
+		assert.Equal(t, 3, resp.Sessions)
+		assert.InDelta(t, 9, resp.CostUSD, 1e-9)
+	})
+
+	t.Run("run", func(t *testing.T) {
+		resp := report(t, `{"run_id":"run-sess-b"}`)
+		assert.Equal(t, "run-sess-b", resp.RunID)
+		assert.Equal(t, 1, resp.Sessions)
+		assert.Equal(t, []ModelCost{{Model: "opus", Sessions: 1, CostUSD: 3, PromptTokens: 220, CompletionTokens: 30}}, resp.Models)
+
+		_, err := handlers.HandleGetCostReport(ctx, json.RawMessage(`{"run_id":"run-missing"}`))
+		var notFound *store.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("invalid since", func(t *testing.T) {
+		_, err := handlers.HandleGetCostReport(ctx, json.RawMessage(`{"since":"yesterday"}`))
+		assert.ErrorContains(t, err, "invalid since")
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+	})
+}
@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/internal/version"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// startTime is when the daemon process started, for reporting uptime
+var startTime = time.Now()
+
+// DaemonHealthResponse is the response for getDaemonHealth
+type DaemonHealthResponse struct {
+	StoreOK            bool   `json:"store_ok"`
+	StoreLatencyMS     int    `json:"store_latency_ms"`
+	ActiveSessionCount int    `json:"active_session_count"`
+	UptimeSeconds      int64  `json:"uptime_seconds"`
+	Version            string `json:"version"`
+}
+
+// HandleGetDaemonHealth reports whether the store is reachable and how many
+// sessions are running. A failing store is reported in the response rather
+// than as an error, so the call works as a readiness probe.
+func (h *SessionHandlers) HandleGetDaemonHealth(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	resp := &DaemonHealthResponse{
+		UptimeSeconds: int64(time.Since(startTime).Seconds()),
+		Version:       version.GetVersion(),
+	}
+
+	start := time.Now()
+	err := h.store.Ping(ctx)
+	resp.StoreLatencyMS = int(time.Since(start).Milliseconds())
+	if err != nil {
+		slog.Warn("daemon health check: store ping failed", "error", err)
+	} else {
+		resp.StoreOK = true
+	}
+
+	running, err := h.store.ListSessions(ctx, store.ListSessionsFilter{Status: []string{store.SessionStatusRunning}})
+	if err != nil {
+		slog.Warn("daemon health check: failed to count running sessions", "error", err)
+	} else {
+		resp.ActiveSessionCount = len(running)
+	}
+
+	return resp, nil
+}
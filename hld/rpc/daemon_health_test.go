@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/internal/version"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandleGetDaemonHealth(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("healthy store", func(t *testing.T) {
+		sqliteStore, err := store.NewSQLiteStore(":memory:")
+		require.NoError(t, err)
+		defer func() { _ = sqliteStore.Close() }()
+
+		for _, s := range []struct{ id, status string }{
+			{"a", store.SessionStatusRunning},
+			{"b", store.SessionStatusRunning},
+			{"c", store.SessionStatusWaitingInput},
+			{"d", store.SessionStatusCompleted},
+		} {
+			require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+				ID: s.id, RunID: "run-" + s.id, Query: "q", Status: s.status,
+				CreatedAt: time.Now(), LastActivityAt: time.Now(),
+			}))
+		}
+
+		handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+		result, err := handlers.HandleGetDaemonHealth(ctx, []byte(`{}`))
+		require.NoError(t, err)
+		resp := result.(*DaemonHealthResponse)
+		assert.True(t, resp.StoreOK)
+		assert.GreaterOrEqual(t, resp.StoreLatencyMS, 0)
+		assert.Equal(t, 2, resp.ActiveSessionCount)
+		assert.GreaterOrEqual(t, resp.UptimeSeconds, int64(0))
+		assert.Equal(t, version.GetVersion(), resp.Version)
+	})
+
+	t.Run("failing ping", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStore := store.NewMockConversationStore(ctrl)
+		mockStore.EXPECT().Ping(gomock.Any()).Return(errors.New("database is locked"))
+		mockStore.EXPECT().
+			ListSessions(gomock.Any(), store.ListSessionsFilter{Status: []string{store.SessionStatusRunning}}).
+			Return([]*store.Session{{ID: "a"}}, nil)
+
+		handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+		result, err := handlers.HandleGetDaemonHealth(ctx, nil)
+		require.NoError(t, err, "a failing store is reported, not returned")
+		resp := result.(*DaemonHealthResponse)
+		assert.False(t, resp.StoreOK)
+		assert.Equal(t, 1, resp.ActiveSessionCount)
+		assert.GreaterOrEqual(t, resp.UptimeSeconds, int64(0))
+		assert.Equal(t, version.GetVersion(), resp.Version)
+	})
+}
@@ -0,0 +1,108 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// DiffConversationsRequest is the request for comparing two conversations
+type DiffConversationsRequest struct {
+	SessionA string `json:"session_a"`
+	SessionB string `json:"session_b"`
+}
+
+// DiffConversationsResponse is the response for comparing two conversations.
+// DivergencePoint is nil when the conversations are identical.
+type DiffConversationsResponse struct {
+	CommonPrefixLength int             `json:"common_prefix_length"`
+	DivergencePoint    *DivergenceInfo `json:"divergence_point,omitempty"`
+}
+
+// DivergenceInfo holds the first pair of events that differ. EventA or EventB
+// is nil when that conversation ended while the other continued.
+type DivergenceInfo struct {
+	EventA *ConversationEvent `json:"event_a,omitempty"`
+	EventB *ConversationEvent `json:"event_b,omitempty"`
+}
+
+// HandleDiffConversations walks two conversations in sequence order and
+// reports how many leading events they share and where they first differ,
+// for comparing a baseline session with a candidate given the same prompt.
+// Parent chains are included, so a fork shares its parent's events.
+func (h *SessionHandlers) HandleDiffConversations(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req DiffConversationsRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.SessionA == "" {
+		return nil, fmt.Errorf("%w: session_a is required", ErrInvalidRequest)
+	}
+	if req.SessionB == "" {
+		return nil, fmt.Errorf("%w: session_b is required", ErrInvalidRequest)
+	}
+
+	eventsA, err := h.diffConversation(ctx, req.SessionA)
+	if err != nil {
+		return nil, err
+	}
+	eventsB, err := h.diffConversation(ctx, req.SessionB)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &DiffConversationsResponse{}
+	for resp.CommonPrefixLength < len(eventsA) && resp.CommonPrefixLength < len(eventsB) {
+		if !sameEvent(eventsA[resp.CommonPrefixLength], eventsB[resp.CommonPrefixLength]) {
+			break
+		}
+		resp.CommonPrefixLength++
+	}
+
+	i := resp.CommonPrefixLength
+	if i == len(eventsA) && i == len(eventsB) {
+		return resp, nil
+	}
+	resp.DivergencePoint = &DivergenceInfo{}
+	if i < len(eventsA) {
+		event := eventToRPC(eventsA[i])
+		resp.DivergencePoint.EventA = &event
+	}
+	if i < len(eventsB) {
+		event := eventToRPC(eventsB[i])
+		resp.DivergencePoint.EventB = &event
+	}
+	return resp, nil
+}
+
+// diffConversation loads a session's conversation for HandleDiffConversations
+func (h *SessionHandlers) diffConversation(ctx context.Context, sessionID string) ([]*store.ConversationEvent, error) {
+	if _, err := h.store.GetSession(ctx, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	events, err := h.store.GetSessionConversation(ctx, sessionID, store.ConversationPage{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	return events, nil
+}
+
+// sameEvent reports whether two events count as the same step of a
+// conversation. Messages compare by content and tool calls by tool and
+// input; tool results compare by result content.
+func sameEvent(a, b *store.ConversationEvent) bool {
+	if a.EventType != b.EventType {
+		return false
+	}
+	switch a.EventType {
+	case store.EventTypeToolCall:
+		return a.ToolName == b.ToolName && a.ToolInputJSON == b.ToolInputJSON
+	case store.EventTypeToolResult:
+		return a.ToolResultContent == b.ToolResultContent
+	default:
+		return a.Content == b.Content
+	}
+}
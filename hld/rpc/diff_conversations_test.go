@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleDiffConversations(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	prompt := store.ConversationEvent{EventType: store.EventTypeMessage, Role: "user", Content: "List files"}
+	call := store.ConversationEvent{EventType: store.EventTypeToolCall, ToolID: "t1", ToolName: "Bash", ToolInputJSON: `{"command":"ls"}`}
+	result := store.ConversationEvent{EventType: store.EventTypeToolResult, ToolResultForID: "t1", ToolResultContent: "a.go b.go"}
+	answer := store.ConversationEvent{EventType: store.EventTypeMessage, Role: "assistant", Content: "Two files"}
+	otherCall := call
+	otherCall.ToolInputJSON = `{"command":"ls -la"}`
+
+	conversations := map[string][]store.ConversationEvent{
+		"baseline":  {prompt, call, result, answer},
+		"identical": {prompt, call, result, answer},
+		"shorter":   {prompt, call},
+		"other-cmd": {prompt, otherCall, result, answer},
+	}
+	for id, events := range conversations {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: id, RunID: "run-" + id, ClaudeSessionID: "claude-" + id, Query: "List files",
+			Status: store.SessionStatusCompleted, CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+		for _, event := range events {
+			event.SessionID, event.ClaudeSessionID = id, "claude-"+id
+			require.NoError(t, sqliteStore.AddConversationEvent(ctx, &event))
+		}
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	diff := func(t *testing.T, a, b string) *DiffConversationsResponse {
+		t.Helper()
+		result, err := handlers.HandleDiffConversations(ctx, json.RawMessage(`{"session_a":"`+a+`","session_b":"`+b+`"}`))
+		require.NoError(t, err)
+		return result.(*DiffConversationsResponse)
+	}
+
+	t.Run("identical", func(t *testing.T) {
+		resp := diff(t, "baseline", "identical")
+		assert.Equal(t, 4, resp.CommonPrefixLength)
+		assert.Nil(t, resp.DivergencePoint)
+	})
+
+	t.Run("one-sided suffix", func(t *testing.T) {
+		resp := diff(t, "baseline", "shorter")
+		assert.Equal(t, 2, resp.CommonPrefixLength)
+		require.NotNil(t, resp.DivergencePoint)
+		require.NotNil(t, resp.DivergencePoint.EventA)
+		assert.Equal(t, store.EventTypeToolResult, resp.DivergencePoint.EventA.EventType)
+		assert.Nil(t, resp.DivergencePoint.EventB)
+	})
+
+	t.Run("middle divergence", func(t *testing.T) {
+		resp := diff(t, "baseline", "other-cmd")
+		assert.Equal(t, 1, resp.CommonPrefixLength)
+		require.NotNil(t, resp.DivergencePoint)
+		require.NotNil(t, resp.DivergencePoint.EventA)
+		require.NotNil(t, resp.DivergencePoint.EventB)
+		assert.Equal(t, `{"command":"ls"}`, resp.DivergencePoint.EventA.ToolInputJSON)
+		assert.Equal(t, `{"command":"ls -la"}`, resp.DivergencePoint.EventB.ToolInputJSON)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := handlers.HandleDiffConversations(ctx, json.RawMessage(`{"session_a":"baseline"}`))
+		assert.EqualError(t, err, "invalid request: session_b is required")
+		var notFound *store.NotFoundError
+		_, err = handlers.HandleDiffConversations(ctx, json.RawMessage(`{"session_a":"baseline","session_b":"missing"}`))
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
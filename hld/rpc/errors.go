@@ -0,0 +1,139 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// RPCError is a class of handler error. Its Code is sent to clients in the
+// data of a JSON-RPC error, so they can tell error classes apart without
+// matching messages.
+type RPCError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// RetryAfter is how long a rate limited client should wait before
+	// retrying, when the daemon said
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *RPCError) Error() string { return e.Message }
+
+// Is matches any RPCError with the same Code, so an error decoded with
+// DecodeError matches its sentinel
+func (e *RPCError) Is(target error) bool {
+	t, ok := target.(*RPCError)
+	return ok && t.Code == e.Code
+}
+
+// Error classes. Handlers wrap them, as in
+// fmt.Errorf("%w: session_id is required", ErrInvalidRequest).
+var (
+	ErrNotFound       = &RPCError{Code: "not_found", Message: "not found"}
+	ErrInvalidRequest = &RPCError{Code: "invalid_request", Message: "invalid request"}
+	ErrConflict       = &RPCError{Code: "conflict", Message: "conflict"}
+	ErrInternal       = &RPCError{Code: "internal", Message: "internal error"}
+	ErrRateLimited    = &RPCError{Code: "rate_limited", Message: "rate limited"}
+	// ErrUnauthorized is returned for a call without a valid bearer token
+	ErrUnauthorized = &RPCError{Code: "unauthorized", Message: "unauthorized"}
+	// ErrUnavailable is returned when the daemon can't serve a call right
+	// now, such as while shutting down; the call may succeed later
+	ErrUnavailable = &RPCError{Code: "unavailable", Message: "unavailable"}
+	// ErrBudgetExceeded is returned when a session has spent its cost budget
+	ErrBudgetExceeded = &RPCError{Code: "budget_exceeded", Message: "budget exceeded"}
+)
+
+// errorData is the data of a JSON-RPC error sent for a handler error
+type errorData struct {
+	Code         string `json:"code"`
+	RetryAfterMS int64  `json:"retry_after_ms,omitempty"`
+}
+
+// newErrorData returns the data sent for a handler error of class
+func newErrorData(err error, class *RPCError) errorData {
+	data := errorData{Code: class.Code}
+	var limited interface{ RetryAfter() time.Duration }
+	if errors.As(err, &limited) {
+		// Rounded up so a client waiting this long is never early
+		data.RetryAfterMS = (limited.RetryAfter() + time.Millisecond - 1).Milliseconds()
+	}
+	return data
+}
+
+// classifyError returns the class of a handler error. Store errors are
+// classified here so handlers can pass them through unwrapped; anything
+// unclassified is internal.
+func classifyError(err error) *RPCError {
+	var class *RPCError
+	switch {
+	case errors.As(err, &class):
+		return class
+	case errors.Is(err, store.ErrNotFound):
+		return ErrNotFound
+	case errors.Is(err, store.ErrAlreadyDecided):
+		return ErrConflict
+	case errors.Is(err, store.ErrBudgetExceeded):
+		return ErrBudgetExceeded
+	case errors.Is(err, ErrShuttingDown):
+		return ErrUnavailable
+	case errors.Is(err, store.ErrInvalidStatus),
+		errors.Is(err, ErrRequestTooLarge),
+		errors.Is(err, ErrResponseTooLarge):
+		return ErrInvalidRequest
+	default:
+		return ErrInternal
+	}
+}
+
+// errorCode returns the JSON-RPC error code sent for a handler error
+func errorCode(err error, class *RPCError) int {
+	var coded interface{ RPCCode() int }
+	if errors.As(err, &coded) {
+		return coded.RPCCode()
+	}
+	if class == ErrInvalidRequest {
+		return InvalidParams
+	}
+	return InternalError
+}
+
+// DecodeError returns the error in a JSON-RPC response, or nil if the
+// response succeeded. Errors from daemons that don't send a class are
+// classified by their JSON-RPC code.
+func DecodeError(data []byte) *RPCError {
+	var resp struct {
+		Error *struct {
+			Code    int             `json:"code"`
+			Message string          `json:"message"`
+			Data    json.RawMessage `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return &RPCError{Code: ErrInternal.Code, Message: fmt.Sprintf("malformed response: %v", err)}
+	}
+	if resp.Error == nil {
+		return nil
+	}
+
+	decoded := &RPCError{Message: resp.Error.Message}
+	var errData errorData
+	if len(resp.Error.Data) > 0 && json.Unmarshal(resp.Error.Data, &errData) == nil && errData.Code != "" {
+		decoded.Code = errData.Code
+		decoded.RetryAfter = time.Duration(errData.RetryAfterMS) * time.Millisecond
+		return decoded
+	}
+	switch resp.Error.Code {
+	case ParseError, InvalidRequest, InvalidParams, RequestTooLarge, ResponseTooLarge:
+		decoded.Code = ErrInvalidRequest.Code
+	case MethodNotFound:
+		decoded.Code = ErrNotFound.Code
+	case RateLimited:
+		decoded.Code = ErrRateLimited.Code
+	default:
+		decoded.Code = ErrInternal.Code
+	}
+	return decoded
+}
@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want *RPCError
+	}{
+		{"wrapped class", fmt.Errorf("%w: session_id is required", ErrInvalidRequest), ErrInvalidRequest},
+		{"class in a chain", fmt.Errorf("failed: %w", fmt.Errorf("%w: busy", ErrConflict)), ErrConflict},
+		{"store not found", fmt.Errorf("failed to get session: %w", &store.NotFoundError{Type: "session", ID: "s1"}), ErrNotFound},
+		{"store already decided", &store.AlreadyDecidedError{ID: "a1", Status: "approved"}, ErrConflict},
+		{"payload too large", &payloadSizeError{err: ErrRequestTooLarge, code: RequestTooLarge}, ErrInvalidRequest},
+		{"unclassified", errors.New("disk on fire"), ErrInternal},
+
+		// Every sentinel a handler can return, bare and wrapped
+		{"not found class", ErrNotFound, ErrNotFound},
+		{"invalid request class", ErrInvalidRequest, ErrInvalidRequest},
+		{"conflict class", ErrConflict, ErrConflict},
+		{"internal class", ErrInternal, ErrInternal},
+		{"rate limited", &rateLimitError{method: "launchSession", retryAfter: time.Second}, ErrRateLimited},
+		{"unauthorized", ErrUnauthorized, ErrUnauthorized},
+		{"wrapped unauthorized", fmt.Errorf("launchSession: %w", ErrUnauthorized), ErrUnauthorized},
+		{"unavailable", ErrUnavailable, ErrUnavailable},
+		{"shutting down", ErrShuttingDown, ErrUnavailable},
+		{"wrapped shutting down", fmt.Errorf("launchSession: %w", ErrShuttingDown), ErrUnavailable},
+		{"budget exceeded", ErrBudgetExceeded, ErrBudgetExceeded},
+		{"store not found sentinel", store.ErrNotFound, ErrNotFound},
+		{"store already decided sentinel", store.ErrAlreadyDecided, ErrConflict},
+		{"store invalid status", fmt.Errorf("session s1: %w", store.ErrInvalidStatus), ErrInvalidRequest},
+		{"store budget exceeded", fmt.Errorf("session s1: %w", store.ErrBudgetExceeded), ErrBudgetExceeded},
+		{"request too large", ErrRequestTooLarge, ErrInvalidRequest},
+		{"response too large", ErrResponseTooLarge, ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Same(t, tt.want, classifyError(tt.err))
+		})
+	}
+}
+
+func TestErrorClassOnTheWire(t *testing.T) {
+	server := NewServer()
+	server.Register("fail", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var req struct {
+			Error string `json:"error"`
+		}
+		_ = json.Unmarshal(params, &req)
+		switch req.Error {
+		case "invalid":
+			return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+		case "missing":
+			return nil, fmt.Errorf("failed to get session: %w", &store.NotFoundError{Type: "session", ID: "s1"})
+		default:
+			return nil, errors.New("disk on fire")
+		}
+	})
+	call := func(t *testing.T, errorName string) []byte {
+		t.Helper()
+		resp := server.handleRequest(context.Background(),
+			[]byte(`{"jsonrpc":"2.0","id":1,"method":"fail","params":{"error":"`+errorName+`"}}`))
+		data, err := json.Marshal(resp)
+		require.NoError(t, err)
+		return data
+	}
+
+	t.Run("invalid request", func(t *testing.T) {
+		data := call(t, "invalid")
+		var resp Response
+		require.NoError(t, json.Unmarshal(data, &resp))
+		assert.Equal(t, InvalidParams, resp.Error.Code)
+
+		decoded := DecodeError(data)
+		require.NotNil(t, decoded)
+		assert.Equal(t, "invalid_request", decoded.Code)
+		assert.Equal(t, "invalid request: session_id is required", decoded.Message)
+		assert.ErrorIs(t, decoded, ErrInvalidRequest)
+		assert.NotErrorIs(t, decoded, ErrNotFound)
+	})
+
+	t.Run("store not found", func(t *testing.T) {
+		decoded := DecodeError(call(t, "missing"))
+		require.NotNil(t, decoded)
+		assert.ErrorIs(t, decoded, ErrNotFound)
+		assert.Equal(t, "failed to get session: session not found: s1", decoded.Message)
+	})
+
+	t.Run("internal", func(t *testing.T) {
+		decoded := DecodeError(call(t, "other"))
+		require.NotNil(t, decoded)
+		assert.ErrorIs(t, decoded, ErrInternal)
+	})
+}
+
+func TestDecodeError(t *testing.T) {
+	assert.Nil(t, DecodeError([]byte(`{"jsonrpc":"2.0","id":1,"result":{"ok":true}}`)))
+
+	// Responses without a class in data are classified by their code
+	decoded := DecodeError([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found: nope"}}`))
+	require.NotNil(t, decoded)
+	assert.ErrorIs(t, decoded, ErrNotFound)
+	decoded = DecodeError([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"request too large"}}`))
+	require.NotNil(t, decoded)
+	assert.ErrorIs(t, decoded, ErrInvalidRequest)
+
+	decoded = DecodeError([]byte(`not json`))
+	require.NotNil(t, decoded)
+	assert.ErrorIs(t, decoded, ErrInternal)
+}
@@ -0,0 +1,132 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/humanlayer/humanlayer/hld/export"
+	"github.com/humanlayer/humanlayer/hld/redact"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// Export formats
+const (
+	ExportFormatMarkdown = "markdown"
+	ExportFormatJSON     = "json" // Same shape as importConversation's events
+)
+
+// ExportConversationRequest is the request for exporting a conversation
+type ExportConversationRequest struct {
+	SessionID string `json:"session_id"`
+	Format    string `json:"format,omitempty"`     // ExportFormatMarkdown (default) or ExportFormatJSON
+	Redact    bool   `json:"redact,omitempty"`     // Strip secrets from the output
+	RedactPII bool   `json:"redact_pii,omitempty"` // Also strip personal data; implies Redact
+}
+
+// ExportConversationResponse is the rendered export
+type ExportConversationResponse struct {
+	SessionID  string `json:"session_id"`
+	Format     string `json:"format"`
+	Content    string `json:"content"`
+	Redactions int    `json:"redactions"` // Number of values replaced by placeholders
+}
+
+// SetRedactor sets the redactor used for redacted exports
+func (h *SessionHandlers) SetRedactor(redactor *redact.Redactor) {
+	h.redactor = redactor
+}
+
+// HandleExportConversation renders a session's conversation for sharing.
+// Redaction applies to the export only; stored events are left as they are.
+func (h *SessionHandlers) HandleExportConversation(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req ExportConversationRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+	if req.Format == "" {
+		req.Format = ExportFormatMarkdown
+	}
+	if req.Format != ExportFormatMarkdown && req.Format != ExportFormatJSON {
+		return nil, fmt.Errorf("%w: unknown format %q (must be %s or %s)", ErrInvalidRequest, req.Format, ExportFormatMarkdown, ExportFormatJSON)
+	}
+
+	sess, err := h.store.GetSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	stored, err := h.store.GetSessionConversation(ctx, req.SessionID, store.ConversationPage{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	events := make([]ConversationEvent, len(stored))
+	for i, event := range stored {
+		events[i] = eventToRPC(event)
+	}
+	title := sess.Title
+	if title == "" {
+		title = sess.Summary
+	}
+
+	resp := &ExportConversationResponse{SessionID: req.SessionID, Format: req.Format}
+	if req.Redact || req.RedactPII {
+		redactor := h.redactor
+		if redactor == nil {
+			// Fall back to the built-in patterns
+			if redactor, err = redact.New(nil); err != nil {
+				return nil, err
+			}
+		}
+		// Redact field by field so the JSON export stays well formed
+		redactField := func(s *string) {
+			var n int
+			*s, n = redactor.Redact(*s, req.RedactPII)
+			resp.Redactions += n
+		}
+		redactField(&title)
+		for i := range events {
+			redactField(&events[i].Content)
+			redactField(&events[i].ToolInputJSON)
+			redactField(&events[i].ToolResultContent)
+			redactField(&events[i].ToolError)
+		}
+	}
+
+	switch req.Format {
+	case ExportFormatJSON:
+		data, err := json.MarshalIndent(struct {
+			Title  string              `json:"title,omitempty"`
+			Events []ConversationEvent `json:"events"`
+		}{Title: title, Events: events}, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode export: %w", err)
+		}
+		resp.Content = string(data)
+	default:
+		resp.Content = export.Markdown(title, exportEvents(events))
+	}
+	return resp, nil
+}
+
+// exportEvents converts events for rendering by the export package
+func exportEvents(events []ConversationEvent) []export.Event {
+	out := make([]export.Event, len(events))
+	for i, event := range events {
+		out[i] = export.Event{
+			Type:            event.EventType,
+			Role:            event.Role,
+			Content:         event.Content,
+			ToolID:          event.ToolID,
+			ToolName:        event.ToolName,
+			ToolInputJSON:   event.ToolInputJSON,
+			ToolResultForID: event.ToolResultForID,
+			ToolResult:      event.ToolResultContent,
+			ToolError:       event.ToolError,
+		}
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// MethodInfo describes an RPC method available to the caller
+type MethodInfo struct {
+	Name      string `json:"name"`
+	Mutating  bool   `json:"mutating,omitempty"`  // Recorded in the audit log
+	Streaming bool   `json:"streaming,omitempty"` // Takes over the connection to stream frames
+}
+
+// ListMethodsResponse lists the methods available to the caller
+type ListMethodsResponse struct {
+	Methods []MethodInfo `json:"methods"`
+}
+
+// GetFeatureFlagsRequest is the request for the effective feature flags
+type GetFeatureFlagsRequest struct {
+	Owner string `json:"owner,omitempty"` // Defaults to the caller's identity
+}
+
+// GetFeatureFlagsResponse is the effective value of each flag for an owner
+type GetFeatureFlagsResponse struct {
+	Owner string          `json:"owner"`
+	Flags map[string]bool `json:"flags"`
+}
+
+// handleListMethods lists the registered methods, leaving out those
+// disabled by a feature flag for the caller
+func (s *Server) handleListMethods(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	methods := []MethodInfo{}
+	for name := range s.handlers {
+		if s.methodEnabled(ctx, name) {
+			methods = append(methods, MethodInfo{Name: name, Mutating: s.mutatingMethods[name]})
+		}
+	}
+	for name := range s.connHandlers {
+		if s.methodEnabled(ctx, name) {
+			methods = append(methods, MethodInfo{Name: name, Streaming: true})
+		}
+	}
+	if s.subscriptionMgr != nil {
+		methods = append(methods, MethodInfo{Name: "Subscribe", Streaming: true})
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+	return &ListMethodsResponse{Methods: methods}, nil
+}
+
+// handleGetFeatureFlags reports the effective flags so clients can adapt to
+// what's enabled
+func (s *Server) handleGetFeatureFlags(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetFeatureFlagsRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+	}
+	if req.Owner == "" {
+		req.Owner = IdentityFromContext(ctx)
+	}
+
+	s.mu.RLock()
+	flags := s.features
+	s.mu.RUnlock()
+	return &GetFeatureFlagsResponse{Owner: req.Owner, Flags: flags.Snapshot(req.Owner)}, nil
+}
@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/humanlayer/humanlayer/hld/feature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatureGatedMethods(t *testing.T) {
+	server := NewServer()
+	noop := func(ctx context.Context, params json.RawMessage) (interface{}, error) { return "ok", nil }
+	server.Register("stableThing", noop)
+	server.RegisterMutating("betaThing", noop)
+	server.RegisterConnHandler("betaStream", func(ctx context.Context, conn net.Conn, params json.RawMessage) error { return nil })
+	server.GateMethod("betaThing", "beta")
+	server.GateMethod("betaStream", "beta")
+
+	flags := feature.New(nil, map[string]map[string]bool{"tester": {"beta": true}})
+	server.SetFeatureFlags(flags)
+
+	local := context.Background()
+	tester := WithIdentity(context.Background(), "tester")
+	call := func(ctx context.Context, method string) *Response {
+		return server.handleRequest(ctx, []byte(`{"jsonrpc":"2.0","method":"`+method+`","id":1}`))
+	}
+	methodNames := func(ctx context.Context) []string {
+		resp := call(ctx, "listMethods")
+		require.Nil(t, resp.Error)
+		var names []string
+		for _, m := range resp.Result.(*ListMethodsResponse).Methods {
+			names = append(names, m.Name)
+		}
+		return names
+	}
+
+	t.Run("unknown flags are off", func(t *testing.T) {
+		resp := call(local, "betaThing")
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, MethodNotFound, resp.Error.Code)
+		assert.Nil(t, call(local, "stableThing").Error)
+
+		names := methodNames(local)
+		assert.Contains(t, names, "stableThing")
+		assert.NotContains(t, names, "betaThing")
+		assert.NotContains(t, names, "betaStream")
+	})
+
+	t.Run("owner scoped flag", func(t *testing.T) {
+		assert.Nil(t, call(tester, "betaThing").Error)
+		names := methodNames(tester)
+		assert.Contains(t, names, "betaThing")
+		assert.Contains(t, names, "betaStream")
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		flags.Update(map[string]bool{"beta": true}, nil)
+		assert.Nil(t, call(local, "betaThing").Error)
+	})
+
+	t.Run("getFeatureFlags", func(t *testing.T) {
+		flags.Update(nil, map[string]map[string]bool{"tester": {"beta": true, feature.Attachments: false}})
+
+		resp := call(tester, "getFeatureFlags")
+		require.Nil(t, resp.Error)
+		result := resp.Result.(*GetFeatureFlagsResponse)
+		assert.Equal(t, "tester", result.Owner)
+		assert.True(t, result.Flags["beta"])
+		assert.False(t, result.Flags[feature.Attachments])
+		assert.True(t, result.Flags[feature.ConversationExport])
+
+		resp = server.handleRequest(tester, []byte(`{"jsonrpc":"2.0","method":"getFeatureFlags","params":{"owner":"someone"},"id":2}`))
+		require.Nil(t, resp.Error)
+		result = resp.Result.(*GetFeatureFlagsResponse)
+		assert.Equal(t, "someone", result.Owner)
+		assert.False(t, result.Flags["beta"])
+		assert.True(t, result.Flags[feature.Attachments])
+	})
+}
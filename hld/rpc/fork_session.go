@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// ForkSessionRequest is the request for forking a session part way through
+type ForkSessionRequest struct {
+	SourceSessionID string `json:"source_session_id"`
+	AtSequence      int    `json:"at_sequence"`         // Last event to copy; 0 copies none
+	NewQuery        string `json:"new_query,omitempty"` // Defaults to the source's query
+}
+
+// ForkSessionResponse is the response for forking a session
+type ForkSessionResponse struct {
+	SessionID  string `json:"session_id"`
+	RunID      string `json:"run_id"`
+	EventCount int    `json:"event_count"` // Events copied from the source
+}
+
+// HandleForkSession copies a session's events up to a sequence number into a
+// new running session, leaving the source untouched, so an alternative can
+// be tried from that point. Only the source's own events are copied, not
+// those of its parent chain. The fork gets a synthetic claude_session_id to
+// scope its sequence numbers; no Claude process is attached to it.
+func (h *SessionHandlers) HandleForkSession(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req ForkSessionRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.SourceSessionID == "" {
+		return nil, fmt.Errorf("%w: source_session_id is required", ErrInvalidRequest)
+	}
+	if req.AtSequence < 0 {
+		return nil, fmt.Errorf("%w: at_sequence cannot be negative", ErrInvalidRequest)
+	}
+
+	source, err := h.store.GetSession(ctx, req.SourceSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	query := req.NewQuery
+	if query == "" {
+		query = source.Query
+	}
+	now := time.Now()
+	sessionID := uuid.New().String()
+	fork := &store.Session{
+		ID:              sessionID,
+		RunID:           uuid.New().String(),
+		ClaudeSessionID: "forked-" + sessionID,
+		Query:           query,
+		Model:           source.Model,
+		WorkingDir:      source.WorkingDir,
+		MaxCostUSD:      source.MaxCostUSD,
+		Status:          store.SessionStatusRunning,
+		CreatedAt:       now,
+		LastActivityAt:  now,
+	}
+
+	copied, err := h.store.ForkSession(ctx, source.ID, req.AtSequence, fork)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fork session: %w", err)
+	}
+
+	slog.Info("forked session",
+		"source_session_id", source.ID,
+		"session_id", sessionID,
+		"at_sequence", req.AtSequence,
+		"event_count", copied)
+
+	return &ForkSessionResponse{
+		SessionID:  sessionID,
+		RunID:      fork.RunID,
+		EventCount: copied,
+	}, nil
+}
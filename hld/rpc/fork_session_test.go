@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleForkSession(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	budget := 2.5
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID: "source", RunID: "run-source", ClaudeSessionID: "claude-source", Query: "Fix the bug",
+		Model: "sonnet", WorkingDir: "/src", MaxCostUSD: &budget, Status: store.SessionStatusCompleted,
+		CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+			SessionID: "source", ClaudeSessionID: "claude-source",
+			EventType: store.EventTypeMessage, Role: "assistant", Content: fmt.Sprintf("event %d", i),
+		}))
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	result, err := handlers.HandleForkSession(ctx, json.RawMessage(`{"source_session_id":"source","at_sequence":2,"new_query":"Try another way"}`))
+	require.NoError(t, err)
+	resp := result.(*ForkSessionResponse)
+	assert.Equal(t, 2, resp.EventCount)
+	assert.NotEqual(t, "source", resp.SessionID)
+
+	fork, err := sqliteStore.GetSession(ctx, resp.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, store.SessionStatusRunning, fork.Status)
+	assert.Equal(t, "Try another way", fork.Query)
+	assert.Equal(t, "sonnet", fork.Model)
+	assert.Equal(t, "/src", fork.WorkingDir)
+	require.NotNil(t, fork.MaxCostUSD)
+	assert.Equal(t, budget, *fork.MaxCostUSD)
+	assert.Equal(t, resp.RunID, fork.RunID)
+
+	conversation, err := handlers.HandleGetConversation(ctx, json.RawMessage(fmt.Sprintf(`{"session_id":%q}`, resp.SessionID)))
+	require.NoError(t, err)
+	events := conversation.(*GetConversationResponse).Events
+	require.Len(t, events, 2)
+	assert.Equal(t, "event 2", events[1].Content)
+
+	source, err := sqliteStore.GetSession(ctx, "source")
+	require.NoError(t, err)
+	assert.Equal(t, store.SessionStatusCompleted, source.Status)
+	sourceEvents, err := sqliteStore.GetSessionConversation(ctx, "source", store.ConversationPage{})
+	require.NoError(t, err)
+	assert.Len(t, sourceEvents, 4)
+
+	result, err = handlers.HandleForkSession(ctx, json.RawMessage(`{"source_session_id":"source","at_sequence":0}`))
+	require.NoError(t, err)
+	empty := result.(*ForkSessionResponse)
+	assert.Zero(t, empty.EventCount)
+	fork, err = sqliteStore.GetSession(ctx, empty.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "Fix the bug", fork.Query, "the source's query is kept without a new one")
+	forkEvents, err := sqliteStore.GetSessionConversation(ctx, empty.SessionID, store.ConversationPage{})
+	require.NoError(t, err)
+	assert.Empty(t, forkEvents)
+
+	t.Run("errors", func(t *testing.T) {
+		var notFound *store.NotFoundError
+		_, err := handlers.HandleForkSession(ctx, json.RawMessage(`{"source_session_id":"missing","at_sequence":1}`))
+		assert.ErrorAs(t, err, &notFound)
+		_, err = handlers.HandleForkSession(ctx, json.RawMessage(`{"at_sequence":1}`))
+		assert.EqualError(t, err, "invalid request: source_session_id is required")
+		_, err = handlers.HandleForkSession(ctx, json.RawMessage(`{"source_session_id":"source","at_sequence":-1}`))
+		assert.EqualError(t, err, "invalid request: at_sequence cannot be negative")
+	})
+}
@@ -7,14 +7,22 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
 	"github.com/humanlayer/humanlayer/hld/approval"
 	"github.com/humanlayer/humanlayer/hld/bus"
+	"github.com/humanlayer/humanlayer/hld/feature"
+	"github.com/humanlayer/humanlayer/hld/redact"
 	"github.com/humanlayer/humanlayer/hld/session"
 	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/humanlayer/humanlayer/hld/translate"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // SessionHandlers provides RPC handlers for session management
@@ -23,14 +31,37 @@ type SessionHandlers struct {
 	store           store.ConversationStore
 	eventBus        bus.EventBus
 	approvalManager approval.Manager
+	redactor        *redact.Redactor
+	features        *feature.Flags
+	translator      *translate.Translator
+	attachments     session.AttachmentPruner
+	retention       *SessionRetention
+	metrics         *handlerMetrics
+	logger          *slog.Logger
+	middleware      []Middleware
+
+	// inflight counts calls still running, which Shutdown waits for. draining
+	// is set once Shutdown starts, under drainMu so no call is added after
+	// the wait begins.
+	inflight sync.WaitGroup
+	drainMu  sync.Mutex
+	draining bool
+
+	// Payload size limits, zero for the defaults
+	maxRequestBytes  int64
+	maxResponseBytes int64
 }
 
-// NewSessionHandlers creates new session RPC handlers
-func NewSessionHandlers(manager session.SessionManager, store store.ConversationStore, approvalManager approval.Manager) *SessionHandlers {
+// NewSessionHandlers creates new session RPC handlers. When registerer is
+// non-nil, every handler's calls and latency are recorded in it. When logger
+// is non-nil, every handler call is logged with its request ID.
+func NewSessionHandlers(manager session.SessionManager, store store.ConversationStore, approvalManager approval.Manager, registerer prometheus.Registerer, logger *slog.Logger) *SessionHandlers {
 	return &SessionHandlers{
 		manager:         manager,
 		store:           store,
 		approvalManager: approvalManager,
+		metrics:         newHandlerMetrics(registerer),
+		logger:          logger,
 	}
 }
 
@@ -39,6 +70,17 @@ func (h *SessionHandlers) SetEventBus(eventBus bus.EventBus) {
 	h.eventBus = eventBus
 }
 
+// SetFeatureFlags sets the flags consulted for dry-run launches
+func (h *SessionHandlers) SetFeatureFlags(flags *feature.Flags) {
+	h.features = flags
+}
+
+// SetAttachmentPruner makes deleteSession delete a session's attachment
+// content along with its rows
+func (h *SessionHandlers) SetAttachmentPruner(pruner session.AttachmentPruner) {
+	h.attachments = pruner
+}
+
 // LaunchSessionRequest is the request for launching a new session
 type LaunchSessionRequest struct {
 	Query                             string                `json:"query"`
@@ -57,24 +99,30 @@ type LaunchSessionRequest struct {
 	Verbose                           bool                  `json:"verbose,omitempty"`
 	DangerouslySkipPermissions        bool                  `json:"dangerously_skip_permissions,omitempty"`
 	DangerouslySkipPermissionsTimeout *int64                `json:"dangerously_skip_permissions_timeout,omitempty"`
+	Owner                             string                `json:"owner,omitempty"`             // Defaults to the caller's identity
+	BypassToolCache                   bool                  `json:"bypass_tool_cache,omitempty"` // Always execute tools, ignoring cached results
+	ToolQuota                         *session.ToolQuota    `json:"tool_quota,omitempty"`        // Limit on tool calls, unlimited if unset
+	Priority                          int                   `json:"priority,omitempty"`          // Launch priority when waiting for a slot; higher starts first
+	DryRun                            bool                  `json:"dry_run,omitempty"`           // Validate and estimate without launching
 }
 
 // LaunchSessionResponse is the response for launching a new session
 type LaunchSessionResponse struct {
-	SessionID string `json:"session_id"`
-	RunID     string `json:"run_id"`
+	SessionID string              `json:"session_id"`
+	RunID     string              `json:"run_id"`
+	Estimate  *LaunchCostEstimate `json:"estimate,omitempty"` // Set for dry runs, which launch nothing
 }
 
 // HandleLaunchSession handles the LaunchSession RPC method
 func (h *SessionHandlers) HandleLaunchSession(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req LaunchSessionRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	// Validate required fields
 	if req.Query == "" {
-		return nil, fmt.Errorf("query is required")
+		return nil, fmt.Errorf("%w: query is required", ErrInvalidRequest)
 	}
 
 	// Build session config with daemon-level settings
@@ -98,6 +146,13 @@ func (h *SessionHandlers) HandleLaunchSession(ctx context.Context, params json.R
 		Title:                             req.Title,
 		DangerouslySkipPermissions:        req.DangerouslySkipPermissions,
 		DangerouslySkipPermissionsTimeout: req.DangerouslySkipPermissionsTimeout,
+		Owner:                             req.Owner,
+		BypassToolCache:                   req.BypassToolCache,
+		ToolQuota:                         req.ToolQuota,
+		Priority:                          req.Priority,
+	}
+	if config.Owner == "" {
+		config.Owner = IdentityFromContext(ctx)
 	}
 
 	// Parse model if provided
@@ -114,6 +169,16 @@ func (h *SessionHandlers) HandleLaunchSession(ctx context.Context, params json.R
 		}
 	}
 
+	if req.DryRun {
+		// Validate tools here too, since a dry run never reaches the manager
+		if h.features.Enabled(feature.ToolAvailabilityCheck, config.Owner) {
+			if err := session.CheckToolAvailability(req.AllowedTools, req.MCPConfig); err != nil {
+				return nil, err
+			}
+		}
+		return &LaunchSessionResponse{Estimate: estimateLaunchCost(req)}, nil
+	}
+
 	// Launch session (RPC always launches, never creates drafts)
 	session, err := h.manager.LaunchSession(ctx, config, false)
 	if err != nil {
@@ -126,32 +191,105 @@ func (h *SessionHandlers) HandleLaunchSession(ctx context.Context, params json.R
 	}, nil
 }
 
-// ListSessionsRequest is the request for listing sessions
+// ListSessionsRequest is the request for listing sessions. Empty fields
+// don't filter.
 type ListSessionsRequest struct {
-	// Could add filters here in the future
+	Status           []string `json:"status,omitempty"`             // Any of these statuses
+	ModelPrefix      string   `json:"model_prefix,omitempty"`       // Models starting with this, such as "claude-sonnet"
+	WorkingDirPrefix string   `json:"working_dir_prefix,omitempty"` // Working directories starting with this
+	CreatedAfter     string   `json:"created_after,omitempty"`      // RFC3339
+	CreatedBefore    string   `json:"created_before,omitempty"`     // RFC3339
+	RunID            string   `json:"run_id,omitempty"`
+
+	Tags map[string]string `json:"tags,omitempty"` // Sessions with all of these tags
+
+	SortBy    string `json:"sort_by,omitempty"`    // One of store.SessionSorts, last_activity_at by default
+	SortOrder string `json:"sort_order,omitempty"` // "asc" or "desc" (default)
+	Limit     int    `json:"limit,omitempty"`      // At most this many sessions, 0 for all
+	Offset    int    `json:"offset,omitempty"`     // Sessions to skip before the first one returned
 }
 
 // ListSessionsResponse is the response for listing sessions
 type ListSessionsResponse struct {
-	Sessions []session.Info `json:"sessions"`
+	Sessions   []SessionState `json:"sessions"`
+	TotalCount int            `json:"total_count"` // Sessions matching the filters, ignoring limit and offset
 }
 
-// HandleListSessions handles the ListSessions RPC method
+// HandleListSessions handles the ListSessions RPC method, returning a page of
+// matching sessions, most recently active first unless another order is
+// requested. Fields that take further queries per
+// session, such as throughput, are only filled in by getSessionState.
 func (h *SessionHandlers) HandleListSessions(ctx context.Context, params json.RawMessage) (interface{}, error) {
-	// Parse request (even though it's empty for now)
 	var req ListSessionsRequest
 	if params != nil {
 		if err := json.Unmarshal(params, &req); err != nil {
-			return nil, fmt.Errorf("invalid request: %w", err)
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 		}
 	}
 
-	// Get all sessions
-	sessions := h.manager.ListSessions()
+	for _, status := range req.Status {
+		if !slices.Contains(store.SessionStatuses, status) {
+			return nil, fmt.Errorf("%w: unknown status %q, must be one of %s",
+				ErrInvalidRequest, status, strings.Join(store.SessionStatuses, ", "))
+		}
+	}
+	if req.SortBy != "" && !slices.Contains(store.SessionSorts, req.SortBy) {
+		return nil, fmt.Errorf("%w: unknown sort_by %q, must be one of %s",
+			ErrInvalidRequest, req.SortBy, strings.Join(store.SessionSorts, ", "))
+	}
+	if req.SortOrder != "" && req.SortOrder != "asc" && req.SortOrder != "desc" {
+		return nil, fmt.Errorf("%w: sort_order must be asc or desc", ErrInvalidRequest)
+	}
+	if req.Limit < 0 || req.Offset < 0 {
+		return nil, fmt.Errorf("%w: limit and offset cannot be negative", ErrInvalidRequest)
+	}
+	filter := store.ListSessionsFilter{
+		Status:           req.Status,
+		ModelPrefix:      req.ModelPrefix,
+		WorkingDirPrefix: req.WorkingDirPrefix,
+		RunID:            req.RunID,
+		TagFilters:       req.Tags,
+		SortBy:           req.SortBy,
+		SortAscending:    req.SortOrder == "asc",
+		Limit:            req.Limit,
+		Offset:           req.Offset,
+	}
+	// Session timestamps are stored in local time and compared as text
+	if req.CreatedAfter != "" {
+		after, err := time.Parse(time.RFC3339, req.CreatedAfter)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid created_after: %w", ErrInvalidRequest, err)
+		}
+		filter.CreatedAfter = after.Local()
+	}
+	if req.CreatedBefore != "" {
+		before, err := time.Parse(time.RFC3339, req.CreatedBefore)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid created_before: %w", ErrInvalidRequest, err)
+		}
+		filter.CreatedBefore = before.Local()
+	}
+	if !filter.CreatedAfter.IsZero() && !filter.CreatedBefore.IsZero() && !filter.CreatedAfter.Before(filter.CreatedBefore) {
+		return nil, fmt.Errorf("%w: created_after must be before created_before", ErrInvalidRequest)
+	}
 
-	return &ListSessionsResponse{
-		Sessions: sessions,
-	}, nil
+	sessions, err := h.store.ListSessions(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	// Unpaged listings already hold every matching session
+	totalCount := len(sessions)
+	if req.Limit > 0 || req.Offset > 0 {
+		if totalCount, err = h.store.CountSessions(ctx, filter); err != nil {
+			return nil, fmt.Errorf("failed to count sessions: %w", err)
+		}
+	}
+
+	resp := &ListSessionsResponse{Sessions: make([]SessionState, len(sessions)), TotalCount: totalCount}
+	for i, sess := range sessions {
+		resp.Sessions[i] = sessionToState(sess)
+	}
+	return resp, nil
 }
 
 // GetSessionLeavesRequest is the request for getting session leaves
@@ -171,7 +309,7 @@ func (h *SessionHandlers) HandleGetSessionLeaves(ctx context.Context, params jso
 	var req GetSessionLeavesRequest
 	if params != nil {
 		if err := json.Unmarshal(params, &req); err != nil {
-			return nil, fmt.Errorf("invalid request: %w", err)
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 		}
 	}
 
@@ -240,56 +378,419 @@ func shouldIncludeSessionRPC(s session.Info, filterType string) bool {
 func (h *SessionHandlers) HandleGetConversation(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req GetConversationRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	// Validate that either SessionID or ClaudeSessionID is provided
 	if req.SessionID == "" && req.ClaudeSessionID == "" {
-		return nil, fmt.Errorf("either session_id or claude_session_id is required")
+		return nil, fmt.Errorf("%w: either session_id or claude_session_id is required", ErrInvalidRequest)
+	}
+	if req.AnchorEventID != 0 && req.AnchorToolID != "" {
+		return nil, fmt.Errorf("%w: only one of anchor_event_id or anchor_tool_id may be provided", ErrInvalidRequest)
+	}
+	if err := validateOrdering(req.Ordering); err != nil {
+		return nil, err
+	}
+	if req.TranslateTo != "" && h.translator == nil {
+		return nil, fmt.Errorf("%w: translation not available: no translation provider is configured", ErrUnavailable)
+	}
+	if req.Limit < 0 || req.AfterSequence < 0 {
+		return nil, fmt.Errorf("%w: limit and after_sequence cannot be negative", ErrInvalidRequest)
+	}
+	if req.Limit > 0 {
+		// Both need the whole conversation to place events correctly
+		if req.AnchorEventID != 0 || req.AnchorToolID != "" {
+			return nil, fmt.Errorf("%w: limit cannot be combined with an anchor", ErrInvalidRequest)
+		}
+		if req.Ordering == OrderingLogical {
+			return nil, fmt.Errorf("%w: limit cannot be combined with logical ordering", ErrInvalidRequest)
+		}
+	}
+
+	ranged := req.FromSequence != nil || req.ToSequence != nil
+	if ranged {
+		if req.SessionID == "" {
+			return nil, fmt.Errorf("%w: from_sequence and to_sequence require session_id", ErrInvalidRequest)
+		}
+		if req.Limit > 0 || req.AfterSequence > 0 {
+			return nil, fmt.Errorf("%w: from_sequence and to_sequence cannot be combined with limit or after_sequence", ErrInvalidRequest)
+		}
+	}
+	if req.SinceSequence != nil {
+		if *req.SinceSequence < 0 {
+			return nil, fmt.Errorf("%w: since_sequence cannot be negative", ErrInvalidRequest)
+		}
+		if ranged || req.Limit > 0 || req.AfterSequence > 0 {
+			return nil, fmt.Errorf("%w: since_sequence cannot be combined with from_sequence, to_sequence, limit or after_sequence", ErrInvalidRequest)
+		}
+	}
+
+	page := store.ConversationPage{AfterSequence: req.AfterSequence}
+	if req.Limit > 0 {
+		// One extra event tells whether another page follows
+		page.Limit = req.Limit + 1
 	}
 
 	var events []*store.ConversationEvent
 	var err error
 
-	if req.ClaudeSessionID != "" {
+	if ranged {
+		fromSeq, toSeq := 0, math.MaxInt32
+		if req.FromSequence != nil {
+			fromSeq = *req.FromSequence
+		}
+		if req.ToSequence != nil {
+			toSeq = *req.ToSequence
+		}
+		events, err = h.store.GetConversationRange(ctx, req.SessionID, fromSeq, toSeq)
+	} else if req.SinceSequence != nil {
+		// A Claude session's sequence numbers already exclude its parents'
+		if req.ClaudeSessionID != "" {
+			events, err = h.store.GetConversation(ctx, req.ClaudeSessionID, store.ConversationPage{AfterSequence: *req.SinceSequence})
+		} else {
+			events, err = h.store.GetConversationRange(ctx, req.SessionID, *req.SinceSequence+1, math.MaxInt32)
+		}
+	} else if req.ClaudeSessionID != "" {
 		// Get conversation by Claude session ID
-		events, err = h.store.GetConversation(ctx, req.ClaudeSessionID)
+		events, err = h.store.GetConversation(ctx, req.ClaudeSessionID, page)
 	} else {
 		// Get conversation by session ID - always returns full history including parents
-		events, err = h.store.GetSessionConversation(ctx, req.SessionID)
+		events, err = h.store.GetSessionConversation(ctx, req.SessionID, page)
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get conversation: %w", err)
 	}
 
+	var nextSequence int
+	if req.Limit > 0 && len(events) > req.Limit {
+		events = events[:req.Limit]
+		// Session histories are paged by position, see store.ConversationPage
+		if req.ClaudeSessionID != "" {
+			nextSequence = events[len(events)-1].Sequence
+		} else {
+			nextSequence = req.AfterSequence + req.Limit
+		}
+	}
+
+	lastSequence := 0
+	if req.SinceSequence != nil {
+		lastSequence = *req.SinceSequence
+	}
+	for _, event := range events {
+		// Parent events are numbered by their own Claude sessions
+		if req.ClaudeSessionID != "" || event.SessionID == req.SessionID {
+			lastSequence = max(lastSequence, event.Sequence)
+		}
+	}
+
+	// Requests for the whole conversation already hold every event
+	totalCount := len(events)
+	if page != (store.ConversationPage{}) || ranged || req.SinceSequence != nil {
+		if req.ClaudeSessionID != "" {
+			totalCount, err = h.store.CountConversation(ctx, req.ClaudeSessionID)
+		} else {
+			totalCount, err = h.store.CountSessionConversation(ctx, req.SessionID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to count conversation: %w", err)
+		}
+	}
+
+	events = filterEventsByLanguage(events, req.Language)
+
+	var positions map[int64]eventPosition
+	if req.Ordering == OrderingLogical {
+		events, positions = orderByLogicalTurn(events)
+	}
+
+	if req.DecisionsOnly {
+		decisions, err := h.toolDecisions(ctx, events)
+		if err != nil {
+			return nil, err
+		}
+		return &GetConversationResponse{
+			Events:       []ConversationEvent{},
+			Decisions:    decisions,
+			NextSequence: nextSequence,
+			TotalCount:   totalCount,
+			HasMore:      nextSequence != 0,
+			LastSequence: lastSequence,
+		}, nil
+	}
+
+	resp := &GetConversationResponse{
+		NextSequence: nextSequence,
+		TotalCount:   totalCount,
+		HasMore:      nextSequence != 0,
+		LastSequence: lastSequence,
+	}
+	if req.AnchorEventID != 0 || req.AnchorToolID != "" {
+		var anchorIndex int
+		events, anchorIndex, err = anchorWindow(events, req)
+		if err != nil {
+			return nil, err
+		}
+		resp.AnchorIndex = &anchorIndex
+	}
+
+	if req.IncludeCostAudit {
+		resp.CostAudit = auditTurnCosts(events)
+	}
+	if req.GroupByToolChain {
+		resp.ToolChains = groupToolChains(events)
+	}
+
+	annotations, err := h.eventAnnotations(ctx, events)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert store events to RPC events
 	rpcEvents := make([]ConversationEvent, len(events))
 	for i, event := range events {
-		rpcEvents[i] = ConversationEvent{
-			ID:                event.ID,
-			SessionID:         event.SessionID,
-			ClaudeSessionID:   event.ClaudeSessionID,
-			Sequence:          event.Sequence,
-			EventType:         event.EventType,
-			CreatedAt:         event.CreatedAt.Format(time.RFC3339),
-			Role:              event.Role,
-			Content:           event.Content,
-			ToolID:            event.ToolID,
-			ToolName:          event.ToolName,
-			ToolInputJSON:     event.ToolInputJSON,
-			ParentToolUseID:   event.ParentToolUseID,
-			ToolResultForID:   event.ToolResultForID,
-			ToolResultContent: event.ToolResultContent,
-			IsCompleted:       event.IsCompleted,
-			ApprovalStatus:    event.ApprovalStatus,
-			ApprovalID:        event.ApprovalID,
-		}
-	}
-
-	return &GetConversationResponse{
-		Events: rpcEvents,
-	}, nil
+		rpcEvents[i] = eventToRPC(event)
+		if pos, ok := positions[event.ID]; ok {
+			rpcEvents[i].Turn = pos.Turn
+			rpcEvents[i].TurnOrder = pos.Order
+		}
+		rpcEvents[i].Annotations = annotations[event.ID]
+	}
+	if req.TranslateTo != "" {
+		if err := h.translateMessages(ctx, rpcEvents, req.TranslateTo); err != nil {
+			return nil, err
+		}
+	}
+	resp.Events = rpcEvents
+
+	return resp, nil
+}
+
+// auditTurnCosts reconciles per-turn costs from events against the session
+// total. Conversation events don't record per-turn cost yet, so the audit is
+// always reported as unavailable.
+func auditTurnCosts(events []*store.ConversationEvent) *CostAudit {
+	return &CostAudit{
+		Available: false,
+		Reason:    "per-turn cost data is not recorded for this conversation",
+	}
+}
+
+// filterEventsByLanguage keeps events tagged with language, or all events
+// when language is empty
+func filterEventsByLanguage(events []*store.ConversationEvent, language string) []*store.ConversationEvent {
+	if language == "" {
+		return events
+	}
+	filtered := make([]*store.ConversationEvent, 0, len(events))
+	for _, event := range events {
+		if strings.EqualFold(event.Language, language) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+const (
+	// maxBatchConversations caps the sessions in one getConversations call
+	maxBatchConversations = 50
+	// maxBatchEvents caps the events returned across all sessions in one
+	// getConversations call
+	maxBatchEvents = 10000
+)
+
+// HandleGetConversations fetches the conversations of several sessions in one
+// call. A session that can't be fetched gets an error entry rather than
+// failing the batch. Once maxBatchEvents is reached, later conversations are
+// truncated.
+func (h *SessionHandlers) HandleGetConversations(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetConversationsRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if len(req.SessionIDs) == 0 {
+		return nil, fmt.Errorf("%w: session_ids is required and cannot be empty", ErrInvalidRequest)
+	}
+	if len(req.SessionIDs) > maxBatchConversations {
+		return nil, fmt.Errorf("%w: at most %d session_ids may be requested", ErrInvalidRequest, maxBatchConversations)
+	}
+	if req.Offset < 0 || req.Limit < 0 {
+		return nil, fmt.Errorf("%w: offset and limit cannot be negative", ErrInvalidRequest)
+	}
+
+	resp := &GetConversationsResponse{
+		Conversations: make(map[string]SessionConversation, len(req.SessionIDs)),
+	}
+	remaining := maxBatchEvents
+	for _, sessionID := range req.SessionIDs {
+		if _, seen := resp.Conversations[sessionID]; seen {
+			continue
+		}
+
+		events, err := h.store.GetSessionConversation(ctx, sessionID, store.ConversationPage{})
+		if err != nil {
+			resp.Conversations[sessionID] = SessionConversation{
+				Events: []ConversationEvent{},
+				Error:  fmt.Sprintf("failed to get conversation: %v", err),
+			}
+			continue
+		}
+
+		events = filterEventsByLanguage(events, req.Language)
+		if req.Offset >= len(events) {
+			events = nil
+		} else {
+			events = events[req.Offset:]
+		}
+		if req.Limit > 0 && len(events) > req.Limit {
+			events = events[:req.Limit]
+		}
+
+		var conversation SessionConversation
+		if len(events) > remaining {
+			events = events[:remaining]
+			conversation.Truncated = true
+		}
+		remaining -= len(events)
+
+		conversation.Events = make([]ConversationEvent, len(events))
+		for i, event := range events {
+			conversation.Events[i] = eventToRPC(event)
+		}
+		resp.Conversations[sessionID] = conversation
+	}
+
+	return resp, nil
+}
+
+const (
+	defaultAnchorWindow = 5
+	maxAnchorWindow     = 100
+)
+
+// anchorWindow narrows events to the requested anchor and its neighbours,
+// returning the window and the anchor's position within it
+func anchorWindow(events []*store.ConversationEvent, req GetConversationRequest) ([]*store.ConversationEvent, int, error) {
+	before, after := defaultAnchorWindow, defaultAnchorWindow
+	if req.Before != nil {
+		before = *req.Before
+	}
+	if req.After != nil {
+		after = *req.After
+	}
+	if before < 0 || after < 0 {
+		return nil, 0, fmt.Errorf("%w: before and after cannot be negative", ErrInvalidRequest)
+	}
+	before = min(before, maxAnchorWindow)
+	after = min(after, maxAnchorWindow)
+
+	anchor := -1
+	for i, event := range events {
+		if req.AnchorEventID != 0 && event.ID == req.AnchorEventID {
+			anchor = i
+			break
+		}
+		if req.AnchorToolID != "" && event.EventType == store.EventTypeToolCall && event.ToolID == req.AnchorToolID {
+			anchor = i
+			break
+		}
+	}
+	if anchor < 0 {
+		// The events are already scoped to the requested session, so a miss
+		// means the anchor doesn't exist or belongs to another session
+		if req.AnchorEventID != 0 {
+			return nil, 0, fmt.Errorf("%w: anchor event %d in conversation", ErrNotFound, req.AnchorEventID)
+		}
+		return nil, 0, fmt.Errorf("%w: anchor tool call %s in conversation", ErrNotFound, req.AnchorToolID)
+	}
+
+	start := max(0, anchor-before)
+	end := min(len(events), anchor+after+1)
+	return events[start:end], anchor - start, nil
+}
+
+// eventToRPC converts a stored conversation event to its RPC representation
+func eventToRPC(event *store.ConversationEvent) ConversationEvent {
+	return ConversationEvent{
+		ID:                event.ID,
+		SessionID:         event.SessionID,
+		ClaudeSessionID:   event.ClaudeSessionID,
+		Sequence:          event.Sequence,
+		EventType:         event.EventType,
+		CreatedAt:         event.CreatedAt.Format(time.RFC3339),
+		Role:              event.Role,
+		Content:           event.Content,
+		ToolID:            event.ToolID,
+		ToolName:          event.ToolName,
+		ToolInputJSON:     event.ToolInputJSON,
+		ParentToolUseID:   event.ParentToolUseID,
+		ToolResultForID:   event.ToolResultForID,
+		ToolResultContent: event.ToolResultContent,
+		ToolResultBytes:   event.ToolResultBytes,
+		ToolResultTokens:  event.ToolResultTokens,
+		ToolResultJSON:    event.ToolResultJSON,
+		ToolError:         event.ToolError,
+		IsCompleted:       event.IsCompleted,
+		ApprovalStatus:    event.ApprovalStatus,
+		ApprovalID:        event.ApprovalID,
+		Permalink:         event.Permalink,
+		Language:          event.Language,
+		ToolCacheHit:      event.ToolCacheHit,
+		Truncated:         event.Truncated,
+		ContentHash:       event.Digest(),
+	}
+}
+
+// HandleGetEventByPermalink resolves an event permalink to the event and its
+// surrounding conversation context
+func (h *SessionHandlers) HandleGetEventByPermalink(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetEventByPermalinkRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.Permalink == "" {
+		return nil, fmt.Errorf("%w: permalink is required", ErrInvalidRequest)
+	}
+	contextSize := req.ContextSize
+	if contextSize <= 0 {
+		contextSize = 2
+	}
+	if contextSize > 50 {
+		contextSize = 50
+	}
+
+	event, err := h.store.GetEventByPermalink(ctx, req.Permalink)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	events, err := h.store.GetConversation(ctx, event.ClaudeSessionID, store.ConversationPage{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	resp := &GetEventByPermalinkResponse{
+		Event:     eventToRPC(event),
+		SessionID: event.SessionID,
+		Before:    []ConversationEvent{},
+		After:     []ConversationEvent{},
+	}
+	for i, e := range events {
+		if e.ID != event.ID {
+			continue
+		}
+		for _, b := range events[max(0, i-contextSize):i] {
+			resp.Before = append(resp.Before, eventToRPC(b))
+		}
+		for _, a := range events[i+1 : min(len(events), i+1+contextSize)] {
+			resp.After = append(resp.After, eventToRPC(a))
+		}
+		break
+	}
+
+	return resp, nil
 }
 
 // HandleGetSessionSnapshots retrieves all file snapshots for a session
@@ -299,21 +800,21 @@ func (h *SessionHandlers) HandleGetSessionSnapshots(ctx context.Context, params
 	// Parse request
 	var req GetSessionSnapshotsRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	slog.Info("parsed request", "session_id", req.SessionID)
 
 	// Validate required fields
 	if req.SessionID == "" {
-		return nil, fmt.Errorf("session_id is required")
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
 	}
 
 	// Verify session exists
 	_, err := h.store.GetSession(ctx, req.SessionID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("session not found")
+			return nil, fmt.Errorf("session %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
@@ -347,12 +848,12 @@ func (h *SessionHandlers) HandleGetSessionSnapshots(ctx context.Context, params
 func (h *SessionHandlers) HandleGetSessionState(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req GetSessionStateRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	// Validate required fields
 	if req.SessionID == "" {
-		return nil, fmt.Errorf("session_id is required")
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
 	}
 
 	// Get session from store
@@ -361,7 +862,107 @@ func (h *SessionHandlers) HandleGetSessionState(ctx context.Context, params json
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
-	// Convert to RPC session state
+	state := sessionToState(session)
+
+	// Throughput is informational, so failures leave it unset
+	throughput, err := h.store.GetSessionThroughput(ctx, req.SessionID)
+	if err != nil {
+		slog.Warn("failed to get session throughput", "session_id", req.SessionID, "error", err)
+	} else if tps, ok := throughput.TokensPerSecond(); ok {
+		state.ThroughputTokensPerSec = &tps
+	}
+
+	// Likewise for the share of context consumed by tool output
+	toolStats, err := h.store.GetToolOutputStats(ctx, req.SessionID)
+	if err != nil {
+		slog.Warn("failed to get tool output stats", "session_id", req.SessionID, "error", err)
+	}
+	for _, st := range toolStats {
+		state.ToolResultBytes += st.Bytes
+		state.ToolResultTokens += st.Tokens
+	}
+
+	state.ToolQuota = h.toolQuotaStatus(ctx, session)
+
+	response := &GetSessionStateResponse{
+		Session: state,
+	}
+	// Event counts are informational too
+	if response.EventCounts, err = h.store.CountEventsByType(ctx, req.SessionID); err != nil {
+		slog.Warn("failed to count session events", "session_id", req.SessionID, "error", err)
+	}
+	if h.eventBus != nil {
+		response.ActiveSubscribers = h.eventBus.GetSessionSubscriberCount(session.ID, session.RunID)
+	}
+	return response, nil
+}
+
+// maxBatchSessionStates caps the sessions in one batchGetSessionState call
+const maxBatchSessionStates = 100
+
+// HandleBatchGetSessionState fetches the states of several sessions with a
+// single query. Like listSessions, it leaves out the fields that take further
+// queries per session, such as throughput. Missing sessions are listed in
+// NotFound rather than failing the call.
+func (h *SessionHandlers) HandleBatchGetSessionState(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req BatchGetSessionStateRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if len(req.SessionIDs) == 0 {
+		return nil, fmt.Errorf("%w: session_ids is required and cannot be empty", ErrInvalidRequest)
+	}
+	if len(req.SessionIDs) > maxBatchSessionStates {
+		return nil, fmt.Errorf("%w: at most %d session_ids may be requested", ErrInvalidRequest, maxBatchSessionStates)
+	}
+
+	sessions, err := h.store.ListSessions(ctx, store.ListSessionsFilter{IDs: req.SessionIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	byID := make(map[string]*store.Session, len(sessions))
+	for _, sess := range sessions {
+		byID[sess.ID] = sess
+	}
+
+	resp := &BatchGetSessionStateResponse{Sessions: []SessionState{}, NotFound: []string{}}
+	seen := make(map[string]bool, len(req.SessionIDs))
+	for _, id := range req.SessionIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if sess, ok := byID[id]; ok {
+			resp.Sessions = append(resp.Sessions, sessionToState(sess))
+		} else {
+			resp.NotFound = append(resp.NotFound, id)
+		}
+	}
+	return resp, nil
+}
+
+// toolQuotaStatus returns the remaining tool quota of a session, or nil if it
+// has no quota. Like the other informational fields, failures leave it unset.
+func (h *SessionHandlers) toolQuotaStatus(ctx context.Context, sess *store.Session) *session.ToolQuotaStatus {
+	quota, err := session.ParseToolQuota(sess.ToolQuota)
+	if err != nil {
+		slog.Warn("failed to parse tool quota", "session_id", sess.ID, "error", err)
+		return nil
+	}
+	if quota.IsUnlimited() {
+		return nil
+	}
+	approvals, err := h.store.GetSessionApprovals(ctx, sess.ID)
+	if err != nil {
+		slog.Warn("failed to get approvals for tool quota", "session_id", sess.ID, "error", err)
+		return nil
+	}
+	return session.ComputeToolQuotaStatus(quota, approvals)
+}
+
+// sessionToState converts a stored session to its RPC representation
+func sessionToState(session *store.Session) SessionState {
 	state := SessionState{
 		ID:                         session.ID,
 		RunID:                      session.RunID,
@@ -379,6 +980,7 @@ func (h *SessionHandlers) HandleGetSessionState(ctx context.Context, params json
 		AutoAcceptEdits:            session.AutoAcceptEdits,
 		DangerouslySkipPermissions: session.DangerouslySkipPermissions,
 		Archived:                   session.Archived,
+		Imported:                   session.Imported,
 	}
 
 	// Set optional fields
@@ -391,6 +993,7 @@ func (h *SessionHandlers) HandleGetSessionState(ctx context.Context, params json
 	if session.CostUSD != nil {
 		state.CostUSD = *session.CostUSD
 	}
+	state.MaxCostUSD = session.MaxCostUSD
 	if session.InputTokens != nil {
 		state.InputTokens = *session.InputTokens
 	}
@@ -411,25 +1014,79 @@ func (h *SessionHandlers) HandleGetSessionState(ctx context.Context, params json
 	if session.DurationMS != nil {
 		state.DurationMS = *session.DurationMS
 	}
+	state.QueuedDurationMS, state.FirstTokenLatencyMS = sessionLatency(session)
+	if session.NumTurns != nil {
+		state.NumTurns = *session.NumTurns
+	}
+	state.NeedsAttention, state.AttentionReason = sessionAttention(session, time.Now())
 
-	return &GetSessionStateResponse{
-		Session: state,
-	}, nil
+	return state
+}
+
+// GetToolOutputStatsRequest is the request for tool output volume by tool
+type GetToolOutputStatsRequest struct {
+	SessionID string `json:"session_id,omitempty"` // Empty aggregates across all sessions
+	Limit     int    `json:"limit,omitempty"`
+}
+
+// ToolOutputStats is the RPC representation of store.ToolOutputStats
+type ToolOutputStats struct {
+	ToolName  string `json:"tool_name"`
+	Results   int    `json:"results"`
+	Bytes     int64  `json:"bytes"`
+	Tokens    int64  `json:"tokens"`
+	MaxTokens int    `json:"max_tokens"`
+}
+
+// GetToolOutputStatsResponse is the response for tool output stats
+type GetToolOutputStatsResponse struct {
+	Tools []ToolOutputStats `json:"tools"`
+}
+
+// HandleGetToolOutputStats returns tools ranked by the estimated tokens their
+// results consumed
+func (h *SessionHandlers) HandleGetToolOutputStats(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetToolOutputStatsRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+	}
+
+	stats, err := h.store.GetToolOutputStats(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool output stats: %w", err)
+	}
+	if req.Limit > 0 && len(stats) > req.Limit {
+		stats = stats[:req.Limit]
+	}
+
+	resp := &GetToolOutputStatsResponse{Tools: make([]ToolOutputStats, len(stats))}
+	for i, st := range stats {
+		resp.Tools[i] = ToolOutputStats{
+			ToolName:  st.ToolName,
+			Results:   st.Results,
+			Bytes:     st.Bytes,
+			Tokens:    st.Tokens,
+			MaxTokens: st.MaxTokens,
+		}
+	}
+	return resp, nil
 }
 
 // HandleContinueSession handles the ContinueSession RPC method
 func (h *SessionHandlers) HandleContinueSession(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req ContinueSessionRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	// Validate required fields
 	if req.SessionID == "" {
-		return nil, fmt.Errorf("session_id is required")
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
 	}
 	if req.Query == "" {
-		return nil, fmt.Errorf("query is required")
+		return nil, fmt.Errorf("%w: query is required", ErrInvalidRequest)
 	}
 
 	// Build session config for manager
@@ -454,7 +1111,7 @@ func (h *SessionHandlers) HandleContinueSession(ctx context.Context, params json
 	if req.MCPConfig != "" {
 		var mcpConfig claudecode.MCPConfig
 		if err := json.Unmarshal([]byte(req.MCPConfig), &mcpConfig); err != nil {
-			return nil, fmt.Errorf("invalid mcp_config JSON: %w", err)
+			return nil, fmt.Errorf("%w: invalid mcp_config JSON: %w", ErrInvalidRequest, err)
 		}
 		config.MCPConfig = &mcpConfig
 	}
@@ -477,12 +1134,12 @@ func (h *SessionHandlers) HandleContinueSession(ctx context.Context, params json
 func (h *SessionHandlers) HandleInterruptSession(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req InterruptSessionRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	// Validate required fields
 	if req.SessionID == "" {
-		return nil, fmt.Errorf("session_id is required")
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
 	}
 
 	// Get session from store
@@ -493,7 +1150,7 @@ func (h *SessionHandlers) HandleInterruptSession(ctx context.Context, params jso
 
 	// Validate session is running
 	if session.Status != store.SessionStatusRunning {
-		return nil, fmt.Errorf("cannot interrupt session with status %s (must be running)", session.Status)
+		return nil, fmt.Errorf("%w: cannot interrupt session with status %s (must be running)", ErrConflict, session.Status)
 	}
 
 	// Interrupt session
@@ -508,16 +1165,117 @@ func (h *SessionHandlers) HandleInterruptSession(ctx context.Context, params jso
 	}, nil
 }
 
+// HandleCancelSession handles the CancelSession RPC method. Unlike
+// interruptSession, the session is stopped for good and can't be resumed.
+func (h *SessionHandlers) HandleCancelSession(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req CancelSessionRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+
+	session, err := h.store.GetSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Only sessions that have started and not yet finished can be cancelled
+	switch session.Status {
+	case store.SessionStatusQueued, store.SessionStatusStarting, store.SessionStatusRunning,
+		store.SessionStatusWaitingInput, store.SessionStatusInterrupting:
+	case store.SessionStatusDraft:
+		return nil, fmt.Errorf("%w: cannot cancel a draft session (discard it instead)", ErrConflict)
+	default:
+		return nil, fmt.Errorf("%w: cannot cancel session with status %s (already finished)", ErrConflict, session.Status)
+	}
+
+	if err := h.manager.CancelSession(ctx, req.SessionID); err != nil {
+		return nil, fmt.Errorf("failed to cancel session: %w", err)
+	}
+
+	return &CancelSessionResponse{
+		Success:   true,
+		SessionID: req.SessionID,
+		Status:    store.SessionStatusCancelled,
+	}, nil
+}
+
+// HandleDeleteSession permanently deletes a session with its conversation,
+// approvals and attachments, for erasure requests. Sessions with a live
+// process must be cancelled first, which force does on the caller's behalf.
+// Subscribers are told with a session_deleted event.
+func (h *SessionHandlers) HandleDeleteSession(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req DeleteSessionRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+	if !req.Confirm {
+		return nil, fmt.Errorf("%w: confirm must be true to permanently delete a session", ErrInvalidRequest)
+	}
+
+	// A soft-deleted session's data is still stored, so it can be erased too
+	session, err := h.store.GetSessionIncludingDeleted(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	switch session.Status {
+	case store.SessionStatusQueued, store.SessionStatusStarting, store.SessionStatusRunning,
+		store.SessionStatusWaitingInput, store.SessionStatusInterrupting:
+		if !req.Force {
+			return nil, fmt.Errorf("%w: cannot delete session with status %s (cancel it first or set force)", ErrConflict, session.Status)
+		}
+		// Cancelling rather than interrupting kills the process before it
+		// returns, so nothing writes to the session once it is deleted
+		if err := h.manager.CancelSession(ctx, req.SessionID); err != nil {
+			return nil, fmt.Errorf("failed to cancel session: %w", err)
+		}
+	}
+
+	if h.attachments != nil {
+		// Keep the session if its attachments can't be deleted, so they stay
+		// referenced and the deletion can be retried
+		if err := h.attachments.DeleteSessionAttachments(ctx, req.SessionID); err != nil {
+			return nil, err
+		}
+	}
+	if err := h.store.DeleteSessionData(ctx, req.SessionID); err != nil {
+		return nil, fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	if h.eventBus != nil {
+		h.eventBus.Publish(bus.Event{
+			Type: bus.EventSessionDeleted,
+			Data: map[string]interface{}{
+				"session_id": session.ID,
+				"run_id":     session.RunID,
+			},
+		})
+	}
+
+	return &DeleteSessionResponse{
+		Success:   true,
+		SessionID: req.SessionID,
+	}, nil
+}
+
 // HandleUpdateSessionSettings handles the UpdateSessionSettings RPC method
 func (h *SessionHandlers) HandleUpdateSessionSettings(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req UpdateSessionSettingsRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	// Validate required fields
 	if req.SessionID == "" {
-		return nil, fmt.Errorf("session_id is required")
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
 	}
 
 	// Get current session to verify it exists
@@ -526,7 +1284,7 @@ func (h *SessionHandlers) HandleUpdateSessionSettings(ctx context.Context, param
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 	if session == nil {
-		return nil, fmt.Errorf("session not found")
+		return nil, fmt.Errorf("session %w", ErrNotFound)
 	}
 
 	// Update session settings
@@ -614,7 +1372,7 @@ func (h *SessionHandlers) HandleUpdateSessionSettings(ctx context.Context, param
 func (h *SessionHandlers) HandleGetRecentPaths(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req GetRecentPathsRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	limit := req.Limit
@@ -644,12 +1402,12 @@ func (h *SessionHandlers) HandleGetRecentPaths(ctx context.Context, params json.
 func (h *SessionHandlers) HandleUpdateSessionTitle(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req UpdateSessionTitleRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	// Validate required fields
 	if req.SessionID == "" {
-		return nil, fmt.Errorf("session_id is required")
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
 	}
 
 	// Update session title
@@ -692,12 +1450,12 @@ type ArchiveSessionResponse struct {
 func (h *SessionHandlers) HandleArchiveSession(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req ArchiveSessionRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	// Validate required fields
 	if req.SessionID == "" {
-		return nil, fmt.Errorf("session_id is required")
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
 	}
 
 	// Update session in store
@@ -729,12 +1487,12 @@ type BulkArchiveSessionsResponse struct {
 func (h *SessionHandlers) HandleBulkArchiveSessions(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req BulkArchiveSessionsRequest
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
 	// Validate required fields
 	if len(req.SessionIDs) == 0 {
-		return nil, fmt.Errorf("session_ids is required and cannot be empty")
+		return nil, fmt.Errorf("%w: session_ids is required and cannot be empty", ErrInvalidRequest)
 	}
 
 	var failedSessions []string
@@ -760,17 +1518,62 @@ func (h *SessionHandlers) HandleBulkArchiveSessions(ctx context.Context, params
 
 // Register registers all session handlers with the RPC server
 func (h *SessionHandlers) Register(server *Server) {
-	server.Register("launchSession", h.HandleLaunchSession)
-	server.Register("listSessions", h.HandleListSessions)
-	server.Register("getSessionLeaves", h.HandleGetSessionLeaves)
-	server.Register("getConversation", h.HandleGetConversation)
-	server.Register("getSessionState", h.HandleGetSessionState)
-	server.Register("continueSession", h.HandleContinueSession)
-	server.Register("interruptSession", h.HandleInterruptSession)
-	server.Register("getSessionSnapshots", h.HandleGetSessionSnapshots)
-	server.Register("updateSessionSettings", h.HandleUpdateSessionSettings)
-	server.Register("updateSessionTitle", h.HandleUpdateSessionTitle)
-	server.Register("getRecentPaths", h.HandleGetRecentPaths)
-	server.Register("archiveSession", h.HandleArchiveSession)
-	server.Register("bulkArchiveSessions", h.HandleBulkArchiveSessions)
+	server.RegisterMutating("launchSession", h.wrap("launchSession", h.HandleLaunchSession))
+	server.Register("listSessions", h.wrap("listSessions", h.HandleListSessions))
+	server.Register("getDaemonHealth", h.wrap("getDaemonHealth", h.HandleGetDaemonHealth))
+	server.Register("getSessionLeaves", h.wrap("getSessionLeaves", h.HandleGetSessionLeaves))
+	server.Register("getConversation", h.wrap("getConversation", h.HandleGetConversation))
+	server.Register("getConversations", h.wrap("getConversations", h.HandleGetConversations))
+	server.Register("getEventByPermalink", h.wrap("getEventByPermalink", h.HandleGetEventByPermalink))
+	server.Register("searchEvents", h.wrap("searchEvents", h.HandleSearchEvents))
+	server.Register("listToolCalls", h.wrap("listToolCalls", h.HandleListToolCalls))
+	server.Register("getSessionState", h.wrap("getSessionState", h.HandleGetSessionState))
+	server.Register("batchGetSessionState", h.wrap("batchGetSessionState", h.HandleBatchGetSessionState))
+	server.Register("getSessionStateAt", h.wrap("getSessionStateAt", h.HandleGetSessionStateAt))
+	server.Register("getSessionTimeline", h.wrap("getSessionTimeline", h.HandleGetSessionTimeline))
+	server.Register("getSessionGraph", h.wrap("getSessionGraph", h.HandleGetSessionGraph))
+	server.Register("getToolOutputStats", h.wrap("getToolOutputStats", h.HandleGetToolOutputStats))
+	server.Register("projectSessionCost", h.wrap("projectSessionCost", h.HandleProjectSessionCost))
+	server.Register("getConversationMetrics", h.wrap("getConversationMetrics", h.HandleGetConversationMetrics))
+	server.Register("getConversationStats", h.wrap("getConversationStats", h.HandleGetConversationStats))
+	server.Register("diffConversations", h.wrap("diffConversations", h.HandleDiffConversations))
+	server.Register("getSessionMetrics", h.wrap("getSessionMetrics", h.HandleGetSessionMetrics))
+	server.Register("listTools", h.wrap("listTools", h.HandleListTools))
+	server.Register("getRunSessions", h.wrap("getRunSessions", h.HandleGetRunSessions))
+	server.Register("getCostReport", h.wrap("getCostReport", h.HandleGetCostReport))
+	server.Register("listModelUsage", h.wrap("listModelUsage", h.HandleListModelUsage))
+	server.Register("getUsageReport", h.wrap("getUsageReport", h.HandleGetUsageReport))
+	server.Register("getAnomalousSessions", h.wrap("getAnomalousSessions", h.HandleGetAnomalousSessions))
+	server.Register("compareSessionCosts", h.wrap("compareSessionCosts", h.HandleCompareSessionCosts))
+	server.Register("exportConversation", h.wrap("exportConversation", h.HandleExportConversation))
+	server.GateMethod("exportConversation", feature.ConversationExport)
+	server.RegisterConnHandler("subscribeSessionState", h.metrics.wrapConn("subscribeSessionState", h.SubscribeSessionStateConn))
+	server.RegisterConnHandler("streamConversation", h.metrics.wrapConn("streamConversation", h.HandleStreamConversation))
+	server.RegisterMutating("continueSession", h.wrap("continueSession", h.HandleContinueSession))
+	server.RegisterMutating("interruptSession", h.wrap("interruptSession", h.HandleInterruptSession))
+	server.RegisterMutating("cancelSession", h.wrap("cancelSession", h.HandleCancelSession))
+	server.RegisterMutating("deleteSession", h.wrap("deleteSession", h.HandleDeleteSession))
+	server.RegisterMutating("softDeleteSession", h.wrap("softDeleteSession", h.HandleSoftDeleteSession))
+	server.RegisterMutating("restoreSession", h.wrap("restoreSession", h.HandleRestoreSession))
+	server.RegisterMutating("vacuum", h.wrap("vacuum", h.HandleVacuum))
+	server.RegisterMutating("runRetention", h.wrap("runRetention", h.HandleRunRetention))
+	server.RegisterMutating("moveSessionsToArchive", h.wrap("moveSessionsToArchive", h.HandleMoveSessionsToArchive))
+	server.RegisterMutating("restoreArchivedSession", h.wrap("restoreArchivedSession", h.HandleRestoreArchivedSession))
+	server.RegisterMutating("setSessionTags", h.wrap("setSessionTags", h.HandleSetSessionTags))
+	server.Register("getSessionTags", h.wrap("getSessionTags", h.HandleGetSessionTags))
+	server.RegisterMutating("setSessionBudget", h.wrap("setSessionBudget", h.HandleSetSessionBudget))
+	server.RegisterMutating("addAnnotation", h.wrap("addAnnotation", h.HandleAddAnnotation))
+	server.Register("listAnnotations", h.wrap("listAnnotations", h.HandleListAnnotations))
+	server.Register("getSessionSnapshots", h.wrap("getSessionSnapshots", h.HandleGetSessionSnapshots))
+	server.RegisterMutating("updateSessionSettings", h.wrap("updateSessionSettings", h.HandleUpdateSessionSettings))
+	server.RegisterMutating("updateSessionTitle", h.wrap("updateSessionTitle", h.HandleUpdateSessionTitle))
+	server.Register("getRecentPaths", h.wrap("getRecentPaths", h.HandleGetRecentPaths))
+	server.RegisterMutating("archiveSession", h.wrap("archiveSession", h.HandleArchiveSession))
+	server.RegisterMutating("bulkArchiveSessions", h.wrap("bulkArchiveSessions", h.HandleBulkArchiveSessions))
+	server.RegisterMutating("importConversation", h.wrap("importConversation", h.HandleImportConversation))
+	server.RegisterMutating("forkSession", h.wrap("forkSession", h.HandleForkSession))
+	server.RegisterMutating("cloneSessionConfig", h.wrap("cloneSessionConfig", h.HandleCloneSessionConfig))
+	server.RegisterMutating("replaySession", h.wrap("replaySession", h.HandleReplaySession))
+	server.RegisterMutating("registerWebhook", h.wrap("registerWebhook", h.HandleRegisterWebhook))
+	server.RegisterMutating("deleteWebhook", h.wrap("deleteWebhook", h.HandleDeleteWebhook))
 }
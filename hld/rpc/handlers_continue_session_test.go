@@ -18,7 +18,7 @@ func TestHandleContinueSession(t *testing.T) {
 	mockManager := session.NewMockSessionManager(ctrl)
 	mockStore := store.NewMockConversationStore(ctrl)
 	mockApprovalManager := approval.NewMockManager(ctrl)
-	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager)
+	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager, nil, nil)
 
 	testCases := []struct {
 		name          string
@@ -189,7 +189,7 @@ func TestHandleContinueSession_ToolsConfiguration(t *testing.T) {
 	mockManager := session.NewMockSessionManager(ctrl)
 	mockStore := store.NewMockConversationStore(ctrl)
 	mockApprovalManager := approval.NewMockManager(ctrl)
-	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager)
+	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager, nil, nil)
 
 	request := `{
 		"session_id": "parent-tools",
@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/humanlayer/humanlayer/hld/approval"
+	"github.com/humanlayer/humanlayer/hld/bus"
 	"github.com/humanlayer/humanlayer/hld/session"
 	"github.com/humanlayer/humanlayer/hld/store"
 	"github.com/stretchr/testify/assert"
@@ -22,9 +24,10 @@ func TestHandleGetConversation(t *testing.T) {
 
 	mockManager := session.NewMockSessionManager(ctrl)
 	mockStore := store.NewMockConversationStore(ctrl)
+	mockStore.EXPECT().GetAnnotationsForSession(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 	mockApprovalManager := approval.NewMockManager(ctrl)
 
-	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager)
+	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager, nil, nil)
 
 	t.Run("get conversation by session ID", func(t *testing.T) {
 		sessionID := "sess-123"
@@ -56,7 +59,7 @@ func TestHandleGetConversation(t *testing.T) {
 		}
 
 		mockStore.EXPECT().
-			GetSessionConversation(gomock.Any(), sessionID).
+			GetSessionConversation(gomock.Any(), sessionID, gomock.Any()).
 			Return(events, nil)
 
 		req := GetConversationRequest{
@@ -92,7 +95,7 @@ func TestHandleGetConversation(t *testing.T) {
 		}
 
 		mockStore.EXPECT().
-			GetConversation(gomock.Any(), claudeSessionID).
+			GetConversation(gomock.Any(), claudeSessionID, gomock.Any()).
 			Return(events, nil)
 
 		req := GetConversationRequest{
@@ -115,13 +118,13 @@ func TestHandleGetConversation(t *testing.T) {
 
 		_, err := handlers.HandleGetConversation(context.Background(), reqJSON)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "either session_id or claude_session_id is required")
+		assert.ErrorIs(t, err, ErrInvalidRequest)
 	})
 
 	t.Run("invalid JSON", func(t *testing.T) {
 		_, err := handlers.HandleGetConversation(context.Background(), []byte(`invalid json`))
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "invalid request")
+		assert.ErrorIs(t, err, ErrInvalidRequest)
 	})
 }
 
@@ -133,7 +136,7 @@ func TestHandleGetSessionState(t *testing.T) {
 	mockStore := store.NewMockConversationStore(ctrl)
 	mockApprovalManager := approval.NewMockManager(ctrl)
 
-	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager)
+	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager, nil, nil)
 
 	t.Run("successful get session state", func(t *testing.T) {
 		sessionID := "sess-123"
@@ -161,6 +164,18 @@ func TestHandleGetSessionState(t *testing.T) {
 		mockStore.EXPECT().
 			GetSession(gomock.Any(), sessionID).
 			Return(dbSession, nil)
+		mockStore.EXPECT().
+			GetSessionThroughput(gomock.Any(), sessionID).
+			Return(&store.SessionThroughput{Turns: 2, OutputTokens: 300, GenerationMS: 6000}, nil)
+		mockStore.EXPECT().
+			GetToolOutputStats(gomock.Any(), sessionID).
+			Return([]*store.ToolOutputStats{
+				{ToolName: "Bash", Results: 2, Bytes: 8000, Tokens: 2000},
+				{ToolName: "Read", Results: 1, Bytes: 400, Tokens: 100},
+			}, nil)
+		mockStore.EXPECT().
+			CountEventsByType(gomock.Any(), sessionID).
+			Return(map[string]int{"message": 4, "tool_call": 3, "tool_result": 3}, nil)
 
 		req := GetSessionStateRequest{
 			SessionID: sessionID,
@@ -172,6 +187,11 @@ func TestHandleGetSessionState(t *testing.T) {
 
 		resp, ok := result.(*GetSessionStateResponse)
 		require.True(t, ok)
+		require.NotNil(t, resp.Session.ThroughputTokensPerSec)
+		assert.Equal(t, 50.0, *resp.Session.ThroughputTokensPerSec)
+		assert.Equal(t, int64(8400), resp.Session.ToolResultBytes)
+		assert.Equal(t, int64(2100), resp.Session.ToolResultTokens)
+		assert.Equal(t, map[string]int{"message": 4, "tool_call": 3, "tool_result": 3}, resp.EventCounts)
 		assert.Equal(t, sessionID, resp.Session.ID)
 		assert.Equal(t, "run-456", resp.Session.RunID)
 		assert.Equal(t, "claude-789", resp.Session.ClaudeSessionID)
@@ -198,6 +218,15 @@ func TestHandleGetSessionState(t *testing.T) {
 		mockStore.EXPECT().
 			GetSession(gomock.Any(), sessionID).
 			Return(dbSession, nil)
+		mockStore.EXPECT().
+			GetSessionThroughput(gomock.Any(), sessionID).
+			Return(&store.SessionThroughput{}, nil)
+		mockStore.EXPECT().
+			GetToolOutputStats(gomock.Any(), sessionID).
+			Return(nil, nil)
+		mockStore.EXPECT().
+			CountEventsByType(gomock.Any(), sessionID).
+			Return(map[string]int{}, nil)
 
 		req := GetSessionStateRequest{
 			SessionID: sessionID,
@@ -209,6 +238,7 @@ func TestHandleGetSessionState(t *testing.T) {
 
 		resp, ok := result.(*GetSessionStateResponse)
 		require.True(t, ok)
+		assert.Nil(t, resp.Session.ThroughputTokensPerSec, "no completed turns means throughput is unavailable")
 		assert.Equal(t, store.SessionStatusFailed, resp.Session.Status)
 		assert.Equal(t, "Connection timeout", resp.Session.ErrorMessage)
 	})
@@ -219,7 +249,7 @@ func TestHandleGetSessionState(t *testing.T) {
 
 		_, err := handlers.HandleGetSessionState(context.Background(), reqJSON)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "session_id is required")
+		assert.ErrorIs(t, err, ErrInvalidRequest)
 	})
 
 	t.Run("session not found", func(t *testing.T) {
@@ -236,7 +266,201 @@ func TestHandleGetSessionState(t *testing.T) {
 
 		_, err := handlers.HandleGetSessionState(context.Background(), reqJSON)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "failed to get session")
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func TestHandleBatchGetSessionState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+
+	t.Run("looks up every session in one query", func(t *testing.T) {
+		mockStore.EXPECT().
+			ListSessions(gomock.Any(), store.ListSessionsFilter{IDs: []string{"sess-b", "sess-missing", "sess-a", "sess-b"}}).
+			Return([]*store.Session{
+				{ID: "sess-a", RunID: "run-a", Status: store.SessionStatusRunning, CreatedAt: time.Now(), LastActivityAt: time.Now()},
+				{ID: "sess-b", RunID: "run-b", Status: store.SessionStatusCompleted, CreatedAt: time.Now(), LastActivityAt: time.Now()},
+			}, nil).
+			Times(1)
+
+		result, err := handlers.HandleBatchGetSessionState(context.Background(),
+			json.RawMessage(`{"session_ids":["sess-b","sess-missing","sess-a","sess-b"]}`))
+		require.NoError(t, err)
+		resp := result.(*BatchGetSessionStateResponse)
+
+		require.Len(t, resp.Sessions, 2)
+		assert.Equal(t, "sess-b", resp.Sessions[0].ID, "request order")
+		assert.Equal(t, store.SessionStatusCompleted, resp.Sessions[0].Status)
+		assert.Equal(t, "sess-a", resp.Sessions[1].ID)
+		assert.Equal(t, []string{"sess-missing"}, resp.NotFound)
+	})
+
+	t.Run("caps the batch size", func(t *testing.T) {
+		ids := make([]string, maxBatchSessionStates+1)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("sess-%d", i)
+		}
+		params, err := json.Marshal(BatchGetSessionStateRequest{SessionIDs: ids})
+		require.NoError(t, err)
+		_, err = handlers.HandleBatchGetSessionState(context.Background(), params)
+		assert.EqualError(t, err, "invalid request: at most 100 session_ids may be requested")
+
+		_, err = handlers.HandleBatchGetSessionState(context.Background(), json.RawMessage(`{"session_ids":[]}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestHandleListSessions(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	for i, sess := range []*store.Session{
+		{ID: "sess-a", RunID: "run-1", Status: store.SessionStatusCompleted, Model: "claude-sonnet-4", WorkingDir: "/src/app"},
+		{ID: "sess-b", RunID: "run-2", Status: store.SessionStatusRunning, Model: "claude-opus-4", WorkingDir: "/src/api"},
+		{ID: "sess-c", RunID: "run-3", Status: store.SessionStatusFailed, Model: "claude-sonnet-4-5", WorkingDir: "/srcs"},
+		{ID: "sess-d", RunID: "run-4", Status: store.SessionStatusCompleted, Model: "claude_sonnet", WorkingDir: "/other/src"},
+	} {
+		sess.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		sess.LastActivityAt = sess.CreatedAt
+		require.NoError(t, sqliteStore.CreateSession(ctx, sess))
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	list := func(t *testing.T, params string) []string {
+		t.Helper()
+		result, err := handlers.HandleListSessions(ctx, json.RawMessage(params))
+		require.NoError(t, err)
+		var ids []string
+		for _, sess := range result.(*ListSessionsResponse).Sessions {
+			ids = append(ids, sess.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"sess-d", "sess-c", "sess-b", "sess-a"}, list(t, `{}`), "most recently active first")
+	assert.Equal(t, []string{"sess-d", "sess-c", "sess-a"}, list(t, `{"status":["completed","failed"]}`))
+	// The prefix is matched literally, so _ isn't a wildcard
+	assert.Equal(t, []string{"sess-c", "sess-a"}, list(t, `{"model_prefix":"claude-sonnet"}`))
+	assert.Equal(t, []string{"sess-b"}, list(t, `{"run_id":"run-2"}`))
+	assert.Equal(t, []string{"sess-c"}, list(t, fmt.Sprintf(`{"created_after":%q,"created_before":%q}`,
+		base.Add(90*time.Minute).UTC().Format(time.RFC3339), base.Add(150*time.Minute).UTC().Format(time.RFC3339))))
+	assert.Equal(t, []string{"sess-a"}, list(t, fmt.Sprintf(`{"status":["completed"],"created_before":%q}`,
+		base.Add(time.Hour).Format(time.RFC3339))))
+	assert.Empty(t, list(t, `{"status":["draft"]}`))
+	assert.Equal(t, []string{"sess-b", "sess-a"}, list(t, `{"working_dir_prefix":"/src/"}`))
+	// Filters are combined
+	assert.Equal(t, []string{"sess-a"}, list(t, `{"working_dir_prefix":"/src/","status":["completed"]}`))
+	assert.Empty(t, list(t, `{"working_dir_prefix":"/src/","status":["completed"],"run_id":"run-4"}`))
+
+	result, err := handlers.HandleListSessions(ctx, json.RawMessage(`{"run_id":"run-3"}`))
+	require.NoError(t, err)
+	sess := result.(*ListSessionsResponse).Sessions[0]
+	assert.Equal(t, store.SessionStatusFailed, sess.Status)
+	assert.Equal(t, "claude-sonnet-4-5", sess.Model)
+
+	_, err = handlers.HandleListSessions(ctx, json.RawMessage(`{"status":["completed","done"]}`))
+	assert.EqualError(t, err, `invalid request: unknown status "done", must be one of draft, queued, starting, running, completed, `+
+		`failed, waiting_input, interrupting, interrupted, discarded, cancelled`)
+
+	for _, params := range []string{
+		`{"created_after":"yesterday"}`,
+		`{"created_after":"2026-03-02T00:00:00Z","created_before":"2026-03-01T00:00:00Z"}`,
+	} {
+		_, err := handlers.HandleListSessions(ctx, json.RawMessage(params))
+		assert.Error(t, err, params)
+	}
+}
+
+func TestHandleListSessionsSortAndPage(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	intPtr := func(n int) *int { return &n }
+	floatPtr := func(f float64) *float64 { return &f }
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	for i, u := range []store.SessionUpdate{
+		{CostUSD: floatPtr(0.3), InputTokens: intPtr(100), OutputTokens: intPtr(50)},
+		{CacheReadInputTokens: intPtr(1000)},
+		{CostUSD: floatPtr(1.2)},
+		{CostUSD: floatPtr(0.05), InputTokens: intPtr(10)},
+		{CostUSD: floatPtr(0.7), OutputTokens: intPtr(400), CacheCreationInputTokens: intPtr(100)},
+	} {
+		id := fmt.Sprintf("sess-%d", i+1)
+		status := store.SessionStatusCompleted
+		if i == 3 {
+			status = store.SessionStatusFailed
+		}
+		// Created in order, but last active in reverse
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: id, RunID: "run-" + id, Status: status,
+			CreatedAt: base.Add(time.Duration(i) * time.Hour), LastActivityAt: base.Add(time.Duration(5-i) * time.Hour),
+		}))
+		require.NoError(t, sqliteStore.UpdateSession(ctx, id, u))
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	list := func(t *testing.T, params string) ([]string, int) {
+		t.Helper()
+		result, err := handlers.HandleListSessions(ctx, json.RawMessage(params))
+		require.NoError(t, err)
+		resp := result.(*ListSessionsResponse)
+		ids := []string{}
+		for _, sess := range resp.Sessions {
+			ids = append(ids, sess.ID)
+		}
+		return ids, resp.TotalCount
+	}
+
+	for params, want := range map[string][]string{
+		`{}`:                       {"sess-1", "sess-2", "sess-3", "sess-4", "sess-5"},
+		`{"sort_by":"created_at"}`: {"sess-5", "sess-4", "sess-3", "sess-2", "sess-1"},
+		`{"sort_by":"created_at","sort_order":"asc"}`:       {"sess-1", "sess-2", "sess-3", "sess-4", "sess-5"},
+		`{"sort_by":"last_activity_at","sort_order":"asc"}`: {"sess-5", "sess-4", "sess-3", "sess-2", "sess-1"},
+		`{"sort_by":"cost_usd"}`:                            {"sess-3", "sess-5", "sess-1", "sess-4", "sess-2"},
+		`{"sort_by":"total_tokens"}`:                        {"sess-2", "sess-5", "sess-1", "sess-4", "sess-3"},
+		`{"sort_by":"total_tokens","sort_order":"asc"}`:     {"sess-3", "sess-4", "sess-1", "sess-5", "sess-2"},
+	} {
+		ids, total := list(t, params)
+		assert.Equal(t, want, ids, params)
+		assert.Equal(t, 5, total, params)
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		var joined []string
+		for offset := 0; offset < 5; offset += 2 {
+			ids, total := list(t, fmt.Sprintf(`{"sort_by":"cost_usd","limit":2,"offset":%d}`, offset))
+			assert.LessOrEqual(t, len(ids), 2)
+			assert.Equal(t, 5, total)
+			joined = append(joined, ids...)
+		}
+		assert.Equal(t, []string{"sess-3", "sess-5", "sess-1", "sess-4", "sess-2"}, joined)
+
+		ids, total := list(t, `{"limit":2,"offset":10}`)
+		assert.Empty(t, ids)
+		assert.Equal(t, 5, total)
+
+		ids, total = list(t, `{"status":["completed"],"sort_by":"created_at","limit":2,"offset":2}`)
+		assert.Equal(t, []string{"sess-2", "sess-1"}, ids)
+		assert.Equal(t, 4, total, "the count applies the filters")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for params, want := range map[string]string{
+			`{"sort_by":"name"}`:  `invalid request: unknown sort_by "name", must be one of created_at, last_activity_at, cost_usd, total_tokens`,
+			`{"sort_order":"up"}`: "invalid request: sort_order must be asc or desc",
+			`{"offset":-1}`:       "invalid request: limit and offset cannot be negative",
+		} {
+			_, err := handlers.HandleListSessions(ctx, json.RawMessage(params))
+			assert.EqualError(t, err, want, params)
+		}
 	})
 }
 
@@ -248,7 +472,7 @@ func TestHandleGetSessionLeaves(t *testing.T) {
 	mockStore := store.NewMockConversationStore(ctrl)
 	mockApprovalManager := approval.NewMockManager(ctrl)
 
-	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager)
+	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager, nil, nil)
 
 	t.Run("empty sessions list", func(t *testing.T) {
 		// Mock empty sessions
@@ -492,7 +716,7 @@ func TestHandleInterruptSession(t *testing.T) {
 	mockManager := session.NewMockSessionManager(ctrl)
 	mockStore := store.NewMockConversationStore(ctrl)
 	mockApprovalManager := approval.NewMockManager(ctrl)
-	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager)
+	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager, nil, nil)
 
 	t.Run("successful interrupt", func(t *testing.T) {
 		sessionID := "test-123"
@@ -526,7 +750,7 @@ func TestHandleInterruptSession(t *testing.T) {
 
 		_, err := handlers.HandleInterruptSession(context.Background(), reqJSON)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "session_id is required")
+		assert.ErrorIs(t, err, ErrInvalidRequest)
 	})
 
 	t.Run("session not found", func(t *testing.T) {
@@ -534,7 +758,7 @@ func TestHandleInterruptSession(t *testing.T) {
 
 		mockStore.EXPECT().
 			GetSession(gomock.Any(), sessionID).
-			Return(nil, fmt.Errorf("session not found"))
+			Return(nil, &store.NotFoundError{Type: "session", ID: "sess-123"})
 
 		req := InterruptSessionRequest{
 			SessionID: sessionID,
@@ -543,7 +767,7 @@ func TestHandleInterruptSession(t *testing.T) {
 
 		_, err := handlers.HandleInterruptSession(context.Background(), reqJSON)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "failed to get session")
+		assert.ErrorIs(t, err, store.ErrNotFound)
 	})
 
 	t.Run("session not running", func(t *testing.T) {
@@ -563,7 +787,7 @@ func TestHandleInterruptSession(t *testing.T) {
 
 		_, err := handlers.HandleInterruptSession(context.Background(), reqJSON)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "cannot interrupt session with status completed")
+		assert.ErrorIs(t, err, ErrConflict)
 	})
 
 	t.Run("interrupt fails", func(t *testing.T) {
@@ -578,7 +802,7 @@ func TestHandleInterruptSession(t *testing.T) {
 
 		mockManager.EXPECT().
 			InterruptSession(gomock.Any(), sessionID).
-			Return(fmt.Errorf("interrupt failed"))
+			Return(assert.AnError)
 
 		req := InterruptSessionRequest{
 			SessionID: sessionID,
@@ -587,7 +811,176 @@ func TestHandleInterruptSession(t *testing.T) {
 
 		_, err := handlers.HandleInterruptSession(context.Background(), reqJSON)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "failed to interrupt session")
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func TestHandleCancelSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockManager := session.NewMockSessionManager(ctrl)
+	mockStore := store.NewMockConversationStore(ctrl)
+	handlers := NewSessionHandlers(mockManager, mockStore, nil, nil, nil)
+
+	for _, status := range []string{store.SessionStatusRunning, store.SessionStatusWaitingInput} {
+		t.Run("cancels a "+status+" session", func(t *testing.T) {
+			mockStore.EXPECT().GetSession(gomock.Any(), "sess-1").
+				Return(&store.Session{ID: "sess-1", Status: status}, nil)
+			mockManager.EXPECT().CancelSession(gomock.Any(), "sess-1").Return(nil).Times(1)
+
+			result, err := handlers.HandleCancelSession(context.Background(), json.RawMessage(`{"session_id":"sess-1"}`))
+			require.NoError(t, err)
+			resp := result.(*CancelSessionResponse)
+			assert.True(t, resp.Success)
+			assert.Equal(t, store.SessionStatusCancelled, resp.Status)
+		})
+	}
+
+	t.Run("rejects finished sessions", func(t *testing.T) {
+		for _, status := range []string{store.SessionStatusCompleted, store.SessionStatusFailed, store.SessionStatusCancelled} {
+			mockStore.EXPECT().GetSession(gomock.Any(), "sess-1").
+				Return(&store.Session{ID: "sess-1", Status: status}, nil)
+
+			_, err := handlers.HandleCancelSession(context.Background(), json.RawMessage(`{"session_id":"sess-1"}`))
+			assert.ErrorIs(t, err, ErrConflict)
+			assert.EqualError(t, err, "conflict: cannot cancel session with status "+status+" (already finished)")
+		}
+	})
+
+	t.Run("rejects drafts", func(t *testing.T) {
+		mockStore.EXPECT().GetSession(gomock.Any(), "sess-1").
+			Return(&store.Session{ID: "sess-1", Status: store.SessionStatusDraft}, nil)
+
+		_, err := handlers.HandleCancelSession(context.Background(), json.RawMessage(`{"session_id":"sess-1"}`))
+		assert.EqualError(t, err, "conflict: cannot cancel a draft session (discard it instead)")
+	})
+
+	t.Run("reports a failed cancel", func(t *testing.T) {
+		mockStore.EXPECT().GetSession(gomock.Any(), "sess-1").
+			Return(&store.Session{ID: "sess-1", Status: store.SessionStatusRunning}, nil)
+		mockManager.EXPECT().CancelSession(gomock.Any(), "sess-1").Return(fmt.Errorf("failed to kill Claude session: denied"))
+
+		_, err := handlers.HandleCancelSession(context.Background(), json.RawMessage(`{"session_id":"sess-1"}`))
+		assert.EqualError(t, err, "failed to cancel session: failed to kill Claude session: denied")
+	})
+
+	t.Run("missing session ID", func(t *testing.T) {
+		_, err := handlers.HandleCancelSession(context.Background(), json.RawMessage(`{}`))
+		assert.EqualError(t, err, "invalid request: session_id is required")
+	})
+}
+
+type fakeAttachmentPruner struct{ deleted []string }
+
+func (f *fakeAttachmentPruner) DeleteSessionAttachments(ctx context.Context, sessionID string) error {
+	f.deleted = append(f.deleted, sessionID)
+	return nil
+}
+
+func TestHandleDeleteSession(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	for id, status := range map[string]string{"sess-done": store.SessionStatusCompleted, "sess-live": store.SessionStatusRunning} {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: id, RunID: "run-" + id, ClaudeSessionID: "claude-" + id, Query: "q", Status: status,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+			SessionID: id, ClaudeSessionID: "claude-" + id, EventType: store.EventTypeMessage, Role: "user", Content: "personal data",
+		}))
+	}
+	require.NoError(t, sqliteStore.CreateApproval(ctx, &store.Approval{
+		ID: "appr-done", RunID: "run-sess-done", SessionID: "sess-done", Status: store.ApprovalStatusLocalPending,
+		CreatedAt: time.Now(), ToolName: "Bash", ToolInput: json.RawMessage(`{}`),
+	}))
+	pruner := &fakeAttachmentPruner{}
+	eventBus := bus.NewEventBus()
+	deleted := eventBus.Subscribe(ctx, bus.EventFilter{Types: []bus.EventType{bus.EventSessionDeleted}})
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+	handlers.SetAttachmentPruner(pruner)
+	handlers.SetEventBus(eventBus)
+
+	t.Run("requires confirmation", func(t *testing.T) {
+		_, err := handlers.HandleDeleteSession(ctx, json.RawMessage(`{"session_id":"sess-done"}`))
+		assert.ErrorContains(t, err, "confirm must be true")
+		_, err = sqliteStore.GetSession(ctx, "sess-done")
+		assert.NoError(t, err)
+	})
+
+	t.Run("refuses a running session", func(t *testing.T) {
+		_, err := handlers.HandleDeleteSession(ctx, json.RawMessage(`{"session_id":"sess-live","confirm":true}`))
+		assert.ErrorContains(t, err, "cannot delete session with status running")
+		_, err = sqliteStore.GetSession(ctx, "sess-live")
+		assert.NoError(t, err)
+	})
+
+	t.Run("deletes the session and its events", func(t *testing.T) {
+		result, err := handlers.HandleDeleteSession(ctx, json.RawMessage(`{"session_id":"sess-done","confirm":true}`))
+		require.NoError(t, err)
+		assert.Equal(t, &DeleteSessionResponse{Success: true, SessionID: "sess-done"}, result)
+		assert.Equal(t, []string{"sess-done"}, pruner.deleted)
+
+		var notFound *store.NotFoundError
+		_, err = sqliteStore.GetSession(ctx, "sess-done")
+		assert.ErrorAs(t, err, &notFound)
+		_, err = sqliteStore.GetSessionConversation(ctx, "sess-done", store.ConversationPage{})
+		assert.ErrorAs(t, err, &notFound)
+
+		events, err := sqliteStore.GetConversation(ctx, "claude-sess-done", store.ConversationPage{})
+		require.NoError(t, err)
+		assert.Empty(t, events)
+		approvals, err := sqliteStore.GetPendingApprovals(ctx, "sess-done")
+		require.NoError(t, err)
+		assert.Empty(t, approvals)
+
+		select {
+		case event := <-deleted.Channel:
+			assert.Equal(t, "sess-done", event.Data["session_id"])
+			assert.Equal(t, "run-sess-done", event.Data["run_id"])
+		case <-time.After(time.Second):
+			t.Fatal("no session_deleted event")
+		}
+	})
+
+	t.Run("force cancels a running session first", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		manager := session.NewMockSessionManager(ctrl)
+		manager.EXPECT().CancelSession(gomock.Any(), "sess-live").DoAndReturn(func(ctx context.Context, id string) error {
+			status := store.SessionStatusCancelled
+			return sqliteStore.UpdateSession(ctx, id, store.SessionUpdate{Status: &status})
+		})
+		forcing := NewSessionHandlers(manager, sqliteStore, nil, nil, nil)
+
+		_, err := forcing.HandleDeleteSession(ctx, json.RawMessage(`{"session_id":"sess-live","confirm":true,"force":true}`))
+		require.NoError(t, err)
+		var notFound *store.NotFoundError
+		_, err = sqliteStore.GetSession(ctx, "sess-live")
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("deletes a soft-deleted session", func(t *testing.T) {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: "sess-hidden", RunID: "run-sess-hidden", ClaudeSessionID: "claude-sess-hidden", Query: "q",
+			Status: store.SessionStatusCompleted, CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+		require.NoError(t, sqliteStore.SoftDeleteSession(ctx, "sess-hidden"))
+
+		result, err := handlers.HandleDeleteSession(ctx, json.RawMessage(`{"session_id":"sess-hidden","confirm":true}`))
+		require.NoError(t, err)
+		assert.Equal(t, &DeleteSessionResponse{Success: true, SessionID: "sess-hidden"}, result)
+		var notFound *store.NotFoundError
+		_, err = sqliteStore.GetSessionIncludingDeleted(ctx, "sess-hidden")
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		_, err := handlers.HandleDeleteSession(ctx, json.RawMessage(`{"session_id":"sess-done","confirm":true}`))
+		var notFound *store.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
 	})
 }
 
@@ -599,7 +992,7 @@ func TestHandleGetSessionSnapshots(t *testing.T) {
 	mockStore := store.NewMockConversationStore(ctrl)
 	mockApprovalManager := approval.NewMockManager(ctrl)
 
-	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager)
+	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager, nil, nil)
 
 	t.Run("successful retrieval", func(t *testing.T) {
 		sessionID := "test-session"
@@ -680,7 +1073,7 @@ func TestHandleGetSessionSnapshots(t *testing.T) {
 
 		_, err := handlers.HandleGetSessionSnapshots(context.Background(), reqJSON)
 		require.Error(t, err)
-		assert.Contains(t, err.Error(), "session not found")
+		assert.ErrorIs(t, err, ErrNotFound)
 	})
 
 	t.Run("missing session_id", func(t *testing.T) {
@@ -689,7 +1082,7 @@ func TestHandleGetSessionSnapshots(t *testing.T) {
 
 		_, err := handlers.HandleGetSessionSnapshots(context.Background(), reqJSON)
 		require.Error(t, err)
-		assert.Contains(t, err.Error(), "session_id is required")
+		assert.ErrorIs(t, err, ErrInvalidRequest)
 	})
 
 	t.Run("empty snapshots", func(t *testing.T) {
@@ -723,7 +1116,7 @@ func TestHandleGetSessionSnapshots(t *testing.T) {
 	t.Run("invalid JSON", func(t *testing.T) {
 		_, err := handlers.HandleGetSessionSnapshots(context.Background(), []byte(`invalid json`))
 		require.Error(t, err)
-		assert.Contains(t, err.Error(), "invalid request")
+		assert.ErrorIs(t, err, ErrInvalidRequest)
 	})
 
 	t.Run("store error", func(t *testing.T) {
@@ -738,7 +1131,7 @@ func TestHandleGetSessionSnapshots(t *testing.T) {
 
 		mockStore.EXPECT().
 			GetFileSnapshots(gomock.Any(), sessionID).
-			Return(nil, fmt.Errorf("database error"))
+			Return(nil, assert.AnError)
 
 		req := GetSessionSnapshotsRequest{
 			SessionID: sessionID,
@@ -747,7 +1140,7 @@ func TestHandleGetSessionSnapshots(t *testing.T) {
 
 		_, err := handlers.HandleGetSessionSnapshots(context.Background(), reqJSON)
 		require.Error(t, err)
-		assert.Contains(t, err.Error(), "failed to get snapshots")
+		assert.ErrorIs(t, err, assert.AnError)
 	})
 }
 
@@ -759,7 +1152,7 @@ func TestHandleUpdateSessionSettings(t *testing.T) {
 	mockStore := store.NewMockConversationStore(ctrl)
 	mockApprovalManager := approval.NewMockManager(ctrl)
 
-	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager)
+	handlers := NewSessionHandlers(mockManager, mockStore, mockApprovalManager, nil, nil)
 
 	t.Run("auto-approve pending approvals when bypass permissions enabled", func(t *testing.T) {
 		sessionID := "sess-auto"
@@ -905,6 +1298,617 @@ func TestHandleUpdateSessionSettings(t *testing.T) {
 
 		_, err := handlers.HandleUpdateSessionSettings(context.Background(), reqJSON)
 		require.Error(t, err)
-		assert.Contains(t, err.Error(), "failed to get session")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func TestHandleGetEventByPermalink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+
+	var events []*store.ConversationEvent
+	for i := 1; i <= 5; i++ {
+		events = append(events, &store.ConversationEvent{
+			ID:              int64(i),
+			SessionID:       "sess-1",
+			ClaudeSessionID: "claude-1",
+			Sequence:        i,
+			EventType:       store.EventTypeMessage,
+			Permalink:       fmt.Sprintf("evt_%d", i),
+			CreatedAt:       time.Now(),
+		})
+	}
+
+	t.Run("returns event with surrounding context", func(t *testing.T) {
+		mockStore.EXPECT().GetEventByPermalink(gomock.Any(), "evt_2").Return(events[1], nil)
+		mockStore.EXPECT().GetConversation(gomock.Any(), "claude-1", gomock.Any()).Return(events, nil)
+
+		result, err := handlers.HandleGetEventByPermalink(context.Background(), json.RawMessage(`{"permalink":"evt_2"}`))
+		require.NoError(t, err)
+
+		resp := result.(*GetEventByPermalinkResponse)
+		assert.Equal(t, int64(2), resp.Event.ID)
+		assert.Equal(t, "evt_2", resp.Event.Permalink)
+		assert.Equal(t, "sess-1", resp.SessionID)
+		require.Len(t, resp.Before, 1)
+		assert.Equal(t, 1, resp.Before[0].Sequence)
+		require.Len(t, resp.After, 2)
+		assert.Equal(t, 3, resp.After[0].Sequence)
+		assert.Equal(t, 4, resp.After[1].Sequence)
+	})
+
+	t.Run("missing permalink", func(t *testing.T) {
+		_, err := handlers.HandleGetEventByPermalink(context.Background(), json.RawMessage(`{}`))
+		assert.EqualError(t, err, "invalid request: permalink is required")
+	})
+
+	t.Run("unknown permalink", func(t *testing.T) {
+		mockStore.EXPECT().GetEventByPermalink(gomock.Any(), "evt_missing").
+			Return(nil, &store.NotFoundError{Type: "event", ID: "evt_missing"})
+
+		_, err := handlers.HandleGetEventByPermalink(context.Background(), json.RawMessage(`{"permalink":"evt_missing"}`))
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
+
+func TestHandleGetConversationAnchor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	mockStore.EXPECT().GetAnnotationsForSession(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+
+	var events []*store.ConversationEvent
+	for i := 1; i <= 10; i++ {
+		event := &store.ConversationEvent{
+			ID:              int64(100 + i),
+			SessionID:       "sess-1",
+			ClaudeSessionID: "claude-1",
+			Sequence:        i,
+			EventType:       store.EventTypeMessage,
+			CreatedAt:       time.Now(),
+		}
+		if i == 6 {
+			event.EventType = store.EventTypeToolCall
+			event.ToolID = "tool-6"
+		}
+		events = append(events, event)
+	}
+
+	t.Run("anchors to event id", func(t *testing.T) {
+		mockStore.EXPECT().GetSessionConversation(gomock.Any(), "sess-1", gomock.Any()).Return(events, nil)
+
+		result, err := handlers.HandleGetConversation(context.Background(),
+			json.RawMessage(`{"session_id":"sess-1","anchor_event_id":102,"before":3,"after":2}`))
+		require.NoError(t, err)
+
+		resp := result.(*GetConversationResponse)
+		require.Len(t, resp.Events, 4) // clamped at the start: 1 before, anchor, 2 after
+		require.NotNil(t, resp.AnchorIndex)
+		assert.Equal(t, 1, *resp.AnchorIndex)
+		assert.Equal(t, int64(102), resp.Events[*resp.AnchorIndex].ID)
+	})
+
+	t.Run("anchors to tool call", func(t *testing.T) {
+		mockStore.EXPECT().GetSessionConversation(gomock.Any(), "sess-1", gomock.Any()).Return(events, nil)
+
+		result, err := handlers.HandleGetConversation(context.Background(),
+			json.RawMessage(`{"session_id":"sess-1","anchor_tool_id":"tool-6","before":1,"after":0}`))
+		require.NoError(t, err)
+
+		resp := result.(*GetConversationResponse)
+		require.Len(t, resp.Events, 2)
+		assert.Equal(t, 5, resp.Events[0].Sequence)
+		assert.Equal(t, "tool-6", resp.Events[*resp.AnchorIndex].ToolID)
+	})
+
+	t.Run("anchor from another session", func(t *testing.T) {
+		mockStore.EXPECT().GetSessionConversation(gomock.Any(), "sess-1", gomock.Any()).Return(events, nil)
+
+		_, err := handlers.HandleGetConversation(context.Background(),
+			json.RawMessage(`{"session_id":"sess-1","anchor_event_id":999}`))
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("conflicting anchors", func(t *testing.T) {
+		_, err := handlers.HandleGetConversation(context.Background(),
+			json.RawMessage(`{"session_id":"sess-1","anchor_event_id":101,"anchor_tool_id":"tool-6"}`))
+		assert.EqualError(t, err, "invalid request: only one of anchor_event_id or anchor_tool_id may be provided")
+	})
+}
+
+func TestHandleGetConversationLanguageFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	mockStore.EXPECT().GetAnnotationsForSession(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+
+	events := []*store.ConversationEvent{
+		{ID: 1, SessionID: "sess-1", Sequence: 1, EventType: store.EventTypeMessage, Role: "user", Content: "hola", Language: "es"},
+		{ID: 2, SessionID: "sess-1", Sequence: 2, EventType: store.EventTypeMessage, Role: "assistant", Content: "hello", Language: "en"},
+		{ID: 3, SessionID: "sess-1", Sequence: 3, EventType: store.EventTypeToolCall, ToolID: "t1"},
+		{ID: 4, SessionID: "sess-1", Sequence: 4, EventType: store.EventTypeMessage, Role: "assistant", Content: "adiós", Language: "es"},
+	}
+
+	t.Run("keeps only matching events", func(t *testing.T) {
+		mockStore.EXPECT().GetSessionConversation(gomock.Any(), "sess-1", gomock.Any()).Return(events, nil)
+
+		result, err := handlers.HandleGetConversation(context.Background(),
+			json.RawMessage(`{"session_id":"sess-1","language":"ES"}`))
+		require.NoError(t, err)
+
+		resp := result.(*GetConversationResponse)
+		require.Len(t, resp.Events, 2)
+		assert.Equal(t, int64(1), resp.Events[0].ID)
+		assert.Equal(t, "es", resp.Events[0].Language)
+		assert.Equal(t, int64(4), resp.Events[1].ID)
+	})
+
+	t.Run("anchor must match the filter", func(t *testing.T) {
+		mockStore.EXPECT().GetSessionConversation(gomock.Any(), "sess-1", gomock.Any()).Return(events, nil)
+
+		_, err := handlers.HandleGetConversation(context.Background(),
+			json.RawMessage(`{"session_id":"sess-1","language":"es","anchor_event_id":2}`))
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestHandleGetConversationPagination(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	for _, sess := range []*store.Session{
+		{ID: "sess-parent", RunID: "run-parent", ClaudeSessionID: "claude-parent"},
+		{ID: "sess-child", RunID: "run-child", ClaudeSessionID: "claude-child", ParentSessionID: "sess-parent"},
+	} {
+		sess.Status, sess.CreatedAt, sess.LastActivityAt = store.SessionStatusCompleted, time.Now(), time.Now()
+		require.NoError(t, sqliteStore.CreateSession(ctx, sess))
+		for i := 0; i < 5; i++ {
+			require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+				SessionID:       sess.ID,
+				ClaudeSessionID: sess.ClaudeSessionID,
+				EventType:       store.EventTypeMessage,
+				Role:            "assistant",
+				Content:         fmt.Sprintf("%s %d", sess.ID, i),
+			}))
+		}
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	get := func(t *testing.T, params string) *GetConversationResponse {
+		t.Helper()
+		result, err := handlers.HandleGetConversation(ctx, json.RawMessage(params))
+		require.NoError(t, err)
+		return result.(*GetConversationResponse)
+	}
+	ids := func(events []ConversationEvent) []int64 {
+		out := make([]int64, len(events))
+		for i, event := range events {
+			out[i] = event.ID
+		}
+		return out
+	}
+	// pages follows the cursor from the start and returns each page's event IDs
+	pages := func(t *testing.T, lookup string) [][]int64 {
+		t.Helper()
+		var out [][]int64
+		cursor := 0
+		for {
+			resp := get(t, fmt.Sprintf(`{%s,"limit":4,"after_sequence":%d}`, lookup, cursor))
+			out = append(out, ids(resp.Events))
+			assert.Equal(t, resp.NextSequence != 0, resp.HasMore)
+			if resp.NextSequence == 0 {
+				return out
+			}
+			require.Greater(t, resp.NextSequence, cursor)
+			cursor = resp.NextSequence
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		lookup string
+		sizes  []int
+	}{
+		{"session history across parents", `"session_id":"sess-child"`, []int{4, 4, 2}},
+		{"claude session", `"claude_session_id":"claude-child"`, []int{4, 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			full := get(t, "{"+tc.lookup+"}")
+			assert.Zero(t, full.NextSequence)
+			assert.False(t, full.HasMore)
+			assert.Equal(t, len(full.Events), full.TotalCount)
+
+			var joined []int64
+			for i, page := range pages(t, tc.lookup) {
+				assert.Len(t, page, tc.sizes[i])
+				joined = append(joined, page...)
+			}
+			// Pages don't overlap and together are the whole conversation
+			assert.Equal(t, ids(full.Events), joined)
+
+			// Without a cursor, the first page is returned
+			first := get(t, "{"+tc.lookup+`,"limit":4}`)
+			assert.Equal(t, ids(full.Events)[:4], ids(first.Events))
+			assert.Equal(t, len(full.Events), first.TotalCount)
+			assert.True(t, first.HasMore)
+		})
+	}
+
+	t.Run("past the end is empty", func(t *testing.T) {
+		resp := get(t, `{"session_id":"sess-child","limit":4,"after_sequence":10}`)
+		assert.NotNil(t, resp.Events)
+		assert.Empty(t, resp.Events)
+		assert.Zero(t, resp.NextSequence)
+		assert.False(t, resp.HasMore)
+		assert.Equal(t, 10, resp.TotalCount)
+	})
+
+	t.Run("rejects invalid combinations", func(t *testing.T) {
+		for params, want := range map[string]string{
+			`{"session_id":"sess-child","limit":-1}`:                                "invalid request: limit and after_sequence cannot be negative",
+			`{"session_id":"sess-child","limit":4,"anchor_event_id":1}`:             "invalid request: limit cannot be combined with an anchor",
+			`{"session_id":"sess-child","limit":4,"ordering":"logical"}`:            "invalid request: limit cannot be combined with logical ordering",
+			`{"session_id":"sess-child","after_sequence":-3,"ordering":"sequence"}`: "invalid request: limit and after_sequence cannot be negative",
+		} {
+			_, err := handlers.HandleGetConversation(ctx, json.RawMessage(params))
+			assert.EqualError(t, err, want, params)
+		}
+	})
+}
+
+func TestHandleGetConversationSinceSequence(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	for _, sess := range []*store.Session{
+		{ID: "sess-parent", RunID: "run-parent", ClaudeSessionID: "claude-parent"},
+		{ID: "sess-child", RunID: "run-child", ClaudeSessionID: "claude-child", ParentSessionID: "sess-parent"},
+	} {
+		sess.Status, sess.CreatedAt, sess.LastActivityAt = store.SessionStatusCompleted, time.Now(), time.Now()
+		require.NoError(t, sqliteStore.CreateSession(ctx, sess))
+	}
+	add := func(t *testing.T, sessionID, claudeSessionID string, n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+				SessionID: sessionID, ClaudeSessionID: claudeSessionID,
+				EventType: store.EventTypeMessage, Role: "assistant", Content: fmt.Sprintf("%s %d", sessionID, i),
+			}))
+		}
+	}
+	add(t, "sess-parent", "claude-parent", 5)
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	get := func(t *testing.T, params string) *GetConversationResponse {
+		t.Helper()
+		result, err := handlers.HandleGetConversation(ctx, json.RawMessage(params))
+		require.NoError(t, err)
+		return result.(*GetConversationResponse)
+	}
+
+	// The full history ends with the parent's events, which don't move the
+	// child's cursor
+	full := get(t, `{"session_id":"sess-child"}`)
+	assert.Len(t, full.Events, 5)
+	assert.Zero(t, full.LastSequence)
+
+	resp := get(t, `{"session_id":"sess-child","since_sequence":0}`)
+	assert.NotNil(t, resp.Events)
+	assert.Empty(t, resp.Events, "nothing new")
+	assert.Zero(t, resp.LastSequence)
+
+	add(t, "sess-child", "claude-child", 3)
+	resp = get(t, `{"session_id":"sess-child","since_sequence":0}`)
+	require.Len(t, resp.Events, 3)
+	assert.Equal(t, "sess-child 0", resp.Events[0].Content)
+	assert.Equal(t, 3, resp.LastSequence)
+	assert.Equal(t, 8, resp.TotalCount)
+
+	add(t, "sess-child", "claude-child", 1)
+	resp = get(t, fmt.Sprintf(`{"session_id":"sess-child","since_sequence":%d}`, resp.LastSequence))
+	require.Len(t, resp.Events, 1)
+	assert.Equal(t, 4, resp.Events[0].Sequence)
+	assert.Equal(t, 4, resp.LastSequence)
+
+	resp = get(t, `{"session_id":"sess-child","since_sequence":4}`)
+	assert.Empty(t, resp.Events)
+	assert.Equal(t, 4, resp.LastSequence, "the cursor is kept when nothing is new")
+
+	t.Run("claude session", func(t *testing.T) {
+		resp := get(t, `{"claude_session_id":"claude-parent","since_sequence":3}`)
+		require.Len(t, resp.Events, 2)
+		assert.Equal(t, 4, resp.Events[0].Sequence)
+		assert.Equal(t, 5, resp.LastSequence)
+
+		resp = get(t, `{"claude_session_id":"claude-parent","since_sequence":5}`)
+		assert.Empty(t, resp.Events)
+		assert.Equal(t, 5, resp.LastSequence)
+	})
+
+	t.Run("rejects invalid combinations", func(t *testing.T) {
+		for params, want := range map[string]string{
+			`{"session_id":"sess-child","since_sequence":-1}`:                  "invalid request: since_sequence cannot be negative",
+			`{"session_id":"sess-child","since_sequence":1,"limit":2}`:         "invalid request: since_sequence cannot be combined with from_sequence, to_sequence, limit or after_sequence",
+			`{"session_id":"sess-child","since_sequence":1,"from_sequence":2}`: "invalid request: since_sequence cannot be combined with from_sequence, to_sequence, limit or after_sequence",
+		} {
+			_, err := handlers.HandleGetConversation(ctx, json.RawMessage(params))
+			assert.EqualError(t, err, want, params)
+		}
+	})
+}
+
+func TestHandleGetConversationRange(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	for _, sess := range []*store.Session{
+		{ID: "sess-parent", RunID: "run-parent", ClaudeSessionID: "claude-parent"},
+		{ID: "sess-child", RunID: "run-child", ClaudeSessionID: "claude-child", ParentSessionID: "sess-parent"},
+	} {
+		sess.Status, sess.CreatedAt, sess.LastActivityAt = store.SessionStatusCompleted, time.Now(), time.Now()
+		require.NoError(t, sqliteStore.CreateSession(ctx, sess))
+		for i := 1; i <= 5; i++ {
+			require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+				SessionID:       sess.ID,
+				ClaudeSessionID: sess.ClaudeSessionID,
+				EventType:       store.EventTypeMessage,
+				Role:            "assistant",
+				Content:         fmt.Sprintf("%s %d", sess.ID, i),
+			}))
+		}
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	contents := func(t *testing.T, params string) []string {
+		t.Helper()
+		result, err := handlers.HandleGetConversation(ctx, json.RawMessage(params))
+		require.NoError(t, err)
+		events := result.(*GetConversationResponse).Events
+		out := make([]string, len(events))
+		for i, event := range events {
+			out[i] = event.Content
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"sess-child 2", "sess-child 3", "sess-child 4"},
+		contents(t, `{"session_id":"sess-child","from_sequence":2,"to_sequence":4}`), "both bounds are inclusive")
+	assert.Equal(t, []string{"sess-child 4", "sess-child 5"},
+		contents(t, `{"session_id":"sess-child","from_sequence":4}`), "an omitted bound is open")
+	assert.Equal(t, []string{"sess-child 1"},
+		contents(t, `{"session_id":"sess-child","to_sequence":1}`))
+	assert.Empty(t, contents(t, `{"session_id":"sess-child","from_sequence":6,"to_sequence":9}`))
+
+	for params, want := range map[string]string{
+		`{"claude_session_id":"claude-child","from_sequence":1}`:           "invalid request: from_sequence and to_sequence require session_id",
+		`{"session_id":"sess-child","to_sequence":3,"limit":2}`:            "invalid request: from_sequence and to_sequence cannot be combined with limit or after_sequence",
+		`{"session_id":"sess-child","from_sequence":3,"after_sequence":1}`: "invalid request: from_sequence and to_sequence cannot be combined with limit or after_sequence",
+	} {
+		_, err := handlers.HandleGetConversation(ctx, json.RawMessage(params))
+		assert.EqualError(t, err, want, params)
+	}
+
+	_, err = handlers.HandleGetConversation(ctx, json.RawMessage(`{"session_id":"sess-child","from_sequence":4,"to_sequence":2}`))
+	assert.Error(t, err, "a reversed range is rejected")
+}
+
+func TestHandleGetConversationContentHash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	mockStore.EXPECT().GetAnnotationsForSession(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+
+	message := &store.ConversationEvent{ID: 1, SessionID: "sess-1", Sequence: 1, EventType: store.EventTypeMessage, Role: "assistant", Content: "teh answer", CreatedAt: time.Now()}
+	corrected := *message
+	corrected.ID, corrected.Content, corrected.CreatedAt = 2, "the answer", time.Now().Add(time.Minute)
+
+	mockStore.EXPECT().GetSessionConversation(gomock.Any(), "sess-1", gomock.Any()).Return([]*store.ConversationEvent{message}, nil)
+	result, err := handlers.HandleGetConversation(context.Background(), json.RawMessage(`{"session_id":"sess-1"}`))
+	require.NoError(t, err)
+	single := result.(*GetConversationResponse).Events[0].ContentHash
+	assert.Equal(t, message.Digest(), single)
+
+	// Batched responses carry the same hash, and a correction changes it
+	mockStore.EXPECT().GetSessionConversation(gomock.Any(), "sess-1", gomock.Any()).Return([]*store.ConversationEvent{message, &corrected}, nil)
+	result, err = handlers.HandleGetConversations(context.Background(), json.RawMessage(`{"session_ids":["sess-1"]}`))
+	require.NoError(t, err)
+	events := result.(*GetConversationsResponse).Conversations["sess-1"].Events
+	require.Len(t, events, 2)
+	assert.Equal(t, single, events[0].ContentHash)
+	assert.NotEqual(t, single, events[1].ContentHash)
+}
+
+func TestHandleGetConversationToolResult(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	mockStore.EXPECT().GetAnnotationsForSession(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+
+	failed := &store.ConversationEvent{
+		ID: 1, SessionID: "sess-1", Sequence: 1, EventType: store.EventTypeToolResult, Role: "user", CreatedAt: time.Now(),
+		ToolResultForID:   "toolu_1",
+		ToolResultContent: "file not found",
+		ToolResultJSON:    `[{"type":"text","text":"file not found"}]`,
+		ToolError:         "file not found",
+	}
+	mockStore.EXPECT().GetSessionConversation(gomock.Any(), "sess-1", gomock.Any()).Return([]*store.ConversationEvent{failed}, nil)
+
+	result, err := handlers.HandleGetConversation(context.Background(), json.RawMessage(`{"session_id":"sess-1"}`))
+	require.NoError(t, err)
+	data, err := json.Marshal(result.(*GetConversationResponse).Events[0])
+	require.NoError(t, err)
+
+	var event map[string]any
+	require.NoError(t, json.Unmarshal(data, &event))
+	assert.Equal(t, `[{"type":"text","text":"file not found"}]`, event["tool_result_json"])
+	assert.Equal(t, "file not found", event["tool_error"])
+}
+
+func TestHandleGetConversations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+
+	makeEvents := func(sessionID string, n int) []*store.ConversationEvent {
+		events := make([]*store.ConversationEvent, n)
+		for i := range events {
+			events[i] = &store.ConversationEvent{
+				ID:        int64(i + 1),
+				SessionID: sessionID,
+				Sequence:  i + 1,
+				EventType: store.EventTypeMessage,
+				CreatedAt: time.Now(),
+			}
+		}
+		return events
+	}
+
+	t.Run("returns each session with per-session errors", func(t *testing.T) {
+		mockStore.EXPECT().GetSessionConversation(gomock.Any(), "sess-1", gomock.Any()).Return(makeEvents("sess-1", 5), nil)
+		mockStore.EXPECT().GetSessionConversation(gomock.Any(), "missing", gomock.Any()).Return(nil, fmt.Errorf("session not found: missing"))
+		mockStore.EXPECT().GetSessionConversation(gomock.Any(), "sess-2", gomock.Any()).Return(makeEvents("sess-2", 2), nil)
+
+		result, err := handlers.HandleGetConversations(context.Background(),
+			json.RawMessage(`{"session_ids":["sess-1","missing","sess-2","sess-1"],"offset":1,"limit":3}`))
+		require.NoError(t, err)
+
+		resp := result.(*GetConversationsResponse)
+		require.Len(t, resp.Conversations, 3)
+
+		sess1 := resp.Conversations["sess-1"]
+		require.Len(t, sess1.Events, 3)
+		assert.Equal(t, 2, sess1.Events[0].Sequence)
+		assert.Empty(t, sess1.Error)
+
+		assert.Len(t, resp.Conversations["sess-2"].Events, 1)
+		assert.Contains(t, resp.Conversations["missing"].Error, "session not found")
+	})
+
+	t.Run("truncates once the batch event cap is reached", func(t *testing.T) {
+		mockStore.EXPECT().GetSessionConversation(gomock.Any(), "big-1", gomock.Any()).Return(makeEvents("big-1", maxBatchEvents-10), nil)
+		mockStore.EXPECT().GetSessionConversation(gomock.Any(), "big-2", gomock.Any()).Return(makeEvents("big-2", 50), nil)
+
+		result, err := handlers.HandleGetConversations(context.Background(),
+			json.RawMessage(`{"session_ids":["big-1","big-2"]}`))
+		require.NoError(t, err)
+
+		resp := result.(*GetConversationsResponse)
+		assert.False(t, resp.Conversations["big-1"].Truncated)
+		assert.True(t, resp.Conversations["big-2"].Truncated)
+		assert.Len(t, resp.Conversations["big-2"].Events, 10)
+	})
+
+	t.Run("rejects empty and oversized batches", func(t *testing.T) {
+		_, err := handlers.HandleGetConversations(context.Background(), json.RawMessage(`{"session_ids":[]}`))
+		assert.EqualError(t, err, "invalid request: session_ids is required and cannot be empty")
+
+		ids := make([]string, maxBatchConversations+1)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("sess-%d", i)
+		}
+		params, _ := json.Marshal(GetConversationsRequest{SessionIDs: ids})
+		_, err = handlers.HandleGetConversations(context.Background(), params)
+		assert.EqualError(t, err, "invalid request: at most 50 session_ids may be requested")
+	})
+}
+
+func TestHandleGetConversationCostAudit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	mockStore.EXPECT().GetAnnotationsForSession(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+
+	events := []*store.ConversationEvent{
+		{ID: 1, SessionID: "sess-1", Sequence: 1, EventType: store.EventTypeMessage, Role: "assistant", Content: "hi"},
+	}
+	mockStore.EXPECT().GetSessionConversation(gomock.Any(), "sess-1", gomock.Any()).Return(events, nil).Times(2)
+
+	result, err := handlers.HandleGetConversation(context.Background(), json.RawMessage(`{"session_id":"sess-1"}`))
+	require.NoError(t, err)
+	assert.Nil(t, result.(*GetConversationResponse).CostAudit, "audit is only included on request")
+
+	result, err = handlers.HandleGetConversation(context.Background(),
+		json.RawMessage(`{"session_id":"sess-1","include_cost_audit":true}`))
+	require.NoError(t, err)
+	audit := result.(*GetConversationResponse).CostAudit
+	require.NotNil(t, audit)
+	assert.False(t, audit.Available)
+	assert.NotEmpty(t, audit.Reason)
+}
+
+func TestHandleLaunchSessionDryRun(t *testing.T) {
+	// No manager: a dry run must not launch anything
+	handlers := NewSessionHandlers(nil, nil, nil, nil, nil)
+
+	query := strings.Repeat("a", 4000)
+	result, err := handlers.HandleLaunchSession(context.Background(),
+		json.RawMessage(`{"query":"`+query+`","model":"sonnet","dry_run":true}`))
+	require.NoError(t, err)
+
+	resp := result.(*LaunchSessionResponse)
+	assert.Empty(t, resp.SessionID)
+	require.NotNil(t, resp.Estimate)
+	assert.Equal(t, "sonnet", resp.Estimate.Model)
+	assert.Equal(t, 1000, resp.Estimate.InputTokens)
+	assert.InDelta(t, 0.003, resp.Estimate.EstimatedCostUSD, 1e-9)
+
+	t.Run("unknown model prices at the ceiling", func(t *testing.T) {
+		result, err := handlers.HandleLaunchSession(context.Background(),
+			json.RawMessage(`{"query":"hello","system_prompt":"be brief","dry_run":true}`))
+		require.NoError(t, err)
+
+		estimate := result.(*LaunchSessionResponse).Estimate
+		assert.Equal(t, "default", estimate.Model)
+		assert.Equal(t, 4, estimate.InputTokens)
+		assert.Equal(t, ModelInputPricing["opus"], estimate.InputPricePerMTokUSD)
+	})
+
+	t.Run("still validates the request", func(t *testing.T) {
+		_, err := handlers.HandleLaunchSession(context.Background(), json.RawMessage(`{"dry_run":true}`))
+		assert.EqualError(t, err, "invalid request: query is required")
 	})
+
+	t.Run("rejects unavailable allowed tools", func(t *testing.T) {
+		_, err := handlers.HandleLaunchSession(context.Background(), json.RawMessage(
+			`{"query":"hello","dry_run":true,"allowed_tools":["Read","Bash(git:*)","Frobnicate","mcp__missing__tool"]}`))
+		var unavailable *session.UnavailableToolsError
+		require.ErrorAs(t, err, &unavailable)
+		assert.Equal(t, []string{"Frobnicate", "mcp__missing__tool"}, unavailable.Missing)
+	})
+}
+
+func TestHandleListTools(t *testing.T) {
+	handlers := NewSessionHandlers(nil, nil, nil, nil, nil)
+	result, err := handlers.HandleListTools(context.Background(),
+		json.RawMessage(`{"mcp_config":{"mcpServers":{"docs":{"type":"http","url":"https://docs.example.com/mcp"}}}}`))
+	require.NoError(t, err)
+
+	available := make(map[string]bool)
+	for _, tool := range result.(*ListToolsResponse).Tools {
+		available[tool.Name] = tool.Available
+	}
+	assert.True(t, available["Read"])
+	assert.True(t, available["mcp__codelayer"])
+	assert.True(t, available["mcp__docs"])
 }
@@ -0,0 +1,431 @@
+package rpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// ImportConversationRequest is the request for importing a conversation
+// recorded by another tool. Exactly one of Events (the getConversation
+// export format) or Messages (the provider messages format) must be set.
+type ImportConversationRequest struct {
+	Title      string              `json:"title,omitempty"`
+	Query      string              `json:"query,omitempty"`
+	Model      string              `json:"model,omitempty"`
+	WorkingDir string              `json:"working_dir,omitempty"`
+	CreatedAt  string              `json:"created_at,omitempty"` // RFC3339, defaults to now
+	Events     []ConversationEvent `json:"events,omitempty"`
+	Messages   []ImportMessage     `json:"messages,omitempty"`
+}
+
+// ImportMessage is a single message in the provider messages format. Content
+// is either a plain string or an array of content blocks.
+type ImportMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// importContentBlock is a single content block within an ImportMessage
+type importContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	Thinking  string          `json:"thinking,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+}
+
+// ImportConversationResponse is the response for importing a conversation.
+// EventCount is the number of events in the import, of which NewEventCount
+// were stored and SkippedEventCount were already present.
+type ImportConversationResponse struct {
+	SessionID         string `json:"session_id"`
+	RunID             string `json:"run_id"`
+	EventCount        int    `json:"event_count"`
+	NewEventCount     int    `json:"new_event_count"`
+	SkippedEventCount int    `json:"skipped_event_count"`
+}
+
+// HandleImportConversation creates an imported session from an external
+// transcript. Imported sessions are stored as completed, have no live Claude
+// session behind them and cannot be continued. Source IDs and sequence
+// numbers are discarded and fresh ones are assigned.
+func (h *SessionHandlers) HandleImportConversation(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req ImportConversationRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	var events []*store.ConversationEvent
+	var err error
+	switch {
+	case len(req.Events) > 0 && len(req.Messages) > 0:
+		return nil, fmt.Errorf("%w: only one of events or messages may be provided", ErrInvalidRequest)
+	case len(req.Events) > 0:
+		events, err = importEventsFromExport(req.Events)
+	case len(req.Messages) > 0:
+		events, err = importEventsFromMessages(req.Messages)
+	default:
+		return nil, fmt.Errorf("%w: events or messages is required", ErrInvalidRequest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid import: %w", ErrInvalidRequest, err)
+	}
+
+	createdAt := time.Now()
+	if req.CreatedAt != "" {
+		createdAt, err = time.Parse(time.RFC3339, req.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid created_at: %w", ErrInvalidRequest, err)
+		}
+	}
+
+	// Fall back to the first user message for the query so imported sessions
+	// are searchable and display sensibly in session lists
+	query := req.Query
+	if query == "" {
+		for _, event := range events {
+			if event.EventType == store.EventTypeMessage && event.Role == "user" {
+				query = event.Content
+				break
+			}
+		}
+	}
+
+	hashes := importContentHashes(events)
+	for i, event := range events {
+		event.ContentHash = hashes[i]
+	}
+
+	existing, skipped, err := h.findImportedConversation(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		response := &ImportConversationResponse{
+			SessionID:         existing.ID,
+			RunID:             existing.RunID,
+			EventCount:        len(events),
+			NewEventCount:     len(events) - skipped,
+			SkippedEventCount: skipped,
+		}
+		for i, event := range events[skipped:] {
+			event.SessionID = existing.ID
+			event.ClaudeSessionID = existing.ClaudeSessionID
+			if err := h.store.AddConversationEvent(ctx, event); err != nil {
+				return nil, fmt.Errorf("failed to import event %d: %w", skipped+i, err)
+			}
+		}
+		slog.Info("imported conversation into existing session",
+			"session_id", existing.ID,
+			"new_events", response.NewEventCount,
+			"skipped_events", skipped)
+		return response, nil
+	}
+
+	sessionID := uuid.New().String()
+	// A synthetic claude_session_id scopes sequence numbers and lets the
+	// conversation be fetched like any other session
+	claudeSessionID := "imported-" + sessionID
+	dbSession := &store.Session{
+		ID:              sessionID,
+		RunID:           uuid.New().String(),
+		ClaudeSessionID: claudeSessionID,
+		Query:           query,
+		Title:           req.Title,
+		Model:           req.Model,
+		WorkingDir:      req.WorkingDir,
+		Status:          store.SessionStatusCompleted,
+		CreatedAt:       createdAt,
+		LastActivityAt:  createdAt,
+		Imported:        true,
+	}
+	if err := h.store.CreateSession(ctx, dbSession); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	for i, event := range events {
+		event.SessionID = sessionID
+		event.ClaudeSessionID = claudeSessionID
+		if err := h.store.AddConversationEvent(ctx, event); err != nil {
+			// Don't leave a half-imported session behind
+			if cleanupErr := h.store.DeleteSessionData(ctx, sessionID); cleanupErr != nil {
+				slog.Error("failed to clean up partial import", "session_id", sessionID, "error", cleanupErr)
+			}
+			return nil, fmt.Errorf("failed to import event %d: %w", i, err)
+		}
+	}
+
+	update := store.SessionUpdate{CompletedAt: &createdAt}
+	if err := h.store.UpdateSession(ctx, sessionID, update); err != nil {
+		slog.Warn("failed to set completed_at on imported session", "session_id", sessionID, "error", err)
+	}
+
+	slog.Info("imported conversation",
+		"session_id", sessionID,
+		"event_count", len(events))
+
+	return &ImportConversationResponse{
+		SessionID:     sessionID,
+		RunID:         dbSession.RunID,
+		EventCount:    len(events),
+		NewEventCount: len(events),
+	}, nil
+}
+
+// findImportedConversation looks for an imported session already holding a
+// prefix of the conversation with the given content hashes. It returns the
+// session and the number of events already present, or nil if the events
+// should go into a new session. A session that has diverged from the import
+// after the shared prefix isn't reused, since appending would interleave two
+// different transcripts.
+func (h *SessionHandlers) findImportedConversation(ctx context.Context, hashes []string) (*store.Session, int, error) {
+	found, err := h.store.FindEventContentHashes(ctx, hashes)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to check for existing import: %w", err)
+	}
+
+	// The chain makes the longest matching prefix the only one worth checking
+	present := 0
+	for i := len(hashes) - 1; i >= 0; i-- {
+		if _, ok := found[hashes[i]]; ok {
+			present = i + 1
+			break
+		}
+	}
+	if present == 0 {
+		return nil, 0, nil
+	}
+
+	sessionID := found[hashes[present-1]]
+	if present < len(hashes) {
+		last, err := h.store.GetLastEventContentHash(ctx, sessionID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to check for existing import: %w", err)
+		}
+		if last != hashes[present-1] {
+			return nil, 0, nil
+		}
+	}
+
+	session, err := h.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get existing import: %w", err)
+	}
+	return session, present, nil
+}
+
+// importContentHashes returns the chained content hash of each event. An
+// event's hash covers its own content and the hash of the event before it, so
+// equal hashes mean equal conversations up to that point.
+func importContentHashes(events []*store.ConversationEvent) []string {
+	hashes := make([]string, len(events))
+	prev := ""
+	for i, event := range events {
+		content, _ := json.Marshal([]string{
+			prev,
+			event.EventType,
+			event.Role,
+			event.Content,
+			event.ToolID,
+			event.ToolName,
+			event.ToolInputJSON,
+			event.ParentToolUseID,
+			event.ToolResultForID,
+			event.ToolResultContent,
+			event.Language,
+		})
+		sum := sha256.Sum256(content)
+		prev = hex.EncodeToString(sum[:])
+		hashes[i] = prev
+	}
+	return hashes
+}
+
+// importEventsFromExport validates events in the getConversation export format
+func importEventsFromExport(in []ConversationEvent) ([]*store.ConversationEvent, error) {
+	events := make([]*store.ConversationEvent, 0, len(in))
+	for i, e := range in {
+		event := &store.ConversationEvent{
+			EventType: e.EventType,
+			Role:      e.Role,
+			Content:   e.Content,
+			Language:  e.Language,
+		}
+		switch e.EventType {
+		case store.EventTypeMessage, store.EventTypeThinking, store.EventTypeSystem:
+			if e.EventType == store.EventTypeMessage && !isImportRole(e.Role) {
+				return nil, fmt.Errorf("events[%d]: role must be user, assistant or system, got %q", i, e.Role)
+			}
+			if e.Content == "" {
+				return nil, fmt.Errorf("events[%d]: content is required for %s events", i, e.EventType)
+			}
+		case store.EventTypeToolCall:
+			if e.ToolID == "" || e.ToolName == "" {
+				return nil, fmt.Errorf("events[%d]: tool_id and tool_name are required for tool_call events", i)
+			}
+			if e.ToolInputJSON != "" && !json.Valid([]byte(e.ToolInputJSON)) {
+				return nil, fmt.Errorf("events[%d]: tool_input_json is not valid JSON", i)
+			}
+			event.ToolID = e.ToolID
+			event.ToolName = e.ToolName
+			event.ToolInputJSON = e.ToolInputJSON
+			event.ParentToolUseID = e.ParentToolUseID
+			event.IsCompleted = true
+		case store.EventTypeToolResult:
+			if e.ToolResultForID == "" {
+				return nil, fmt.Errorf("events[%d]: tool_result_for_id is required for tool_result events", i)
+			}
+			event.ToolResultForID = e.ToolResultForID
+			event.ToolResultContent = e.ToolResultContent
+			event.ParentToolUseID = e.ParentToolUseID
+		case "":
+			return nil, fmt.Errorf("events[%d]: event_type is required", i)
+		default:
+			return nil, fmt.Errorf("events[%d]: unknown event_type %q", i, e.EventType)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// importEventsFromMessages validates and flattens messages in the provider
+// messages format into conversation events
+func importEventsFromMessages(in []ImportMessage) ([]*store.ConversationEvent, error) {
+	var events []*store.ConversationEvent
+	for i, msg := range in {
+		if !isImportRole(msg.Role) {
+			return nil, fmt.Errorf("messages[%d]: role must be user, assistant or system, got %q", i, msg.Role)
+		}
+		if len(msg.Content) == 0 {
+			return nil, fmt.Errorf("messages[%d]: content is required", i)
+		}
+
+		// Plain string content is a single text message
+		var text string
+		if err := json.Unmarshal(msg.Content, &text); err == nil {
+			if text == "" {
+				return nil, fmt.Errorf("messages[%d]: content is empty", i)
+			}
+			events = append(events, &store.ConversationEvent{
+				EventType: store.EventTypeMessage,
+				Role:      msg.Role,
+				Content:   text,
+			})
+			continue
+		}
+
+		var blocks []importContentBlock
+		if err := json.Unmarshal(msg.Content, &blocks); err != nil {
+			return nil, fmt.Errorf("messages[%d]: content must be a string or an array of content blocks", i)
+		}
+		for j, block := range blocks {
+			event, err := importEventFromBlock(msg.Role, block)
+			if err != nil {
+				return nil, fmt.Errorf("messages[%d].content[%d]: %w", i, j, err)
+			}
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// importEventFromBlock converts a single content block to a conversation event
+func importEventFromBlock(role string, block importContentBlock) (*store.ConversationEvent, error) {
+	switch block.Type {
+	case "text":
+		if block.Text == "" {
+			return nil, fmt.Errorf("text is required for text blocks")
+		}
+		return &store.ConversationEvent{
+			EventType: store.EventTypeMessage,
+			Role:      role,
+			Content:   block.Text,
+		}, nil
+	case "thinking":
+		if block.Thinking == "" {
+			return nil, fmt.Errorf("thinking is required for thinking blocks")
+		}
+		return &store.ConversationEvent{
+			EventType: store.EventTypeThinking,
+			Role:      role,
+			Content:   block.Thinking,
+		}, nil
+	case "tool_use":
+		if block.ID == "" || block.Name == "" {
+			return nil, fmt.Errorf("id and name are required for tool_use blocks")
+		}
+		input := "{}"
+		if len(block.Input) > 0 {
+			if !json.Valid(block.Input) {
+				return nil, fmt.Errorf("input is not valid JSON")
+			}
+			input = string(block.Input)
+		}
+		return &store.ConversationEvent{
+			EventType:     store.EventTypeToolCall,
+			Role:          role,
+			ToolID:        block.ID,
+			ToolName:      block.Name,
+			ToolInputJSON: input,
+			IsCompleted:   true,
+		}, nil
+	case "tool_result":
+		if block.ToolUseID == "" {
+			return nil, fmt.Errorf("tool_use_id is required for tool_result blocks")
+		}
+		content, err := importToolResultContent(block.Content)
+		if err != nil {
+			return nil, err
+		}
+		return &store.ConversationEvent{
+			EventType:         store.EventTypeToolResult,
+			Role:              role,
+			ToolResultForID:   block.ToolUseID,
+			ToolResultContent: content,
+		}, nil
+	case "":
+		return nil, fmt.Errorf("type is required")
+	default:
+		return nil, fmt.Errorf("unsupported block type %q", block.Type)
+	}
+}
+
+// importToolResultContent flattens tool_result content, which may be a string
+// or an array of text blocks
+func importToolResultContent(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	var blocks []importContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return "", fmt.Errorf("content must be a string or an array of text blocks")
+	}
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// isImportRole reports whether role is a valid message role
+func isImportRole(role string) bool {
+	return role == "user" || role == "assistant" || role == "system"
+}
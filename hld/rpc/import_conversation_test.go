@@ -0,0 +1,150 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleImportConversation(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	importAndFetch := func(t *testing.T, params string) (*store.Session, []*store.ConversationEvent) {
+		t.Helper()
+		result, err := handlers.HandleImportConversation(ctx, json.RawMessage(params))
+		require.NoError(t, err)
+		resp := result.(*ImportConversationResponse)
+
+		sess, err := sqliteStore.GetSession(ctx, resp.SessionID)
+		require.NoError(t, err)
+		events, err := sqliteStore.GetSessionConversation(ctx, resp.SessionID, store.ConversationPage{})
+		require.NoError(t, err)
+		require.Len(t, events, resp.EventCount)
+		return sess, events
+	}
+
+	t.Run("export format", func(t *testing.T) {
+		sess, events := importAndFetch(t, `{
+			"title": "From elsewhere",
+			"created_at": "2025-01-02T03:04:05Z",
+			"events": [
+				{"id": 99, "sequence": 42, "event_type": "message", "role": "user", "content": "list files"},
+				{"event_type": "tool_call", "tool_id": "t1", "tool_name": "Bash", "tool_input_json": "{\"command\":\"ls\"}"},
+				{"event_type": "tool_result", "tool_result_for_id": "t1", "tool_result_content": "a.go"},
+				{"event_type": "message", "role": "assistant", "content": "one file"}
+			]
+		}`)
+
+		assert.True(t, sess.Imported)
+		assert.Equal(t, store.SessionStatusCompleted, sess.Status)
+		assert.Equal(t, "From elsewhere", sess.Title)
+		assert.Equal(t, "list files", sess.Query)
+		assert.Equal(t, 2025, sess.CreatedAt.Year())
+		require.Len(t, events, 4)
+		for i, event := range events {
+			assert.Equal(t, i+1, event.Sequence, "sequence numbers should be reassigned")
+			assert.NotEqual(t, int64(99), event.ID)
+			assert.NotEmpty(t, event.Permalink)
+		}
+		assert.Equal(t, "Bash", events[1].ToolName)
+		assert.True(t, events[1].IsCompleted)
+		assert.Equal(t, "a.go", events[2].ToolResultContent)
+	})
+
+	t.Run("messages format", func(t *testing.T) {
+		_, events := importAndFetch(t, `{
+			"messages": [
+				{"role": "user", "content": "hi"},
+				{"role": "assistant", "content": [
+					{"type": "thinking", "thinking": "hmm"},
+					{"type": "text", "text": "let me look"},
+					{"type": "tool_use", "id": "tu1", "name": "Read", "input": {"path": "x"}}
+				]},
+				{"role": "user", "content": [
+					{"type": "tool_result", "tool_use_id": "tu1", "content": [{"type": "text", "text": "contents"}]}
+				]}
+			]
+		}`)
+
+		require.Len(t, events, 5)
+		assert.Equal(t, store.EventTypeMessage, events[0].EventType)
+		assert.Equal(t, store.EventTypeThinking, events[1].EventType)
+		assert.Equal(t, "let me look", events[2].Content)
+		assert.Equal(t, `{"path": "x"}`, events[3].ToolInputJSON)
+		assert.Equal(t, "tu1", events[4].ToolResultForID)
+		assert.Equal(t, "contents", events[4].ToolResultContent)
+	})
+
+	t.Run("re-imports are deduplicated", func(t *testing.T) {
+		importOnce := func(t *testing.T, params string) *ImportConversationResponse {
+			t.Helper()
+			result, err := handlers.HandleImportConversation(ctx, json.RawMessage(params))
+			require.NoError(t, err)
+			return result.(*ImportConversationResponse)
+		}
+		transcript := `{"role": "user", "content": "dedup me"}, {"role": "assistant", "content": "sure"}`
+
+		first := importOnce(t, `{"messages": [`+transcript+`]}`)
+		assert.Equal(t, 2, first.NewEventCount)
+		assert.Equal(t, 0, first.SkippedEventCount)
+
+		second := importOnce(t, `{"messages": [`+transcript+`]}`)
+		assert.Equal(t, first.SessionID, second.SessionID)
+		assert.Equal(t, 0, second.NewEventCount)
+		assert.Equal(t, 2, second.SkippedEventCount)
+
+		events, err := sqliteStore.GetSessionConversation(ctx, first.SessionID, store.ConversationPage{})
+		require.NoError(t, err)
+		assert.Len(t, events, 2, "re-import must not duplicate events")
+
+		// A longer transcript of the same conversation appends only new events
+		extended := importOnce(t, `{"messages": [`+transcript+`, {"role": "user", "content": "thanks"}]}`)
+		assert.Equal(t, first.SessionID, extended.SessionID)
+		assert.Equal(t, 1, extended.NewEventCount)
+		assert.Equal(t, 2, extended.SkippedEventCount)
+
+		events, err = sqliteStore.GetSessionConversation(ctx, first.SessionID, store.ConversationPage{})
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+		assert.Equal(t, "thanks", events[2].Content)
+		assert.Equal(t, 3, events[2].Sequence)
+
+		// A transcript that diverges after the shared prefix gets its own session
+		diverged := importOnce(t, `{"messages": [`+transcript+`, {"role": "user", "content": "something else"}]}`)
+		assert.NotEqual(t, first.SessionID, diverged.SessionID)
+		assert.Equal(t, 3, diverged.NewEventCount)
+	})
+
+	t.Run("rejects malformed imports", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			params string
+			errMsg string
+		}{
+			{"empty", `{}`, "events or messages is required"},
+			{"both formats", `{"events": [{"event_type": "message", "role": "user", "content": "x"}], "messages": [{"role": "user", "content": "x"}]}`, "only one of"},
+			{"unknown event type", `{"events": [{"event_type": "message", "role": "user", "content": "x"}, {"event_type": "bogus"}]}`, `events[1]: unknown event_type "bogus"`},
+			{"tool call without id", `{"events": [{"event_type": "tool_call", "tool_name": "Bash"}]}`, "events[0]: tool_id and tool_name are required"},
+			{"bad role", `{"messages": [{"role": "robot", "content": "x"}]}`, `messages[0]: role must be user, assistant or system, got "robot"`},
+			{"bad block", `{"messages": [{"role": "assistant", "content": [{"type": "text", "text": "ok"}, {"type": "image"}]}]}`, `messages[0].content[1]: unsupported block type "image"`},
+			{"bad created_at", `{"created_at": "yesterday", "messages": [{"role": "user", "content": "x"}]}`, "invalid created_at"},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := handlers.HandleImportConversation(ctx, json.RawMessage(tc.params))
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errMsg)
+			})
+		}
+	})
+}
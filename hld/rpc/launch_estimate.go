@@ -0,0 +1,38 @@
+package rpc
+
+import "github.com/humanlayer/humanlayer/hld/store"
+
+// LaunchCostEstimate bounds the cost of a launch's first request. It counts
+// only what the launch supplies (query, prompts and instructions), not Claude
+// Code's own system prompt and tool definitions, and says nothing about later
+// turns.
+type LaunchCostEstimate struct {
+	Model                string  `json:"model"` // Pricing model used, "default" when unspecified or unknown
+	InputTokens          int     `json:"input_tokens"`
+	InputPricePerMTokUSD float64 `json:"input_price_per_mtok_usd"`
+	EstimatedCostUSD     float64 `json:"estimated_cost_usd"`
+}
+
+// estimateLaunchCost estimates the first request's input tokens with the
+// store's token estimator and prices them from the model pricing snapshot
+func estimateLaunchCost(req LaunchSessionRequest) *LaunchCostEstimate {
+	model := req.Model
+	if _, ok := ModelInputPricing[model]; !ok {
+		model = "default"
+	}
+
+	tokens := 0
+	for _, text := range []string{req.Query, req.SystemPrompt, req.AppendSystemPrompt, req.CustomInstructions} {
+		if text != "" {
+			tokens += store.EstimateTokens(text)
+		}
+	}
+
+	price := GetModelInputPrice(model)
+	return &LaunchCostEstimate{
+		Model:                model,
+		InputTokens:          tokens,
+		InputPricePerMTokUSD: price,
+		EstimatedCostUSD:     float64(tokens) * price / 1_000_000,
+	}
+}
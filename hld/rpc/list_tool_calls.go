@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+const (
+	defaultListToolCallsLimit = 50
+	maxListToolCallsLimit     = 500
+)
+
+// ListToolCallsRequest is the request for listing tool calls across sessions
+type ListToolCallsRequest struct {
+	ToolName string `json:"tool_name,omitempty"`
+	RunID    string `json:"run_id,omitempty"`
+	Since    string `json:"since,omitempty"` // RFC3339, inclusive
+	Limit    int    `json:"limit,omitempty"` // Defaults to 50, at most 500
+}
+
+// ToolCall is a tool call event with the run ID of its session
+type ToolCall struct {
+	ConversationEvent
+	RunID string `json:"run_id"`
+}
+
+// ListToolCallsResponse holds the matching tool calls, most recent first
+type ListToolCallsResponse struct {
+	ToolCalls []ToolCall `json:"tool_calls"`
+}
+
+// HandleListToolCalls lists tool calls from every session, so questions such
+// as which sessions ran a tool and with what input can be answered without
+// fetching each conversation
+func (h *SessionHandlers) HandleListToolCalls(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req ListToolCallsRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+	}
+	if req.Limit < 0 {
+		return nil, fmt.Errorf("%w: limit must not be negative", ErrInvalidRequest)
+	}
+
+	filter := store.ToolCallFilter{
+		ToolName: req.ToolName,
+		RunID:    req.RunID,
+		Limit:    req.Limit,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultListToolCallsLimit
+	}
+	if filter.Limit > maxListToolCallsLimit {
+		filter.Limit = maxListToolCallsLimit
+	}
+	// Timestamps are stored in local time and compared as text
+	if req.Since != "" {
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid since: %w", ErrInvalidRequest, err)
+		}
+		filter.Since = since.Local()
+	}
+
+	calls, err := h.store.ListToolCalls(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tool calls: %w", err)
+	}
+
+	resp := &ListToolCallsResponse{ToolCalls: make([]ToolCall, 0, len(calls))}
+	for _, call := range calls {
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+			ConversationEvent: eventToRPC(&call.ConversationEvent),
+			RunID:             call.SessionRunID,
+		})
+	}
+	return resp, nil
+}
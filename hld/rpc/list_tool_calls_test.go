@@ -0,0 +1,115 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleListToolCalls(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	// 5 sessions with 10 tool calls each, alternating Bash and Read, each
+	// followed by a result. Sessions 0 and 1 ran days before the others.
+	for i := 0; i < 5; i++ {
+		sessionID := fmt.Sprintf("sess-%d", i)
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: sessionID, RunID: fmt.Sprintf("run-%d", i), Query: "q", Status: store.SessionStatusCompleted,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+		day := 1
+		if i >= 2 {
+			day = 5
+		}
+		for j := 0; j < 10; j++ {
+			toolName := "Bash"
+			if j%2 == 1 {
+				toolName = "Read"
+			}
+			call := &store.ConversationEvent{
+				SessionID: sessionID, ClaudeSessionID: "claude-" + sessionID, EventType: store.EventTypeToolCall,
+				ToolID: fmt.Sprintf("%s-t%d", sessionID, j), ToolName: toolName, ToolInputJSON: fmt.Sprintf(`{"n":%d}`, j),
+			}
+			require.NoError(t, sqliteStore.AddConversationEvent(ctx, call))
+			createdAt := fmt.Sprintf("2026-03-%02d %02d:%02d:00", day, i, j)
+			_, err := sqliteStore.GetDB().Exec(`UPDATE conversation_events SET created_at = ? WHERE id = ?`, createdAt, call.ID)
+			require.NoError(t, err)
+			require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+				SessionID: sessionID, ClaudeSessionID: "claude-" + sessionID, EventType: store.EventTypeToolResult,
+				ToolResultForID: call.ToolID, ToolResultContent: "ok",
+			}))
+		}
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	list := func(params string) []ToolCall {
+		t.Helper()
+		result, err := handlers.HandleListToolCalls(ctx, json.RawMessage(params))
+		require.NoError(t, err)
+		return result.(*ListToolCallsResponse).ToolCalls
+	}
+
+	t.Run("all tool calls, most recent first", func(t *testing.T) {
+		calls := list(`{"limit":500}`)
+		require.Len(t, calls, 50)
+		for i := 1; i < len(calls); i++ {
+			assert.LessOrEqual(t, calls[i].CreatedAt, calls[i-1].CreatedAt, "call %d is out of order", i)
+		}
+		assert.Equal(t, "sess-4-t9", calls[0].ToolID)
+		assert.Equal(t, "run-4", calls[0].RunID)
+		assert.Equal(t, `{"n":9}`, calls[0].ToolInputJSON)
+		for _, call := range calls {
+			assert.Equal(t, store.EventTypeToolCall, call.EventType)
+		}
+		assert.Len(t, list(`{}`), 50, "the default limit covers them all")
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		bash := list(`{"tool_name":"Bash","limit":500}`)
+		require.Len(t, bash, 25)
+		for _, call := range bash {
+			assert.Equal(t, "Bash", call.ToolName)
+		}
+
+		run := list(`{"tool_name":"Read","run_id":"run-3"}`)
+		require.Len(t, run, 5)
+		for _, call := range run {
+			assert.Equal(t, "sess-3", call.SessionID)
+			assert.Equal(t, "run-3", call.RunID)
+		}
+
+		recent := list(`{"since":"2026-03-03T00:00:00Z","limit":500}`)
+		require.Len(t, recent, 30)
+		for _, call := range recent {
+			assert.NotContains(t, []string{"run-0", "run-1"}, call.RunID)
+		}
+
+		assert.Len(t, list(`{"limit":7}`), 7)
+		assert.Empty(t, list(`{"tool_name":"Write"}`))
+	})
+
+	t.Run("soft-deleted sessions are left out", func(t *testing.T) {
+		require.NoError(t, sqliteStore.SoftDeleteSession(ctx, "sess-0"))
+		defer func() { require.NoError(t, sqliteStore.RestoreSession(ctx, "sess-0")) }()
+		assert.Len(t, list(`{"limit":500}`), 40)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, params := range []string{
+			`{"limit":-1}`,
+			`{"since":"yesterday"}`,
+		} {
+			_, err := handlers.HandleListToolCalls(ctx, json.RawMessage(params))
+			assert.Error(t, err, params)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
+	"github.com/humanlayer/humanlayer/hld/session"
+)
+
+// ListToolsRequest is the request for listing the tools a launch could allow
+type ListToolsRequest struct {
+	MCPConfig *claudecode.MCPConfig `json:"mcp_config,omitempty"` // MCP servers the launch would configure
+}
+
+// ListToolsResponse is the response for listing tools
+type ListToolsResponse struct {
+	Tools []session.ToolInfo `json:"tools"`
+}
+
+// HandleListTools lists the built-in tools and the MCP servers a session
+// launched with the given MCP configuration could use, with whether each
+// server can be started
+func (h *SessionHandlers) HandleListTools(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req ListToolsRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+	}
+	return &ListToolsResponse{Tools: session.ListTools(req.MCPConfig)}, nil
+}
@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// Conversation orderings
+const (
+	OrderingSequence = "sequence" // Storage order, as events arrived
+	OrderingLogical  = "logical"  // Grouped by turn, tool results after their calls
+)
+
+// eventPosition is an event's place in the logical ordering
+type eventPosition struct {
+	Turn  int // Starts at 1 and increases with each user message
+	Order int // Position within the turn, from 1
+}
+
+// validateOrdering checks a requested ordering, which defaults to sequence
+func validateOrdering(ordering string) error {
+	switch ordering {
+	case "", OrderingSequence, OrderingLogical:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown ordering %q (must be %s or %s)", ErrInvalidRequest, ordering, OrderingSequence, OrderingLogical)
+	}
+}
+
+// orderByLogicalTurn reorders events so parallel tool calls read coherently.
+// Events are grouped into turns, each starting at a user message. Within a
+// turn, events keep the order they were initiated in: a tool result is moved
+// up to sit after its call and any earlier results for that call, and a
+// sub-agent's events are kept together under the call that started it. The
+// input slice is not modified.
+func orderByLogicalTurn(events []*store.ConversationEvent) ([]*store.ConversationEvent, map[int64]eventPosition) {
+	type sortKey struct {
+		turn   int
+		anchor int // Sequence of the call the event belongs under, or its own
+		rank   int // 0 for the anchor itself, 1 for events nested under it
+		seq    int
+	}
+
+	calls := make(map[string]sortKey) // tool ID -> key of its call
+	keys := make(map[*store.ConversationEvent]sortKey, len(events))
+	turn := 0
+	for _, event := range events {
+		if turn == 0 || (event.EventType == store.EventTypeMessage && event.Role == "user") {
+			turn++
+		}
+		key := sortKey{turn: turn, anchor: event.Sequence, seq: event.Sequence}
+
+		// Results follow their call, and sub-agent events the call that
+		// spawned them, as long as that call is in the same turn
+		parentID := event.ParentToolUseID
+		if event.EventType == store.EventTypeToolResult {
+			parentID = event.ToolResultForID
+		}
+		if parent, ok := calls[parentID]; ok && parentID != "" && parent.turn == turn {
+			key.anchor, key.rank = parent.anchor, 1
+		}
+
+		if event.EventType == store.EventTypeToolCall && event.ToolID != "" {
+			calls[event.ToolID] = key
+		}
+		keys[event] = key
+	}
+
+	ordered := make([]*store.ConversationEvent, len(events))
+	copy(ordered, events)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := keys[ordered[i]], keys[ordered[j]]
+		if a.turn != b.turn {
+			return a.turn < b.turn
+		}
+		if a.anchor != b.anchor {
+			return a.anchor < b.anchor
+		}
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		return a.seq < b.seq
+	})
+
+	positions := make(map[int64]eventPosition, len(ordered))
+	order := 0
+	for i, event := range ordered {
+		if i == 0 || keys[event].turn != keys[ordered[i-1]].turn {
+			order = 0
+		}
+		order++
+		positions[event.ID] = eventPosition{Turn: keys[event].turn, Order: order}
+	}
+	return ordered, positions
+}
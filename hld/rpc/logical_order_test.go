@@ -0,0 +1,121 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parallelToolEvents is two turns where the first runs two tools and a
+// sub-agent concurrently, so results arrive out of call order
+func parallelToolEvents() []*store.ConversationEvent {
+	events := []*store.ConversationEvent{
+		{EventType: store.EventTypeMessage, Role: "user", Content: "check everything"},
+		{EventType: store.EventTypeToolCall, ToolID: "a", ToolName: "Read"},
+		{EventType: store.EventTypeToolCall, ToolID: "b", ToolName: "Bash"},
+		{EventType: store.EventTypeToolCall, ToolID: "task", ToolName: "Task"},
+		{EventType: store.EventTypeToolResult, ToolResultForID: "b"},
+		{EventType: store.EventTypeToolCall, ToolID: "sub", ToolName: "Grep", ParentToolUseID: "task"},
+		{EventType: store.EventTypeToolResult, ToolResultForID: "a"},
+		{EventType: store.EventTypeToolResult, ToolResultForID: "sub", ParentToolUseID: "task"},
+		{EventType: store.EventTypeToolResult, ToolResultForID: "task"},
+		{EventType: store.EventTypeMessage, Role: "assistant", Content: "all good"},
+		{EventType: store.EventTypeMessage, Role: "user", Content: "thanks"},
+		{EventType: store.EventTypeMessage, Role: "assistant", Content: "welcome"},
+	}
+	for i, event := range events {
+		event.ID = int64(i + 1)
+		event.Sequence = i + 1
+	}
+	return events
+}
+
+// eventLabel names an event for comparing orderings
+func eventLabel(event *store.ConversationEvent) string {
+	switch event.EventType {
+	case store.EventTypeToolCall:
+		return "call:" + event.ToolID
+	case store.EventTypeToolResult:
+		return "result:" + event.ToolResultForID
+	default:
+		return event.Role + ":" + event.Content
+	}
+}
+
+func TestOrderByLogicalTurn(t *testing.T) {
+	events := parallelToolEvents()
+	ordered, positions := orderByLogicalTurn(events)
+
+	var labels []string
+	for _, event := range ordered {
+		labels = append(labels, eventLabel(event))
+	}
+	assert.Equal(t, []string{
+		"user:check everything",
+		"call:a", "result:a",
+		"call:b", "result:b",
+		"call:task", "call:sub", "result:sub", "result:task",
+		"assistant:all good",
+		"user:thanks",
+		"assistant:welcome",
+	}, labels)
+
+	assert.Equal(t, eventPosition{Turn: 1, Order: 1}, positions[1])
+	assert.Equal(t, eventPosition{Turn: 1, Order: 3}, positions[7], "result:a moves up behind its call")
+	assert.Equal(t, eventPosition{Turn: 2, Order: 1}, positions[11])
+	assert.Equal(t, eventPosition{Turn: 2, Order: 2}, positions[12])
+
+	// The input keeps storage order
+	assert.Equal(t, int64(5), events[4].ID)
+}
+
+func TestHandleGetConversationOrdering(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID:              "sess-order",
+		RunID:           "run-order",
+		ClaudeSessionID: "claude-order",
+		Status:          store.SessionStatusRunning,
+		CreatedAt:       time.Now(),
+		LastActivityAt:  time.Now(),
+	}))
+	for _, event := range parallelToolEvents() {
+		event.ID, event.Sequence = 0, 0
+		event.SessionID = "sess-order"
+		event.ClaudeSessionID = "claude-order"
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, event))
+	}
+
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+	get := func(params string) []ConversationEvent {
+		result, err := handlers.HandleGetConversation(ctx, json.RawMessage(params))
+		require.NoError(t, err)
+		return result.(*GetConversationResponse).Events
+	}
+
+	raw := get(`{"session_id":"sess-order"}`)
+	require.Len(t, raw, 12)
+	for i := 1; i < len(raw); i++ {
+		assert.Less(t, raw[i-1].Sequence, raw[i].Sequence, "default ordering is by sequence")
+	}
+	assert.Zero(t, raw[0].Turn)
+
+	logical := get(`{"session_id":"sess-order","ordering":"logical"}`)
+	require.Len(t, logical, 12)
+	assert.Equal(t, "a", logical[2].ToolResultForID)
+	assert.Equal(t, 1, logical[2].Turn)
+	assert.Equal(t, 3, logical[2].TurnOrder)
+	assert.Equal(t, 2, logical[11].Turn)
+
+	_, err = handlers.HandleGetConversation(ctx, json.RawMessage(`{"session_id":"sess-order","ordering":"random"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/humanlayer/humanlayer/hld/bus"
+)
+
+// Subscription modes
+const (
+	// SubscribeModeDeltas delivers every event as published, including
+	// streaming fragments. It is the default.
+	SubscribeModeDeltas = "deltas"
+	// SubscribeModeMessages folds streaming fragments into whole messages and
+	// tool calls, so subscribers only see complete logical units
+	SubscribeModeMessages = "messages"
+)
+
+// validateSubscribeMode rejects unknown subscription modes
+func validateSubscribeMode(mode string) error {
+	switch mode {
+	case "", SubscribeModeDeltas, SubscribeModeMessages:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown mode %q (must be %s or %s)", ErrInvalidRequest, mode, SubscribeModeDeltas, SubscribeModeMessages)
+	}
+}
+
+// isPartialEvent reports whether a conversation_updated event is a streaming
+// fragment rather than a whole message. Fragments set "partial" to true and
+// carry the new text in "content", or for tool calls the next piece of the
+// input JSON in "tool_input_json".
+func isPartialEvent(event bus.Event) bool {
+	if event.Type != bus.EventConversationUpdated {
+		return false
+	}
+	partial, _ := event.Data["partial"].(bool)
+	return partial
+}
+
+// coalesceKey identifies the in-flight message a conversation event belongs
+// to. A stream has at most one message of each content type in flight per
+// session and sub-task; tool calls are further keyed by tool ID.
+func coalesceKey(event bus.Event) string {
+	field := func(name string) string {
+		value, _ := event.Data[name].(string)
+		return value
+	}
+	return strings.Join([]string{
+		field("session_id"),
+		field("parent_tool_use_id"),
+		field("content_type"),
+		field("tool_id"),
+	}, "\x00")
+}
+
+// pendingMessage accumulates the fragments of a single message
+type pendingMessage struct {
+	first bus.Event
+	text  strings.Builder
+}
+
+// messageCoalescer folds streaming fragments into whole conversation events
+// for a single subscriber. It is not safe for concurrent use.
+type messageCoalescer struct {
+	pending map[string]*pendingMessage
+	order   []string // keys in arrival order, so flushes preserve stream order
+}
+
+func newMessageCoalescer() *messageCoalescer {
+	return &messageCoalescer{pending: make(map[string]*pendingMessage)}
+}
+
+// Add consumes an event and returns the events ready for delivery, in order.
+// Fragments are held back. A whole event supersedes any fragments of the same
+// message, since it already carries the complete content. A terminal session
+// event first flushes whatever fragments are still pending for that session.
+func (c *messageCoalescer) Add(event bus.Event) []bus.Event {
+	if isPartialEvent(event) {
+		key := coalesceKey(event)
+		msg, ok := c.pending[key]
+		if !ok {
+			msg = &pendingMessage{first: event}
+			c.pending[key] = msg
+			c.order = append(c.order, key)
+		}
+		if event.Data["content_type"] == "tool_use" {
+			fragment, _ := event.Data["tool_input_json"].(string)
+			msg.text.WriteString(fragment)
+		} else {
+			fragment, _ := event.Data["content"].(string)
+			msg.text.WriteString(fragment)
+		}
+		return nil
+	}
+
+	if event.Type == bus.EventConversationUpdated {
+		c.drop(coalesceKey(event))
+		return []bus.Event{event}
+	}
+
+	if isTerminalEvent(event) {
+		sessionID, _ := event.Data["session_id"].(string)
+		return append(c.flush(sessionID), event)
+	}
+	return []bus.Event{event}
+}
+
+// drop discards pending fragments for key
+func (c *messageCoalescer) drop(key string) {
+	if _, ok := c.pending[key]; !ok {
+		return
+	}
+	delete(c.pending, key)
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// flush assembles and returns the pending messages of a session
+func (c *messageCoalescer) flush(sessionID string) []bus.Event {
+	var out []bus.Event
+	remaining := c.order[:0]
+	for _, key := range c.order {
+		msg := c.pending[key]
+		if id, _ := msg.first.Data["session_id"].(string); id != sessionID {
+			remaining = append(remaining, key)
+			continue
+		}
+		delete(c.pending, key)
+		out = append(out, msg.assemble())
+	}
+	c.order = remaining
+	return out
+}
+
+// assemble builds a whole event from the accumulated fragments, shaped like
+// the events published for complete messages
+func (m *pendingMessage) assemble() bus.Event {
+	data := make(map[string]interface{}, len(m.first.Data))
+	for k, v := range m.first.Data {
+		data[k] = v
+	}
+	delete(data, "partial")
+	if data["content_type"] == "tool_use" {
+		delete(data, "tool_input_json")
+		var toolInput map[string]interface{}
+		if err := json.Unmarshal([]byte(m.text.String()), &toolInput); err == nil {
+			data["tool_input"] = toolInput
+		}
+	} else {
+		data["content"] = m.text.String()
+	}
+	return bus.Event{
+		Type:      m.first.Type,
+		Timestamp: m.first.Timestamp,
+		Data:      data,
+	}
+}
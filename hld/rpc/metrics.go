@@ -0,0 +1,99 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// handlerMetrics counts and times RPC handler calls. A nil *handlerMetrics
+// records nothing.
+type handlerMetrics struct {
+	calls    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// newHandlerMetrics creates the handler instruments and registers them with
+// reg. It returns nil when reg is nil.
+func newHandlerMetrics(reg prometheus.Registerer) *handlerMetrics {
+	if reg == nil {
+		return nil
+	}
+	return &handlerMetrics{
+		calls: registerCollector(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "hld",
+			Subsystem: "rpc",
+			Name:      "handler_calls_total",
+			Help:      "RPC handler calls by handler and outcome.",
+		}, []string{"handler_name", "status"})),
+		duration: registerCollector(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "hld",
+			Subsystem: "rpc",
+			Name:      "handler_duration_seconds",
+			Help:      "RPC handler latency by handler and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"handler_name", "status"})),
+	}
+}
+
+// registerCollector registers c with reg, reusing the collector already
+// registered under the same name so that handlers built twice against one
+// registry share their instruments
+func registerCollector[C prometheus.Collector](reg prometheus.Registerer, c C) C {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return c
+}
+
+// observe records one call that started at start
+func (m *handlerMetrics) observe(name string, start time.Time, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.calls.WithLabelValues(name, status).Inc()
+	m.duration.WithLabelValues(name, status).Observe(time.Since(start).Seconds())
+}
+
+// wrap returns handler instrumented under name
+func (m *handlerMetrics) wrap(name string, handler HandlerFunc) HandlerFunc {
+	if m == nil {
+		return handler
+	}
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		start := time.Now()
+		result, err := handler(ctx, params)
+		m.observe(name, start, err)
+		return result, err
+	}
+}
+
+// wrapConn returns a connection handler instrumented under name. Its latency
+// is the lifetime of the stream, and a stream ended by the client closing the
+// connection counts as ok.
+func (m *handlerMetrics) wrapConn(name string, handler ConnHandlerFunc) ConnHandlerFunc {
+	if m == nil {
+		return handler
+	}
+	return func(ctx context.Context, conn net.Conn, params json.RawMessage) error {
+		start := time.Now()
+		err := handler(ctx, conn, params)
+		if errors.Is(err, context.Canceled) {
+			m.observe(name, start, nil)
+		} else {
+			m.observe(name, start, err)
+		}
+		return err
+	}
+}
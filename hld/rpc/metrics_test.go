@@ -0,0 +1,61 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerMetrics(t *testing.T) {
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	ctx := context.Background()
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID:             "sess-1",
+		RunID:          "run-1",
+		Query:          "test",
+		Status:         store.SessionStatusRunning,
+		CreatedAt:      time.Now(),
+		LastActivityAt: time.Now(),
+	}))
+
+	registry := prometheus.NewRegistry()
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, registry, nil)
+	server := NewServer()
+	handlers.Register(server)
+
+	resp := server.handleRequest(ctx, []byte(`{"jsonrpc":"2.0","method":"getSessionState","params":{"session_id":"sess-1"},"id":1}`))
+	require.Nil(t, resp.Error)
+	resp = server.handleRequest(ctx, []byte(`{"jsonrpc":"2.0","method":"getSessionState","params":{"session_id":"missing"},"id":2}`))
+	require.NotNil(t, resp.Error)
+	resp = server.handleRequest(ctx, []byte(`{"jsonrpc":"2.0","method":"getSessionState","params":{"session_id":"sess-1"},"id":3}`))
+	require.Nil(t, resp.Error)
+
+	calls := handlers.metrics.calls
+	assert.Equal(t, 2.0, testutil.ToFloat64(calls.WithLabelValues("getSessionState", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(calls.WithLabelValues("getSessionState", "error")))
+	assert.Equal(t, 2, testutil.CollectAndCount(calls), "only called handlers have series")
+	assert.Equal(t, 2, testutil.CollectAndCount(handlers.metrics.duration))
+
+	t.Run("handlers built twice share the registry's instruments", func(t *testing.T) {
+		again := NewSessionHandlers(nil, sqliteStore, nil, registry, nil)
+		assert.Same(t, handlers.metrics.calls, again.metrics.calls)
+	})
+
+	t.Run("nil registerer records nothing", func(t *testing.T) {
+		plain := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+		assert.Nil(t, plain.metrics)
+		server := NewServer()
+		plain.Register(server)
+		resp := server.handleRequest(ctx, []byte(`{"jsonrpc":"2.0","method":"getSessionState","params":{"session_id":"sess-1"},"id":1}`))
+		assert.Nil(t, resp.Error)
+	})
+}
@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+// Middleware wraps a handler to add behaviour around every call
+type Middleware func(HandlerFunc) HandlerFunc
+
+type methodKey struct{}
+
+// MethodFromContext returns the RPC method being handled, as seen by
+// middleware registered with SessionHandlers.Use
+func MethodFromContext(ctx context.Context) string {
+	method, _ := ctx.Value(methodKey{}).(string)
+	return method
+}
+
+// Use adds middleware around every session handler. The first middleware is
+// the outermost. It must be called before Register, and applies to
+// request/response handlers only, not streaming ones.
+func (h *SessionHandlers) Use(middleware ...Middleware) {
+	h.middleware = append(h.middleware, middleware...)
+}
+
+// wrap applies the registered middleware, request logging, metrics,
+// payload limits and in-flight tracking to the handler for method
+func (h *SessionHandlers) wrap(method string, handler HandlerFunc) HandlerFunc {
+	for i := len(h.middleware) - 1; i >= 0; i-- {
+		handler = h.middleware[i](handler)
+	}
+	if len(h.middleware) > 0 {
+		next := handler
+		handler = func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			return next(context.WithValue(ctx, methodKey{}, method), params)
+		}
+	}
+	return h.trackInflight(h.limitPayloads(h.metrics.wrap(method, h.logCalls(method, handler))))
+}
+
+// LoggingMiddleware logs each call's method and duration, and its error if it
+// failed
+func LoggingMiddleware(logger *slog.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			start := time.Now()
+			result, err := next(ctx, params)
+			if err != nil {
+				logger.WarnContext(ctx, "rpc call failed",
+					"method", MethodFromContext(ctx),
+					"duration", time.Since(start),
+					"error", err)
+			} else {
+				logger.DebugContext(ctx, "rpc call",
+					"method", MethodFromContext(ctx),
+					"duration", time.Since(start))
+			}
+			return result, err
+		}
+	}
+}
+
+// RecoveryMiddleware turns a panicking handler into a failed call, so one bad
+// request cannot take down the daemon
+func RecoveryMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, params json.RawMessage) (result interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("rpc handler panicked",
+					"method", MethodFromContext(ctx),
+					"panic", r,
+					"stack", string(debug.Stack()))
+				result, err = nil, fmt.Errorf("internal error: handler panicked: %v", r)
+			}
+		}()
+		return next(ctx, params)
+	}
+}
+
+// TimeoutMiddleware gives each call a deadline of d. A handler still running
+// at the deadline is abandoned and the call fails with
+// context.DeadlineExceeded; handlers that honour their context stop too.
+func TimeoutMiddleware(d time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			type outcome struct {
+				result interface{}
+				err    error
+				panic  interface{}
+			}
+			done := make(chan outcome, 1)
+			go func() {
+				var o outcome
+				defer func() {
+					// Re-raised on the caller's goroutine so that outer
+					// middleware such as RecoveryMiddleware sees it
+					o.panic = recover()
+					done <- o
+				}()
+				o.result, o.err = next(ctx, params)
+			}()
+
+			select {
+			case o := <-done:
+				if o.panic != nil {
+					panic(o.panic)
+				}
+				return o.result, o.err
+			case <-ctx.Done():
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return nil, fmt.Errorf("%s timed out after %s: %w", MethodFromContext(ctx), d, ctx.Err())
+				}
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
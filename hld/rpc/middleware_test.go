@@ -0,0 +1,103 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionHandlersUse(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+				calls = append(calls, name+":"+MethodFromContext(ctx))
+				return next(ctx, params)
+			}
+		}
+	}
+
+	handlers := NewSessionHandlers(nil, nil, nil, nil, nil)
+	handlers.Use(record("outer"), record("inner"))
+	server := NewServer()
+	handlers.Register(server)
+
+	// The handler rejects the empty request before touching the store
+	resp := server.handleRequest(context.Background(), []byte(`{"jsonrpc":"2.0","method":"getSessionState","params":{},"id":1}`))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, []string{"outer:getSessionState", "inner:getSessionState"}, calls)
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	ctx := context.WithValue(context.Background(), methodKey{}, "doThing")
+
+	ok := LoggingMiddleware(logger)(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return "done", nil
+	})
+	result, err := ok(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "done", result)
+	assert.Contains(t, buf.String(), "method=doThing")
+	assert.Contains(t, buf.String(), "duration=")
+
+	buf.Reset()
+	failing := LoggingMiddleware(logger)(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	_, err = failing(ctx, nil)
+	assert.EqualError(t, err, "boom")
+	assert.Contains(t, buf.String(), "level=WARN")
+	assert.Contains(t, buf.String(), "error=boom")
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	handler := RecoveryMiddleware(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		panic("nil map")
+	})
+	result, err := handler(context.Background(), nil)
+	assert.Nil(t, result)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nil map")
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	t.Run("fast handler", func(t *testing.T) {
+		handler := TimeoutMiddleware(time.Second)(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline)
+			return "done", nil
+		})
+		result, err := handler(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "done", result)
+	})
+
+	t.Run("handler ignoring its context is abandoned", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		handler := TimeoutMiddleware(10 * time.Millisecond)(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			<-release
+			return "late", nil
+		})
+		_, err := handler(context.Background(), nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("panics reach outer recovery", func(t *testing.T) {
+		handler := RecoveryMiddleware(TimeoutMiddleware(time.Second)(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			panic("boom")
+		}))
+		_, err := handler(context.Background(), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "boom")
+	})
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ModelUsageSummary totals the sessions that used one model
+type ModelUsageSummary struct {
+	Model        string  `json:"model"` // Empty for sessions without a recorded model
+	SessionCount int     `json:"session_count"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	TotalTokens  int64   `json:"total_tokens"` // Prompt tokens, including cache reads and writes, plus completion tokens
+}
+
+// ListModelUsageResponse lists every model seen, most expensive first
+type ListModelUsageResponse struct {
+	Models []ModelUsageSummary `json:"models"`
+}
+
+// HandleListModelUsage returns how many sessions each model ran and what
+// they cost, over every session in the store
+func (h *SessionHandlers) HandleListModelUsage(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	models, err := h.store.AggregateCostByModel(ctx, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate model usage: %w", err)
+	}
+
+	resp := &ListModelUsageResponse{Models: make([]ModelUsageSummary, len(models))}
+	for i, m := range models {
+		resp.Models[i] = ModelUsageSummary{
+			Model:        m.Model,
+			SessionCount: m.SessionCount,
+			TotalCostUSD: m.CostUSD,
+			TotalTokens:  m.PromptTokens + m.CompletionTokens,
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,47 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleListModelUsage(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	floatPtr := func(v float64) *float64 { return &v }
+	intPtr := func(v int) *int { return &v }
+	for _, sess := range []struct {
+		id, model string
+		update    store.SessionUpdate
+	}{
+		{"sess-a", "sonnet", store.SessionUpdate{CostUSD: floatPtr(1), InputTokens: intPtr(100), OutputTokens: intPtr(10)}},
+		{"sess-b", "sonnet", store.SessionUpdate{CostUSD: floatPtr(0.5), InputTokens: intPtr(50), CacheReadInputTokens: intPtr(5)}},
+		{"sess-c", "opus", store.SessionUpdate{CostUSD: floatPtr(4), InputTokens: intPtr(300), OutputTokens: intPtr(40)}},
+		{"sess-d", "haiku", store.SessionUpdate{CostUSD: floatPtr(0.25), OutputTokens: intPtr(7)}},
+		{"sess-e", "haiku", store.SessionUpdate{}},
+	} {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: sess.id, RunID: "run-" + sess.id, Model: sess.model, Status: store.SessionStatusCompleted,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+		require.NoError(t, sqliteStore.UpdateSession(ctx, sess.id, sess.update))
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	result, err := handlers.HandleListModelUsage(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []ModelUsageSummary{
+		{Model: "opus", SessionCount: 1, TotalCostUSD: 4, TotalTokens: 340},
+		{Model: "sonnet", SessionCount: 2, TotalCostUSD: 1.5, TotalTokens: 165},
+		{Model: "haiku", SessionCount: 2, TotalCostUSD: 0.25, TotalTokens: 7},
+	}, result.(*ListModelUsageResponse).Models)
+
+}
@@ -0,0 +1,100 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// MoveSessionsToArchiveRequest is the request for moving old sessions to
+// the archive tables
+type MoveSessionsToArchiveRequest struct {
+	OlderThanDays int      `json:"older_than_days"`    // Days since the session was last active
+	Statuses      []string `json:"statuses,omitempty"` // Terminal statuses to archive, all of them if empty
+}
+
+// MoveSessionsToArchiveResponse is the response for moving old sessions to
+// the archive tables
+type MoveSessionsToArchiveResponse struct {
+	Archived []string `json:"archived"` // IDs of the sessions archived
+}
+
+// HandleMoveSessionsToArchive moves terminal sessions last active more than
+// older_than_days ago to the archive tables, along with all of their data.
+// Sessions that other sessions were continued from are skipped, so their
+// continuations keep their history. Unlike archiveSession, which only sets
+// a session's archived flag, this takes sessions out of the main tables.
+func (h *SessionHandlers) HandleMoveSessionsToArchive(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req MoveSessionsToArchiveRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.OlderThanDays <= 0 {
+		return nil, fmt.Errorf("%w: older_than_days must be positive", ErrInvalidRequest)
+	}
+	statuses := make(map[string]bool)
+	for _, status := range req.Statuses {
+		if !store.IsTerminalSessionStatus(status) {
+			return nil, fmt.Errorf("%w: cannot archive %s sessions", ErrInvalidRequest, status)
+		}
+		statuses[status] = true
+	}
+	olderThan := time.Now().AddDate(0, 0, -req.OlderThanDays)
+
+	sessions, err := h.store.ListSessions(ctx, store.ListSessionsFilter{IncludeDeleted: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	parents := make(map[string]bool)
+	for _, s := range sessions {
+		if s.ParentSessionID != "" {
+			parents[s.ParentSessionID] = true
+		}
+	}
+
+	resp := &MoveSessionsToArchiveResponse{Archived: []string{}}
+	for _, s := range sessions {
+		if !store.IsTerminalSessionStatus(s.Status) || (len(statuses) > 0 && !statuses[s.Status]) ||
+			!s.LastActivityAt.Before(olderThan) || parents[s.ID] {
+			continue
+		}
+		if err := h.store.ArchiveSession(ctx, s.ID); err != nil {
+			return nil, fmt.Errorf("failed to archive session %s: %w", s.ID, err)
+		}
+		resp.Archived = append(resp.Archived, s.ID)
+	}
+	return resp, nil
+}
+
+// RestoreArchivedSessionRequest is the request for moving a session back
+// from the archive tables
+type RestoreArchivedSessionRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// RestoreArchivedSessionResponse is the response for restoring an archived
+// session
+type RestoreArchivedSessionResponse struct {
+	Success   bool   `json:"success"`
+	SessionID string `json:"session_id"`
+}
+
+// HandleRestoreArchivedSession moves a session that moveSessionsToArchive
+// archived, and all of its data, back to the main tables
+func (h *SessionHandlers) HandleRestoreArchivedSession(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req RestoreArchivedSessionRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+
+	if err := h.store.RestoreArchivedSession(ctx, req.SessionID); err != nil {
+		return nil, fmt.Errorf("failed to restore archived session: %w", err)
+	}
+	return &RestoreArchivedSessionResponse{Success: true, SessionID: req.SessionID}, nil
+}
@@ -0,0 +1,102 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleMoveSessionsToArchive(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	old := time.Now().AddDate(0, 0, -40)
+	create := func(id, status, parent string, lastActivity time.Time) {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: id, RunID: "run-" + id, ClaudeSessionID: "claude-" + id, ParentSessionID: parent, Query: "q",
+			Status: status, CreatedAt: lastActivity, LastActivityAt: lastActivity,
+		}))
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+			SessionID: id, ClaudeSessionID: "claude-" + id, EventType: store.EventTypeMessage, Role: "user", Content: "hi",
+		}))
+		require.NoError(t, sqliteStore.UpdateSession(ctx, id, store.SessionUpdate{LastActivityAt: &lastActivity}))
+	}
+	for i := 0; i < 45; i++ {
+		create(fmt.Sprintf("completed-%d", i), store.SessionStatusCompleted, "", old)
+	}
+	for i := 0; i < 5; i++ {
+		create(fmt.Sprintf("failed-%d", i), store.SessionStatusFailed, "", old)
+	}
+	create("old-running", store.SessionStatusRunning, "", old)
+	create("old-interrupted", store.SessionStatusInterrupted, "", old)
+	create("recent", store.SessionStatusCompleted, "", time.Now())
+	create("parent", store.SessionStatusCompleted, "", old)
+	create("child", store.SessionStatusCompleted, "parent", time.Now())
+
+	count := func(t *testing.T, table string) int {
+		t.Helper()
+		var n int
+		require.NoError(t, sqliteStore.GetDB().QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n))
+		return n
+	}
+	sessionsBefore, archivedBefore := count(t, "sessions"), count(t, "archived_sessions")
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	result, err := handlers.HandleMoveSessionsToArchive(ctx,
+		json.RawMessage(`{"older_than_days":30,"statuses":["completed","failed"]}`))
+	require.NoError(t, err)
+	assert.Len(t, result.(*MoveSessionsToArchiveResponse).Archived, 50)
+	assert.Equal(t, sessionsBefore-50, count(t, "sessions"))
+	assert.Equal(t, archivedBefore+50, count(t, "archived_sessions"))
+	assert.Equal(t, 50, count(t, "archived_conversation_events"))
+
+	remaining, err := sqliteStore.ListSessions(ctx, store.ListSessionsFilter{})
+	require.NoError(t, err)
+	var ids []string
+	for _, s := range remaining {
+		ids = append(ids, s.ID)
+	}
+	assert.ElementsMatch(t, []string{"old-running", "old-interrupted", "recent", "parent", "child"}, ids)
+	all, err := sqliteStore.ListSessions(ctx, store.ListSessionsFilter{IncludeArchived: true})
+	require.NoError(t, err)
+	assert.Len(t, all, 55)
+
+	t.Run("every terminal status by default", func(t *testing.T) {
+		result, err := handlers.HandleMoveSessionsToArchive(ctx, json.RawMessage(`{"older_than_days":30}`))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"old-interrupted"}, result.(*MoveSessionsToArchiveResponse).Archived)
+	})
+
+	t.Run("restores an archived session", func(t *testing.T) {
+		result, err := handlers.HandleRestoreArchivedSession(ctx, json.RawMessage(`{"session_id":"old-interrupted"}`))
+		require.NoError(t, err)
+		assert.Equal(t, &RestoreArchivedSessionResponse{Success: true, SessionID: "old-interrupted"}, result)
+		restored, err := sqliteStore.GetSession(ctx, "old-interrupted")
+		require.NoError(t, err)
+		assert.Equal(t, store.SessionStatusInterrupted, restored.Status)
+		events, err := sqliteStore.GetSessionConversation(ctx, "old-interrupted", store.ConversationPage{})
+		require.NoError(t, err)
+		assert.Len(t, events, 1)
+
+		_, err = handlers.HandleRestoreArchivedSession(ctx, json.RawMessage(`{"session_id":"old-interrupted"}`))
+		var notFound *store.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		_, err = handlers.HandleRestoreArchivedSession(ctx, json.RawMessage(`{}`))
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := handlers.HandleMoveSessionsToArchive(ctx, json.RawMessage(`{}`))
+		assert.EqualError(t, err, "invalid request: older_than_days must be positive")
+		_, err = handlers.HandleMoveSessionsToArchive(ctx, json.RawMessage(`{"older_than_days":1,"statuses":["running"]}`))
+		assert.EqualError(t, err, "invalid request: cannot archive running sessions")
+	})
+}
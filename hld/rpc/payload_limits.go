@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Default payload limits for session handlers
+const (
+	DefaultMaxRequestBytes  int64 = 4 << 20
+	DefaultMaxResponseBytes int64 = 16 << 20
+)
+
+// JSON-RPC error codes for payloads over the size limits, from the range the
+// spec reserves for implementation-defined server errors
+const (
+	RequestTooLarge  = -32001
+	ResponseTooLarge = -32002
+)
+
+var (
+	// ErrRequestTooLarge is returned for calls whose params exceed MaxRequestBytes
+	ErrRequestTooLarge = errors.New("request too large")
+	// ErrResponseTooLarge is returned for calls whose result would exceed MaxResponseBytes
+	ErrResponseTooLarge = errors.New("response too large")
+)
+
+// payloadSizeError reports a payload over a size limit. It matches
+// ErrRequestTooLarge or ErrResponseTooLarge with errors.Is.
+type payloadSizeError struct {
+	err   error
+	code  int
+	size  int
+	limit int64
+	hint  string
+}
+
+func (e *payloadSizeError) Error() string {
+	msg := fmt.Sprintf("%s: %d bytes exceeds the %d byte limit", e.err, e.size, e.limit)
+	if e.hint != "" {
+		msg += "; " + e.hint
+	}
+	return msg
+}
+
+func (e *payloadSizeError) Unwrap() error { return e.err }
+
+// RPCCode returns the JSON-RPC error code sent for the error
+func (e *payloadSizeError) RPCCode() int { return e.code }
+
+// SetPayloadLimits sets the largest params and the largest encoded result a
+// session handler call may have. Zero keeps the default and a negative
+// limit disables the check. It must be called before Register.
+func (h *SessionHandlers) SetPayloadLimits(maxRequestBytes, maxResponseBytes int64) {
+	h.maxRequestBytes = maxRequestBytes
+	h.maxResponseBytes = maxResponseBytes
+}
+
+func payloadLimit(limit, fallback int64) int64 {
+	if limit == 0 {
+		return fallback
+	}
+	return limit
+}
+
+// limitPayloads rejects params over the request limit before the handler
+// decodes them, and results whose encoding is over the response limit. The
+// result is returned already encoded so it isn't marshalled twice.
+func (h *SessionHandlers) limitPayloads(handler HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		if limit := payloadLimit(h.maxRequestBytes, DefaultMaxRequestBytes); limit > 0 && int64(len(params)) > limit {
+			return nil, &payloadSizeError{err: ErrRequestTooLarge, code: RequestTooLarge, size: len(params), limit: limit}
+		}
+
+		result, err := handler(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		limit := payloadLimit(h.maxResponseBytes, DefaultMaxResponseBytes)
+		if limit < 0 {
+			return result, nil
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode response: %w", err)
+		}
+		if int64(len(data)) > limit {
+			return nil, &payloadSizeError{
+				err: ErrResponseTooLarge, code: ResponseTooLarge, size: len(data), limit: limit,
+				hint: "request less data, such as a smaller limit or page",
+			}
+		}
+		return json.RawMessage(data), nil
+	}
+}
@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadLimits(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID: "sess-1", RunID: "run-1", ClaudeSessionID: "claude-1", Query: "q",
+		Status: store.SessionStatusCompleted, CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+
+	fiveMB := strings.Repeat("x", 5<<20)
+	call := func(handlers *SessionHandlers, method, params string) *Response {
+		server := NewServer()
+		handlers.Register(server)
+		return server.handleRequest(ctx, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":%q,"params":%s,"id":1}`, method, params)))
+	}
+
+	t.Run("request over the default limit", func(t *testing.T) {
+		handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+		resp := call(handlers, "getSessionState", fmt.Sprintf(`{"session_id":"sess-1","padding":%q}`, fiveMB))
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, RequestTooLarge, resp.Error.Code)
+		assert.Contains(t, resp.Error.Message, "request too large")
+
+		resp = call(handlers, "getSessionState", `{"session_id":"sess-1"}`)
+		assert.Nil(t, resp.Error)
+	})
+
+	t.Run("response over the default limit", func(t *testing.T) {
+		// Four 5 MB results put the conversation over 16 MB
+		for i := 0; i < 4; i++ {
+			require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+				SessionID: "sess-1", ClaudeSessionID: "claude-1", EventType: store.EventTypeToolResult,
+				ToolResultForID: fmt.Sprintf("t%d", i), ToolResultContent: fiveMB,
+			}))
+		}
+		handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+		resp := call(handlers, "getConversation", `{"session_id":"sess-1"}`)
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, ResponseTooLarge, resp.Error.Code)
+		assert.Contains(t, resp.Error.Message, "smaller limit")
+
+		resp = call(handlers, "getConversation", `{"session_id":"sess-1","limit":1}`)
+		require.Nil(t, resp.Error)
+		var page GetConversationResponse
+		require.NoError(t, json.Unmarshal(resp.Result.(json.RawMessage), &page))
+		assert.Len(t, page.Events, 1)
+	})
+
+	t.Run("configured limits", func(t *testing.T) {
+		handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+		handlers.SetPayloadLimits(16, -1)
+		resp := call(handlers, "getSessionState", `{"session_id":"sess-1"}`)
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, RequestTooLarge, resp.Error.Code)
+
+		handlers = NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+		handlers.SetPayloadLimits(-1, -1)
+		resp = call(handlers, "getConversation", `{"session_id":"sess-1"}`)
+		assert.Nil(t, resp.Error)
+	})
+
+	t.Run("errors match their sentinels", func(t *testing.T) {
+		handlers := NewSessionHandlers(nil, nil, nil, nil, nil)
+		handler := handlers.limitPayloads(func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			return nil, nil
+		})
+		_, err := handler(ctx, json.RawMessage(`"`+fiveMB+`"`))
+		assert.ErrorIs(t, err, ErrRequestTooLarge)
+	})
+}
@@ -0,0 +1,40 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/humanlayer/humanlayer/hld/session"
+)
+
+// ProviderHandlers provides RPC handlers for inspecting provider health
+type ProviderHandlers struct {
+	overload *session.OverloadMonitor
+}
+
+// NewProviderHandlers creates new provider RPC handlers. overload may be nil.
+func NewProviderHandlers(overload *session.OverloadMonitor) *ProviderHandlers {
+	return &ProviderHandlers{overload: overload}
+}
+
+// GetProviderStatusResponse is the response for fetching provider status
+type GetProviderStatusResponse struct {
+	session.ProviderStatus
+}
+
+// HandleGetProviderStatus reports whether the provider is overloaded and lists
+// recent overload periods
+func (h *ProviderHandlers) HandleGetProviderStatus(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	if h.overload == nil {
+		return &GetProviderStatusResponse{
+			ProviderStatus: session.ProviderStatus{RecentOverloads: []session.OverloadPeriod{}},
+		}, nil
+	}
+
+	return &GetProviderStatusResponse{ProviderStatus: h.overload.Status()}, nil
+}
+
+// Register registers all provider handlers with the RPC server
+func (h *ProviderHandlers) Register(server *Server) {
+	server.Register("getProviderStatus", h.HandleGetProviderStatus)
+}
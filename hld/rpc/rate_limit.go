@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimited is the JSON-RPC error code for calls refused by
+// RateLimitMiddleware, from the implementation-defined server error range
+const RateLimited = -32003
+
+// DefaultRateLimitKey is the key in RateLimitMiddleware's limits holding the
+// limit for methods that don't have their own
+const DefaultRateLimitKey = "*"
+
+// rateLimitError reports a call refused by RateLimitMiddleware. It matches
+// ErrRateLimited with errors.Is.
+type rateLimitError struct {
+	method     string
+	retryAfter time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return fmt.Sprintf("%s: %s: retry after %s", ErrRateLimited, e.method, e.retryAfter)
+}
+
+func (e *rateLimitError) Unwrap() error { return ErrRateLimited }
+
+// RPCCode returns the JSON-RPC error code sent for the error
+func (e *rateLimitError) RPCCode() int { return RateLimited }
+
+// RetryAfter returns how long until the call would be allowed
+func (e *rateLimitError) RetryAfter() time.Duration { return e.retryAfter }
+
+// RateLimitMiddleware limits how often each method may be called, in calls
+// per second with bursts of up to burst calls. A method missing from limits
+// uses the limit under DefaultRateLimitKey, and is unlimited if there is
+// none. Methods are matched regardless of case, as config file keys are
+// lowercased when read. Each method has its own limiter, shared by every
+// client. Refused calls fail with ErrRateLimited and say how long to wait
+// before retrying.
+func RateLimitMiddleware(limits map[string]rate.Limit, burst int) Middleware {
+	// A zero burst would refuse every call
+	burst = max(burst, 1)
+	folded := make(map[string]rate.Limit, len(limits))
+	for method, limit := range limits {
+		folded[strings.ToLower(method)] = limit
+	}
+	var mu sync.Mutex
+	limiters := make(map[string]*rate.Limiter)
+	limiter := func(method string) *rate.Limiter {
+		mu.Lock()
+		defer mu.Unlock()
+		if l, ok := limiters[method]; ok {
+			return l
+		}
+		limit, ok := folded[strings.ToLower(method)]
+		if !ok {
+			if limit, ok = folded[DefaultRateLimitKey]; !ok {
+				limit = rate.Inf
+			}
+		}
+		l := rate.NewLimiter(limit, burst)
+		limiters[method] = l
+		return l
+	}
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			method := MethodFromContext(ctx)
+			reservation := limiter(method).Reserve()
+			if delay := reservation.Delay(); delay > 0 {
+				// Refused calls must not use up the allowance of later ones
+				reservation.Cancel()
+				return nil, &rateLimitError{method: method, retryAfter: delay}
+			}
+			return next(ctx, params)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	ok := func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return "done", nil
+	}
+	withMethod := func(method string) context.Context {
+		return context.WithValue(context.Background(), methodKey{}, method)
+	}
+
+	t.Run("limits calls at ten times the rate", func(t *testing.T) {
+		const limit = 20
+		handler := RateLimitMiddleware(map[string]rate.Limit{"searchEvents": limit}, 1)(ok)
+		ctx := withMethod("searchEvents")
+
+		// Four goroutines together call at 10x the limit for a second
+		var succeeded atomic.Int64
+		var wg sync.WaitGroup
+		deadline := time.Now().Add(time.Second)
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ticker := time.NewTicker(4 * time.Second / (10 * limit))
+				defer ticker.Stop()
+				for time.Now().Before(deadline) {
+					if _, err := handler(ctx, nil); err == nil {
+						succeeded.Add(1)
+					} else {
+						assert.ErrorIs(t, err, ErrRateLimited)
+					}
+					<-ticker.C
+				}
+			}()
+		}
+		wg.Wait()
+		// One second's allowance plus the burst
+		assert.InDelta(t, limit+1, succeeded.Load(), 4)
+	})
+
+	t.Run("default and unlimited methods", func(t *testing.T) {
+		limited := RateLimitMiddleware(map[string]rate.Limit{DefaultRateLimitKey: 1}, 2)(ok)
+		for range 2 {
+			_, err := limited(withMethod("getSessionState"), nil)
+			require.NoError(t, err)
+		}
+		_, err := limited(withMethod("getSessionState"), nil)
+		assert.ErrorIs(t, err, ErrRateLimited)
+		_, err = limited(withMethod("listSessions"), nil)
+		assert.NoError(t, err, "each method has its own limiter")
+
+		unlimited := RateLimitMiddleware(map[string]rate.Limit{"searchEvents": 1}, 1)(ok)
+		for range 100 {
+			_, err := unlimited(withMethod("listSessions"), nil)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("matches methods regardless of case", func(t *testing.T) {
+		// Viper lowercases the keys of rate_limits in the config file
+		handler := RateLimitMiddleware(map[string]rate.Limit{"searchevents": 1}, 1)(ok)
+		_, err := handler(withMethod("searchEvents"), nil)
+		require.NoError(t, err)
+		_, err = handler(withMethod("searchEvents"), nil)
+		assert.ErrorIs(t, err, ErrRateLimited)
+	})
+
+	t.Run("retry after on the wire", func(t *testing.T) {
+		handlers := NewSessionHandlers(nil, nil, nil, nil, nil)
+		handlers.Use(RateLimitMiddleware(map[string]rate.Limit{"getSessionState": 2}, 1))
+		server := NewServer()
+		handlers.Register(server)
+		call := func() []byte {
+			resp := server.handleRequest(context.Background(), []byte(`{"jsonrpc":"2.0","method":"getSessionState","params":{},"id":1}`))
+			data, err := json.Marshal(resp)
+			require.NoError(t, err)
+			return data
+		}
+
+		// The first call reaches the handler, which rejects the empty request
+		assert.ErrorIs(t, DecodeError(call()), ErrInvalidRequest)
+		decoded := DecodeError(call())
+		require.NotNil(t, decoded)
+		assert.ErrorIs(t, decoded, ErrRateLimited)
+		assert.Contains(t, decoded.Message, "rate limited: getSessionState: retry after")
+		assert.Greater(t, decoded.RetryAfter, 400*time.Millisecond)
+		assert.LessOrEqual(t, decoded.RetryAfter, 500*time.Millisecond)
+
+		var resp Response
+		require.NoError(t, json.Unmarshal(call(), &resp))
+		assert.Equal(t, RateLimited, resp.Error.Code)
+	})
+}
@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
+	"github.com/humanlayer/humanlayer/hld/export"
+	"github.com/humanlayer/humanlayer/hld/session"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// ReplaySessionRequest is the request for replaying a session against
+// another model
+type ReplaySessionRequest struct {
+	SourceSessionID string `json:"source_session_id"`
+	TargetModel     string `json:"target_model"` // Alias such as "sonnet" or a full model name
+	DryRun          bool   `json:"dry_run,omitempty"`
+}
+
+// ReplaySessionResponse is the response for replaying a session. SessionID
+// and RunID are empty for a dry run.
+type ReplaySessionResponse struct {
+	SessionID string              `json:"session_id,omitempty"`
+	RunID     string              `json:"run_id,omitempty"`
+	Query     string              `json:"query"`  // Prompt the replay is launched with
+	Events    []ConversationEvent `json:"events"` // Messages the prompt was built from
+}
+
+// HandleReplaySession launches a new session that replays a session's
+// conversation against the target model, so behaviour can be compared across
+// model or tool versions. Only user and assistant messages are replayed, in
+// sequence order and including the parent chain; tool calls, tool results
+// and thinking are stripped so the new model does its own tool work. Claude
+// Code takes a single prompt, so the messages are sent as one transcript.
+func (h *SessionHandlers) HandleReplaySession(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req ReplaySessionRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.SourceSessionID == "" {
+		return nil, fmt.Errorf("%w: source_session_id is required", ErrInvalidRequest)
+	}
+	if req.TargetModel == "" {
+		return nil, fmt.Errorf("%w: target_model is required", ErrInvalidRequest)
+	}
+
+	source, err := h.store.GetSession(ctx, req.SourceSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	stored, err := h.store.GetSessionConversation(ctx, source.ID, store.ConversationPage{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	events := replayMessages(stored)
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: session %s has no messages to replay", ErrInvalidRequest, source.ID)
+	}
+	resp := &ReplaySessionResponse{
+		Query:  export.Markdown("", exportEvents(events)),
+		Events: events,
+	}
+	if req.DryRun {
+		return resp, nil
+	}
+
+	config := session.LaunchSessionConfig{
+		SessionConfig: claudecode.SessionConfig{
+			Query:        resp.Query,
+			Model:        claudecode.Model(req.TargetModel),
+			WorkingDir:   source.WorkingDir,
+			OutputFormat: claudecode.OutputStreamJSON,
+		},
+		Title: replayTitle(source),
+		Owner: IdentityFromContext(ctx),
+	}
+	replay, err := h.manager.LaunchSession(ctx, config, false)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("replaying session",
+		"source_session_id", source.ID,
+		"session_id", replay.ID,
+		"target_model", req.TargetModel,
+		"message_count", len(events))
+
+	resp.SessionID = replay.ID
+	resp.RunID = replay.RunID
+	return resp, nil
+}
+
+// replayMessages keeps the user and assistant messages of a conversation in
+// their stored order
+func replayMessages(stored []*store.ConversationEvent) []ConversationEvent {
+	var events []ConversationEvent
+	for _, event := range stored {
+		if event.EventType != store.EventTypeMessage {
+			continue
+		}
+		if event.Role != "user" && event.Role != "assistant" {
+			continue
+		}
+		events = append(events, eventToRPC(event))
+	}
+	return events
+}
+
+func replayTitle(source *store.Session) string {
+	title := source.Title
+	if title == "" {
+		title = source.Query
+	}
+	return "Replay: " + title
+}
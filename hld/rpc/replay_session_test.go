@@ -0,0 +1,105 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
+	"github.com/humanlayer/humanlayer/hld/session"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandleReplaySession(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID: "source", RunID: "run-source", ClaudeSessionID: "claude-source", Query: "Fix the bug",
+		Title: "Bug fix", Model: "sonnet", WorkingDir: "/src", Status: store.SessionStatusCompleted,
+		CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+	for _, event := range []*store.ConversationEvent{
+		{EventType: store.EventTypeMessage, Role: "user", Content: "Fix the bug"},
+		{EventType: store.EventTypeThinking, Role: "assistant", Content: "Let me look"},
+		{EventType: store.EventTypeToolCall, ToolID: "t1", ToolName: "Read", ToolInputJSON: `{"file":"a.go"}`},
+		{EventType: store.EventTypeToolResult, ToolResultForID: "t1", ToolResultContent: "package main"},
+		{EventType: store.EventTypeMessage, Role: "assistant", Content: "Found it"},
+		{EventType: store.EventTypeMessage, Role: "user", Content: "Now add a test"},
+		{EventType: store.EventTypeSystem, Role: "system", Content: "Session started"},
+	} {
+		event.SessionID, event.ClaudeSessionID = "source", "claude-source"
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, event))
+	}
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID: "empty", RunID: "run-empty", ClaudeSessionID: "claude-empty", Query: "Nothing",
+		Status: store.SessionStatusCompleted, CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+
+	contents := func(events []ConversationEvent) []string {
+		var out []string
+		for _, event := range events {
+			out = append(out, event.Role+": "+event.Content)
+		}
+		return out
+	}
+	messages := []string{"user: Fix the bug", "assistant: Found it", "user: Now add a test"}
+
+	t.Run("launches the messages with the target model", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		manager := session.NewMockSessionManager(ctrl)
+		handlers := NewSessionHandlers(manager, sqliteStore, nil, nil, nil)
+
+		var launched session.LaunchSessionConfig
+		manager.EXPECT().LaunchSession(gomock.Any(), gomock.Any(), false).DoAndReturn(
+			func(ctx context.Context, config session.LaunchSessionConfig, isDraft bool) (*session.Session, error) {
+				launched = config
+				return &session.Session{ID: "replay", RunID: "run-replay"}, nil
+			})
+
+		result, err := handlers.HandleReplaySession(ctx, json.RawMessage(`{"source_session_id":"source","target_model":"opus"}`))
+		require.NoError(t, err)
+		resp := result.(*ReplaySessionResponse)
+		assert.Equal(t, "replay", resp.SessionID)
+		assert.Equal(t, "run-replay", resp.RunID)
+		assert.Equal(t, messages, contents(resp.Events))
+		assert.Equal(t, []int{1, 5, 6}, []int{resp.Events[0].Sequence, resp.Events[1].Sequence, resp.Events[2].Sequence})
+
+		assert.Equal(t, claudecode.ModelOpus, launched.Model)
+		assert.Equal(t, "/src", launched.WorkingDir)
+		assert.Equal(t, "Replay: Bug fix", launched.Title)
+		assert.Equal(t, "**User**: Fix the bug\n\n**Assistant**: Found it\n\n**User**: Now add a test\n\n", launched.Query)
+		assert.Equal(t, resp.Query, launched.Query)
+	})
+
+	t.Run("dry run doesn't launch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		handlers := NewSessionHandlers(session.NewMockSessionManager(ctrl), sqliteStore, nil, nil, nil)
+
+		result, err := handlers.HandleReplaySession(ctx, json.RawMessage(`{"source_session_id":"source","target_model":"claude-sonnet-4-5","dry_run":true}`))
+		require.NoError(t, err)
+		resp := result.(*ReplaySessionResponse)
+		assert.Empty(t, resp.SessionID)
+		assert.Equal(t, messages, contents(resp.Events))
+		assert.NotContains(t, resp.Query, "package main")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+		var notFound *store.NotFoundError
+		_, err := handlers.HandleReplaySession(ctx, json.RawMessage(`{"source_session_id":"missing","target_model":"opus"}`))
+		assert.ErrorAs(t, err, &notFound)
+		_, err = handlers.HandleReplaySession(ctx, json.RawMessage(`{"target_model":"opus"}`))
+		assert.EqualError(t, err, "invalid request: source_session_id is required")
+		_, err = handlers.HandleReplaySession(ctx, json.RawMessage(`{"source_session_id":"source"}`))
+		assert.EqualError(t, err, "invalid request: target_model is required")
+		_, err = handlers.HandleReplaySession(ctx, json.RawMessage(`{"source_session_id":"empty","target_model":"opus"}`))
+		assert.EqualError(t, err, "invalid request: session empty has no messages to replay")
+	})
+}
@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the ID generated for the RPC call being
+// handled, or "" outside a session handler call
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// logCalls gives each call to handler a request ID and, when the handlers
+// have a logger, logs the call's start and its outcome under that ID
+func (h *SessionHandlers) logCalls(method string, handler HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		requestID := uuid.New().String()
+		ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+		if h.logger == nil {
+			return handler(ctx, params)
+		}
+
+		start := time.Now()
+		h.logger.DebugContext(ctx, "rpc request started",
+			"handler", method,
+			"request_id", requestID)
+		result, err := handler(ctx, params)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "rpc request failed",
+				"handler", method,
+				"request_id", requestID,
+				"duration", time.Since(start),
+				"outcome", "error",
+				"error", err)
+		} else {
+			h.logger.InfoContext(ctx, "rpc request completed",
+				"handler", method,
+				"request_id", requestID,
+				"duration", time.Since(start),
+				"outcome", "ok")
+		}
+		return result, err
+	}
+}
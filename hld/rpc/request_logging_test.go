@@ -0,0 +1,121 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHandler keeps every record logged through it
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+// attrs returns the attributes of each record logged so far
+func (h *recordingHandler) attrs() []map[string]slog.Value {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var all []map[string]slog.Value
+	for _, r := range h.records {
+		attrs := map[string]slog.Value{"level": slog.StringValue(r.Level.String())}
+		r.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value
+			return true
+		})
+		all = append(all, attrs)
+	}
+	return all
+}
+
+func TestSessionHandlersRequestLogging(t *testing.T) {
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	require.NoError(t, sqliteStore.CreateSession(context.Background(), &store.Session{
+		ID: "sess-1", RunID: "run-1", Query: "q", Status: store.SessionStatusCompleted,
+		CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+
+	recorder := &recordingHandler{}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, slog.New(recorder))
+	var seen []string
+	handlers.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			seen = append(seen, RequestIDFromContext(ctx))
+			return next(ctx, params)
+		}
+	})
+	server := NewServer()
+	handlers.Register(server)
+
+	resp := server.handleRequest(context.Background(), []byte(`{"jsonrpc":"2.0","method":"listWebhooks","params":{},"id":1}`))
+	require.NotNil(t, resp.Error, "unknown methods never reach the handlers")
+	assert.Empty(t, recorder.attrs())
+
+	resp = server.handleRequest(context.Background(), []byte(`{"jsonrpc":"2.0","method":"getSessionState","params":{"session_id":"sess-1"},"id":2}`))
+	require.Nil(t, resp.Error)
+	resp = server.handleRequest(context.Background(), []byte(`{"jsonrpc":"2.0","method":"getSessionState","params":{},"id":3}`))
+	require.NotNil(t, resp.Error)
+
+	records := recorder.attrs()
+	require.Len(t, records, 4)
+	require.Len(t, seen, 2)
+	assert.NotEqual(t, seen[0], seen[1], "each call gets its own request ID")
+
+	for i, call := range []struct {
+		method, level, outcome string
+	}{
+		{"getSessionState", "INFO", "ok"},
+		{"getSessionState", "ERROR", "error"},
+	} {
+		start, end := records[2*i], records[2*i+1]
+		assert.Equal(t, "DEBUG", start["level"].String())
+		assert.Equal(t, call.method, start["handler"].String())
+		assert.Equal(t, seen[i], start["request_id"].String(), "middleware sees the logged request ID")
+
+		assert.Equal(t, call.level, end["level"].String())
+		assert.Equal(t, call.method, end["handler"].String())
+		assert.Equal(t, seen[i], end["request_id"].String())
+		assert.Equal(t, call.outcome, end["outcome"].String())
+		assert.Contains(t, end, "duration")
+	}
+	assert.Contains(t, records[3], "error")
+	assert.NotContains(t, records[1], "error")
+}
+
+func TestRequestIDWithoutLogger(t *testing.T) {
+	handlers := NewSessionHandlers(nil, nil, nil, nil, nil)
+	var requestID string
+	handlers.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			requestID = RequestIDFromContext(ctx)
+			return next(ctx, params)
+		}
+	})
+	server := NewServer()
+	handlers.Register(server)
+
+	server.handleRequest(context.Background(), []byte(`{"jsonrpc":"2.0","method":"getSessionState","params":{},"id":1}`))
+	assert.NotEmpty(t, requestID)
+	assert.Empty(t, RequestIDFromContext(context.Background()))
+}
@@ -0,0 +1,62 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// GetRunSessionsRequest is the request for the sessions of an orchestrated run
+type GetRunSessionsRequest struct {
+	RunID string `json:"run_id"`
+}
+
+// GetRunSessionsResponse lists a run's sessions with its overall cost and outcome
+type GetRunSessionsResponse struct {
+	RunID        string         `json:"run_id"`
+	Sessions     []SessionState `json:"sessions"` // Oldest first
+	CostUSD      float64        `json:"cost_usd"`
+	TotalTokens  int64          `json:"total_tokens"`  // Input, output and cache tokens
+	StatusCounts map[string]int `json:"status_counts"` // Sessions per status
+}
+
+// HandleGetRunSessions returns every session belonging to a run with their
+// combined cost, token usage and statuses
+func (h *SessionHandlers) HandleGetRunSessions(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetRunSessionsRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.RunID == "" {
+		return nil, fmt.Errorf("%w: run_id is required", ErrInvalidRequest)
+	}
+
+	sessions, err := h.store.GetSessionsByRunID(ctx, req.RunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run sessions: %w", err)
+	}
+	if len(sessions) == 0 {
+		return nil, &store.NotFoundError{Type: "run", ID: req.RunID}
+	}
+
+	resp := &GetRunSessionsResponse{
+		RunID:        req.RunID,
+		Sessions:     make([]SessionState, len(sessions)),
+		StatusCounts: make(map[string]int),
+	}
+	for i, sess := range sessions {
+		resp.Sessions[i] = sessionToState(sess)
+		resp.StatusCounts[sess.Status]++
+		if sess.CostUSD != nil {
+			resp.CostUSD += *sess.CostUSD
+		}
+		for _, tokens := range []*int{sess.InputTokens, sess.OutputTokens, sess.CacheCreationInputTokens, sess.CacheReadInputTokens} {
+			if tokens != nil {
+				resp.TotalTokens += int64(*tokens)
+			}
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetRunSessions(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	floatPtr := func(v float64) *float64 { return &v }
+	intPtr := func(v int) *int { return &v }
+
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID:             "worker",
+		RunID:          "run-1",
+		Status:         store.SessionStatusFailed,
+		CreatedAt:      time.Now(),
+		LastActivityAt: time.Now(),
+	}))
+	require.NoError(t, sqliteStore.UpdateSession(ctx, "worker", store.SessionUpdate{
+		CostUSD:              floatPtr(0.25),
+		InputTokens:          intPtr(100),
+		OutputTokens:         intPtr(50),
+		CacheReadInputTokens: intPtr(15),
+	}))
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	t.Run("aggregates the run", func(t *testing.T) {
+		result, err := handlers.HandleGetRunSessions(ctx, json.RawMessage(`{"run_id":"run-1"}`))
+		require.NoError(t, err)
+		resp := result.(*GetRunSessionsResponse)
+
+		require.Len(t, resp.Sessions, 1)
+		assert.Equal(t, "worker", resp.Sessions[0].ID)
+		assert.InDelta(t, 0.25, resp.CostUSD, 1e-9)
+		assert.Equal(t, int64(165), resp.TotalTokens)
+		assert.Equal(t, map[string]int{store.SessionStatusFailed: 1}, resp.StatusCounts)
+	})
+
+	t.Run("unknown run", func(t *testing.T) {
+		_, err := handlers.HandleGetRunSessions(ctx, json.RawMessage(`{"run_id":"run-missing"}`))
+		var notFound *store.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("requires run_id", func(t *testing.T) {
+		_, err := handlers.HandleGetRunSessions(ctx, json.RawMessage(`{}`))
+		assert.EqualError(t, err, "invalid request: run_id is required")
+	})
+}
@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/session"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// SchedulerHandlers provides RPC handlers for inspecting the launch scheduler
+type SchedulerHandlers struct {
+	scheduler *session.LaunchScheduler
+	store     store.ConversationStore
+}
+
+// NewSchedulerHandlers creates new scheduler RPC handlers. scheduler may be nil.
+func NewSchedulerHandlers(scheduler *session.LaunchScheduler, store store.ConversationStore) *SchedulerHandlers {
+	return &SchedulerHandlers{scheduler: scheduler, store: store}
+}
+
+// QueuedSession is a session waiting for a launch slot
+type QueuedSession struct {
+	SessionID string    `json:"session_id"`
+	Owner     string    `json:"owner"`
+	Priority  int       `json:"priority"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetSchedulerStatusResponse is the response for fetching scheduler status
+type GetSchedulerStatusResponse struct {
+	Enabled bool `json:"enabled"` // True when launches are capped or throttled
+	session.SchedulerStatus
+	QueuedSessions []QueuedSession `json:"queued_sessions"` // In the order they will get a slot
+}
+
+// HandleGetSchedulerStatus returns each owner's running and queued session
+// counts and the sessions waiting for a slot
+func (h *SchedulerHandlers) HandleGetSchedulerStatus(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	if h.scheduler == nil {
+		return &GetSchedulerStatusResponse{
+			SchedulerStatus: session.SchedulerStatus{
+				Active: map[string]int{},
+				Queued: map[string]int{},
+			},
+			QueuedSessions: []QueuedSession{},
+		}, nil
+	}
+
+	status := h.scheduler.Status()
+	queued, err := h.store.ListQueuedSessions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queued sessions: %w", err)
+	}
+	queuedSessions := make([]QueuedSession, 0, len(queued))
+	for _, sess := range queued {
+		queuedSessions = append(queuedSessions, QueuedSession{
+			SessionID: sess.ID,
+			Owner:     sess.Owner,
+			Priority:  sess.Priority,
+			CreatedAt: sess.CreatedAt,
+		})
+	}
+	return &GetSchedulerStatusResponse{
+		Enabled:         status.MaxConcurrent > 0 || status.Throttle > 0,
+		SchedulerStatus: status,
+		QueuedSessions:  queuedSessions,
+	}, nil
+}
+
+// Register registers all scheduler handlers with the RPC server
+func (h *SchedulerHandlers) Register(server *Server) {
+	server.Register("getSchedulerStatus", h.HandleGetSchedulerStatus)
+}
@@ -0,0 +1,53 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/session"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetSchedulerStatus(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	base := time.Now().Add(-time.Hour)
+	for i, sess := range []*store.Session{
+		{ID: "low", Owner: "alice", Priority: 0, Status: store.SessionStatusQueued},
+		{ID: "high", Owner: "bob", Priority: 5, Status: store.SessionStatusQueued},
+		{ID: "running", Owner: "alice", Status: store.SessionStatusRunning},
+	} {
+		sess.RunID, sess.Query = "run-"+sess.ID, "q"
+		sess.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		sess.LastActivityAt = sess.CreatedAt
+		require.NoError(t, sqliteStore.CreateSession(ctx, sess))
+	}
+
+	t.Run("lists queued sessions in grant order", func(t *testing.T) {
+		h := NewSchedulerHandlers(session.NewLaunchScheduler(1, session.SchedulingFair), sqliteStore)
+		result, err := h.HandleGetSchedulerStatus(ctx, nil)
+		require.NoError(t, err)
+		resp := result.(*GetSchedulerStatusResponse)
+		assert.True(t, resp.Enabled)
+		require.Len(t, resp.QueuedSessions, 2)
+		assert.Equal(t, "high", resp.QueuedSessions[0].SessionID)
+		assert.Equal(t, "bob", resp.QueuedSessions[0].Owner)
+		assert.Equal(t, 5, resp.QueuedSessions[0].Priority)
+		assert.Equal(t, "low", resp.QueuedSessions[1].SessionID)
+	})
+
+	t.Run("reports nothing queued without a scheduler", func(t *testing.T) {
+		h := NewSchedulerHandlers(nil, sqliteStore)
+		result, err := h.HandleGetSchedulerStatus(ctx, nil)
+		require.NoError(t, err)
+		resp := result.(*GetSchedulerStatusResponse)
+		assert.False(t, resp.Enabled)
+		assert.Empty(t, resp.QueuedSessions)
+	})
+}
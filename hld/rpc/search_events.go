@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+const (
+	defaultSearchEventsLimit = 50
+	maxSearchEventsLimit     = 500
+)
+
+// SearchEventsRequest is the request for searching conversation content
+type SearchEventsRequest struct {
+	Query      string   `json:"query"`                 // Words to match; double quotes match a phrase
+	SessionID  string   `json:"session_id,omitempty"`  // Only this session's events
+	EventTypes []string `json:"event_types,omitempty"` // Any of these event types
+	Since      string   `json:"since,omitempty"`       // RFC3339, inclusive
+	Until      string   `json:"until,omitempty"`       // RFC3339, exclusive
+	Limit      int      `json:"limit,omitempty"`       // Defaults to 50, at most 500
+}
+
+// SearchEventsResponse holds the matching events, most recent first
+type SearchEventsResponse struct {
+	Events []ConversationEvent `json:"events"`
+}
+
+// HandleSearchEvents finds conversation events by their content
+func (h *SessionHandlers) HandleSearchEvents(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req SearchEventsRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.Query == "" {
+		return nil, fmt.Errorf("%w: query is required", ErrInvalidRequest)
+	}
+	if req.Limit < 0 {
+		return nil, fmt.Errorf("%w: limit must not be negative", ErrInvalidRequest)
+	}
+
+	filter := store.SearchFilter{
+		SessionID:  req.SessionID,
+		EventTypes: req.EventTypes,
+		Limit:      req.Limit,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultSearchEventsLimit
+	}
+	if filter.Limit > maxSearchEventsLimit {
+		filter.Limit = maxSearchEventsLimit
+	}
+	// Timestamps are stored in local time and compared as text
+	if req.Since != "" {
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid since: %w", ErrInvalidRequest, err)
+		}
+		filter.Since = since.Local()
+	}
+	if req.Until != "" {
+		until, err := time.Parse(time.RFC3339, req.Until)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid until: %w", ErrInvalidRequest, err)
+		}
+		filter.Until = until.Local()
+	}
+
+	events, err := h.store.SearchEvents(ctx, req.Query, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search events: %w", err)
+	}
+
+	resp := &SearchEventsResponse{Events: make([]ConversationEvent, 0, len(events))}
+	for _, event := range events {
+		resp.Events = append(resp.Events, eventToRPC(event))
+	}
+	return resp, nil
+}
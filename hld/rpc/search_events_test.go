@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSearchEvents(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	for _, id := range []string{"sess-1", "sess-2"} {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: id, RunID: "run-" + id, Query: "q", Status: store.SessionStatusRunning,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+	}
+	for _, e := range []struct{ session, content string }{
+		{"sess-1", "Deploying the payment service now"},
+		{"sess-1", "The service payment failed"},
+		{"sess-2", "Payment service deployed"},
+	} {
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+			SessionID: e.session, ClaudeSessionID: "claude-" + e.session,
+			EventType: store.EventTypeMessage, Role: "assistant", Content: e.content,
+		}))
+	}
+
+	search := func(params string) (*SearchEventsResponse, error) {
+		result, err := handlers.HandleSearchEvents(ctx, json.RawMessage(params))
+		if err != nil {
+			return nil, err
+		}
+		return result.(*SearchEventsResponse), nil
+	}
+
+	t.Run("phrase within a session", func(t *testing.T) {
+		resp, err := search(`{"query":"\"payment service\"","session_id":"sess-1"}`)
+		require.NoError(t, err)
+		require.Len(t, resp.Events, 1)
+		assert.Equal(t, "Deploying the payment service now", resp.Events[0].Content)
+		assert.Equal(t, "sess-1", resp.Events[0].SessionID)
+	})
+
+	t.Run("words in any order", func(t *testing.T) {
+		resp, err := search(`{"query":"payment service"}`)
+		require.NoError(t, err)
+		assert.Len(t, resp.Events, 3)
+
+		resp, err = search(`{"query":"payment service","limit":1}`)
+		require.NoError(t, err)
+		assert.Len(t, resp.Events, 1)
+	})
+
+	t.Run("empty result is an empty list", func(t *testing.T) {
+		result, err := handlers.HandleSearchEvents(ctx, json.RawMessage(`{"query":"OR *"}`))
+		require.NoError(t, err)
+		data, err := json.Marshal(result)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"events":[]}`, string(data))
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		for _, params := range []string{
+			`{}`,
+			`{"query":"payment","limit":-1}`,
+			`{"query":"payment","since":"yesterday"}`,
+			`{"query":"payment","until":"tomorrow"}`,
+		} {
+			_, err := search(params)
+			assert.Error(t, err, params)
+		}
+	})
+}
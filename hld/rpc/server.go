@@ -8,6 +8,7 @@ import (
 	"net"
 	"sync"
 
+	"github.com/humanlayer/humanlayer/hld/feature"
 	"github.com/humanlayer/humanlayer/hld/internal/version"
 )
 
@@ -16,6 +17,11 @@ type Server struct {
 	handlers        map[string]HandlerFunc
 	connHandlers    map[string]ConnHandlerFunc
 	subscriptionMgr *SubscriptionHandlers
+	auditLogger     *AuditLogger
+	mutatingMethods map[string]bool
+	features        *feature.Flags
+	methodFlags     map[string]string // method -> feature flag gating it
+	tokens          TokenProvider     // Required for every call but health, when set
 	mu              sync.RWMutex
 	versionOverride string
 }
@@ -29,8 +35,10 @@ type ConnHandlerFunc func(ctx context.Context, conn net.Conn, params json.RawMes
 // NewServer creates a new RPC server
 func NewServer() *Server {
 	s := &Server{
-		handlers:     make(map[string]HandlerFunc),
-		connHandlers: make(map[string]ConnHandlerFunc),
+		handlers:        make(map[string]HandlerFunc),
+		connHandlers:    make(map[string]ConnHandlerFunc),
+		mutatingMethods: make(map[string]bool),
+		methodFlags:     make(map[string]string),
 	}
 
 	// Register built-in handlers
@@ -44,6 +52,8 @@ func NewServerWithVersionOverride(versionOverride string) *Server {
 	s := &Server{
 		handlers:        make(map[string]HandlerFunc),
 		connHandlers:    make(map[string]ConnHandlerFunc),
+		mutatingMethods: make(map[string]bool),
+		methodFlags:     make(map[string]string),
 		versionOverride: versionOverride,
 	}
 
@@ -56,6 +66,8 @@ func NewServerWithVersionOverride(versionOverride string) *Server {
 // registerBuiltinHandlers registers the default RPC methods
 func (s *Server) registerBuiltinHandlers() {
 	s.Register("health", s.handleHealthCheck)
+	s.Register("listMethods", s.handleListMethods)
+	s.Register("getFeatureFlags", s.handleGetFeatureFlags)
 }
 
 // Register adds a new RPC method handler
@@ -65,6 +77,23 @@ func (s *Server) Register(method string, handler HandlerFunc) {
 	s.handlers[method] = handler
 }
 
+// RegisterMutating adds a new RPC method handler for a method that changes
+// daemon state. Calls to mutating methods are recorded in the audit log when
+// an audit logger is configured.
+func (s *Server) RegisterMutating(method string, handler HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = handler
+	s.mutatingMethods[method] = true
+}
+
+// SetAuditLogger sets the audit logger used for mutating methods
+func (s *Server) SetAuditLogger(logger *AuditLogger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auditLogger = logger
+}
+
 // RegisterConnHandler adds a new RPC method handler with connection access
 func (s *Server) RegisterConnHandler(method string, handler ConnHandlerFunc) {
 	s.mu.Lock()
@@ -72,6 +101,51 @@ func (s *Server) RegisterConnHandler(method string, handler ConnHandlerFunc) {
 	s.connHandlers[method] = handler
 }
 
+// SetTokenProvider makes every call except health carry a token from
+// provider. The caller identity used by the audit log and feature flags is
+// then derived from the token.
+func (s *Server) SetTokenProvider(provider TokenProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = provider
+}
+
+// authenticate checks the token of a call to method when the server requires
+// one, returning the context and params the handler is called with
+func (s *Server) authenticate(ctx context.Context, method string, params json.RawMessage) (context.Context, json.RawMessage, error) {
+	s.mu.RLock()
+	tokens := s.tokens
+	s.mu.RUnlock()
+	// Health stays open so clients and supervisors can probe the daemon
+	if tokens == nil || method == "health" {
+		return ctx, params, nil
+	}
+	return authenticate(context.WithValue(ctx, methodKey{}, method), tokens, params)
+}
+
+// GateMethod hides method unless flag is enabled for the caller. Calls to a
+// disabled method fail as if it didn't exist.
+func (s *Server) GateMethod(method, flag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methodFlags[method] = flag
+}
+
+// SetFeatureFlags sets the flags consulted for gated methods. Without flags,
+// gated methods follow the flag defaults.
+func (s *Server) SetFeatureFlags(flags *feature.Flags) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.features = flags
+}
+
+// methodEnabled reports whether the caller may use method. Callers must hold
+// s.mu.
+func (s *Server) methodEnabled(ctx context.Context, method string) bool {
+	flag, gated := s.methodFlags[method]
+	return !gated || s.features.Enabled(flag, IdentityFromContext(ctx))
+}
+
 // SetSubscriptionHandlers sets the subscription manager
 func (s *Server) SetSubscriptionHandlers(mgr *SubscriptionHandlers) {
 	s.mu.Lock()
@@ -114,10 +188,30 @@ func (s *Server) ServeConn(ctx context.Context, conn net.Conn) error {
 			continue
 		}
 
-		// Check if this is a Subscribe request
-		if req.Method == "Subscribe" && s.subscriptionMgr != nil {
-			// Handle subscription directly
-			return s.subscriptionMgr.SubscribeConn(ctx, conn, req.Params)
+		// Subscribe and connection handlers take over the connection for
+		// streaming responses, so they are authenticated here
+		s.mu.RLock()
+		subscriptionMgr := s.subscriptionMgr
+		connHandler, isConn := s.connHandlers[req.Method]
+		s.mu.RUnlock()
+		isSubscribe := req.Method == "Subscribe" && subscriptionMgr != nil
+		if isSubscribe || isConn {
+			connCtx, params, err := s.authenticate(ctx, req.Method, req.Params)
+			if err != nil {
+				if err := s.sendResponse(conn, errorResponse(req.ID, err)); err != nil {
+					return fmt.Errorf("failed to send error response: %w", err)
+				}
+				continue
+			}
+			if isSubscribe {
+				return subscriptionMgr.SubscribeConn(connCtx, conn, params)
+			}
+			s.mu.RLock()
+			enabled := s.methodEnabled(connCtx, req.Method)
+			s.mu.RUnlock()
+			if enabled {
+				return connHandler(connCtx, conn, params)
+			}
 		}
 
 		// Process normal request
@@ -194,9 +288,17 @@ func (s *Server) handleRequest(ctx context.Context, data []byte) *Response {
 		}
 	}
 
+	ctx, params, err := s.authenticate(ctx, req.Method, req.Params)
+	if err != nil {
+		return errorResponse(req.ID, err)
+	}
+
 	// Find handler
 	s.mu.RLock()
 	handler, ok := s.handlers[req.Method]
+	ok = ok && s.methodEnabled(ctx, req.Method)
+	mutating := s.mutatingMethods[req.Method]
+	auditLogger := s.auditLogger
 	s.mu.RUnlock()
 
 	if !ok {
@@ -210,17 +312,15 @@ func (s *Server) handleRequest(ctx context.Context, data []byte) *Response {
 		}
 	}
 
+	// Record state-changing calls in the audit log
+	if mutating && auditLogger != nil {
+		handler = auditLogger.Wrap(req.Method, handler)
+	}
+
 	// Execute handler
-	result, err := handler(ctx, req.Params)
+	result, err := handler(ctx, params)
 	if err != nil {
-		return &Response{
-			JSONRPC: "2.0",
-			Error: &Error{
-				Code:    InternalError,
-				Message: err.Error(),
-			},
-			ID: req.ID,
-		}
+		return errorResponse(req.ID, err)
 	}
 
 	return &Response{
@@ -230,6 +330,20 @@ func (s *Server) handleRequest(ctx context.Context, data []byte) *Response {
 	}
 }
 
+// errorResponse returns the response to the call with id that failed with err
+func errorResponse(id interface{}, err error) *Response {
+	class := classifyError(err)
+	return &Response{
+		JSONRPC: "2.0",
+		Error: &Error{
+			Code:    errorCode(err, class),
+			Message: err.Error(),
+			Data:    newErrorData(err, class),
+		},
+		ID: id,
+	}
+}
+
 // sendResponse writes a response to the connection
 func (s *Server) sendResponse(conn net.Conn, resp *Response) error {
 	data, err := json.Marshal(resp)
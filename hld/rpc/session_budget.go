@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// SetSessionBudgetRequest sets or removes a session's cost cap
+type SetSessionBudgetRequest struct {
+	SessionID  string   `json:"session_id"`
+	MaxCostUSD *float64 `json:"max_cost_usd"` // Null removes the cap
+}
+
+// SetSessionBudgetResponse reports a session's cap and what it has spent
+type SetSessionBudgetResponse struct {
+	SessionID  string   `json:"session_id"`
+	MaxCostUSD *float64 `json:"max_cost_usd"`
+	CostUSD    float64  `json:"cost_usd"`
+	Exceeded   bool     `json:"exceeded"` // Set when the session has already spent more than the cap
+}
+
+// HandleSetSessionBudget sets the cost past which a session's events are
+// refused. Lowering the cap below what the session has spent takes effect
+// at its next event.
+func (h *SessionHandlers) HandleSetSessionBudget(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req SetSessionBudgetRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+	if req.MaxCostUSD != nil && *req.MaxCostUSD < 0 {
+		return nil, fmt.Errorf("%w: max_cost_usd cannot be negative", ErrInvalidRequest)
+	}
+
+	sess, err := h.store.GetSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if err := h.store.UpdateSession(ctx, req.SessionID, store.SessionUpdate{MaxCostUSD: &req.MaxCostUSD}); err != nil {
+		return nil, fmt.Errorf("failed to set session budget: %w", err)
+	}
+
+	resp := &SetSessionBudgetResponse{SessionID: sess.ID, MaxCostUSD: req.MaxCostUSD}
+	if sess.CostUSD != nil {
+		resp.CostUSD = *sess.CostUSD
+	}
+	resp.Exceeded = resp.MaxCostUSD != nil && resp.CostUSD > *resp.MaxCostUSD
+	return resp, nil
+}
@@ -0,0 +1,64 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSetSessionBudget(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	spent := 0.75
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID: "sess-1", RunID: "run-sess-1", ClaudeSessionID: "claude-1", Status: store.SessionStatusRunning,
+		CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+	require.NoError(t, sqliteStore.UpdateSession(ctx, "sess-1", store.SessionUpdate{CostUSD: &spent}))
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	result, err := handlers.HandleSetSessionBudget(ctx, json.RawMessage(`{"session_id":"sess-1","max_cost_usd":1.5}`))
+	require.NoError(t, err)
+	resp := result.(*SetSessionBudgetResponse)
+	require.NotNil(t, resp.MaxCostUSD)
+	assert.Equal(t, 1.5, *resp.MaxCostUSD)
+	assert.Equal(t, 0.75, resp.CostUSD)
+	assert.False(t, resp.Exceeded)
+
+	state, err := handlers.HandleGetSessionState(ctx, json.RawMessage(`{"session_id":"sess-1"}`))
+	require.NoError(t, err)
+	require.NotNil(t, state.(*GetSessionStateResponse).Session.MaxCostUSD)
+	assert.Equal(t, 1.5, *state.(*GetSessionStateResponse).Session.MaxCostUSD)
+
+	// Lowering the cap below what was spent refuses the next event
+	result, err = handlers.HandleSetSessionBudget(ctx, json.RawMessage(`{"session_id":"sess-1","max_cost_usd":0.5}`))
+	require.NoError(t, err)
+	assert.True(t, result.(*SetSessionBudgetResponse).Exceeded)
+	err = sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+		SessionID: "sess-1", ClaudeSessionID: "claude-1", EventType: store.EventTypeMessage, Role: "assistant", Content: "over",
+	})
+	assert.ErrorIs(t, err, store.ErrBudgetExceeded)
+
+	result, err = handlers.HandleSetSessionBudget(ctx, json.RawMessage(`{"session_id":"sess-1","max_cost_usd":null}`))
+	require.NoError(t, err)
+	assert.Nil(t, result.(*SetSessionBudgetResponse).MaxCostUSD)
+	assert.False(t, result.(*SetSessionBudgetResponse).Exceeded)
+
+	t.Run("errors", func(t *testing.T) {
+		var notFound *store.NotFoundError
+		_, err := handlers.HandleSetSessionBudget(ctx, json.RawMessage(`{"session_id":"sess-unknown","max_cost_usd":1}`))
+		assert.ErrorAs(t, err, &notFound)
+		_, err = handlers.HandleSetSessionBudget(ctx, json.RawMessage(`{"max_cost_usd":1}`))
+		assert.EqualError(t, err, "invalid request: session_id is required")
+		_, err = handlers.HandleSetSessionBudget(ctx, json.RawMessage(`{"session_id":"sess-1","max_cost_usd":-1}`))
+		assert.EqualError(t, err, "invalid request: max_cost_usd cannot be negative")
+	})
+}
@@ -0,0 +1,169 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// Tool node statuses
+const (
+	ToolNodeStatusPending   = "pending"
+	ToolNodeStatusCompleted = "completed"
+	ToolNodeStatusFailed    = "failed"
+)
+
+// GetSessionGraphRequest is the request for a session's tool call graph
+type GetSessionGraphRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// ToolNode is a tool call in a session's tool call graph
+type ToolNode struct {
+	EventID  int64  `json:"event_id"`
+	ToolName string `json:"tool_name"`
+	Status   string `json:"status"` // pending, completed or failed
+}
+
+// ToolEdge records that a tool call's input used a value from an earlier
+// tool call's result
+type ToolEdge struct {
+	FromEventID int64    `json:"from_event_id"`
+	ToEventID   int64    `json:"to_event_id"`
+	SharedKeys  []string `json:"shared_keys"` // Input fields of the later call holding the values
+}
+
+// GetSessionGraphResponse is the response for a session's tool call graph
+type GetSessionGraphResponse struct {
+	Nodes []ToolNode `json:"nodes"`
+	Edges []ToolEdge `json:"edges"`
+}
+
+// HandleGetSessionGraph returns a session's tool calls as a graph, with an
+// edge from one call to a later one when a string value in the first call's
+// JSON result appears in the later call's input. Edges only point forward in
+// the session, so the graph is acyclic. Results that aren't JSON add no
+// edges. Events inherited from a parent session are left out.
+func (h *SessionHandlers) HandleGetSessionGraph(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetSessionGraphRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+
+	sess, err := h.store.GetSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	// Sessions get a Claude session ID with their first event
+	var stored []*store.ConversationEvent
+	if sess.ClaudeSessionID != "" {
+		if stored, err = h.store.GetConversation(ctx, sess.ClaudeSessionID, store.ConversationPage{}); err != nil {
+			return nil, fmt.Errorf("failed to get conversation: %w", err)
+		}
+	}
+
+	// A result's values can feed any tool call made after it arrived
+	type source struct {
+		eventID int64
+		values  map[string]bool
+	}
+	var sources []source
+	callIDs := make(map[string]int64)
+	resp := &GetSessionGraphResponse{Nodes: []ToolNode{}, Edges: []ToolEdge{}}
+	nodes := make(map[int64]int)
+	for _, event := range stored {
+		if event.SessionID != sess.ID {
+			continue
+		}
+		switch event.EventType {
+		case store.EventTypeToolCall:
+			nodes[event.ID] = len(resp.Nodes)
+			resp.Nodes = append(resp.Nodes, ToolNode{
+				EventID: event.ID, ToolName: event.ToolName, Status: ToolNodeStatusPending,
+			})
+			callIDs[event.ToolID] = event.ID
+
+			fields := jsonStringFields(event.ToolInputJSON)
+			for _, src := range sources {
+				keys := make(map[string]bool)
+				for _, f := range fields {
+					if src.values[f.value] {
+						keys[f.key] = true
+					}
+				}
+				if len(keys) == 0 {
+					continue
+				}
+				edge := ToolEdge{FromEventID: src.eventID, ToEventID: event.ID}
+				for key := range keys {
+					edge.SharedKeys = append(edge.SharedKeys, key)
+				}
+				sort.Strings(edge.SharedKeys)
+				resp.Edges = append(resp.Edges, edge)
+			}
+		case store.EventTypeToolResult:
+			callID, ok := callIDs[event.ToolResultForID]
+			if !ok {
+				continue
+			}
+			node := &resp.Nodes[nodes[callID]]
+			node.Status = ToolNodeStatusCompleted
+			if event.ToolError != "" {
+				node.Status = ToolNodeStatusFailed
+			}
+
+			values := make(map[string]bool)
+			for _, f := range jsonStringFields(event.ToolResultContent) {
+				values[f.value] = true
+			}
+			if len(values) > 0 {
+				sources = append(sources, source{eventID: callID, values: values})
+			}
+		}
+	}
+	return resp, nil
+}
+
+// jsonField is a non-empty string value in a JSON document and the name of
+// the object field holding it
+type jsonField struct {
+	key, value string
+}
+
+// jsonStringFields returns the non-empty string values held by object
+// fields anywhere in a JSON document, or nil if it isn't JSON. Strings in
+// arrays belong to the field holding the array. Numbers and booleans are
+// left out, since small ones match by coincidence too often to show a
+// dependency.
+func jsonStringFields(doc string) []jsonField {
+	var v interface{}
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		return nil
+	}
+	var fields []jsonField
+	var walk func(key string, v interface{})
+	walk = func(key string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				walk(k, child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(key, child)
+			}
+		case string:
+			if key != "" && v != "" {
+				fields = append(fields, jsonField{key: key, value: v})
+			}
+		}
+	}
+	walk("", v)
+	return fields
+}
@@ -0,0 +1,81 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetSessionGraph(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	for _, id := range []string{"sess-1", "empty"} {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: id, RunID: "run-" + id, ClaudeSessionID: "claude-" + id, Query: "q",
+			Status: store.SessionStatusCompleted, CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+	}
+
+	// Glob finds a file that Read then opens and Edit changes; Bash runs
+	// on its own and fails, and the last Read never gets a result
+	events := []store.ConversationEvent{
+		{EventType: store.EventTypeMessage, Role: "user", Content: "Fix the typo"},
+		{EventType: store.EventTypeToolCall, ToolID: "glob", ToolName: "Glob", ToolInputJSON: `{"pattern":"**/*.md"}`},
+		{EventType: store.EventTypeToolResult, ToolResultForID: "glob", ToolResultContent: `{"files":["docs/README.md"],"count":1}`},
+		{EventType: store.EventTypeToolCall, ToolID: "read", ToolName: "Read", ToolInputJSON: `{"file_path":"docs/README.md"}`},
+		{EventType: store.EventTypeToolResult, ToolResultForID: "read", ToolResultContent: "# Readme wiht a typo"},
+		{EventType: store.EventTypeToolCall, ToolID: "bash", ToolName: "Bash", ToolInputJSON: `{"command":"make lint","timeout":1}`},
+		{EventType: store.EventTypeToolResult, ToolResultForID: "bash", ToolError: "exit status 2", ToolResultContent: `{"stderr":"no rule"}`},
+		{EventType: store.EventTypeToolCall, ToolID: "edit", ToolName: "Edit", ToolInputJSON: `{"path":"docs/README.md","edits":[{"old":"wiht","new":"with"}]}`},
+		{EventType: store.EventTypeToolResult, ToolResultForID: "edit", ToolResultContent: `{"ok":true}`},
+		{EventType: store.EventTypeToolCall, ToolID: "reread", ToolName: "Read", ToolInputJSON: `{"file_path":"docs/README.md"}`},
+	}
+	ids := make(map[string]int64)
+	for _, event := range events {
+		event.SessionID, event.ClaudeSessionID = "sess-1", "claude-sess-1"
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, &event))
+		if event.EventType == store.EventTypeToolCall {
+			ids[event.ToolID] = event.ID
+		}
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	result, err := handlers.HandleGetSessionGraph(ctx, json.RawMessage(`{"session_id":"sess-1"}`))
+	require.NoError(t, err)
+	resp := result.(*GetSessionGraphResponse)
+	assert.Equal(t, []ToolNode{
+		{EventID: ids["glob"], ToolName: "Glob", Status: ToolNodeStatusCompleted},
+		{EventID: ids["read"], ToolName: "Read", Status: ToolNodeStatusCompleted},
+		{EventID: ids["bash"], ToolName: "Bash", Status: ToolNodeStatusFailed},
+		{EventID: ids["edit"], ToolName: "Edit", Status: ToolNodeStatusCompleted},
+		{EventID: ids["reread"], ToolName: "Read", Status: ToolNodeStatusPending},
+	}, resp.Nodes)
+	assert.Equal(t, []ToolEdge{
+		{FromEventID: ids["glob"], ToEventID: ids["read"], SharedKeys: []string{"file_path"}},
+		{FromEventID: ids["glob"], ToEventID: ids["edit"], SharedKeys: []string{"path"}},
+		{FromEventID: ids["glob"], ToEventID: ids["reread"], SharedKeys: []string{"file_path"}},
+	}, resp.Edges)
+
+	t.Run("no events", func(t *testing.T) {
+		result, err := handlers.HandleGetSessionGraph(ctx, json.RawMessage(`{"session_id":"empty"}`))
+		require.NoError(t, err)
+		resp := result.(*GetSessionGraphResponse)
+		assert.Empty(t, resp.Nodes)
+		assert.NotNil(t, resp.Edges)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := handlers.HandleGetSessionGraph(ctx, json.RawMessage(`{}`))
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+		_, err = handlers.HandleGetSessionGraph(ctx, json.RawMessage(`{"session_id":"missing"}`))
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
@@ -0,0 +1,49 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// GetSessionMetricsRequest is the request for a session's activity summary
+type GetSessionMetricsRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// SessionMetrics summarizes a session's messages, tool calls and cost
+type SessionMetrics struct {
+	SessionID        string         `json:"session_id"`
+	Messages         int            `json:"messages"`
+	ToolCalls        int            `json:"tool_calls"`
+	ToolCallsByName  map[string]int `json:"tool_calls_by_name"`
+	TimedToolCalls   int            `json:"timed_tool_calls"`    // Calls with a result
+	AvgToolLatencyMS float64        `json:"avg_tool_latency_ms"` // Call to result, one-second resolution
+	CostUSD          float64        `json:"cost_usd"`
+}
+
+// HandleGetSessionMetrics summarizes a session's tool use without
+// transferring its events
+func (h *SessionHandlers) HandleGetSessionMetrics(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetSessionMetricsRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+
+	metrics, err := h.store.GetSessionMetrics(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session metrics: %w", err)
+	}
+	return &SessionMetrics{
+		SessionID:        req.SessionID,
+		Messages:         metrics.Messages,
+		ToolCalls:        metrics.ToolCalls,
+		ToolCallsByName:  metrics.ToolCallsByName,
+		TimedToolCalls:   metrics.TimedToolCalls,
+		AvgToolLatencyMS: metrics.AvgToolLatencyMS,
+		CostUSD:          metrics.CostUSD,
+	}, nil
+}
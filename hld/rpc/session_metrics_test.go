@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandleGetSessionMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+	ctx := context.Background()
+
+	t.Run("maps store metrics", func(t *testing.T) {
+		mockStore.EXPECT().GetSessionMetrics(gomock.Any(), "sess-1").Return(&store.SessionMetrics{
+			Messages:         2,
+			ToolCalls:        3,
+			ToolCallsByName:  map[string]int{"Bash": 2, "Read": 1},
+			TimedToolCalls:   2,
+			AvgToolLatencyMS: 4000,
+			CostUSD:          0.42,
+		}, nil)
+
+		result, err := handlers.HandleGetSessionMetrics(ctx, json.RawMessage(`{"session_id":"sess-1"}`))
+		require.NoError(t, err)
+		data, err := json.Marshal(result)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"session_id": "sess-1",
+			"messages": 2,
+			"tool_calls": 3,
+			"tool_calls_by_name": {"Bash": 2, "Read": 1},
+			"timed_tool_calls": 2,
+			"avg_tool_latency_ms": 4000,
+			"cost_usd": 0.42
+		}`, string(data))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := handlers.HandleGetSessionMetrics(ctx, json.RawMessage(`{}`))
+		assert.Error(t, err)
+
+		mockStore.EXPECT().GetSessionMetrics(gomock.Any(), "missing").Return(nil, &store.NotFoundError{Type: "session", ID: "missing"})
+		_, err = handlers.HandleGetSessionMetrics(ctx, json.RawMessage(`{"session_id":"missing"}`))
+		var notFound *store.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
@@ -0,0 +1,222 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/session"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// SessionRetention deletes finished sessions once they have gone the
+// retention period without activity, and the least recently active ones
+// beyond maxSessions, then returns the space they took to the filesystem.
+// It removes the same sessions session eviction may: running sessions,
+// sessions with pending approvals and sessions that others were continued
+// from are kept.
+type SessionRetention struct {
+	store       store.ConversationStore
+	retention   time.Duration
+	maxSessions int
+	interval    time.Duration
+	attachments session.AttachmentPruner
+	now         func() time.Time
+}
+
+// NewSessionRetention creates a new session retention runner. A zero
+// retention or maxSessions disables that limit.
+func NewSessionRetention(store store.ConversationStore, retention time.Duration, maxSessions int, interval time.Duration) *SessionRetention {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &SessionRetention{
+		store:       store,
+		retention:   retention,
+		maxSessions: maxSessions,
+		interval:    interval,
+		now:         time.Now,
+	}
+}
+
+// SetAttachmentPruner makes retention delete a session's attachment content
+// along with its rows
+func (r *SessionRetention) SetAttachmentPruner(pruner session.AttachmentPruner) {
+	r.attachments = pruner
+}
+
+// Start periodically prunes expired sessions until ctx is cancelled
+func (r *SessionRetention) Start(ctx context.Context) {
+	slog.Info("starting session retention",
+		"retention", r.retention,
+		"max_sessions", r.maxSessions,
+		"interval", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	// Do an initial pass immediately
+	r.logPrune(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("session retention shutting down")
+			return
+		case <-ticker.C:
+			r.logPrune(ctx)
+		}
+	}
+}
+
+func (r *SessionRetention) logPrune(ctx context.Context) {
+	resp, err := r.PruneOnce(ctx)
+	if err != nil {
+		slog.Error("failed to prune expired sessions", "error", err)
+	}
+	if resp.DeletedSessions > 0 {
+		slog.Info("pruned expired sessions",
+			"sessions", resp.DeletedSessions,
+			"events", resp.DeletedEvents,
+			"retention", r.retention,
+			"max_sessions", r.maxSessions)
+	}
+}
+
+// PruneOnce runs a single retention pass. On error, the response holds what
+// was deleted before it.
+func (r *SessionRetention) PruneOnce(ctx context.Context) (*RunRetentionResponse, error) {
+	if r.store == nil || (r.retention <= 0 && r.maxSessions <= 0) {
+		return &RunRetentionResponse{}, nil
+	}
+	var olderThan time.Time
+	if r.retention > 0 {
+		olderThan = r.now().Add(-r.retention)
+	}
+	return pruneSessions(ctx, r.store, r.attachments, olderThan, r.maxSessions)
+}
+
+// pruneSessions deletes the sessions GetExpiredSessionIDs returns for
+// olderThan, then those GetEvictableSessionIDs returns for maxSessions, and
+// vacuums the database if any were deleted. A zero olderThan or maxSessions
+// skips that step.
+func pruneSessions(ctx context.Context, s store.ConversationStore, attachments session.AttachmentPruner, olderThan time.Time, maxSessions int) (*RunRetentionResponse, error) {
+	resp := &RunRetentionResponse{}
+	if !olderThan.IsZero() {
+		ids, err := s.GetExpiredSessionIDs(ctx, olderThan)
+		if err != nil {
+			return resp, fmt.Errorf("failed to get expired sessions: %w", err)
+		}
+		if err := deleteRetainedSessions(ctx, s, attachments, ids, resp); err != nil {
+			return resp, err
+		}
+	}
+	if maxSessions > 0 {
+		// Counted after the expired sessions are gone
+		ids, err := s.GetEvictableSessionIDs(ctx, maxSessions)
+		if err != nil {
+			return resp, fmt.Errorf("failed to get sessions over the cap: %w", err)
+		}
+		if err := deleteRetainedSessions(ctx, s, attachments, ids, resp); err != nil {
+			return resp, err
+		}
+	}
+
+	if resp.DeletedSessions > 0 {
+		if err := s.IncrementalVacuum(ctx); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+// deleteRetainedSessions deletes the sessions with ids and adds them and
+// their events to resp
+func deleteRetainedSessions(ctx context.Context, s store.ConversationStore, attachments session.AttachmentPruner, ids []string, resp *RunRetentionResponse) error {
+	for _, id := range ids {
+		// Finished sessions gain no events, so the count holds until the
+		// session is deleted
+		counts, err := s.CountEventsByType(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to count events of session %s: %w", id, err)
+		}
+		if attachments != nil {
+			// Keep the session if its attachments can't be deleted, so they
+			// stay referenced and the next pass retries
+			if err := attachments.DeleteSessionAttachments(ctx, id); err != nil {
+				return err
+			}
+		}
+		if err := s.DeleteSessionData(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete session %s: %w", id, err)
+		}
+		resp.DeletedSessions++
+		for _, n := range counts {
+			resp.DeletedEvents += int64(n)
+		}
+	}
+	return nil
+}
+
+// RunRetentionRequest is the request for pruning expired sessions on demand.
+// Each limit defaults to the daemon's setting.
+type RunRetentionRequest struct {
+	RetentionDays int `json:"retention_days,omitempty"` // Defaults to the daemon's retention_days
+	MaxSessions   int `json:"max_sessions,omitempty"`   // Defaults to the daemon's retention_max_sessions
+}
+
+// RunRetentionResponse counts what a retention pass deleted
+type RunRetentionResponse struct {
+	DeletedSessions int64 `json:"deleted_sessions"`
+	DeletedEvents   int64 `json:"deleted_events"`
+}
+
+// SetSessionRetention gives runRetention the daemon's retention limits
+func (h *SessionHandlers) SetSessionRetention(retention *SessionRetention) {
+	h.retention = retention
+}
+
+// HandleRunRetention deletes finished sessions inactive for longer than
+// retention_days and the least recently active ones beyond max_sessions,
+// defaulting to the daemon's configured limits, along with their
+// conversations, and reports how many of each were deleted
+func (h *SessionHandlers) HandleRunRetention(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req RunRetentionRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+	}
+
+	if req.RetentionDays < 0 {
+		return nil, fmt.Errorf("%w: retention_days cannot be negative", ErrInvalidRequest)
+	}
+	if req.MaxSessions < 0 {
+		return nil, fmt.Errorf("%w: max_sessions cannot be negative", ErrInvalidRequest)
+	}
+	retention := time.Duration(req.RetentionDays) * 24 * time.Hour
+	maxSessions := req.MaxSessions
+	if h.retention != nil {
+		if retention == 0 {
+			retention = h.retention.retention
+		}
+		if maxSessions == 0 {
+			maxSessions = h.retention.maxSessions
+		}
+	}
+	if retention <= 0 && maxSessions <= 0 {
+		return nil, fmt.Errorf("%w: retention_days or max_sessions is required when the daemon has no retention configured", ErrInvalidRequest)
+	}
+
+	var olderThan time.Time
+	if retention > 0 {
+		olderThan = time.Now().Add(-retention)
+	}
+	resp, err := pruneSessions(ctx, h.store, h.attachments, olderThan, maxSessions)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionRetention(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	now := time.Now()
+	for _, sess := range []struct {
+		id, status   string
+		lastActivity time.Time
+		events       int
+	}{
+		{id: "expired", status: store.SessionStatusCompleted, lastActivity: now.Add(-10 * 24 * time.Hour), events: 3},
+		{id: "expired-failed", status: store.SessionStatusFailed, lastActivity: now.Add(-10 * 24 * time.Hour), events: 1},
+		{id: "stale", status: store.SessionStatusCompleted, lastActivity: now.Add(-3 * 24 * time.Hour), events: 2},
+		{id: "recent", status: store.SessionStatusCompleted, lastActivity: now, events: 1},
+		{id: "running", status: store.SessionStatusRunning, lastActivity: now.Add(-10 * 24 * time.Hour)},
+		{id: "waiting", status: store.SessionStatusCompleted, lastActivity: now.Add(-10 * 24 * time.Hour)},
+	} {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: sess.id, RunID: "run-" + sess.id, ClaudeSessionID: "claude-" + sess.id, Query: "q", Status: sess.status,
+			CreatedAt: sess.lastActivity, LastActivityAt: sess.lastActivity,
+		}))
+		for range sess.events {
+			require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+				SessionID: sess.id, ClaudeSessionID: "claude-" + sess.id,
+				EventType: store.EventTypeMessage, Role: "assistant", Content: "done",
+			}))
+		}
+	}
+	require.NoError(t, sqliteStore.CreateApproval(ctx, &store.Approval{
+		ID: "appr-waiting", RunID: "run-waiting", SessionID: "waiting", Status: store.ApprovalStatusLocalPending,
+		CreatedAt: now, ToolName: "Bash", ToolInput: json.RawMessage(`{}`),
+	}))
+
+	exists := func(id string) bool {
+		_, err := sqliteStore.GetSession(ctx, id)
+		return err == nil
+	}
+
+	pruner := &fakeAttachmentPruner{}
+	retention := NewSessionRetention(sqliteStore, 7*24*time.Hour, 0, 0)
+	retention.SetAttachmentPruner(pruner)
+	resp, err := retention.PruneOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &RunRetentionResponse{DeletedSessions: 2, DeletedEvents: 4}, resp)
+	assert.ElementsMatch(t, []string{"expired", "expired-failed"}, pruner.deleted)
+	for id, kept := range map[string]bool{
+		"expired": false, "expired-failed": false, "stale": true, "recent": true, "running": true, "waiting": true,
+	} {
+		assert.Equal(t, kept, exists(id), id)
+	}
+
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	t.Run("requires a retention period", func(t *testing.T) {
+		_, err := handlers.HandleRunRetention(ctx, nil)
+		assert.EqualError(t, err, "invalid request: retention_days or max_sessions is required when the daemon has no retention configured")
+		_, err = handlers.HandleRunRetention(ctx, json.RawMessage(`{"retention_days":-1}`))
+		assert.EqualError(t, err, "invalid request: retention_days cannot be negative")
+		_, err = handlers.HandleRunRetention(ctx, json.RawMessage(`{"max_sessions":-1}`))
+		assert.EqualError(t, err, "invalid request: max_sessions cannot be negative")
+	})
+
+	t.Run("defaults to the configured retention", func(t *testing.T) {
+		handlers.SetSessionRetention(retention)
+		result, err := handlers.HandleRunRetention(ctx, json.RawMessage(`{}`))
+		require.NoError(t, err)
+		assert.Equal(t, &RunRetentionResponse{}, result)
+	})
+
+	t.Run("retention_days overrides the configured retention", func(t *testing.T) {
+		result, err := handlers.HandleRunRetention(ctx, json.RawMessage(`{"retention_days":2}`))
+		require.NoError(t, err)
+		assert.Equal(t, &RunRetentionResponse{DeletedSessions: 1, DeletedEvents: 2}, result)
+		assert.False(t, exists("stale"))
+		assert.True(t, exists("recent"))
+	})
+
+	t.Run("max_sessions prunes the least recently active removable sessions", func(t *testing.T) {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: "newest", RunID: "run-newest", ClaudeSessionID: "claude-newest", Query: "q",
+			Status: store.SessionStatusCompleted, CreatedAt: now, LastActivityAt: now.Add(time.Minute),
+		}))
+		require.NoError(t, sqliteStore.SoftDeleteSession(ctx, "newest"))
+
+		// Four sessions are left; the running and waiting ones can't be pruned
+		result, err := handlers.HandleRunRetention(ctx, json.RawMessage(`{"max_sessions":2}`))
+		require.NoError(t, err)
+		assert.Equal(t, &RunRetentionResponse{DeletedSessions: 2, DeletedEvents: 1}, result)
+		assert.False(t, exists("recent"))
+		_, err = sqliteStore.GetSessionIncludingDeleted(ctx, "newest")
+		assert.ErrorIs(t, err, store.ErrNotFound, "soft-deleted sessions are pruned too")
+		assert.True(t, exists("running"))
+		assert.True(t, exists("waiting"))
+	})
+
+	t.Run("the configured cap applies without a retention period", func(t *testing.T) {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: "extra", RunID: "run-extra", ClaudeSessionID: "claude-extra", Query: "q",
+			Status: store.SessionStatusCancelled, CreatedAt: now, LastActivityAt: now,
+		}))
+		capped := NewSessionRetention(sqliteStore, 0, 2, 0)
+		resp, err := capped.PruneOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, &RunRetentionResponse{DeletedSessions: 1}, resp)
+		assert.False(t, exists("extra"))
+	})
+}
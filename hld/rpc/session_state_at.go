@@ -0,0 +1,185 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// GetSessionStateAtRequest is the request for a session's state at a past time
+type GetSessionStateAtRequest struct {
+	SessionID string `json:"session_id"`
+	At        string `json:"at"` // RFC3339
+}
+
+// GetSessionStateAtResponse is a session's summary as of a point in time. It
+// is reconstructed from the session's timestamps, recorded turns, approvals
+// and events rather than read from stored snapshots.
+type GetSessionStateAtResponse struct {
+	SessionID string `json:"session_id"`
+	At        string `json:"at"`
+	Status    string `json:"status"`
+	// CostUSD is the session total once it had ended. Before that only the
+	// total is known, so it is apportioned across turns by output tokens and
+	// CostEstimated is set; it is nil if no turns were recorded.
+	CostUSD          *float64 `json:"cost_usd,omitempty"`
+	CostEstimated    bool     `json:"cost_estimated,omitempty"`
+	Turns            int      `json:"turns"`
+	OutputTokens     int      `json:"output_tokens"`
+	Events           int      `json:"events"`
+	ToolCalls        int      `json:"tool_calls"`
+	PendingApprovals int      `json:"pending_approvals"`
+	LastActivityAt   string   `json:"last_activity_at"`
+}
+
+// HandleGetSessionStateAt reconstructs a session's state at a past time
+func (h *SessionHandlers) HandleGetSessionStateAt(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetSessionStateAtRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+	if req.At == "" {
+		return nil, fmt.Errorf("%w: at is required", ErrInvalidRequest)
+	}
+	at, err := time.Parse(time.RFC3339, req.At)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid at: %w", ErrInvalidRequest, err)
+	}
+
+	sess, err := h.store.GetSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if at.Before(sess.CreatedAt.Truncate(time.Second)) {
+		return nil, &store.NotFoundError{Type: "session", ID: req.SessionID}
+	}
+
+	turns, err := h.store.GetSessionTurns(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session turns: %w", err)
+	}
+	approvals, err := h.store.GetSessionApprovals(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get approvals: %w", err)
+	}
+	// Sessions get a Claude session ID with their first event
+	var events []*store.ConversationEvent
+	if sess.ClaudeSessionID != "" {
+		if events, err = h.store.GetConversation(ctx, sess.ClaudeSessionID, store.ConversationPage{}); err != nil {
+			return nil, fmt.Errorf("failed to get conversation: %w", err)
+		}
+	}
+
+	resp := &GetSessionStateAtResponse{
+		SessionID:      req.SessionID,
+		At:             at.Format(time.RFC3339),
+		LastActivityAt: sess.CreatedAt.Format(time.RFC3339),
+	}
+	lastActivity := sess.CreatedAt
+	noteActivity := func(t time.Time) {
+		if t.After(lastActivity) {
+			lastActivity = t
+		}
+	}
+
+	for _, event := range events {
+		if event.SessionID != sess.ID || event.CreatedAt.After(at) {
+			continue
+		}
+		resp.Events++
+		if event.EventType == store.EventTypeToolCall {
+			resp.ToolCalls++
+		}
+		noteActivity(event.CreatedAt)
+	}
+	for _, approval := range approvals {
+		if approval.CreatedAt.After(at) {
+			continue
+		}
+		if approval.RespondedAt == nil || approval.RespondedAt.After(at) {
+			resp.PendingApprovals++
+		}
+	}
+
+	var total float64
+	if sess.CostUSD != nil {
+		total = *sess.CostUSD
+	}
+	costs := turnCosts(total, turns)
+	var cost float64
+	for i, turn := range turns {
+		if turn.CreatedAt.After(at) {
+			continue
+		}
+		resp.Turns++
+		resp.OutputTokens += turn.OutputTokens
+		if costs != nil {
+			cost += costs[i]
+		}
+		noteActivity(turn.CreatedAt)
+	}
+
+	resp.Status = statusAt(sess, at, resp.PendingApprovals > 0)
+	if end := sessionEnd(sess); end != nil && !at.Before(*end) {
+		// The session had ended, so its final totals apply
+		resp.CostUSD = sess.CostUSD
+		if sess.OutputTokens != nil {
+			resp.OutputTokens = max(resp.OutputTokens, *sess.OutputTokens)
+		}
+		noteActivity(*end)
+	} else if resp.Turns > 0 && costs != nil {
+		resp.CostUSD = &cost
+		resp.CostEstimated = true
+	}
+	resp.LastActivityAt = lastActivity.Format(time.RFC3339)
+	return resp, nil
+}
+
+// sessionEnd returns when a session reached a final status, or nil if it
+// hasn't. Sessions that ended without a completion time use their last
+// activity.
+func sessionEnd(sess *store.Session) *time.Time {
+	switch sess.Status {
+	case store.SessionStatusCompleted, store.SessionStatusFailed,
+		store.SessionStatusInterrupted, store.SessionStatusDiscarded, store.SessionStatusCancelled:
+		if sess.CompletedAt != nil {
+			return sess.CompletedAt
+		}
+		return &sess.LastActivityAt
+	}
+	return nil
+}
+
+// statusAt infers the status a session had at a point in its lifetime
+func statusAt(sess *store.Session, at time.Time, waitingForApproval bool) string {
+	if end := sessionEnd(sess); end != nil && !at.Before(*end) {
+		return sess.Status
+	}
+	switch {
+	case sess.StartedAt != nil && !at.Before(*sess.StartedAt):
+		if waitingForApproval {
+			return store.SessionStatusWaitingInput
+		}
+		return store.SessionStatusRunning
+	case sess.QueuedAt != nil && !at.Before(*sess.QueuedAt):
+		return store.SessionStatusStarting
+	case sess.Status == store.SessionStatusDraft || sess.Status == store.SessionStatusDiscarded:
+		return store.SessionStatusDraft
+	case sess.QueuedAt != nil:
+		// Sessions queue as soon as they are created unless they started as
+		// a draft that was launched later
+		if sess.QueuedAt.Sub(sess.CreatedAt) > time.Second {
+			return store.SessionStatusDraft
+		}
+		return store.SessionStatusStarting
+	default:
+		// Launched before launch times were recorded
+		return store.SessionStatusRunning
+	}
+}
@@ -0,0 +1,147 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetSessionStateAt(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	// Events are stamped when they're added, so the timeline puts "now" 45s in
+	base := time.Now().Truncate(time.Second).Add(-45 * time.Second)
+	at := func(s int) time.Time { return base.Add(time.Duration(s) * time.Second) }
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID:              "sess-history",
+		RunID:           "run-history",
+		ClaudeSessionID: "claude-history",
+		Status:          store.SessionStatusRunning,
+		CreatedAt:       base,
+		LastActivityAt:  base,
+	}))
+
+	// Turns split the final $4 by output tokens: $1 then $3
+	for i, turn := range []struct {
+		sec    int
+		tokens int
+	}{{20, 100}, {40, 300}} {
+		require.NoError(t, sqliteStore.RecordTurnUsage(ctx, &store.TurnUsage{
+			SessionID:    "sess-history",
+			MessageID:    fmt.Sprintf("msg_%d", i),
+			OutputTokens: turn.tokens,
+			CreatedAt:    at(turn.sec),
+		}))
+	}
+	for _, event := range []*store.ConversationEvent{
+		{EventType: store.EventTypeToolCall, ToolID: "t1", ToolName: "Bash"},
+		{EventType: store.EventTypeMessage, Role: "assistant", Content: "done"},
+	} {
+		event.SessionID = "sess-history"
+		event.ClaudeSessionID = "claude-history"
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, event))
+	}
+	// Pending from 25s until it's answered now, at 45s
+	require.NoError(t, sqliteStore.CreateApproval(ctx, &store.Approval{
+		ID:        "appr-1",
+		RunID:     "run-history",
+		SessionID: "sess-history",
+		Status:    store.ApprovalStatusLocalPending,
+		CreatedAt: at(25),
+		ToolName:  "Bash",
+		ToolInput: json.RawMessage(`{}`),
+	}))
+	require.NoError(t, sqliteStore.UpdateApprovalResponse(ctx, "appr-1", store.ApprovalStatusLocalApproved, "", ""))
+
+	cost := 4.0
+	outputTokens := 400
+	completed := store.SessionStatusCompleted
+	require.NoError(t, sqliteStore.UpdateSession(ctx, "sess-history", store.SessionUpdate{
+		QueuedAt:     ptr(at(0)),
+		StartedAt:    ptr(at(10)),
+		Status:       &completed,
+		CompletedAt:  ptr(at(100)),
+		CostUSD:      &cost,
+		OutputTokens: &outputTokens,
+	}))
+
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+	stateAt := func(t *testing.T, sec int) *GetSessionStateAtResponse {
+		result, err := handlers.HandleGetSessionStateAt(ctx, json.RawMessage(
+			fmt.Sprintf(`{"session_id":"sess-history","at":%q}`, at(sec).Format(time.RFC3339))))
+		require.NoError(t, err)
+		return result.(*GetSessionStateAtResponse)
+	}
+
+	t.Run("before the session existed", func(t *testing.T) {
+		_, err := handlers.HandleGetSessionStateAt(ctx, json.RawMessage(
+			fmt.Sprintf(`{"session_id":"sess-history","at":%q}`, at(-60).Format(time.RFC3339))))
+		var notFound *store.NotFoundError
+		require.True(t, errors.As(err, &notFound), "got %v", err)
+	})
+
+	t.Run("queued", func(t *testing.T) {
+		state := stateAt(t, 5)
+		assert.Equal(t, store.SessionStatusStarting, state.Status)
+		assert.Nil(t, state.CostUSD)
+		assert.Zero(t, state.Turns)
+	})
+
+	t.Run("mid run", func(t *testing.T) {
+		state := stateAt(t, 22)
+		assert.Equal(t, store.SessionStatusRunning, state.Status)
+		require.NotNil(t, state.CostUSD)
+		assert.InDelta(t, 1.0, *state.CostUSD, 1e-9)
+		assert.True(t, state.CostEstimated)
+		assert.Equal(t, 1, state.Turns)
+		assert.Equal(t, 100, state.OutputTokens)
+		assert.Zero(t, state.Events)
+		assert.Equal(t, at(20).Format(time.RFC3339), state.LastActivityAt)
+	})
+
+	t.Run("waiting on approval", func(t *testing.T) {
+		state := stateAt(t, 30)
+		assert.Equal(t, store.SessionStatusWaitingInput, state.Status)
+		assert.Equal(t, 1, state.PendingApprovals)
+	})
+
+	t.Run("late in the run", func(t *testing.T) {
+		state := stateAt(t, 60)
+		assert.Equal(t, store.SessionStatusRunning, state.Status)
+		assert.Zero(t, state.PendingApprovals)
+		require.NotNil(t, state.CostUSD)
+		assert.InDelta(t, 4.0, *state.CostUSD, 1e-9)
+		assert.True(t, state.CostEstimated)
+		assert.Equal(t, 2, state.Events)
+		assert.Equal(t, 1, state.ToolCalls)
+	})
+
+	t.Run("after completion", func(t *testing.T) {
+		state := stateAt(t, 200)
+		assert.Equal(t, store.SessionStatusCompleted, state.Status)
+		require.NotNil(t, state.CostUSD)
+		assert.Equal(t, 4.0, *state.CostUSD)
+		assert.False(t, state.CostEstimated)
+		assert.Equal(t, 400, state.OutputTokens)
+		assert.Equal(t, at(100).Format(time.RFC3339), state.LastActivityAt)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		_, err := handlers.HandleGetSessionStateAt(ctx, json.RawMessage(`{"session_id":"sess-history"}`))
+		assert.Error(t, err)
+		_, err = handlers.HandleGetSessionStateAt(ctx, json.RawMessage(`{"session_id":"sess-history","at":"yesterday"}`))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,163 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/bus"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// sessionStateCoalesceWindow bounds how often a session state subscriber
+// receives updates. Events arriving within the window are folded into a
+// single snapshot taken when the window closes.
+const sessionStateCoalesceWindow = 250 * time.Millisecond
+
+// SubscribeSessionStateConn streams the summary state of a single session.
+// A snapshot is pushed immediately and then whenever the session changes,
+// until the session reaches a terminal status, when a final snapshot is sent
+// and the stream ends.
+func (h *SessionHandlers) SubscribeSessionStateConn(ctx context.Context, conn net.Conn, params json.RawMessage) error {
+	var req SubscribeSessionStateRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return sendJSONResponse(conn, &Response{
+				JSONRPC: "2.0",
+				Error: &Error{
+					Code:    InvalidParams,
+					Message: fmt.Sprintf("invalid request: %v", err),
+				},
+			})
+		}
+	}
+	if req.SessionID == "" {
+		return sendJSONResponse(conn, &Response{
+			JSONRPC: "2.0",
+			Error: &Error{
+				Code:    InvalidParams,
+				Message: "session_id is required",
+			},
+		})
+	}
+	if h.eventBus == nil {
+		return sendJSONResponse(conn, &Response{
+			JSONRPC: "2.0",
+			Error: &Error{
+				Code:    InternalError,
+				Message: "event bus not configured",
+			},
+		})
+	}
+
+	connCtx, connCancel := context.WithCancel(ctx)
+	defer connCancel()
+
+	// Subscribe before the first snapshot so no change is missed in between
+	sub := h.eventBus.Subscribe(connCtx, bus.EventFilter{SessionID: req.SessionID})
+	defer h.eventBus.Unsubscribe(sub.ID)
+
+	session, err := h.store.GetSession(ctx, req.SessionID)
+	if err != nil {
+		return sendJSONResponse(conn, &Response{
+			JSONRPC: "2.0",
+			Error: &Error{
+				Code:    InternalError,
+				Message: fmt.Sprintf("failed to get session: %v", err),
+			},
+		})
+	}
+
+	slog.Info("client subscribed to session state",
+		"subscription_id", sub.ID,
+		"session_id", req.SessionID,
+	)
+
+	last := sessionToState(session)
+	final := store.IsTerminalSessionStatus(last.Status)
+	if err := sendSessionState(conn, last, final); err != nil {
+		return err
+	}
+	if final {
+		return nil
+	}
+
+	go watchConnClose(connCtx, conn, connCancel, "subscription_id", sub.ID)
+
+	var flush <-chan time.Time
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-connCtx.Done():
+			return connCtx.Err()
+
+		case _, ok := <-sub.Channel:
+			if !ok {
+				return nil
+			}
+			// Start a coalescing window on the first event; later events in
+			// the same window are absorbed by the pending flush.
+			if flush == nil {
+				flush = time.After(sessionStateCoalesceWindow)
+			}
+
+		case <-flush:
+			flush = nil
+
+			session, err := h.store.GetSession(connCtx, req.SessionID)
+			if err != nil {
+				slog.Error("failed to refresh session state",
+					"subscription_id", sub.ID,
+					"session_id", req.SessionID,
+					"error", err,
+				)
+				continue
+			}
+
+			state := sessionToState(session)
+			final := store.IsTerminalSessionStatus(state.Status)
+			if !final && reflect.DeepEqual(state, last) {
+				continue
+			}
+			if err := sendSessionState(conn, state, final); err != nil {
+				return err
+			}
+			if final {
+				return nil
+			}
+			last = state
+
+		case <-heartbeat.C:
+			if err := sendJSONResponse(conn, &Response{
+				JSONRPC: "2.0",
+				Result: map[string]interface{}{
+					"type":    "heartbeat",
+					"message": "Connection alive",
+				},
+			}); err != nil {
+				return fmt.Errorf("failed to send heartbeat: %w", err)
+			}
+		}
+	}
+}
+
+// sendSessionState writes a session state notification to the connection
+func sendSessionState(conn net.Conn, state SessionState, final bool) error {
+	if err := sendJSONResponse(conn, &Response{
+		JSONRPC: "2.0",
+		Result: &SessionStateNotification{
+			Type:    "session_state",
+			Session: state,
+			Final:   final,
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to send session state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/bus"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSubscribeSessionStateConn(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	eventBus := bus.NewEventBus()
+	handlers := NewSessionHandlers(nil, mockStore, nil, nil, nil)
+	handlers.SetEventBus(eventBus)
+
+	var mu sync.Mutex
+	status := store.SessionStatusRunning
+	turns := 1
+	fetches := 0
+	mockStore.EXPECT().GetSession(gomock.Any(), "sess-1").DoAndReturn(
+		func(ctx context.Context, id string) (*store.Session, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			fetches++
+			n := turns
+			return &store.Session{ID: id, Status: status, NumTurns: &n}, nil
+		}).AnyTimes()
+
+	server, client := net.Pipe()
+	defer func() { _ = client.Close() }()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handlers.SubscribeSessionStateConn(context.Background(), server, json.RawMessage(`{"session_id":"sess-1"}`))
+		_ = server.Close()
+	}()
+
+	reader := bufio.NewReader(client)
+	readState := func() SessionStateNotification {
+		_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+		line, err := reader.ReadBytes('\n')
+		require.NoError(t, err)
+		var resp struct {
+			Result SessionStateNotification `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(line, &resp))
+		return resp.Result
+	}
+
+	initial := readState()
+	assert.Equal(t, "session_state", initial.Type)
+	assert.Equal(t, store.SessionStatusRunning, initial.Session.Status)
+	assert.False(t, initial.Final)
+
+	// A burst of events is coalesced into a single update
+	mu.Lock()
+	turns = 5
+	mu.Unlock()
+	for i := 0; i < 10; i++ {
+		eventBus.Publish(bus.Event{
+			Type: bus.EventConversationUpdated,
+			Data: map[string]interface{}{"session_id": "sess-1"},
+		})
+	}
+	update := readState()
+	assert.Equal(t, 5, update.Session.NumTurns)
+	mu.Lock()
+	assert.Equal(t, 2, fetches, "burst should trigger a single refresh")
+	status = store.SessionStatusCompleted
+	mu.Unlock()
+
+	eventBus.Publish(bus.Event{
+		Type: bus.EventSessionStatusChanged,
+		Data: map[string]interface{}{"session_id": "sess-1"},
+	})
+	last := readState()
+	assert.Equal(t, store.SessionStatusCompleted, last.Session.Status)
+	assert.True(t, last.Final)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not end after terminal status")
+	}
+}
+
+func TestSubscribeSessionStateConnRequiresSessionID(t *testing.T) {
+	handlers := NewSessionHandlers(nil, nil, nil, nil, nil)
+	handlers.SetEventBus(bus.NewEventBus())
+
+	server, client := net.Pipe()
+	defer func() { _ = client.Close() }()
+
+	go func() {
+		_ = handlers.SubscribeSessionStateConn(context.Background(), server, json.RawMessage(`{}`))
+		_ = server.Close()
+	}()
+
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(client).ReadBytes('\n')
+	require.NoError(t, err)
+
+	var resp Response
+	require.NoError(t, json.Unmarshal(line, &resp))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, InvalidParams, resp.Error.Code)
+}
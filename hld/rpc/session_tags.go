@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SetSessionTagsRequest adds, replaces or removes tags on a session
+type SetSessionTagsRequest struct {
+	SessionID string            `json:"session_id"`
+	Tags      map[string]string `json:"tags"` // An empty value removes the tag
+}
+
+// GetSessionTagsRequest is the request for a session's tags
+type GetSessionTagsRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// SessionTagsResponse holds all of a session's tags
+type SessionTagsResponse struct {
+	SessionID string            `json:"session_id"`
+	Tags      map[string]string `json:"tags"`
+}
+
+// HandleSetSessionTags merges tags into a session's existing tags and
+// returns the result
+func (h *SessionHandlers) HandleSetSessionTags(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req SetSessionTagsRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+	if len(req.Tags) == 0 {
+		return nil, fmt.Errorf("%w: tags is required", ErrInvalidRequest)
+	}
+	for key := range req.Tags {
+		if key == "" {
+			return nil, fmt.Errorf("%w: tag keys must not be empty", ErrInvalidRequest)
+		}
+	}
+
+	if err := h.store.SetSessionTags(ctx, req.SessionID, req.Tags); err != nil {
+		return nil, fmt.Errorf("failed to set session tags: %w", err)
+	}
+	return h.sessionTags(ctx, req.SessionID)
+}
+
+// HandleGetSessionTags returns a session's tags
+func (h *SessionHandlers) HandleGetSessionTags(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetSessionTagsRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+
+	// Tell an untagged session apart from one that doesn't exist
+	if _, err := h.store.GetSession(ctx, req.SessionID); err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return h.sessionTags(ctx, req.SessionID)
+}
+
+func (h *SessionHandlers) sessionTags(ctx context.Context, sessionID string) (*SessionTagsResponse, error) {
+	tags, err := h.store.GetSessionTags(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session tags: %w", err)
+	}
+	return &SessionTagsResponse{SessionID: sessionID, Tags: tags}, nil
+}
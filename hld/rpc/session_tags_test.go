@@ -0,0 +1,54 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSessionTags(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	for _, id := range []string{"sess-1", "sess-2"} {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: id, RunID: "run-" + id, Status: store.SessionStatusCompleted,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	result, err := handlers.HandleSetSessionTags(ctx, json.RawMessage(`{"session_id":"sess-1","tags":{"env":"prod","project":"billing"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, &SessionTagsResponse{SessionID: "sess-1", Tags: map[string]string{"env": "prod", "project": "billing"}}, result)
+
+	result, err = handlers.HandleGetSessionTags(ctx, json.RawMessage(`{"session_id":"sess-2"}`))
+	require.NoError(t, err)
+	assert.Empty(t, result.(*SessionTagsResponse).Tags)
+
+	listed, err := handlers.HandleListSessions(ctx, json.RawMessage(`{"tags":{"env":"prod"}}`))
+	require.NoError(t, err)
+	sessions := listed.(*ListSessionsResponse).Sessions
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "sess-1", sessions[0].ID)
+
+	t.Run("errors", func(t *testing.T) {
+		var notFound *store.NotFoundError
+		_, err := handlers.HandleGetSessionTags(ctx, json.RawMessage(`{"session_id":"sess-unknown"}`))
+		assert.ErrorAs(t, err, &notFound)
+		_, err = handlers.HandleSetSessionTags(ctx, json.RawMessage(`{"session_id":"sess-unknown","tags":{"env":"prod"}}`))
+		assert.ErrorAs(t, err, &notFound)
+
+		_, err = handlers.HandleSetSessionTags(ctx, json.RawMessage(`{"session_id":"sess-1","tags":{"":"x"}}`))
+		assert.EqualError(t, err, "invalid request: tag keys must not be empty")
+		_, err = handlers.HandleSetSessionTags(ctx, json.RawMessage(`{"session_id":"sess-1"}`))
+		assert.EqualError(t, err, "invalid request: tags is required")
+	})
+}
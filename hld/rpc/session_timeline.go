@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// GetSessionTimelineRequest is the request for a session's event timeline
+type GetSessionTimelineRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// TimelineEvent is a conversation event placed on the session's timeline
+type TimelineEvent struct {
+	ConversationEvent
+	ElapsedMS  int64 `json:"elapsed_ms"`  // Since the session was created
+	DurationMS int64 `json:"duration_ms"` // Until the next event, 0 for the last event
+}
+
+// GetSessionTimelineResponse is the response for a session's event timeline
+type GetSessionTimelineResponse struct {
+	SessionID string          `json:"session_id"`
+	CreatedAt string          `json:"created_at"` // ISO 8601 timestamp the elapsed times count from
+	Events    []TimelineEvent `json:"events"`
+}
+
+// HandleGetSessionTimeline returns a session's events in sequence order with
+// how long after the session's creation each one was recorded and how long
+// it was until the next, for finding where a slow session spent its time.
+// Events inherited from a parent session are left out.
+func (h *SessionHandlers) HandleGetSessionTimeline(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetSessionTimelineRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+
+	sess, err := h.store.GetSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	// Sessions get a Claude session ID with their first event
+	var stored []*store.ConversationEvent
+	if sess.ClaudeSessionID != "" {
+		if stored, err = h.store.GetConversation(ctx, sess.ClaudeSessionID, store.ConversationPage{}); err != nil {
+			return nil, fmt.Errorf("failed to get conversation: %w", err)
+		}
+	}
+
+	var events []*store.ConversationEvent
+	for _, event := range stored {
+		if event.SessionID == sess.ID {
+			events = append(events, event)
+		}
+	}
+
+	resp := &GetSessionTimelineResponse{
+		SessionID: sess.ID,
+		CreatedAt: sess.CreatedAt.Format(time.RFC3339Nano),
+		Events:    make([]TimelineEvent, len(events)),
+	}
+	for i, event := range events {
+		resp.Events[i] = TimelineEvent{
+			ConversationEvent: eventToRPC(event),
+			ElapsedMS:         event.CreatedAt.Sub(sess.CreatedAt).Milliseconds(),
+		}
+		if i+1 < len(events) {
+			resp.Events[i].DurationMS = events[i+1].CreatedAt.Sub(event.CreatedAt).Milliseconds()
+		}
+	}
+	return resp, nil
+}
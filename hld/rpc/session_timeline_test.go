@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetSessionTimeline(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	createdAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, id := range []string{"sess-1", "empty"} {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: id, RunID: "run-" + id, ClaudeSessionID: "claude-" + id, Query: "q",
+			Status: store.SessionStatusCompleted, CreatedAt: createdAt, LastActivityAt: createdAt,
+		}))
+	}
+
+	seed := []struct {
+		at    string
+		event store.ConversationEvent
+	}{
+		{"2026-03-01 10:00:00.250", store.ConversationEvent{EventType: store.EventTypeMessage, Role: "user", Content: "List files"}},
+		{"2026-03-01 10:00:01.000", store.ConversationEvent{EventType: store.EventTypeToolCall, ToolID: "t1", ToolName: "Bash"}},
+		{"2026-03-01 10:00:04.125", store.ConversationEvent{EventType: store.EventTypeToolResult, ToolResultForID: "t1", ToolResultContent: "a.go"}},
+		{"2026-03-01 10:00:04.500", store.ConversationEvent{EventType: store.EventTypeMessage, Role: "assistant", Content: "One file"}},
+	}
+	for _, s := range seed {
+		event := s.event
+		event.SessionID, event.ClaudeSessionID = "sess-1", "claude-sess-1"
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, &event))
+		_, err := sqliteStore.GetDB().Exec(`UPDATE conversation_events SET created_at = ? WHERE id = ?`, s.at, event.ID)
+		require.NoError(t, err)
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	result, err := handlers.HandleGetSessionTimeline(ctx, json.RawMessage(`{"session_id":"sess-1"}`))
+	require.NoError(t, err)
+	resp := result.(*GetSessionTimelineResponse)
+	require.Len(t, resp.Events, 4)
+	for i, want := range []struct {
+		elapsed, duration int64
+	}{{250, 750}, {1000, 3125}, {4125, 375}, {4500, 0}} {
+		assert.InDelta(t, want.elapsed, resp.Events[i].ElapsedMS, 1, "event %d elapsed", i)
+		assert.InDelta(t, want.duration, resp.Events[i].DurationMS, 1, "event %d duration", i)
+	}
+	assert.Equal(t, store.EventTypeToolCall, resp.Events[1].EventType)
+
+	t.Run("no events", func(t *testing.T) {
+		result, err := handlers.HandleGetSessionTimeline(ctx, json.RawMessage(`{"session_id":"empty"}`))
+		require.NoError(t, err)
+		assert.Empty(t, result.(*GetSessionTimelineResponse).Events)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := handlers.HandleGetSessionTimeline(ctx, json.RawMessage(`{}`))
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+		_, err = handlers.HandleGetSessionTimeline(ctx, json.RawMessage(`{"session_id":"missing"}`))
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
@@ -0,0 +1,50 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrShuttingDown is returned for calls that arrive after Shutdown started
+var ErrShuttingDown = errors.New("daemon is shutting down")
+
+// trackInflight counts the handler's calls as in flight until they return,
+// and rejects new calls once Shutdown has started
+func (h *SessionHandlers) trackInflight(handler HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		h.drainMu.Lock()
+		if h.draining {
+			h.drainMu.Unlock()
+			return nil, ErrShuttingDown
+		}
+		h.inflight.Add(1)
+		h.drainMu.Unlock()
+		defer h.inflight.Done()
+
+		return handler(ctx, params)
+	}
+}
+
+// Shutdown stops accepting calls and waits for those in flight to return, so
+// their responses are sent before the daemon exits. It returns an error if
+// ctx ends first. Streaming handlers are not waited for.
+func (h *SessionHandlers) Shutdown(ctx context.Context) error {
+	h.drainMu.Lock()
+	h.draining = true
+	h.drainMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("in-flight requests did not finish: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,59 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionHandlersShutdown(t *testing.T) {
+	t.Run("waits for in-flight calls", func(t *testing.T) {
+		handlers := NewSessionHandlers(nil, nil, nil, nil, nil)
+		var started sync.WaitGroup
+		var completed atomic.Int32
+		slow := handlers.wrap("slow", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			started.Done()
+			time.Sleep(30 * time.Millisecond)
+			completed.Add(1)
+			return "done", nil
+		})
+
+		started.Add(10)
+		for i := 0; i < 10; i++ {
+			go func() { _, _ = slow(context.Background(), nil) }()
+		}
+		started.Wait()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		require.NoError(t, handlers.Shutdown(ctx))
+		assert.Equal(t, int32(10), completed.Load())
+
+		_, err := slow(context.Background(), nil)
+		assert.ErrorIs(t, err, ErrShuttingDown)
+	})
+
+	t.Run("gives up at the deadline", func(t *testing.T) {
+		handlers := NewSessionHandlers(nil, nil, nil, nil, nil)
+		release := make(chan struct{})
+		defer close(release)
+		entered := make(chan struct{})
+		stuck := handlers.wrap("stuck", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			close(entered)
+			<-release
+			return nil, nil
+		})
+		go func() { _, _ = stuck(context.Background(), nil) }()
+		<-entered
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, handlers.Shutdown(ctx), context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// SoftDeleteSessionRequest is the request for hiding or restoring a session
+type SoftDeleteSessionRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// SoftDeleteSessionResponse is the response for hiding or restoring a session
+type SoftDeleteSessionResponse struct {
+	Success   bool   `json:"success"`
+	SessionID string `json:"session_id"`
+}
+
+// HandleSoftDeleteSession hides a finished session from getSessionState,
+// listSessions and the other session lookups while keeping its data, so
+// restoreSession can bring it back. Soft-deleted sessions can still be
+// permanently deleted with deleteSession.
+func (h *SessionHandlers) HandleSoftDeleteSession(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	req, err := parseSoftDeleteSessionRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := h.store.GetSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if !store.IsTerminalSessionStatus(session.Status) {
+		return nil, fmt.Errorf("%w: cannot soft-delete session with status %s", ErrConflict, session.Status)
+	}
+
+	if err := h.store.SoftDeleteSession(ctx, req.SessionID); err != nil {
+		return nil, fmt.Errorf("failed to soft-delete session: %w", err)
+	}
+	return &SoftDeleteSessionResponse{Success: true, SessionID: req.SessionID}, nil
+}
+
+// HandleRestoreSession makes a soft-deleted session visible again
+func (h *SessionHandlers) HandleRestoreSession(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	req, err := parseSoftDeleteSessionRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.store.RestoreSession(ctx, req.SessionID); err != nil {
+		return nil, fmt.Errorf("failed to restore session: %w", err)
+	}
+	return &SoftDeleteSessionResponse{Success: true, SessionID: req.SessionID}, nil
+}
+
+func parseSoftDeleteSessionRequest(params json.RawMessage) (*SoftDeleteSessionRequest, error) {
+	var req SoftDeleteSessionRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrInvalidRequest)
+	}
+	return &req, nil
+}
@@ -0,0 +1,62 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSoftDeleteSession(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	for id, status := range map[string]string{"sess-done": store.SessionStatusCompleted, "sess-live": store.SessionStatusRunning} {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: id, RunID: "run-" + id, ClaudeSessionID: "claude-" + id, Query: "q", Status: status,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+	}
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	t.Run("requires a session", func(t *testing.T) {
+		_, err := handlers.HandleSoftDeleteSession(ctx, json.RawMessage(`{}`))
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+		_, err = handlers.HandleRestoreSession(ctx, json.RawMessage(`{}`))
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+	})
+
+	t.Run("refuses an active session", func(t *testing.T) {
+		_, err := handlers.HandleSoftDeleteSession(ctx, json.RawMessage(`{"session_id":"sess-live"}`))
+		assert.ErrorIs(t, err, ErrConflict)
+		_, err = sqliteStore.GetSession(ctx, "sess-live")
+		assert.NoError(t, err)
+	})
+
+	t.Run("hides and restores a session", func(t *testing.T) {
+		result, err := handlers.HandleSoftDeleteSession(ctx, json.RawMessage(`{"session_id":"sess-done"}`))
+		require.NoError(t, err)
+		assert.Equal(t, &SoftDeleteSessionResponse{Success: true, SessionID: "sess-done"}, result)
+		var notFound *store.NotFoundError
+		_, err = sqliteStore.GetSession(ctx, "sess-done")
+		assert.ErrorAs(t, err, &notFound)
+
+		_, err = handlers.HandleSoftDeleteSession(ctx, json.RawMessage(`{"session_id":"sess-done"}`))
+		assert.ErrorAs(t, err, &notFound)
+
+		result, err = handlers.HandleRestoreSession(ctx, json.RawMessage(`{"session_id":"sess-done"}`))
+		require.NoError(t, err)
+		assert.Equal(t, &SoftDeleteSessionResponse{Success: true, SessionID: "sess-done"}, result)
+		_, err = sqliteStore.GetSession(ctx, "sess-done")
+		assert.NoError(t, err)
+
+		_, err = handlers.HandleRestoreSession(ctx, json.RawMessage(`{"session_id":"sess-done"}`))
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
@@ -2,6 +2,7 @@ package rpc
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/humanlayer/humanlayer/hld/bus"
+	"github.com/humanlayer/humanlayer/hld/store"
 )
 
 // SubscriptionHandlers provides RPC handlers for event subscriptions
@@ -33,12 +35,86 @@ type SubscribeRequest struct {
 	EventTypes []string `json:"event_types,omitempty"` // Optional filter by event types
 	SessionID  string   `json:"session_id,omitempty"`  // Optional filter by session
 	RunID      string   `json:"run_id,omitempty"`      // Optional filter by run ID
+
+	// BatchWindowMS groups events arriving within the window into a single
+	// EventBatchNotification. Zero delivers each event individually.
+	BatchWindowMS int `json:"batch_window_ms,omitempty"`
+
+	// Mode selects SubscribeModeDeltas (default) or SubscribeModeMessages,
+	// which delivers only whole messages and tool calls instead of streaming
+	// fragments. Coalescing happens before batching.
+	Mode string `json:"mode,omitempty"`
+
+	// ResumeToken is taken from a ReconnectNotification. When set it restores
+	// the filters of the handed-off subscription and the fields above are ignored.
+	ResumeToken string `json:"resume_token,omitempty"`
 }
 
+// maxBatchWindow caps the subscriber batching window
+const maxBatchWindow = time.Second
+
 // SubscribeResponse is sent when subscription is established
 type SubscribeResponse struct {
 	SubscriptionID string `json:"subscription_id"`
 	Message        string `json:"message"`
+
+	// ResumedFrom is the time of the last event delivered before the handoff
+	// when resuming. Events published after it while no daemon was listening
+	// are not replayed, so clients should refresh state they care about.
+	ResumedFrom string `json:"resumed_from,omitempty"`
+}
+
+// ReconnectNotification is a control frame sent to subscribers when the
+// daemon shuts down. Clients should reconnect after RetryAfterMS and pass
+// ResumeToken in their SubscribeRequest to pick up where they left off.
+type ReconnectNotification struct {
+	Type         string `json:"type"` // always "reconnect"
+	Reason       string `json:"reason"`
+	ResumeToken  string `json:"resume_token"`
+	RetryAfterMS int    `json:"retry_after_ms"`
+}
+
+// reconnectRetryAfter is how long subscribers are asked to wait before
+// reconnecting after a handoff
+const reconnectRetryAfter = 500 * time.Millisecond
+
+// resumeState is the payload of a resume token
+type resumeState struct {
+	EventTypes    []string  `json:"event_types,omitempty"`
+	SessionID     string    `json:"session_id,omitempty"`
+	RunID         string    `json:"run_id,omitempty"`
+	BatchWindowMS int       `json:"batch_window_ms,omitempty"`
+	Mode          string    `json:"mode,omitempty"`
+	LastEventAt   time.Time `json:"last_event_at"`
+}
+
+// encodeResumeToken packs a subscription's filters and position into an opaque token
+func encodeResumeToken(req SubscribeRequest, lastEventAt time.Time) (string, error) {
+	data, err := json.Marshal(resumeState{
+		EventTypes:    req.EventTypes,
+		SessionID:     req.SessionID,
+		RunID:         req.RunID,
+		BatchWindowMS: req.BatchWindowMS,
+		Mode:          req.Mode,
+		LastEventAt:   lastEventAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeResumeToken reverses encodeResumeToken
+func decodeResumeToken(token string) (*resumeState, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed resume token", ErrInvalidRequest)
+	}
+	var state resumeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%w: malformed resume token", ErrInvalidRequest)
+	}
+	return &state, nil
 }
 
 // EventNotification is sent to subscribers when events occur
@@ -46,12 +122,17 @@ type EventNotification struct {
 	Event bus.Event `json:"event"`
 }
 
+// EventBatchNotification is sent to subscribers that requested batching
+type EventBatchNotification struct {
+	Events []bus.Event `json:"events"`
+}
+
 // HandleSubscribe handles the Subscribe RPC method with long-polling
 func (h *SubscriptionHandlers) HandleSubscribe(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var req SubscribeRequest
 	if params != nil {
 		if err := json.Unmarshal(params, &req); err != nil {
-			return nil, fmt.Errorf("invalid request: %w", err)
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 		}
 	}
 
@@ -78,6 +159,56 @@ func (h *SubscriptionHandlers) SubscribeConn(ctx context.Context, conn net.Conn,
 		}
 	}
 
+	var resumedFrom time.Time
+	if req.ResumeToken != "" {
+		state, err := decodeResumeToken(req.ResumeToken)
+		if err != nil {
+			return sendJSONResponse(conn, &Response{
+				JSONRPC: "2.0",
+				Error: &Error{
+					Code:    InvalidParams,
+					Message: err.Error(),
+				},
+			})
+		}
+		req = SubscribeRequest{
+			EventTypes:    state.EventTypes,
+			SessionID:     state.SessionID,
+			RunID:         state.RunID,
+			BatchWindowMS: state.BatchWindowMS,
+			Mode:          state.Mode,
+		}
+		resumedFrom = state.LastEventAt
+	}
+
+	if req.BatchWindowMS < 0 {
+		return sendJSONResponse(conn, &Response{
+			JSONRPC: "2.0",
+			Error: &Error{
+				Code:    InvalidParams,
+				Message: "batch_window_ms cannot be negative",
+			},
+		})
+	}
+	if err := validateSubscribeMode(req.Mode); err != nil {
+		return sendJSONResponse(conn, &Response{
+			JSONRPC: "2.0",
+			Error: &Error{
+				Code:    InvalidParams,
+				Message: err.Error(),
+			},
+		})
+	}
+	var coalescer *messageCoalescer
+	if req.Mode == SubscribeModeMessages {
+		coalescer = newMessageCoalescer()
+	}
+
+	batchWindow := time.Duration(req.BatchWindowMS) * time.Millisecond
+	if batchWindow > maxBatchWindow {
+		batchWindow = maxBatchWindow
+	}
+
 	// Convert string event types to bus.EventType
 	var eventTypes []bus.EventType
 	for _, t := range req.EventTypes {
@@ -106,15 +237,22 @@ func (h *SubscriptionHandlers) SubscribeConn(ctx context.Context, conn net.Conn,
 		"filter_has_session_id", req.SessionID != "",
 		"filter_has_run_id", req.RunID != "",
 		"filter_has_event_types", len(req.EventTypes) > 0,
+		"batch_window", batchWindow,
+		"mode", req.Mode,
+		"resumed", !resumedFrom.IsZero(),
 	)
 
 	// Send initial success response
+	subResp := &SubscribeResponse{
+		SubscriptionID: sub.ID,
+		Message:        "Subscription established. Waiting for events...",
+	}
+	if !resumedFrom.IsZero() {
+		subResp.ResumedFrom = resumedFrom.Format(time.RFC3339Nano)
+	}
 	resp := &Response{
 		JSONRPC: "2.0",
-		Result: &SubscribeResponse{
-			SubscriptionID: sub.ID,
-			Message:        "Subscription established. Waiting for events...",
-		},
+		Result:  subResp,
 	}
 	if err := sendJSONResponse(conn, resp); err != nil {
 		return fmt.Errorf("failed to send subscription response: %w", err)
@@ -125,38 +263,41 @@ func (h *SubscriptionHandlers) SubscribeConn(ctx context.Context, conn net.Conn,
 	defer connCancel()
 
 	// Monitor connection in a separate goroutine
-	go func() {
-		// Try to read from connection - will fail when closed
-		buf := make([]byte, 1)
-		for {
-			_ = conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
-			_, err := conn.Read(buf)
-			if err != nil {
-				// Check if it's a timeout error (which is expected)
-				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-					// Timeout is expected, continue monitoring
-					continue
-				}
-				// Real error or connection closed
-				slog.Debug("subscription connection closed",
-					"subscription_id", sub.ID,
-					"error", err,
-				)
-				connCancel()
-				return
-			}
+	go watchConnClose(connCtx, conn, connCancel, "subscription_id", sub.ID)
+
+	// Pending events and flush timer when batching is enabled
+	var batch []bus.Event
+	var flush <-chan time.Time
+
+	// Position handed to the next daemon instance on shutdown
+	lastEventAt := resumedFrom
+	if lastEventAt.IsZero() {
+		lastEventAt = time.Now()
+	}
+
+	// The daemon context ending means the daemon is shutting down rather
+	// than the client going away, so hand the subscriber off
+	handoff := func() {
+		if ctx.Err() == nil {
+			return
 		}
-	}()
+		if err := sendEventBatch(conn, batch); err == nil && len(batch) > 0 {
+			lastEventAt = batch[len(batch)-1].Timestamp
+		}
+		sendReconnect(conn, sub.ID, req, lastEventAt)
+	}
 
 	// Long-poll for events
 	for {
 		select {
 		case <-connCtx.Done():
+			handoff()
 			return connCtx.Err()
 
 		case event, ok := <-sub.Channel:
 			if !ok {
 				// Channel closed, subscription ended
+				handoff()
 				return nil
 			}
 
@@ -169,22 +310,60 @@ func (h *SubscriptionHandlers) SubscribeConn(ctx context.Context, conn net.Conn,
 				continue
 			}
 
-			// Send event notification
-			notification := &Response{
-				JSONRPC: "2.0",
-				Result: &EventNotification{
-					Event: event,
-				},
+			ready := []bus.Event{event}
+			if coalescer != nil {
+				ready = coalescer.Add(event)
 			}
-			if err := sendJSONResponse(conn, notification); err != nil {
-				return fmt.Errorf("failed to send event notification: %w", err)
+
+			for _, event := range ready {
+				if batchWindow > 0 {
+					batch = append(batch, event)
+					// Terminal events are delivered without waiting for the window
+					if isTerminalEvent(event) {
+						if err := sendEventBatch(conn, batch); err != nil {
+							return err
+						}
+						lastEventAt = event.Timestamp
+						batch = nil
+						flush = nil
+					} else if flush == nil {
+						flush = time.After(batchWindow)
+					}
+					continue
+				}
+
+				// Send event notification
+				notification := &Response{
+					JSONRPC: "2.0",
+					Result: &EventNotification{
+						Event: event,
+					},
+				}
+				if err := sendJSONResponse(conn, notification); err != nil {
+					return fmt.Errorf("failed to send event notification: %w", err)
+				}
+				lastEventAt = event.Timestamp
+
+				slog.Debug("sent event notification to subscriber",
+					"subscription_id", sub.ID,
+					"event_type", event.Type,
+					"event_data", event.Data,
+				)
 			}
 
-			slog.Debug("sent event notification to subscriber",
+		case <-flush:
+			flush = nil
+			if err := sendEventBatch(conn, batch); err != nil {
+				return err
+			}
+			if len(batch) > 0 {
+				lastEventAt = batch[len(batch)-1].Timestamp
+			}
+			slog.Debug("sent event batch to subscriber",
 				"subscription_id", sub.ID,
-				"event_type", event.Type,
-				"event_data", event.Data,
+				"count", len(batch),
 			)
+			batch = nil
 
 		case <-time.After(30 * time.Second):
 			// Send heartbeat to keep connection alive
@@ -202,6 +381,83 @@ func (h *SubscriptionHandlers) SubscribeConn(ctx context.Context, conn net.Conn,
 	}
 }
 
+// sendReconnect writes the shutdown handoff control frame. Failures are only
+// logged since the connection is going away regardless.
+func sendReconnect(conn net.Conn, subscriptionID string, req SubscribeRequest, lastEventAt time.Time) {
+	token, err := encodeResumeToken(req, lastEventAt)
+	if err != nil {
+		slog.Warn("failed to encode resume token", "subscription_id", subscriptionID, "error", err)
+		return
+	}
+	if err := sendJSONResponse(conn, &Response{
+		JSONRPC: "2.0",
+		Result: &ReconnectNotification{
+			Type:         "reconnect",
+			Reason:       "daemon_shutdown",
+			ResumeToken:  token,
+			RetryAfterMS: int(reconnectRetryAfter / time.Millisecond),
+		},
+	}); err != nil {
+		slog.Debug("failed to send reconnect notification", "subscription_id", subscriptionID, "error", err)
+		return
+	}
+	slog.Debug("sent reconnect handoff to subscriber", "subscription_id", subscriptionID)
+}
+
+// isTerminalEvent reports whether an event ends a session's activity and so
+// should not be held back by batching
+func isTerminalEvent(event bus.Event) bool {
+	if event.Type != bus.EventSessionStatusChanged {
+		return false
+	}
+	status, _ := event.Data["new_status"].(string)
+	return store.IsTerminalSessionStatus(status)
+}
+
+// sendEventBatch writes a batch of events as a single notification
+func sendEventBatch(conn net.Conn, events []bus.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := sendJSONResponse(conn, &Response{
+		JSONRPC: "2.0",
+		Result: &EventBatchNotification{
+			Events: events,
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to send event batch: %w", err)
+	}
+	return nil
+}
+
+// watchConnClose polls the connection with short read deadlines and calls
+// cancel once the peer closes it. It must only be used on connections that
+// the client no longer writes requests to.
+func watchConnClose(ctx context.Context, conn net.Conn, cancel context.CancelFunc, logArgs ...any) {
+	// Try to read from connection - will fail when closed
+	buf := make([]byte, 1)
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		_, err := conn.Read(buf)
+		if err != nil {
+			// Check if it's a timeout error (which is expected)
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				// Timeout is expected, continue monitoring
+				continue
+			}
+			// Real error or connection closed
+			slog.Debug("subscription connection closed",
+				append(logArgs, "error", err)...,
+			)
+			cancel()
+			return
+		}
+	}
+}
+
 // sendJSONResponse writes a JSON response followed by newline
 func sendJSONResponse(conn net.Conn, resp interface{}) error {
 	data, err := json.Marshal(resp)
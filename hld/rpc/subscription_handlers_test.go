@@ -0,0 +1,262 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/bus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startSubscription runs SubscribeConn over an in-memory pipe and returns a
+// reader for the frames it writes
+func startSubscription(t *testing.T, eventBus bus.EventBus, params string) (*bufio.Reader, net.Conn) {
+	t.Helper()
+	reader, client, _ := startSubscriptionCtx(t, context.Background(), eventBus, params)
+	return reader, client
+}
+
+// startSubscriptionCtx is startSubscription with a caller-controlled daemon
+// context. It also returns the subscription confirmation.
+func startSubscriptionCtx(t *testing.T, ctx context.Context, eventBus bus.EventBus, params string) (*bufio.Reader, net.Conn, SubscribeResponse) {
+	t.Helper()
+	handlers := NewSubscriptionHandlers(eventBus)
+	server, client := net.Pipe()
+	t.Cleanup(func() { _ = client.Close() })
+
+	go func() {
+		_ = handlers.SubscribeConn(ctx, server, json.RawMessage(params))
+		_ = server.Close()
+	}()
+
+	reader := bufio.NewReader(client)
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := reader.ReadBytes('\n') // subscription established
+	require.NoError(t, err)
+	var confirm struct {
+		Result SubscribeResponse `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(line, &confirm))
+
+	// Wait for the subscriber to be registered before publishing
+	require.Eventually(t, func() bool { return eventBus.GetSubscriberCount() == 1 }, time.Second, 5*time.Millisecond)
+	return reader, client, confirm.Result
+}
+
+func readBatch(t *testing.T, reader *bufio.Reader, client net.Conn, timeout time.Duration) []bus.Event {
+	t.Helper()
+	_ = client.SetReadDeadline(time.Now().Add(timeout))
+	line, err := reader.ReadBytes('\n')
+	require.NoError(t, err)
+	var resp struct {
+		Result EventBatchNotification `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(line, &resp))
+	return resp.Result.Events
+}
+
+func TestSubscribeConnBatching(t *testing.T) {
+	t.Run("burst is delivered in fewer frames", func(t *testing.T) {
+		eventBus := bus.NewEventBus()
+		reader, client := startSubscription(t, eventBus, `{"batch_window_ms":50}`)
+
+		const burst = 20
+		for i := 0; i < burst; i++ {
+			eventBus.Publish(bus.Event{
+				Type: bus.EventConversationUpdated,
+				Data: map[string]interface{}{"session_id": "sess-1", "n": i},
+			})
+		}
+
+		frames := 0
+		received := 0
+		for received < burst {
+			events := readBatch(t, reader, client, 2*time.Second)
+			require.NotEmpty(t, events)
+			received += len(events)
+			frames++
+		}
+		assert.Equal(t, burst, received)
+		assert.Less(t, frames, burst, "batching should reduce the number of frames")
+	})
+
+	t.Run("terminal events flush immediately", func(t *testing.T) {
+		eventBus := bus.NewEventBus()
+		reader, client := startSubscription(t, eventBus, `{"batch_window_ms":1000}`)
+
+		eventBus.Publish(bus.Event{
+			Type: bus.EventConversationUpdated,
+			Data: map[string]interface{}{"session_id": "sess-1"},
+		})
+		eventBus.Publish(bus.Event{
+			Type: bus.EventSessionStatusChanged,
+			Data: map[string]interface{}{"session_id": "sess-1", "new_status": "completed"},
+		})
+
+		// Well under the 1s window
+		events := readBatch(t, reader, client, 500*time.Millisecond)
+		require.Len(t, events, 2)
+		assert.Equal(t, bus.EventSessionStatusChanged, events[1].Type)
+	})
+}
+
+func TestSubscribeConnShutdownHandoff(t *testing.T) {
+	eventBus := bus.NewEventBus()
+	ctx, shutdown := context.WithCancel(context.Background())
+	reader, client, _ := startSubscriptionCtx(t, ctx, eventBus, `{"session_id":"sess-1","event_types":["conversation_updated"]}`)
+
+	eventBus.Publish(bus.Event{
+		Type: bus.EventConversationUpdated,
+		Data: map[string]interface{}{"session_id": "sess-1"},
+	})
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := reader.ReadBytes('\n')
+	require.NoError(t, err)
+	var first struct {
+		Result EventNotification `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(line, &first))
+	delivered := first.Result.Event.Timestamp
+
+	// Simulate the daemon shutting down
+	shutdown()
+
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err = reader.ReadBytes('\n')
+	require.NoError(t, err)
+	var frame struct {
+		Result ReconnectNotification `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(line, &frame))
+	assert.Equal(t, "reconnect", frame.Result.Type)
+	assert.Equal(t, "daemon_shutdown", frame.Result.Reason)
+	assert.Positive(t, frame.Result.RetryAfterMS)
+	require.NotEmpty(t, frame.Result.ResumeToken)
+
+	// Resuming on a fresh instance restores the original filters
+	newBus := bus.NewEventBus()
+	params, err := json.Marshal(SubscribeRequest{ResumeToken: frame.Result.ResumeToken})
+	require.NoError(t, err)
+	reader, client, confirm := startSubscriptionCtx(t, context.Background(), newBus, string(params))
+	resumedFrom, err := time.Parse(time.RFC3339Nano, confirm.ResumedFrom)
+	require.NoError(t, err)
+	assert.True(t, resumedFrom.Equal(delivered))
+
+	newBus.Publish(bus.Event{Type: bus.EventConversationUpdated, Data: map[string]interface{}{"session_id": "other"}})
+	newBus.Publish(bus.Event{Type: bus.EventConversationUpdated, Data: map[string]interface{}{"session_id": "sess-1"}})
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err = reader.ReadBytes('\n')
+	require.NoError(t, err)
+	var notification struct {
+		Result EventNotification `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(line, &notification))
+	assert.Equal(t, "sess-1", notification.Result.Event.Data["session_id"])
+}
+
+func TestSubscribeConnRejectsMalformedResumeToken(t *testing.T) {
+	handlers := NewSubscriptionHandlers(bus.NewEventBus())
+	server, client := net.Pipe()
+	defer func() { _ = client.Close() }()
+
+	go func() {
+		_ = handlers.SubscribeConn(context.Background(), server, json.RawMessage(`{"resume_token":"!!"}`))
+		_ = server.Close()
+	}()
+
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(client).ReadBytes('\n')
+	require.NoError(t, err)
+	var resp Response
+	require.NoError(t, json.Unmarshal(line, &resp))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, "invalid request: malformed resume token", resp.Error.Message)
+}
+
+func TestSubscribeConnMessagesMode(t *testing.T) {
+	readEvent := func(t *testing.T, reader *bufio.Reader, client net.Conn) bus.Event {
+		t.Helper()
+		_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+		line, err := reader.ReadBytes('\n')
+		require.NoError(t, err)
+		var resp struct {
+			Result EventNotification `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(line, &resp))
+		return resp.Result.Event
+	}
+	fragment := func(data map[string]interface{}) bus.Event {
+		data["session_id"] = "sess-1"
+		data["partial"] = true
+		return bus.Event{Type: bus.EventConversationUpdated, Data: data}
+	}
+
+	t.Run("fragments collapse into whole events", func(t *testing.T) {
+		eventBus := bus.NewEventBus()
+		reader, client := startSubscription(t, eventBus, `{"mode":"messages"}`)
+
+		for _, text := range []string{"Hel", "lo, ", "world"} {
+			eventBus.Publish(fragment(map[string]interface{}{"content_type": "text", "role": "assistant", "content": text}))
+		}
+		for _, input := range []string{`{"comm`, `and":"ls"}`} {
+			eventBus.Publish(fragment(map[string]interface{}{"content_type": "tool_use", "tool_id": "t1", "tool_name": "Bash", "tool_input_json": input}))
+		}
+		eventBus.Publish(bus.Event{
+			Type: bus.EventSessionStatusChanged,
+			Data: map[string]interface{}{"session_id": "sess-1", "new_status": "completed"},
+		})
+
+		message := readEvent(t, reader, client)
+		assert.Equal(t, bus.EventConversationUpdated, message.Type)
+		assert.Equal(t, "Hello, world", message.Data["content"])
+		assert.NotContains(t, message.Data, "partial")
+
+		toolCall := readEvent(t, reader, client)
+		assert.Equal(t, "t1", toolCall.Data["tool_id"])
+		assert.Equal(t, map[string]interface{}{"command": "ls"}, toolCall.Data["tool_input"])
+
+		assert.Equal(t, bus.EventSessionStatusChanged, readEvent(t, reader, client).Type)
+	})
+
+	t.Run("whole event supersedes its fragments", func(t *testing.T) {
+		eventBus := bus.NewEventBus()
+		reader, client := startSubscription(t, eventBus, `{"mode":"messages"}`)
+
+		eventBus.Publish(fragment(map[string]interface{}{"content_type": "text", "content": "Hi"}))
+		eventBus.Publish(bus.Event{
+			Type: bus.EventConversationUpdated,
+			Data: map[string]interface{}{"session_id": "sess-1", "content_type": "text", "content": "Hi there"},
+		})
+		eventBus.Publish(bus.Event{
+			Type: bus.EventSessionStatusChanged,
+			Data: map[string]interface{}{"session_id": "sess-1", "new_status": "completed"},
+		})
+
+		assert.Equal(t, "Hi there", readEvent(t, reader, client).Data["content"])
+		assert.Equal(t, bus.EventSessionStatusChanged, readEvent(t, reader, client).Type, "fragments should not be flushed again")
+	})
+
+	t.Run("rejects unknown mode", func(t *testing.T) {
+		handlers := NewSubscriptionHandlers(bus.NewEventBus())
+		server, client := net.Pipe()
+		defer func() { _ = client.Close() }()
+
+		go func() {
+			_ = handlers.SubscribeConn(context.Background(), server, json.RawMessage(`{"mode":"words"}`))
+			_ = server.Close()
+		}()
+
+		_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+		line, err := bufio.NewReader(client).ReadBytes('\n')
+		require.NoError(t, err)
+		var resp Response
+		require.NoError(t, json.Unmarshal(line, &resp))
+		require.NotNil(t, resp.Error)
+		assert.Contains(t, resp.Error.Message, `unknown mode "words"`)
+	})
+}
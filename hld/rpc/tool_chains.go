@@ -0,0 +1,165 @@
+package rpc
+
+import (
+	"encoding/json"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// ToolChain is a group of tool calls where each step used the output of an
+// earlier one. Calls with no inferable dependency form standalone chains of
+// a single step.
+type ToolChain struct {
+	ID         int             `json:"id"`
+	Standalone bool            `json:"standalone"`
+	Steps      []ToolChainStep `json:"steps"` // In call order
+}
+
+// ToolChainStep is one tool call within a chain
+type ToolChainStep struct {
+	ToolID        string `json:"tool_id"`
+	ToolName      string `json:"tool_name"`
+	CallEventID   int64  `json:"call_event_id"`
+	ResultEventID int64  `json:"result_event_id,omitempty"` // Zero while the call has no result
+	// DependsOn is the tool ID of the earlier step this call built on, and
+	// Evidence the value that links them
+	DependsOn string `json:"depends_on,omitempty"`
+	Evidence  string `json:"evidence,omitempty"`
+}
+
+// chainTokenPattern finds identifier-like values (paths, IDs, URLs) that are
+// specific enough to show one call used another's output
+var chainTokenPattern = regexp.MustCompile(`[A-Za-z0-9_./:@#-]{6,}`)
+
+// maxChainTokens bounds how much of a large tool result is considered
+const maxChainTokens = 500
+
+// chainTokens returns the identifier-like values in s. Plain words are left
+// out since they would link unrelated calls.
+func chainTokens(s string) []string {
+	var tokens []string
+	for _, token := range chainTokenPattern.FindAllString(s, maxChainTokens) {
+		token = strings.Trim(token, ".:-#")
+		if len(token) < 6 || !strings.ContainsAny(token, "0123456789/._-") {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// inputChainTokens returns the values in a tool input, leaving out its keys
+// so that calls sharing a parameter name aren't linked
+func inputChainTokens(inputJSON string) []string {
+	var input interface{}
+	if err := json.Unmarshal([]byte(inputJSON), &input); err != nil {
+		return chainTokens(inputJSON)
+	}
+	var tokens []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			tokens = append(tokens, chainTokens(v)...)
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			// Sorted so the reported evidence is stable
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				walk(v[key])
+			}
+		}
+	}
+	walk(input)
+	return tokens
+}
+
+// groupToolChains links tool calls into chains. A call depends on an earlier
+// call when it was issued after that call's result arrived and its input
+// repeats a value from that call's result or input, such as reading a file
+// and then editing the same path. Each call links to the most recent such
+// call. This is a display heuristic computed from the events; nothing is
+// stored.
+func groupToolChains(events []*store.ConversationEvent) []ToolChain {
+	type node struct {
+		step      ToolChainStep
+		callSeq   int
+		resultSeq int             // Zero until a result arrives
+		tokens    map[string]bool // Values from the result
+		input     []string        // Values from the input
+		parent    int             // Index of the node this one depends on, or -1
+	}
+
+	var nodes []*node
+	byToolID := make(map[string]*node)
+	for _, event := range events {
+		switch event.EventType {
+		case store.EventTypeToolCall:
+			if event.ToolID == "" {
+				continue
+			}
+			n := &node{
+				step: ToolChainStep{
+					ToolID:      event.ToolID,
+					ToolName:    event.ToolName,
+					CallEventID: event.ID,
+				},
+				callSeq: event.Sequence,
+				tokens:  make(map[string]bool),
+				input:   inputChainTokens(event.ToolInputJSON),
+				parent:  -1,
+			}
+			nodes = append(nodes, n)
+			byToolID[event.ToolID] = n
+		case store.EventTypeToolResult:
+			if n, ok := byToolID[event.ToolResultForID]; ok {
+				n.step.ResultEventID = event.ID
+				n.resultSeq = event.Sequence
+				for _, token := range chainTokens(event.ToolResultContent) {
+					n.tokens[token] = true
+				}
+			}
+		}
+	}
+
+	// Find each call's dependency using the values known before it was issued
+	for i, n := range nodes {
+		for j := i - 1; j >= 0 && n.parent < 0; j-- {
+			prev := nodes[j]
+			if prev.resultSeq == 0 || prev.resultSeq > n.callSeq {
+				continue
+			}
+			for _, token := range n.input {
+				if prev.tokens[token] || slices.Contains(prev.input, token) {
+					n.parent = j
+					n.step.DependsOn = prev.step.ToolID
+					n.step.Evidence = token
+					break
+				}
+			}
+		}
+	}
+
+	// Each chain is the tree rooted at a call with no dependency
+	chainOf := make([]int, len(nodes))
+	var chains []ToolChain
+	for i, n := range nodes {
+		if n.parent < 0 {
+			chainOf[i] = len(chains)
+			chains = append(chains, ToolChain{ID: len(chains) + 1})
+		} else {
+			chainOf[i] = chainOf[n.parent]
+		}
+		chains[chainOf[i]].Steps = append(chains[chainOf[i]].Steps, n.step)
+	}
+	for i := range chains {
+		chains[i].Standalone = len(chains[i].Steps) == 1
+	}
+	return chains
+}
@@ -0,0 +1,104 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupToolChains(t *testing.T) {
+	var events []*store.ConversationEvent
+	call := func(toolID, name, input string) {
+		events = append(events, &store.ConversationEvent{
+			ID: int64(len(events) + 1), Sequence: len(events) + 1,
+			EventType: store.EventTypeToolCall, ToolID: toolID, ToolName: name, ToolInputJSON: input,
+		})
+	}
+	result := func(toolID, content string) {
+		events = append(events, &store.ConversationEvent{
+			ID: int64(len(events) + 1), Sequence: len(events) + 1,
+			EventType: store.EventTypeToolResult, ToolResultForID: toolID, ToolResultContent: content,
+		})
+	}
+
+	call("t1", "Glob", `{"pattern":"*.go"}`)
+	result("t1", "src/main.go\nsrc/util.go")
+	call("t2", "Read", `{"file_path":"src/main.go"}`)
+	result("t2", "package main")
+	call("t3", "Bash", `{"command":"date"}`)
+	result("t3", "Tue Oct 14")
+	call("t4", "Edit", `{"file_path":"src/main.go","old_string":"main"}`)
+	result("t4", "ok")
+	// Issued in parallel: t6 was sent before t5's result, so it can't depend on it
+	call("t5", "Read", `{"file_path":"docs/readme.md"}`)
+	call("t6", "Grep", `{"path":"docs/readme.md"}`)
+	result("t5", "# Readme")
+	result("t6", "no matches")
+
+	chains := groupToolChains(events)
+	require.Len(t, chains, 4)
+
+	assert.False(t, chains[0].Standalone)
+	require.Len(t, chains[0].Steps, 3)
+	assert.Equal(t, []string{"t1", "t2", "t4"}, []string{chains[0].Steps[0].ToolID, chains[0].Steps[1].ToolID, chains[0].Steps[2].ToolID})
+	assert.Empty(t, chains[0].Steps[0].DependsOn)
+	assert.Equal(t, "t1", chains[0].Steps[1].DependsOn)
+	assert.Equal(t, "src/main.go", chains[0].Steps[1].Evidence)
+	assert.Equal(t, "t2", chains[0].Steps[2].DependsOn, "links to the most recent call")
+	assert.Equal(t, int64(2), chains[0].Steps[0].ResultEventID)
+
+	for i, toolID := range []string{"t3", "t5", "t6"} {
+		chain := chains[i+1]
+		assert.True(t, chain.Standalone, toolID)
+		require.Len(t, chain.Steps, 1)
+		assert.Equal(t, toolID, chain.Steps[0].ToolID)
+		assert.Empty(t, chain.Steps[0].DependsOn)
+	}
+}
+
+func TestHandleGetConversationToolChains(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+		ID:              "sess-chains",
+		RunID:           "run-chains",
+		ClaudeSessionID: "claude-chains",
+		Status:          store.SessionStatusRunning,
+		CreatedAt:       time.Now(),
+		LastActivityAt:  time.Now(),
+	}))
+	for _, event := range []*store.ConversationEvent{
+		{EventType: store.EventTypeMessage, Role: "user", Content: "fix it"},
+		{EventType: store.EventTypeToolCall, ToolID: "t1", ToolName: "Glob", ToolInputJSON: `{"pattern":"*.go"}`},
+		{EventType: store.EventTypeToolResult, ToolResultForID: "t1", ToolResultContent: "cmd/app.go"},
+		{EventType: store.EventTypeToolCall, ToolID: "t2", ToolName: "Read", ToolInputJSON: `{"file_path":"cmd/app.go"}`},
+		{EventType: store.EventTypeToolResult, ToolResultForID: "t2", ToolResultContent: "package main"},
+	} {
+		event.SessionID = "sess-chains"
+		event.ClaudeSessionID = "claude-chains"
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, event))
+	}
+
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	result, err := handlers.HandleGetConversation(ctx, json.RawMessage(`{"session_id":"sess-chains"}`))
+	require.NoError(t, err)
+	assert.Nil(t, result.(*GetConversationResponse).ToolChains)
+
+	result, err = handlers.HandleGetConversation(ctx, json.RawMessage(`{"session_id":"sess-chains","group_by_tool_chain":true}`))
+	require.NoError(t, err)
+	resp := result.(*GetConversationResponse)
+	assert.Len(t, resp.Events, 5, "the flat view is kept")
+	require.Len(t, resp.ToolChains, 1)
+	require.Len(t, resp.ToolChains[0].Steps, 2)
+	assert.Equal(t, "t1", resp.ToolChains[0].Steps[1].DependsOn)
+	assert.Equal(t, resp.Events[1].ID, resp.ToolChains[0].Steps[0].CallEventID)
+}
@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// Tool decision values
+const (
+	DecisionApproved = "approved"
+	DecisionRejected = "rejected"
+	DecisionPending  = "pending"
+
+	DecisionModeManual = "manual"
+	DecisionModeAuto   = "auto"
+)
+
+// autoApprovalPolicies maps the comment the approval manager records on
+// auto-approved calls to the policy responsible
+var autoApprovalPolicies = map[string]string{
+	"dangerous skip permissions": "dangerous_skip_permissions",
+	"auto-accept mode":           "auto_accept_edits",
+}
+
+// toolDecisions joins the approvals of every session in events with the tool
+// calls they were raised for. Approvals are returned oldest first; sessions
+// without approvals contribute nothing.
+func (h *SessionHandlers) toolDecisions(ctx context.Context, events []*store.ConversationEvent) ([]ToolDecision, error) {
+	toolCalls := make(map[string]*store.ConversationEvent) // approval ID -> tool call
+	byToolID := make(map[string]*store.ConversationEvent)
+	var sessionIDs []string
+	seen := make(map[string]bool)
+	for _, event := range events {
+		if !seen[event.SessionID] {
+			seen[event.SessionID] = true
+			sessionIDs = append(sessionIDs, event.SessionID)
+		}
+		if event.EventType != store.EventTypeToolCall {
+			continue
+		}
+		byToolID[event.ToolID] = event
+		if event.ApprovalID != "" {
+			toolCalls[event.ApprovalID] = event
+		}
+	}
+
+	decisions := make([]ToolDecision, 0)
+	for _, sessionID := range sessionIDs {
+		approvals, err := h.store.GetSessionApprovals(ctx, sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get approvals: %w", err)
+		}
+		for _, approval := range approvals {
+			toolCall := toolCalls[approval.ID]
+			if toolCall == nil && approval.ToolUseID != nil {
+				toolCall = byToolID[*approval.ToolUseID]
+			}
+			decisions = append(decisions, toolDecision(approval, toolCall))
+		}
+	}
+	return decisions, nil
+}
+
+// toolDecision describes a single approval. Auto-approved calls are created
+// already approved and never receive a response, which is how they are told
+// apart from manual decisions.
+func toolDecision(approval *store.Approval, toolCall *store.ConversationEvent) ToolDecision {
+	d := ToolDecision{
+		ApprovalID:    approval.ID,
+		SessionID:     approval.SessionID,
+		ToolName:      approval.ToolName,
+		ToolInputJSON: string(approval.ToolInput),
+		Mode:          DecisionModeManual,
+		RequestedAt:   approval.CreatedAt.Format(time.RFC3339),
+		Reason:        approval.Comment,
+	}
+	if toolCall != nil {
+		d.ToolID = toolCall.ToolID
+		if toolCall.ToolInputJSON != "" {
+			d.ToolInputJSON = toolCall.ToolInputJSON
+		}
+	}
+
+	switch approval.Status {
+	case store.ApprovalStatusLocalApproved:
+		d.Decision = DecisionApproved
+	case store.ApprovalStatusLocalDenied:
+		d.Decision = DecisionRejected
+	default:
+		d.Decision = DecisionPending
+		return d
+	}
+
+	if approval.RespondedAt == nil && approval.Status == store.ApprovalStatusLocalApproved {
+		d.Mode = DecisionModeAuto
+		d.DecidedBy = autoApprovalPolicy(approval.Comment)
+		d.DecidedAt = d.RequestedAt
+		return d
+	}
+	// Approvals don't record the responder's identity
+	d.DecidedBy = "human"
+	if approval.RespondedAt != nil {
+		d.DecidedAt = approval.RespondedAt.Format(time.RFC3339)
+	}
+	return d
+}
+
+// autoApprovalPolicy names the policy behind an auto-approval comment
+func autoApprovalPolicy(comment string) string {
+	for phrase, policy := range autoApprovalPolicies {
+		if strings.Contains(comment, phrase) {
+			return policy
+		}
+	}
+	return "policy"
+}
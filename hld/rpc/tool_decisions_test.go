@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/approval"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetConversationDecisionsOnly(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	approvals := approval.NewManager(sqliteStore, nil)
+	handlers := NewSessionHandlers(nil, sqliteStore, approvals, nil, nil)
+
+	createSession := func(id string, autoAcceptEdits bool) {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID:              id,
+			RunID:           "run-" + id,
+			ClaudeSessionID: "claude-" + id,
+			Query:           "test",
+			Status:          store.SessionStatusRunning,
+			AutoAcceptEdits: autoAcceptEdits,
+			CreatedAt:       time.Now(),
+			LastActivityAt:  time.Now(),
+		}))
+	}
+	addEvent := func(sessionID string, event *store.ConversationEvent) {
+		event.SessionID = sessionID
+		event.ClaudeSessionID = "claude-" + sessionID
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, event))
+	}
+	getDecisions := func(sessionID string) []ToolDecision {
+		result, err := handlers.HandleGetConversation(ctx,
+			json.RawMessage(`{"session_id":"`+sessionID+`","decisions_only":true}`))
+		require.NoError(t, err)
+		resp := result.(*GetConversationResponse)
+		assert.Empty(t, resp.Events, "conversation events are left out")
+		require.NotNil(t, resp.Decisions)
+		return resp.Decisions
+	}
+
+	createSession("sess-1", true)
+	addEvent("sess-1", &store.ConversationEvent{EventType: store.EventTypeMessage, Role: "user", Content: "go"})
+	for _, tool := range []struct{ id, name string }{{"t1", "Bash"}, {"t2", "Bash"}, {"t3", "Edit"}} {
+		addEvent("sess-1", &store.ConversationEvent{
+			EventType:     store.EventTypeToolCall,
+			ToolID:        tool.id,
+			ToolName:      tool.name,
+			ToolInputJSON: `{"n":"` + tool.id + `"}`,
+		})
+	}
+
+	approved, err := approvals.CreateApprovalWithToolUseID(ctx, "sess-1", "Bash", json.RawMessage(`{"n":"t1"}`), "t1")
+	require.NoError(t, err)
+	require.NoError(t, approvals.ApproveToolCall(ctx, approved.ID, "looks fine"))
+	denied, err := approvals.CreateApprovalWithToolUseID(ctx, "sess-1", "Bash", json.RawMessage(`{"n":"t2"}`), "t2")
+	require.NoError(t, err)
+	require.NoError(t, approvals.DenyToolCall(ctx, denied.ID, "too risky"))
+	auto, err := approvals.CreateApprovalWithToolUseID(ctx, "sess-1", "Edit", json.RawMessage(`{"n":"t3"}`), "t3")
+	require.NoError(t, err)
+
+	decisions := getDecisions("sess-1")
+	require.Len(t, decisions, 3)
+
+	assert.Equal(t, approved.ID, decisions[0].ApprovalID)
+	assert.Equal(t, "t1", decisions[0].ToolID)
+	assert.Equal(t, DecisionApproved, decisions[0].Decision)
+	assert.Equal(t, DecisionModeManual, decisions[0].Mode)
+	assert.Equal(t, "human", decisions[0].DecidedBy)
+	assert.NotEmpty(t, decisions[0].DecidedAt)
+	assert.Equal(t, "looks fine", decisions[0].Reason)
+
+	assert.Equal(t, DecisionRejected, decisions[1].Decision)
+	assert.Equal(t, DecisionModeManual, decisions[1].Mode)
+	assert.Equal(t, "too risky", decisions[1].Reason)
+
+	assert.Equal(t, auto.ID, decisions[2].ApprovalID)
+	assert.Equal(t, "t3", decisions[2].ToolID)
+	assert.Equal(t, DecisionApproved, decisions[2].Decision)
+	assert.Equal(t, DecisionModeAuto, decisions[2].Mode)
+	assert.Equal(t, "auto_accept_edits", decisions[2].DecidedBy)
+
+	t.Run("session without approvals", func(t *testing.T) {
+		createSession("sess-2", false)
+		addEvent("sess-2", &store.ConversationEvent{EventType: store.EventTypeMessage, Role: "user", Content: "hi"})
+		assert.Empty(t, getDecisions("sess-2"))
+	})
+}
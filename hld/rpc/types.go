@@ -1,5 +1,7 @@
 package rpc
 
+import "github.com/humanlayer/humanlayer/hld/session"
+
 // HealthCheckRequest is the request for health check RPC
 type HealthCheckRequest struct{}
 
@@ -13,6 +15,56 @@ type HealthCheckResponse struct {
 type GetConversationRequest struct {
 	SessionID       string `json:"session_id,omitempty"`        // Get by session ID
 	ClaudeSessionID string `json:"claude_session_id,omitempty"` // Get by Claude session ID
+
+	// Optional anchor: return only the anchor event and a window of its
+	// neighbours. At most one of AnchorEventID or AnchorToolID may be set.
+	AnchorEventID int64  `json:"anchor_event_id,omitempty"`
+	AnchorToolID  string `json:"anchor_tool_id,omitempty"` // Anchors to the tool_call with this tool ID
+	Before        *int   `json:"before,omitempty"`         // Events before the anchor (default 5)
+	After         *int   `json:"after,omitempty"`          // Events after the anchor (default 5)
+
+	// Language keeps only events tagged with this language
+	Language string `json:"language,omitempty"`
+
+	// IncludeCostAudit adds a check that per-turn costs reconcile with the
+	// session total
+	IncludeCostAudit bool `json:"include_cost_audit,omitempty"`
+
+	// DecisionsOnly returns the tool calls that went through approval and
+	// their outcomes in Decisions, instead of the conversation events
+	DecisionsOnly bool `json:"decisions_only,omitempty"`
+
+	// GroupByToolChain adds ToolChains, linking tool calls that used each
+	// other's results. Events are still returned in the flat view.
+	GroupByToolChain bool `json:"group_by_tool_chain,omitempty"`
+
+	// Ordering is OrderingSequence (default) or OrderingLogical, which groups
+	// events by turn with each tool result after its call
+	Ordering string `json:"ordering,omitempty"`
+
+	// TranslateTo translates message content into this language tag, set in
+	// TranslatedContent next to the original. Tool inputs and code are left
+	// untranslated.
+	TranslateTo string `json:"translate_to,omitempty"`
+
+	// Limit returns at most this many events, starting after AfterSequence.
+	// Pass the response's NextSequence as AfterSequence to get the next page.
+	// Zero returns the whole conversation.
+	Limit         int `json:"limit,omitempty"`
+	AfterSequence int `json:"after_sequence,omitempty"`
+
+	// FromSequence and ToSequence return only the session's own events with
+	// sequence numbers in this inclusive range, leaving out its parent
+	// chain. Either may be omitted to leave that end open. They require
+	// SessionID and can't be combined with Limit or AfterSequence.
+	FromSequence *int `json:"from_sequence,omitempty"`
+	ToSequence   *int `json:"to_sequence,omitempty"`
+
+	// SinceSequence returns only events with sequence numbers above it, for
+	// polling: pass the response's LastSequence back to get what was added
+	// since. With SessionID it counts the session's own events, leaving out
+	// its parent chain. It can't be combined with the other ranges.
+	SinceSequence *int `json:"since_sequence,omitempty"`
 }
 
 // ConversationEvent represents a single event in the conversation
@@ -37,16 +89,116 @@ type ConversationEvent struct {
 	// Tool result fields
 	ToolResultForID   string `json:"tool_result_for_id,omitempty"`
 	ToolResultContent string `json:"tool_result_content,omitempty"`
+	ToolResultBytes   int    `json:"tool_result_bytes,omitempty"`
+	ToolResultTokens  int    `json:"tool_result_tokens,omitempty"` // Estimated
+	ToolResultJSON    string `json:"tool_result_json,omitempty"`   // The result as the provider sent it
+	ToolError         string `json:"tool_error,omitempty"`         // Set when the tool reported an error
 
 	// Approval tracking
 	IsCompleted    bool   `json:"is_completed"`
 	ApprovalStatus string `json:"approval_status,omitempty"` // NULL, 'pending', 'approved', 'denied'
 	ApprovalID     string `json:"approval_id,omitempty"`
+
+	// Stable ID for deep-linking, resolvable via getEventByPermalink
+	Permalink string `json:"permalink,omitempty"`
+	Language  string `json:"language,omitempty"` // Declared or detected language tag, empty if unknown
+
+	// Set on tool results served from the tool result cache instead of executing
+	ToolCacheHit bool `json:"tool_cache_hit,omitempty"`
+
+	// Set when the provider stream was cut off before this event's turn
+	// finished, so the content may be partial
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Set with logical ordering: the event's turn and its position within it
+	Turn      int `json:"turn,omitempty"`
+	TurnOrder int `json:"turn_order,omitempty"`
+
+	// Hash of the event's type, role, content and tool fields. It changes
+	// only when that content does, so clients can use it to cache events and
+	// detect corrections.
+	ContentHash string `json:"content_hash"`
+
+	// Set on messages when translation is requested
+	TranslatedContent string `json:"translated_content,omitempty"`
+
+	// Reviewer notes on this event, oldest first
+	Annotations []Annotation `json:"annotations,omitempty"`
 }
 
 // GetConversationResponse is the response for fetching conversation history
 type GetConversationResponse struct {
-	Events []ConversationEvent `json:"events"`
+	Events      []ConversationEvent `json:"events"`
+	AnchorIndex *int                `json:"anchor_index,omitempty"` // Position of the anchor within Events
+	CostAudit   *CostAudit          `json:"cost_audit,omitempty"`   // Set when IncludeCostAudit is requested
+	Decisions   []ToolDecision      `json:"decisions"`              // Set when DecisionsOnly is requested, null otherwise
+	ToolChains  []ToolChain         `json:"tool_chains,omitempty"`  // Set when GroupByToolChain is requested
+	// NextSequence is the cursor for the page after this one, or 0 if this
+	// is the last page
+	NextSequence int `json:"next_sequence,omitempty"`
+	// TotalCount is how many events the whole conversation holds, and
+	// HasMore whether another page follows this one
+	TotalCount int  `json:"total_count"`
+	HasMore    bool `json:"has_more"`
+	// LastSequence is the highest sequence number returned, of the session's
+	// own events for SessionID lookups, or SinceSequence if none were
+	LastSequence int `json:"last_sequence"`
+}
+
+// ToolDecision is the approval outcome for a single tool call
+type ToolDecision struct {
+	ApprovalID    string `json:"approval_id"`
+	SessionID     string `json:"session_id"`
+	ToolID        string `json:"tool_id,omitempty"` // Empty if the approval was never linked to a tool call
+	ToolName      string `json:"tool_name"`
+	ToolInputJSON string `json:"tool_input_json,omitempty"`
+	Decision      string `json:"decision"`             // 'approved', 'rejected' or 'pending'
+	Mode          string `json:"mode"`                 // 'manual' or 'auto'
+	DecidedBy     string `json:"decided_by,omitempty"` // 'human', or the policy that auto-approved
+	RequestedAt   string `json:"requested_at"`
+	DecidedAt     string `json:"decided_at,omitempty"`
+	Reason        string `json:"reason,omitempty"` // Comment or rejection reason
+}
+
+// CostAudit reports whether per-turn costs reconcile with the session total
+type CostAudit struct {
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"` // Why the audit is unavailable
+}
+
+// GetConversationsRequest is the request for fetching several sessions'
+// conversations at once. Filter and pagination options apply to every session.
+type GetConversationsRequest struct {
+	SessionIDs []string `json:"session_ids"`
+	Language   string   `json:"language,omitempty"` // Keeps only events tagged with this language
+	Offset     int      `json:"offset,omitempty"`   // Events to skip in each conversation
+	Limit      int      `json:"limit,omitempty"`    // Max events per conversation, 0 for no limit
+}
+
+// SessionConversation is one session's result within a GetConversationsResponse
+type SessionConversation struct {
+	Events    []ConversationEvent `json:"events"`
+	Truncated bool                `json:"truncated,omitempty"` // Cut short by the batch event cap
+	Error     string              `json:"error,omitempty"`     // Set when this session couldn't be fetched
+}
+
+// GetConversationsResponse maps each requested session ID to its conversation
+type GetConversationsResponse struct {
+	Conversations map[string]SessionConversation `json:"conversations"`
+}
+
+// GetEventByPermalinkRequest is the request for resolving an event permalink
+type GetEventByPermalinkRequest struct {
+	Permalink   string `json:"permalink"`
+	ContextSize int    `json:"context_size,omitempty"` // Events to include on each side, default 2
+}
+
+// GetEventByPermalinkResponse is the response for resolving an event permalink
+type GetEventByPermalinkResponse struct {
+	Event     ConversationEvent   `json:"event"`
+	SessionID string              `json:"session_id"`
+	Before    []ConversationEvent `json:"before"` // Preceding events in sequence order
+	After     []ConversationEvent `json:"after"`  // Following events in sequence order
 }
 
 // GetSessionStateRequest is the request for fetching session state
@@ -56,38 +208,75 @@ type GetSessionStateRequest struct {
 
 // SessionState represents the current state of a session
 type SessionState struct {
-	ID                                  string  `json:"id"`
-	RunID                               string  `json:"run_id"`
-	ClaudeSessionID                     string  `json:"claude_session_id,omitempty"`
-	ParentSessionID                     string  `json:"parent_session_id,omitempty"`
-	Status                              string  `json:"status"` // starting, running, completed, failed, waiting_input
-	Query                               string  `json:"query"`
-	Summary                             string  `json:"summary"`
-	Title                               string  `json:"title"`
-	Model                               string  `json:"model,omitempty"`
-	ModelID                             string  `json:"model_id,omitempty"`
-	WorkingDir                          string  `json:"working_dir,omitempty"`
-	CreatedAt                           string  `json:"created_at"`
-	LastActivityAt                      string  `json:"last_activity_at"`
-	CompletedAt                         string  `json:"completed_at,omitempty"`
-	ErrorMessage                        string  `json:"error_message,omitempty"`
-	CostUSD                             float64 `json:"cost_usd,omitempty"`
-	InputTokens                         int     `json:"input_tokens,omitempty"`
-	OutputTokens                        int     `json:"output_tokens,omitempty"`
-	CacheCreationInputTokens            int     `json:"cache_creation_input_tokens,omitempty"`
-	CacheReadInputTokens                int     `json:"cache_read_input_tokens,omitempty"`
-	EffectiveContextTokens              int     `json:"effective_context_tokens,omitempty"`
-	ContextLimit                        int     `json:"context_limit,omitempty"`
-	DurationMS                          int     `json:"duration_ms,omitempty"`
-	AutoAcceptEdits                     bool    `json:"auto_accept_edits"`
-	DangerouslySkipPermissions          bool    `json:"dangerously_skip_permissions"`
-	DangerouslySkipPermissionsExpiresAt string  `json:"dangerously_skip_permissions_expires_at,omitempty"`
-	Archived                            bool    `json:"archived"`
+	ID                                  string                   `json:"id"`
+	RunID                               string                   `json:"run_id"`
+	ClaudeSessionID                     string                   `json:"claude_session_id,omitempty"`
+	ParentSessionID                     string                   `json:"parent_session_id,omitempty"`
+	Status                              string                   `json:"status"` // starting, running, completed, failed, waiting_input
+	Query                               string                   `json:"query"`
+	Summary                             string                   `json:"summary"`
+	Title                               string                   `json:"title"`
+	Model                               string                   `json:"model,omitempty"`
+	ModelID                             string                   `json:"model_id,omitempty"`
+	WorkingDir                          string                   `json:"working_dir,omitempty"`
+	CreatedAt                           string                   `json:"created_at"`
+	LastActivityAt                      string                   `json:"last_activity_at"`
+	CompletedAt                         string                   `json:"completed_at,omitempty"`
+	ErrorMessage                        string                   `json:"error_message,omitempty"`
+	CostUSD                             float64                  `json:"cost_usd,omitempty"`
+	MaxCostUSD                          *float64                 `json:"max_cost_usd,omitempty"` // Cost cap past which events are refused, nil for none
+	InputTokens                         int                      `json:"input_tokens,omitempty"`
+	OutputTokens                        int                      `json:"output_tokens,omitempty"`
+	CacheCreationInputTokens            int                      `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens                int                      `json:"cache_read_input_tokens,omitempty"`
+	EffectiveContextTokens              int                      `json:"effective_context_tokens,omitempty"`
+	ContextLimit                        int                      `json:"context_limit,omitempty"`
+	DurationMS                          int                      `json:"duration_ms,omitempty"`
+	QueuedDurationMS                    int64                    `json:"queued_duration_ms"`               // Time spent waiting for a launch slot, zero if it never queued
+	FirstTokenLatencyMS                 *int64                   `json:"first_token_latency_ms,omitempty"` // Process start to first assistant output, nil until output arrives
+	NumTurns                            int                      `json:"num_turns,omitempty"`
+	ThroughputTokensPerSec              *float64                 `json:"throughput_tokens_per_sec,omitempty"` // Nil until a turn completes
+	ToolResultBytes                     int64                    `json:"tool_result_bytes,omitempty"`
+	ToolResultTokens                    int64                    `json:"tool_result_tokens,omitempty"` // Estimated tokens consumed by tool results
+	AutoAcceptEdits                     bool                     `json:"auto_accept_edits"`
+	DangerouslySkipPermissions          bool                     `json:"dangerously_skip_permissions"`
+	DangerouslySkipPermissionsExpiresAt string                   `json:"dangerously_skip_permissions_expires_at,omitempty"`
+	Archived                            bool                     `json:"archived"`
+	Imported                            bool                     `json:"imported,omitempty"`
+	NeedsAttention                      bool                     `json:"needs_attention"`
+	AttentionReason                     string                   `json:"attention_reason,omitempty"` // Why the session needs attention
+	ToolQuota                           *session.ToolQuotaStatus `json:"tool_quota,omitempty"`       // Remaining tool calls, set by getSessionState for quota'd sessions
 }
 
 // GetSessionStateResponse is the response for fetching session state
 type GetSessionStateResponse struct {
+	Session           SessionState   `json:"session"`
+	ActiveSubscribers int            `json:"active_subscribers"` // Live event subscriptions scoped to this session
+	EventCounts       map[string]int `json:"event_counts"`       // The session's events by type, without types it has none of
+}
+
+// BatchGetSessionStateRequest is the request for the states of several sessions
+type BatchGetSessionStateRequest struct {
+	SessionIDs []string `json:"session_ids"`
+}
+
+// BatchGetSessionStateResponse holds the sessions found, in request order,
+// and the requested IDs that don't exist
+type BatchGetSessionStateResponse struct {
+	Sessions []SessionState `json:"sessions"`
+	NotFound []string       `json:"not_found"`
+}
+
+// SubscribeSessionStateRequest is the request for streaming a session's state
+type SubscribeSessionStateRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// SessionStateNotification is pushed to session state subscribers
+type SessionStateNotification struct {
+	Type    string       `json:"type"` // always "session_state"
 	Session SessionState `json:"session"`
+	Final   bool         `json:"final"` // true once the session reached a terminal status
 }
 
 // GetSessionSnapshotsRequest requests file snapshots for a session
@@ -147,6 +336,31 @@ type InterruptSessionResponse struct {
 	Status    string `json:"status"`
 }
 
+// CancelSessionRequest is the request for cancelling a session
+type CancelSessionRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// CancelSessionResponse is the response for cancelling a session
+type CancelSessionResponse struct {
+	Success   bool   `json:"success"`
+	SessionID string `json:"session_id"`
+	Status    string `json:"status"`
+}
+
+// DeleteSessionRequest is the request for permanently deleting a session
+type DeleteSessionRequest struct {
+	SessionID string `json:"session_id"`
+	Confirm   bool   `json:"confirm"`         // Must be true, to guard against accidental deletion
+	Force     bool   `json:"force,omitempty"` // Cancel a live session first instead of refusing
+}
+
+// DeleteSessionResponse is the response for deleting a session
+type DeleteSessionResponse struct {
+	Success   bool   `json:"success"`
+	SessionID string `json:"session_id"`
+}
+
 // UpdateSessionSettingsRequest is the request for updating session settings
 type UpdateSessionSettingsRequest struct {
 	SessionID                           string `json:"session_id"`
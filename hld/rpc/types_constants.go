@@ -43,7 +43,7 @@ func (d Decision) IsValidForApprovalType(approvalType ApprovalType) bool {
 // ValidateForApprovalType validates the decision for a specific approval type and returns an error if invalid
 func (d Decision) ValidateForApprovalType(approvalType ApprovalType) error {
 	if !d.IsValidForApprovalType(approvalType) {
-		return fmt.Errorf("invalid decision '%s' for approval type '%s'", d, approvalType)
+		return fmt.Errorf("%w: invalid decision '%s' for approval type '%s'", ErrInvalidRequest, d, approvalType)
 	}
 	return nil
 }
@@ -66,7 +66,7 @@ func IsValidApprovalType(s string) bool {
 // ParseApprovalType parses a string into an ApprovalType, returning an error if invalid
 func ParseApprovalType(s string) (ApprovalType, error) {
 	if !IsValidApprovalType(s) {
-		return "", fmt.Errorf("invalid approval type: %s", s)
+		return "", fmt.Errorf("%w: invalid approval type: %s", ErrInvalidRequest, s)
 	}
 	return ApprovalType(s), nil
 }
@@ -77,7 +77,7 @@ func ParseDecision(s string) (Decision, error) {
 	case string(DecisionApprove), string(DecisionDeny), string(DecisionRespond):
 		return Decision(s), nil
 	default:
-		return "", fmt.Errorf("invalid decision: %s", s)
+		return "", fmt.Errorf("%w: invalid decision: %s", ErrInvalidRequest, s)
 	}
 }
 
@@ -94,3 +94,21 @@ func GetModelContextLimit(model string) int {
 	}
 	return ModelContextLimits["default"]
 }
+
+// ModelInputPricing is a snapshot of input token prices in USD per million
+// tokens, used for launch cost estimates. Unknown or unspecified models use
+// the default, which is the most expensive price so estimates stay a ceiling.
+var ModelInputPricing = map[string]float64{
+	"opus":    15.00,
+	"sonnet":  3.00,
+	"haiku":   0.80,
+	"default": 15.00,
+}
+
+// GetModelInputPrice returns the input price per million tokens for a model
+func GetModelInputPrice(model string) float64 {
+	if price, ok := ModelInputPricing[model]; ok {
+		return price
+	}
+	return ModelInputPricing["default"]
+}
@@ -0,0 +1,131 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// sessionLatency splits a session's launch into the time it waited for a
+// launch slot and the time from process start to the first assistant output.
+// Sessions that never queued have a zero queued duration; the first-token
+// latency is nil until output arrives.
+func sessionLatency(sess *store.Session) (queuedMS int64, firstTokenMS *int64) {
+	if sess.QueuedAt != nil && sess.StartedAt != nil && sess.StartedAt.After(*sess.QueuedAt) {
+		queuedMS = sess.StartedAt.Sub(*sess.QueuedAt).Milliseconds()
+	}
+	if sess.StartedAt != nil && sess.FirstEventAt != nil {
+		ms := max(0, sess.FirstEventAt.Sub(*sess.StartedAt).Milliseconds())
+		firstTokenMS = &ms
+	}
+	return queuedMS, firstTokenMS
+}
+
+// GetUsageReportRequest is the request for aggregate session usage
+type GetUsageReportRequest struct {
+	Since string `json:"since,omitempty"` // RFC3339; sessions created before it are left out
+}
+
+// LatencyStats summarizes a latency across sessions, in milliseconds
+type LatencyStats struct {
+	Count int   `json:"count"`
+	Avg   int64 `json:"avg_ms"`
+	P50   int64 `json:"p50_ms"`
+	P95   int64 `json:"p95_ms"`
+	Max   int64 `json:"max_ms"`
+}
+
+// GetUsageReportResponse aggregates usage and launch latency across sessions
+// for capacity planning. Queued time is scheduling delay in this daemon;
+// first-token latency and duration are time spent with the provider.
+type GetUsageReportResponse struct {
+	Sessions          int          `json:"sessions"`
+	CostUSD           float64      `json:"cost_usd"`
+	InputTokens       int64        `json:"input_tokens"`
+	OutputTokens      int64        `json:"output_tokens"`
+	QueuedDuration    LatencyStats `json:"queued_duration"`     // Launched sessions
+	FirstTokenLatency LatencyStats `json:"first_token_latency"` // Sessions that produced output
+	Duration          LatencyStats `json:"duration"`            // Completed sessions
+}
+
+// HandleGetUsageReport aggregates usage and latency across sessions
+func (h *SessionHandlers) HandleGetUsageReport(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req GetUsageReportRequest
+	if params != nil {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+	}
+	var since time.Time
+	if req.Since != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, req.Since); err != nil {
+			return nil, fmt.Errorf("%w: invalid since: %w", ErrInvalidRequest, err)
+		}
+	}
+
+	sessions, err := h.store.ListSessions(ctx, store.ListSessionsFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	resp := &GetUsageReportResponse{}
+	var queued, firstToken, duration []int64
+	for _, sess := range sessions {
+		if sess.CreatedAt.Before(since) {
+			continue
+		}
+		resp.Sessions++
+		if sess.CostUSD != nil {
+			resp.CostUSD += *sess.CostUSD
+		}
+		if sess.InputTokens != nil {
+			resp.InputTokens += int64(*sess.InputTokens)
+		}
+		if sess.OutputTokens != nil {
+			resp.OutputTokens += int64(*sess.OutputTokens)
+		}
+
+		queuedMS, firstTokenMS := sessionLatency(sess)
+		if sess.StartedAt != nil {
+			queued = append(queued, queuedMS)
+		}
+		if firstTokenMS != nil {
+			firstToken = append(firstToken, *firstTokenMS)
+		}
+		if sess.DurationMS != nil {
+			duration = append(duration, int64(*sess.DurationMS))
+		}
+	}
+	resp.QueuedDuration = summarizeLatency(queued)
+	resp.FirstTokenLatency = summarizeLatency(firstToken)
+	resp.Duration = summarizeLatency(duration)
+	return resp, nil
+}
+
+// summarizeLatency computes nearest-rank percentiles of the given samples
+func summarizeLatency(samples []int64) LatencyStats {
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	var total int64
+	for _, v := range samples {
+		total += v
+	}
+	percentile := func(p int) int64 {
+		rank := (p*len(samples) + 99) / 100
+		return samples[max(rank, 1)-1]
+	}
+	return LatencyStats{
+		Count: len(samples),
+		Avg:   total / int64(len(samples)),
+		P50:   percentile(50),
+		P95:   percentile(95),
+		Max:   samples[len(samples)-1],
+	}
+}
@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionLatency(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	base := time.Now().Add(-time.Hour)
+	at := func(ms int) *time.Time {
+		t := base.Add(time.Duration(ms) * time.Millisecond)
+		return &t
+	}
+	intPtr := func(v int) *int { return &v }
+
+	sessions := []struct {
+		id     string
+		update store.SessionUpdate
+	}{
+		// Waited 2s for a slot, first output 800ms after start
+		{"queued", store.SessionUpdate{QueuedAt: at(0), StartedAt: at(2000), FirstEventAt: at(2800), DurationMS: intPtr(5000)}},
+		// Started straight away
+		{"immediate", store.SessionUpdate{QueuedAt: at(0), StartedAt: at(0), FirstEventAt: at(300), DurationMS: intPtr(1000)}},
+		// Started but no output yet
+		{"starting", store.SessionUpdate{QueuedAt: at(0), StartedAt: at(100)}},
+		// Never launched
+		{"draft", store.SessionUpdate{}},
+	}
+	for _, s := range sessions {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID:             s.id,
+			RunID:          "run-" + s.id,
+			Status:         store.SessionStatusRunning,
+			CreatedAt:      base,
+			LastActivityAt: base,
+		}))
+		if s.update != (store.SessionUpdate{}) {
+			require.NoError(t, sqliteStore.UpdateSession(ctx, s.id, s.update))
+		}
+	}
+
+	handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	t.Run("session state", func(t *testing.T) {
+		state := func(id string) SessionState {
+			result, err := handlers.HandleGetSessionState(ctx, json.RawMessage(fmt.Sprintf(`{"session_id":%q}`, id)))
+			require.NoError(t, err)
+			return result.(*GetSessionStateResponse).Session
+		}
+
+		queued := state("queued")
+		assert.Equal(t, int64(2000), queued.QueuedDurationMS)
+		require.NotNil(t, queued.FirstTokenLatencyMS)
+		assert.Equal(t, int64(800), *queued.FirstTokenLatencyMS)
+		assert.Equal(t, 5000, queued.DurationMS)
+
+		immediate := state("immediate")
+		assert.Zero(t, immediate.QueuedDurationMS)
+		require.NotNil(t, immediate.FirstTokenLatencyMS)
+		assert.Equal(t, int64(300), *immediate.FirstTokenLatencyMS)
+
+		assert.Nil(t, state("starting").FirstTokenLatencyMS)
+
+		draft := state("draft")
+		assert.Zero(t, draft.QueuedDurationMS)
+		assert.Nil(t, draft.FirstTokenLatencyMS)
+	})
+
+	t.Run("usage report", func(t *testing.T) {
+		result, err := handlers.HandleGetUsageReport(ctx, nil)
+		require.NoError(t, err)
+		report := result.(*GetUsageReportResponse)
+
+		assert.Equal(t, 4, report.Sessions)
+		assert.Equal(t, LatencyStats{Count: 3, Avg: 700, P50: 100, P95: 2000, Max: 2000}, report.QueuedDuration)
+		assert.Equal(t, LatencyStats{Count: 2, Avg: 550, P50: 300, P95: 800, Max: 800}, report.FirstTokenLatency)
+		assert.Equal(t, LatencyStats{Count: 2, Avg: 3000, P50: 1000, P95: 5000, Max: 5000}, report.Duration)
+
+		result, err = handlers.HandleGetUsageReport(ctx, json.RawMessage(
+			fmt.Sprintf(`{"since":%q}`, time.Now().Format(time.RFC3339))))
+		require.NoError(t, err)
+		assert.Zero(t, result.(*GetUsageReportResponse).Sessions)
+	})
+}
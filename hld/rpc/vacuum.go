@@ -0,0 +1,82 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// VacuumRequest is the request for deleting old conversation data
+type VacuumRequest struct {
+	OlderThan            string `json:"older_than"`                       // RFC3339
+	KeepTerminalSessions bool   `json:"keep_terminal_sessions,omitempty"` // Delete only the sessions' events
+}
+
+// VacuumResponse is the response for deleting old conversation data
+type VacuumResponse struct {
+	DeletedEvents   int64 `json:"deleted_events"`
+	DeletedSessions int64 `json:"deleted_sessions"`
+}
+
+// HandleVacuum deletes the conversation events of terminal sessions last
+// active before older_than, and those sessions too unless
+// keep_terminal_sessions is set
+func (h *SessionHandlers) HandleVacuum(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req VacuumRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.OlderThan == "" {
+		return nil, fmt.Errorf("%w: older_than is required", ErrInvalidRequest)
+	}
+	olderThan, err := time.Parse(time.RFC3339, req.OlderThan)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid older_than: %w", ErrInvalidRequest, err)
+	}
+
+	if !req.KeepTerminalSessions && h.attachments != nil {
+		// Attachment content lives outside the store, so delete it first
+		// for the sessions the store is about to delete
+		if err := h.deleteVacuumedAttachments(ctx, olderThan); err != nil {
+			return nil, err
+		}
+	}
+
+	deletedEvents, deletedSessions, err := h.store.VacuumOldEvents(ctx, olderThan, req.KeepTerminalSessions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to vacuum old events: %w", err)
+	}
+	return &VacuumResponse{
+		DeletedEvents:   deletedEvents,
+		DeletedSessions: deletedSessions,
+	}, nil
+}
+
+// deleteVacuumedAttachments deletes the attachments of the sessions that
+// VacuumOldEvents deletes: terminal sessions last active before olderThan
+// that no session was continued from
+func (h *SessionHandlers) deleteVacuumedAttachments(ctx context.Context, olderThan time.Time) error {
+	sessions, err := h.store.ListSessions(ctx, store.ListSessionsFilter{})
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	parents := make(map[string]bool)
+	for _, s := range sessions {
+		if s.ParentSessionID != "" {
+			parents[s.ParentSessionID] = true
+		}
+	}
+	for _, s := range sessions {
+		if !store.IsTerminalSessionStatus(s.Status) || !s.LastActivityAt.Before(olderThan) || parents[s.ID] {
+			continue
+		}
+		if err := h.attachments.DeleteSessionAttachments(ctx, s.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleVacuum(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	seed := func(t *testing.T) (*SessionHandlers, *fakeAttachmentPruner) {
+		sqliteStore, err := store.NewSQLiteStore(":memory:")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sqliteStore.Close() })
+
+		for _, s := range []struct {
+			id, status   string
+			lastActivity time.Time
+		}{
+			{"old", store.SessionStatusCompleted, now.Add(-48 * time.Hour)},
+			{"old-running", store.SessionStatusRunning, now.Add(-48 * time.Hour)},
+			{"recent", store.SessionStatusCompleted, now},
+		} {
+			require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+				ID: s.id, RunID: "run-" + s.id, ClaudeSessionID: "claude-" + s.id, Query: "q", Status: s.status,
+				CreatedAt: s.lastActivity, LastActivityAt: s.lastActivity,
+			}))
+			for i := 0; i < 3; i++ {
+				require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+					SessionID: s.id, ClaudeSessionID: "claude-" + s.id,
+					EventType: store.EventTypeMessage, Role: "assistant", Content: fmt.Sprintf("event %d", i),
+				}))
+			}
+			lastActivity := s.lastActivity
+			require.NoError(t, sqliteStore.UpdateSession(ctx, s.id, store.SessionUpdate{LastActivityAt: &lastActivity}))
+		}
+
+		handlers := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+		pruner := &fakeAttachmentPruner{}
+		handlers.SetAttachmentPruner(pruner)
+		return handlers, pruner
+	}
+	olderThan := now.Add(-24 * time.Hour).Format(time.RFC3339)
+
+	t.Run("keep sessions", func(t *testing.T) {
+		handlers, pruner := seed(t)
+		params, _ := json.Marshal(VacuumRequest{OlderThan: olderThan, KeepTerminalSessions: true})
+		result, err := handlers.HandleVacuum(ctx, params)
+		require.NoError(t, err)
+		assert.Equal(t, &VacuumResponse{DeletedEvents: 3, DeletedSessions: 0}, result)
+		assert.Empty(t, pruner.deleted, "kept sessions keep their attachments")
+
+		_, err = handlers.store.GetSession(ctx, "old")
+		assert.NoError(t, err)
+	})
+
+	t.Run("delete sessions", func(t *testing.T) {
+		handlers, pruner := seed(t)
+		params, _ := json.Marshal(VacuumRequest{OlderThan: olderThan})
+		result, err := handlers.HandleVacuum(ctx, params)
+		require.NoError(t, err)
+		assert.Equal(t, &VacuumResponse{DeletedEvents: 3, DeletedSessions: 1}, result)
+		assert.Equal(t, []string{"old"}, pruner.deleted)
+
+		var notFound *store.NotFoundError
+		_, err = handlers.store.GetSession(ctx, "old")
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		handlers, _ := seed(t)
+		_, err := handlers.HandleVacuum(ctx, json.RawMessage(`{}`))
+		assert.EqualError(t, err, "invalid request: older_than is required")
+		_, err = handlers.HandleVacuum(ctx, json.RawMessage(`{"older_than":"yesterday"}`))
+		assert.ErrorContains(t, err, "invalid older_than")
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+	})
+}
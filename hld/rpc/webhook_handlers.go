@@ -0,0 +1,121 @@
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/humanlayer/humanlayer/hld/webhook"
+)
+
+// Webhook is the RPC representation of a webhook registration. The secret
+// is only returned when registering.
+type Webhook struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// RegisterWebhookRequest is the request for registering a webhook
+type RegisterWebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"` // Generated if empty
+	Events []string `json:"events"`           // Event names such as "session.completed"
+}
+
+// RegisterWebhookResponse is the response for registering a webhook
+type RegisterWebhookResponse struct {
+	Webhook Webhook `json:"webhook"`
+	Secret  string  `json:"secret"` // Key for verifying X-HumanLayer-Signature
+}
+
+// DeleteWebhookRequest is the request for removing a webhook
+type DeleteWebhookRequest struct {
+	ID string `json:"id"`
+}
+
+// DeleteWebhookResponse is the response for removing a webhook
+type DeleteWebhookResponse struct {
+	Success bool   `json:"success"`
+	ID      string `json:"id"`
+}
+
+// HandleRegisterWebhook registers a URL to be notified of session events
+func (h *SessionHandlers) HandleRegisterWebhook(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req RegisterWebhookRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.URL == "" {
+		return nil, fmt.Errorf("%w: url is required", ErrInvalidRequest)
+	}
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidRequest)
+	}
+	if len(req.Events) == 0 {
+		return nil, fmt.Errorf("%w: events is required", ErrInvalidRequest)
+	}
+	known := webhook.EventNames()
+	for _, event := range req.Events {
+		if !slices.Contains(known, event) {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidRequest, event)
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed to generate secret: %w", err)
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	registration := &store.Webhook{
+		ID:        uuid.New().String(),
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    req.Events,
+		CreatedAt: time.Now(),
+	}
+	if err := h.store.CreateWebhook(ctx, registration); err != nil {
+		return nil, fmt.Errorf("failed to register webhook: %w", err)
+	}
+
+	return &RegisterWebhookResponse{
+		Webhook: Webhook{
+			ID:        registration.ID,
+			URL:       registration.URL,
+			Events:    registration.Events,
+			CreatedAt: registration.CreatedAt.Format(time.RFC3339),
+		},
+		Secret: secret,
+	}, nil
+}
+
+// HandleDeleteWebhook removes a webhook registration
+func (h *SessionHandlers) HandleDeleteWebhook(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var req DeleteWebhookRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	if req.ID == "" {
+		return nil, fmt.Errorf("%w: id is required", ErrInvalidRequest)
+	}
+
+	if err := h.store.DeleteWebhook(ctx, req.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return &DeleteWebhookResponse{Success: true, ID: req.ID}, nil
+}
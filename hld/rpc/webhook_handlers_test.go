@@ -0,0 +1,92 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRegisterWebhook(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+	h := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	register := func(req RegisterWebhookRequest) (*RegisterWebhookResponse, error) {
+		params, _ := json.Marshal(req)
+		result, err := h.HandleRegisterWebhook(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		return result.(*RegisterWebhookResponse), nil
+	}
+
+	t.Run("with secret", func(t *testing.T) {
+		resp, err := register(RegisterWebhookRequest{
+			URL: "https://example.com/hook", Secret: "s3cret", Events: []string{"session.completed"},
+		})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.Webhook.ID)
+		assert.Equal(t, "https://example.com/hook", resp.Webhook.URL)
+		assert.Equal(t, []string{"session.completed"}, resp.Webhook.Events)
+		assert.NotEmpty(t, resp.Webhook.CreatedAt)
+		assert.Equal(t, "s3cret", resp.Secret)
+	})
+
+	t.Run("generated secret", func(t *testing.T) {
+		resp, err := register(RegisterWebhookRequest{
+			URL: "http://localhost:9000", Events: []string{"session.failed", "session.interrupted"},
+		})
+		require.NoError(t, err)
+		assert.Len(t, resp.Secret, 64)
+
+		webhooks, err := sqliteStore.ListWebhooks(ctx)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 2)
+		assert.Equal(t, resp.Secret, webhooks[1].Secret)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		for _, tc := range []struct {
+			req     RegisterWebhookRequest
+			wantErr string
+		}{
+			{RegisterWebhookRequest{Events: []string{"session.completed"}}, "invalid request: url is required"},
+			{RegisterWebhookRequest{URL: "example.com/hook", Events: []string{"session.completed"}}, "invalid request: url must be an absolute http or https URL"},
+			{RegisterWebhookRequest{URL: "ftp://example.com", Events: []string{"session.completed"}}, "invalid request: url must be an absolute http or https URL"},
+			{RegisterWebhookRequest{URL: "https://example.com"}, "invalid request: events is required"},
+			{RegisterWebhookRequest{URL: "https://example.com", Events: []string{"session.exploded"}}, `invalid request: unknown event "session.exploded"`},
+		} {
+			_, err := register(tc.req)
+			assert.EqualError(t, err, tc.wantErr)
+		}
+	})
+}
+
+func TestHandleDeleteWebhook(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+	h := NewSessionHandlers(nil, sqliteStore, nil, nil, nil)
+
+	require.NoError(t, sqliteStore.CreateWebhook(ctx, &store.Webhook{
+		ID: "wh-1", URL: "https://example.com", Secret: "s", Events: []string{"session.completed"},
+	}))
+
+	result, err := h.HandleDeleteWebhook(ctx, json.RawMessage(`{"id":"wh-1"}`))
+	require.NoError(t, err)
+	assert.Equal(t, &DeleteWebhookResponse{Success: true, ID: "wh-1"}, result)
+
+	_, err = h.HandleDeleteWebhook(ctx, json.RawMessage(`{"id":"wh-1"}`))
+	var notFound *store.NotFoundError
+	assert.ErrorAs(t, err, &notFound)
+
+	_, err = h.HandleDeleteWebhook(ctx, json.RawMessage(`{}`))
+	assert.EqualError(t, err, "invalid request: id is required")
+}
@@ -0,0 +1,83 @@
+package session
+
+// AssembledEventKind identifies the type of a provider-agnostic stream event
+type AssembledEventKind string
+
+const (
+	// AssembledUsage carries token usage for the current turn
+	AssembledUsage AssembledEventKind = "usage"
+	// AssembledSessionCreated indicates the provider created a session
+	AssembledSessionCreated AssembledEventKind = "session_created"
+	// AssembledModel reports the model serving the session
+	AssembledModel AssembledEventKind = "model"
+	// AssembledMessage is a text message from the user or assistant
+	AssembledMessage AssembledEventKind = "message"
+	// AssembledToolUse is a tool invocation requested by the assistant
+	AssembledToolUse AssembledEventKind = "tool_use"
+	// AssembledToolResult is the result of a tool invocation
+	AssembledToolResult AssembledEventKind = "tool_result"
+	// AssembledThinking is assistant reasoning content
+	AssembledThinking AssembledEventKind = "thinking"
+	// AssembledResult marks the end of a run
+	AssembledResult AssembledEventKind = "result"
+)
+
+// TokenUsage is the token accounting reported for a turn
+type TokenUsage struct {
+	InputTokens              int
+	OutputTokens             int
+	CacheCreationInputTokens int
+	CacheReadInputTokens     int
+}
+
+// RunResult describes how a run finished
+type RunResult struct {
+	IsError    bool
+	CostUSD    float64
+	DurationMS int
+	Error      string
+	Usage      *TokenUsage // Cumulative usage reported at completion, informational only
+}
+
+// AssembledEvent is a typed, provider-agnostic event produced from raw stream chunks.
+// Only the fields relevant to Kind are populated.
+type AssembledEvent struct {
+	Kind            AssembledEventKind
+	ParentToolUseID string // Set for events emitted by sub-tasks
+	MessageID       string // Provider message ID, set for usage events when known
+
+	// Message, thinking and session created fields
+	Role     string
+	Content  string
+	Subtype  string
+	Language string // Provider-declared language tag, if any
+
+	// Tool use fields
+	ToolID        string
+	ToolName      string
+	ToolInputJSON string
+
+	// Tool result fields
+	ToolResultForID   string
+	ToolResultContent string
+	ToolResultIsError bool
+	ToolResultJSON    string // The result as the provider sent it
+
+	// Model fields
+	ModelID   string // Full provider model ID
+	ModelName string // Simplified model name, empty if unrecognized
+
+	Usage  *TokenUsage
+	Result *RunResult
+}
+
+// StreamAssembler turns raw provider stream chunks into assembled events.
+// Adding a provider means implementing an assembler for its chunk type; the
+// session manager persists and publishes assembled events independently of
+// where they came from.
+type StreamAssembler[Chunk any] interface {
+	// Assemble converts a single chunk into zero or more events in stream order.
+	// On error it returns the events assembled before the failure, which the
+	// caller should still apply.
+	Assemble(chunk Chunk) ([]AssembledEvent, error)
+}
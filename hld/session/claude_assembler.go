@@ -0,0 +1,142 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
+)
+
+// ClaudeStreamAssembler assembles Claude Code stream-json events
+type ClaudeStreamAssembler struct{}
+
+// Compile-time check that ClaudeStreamAssembler implements StreamAssembler
+var _ StreamAssembler[claudecode.StreamEvent] = ClaudeStreamAssembler{}
+
+// Assemble converts a Claude stream event into assembled events
+func (ClaudeStreamAssembler) Assemble(event claudecode.StreamEvent) ([]AssembledEvent, error) {
+	var events []AssembledEvent
+
+	// Token usage from assistant messages comes first so it is recorded even
+	// before the Claude session ID is known
+	if event.Type == "assistant" && event.Message != nil && event.Message.Role == "assistant" && event.Message.Usage != nil {
+		usage := event.Message.Usage
+		events = append(events, AssembledEvent{
+			Kind:            AssembledUsage,
+			ParentToolUseID: event.ParentToolUseID,
+			MessageID:       event.Message.ID,
+			Usage: &TokenUsage{
+				InputTokens:              usage.InputTokens,
+				OutputTokens:             usage.OutputTokens,
+				CacheCreationInputTokens: usage.CacheCreationInputTokens,
+				CacheReadInputTokens:     usage.CacheReadInputTokens,
+			},
+		})
+	}
+
+	switch event.Type {
+	case "system":
+		switch event.Subtype {
+		case "session_created":
+			events = append(events, AssembledEvent{
+				Kind:            AssembledSessionCreated,
+				ParentToolUseID: event.ParentToolUseID,
+				Role:            "system",
+				Subtype:         event.Subtype,
+				Content:         fmt.Sprintf("Session created with ID: %s", event.SessionID),
+			})
+		case "init":
+			if event.Model != "" {
+				events = append(events, AssembledEvent{
+					Kind:      AssembledModel,
+					ModelID:   event.Model,
+					ModelName: claudeModelName(event.Model),
+				})
+			}
+		}
+
+	case "assistant", "user":
+		if event.Message == nil {
+			break
+		}
+		for _, content := range event.Message.Content {
+			switch content.Type {
+			case "text":
+				events = append(events, AssembledEvent{
+					Kind:            AssembledMessage,
+					ParentToolUseID: event.ParentToolUseID,
+					Role:            event.Message.Role,
+					Content:         content.Text,
+				})
+
+			case "tool_use":
+				inputJSON, err := json.Marshal(content.Input)
+				if err != nil {
+					return events, fmt.Errorf("failed to marshal tool input: %w", err)
+				}
+				events = append(events, AssembledEvent{
+					Kind:            AssembledToolUse,
+					ParentToolUseID: event.ParentToolUseID,
+					ToolID:          content.ID,
+					ToolName:        content.Name,
+					ToolInputJSON:   string(inputJSON),
+				})
+
+			case "tool_result":
+				events = append(events, AssembledEvent{
+					Kind:              AssembledToolResult,
+					ParentToolUseID:   event.ParentToolUseID,
+					Role:              "user",
+					ToolResultForID:   content.ToolUseID,
+					ToolResultContent: content.Content.Value,
+					ToolResultIsError: content.IsError,
+					ToolResultJSON:    string(content.Content.Raw),
+				})
+
+			case "thinking":
+				events = append(events, AssembledEvent{
+					Kind:            AssembledThinking,
+					ParentToolUseID: event.ParentToolUseID,
+					Role:            event.Message.Role,
+					Content:         content.Thinking,
+				})
+			}
+		}
+
+	case "result":
+		result := &RunResult{
+			IsError:    event.IsError,
+			CostUSD:    event.CostUSD,
+			DurationMS: event.DurationMS,
+			Error:      event.Error,
+		}
+		if event.Usage != nil {
+			result.Usage = &TokenUsage{
+				InputTokens:              event.Usage.InputTokens,
+				OutputTokens:             event.Usage.OutputTokens,
+				CacheCreationInputTokens: event.Usage.CacheCreationInputTokens,
+				CacheReadInputTokens:     event.Usage.CacheReadInputTokens,
+			}
+		}
+		events = append(events, AssembledEvent{
+			Kind:   AssembledResult,
+			Result: result,
+		})
+	}
+
+	return events, nil
+}
+
+// claudeModelName extracts the simple model name from an API model ID (case-insensitive)
+func claudeModelName(modelID string) string {
+	lowerModel := strings.ToLower(modelID)
+	if strings.Contains(lowerModel, "opus") {
+		return "opus"
+	} else if strings.Contains(lowerModel, "sonnet") {
+		return "sonnet"
+	} else if strings.Contains(lowerModel, "haiku") {
+		return "haiku"
+	}
+	return ""
+}
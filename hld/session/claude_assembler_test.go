@@ -0,0 +1,95 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"testing"
+
+	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadClaudeFixture(t *testing.T, path string) []claudecode.StreamEvent {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	var events []claudecode.StreamEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event claudecode.StreamEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	require.NoError(t, scanner.Err())
+	return events
+}
+
+func TestClaudeStreamAssembler(t *testing.T) {
+	chunks := loadClaudeFixture(t, "testdata/claude_stream.jsonl")
+	require.Len(t, chunks, 6)
+
+	assembler := ClaudeStreamAssembler{}
+	var got [][]AssembledEvent
+	for _, chunk := range chunks {
+		events, err := assembler.Assemble(chunk)
+		require.NoError(t, err)
+		got = append(got, events)
+	}
+
+	// init
+	assert.Equal(t, []AssembledEvent{{
+		Kind:      AssembledModel,
+		ModelID:   "claude-sonnet-4-20250514",
+		ModelName: "sonnet",
+	}}, got[0])
+
+	// session_created
+	assert.Equal(t, []AssembledEvent{{
+		Kind:    AssembledSessionCreated,
+		Role:    "system",
+		Subtype: "session_created",
+		Content: "Session created with ID: claude-abc",
+	}}, got[1])
+
+	// assistant message: usage first, then content blocks in order
+	assert.Equal(t, []AssembledEvent{
+		{Kind: AssembledUsage, MessageID: "msg_1", Usage: &TokenUsage{InputTokens: 10, OutputTokens: 20, CacheCreationInputTokens: 3, CacheReadInputTokens: 4}},
+		{Kind: AssembledThinking, Role: "assistant", Content: "Let me look at the file."},
+		{Kind: AssembledMessage, Role: "assistant", Content: "I'll read it."},
+		{Kind: AssembledToolUse, ToolID: "toolu_1", ToolName: "Read", ToolInputJSON: `{"file_path":"main.go"}`},
+	}, got[2])
+
+	// tool result with array content
+	assert.Equal(t, []AssembledEvent{{
+		Kind:              AssembledToolResult,
+		Role:              "user",
+		ToolResultForID:   "toolu_1",
+		ToolResultContent: "package main",
+		ToolResultJSON:    `[{"type":"text","text":"package main"}]`,
+	}}, got[3])
+
+	// sub-task events carry the parent tool use ID
+	require.Len(t, got[4], 2)
+	assert.Equal(t, AssembledUsage, got[4][0].Kind)
+	assert.Equal(t, "toolu_task", got[4][0].ParentToolUseID)
+	assert.Equal(t, "toolu_task", got[4][1].ParentToolUseID)
+
+	// result
+	require.Len(t, got[5], 1)
+	assert.Equal(t, AssembledResult, got[5][0].Kind)
+	assert.Equal(t, &RunResult{
+		CostUSD:    0.25,
+		DurationMS: 1500,
+		Usage:      &TokenUsage{InputTokens: 100, OutputTokens: 200, CacheReadInputTokens: 50},
+	}, got[5][0].Result)
+}
+
+func TestClaudeModelName(t *testing.T) {
+	assert.Equal(t, "opus", claudeModelName("claude-OPUS-4"))
+	assert.Equal(t, "haiku", claudeModelName("claude-3-5-haiku"))
+	assert.Equal(t, "", claudeModelName("gpt-4"))
+}
@@ -67,7 +67,7 @@ func TestContinueSessionInheritance(t *testing.T) {
 		_, _ = manager.ContinueSession(ctx, req)
 
 		// We expect it to fail at launch, but let's check the session was created with inherited config
-		sessions, err := sqliteStore.ListSessions(ctx)
+		sessions, err := sqliteStore.ListSessions(ctx, store.ListSessionsFilter{})
 		if err != nil {
 			t.Fatalf("Failed to list sessions: %v", err)
 		}
@@ -188,7 +188,7 @@ func TestContinueSessionInheritance(t *testing.T) {
 		// Expected to fail due to missing Claude binary
 
 		// Find the child session
-		sessions, err := sqliteStore.ListSessions(ctx)
+		sessions, err := sqliteStore.ListSessions(ctx, store.ListSessionsFilter{})
 		if err != nil {
 			t.Fatalf("Failed to list sessions: %v", err)
 		}
@@ -243,7 +243,7 @@ func TestContinueSessionInheritance(t *testing.T) {
 		// Expected to fail due to missing Claude binary
 
 		// Find the child session
-		sessions, err := sqliteStore.ListSessions(ctx)
+		sessions, err := sqliteStore.ListSessions(ctx, store.ListSessionsFilter{})
 		if err != nil {
 			t.Fatalf("Failed to list sessions: %v", err)
 		}
@@ -320,7 +320,7 @@ func TestContinueSessionInheritance(t *testing.T) {
 		// Expected to fail due to missing Claude binary
 
 		// Find the child session
-		sessions, err := sqliteStore.ListSessions(ctx)
+		sessions, err := sqliteStore.ListSessions(ctx, store.ListSessionsFilter{})
 		if err != nil {
 			t.Fatalf("Failed to list sessions: %v", err)
 		}
@@ -499,7 +499,7 @@ func TestContinueSessionInheritance(t *testing.T) {
 		// Expected to fail due to missing Claude binary
 
 		// Find the child session
-		sessions, err := sqliteStore.ListSessions(ctx)
+		sessions, err := sqliteStore.ListSessions(ctx, store.ListSessionsFilter{})
 		if err != nil {
 			t.Fatalf("Failed to list sessions: %v", err)
 		}
@@ -609,7 +609,7 @@ func TestContinueSessionInheritance(t *testing.T) {
 		// Expected to fail due to missing Claude binary
 
 		// Find the child session
-		sessions, err := sqliteStore.ListSessions(ctx)
+		sessions, err := sqliteStore.ListSessions(ctx, store.ListSessionsFilter{})
 		if err != nil {
 			t.Fatalf("Failed to list sessions: %v", err)
 		}
@@ -727,7 +727,7 @@ func TestContinueSessionInheritance(t *testing.T) {
 				// Expected to fail due to missing Claude binary
 
 				// Find the child session
-				sessions, err := sqliteStore.ListSessions(ctx)
+				sessions, err := sqliteStore.ListSessions(ctx, store.ListSessionsFilter{})
 				if err != nil {
 					t.Fatalf("Failed to list sessions: %v", err)
 				}
@@ -787,7 +787,7 @@ func TestContinueSessionInheritance(t *testing.T) {
 
 		// Find parent session
 		var parentSession *store.Session
-		sessions, _ := sqliteStore.ListSessions(ctx)
+		sessions, _ := sqliteStore.ListSessions(ctx, store.ListSessionsFilter{})
 		for _, s := range sessions {
 			if s.ParentSessionID == grandparentID {
 				parentSession = s
@@ -827,7 +827,7 @@ func TestContinueSessionInheritance(t *testing.T) {
 
 		// Find child session
 		var childSession *store.Session
-		sessions, _ = sqliteStore.ListSessions(ctx)
+		sessions, _ = sqliteStore.ListSessions(ctx, store.ListSessionsFilter{})
 		for _, s := range sessions {
 			if s.ParentSessionID == parentSession.ID {
 				childSession = s
@@ -895,7 +895,7 @@ func TestContinueSessionInheritance(t *testing.T) {
 		// Expected to fail due to missing Claude binary
 
 		// Find the child session
-		sessions, err := sqliteStore.ListSessions(ctx)
+		sessions, err := sqliteStore.ListSessions(ctx, store.ListSessionsFilter{})
 		if err != nil {
 			t.Fatalf("Failed to list sessions: %v", err)
 		}
@@ -0,0 +1,166 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// SessionEvictor keeps the number of stored sessions under a fixed cap by
+// deleting the least recently active terminal sessions
+type SessionEvictor struct {
+	store       store.ConversationStore
+	maxSessions int
+	archiveDir  string
+	interval    time.Duration
+	attachments AttachmentPruner
+
+	evicted atomic.Int64
+}
+
+// AttachmentPruner deletes the stored content of a session's attachments
+type AttachmentPruner interface {
+	DeleteSessionAttachments(ctx context.Context, sessionID string) error
+}
+
+// NewSessionEvictor creates a new session evictor. If archiveDir is non-empty,
+// each session and its conversation are written there as JSON before deletion.
+func NewSessionEvictor(store store.ConversationStore, maxSessions int, archiveDir string, interval time.Duration) *SessionEvictor {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &SessionEvictor{
+		store:       store,
+		maxSessions: maxSessions,
+		archiveDir:  archiveDir,
+		interval:    interval,
+	}
+}
+
+// SetAttachmentPruner makes eviction delete a session's attachment content
+// along with its rows
+func (e *SessionEvictor) SetAttachmentPruner(pruner AttachmentPruner) {
+	e.attachments = pruner
+}
+
+// Start periodically evicts sessions until ctx is cancelled
+func (e *SessionEvictor) Start(ctx context.Context) {
+	slog.Info("starting session evictor",
+		"max_sessions", e.maxSessions,
+		"archive_dir", e.archiveDir,
+		"interval", e.interval)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	// Do an initial pass immediately
+	e.EvictOnce(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("session evictor shutting down")
+			return
+		case <-ticker.C:
+			e.EvictOnce(ctx)
+		}
+	}
+}
+
+// EvictOnce runs a single eviction pass and returns the number of sessions evicted
+func (e *SessionEvictor) EvictOnce(ctx context.Context) int {
+	if e.store == nil || e.maxSessions <= 0 {
+		return 0
+	}
+
+	ids, err := e.store.GetEvictableSessionIDs(ctx, e.maxSessions)
+	if err != nil {
+		slog.Error("failed to query evictable sessions", "error", err)
+		return 0
+	}
+
+	count := 0
+	for _, id := range ids {
+		if err := e.evict(ctx, id); err != nil {
+			slog.Error("failed to evict session", "session_id", id, "error", err)
+			// Continue with other sessions
+			continue
+		}
+		count++
+	}
+
+	if count > 0 {
+		total := e.evicted.Add(int64(count))
+		slog.Info("evicted sessions over storage cap",
+			"count", count,
+			"total_evicted", total,
+			"max_sessions", e.maxSessions)
+	}
+	return count
+}
+
+// EvictedCount returns the total number of sessions evicted since start
+func (e *SessionEvictor) EvictedCount() int64 {
+	return e.evicted.Load()
+}
+
+func (e *SessionEvictor) evict(ctx context.Context, sessionID string) error {
+	if e.archiveDir != "" {
+		if err := e.archive(ctx, sessionID); err != nil {
+			// Never delete a session we failed to archive
+			return fmt.Errorf("failed to archive session: %w", err)
+		}
+	}
+	if e.attachments != nil {
+		// Keep the session if its attachments can't be deleted, so they stay
+		// referenced and the next pass retries
+		if err := e.attachments.DeleteSessionAttachments(ctx, sessionID); err != nil {
+			return err
+		}
+	}
+	return e.store.DeleteSessionData(ctx, sessionID)
+}
+
+// evictedSessionArchive is the on-disk format for archived sessions
+type evictedSessionArchive struct {
+	Session      *store.Session             `json:"session"`
+	Conversation []*store.ConversationEvent `json:"conversation"`
+	EvictedAt    time.Time                  `json:"evicted_at"`
+}
+
+func (e *SessionEvictor) archive(ctx context.Context, sessionID string) error {
+	// Soft-deleted sessions are evicted like any other
+	sess, err := e.store.GetSessionIncludingDeleted(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	events, err := e.store.GetSessionConversation(ctx, sessionID, store.ConversationPage{})
+	if err != nil {
+		return fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	data, err := json.Marshal(evictedSessionArchive{
+		Session:      sess,
+		Conversation: events,
+		EvictedAt:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal archive: %w", err)
+	}
+
+	if err := os.MkdirAll(e.archiveDir, 0700); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	path := filepath.Join(e.archiveDir, sessionID+".json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/attachment"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionEvictor(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) *store.SQLiteStore {
+		sqliteStore, err := store.NewSQLiteStore(":memory:")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sqliteStore.Close() })
+
+		base := time.Now().Add(-time.Hour)
+		sessions := []struct {
+			id     string
+			status string
+			parent string
+		}{
+			{"oldest-completed", store.SessionStatusCompleted, ""},
+			{"old-running", store.SessionStatusRunning, ""},
+			{"old-waiting", store.SessionStatusWaitingInput, ""},
+			{"old-parent", store.SessionStatusCompleted, ""},
+			{"mid-failed", store.SessionStatusFailed, ""},
+			{"child", store.SessionStatusCompleted, "old-parent"},
+			{"newest-completed", store.SessionStatusCompleted, ""},
+		}
+		for i, s := range sessions {
+			require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+				ID:              s.id,
+				RunID:           "run-" + s.id,
+				ClaudeSessionID: "claude-" + s.id,
+				ParentSessionID: s.parent,
+				Query:           "query",
+				Status:          s.status,
+				CreatedAt:       base.Add(time.Duration(i) * time.Minute),
+				LastActivityAt:  base.Add(time.Duration(i) * time.Minute),
+			}))
+		}
+		require.NoError(t, sqliteStore.AddConversationEvent(ctx, &store.ConversationEvent{
+			SessionID:       "oldest-completed",
+			ClaudeSessionID: "claude-oldest-completed",
+			EventType:       store.EventTypeMessage,
+			Role:            "user",
+			Content:         "hello",
+		}))
+		return sqliteStore
+	}
+
+	remainingIDs := func(t *testing.T, s store.ConversationStore) []string {
+		sessions, err := s.ListSessions(ctx, store.ListSessionsFilter{})
+		require.NoError(t, err)
+		var ids []string
+		for _, sess := range sessions {
+			ids = append(ids, sess.ID)
+		}
+		return ids
+	}
+
+	t.Run("evicts oldest terminal sessions over cap", func(t *testing.T) {
+		sqliteStore := setup(t)
+		evictor := NewSessionEvictor(sqliteStore, 5, "", 0)
+
+		assert.Equal(t, 2, evictor.EvictOnce(ctx))
+		assert.Equal(t, int64(2), evictor.EvictedCount())
+
+		ids := remainingIDs(t, sqliteStore)
+		assert.Len(t, ids, 5)
+		assert.NotContains(t, ids, "oldest-completed")
+		assert.NotContains(t, ids, "mid-failed")
+		// Active sessions and parents of other sessions are kept
+		assert.Contains(t, ids, "old-running")
+		assert.Contains(t, ids, "old-waiting")
+		assert.Contains(t, ids, "old-parent")
+
+		// Under the cap nothing more is evicted
+		assert.Equal(t, 0, evictor.EvictOnce(ctx))
+	})
+
+	t.Run("never evicts active sessions", func(t *testing.T) {
+		sqliteStore := setup(t)
+		evictor := NewSessionEvictor(sqliteStore, 1, "", 0)
+
+		evictor.EvictOnce(ctx)
+		ids := remainingIDs(t, sqliteStore)
+		assert.Contains(t, ids, "old-running")
+		assert.Contains(t, ids, "old-waiting")
+	})
+
+	t.Run("keeps sessions with pending approvals", func(t *testing.T) {
+		sqliteStore := setup(t)
+		require.NoError(t, sqliteStore.CreateApproval(ctx, &store.Approval{
+			ID: "appr-1", RunID: "run-oldest-completed", SessionID: "oldest-completed",
+			Status: store.ApprovalStatusLocalPending, CreatedAt: time.Now(), ToolName: "Bash", ToolInput: json.RawMessage(`{}`),
+		}))
+		evictor := NewSessionEvictor(sqliteStore, 6, "", 0)
+
+		assert.Equal(t, 1, evictor.EvictOnce(ctx))
+		ids := remainingIDs(t, sqliteStore)
+		assert.Contains(t, ids, "oldest-completed")
+		assert.NotContains(t, ids, "mid-failed")
+	})
+
+	t.Run("archives before evicting", func(t *testing.T) {
+		sqliteStore := setup(t)
+		archiveDir := filepath.Join(t.TempDir(), "archive")
+		evictor := NewSessionEvictor(sqliteStore, 6, archiveDir, 0)
+
+		assert.Equal(t, 1, evictor.EvictOnce(ctx))
+
+		data, err := os.ReadFile(filepath.Join(archiveDir, "oldest-completed.json"))
+		require.NoError(t, err)
+		var archived evictedSessionArchive
+		require.NoError(t, json.Unmarshal(data, &archived))
+		assert.Equal(t, "oldest-completed", archived.Session.ID)
+		require.Len(t, archived.Conversation, 1)
+		assert.Equal(t, "hello", archived.Conversation[0].Content)
+	})
+
+	t.Run("deletes attachment content of evicted sessions", func(t *testing.T) {
+		sqliteStore := setup(t)
+		blobs, err := attachment.NewLocalStore(t.TempDir())
+		require.NoError(t, err)
+		attachments := attachment.NewService(blobs, sqliteStore)
+		evicted, err := attachments.Add(ctx, "oldest-completed", "a.txt", "text/plain", strings.NewReader("evicted"), 7)
+		require.NoError(t, err)
+		kept, err := attachments.Add(ctx, "newest-completed", "b.txt", "text/plain", strings.NewReader("kept"), 4)
+		require.NoError(t, err)
+
+		evictor := NewSessionEvictor(sqliteStore, 6, "", 0)
+		evictor.SetAttachmentPruner(attachments)
+		assert.Equal(t, 1, evictor.EvictOnce(ctx))
+
+		_, err = blobs.Get(ctx, evicted.StorageKey)
+		assert.ErrorIs(t, err, attachment.ErrNotFound)
+		_, err = sqliteStore.GetAttachment(ctx, evicted.ID)
+		assert.Error(t, err)
+
+		_, content, err := attachments.Open(ctx, kept.ID)
+		require.NoError(t, err)
+		_ = content.Close()
+	})
+
+	t.Run("evicts soft-deleted sessions", func(t *testing.T) {
+		sqliteStore := setup(t)
+		require.NoError(t, sqliteStore.SoftDeleteSession(ctx, "oldest-completed"))
+		archiveDir := filepath.Join(t.TempDir(), "archive")
+		evictor := NewSessionEvictor(sqliteStore, 6, archiveDir, 0)
+
+		assert.Equal(t, 1, evictor.EvictOnce(ctx))
+		_, err := sqliteStore.GetSessionIncludingDeleted(ctx, "oldest-completed")
+		var notFound *store.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+
+		data, err := os.ReadFile(filepath.Join(archiveDir, "oldest-completed.json"))
+		require.NoError(t, err)
+		var archived evictedSessionArchive
+		require.NoError(t, json.Unmarshal(data, &archived))
+		assert.NotNil(t, archived.Session.DeletedAt)
+		require.Len(t, archived.Conversation, 1)
+
+		// The cap is met, so the next pass doesn't retry anything
+		assert.Equal(t, 0, evictor.EvictOnce(ctx))
+	})
+
+	t.Run("keeps session when attachments can't be deleted", func(t *testing.T) {
+		sqliteStore := setup(t)
+		evictor := NewSessionEvictor(sqliteStore, 6, "", 0)
+		evictor.SetAttachmentPruner(failingPruner{})
+
+		assert.Equal(t, 0, evictor.EvictOnce(ctx))
+		assert.Contains(t, remainingIDs(t, sqliteStore), "oldest-completed")
+	})
+}
+
+type failingPruner struct{}
+
+func (failingPruner) DeleteSessionAttachments(ctx context.Context, sessionID string) error {
+	return errors.New("object store unavailable")
+}
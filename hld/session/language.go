@@ -0,0 +1,17 @@
+package session
+
+// LanguageDetector identifies the language of message text for sessions whose
+// provider doesn't declare one. Implementations return a language tag such as
+// "es", or "" when unsure.
+type LanguageDetector interface {
+	DetectLanguage(text string) string
+}
+
+// eventLanguage returns the provider-declared language for an event, falling
+// back to the detector when one is configured
+func eventLanguage(detector LanguageDetector, declared, text string) string {
+	if declared != "" || detector == nil || text == "" {
+		return declared
+	}
+	return detector.DetectLanguage(text)
+}
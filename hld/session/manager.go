@@ -3,6 +3,7 @@ package session
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -15,6 +16,7 @@ import (
 	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
 	"github.com/humanlayer/humanlayer/hld/bus"
 	hldconfig "github.com/humanlayer/humanlayer/hld/config"
+	"github.com/humanlayer/humanlayer/hld/feature"
 	"github.com/humanlayer/humanlayer/hld/store"
 )
 
@@ -30,8 +32,32 @@ type Manager struct {
 	store              store.ConversationStore
 	approvalReconciler ApprovalReconciler
 	pendingQueries     sync.Map // map[sessionID]query - stores queries waiting for Claude session ID
+	turnStarts         sync.Map // map[sessionID]time.Time - when the current model turn's input was sent
+	launchSlots        sync.Map // map[sessionID]func() - releases the session's scheduler slot
 	socketPath         string   // Daemon socket path for MCP servers
 	httpPort           int      // HTTP server port for proxy endpoint
+
+	// assembler parses raw stream events; nil defaults to ClaudeStreamAssembler
+	assembler StreamAssembler[claudecode.StreamEvent]
+
+	// scheduler caps concurrently running sessions; nil means no cap
+	scheduler *LaunchScheduler
+
+	// languageDetector tags messages whose provider doesn't declare a language
+	languageDetector LanguageDetector
+
+	// overload coordinates backoff when the provider reports overload; nil
+	// disables overload handling
+	overload *OverloadMonitor
+
+	// toolCache answers repeated calls to cacheable tools; nil disables caching
+	toolCache *ToolResultCache
+	// cachedToolUses tracks tool_use_ids answered from toolCache so their
+	// results are flagged as cache hits
+	cachedToolUses sync.Map // map[string]struct{}
+
+	// features gates optional launch behaviors; nil uses the flag defaults
+	features *feature.Flags
 }
 
 // Compile-time check that Manager implements SessionManager
@@ -96,6 +122,177 @@ func (m *Manager) SetApprovalReconciler(reconciler ApprovalReconciler) {
 	m.approvalReconciler = reconciler
 }
 
+// SetLaunchScheduler sets the scheduler used to cap concurrently running sessions
+func (m *Manager) SetLaunchScheduler(scheduler *LaunchScheduler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scheduler = scheduler
+}
+
+// SetOverloadMonitor sets the monitor notified when sessions fail due to
+// provider overload
+func (m *Manager) SetOverloadMonitor(monitor *OverloadMonitor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.overload = monitor
+}
+
+// noteProviderError reports a session failure caused by provider overload
+func (m *Manager) noteProviderError(sessionID, message string) {
+	m.mu.RLock()
+	monitor := m.overload
+	m.mu.RUnlock()
+	if monitor == nil || !IsOverloadError(message) {
+		return
+	}
+	backoff := monitor.ReportOverload(0)
+	slog.Warn("session failed due to provider overload",
+		"session_id", sessionID,
+		"backoff", backoff)
+}
+
+// SetFeatureFlags sets the flags gating optional launch behaviors
+func (m *Manager) SetFeatureFlags(flags *feature.Flags) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.features = flags
+}
+
+// featureEnabled reports whether a flag is on for owner
+func (m *Manager) featureEnabled(name, owner string) bool {
+	if owner == "" {
+		owner = DefaultOwner
+	}
+	m.mu.RLock()
+	flags := m.features
+	m.mu.RUnlock()
+	return flags.Enabled(name, owner)
+}
+
+// SetToolResultCache sets the cache used to answer repeated cacheable tool calls
+func (m *Manager) SetToolResultCache(cache *ToolResultCache) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toolCache = cache
+}
+
+// CachedToolResult returns a cached result for a tool call that is about to
+// run, unless the tool isn't cacheable or the session bypasses the cache. A
+// hit is remembered so the result Claude reports for the call is flagged.
+func (m *Manager) CachedToolResult(ctx context.Context, sessionID, toolUseID, toolName string, input json.RawMessage) (string, bool) {
+	m.mu.RLock()
+	cache := m.toolCache
+	m.mu.RUnlock()
+	if cache == nil || !cache.Cacheable(toolName) {
+		return "", false
+	}
+
+	sess, err := m.store.GetSession(ctx, sessionID)
+	if err != nil || sess.BypassToolCache {
+		return "", false
+	}
+
+	result, ok := cache.Lookup(toolName, string(input))
+	if ok {
+		m.cachedToolUses.Store(toolUseID, struct{}{})
+		slog.Debug("serving tool call from cache",
+			"session_id", sessionID,
+			"tool_use_id", toolUseID,
+			"tool_name", toolName)
+	}
+	return result, ok
+}
+
+// SetLanguageDetector sets the hook used to tag message events with a language
+func (m *Manager) SetLanguageDetector(detector LanguageDetector) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.languageDetector = detector
+}
+
+// errLaunchCancelled is returned by acquireLaunchSlot when the session was
+// cancelled or deleted while it was queued
+var errLaunchCancelled = errors.New("session was cancelled while queued")
+
+// acquireLaunchSlot blocks until the session may start a Claude process and
+// returns when it started waiting. A session that has to wait is queued until
+// it gets a slot. It doesn't wait when no scheduler is configured.
+func (m *Manager) acquireLaunchSlot(ctx context.Context, sessionID, runID, owner string, priority int) (time.Time, error) {
+	queuedAt := time.Now()
+	m.mu.RLock()
+	scheduler := m.scheduler
+	m.mu.RUnlock()
+	if scheduler == nil {
+		return queuedAt, nil
+	}
+
+	// A session that can't be marked queued stops waiting and fails
+	waitCtx, stopWaiting := context.WithCancel(ctx)
+	defer stopWaiting()
+	queued := false
+	var queueErr error
+	release, err := scheduler.AcquireNotify(waitCtx, owner, priority, func() {
+		queued = true
+		if queueErr = m.setLaunchStatus(ctx, sessionID, runID, StatusStarting, StatusQueued); queueErr != nil {
+			stopWaiting()
+		}
+	})
+	if queueErr != nil {
+		if err == nil {
+			release()
+		}
+		return queuedAt, queueErr
+	}
+	if err != nil {
+		return queuedAt, fmt.Errorf("failed to acquire launch slot: %w", err)
+	}
+	if queued {
+		// The session may have been cancelled or deleted while it waited
+		session, err := m.store.GetSession(ctx, sessionID)
+		if errors.Is(err, store.ErrNotFound) || (err == nil && session.Status == store.SessionStatusCancelled) {
+			release()
+			return queuedAt, errLaunchCancelled
+		}
+		if err != nil {
+			release()
+			return queuedAt, fmt.Errorf("failed to check queued session: %w", err)
+		}
+		if err := m.setLaunchStatus(ctx, sessionID, runID, StatusQueued, StatusStarting); err != nil {
+			release()
+			return queuedAt, err
+		}
+	}
+	m.launchSlots.Store(sessionID, release)
+	return queuedAt, nil
+}
+
+// setLaunchStatus moves a launching session between queued and starting
+func (m *Manager) setLaunchStatus(ctx context.Context, sessionID, runID string, oldStatus, newStatus Status) error {
+	status := string(newStatus)
+	if err := m.store.UpdateSession(ctx, sessionID, store.SessionUpdate{Status: &status}); err != nil {
+		return fmt.Errorf("failed to set launch status to %s: %w", status, err)
+	}
+	if m.eventBus != nil {
+		m.eventBus.Publish(bus.Event{
+			Type: bus.EventSessionStatusChanged,
+			Data: map[string]interface{}{
+				"session_id": sessionID,
+				"run_id":     runID,
+				"old_status": string(oldStatus),
+				"new_status": string(newStatus),
+			},
+		})
+	}
+	return nil
+}
+
+// releaseLaunchSlot frees the session's scheduler slot, if it holds one
+func (m *Manager) releaseLaunchSlot(sessionID string) {
+	if release, ok := m.launchSlots.LoadAndDelete(sessionID); ok {
+		release.(func())()
+	}
+}
+
 // SetHTTPPort sets the HTTP port for the proxy endpoint
 func (m *Manager) SetHTTPPort(port int) {
 	m.mu.Lock()
@@ -185,6 +382,15 @@ func (m *Manager) LaunchSession(ctx context.Context, config LaunchSessionConfig,
 	if err != nil {
 		return nil, fmt.Errorf("cannot launch session: %w", err)
 	}
+
+	// Catch allowed tools the session couldn't use before creating it. Drafts
+	// may still be edited, so they aren't checked.
+	if !isDraft && m.featureEnabled(feature.ToolAvailabilityCheck, config.Owner) {
+		if err := CheckToolAvailability(config.AllowedTools, config.MCPConfig); err != nil {
+			return nil, err
+		}
+	}
+
 	// Generate unique IDs
 	sessionID := uuid.New().String()
 	runID := uuid.New().String()
@@ -346,6 +552,23 @@ func (m *Manager) LaunchSession(ctx context.Context, config LaunchSessionConfig,
 	// Handle auto-accept edits from config
 	dbSession.AutoAcceptEdits = config.AutoAcceptEdits
 
+	dbSession.Owner = config.Owner
+	if dbSession.Owner == "" {
+		dbSession.Owner = DefaultOwner
+	}
+	dbSession.BypassToolCache = config.BypassToolCache
+	dbSession.Priority = config.Priority
+	if config.ToolQuota != nil {
+		if err := config.ToolQuota.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid tool quota: %w", err)
+		}
+		toolQuota, err := encodeToolQuota(config.ToolQuota)
+		if err != nil {
+			return nil, err
+		}
+		dbSession.ToolQuota = toolQuota
+	}
+
 	// Handle dangerously skip permissions from config
 	if config.DangerouslySkipPermissions {
 		dbSession.DangerouslySkipPermissions = true
@@ -450,9 +673,19 @@ func (m *Manager) LaunchSession(ctx context.Context, config LaunchSessionConfig,
 		"mcp_servers", mcpServerCount,
 		"mcp_servers_detail", mcpServersDetail)
 
+	// Wait for a slot if a concurrency cap is configured
+	queuedAt, err := m.acquireLaunchSlot(ctx, sessionID, runID, dbSession.Owner, dbSession.Priority)
+	if err != nil {
+		if !errors.Is(err, errLaunchCancelled) {
+			m.updateSessionStatus(ctx, sessionID, StatusFailed, err.Error())
+		}
+		return nil, err
+	}
+
 	// Launch Claude session (without daemon-level settings)
 	claudeSession, err := client.Launch(claudeConfig)
 	if err != nil {
+		m.releaseLaunchSlot(sessionID)
 		slog.Error("failed to launch Claude session",
 			"session_id", sessionID,
 			"error", err,
@@ -475,6 +708,8 @@ func (m *Manager) LaunchSession(ctx context.Context, config LaunchSessionConfig,
 	update := store.SessionUpdate{
 		Status:         &statusRunning,
 		LastActivityAt: &now,
+		QueuedAt:       &queuedAt,
+		StartedAt:      &now,
 	}
 	if err := m.store.UpdateSession(ctx, sessionID, update); err != nil {
 		slog.Error("failed to update session status to running", "error", err)
@@ -548,6 +783,17 @@ func (m *Manager) LaunchSession(ctx context.Context, config LaunchSessionConfig,
 func (m *Manager) monitorSession(ctx context.Context, sessionID, runID string, claudeSession ClaudeSession, startTime time.Time, config claudecode.SessionConfig) {
 	// Get the session ID from the Claude session once available
 	var claudeSessionID string
+	// A run that ends without a result event was cut off mid-turn
+	sawResult := false
+	// First-token latency runs to the first assistant output
+	sawAssistant := false
+	// Set once the store refuses events because the session spent its budget
+	overBudget := false
+
+	// The first turn starts when the process is launched
+	m.turnStarts.Store(sessionID, startTime)
+	defer m.turnStarts.Delete(sessionID)
+	defer m.releaseLaunchSlot(sessionID)
 
 eventLoop:
 	for {
@@ -625,9 +871,25 @@ eventLoop:
 				}
 			}
 
+			if event.Type == "result" {
+				sawResult = true
+			}
+			if event.Type == "assistant" && !sawAssistant {
+				sawAssistant = true
+				firstEventAt := time.Now()
+				if err := m.store.UpdateSession(ctx, sessionID, store.SessionUpdate{FirstEventAt: &firstEventAt}); err != nil {
+					slog.Error("failed to record first event time", "session_id", sessionID, "error", err)
+				}
+			}
+
 			// Process and store event
 			if err := m.processStreamEvent(ctx, sessionID, claudeSessionID, event); err != nil {
-				slog.Error("failed to process stream event", "error", err)
+				if !errors.Is(err, store.ErrBudgetExceeded) {
+					slog.Error("failed to process stream event", "error", err)
+				} else if !overBudget {
+					overBudget = true
+					m.stopOverBudgetSession(ctx, sessionID, runID, claudeSession)
+				}
 			}
 		}
 	}
@@ -644,9 +906,28 @@ eventLoop:
 
 	endTime := time.Now()
 
-	// First check if this was an intentional interrupt (regardless of error)
+	// First check if this was an intentional interrupt or cancellation (regardless of error)
 	session, dbErr := m.store.GetSession(ctx, sessionID)
-	if dbErr == nil && session != nil && session.Status == string(StatusInterrupting) {
+	interrupted := dbErr == nil && session != nil && session.Status == string(StatusInterrupting)
+	cancelled := dbErr == nil && session != nil && session.Status == string(StatusCancelled)
+
+	// Interrupted and cancelled runs are expected to stop short. Otherwise a
+	// run without a result event was cut off, and its last assistant output
+	// may be partial.
+	if !interrupted && !cancelled && !sawResult && claudeSessionID != "" {
+		m.markTruncatedTurn(ctx, sessionID, claudeSessionID)
+	}
+
+	if overBudget {
+		// stopOverBudgetSession already recorded the failure; the interrupt
+		// isn't a separate one
+		slog.Debug("session exceeded its budget, keeping failed status",
+			"session_id", sessionID)
+	} else if cancelled {
+		// CancelSession already recorded the final status; the kill isn't a failure
+		slog.Debug("session was cancelled, keeping cancelled status",
+			"session_id", sessionID)
+	} else if interrupted {
 		// This was an interrupted session, mark as interrupted (not failed or completed)
 		slog.Debug("session was interrupted, marking as interrupted",
 			"session_id", sessionID,
@@ -678,12 +959,14 @@ eventLoop:
 			"error", err.Error(),
 			"duration", endTime.Sub(startTime))
 		m.updateSessionStatus(ctx, sessionID, StatusFailed, err.Error())
+		m.noteProviderError(sessionID, err.Error())
 	} else if result != nil && result.IsError {
 		slog.Error("claude process failed with error result",
 			"session_id", sessionID,
 			"error", result.Error,
 			"duration", endTime.Sub(startTime))
 		m.updateSessionStatus(ctx, sessionID, StatusFailed, result.Error)
+		m.noteProviderError(sessionID, result.Error+" "+result.Result)
 	} else {
 		// No longer updating in-memory session
 
@@ -733,7 +1016,7 @@ eventLoop:
 
 	// Determine final status for logging
 	finalStatus := StatusCompleted
-	if err != nil || (result != nil && result.IsError) {
+	if overBudget || err != nil || (result != nil && result.IsError) {
 		finalStatus = StatusFailed
 	} else if dbErr == nil && session != nil && session.Status == string(StatusInterrupting) {
 		finalStatus = StatusInterrupted
@@ -756,6 +1039,99 @@ eventLoop:
 	m.pendingQueries.Delete(sessionID)
 }
 
+// stopOverBudgetSession fails a session whose events the store refused
+// because it has spent its cost budget, and interrupts its process so it stops
+// spending. Events produced while the process winds down are dropped.
+func (m *Manager) stopOverBudgetSession(ctx context.Context, sessionID, runID string, claudeSession ClaudeSession) {
+	message := store.ErrBudgetExceeded.Error()
+	oldStatus := string(StatusRunning)
+	var costUSD, maxCostUSD float64
+	if sess, err := m.store.GetSession(ctx, sessionID); err == nil {
+		oldStatus = sess.Status
+		if sess.CostUSD != nil && sess.MaxCostUSD != nil {
+			costUSD, maxCostUSD = *sess.CostUSD, *sess.MaxCostUSD
+			message = fmt.Sprintf("%s: spent $%.2f of its $%.2f budget", message, costUSD, maxCostUSD)
+		}
+	}
+	slog.Warn("session exceeded its cost budget, stopping",
+		"session_id", sessionID,
+		"cost_usd", costUSD,
+		"max_cost_usd", maxCostUSD)
+
+	// Record the failure before interrupting, so the monitor keeps it rather
+	// than reporting the interrupted process
+	m.updateSessionStatus(ctx, sessionID, StatusFailed, message)
+	if err := claudeSession.Interrupt(); err != nil {
+		slog.Error("failed to interrupt session over budget, killing it",
+			"session_id", sessionID,
+			"error", err)
+		if err := claudeSession.Kill(); err != nil {
+			slog.Error("failed to kill session over budget", "session_id", sessionID, "error", err)
+		}
+	}
+
+	if m.eventBus != nil {
+		m.eventBus.Publish(bus.Event{
+			Type: bus.EventSessionBudgetExceeded,
+			Data: map[string]interface{}{
+				"session_id":   sessionID,
+				"run_id":       runID,
+				"cost_usd":     costUSD,
+				"max_cost_usd": maxCostUSD,
+				"message":      message,
+			},
+		})
+		m.eventBus.Publish(bus.Event{
+			Type: bus.EventSessionStatusChanged,
+			Data: map[string]interface{}{
+				"session_id": sessionID,
+				"run_id":     runID,
+				"old_status": oldStatus,
+				"new_status": string(StatusFailed),
+			},
+		})
+	}
+}
+
+// markTruncatedTurn flags the session's latest assistant output as truncated
+// after its stream ended without a result. Only the final event can have been
+// cut off; everything before it was followed by more output.
+func (m *Manager) markTruncatedTurn(ctx context.Context, sessionID, claudeSessionID string) {
+	events, err := m.store.GetConversation(ctx, claudeSessionID, store.ConversationPage{})
+	if err != nil {
+		slog.Error("failed to check for truncated turn", "session_id", sessionID, "error", err)
+		return
+	}
+	if len(events) == 0 {
+		return
+	}
+	last := events[len(events)-1]
+	if last.Role != "assistant" || (last.EventType != store.EventTypeMessage && last.EventType != store.EventTypeThinking) {
+		return
+	}
+
+	if err := m.store.MarkEventTruncated(ctx, last.ID); err != nil {
+		slog.Error("failed to mark event truncated", "session_id", sessionID, "event_id", last.ID, "error", err)
+		return
+	}
+	slog.Warn("provider stream ended without a result, marked last message truncated",
+		"session_id", sessionID,
+		"event_id", last.ID)
+
+	if m.eventBus != nil {
+		m.eventBus.Publish(bus.Event{
+			Type: bus.EventConversationUpdated,
+			Data: map[string]interface{}{
+				"session_id":        sessionID,
+				"claude_session_id": claudeSessionID,
+				"event_id":          last.ID,
+				"event_type":        last.EventType,
+				"truncated":         true,
+			},
+		})
+	}
+}
+
 // updateSessionStatus updates the status of a session in the database
 func (m *Manager) updateSessionStatus(ctx context.Context, sessionID string, status Status, errorMsg string) {
 	// Update database
@@ -811,6 +1187,7 @@ func (m *Manager) GetSessionInfo(sessionID string) (*Info, error) {
 		WorkingDir:                          dbSession.WorkingDir,
 		AutoAcceptEdits:                     dbSession.AutoAcceptEdits,
 		Archived:                            dbSession.Archived,
+		Imported:                            dbSession.Imported,
 		EditorState:                         dbSession.EditorState,
 		DangerouslySkipPermissions:          dbSession.DangerouslySkipPermissions,
 		DangerouslySkipPermissionsExpiresAt: dbSession.DangerouslySkipPermissionsExpiresAt,
@@ -856,7 +1233,7 @@ func (m *Manager) GetSessionInfo(sessionID string) (*Info, error) {
 // ListSessions returns all sessions from the database
 func (m *Manager) ListSessions() []Info {
 	ctx := context.Background()
-	dbSessions, err := m.store.ListSessions(ctx)
+	dbSessions, err := m.store.ListSessions(ctx, store.ListSessionsFilter{})
 	if err != nil {
 		slog.Error("failed to list sessions from database", "error", err)
 		return []Info{}
@@ -882,6 +1259,7 @@ func (m *Manager) ListSessions() []Info {
 			WorkingDir:                          dbSession.WorkingDir,
 			AutoAcceptEdits:                     dbSession.AutoAcceptEdits,
 			Archived:                            dbSession.Archived,
+			Imported:                            dbSession.Imported,
 			DangerouslySkipPermissions:          dbSession.DangerouslySkipPermissions,
 			DangerouslySkipPermissionsExpiresAt: dbSession.DangerouslySkipPermissionsExpiresAt,
 			EditorState:                         dbSession.EditorState,
@@ -951,64 +1329,82 @@ func (m *Manager) processStreamEvent(ctx context.Context, sessionID string, clau
 			"raw_event_json", string(eventJSON))
 	}
 
-	// Process token updates from assistant messages even without claudeSessionID
-	if event.Type == "assistant" && event.Message != nil && event.Message.Role == "assistant" && event.Message.Usage != nil {
+	assembler := m.assembler
+	if assembler == nil {
+		assembler = ClaudeStreamAssembler{}
+	}
+
+	events, assembleErr := assembler.Assemble(event)
+	for _, ev := range events {
+		if err := m.applyAssembledEvent(ctx, sessionID, claudeSessionID, ev); err != nil {
+			return err
+		}
+	}
+	return assembleErr
+}
+
+// applyAssembledEvent persists a single assembled event and notifies subscribers
+func (m *Manager) applyAssembledEvent(ctx context.Context, sessionID string, claudeSessionID string, ev AssembledEvent) error {
+	// Process token updates even without claudeSessionID
+	if ev.Kind == AssembledUsage {
 		// QUICK FIX: Skip token updates for subagent events
 		// Subagents have parent_tool_use_id set at the event level
-		if event.ParentToolUseID != "" {
+		if ev.ParentToolUseID != "" {
 			slog.Debug("skipping token update for subagent event",
 				"session_id", sessionID,
-				"parent_tool_use_id", event.ParentToolUseID)
-			// Continue processing the rest of the event, just skip token updates
+				"parent_tool_use_id", ev.ParentToolUseID)
+			return nil
+		}
+
+		usage := ev.Usage
+		m.recordTurnUsage(ctx, sessionID, ev.MessageID, usage.OutputTokens)
+
+		// Compute effective context tokens (what's actually in the context window)
+		// This includes ALL tokens that count toward the context limit
+		effective := usage.InputTokens + usage.OutputTokens + usage.CacheReadInputTokens + usage.CacheCreationInputTokens
+
+		now := time.Now()
+		update := store.SessionUpdate{
+			InputTokens:              &usage.InputTokens,
+			OutputTokens:             &usage.OutputTokens,
+			CacheCreationInputTokens: &usage.CacheCreationInputTokens,
+			CacheReadInputTokens:     &usage.CacheReadInputTokens,
+			EffectiveContextTokens:   &effective,
+			LastActivityAt:           &now,
+		}
+
+		if err := m.store.UpdateSession(ctx, sessionID, update); err != nil {
+			slog.Error("failed to update token usage",
+				"session_id", sessionID,
+				"error", err)
 		} else {
-			// Original token update logic for root-level events
-			usage := event.Message.Usage
-			// Compute effective context tokens (what's actually in the context window)
-			// This includes ALL tokens that count toward the context limit
-			effective := usage.InputTokens + usage.OutputTokens + usage.CacheReadInputTokens + usage.CacheCreationInputTokens
-
-			now := time.Now()
-			update := store.SessionUpdate{
-				InputTokens:              &usage.InputTokens,
-				OutputTokens:             &usage.OutputTokens,
-				CacheCreationInputTokens: &usage.CacheCreationInputTokens,
-				CacheReadInputTokens:     &usage.CacheReadInputTokens,
-				EffectiveContextTokens:   &effective,
-				LastActivityAt:           &now,
-			}
+			// Publish event to notify UI about token update
+			// The UI needs "new_status" field even though we're not changing status
+			if m.eventBus != nil {
+				// Get current session to include current status
+				session, _ := m.store.GetSession(ctx, sessionID)
+				currentStatus := "running"
+				if session != nil && session.Status != "" {
+					currentStatus = session.Status
+				}
 
-			if err := m.store.UpdateSession(ctx, sessionID, update); err != nil {
-				slog.Error("failed to update token usage",
+				slog.Debug("Publishing token update event",
 					"session_id", sessionID,
-					"error", err)
-			} else {
-				// Publish event to notify UI about token update
-				// The UI needs "new_status" field even though we're not changing status
-				if m.eventBus != nil {
-					// Get current session to include current status
-					session, _ := m.store.GetSession(ctx, sessionID)
-					currentStatus := "running"
-					if session != nil && session.Status != "" {
-						currentStatus = session.Status
-					}
+					"status", currentStatus,
+					"effective_tokens", effective)
 
-					slog.Debug("Publishing token update event",
-						"session_id", sessionID,
-						"status", currentStatus,
-						"effective_tokens", effective)
-
-					m.eventBus.Publish(bus.Event{
-						Type: bus.EventSessionStatusChanged,
-						Data: map[string]interface{}{
-							"session_id": sessionID,
-							"new_status": currentStatus, // Required by UI handler
-							"old_status": currentStatus, // Status isn't changing, just tokens
-							"reason":     "token_update",
-						},
-					})
-				}
+				m.eventBus.Publish(bus.Event{
+					Type: bus.EventSessionStatusChanged,
+					Data: map[string]interface{}{
+						"session_id": sessionID,
+						"new_status": currentStatus, // Required by UI handler
+						"old_status": currentStatus, // Status isn't changing, just tokens
+						"reason":     "token_update",
+					},
+				})
 			}
 		}
+		return nil
 	}
 
 	// Skip remaining event processing without claude session ID
@@ -1016,277 +1412,285 @@ func (m *Manager) processStreamEvent(ctx context.Context, sessionID string, clau
 		return nil
 	}
 
-	switch event.Type {
-	case "system":
-		// System events (session created, tools available, etc)
-		switch event.Subtype {
-		case "session_created":
-			// Store system event
-			convEvent := &store.ConversationEvent{
-				SessionID:       sessionID,
-				ClaudeSessionID: claudeSessionID,
-				EventType:       store.EventTypeSystem,
-				Role:            "system",
-				Content:         fmt.Sprintf("Session created with ID: %s", event.SessionID),
-				ParentToolUseID: event.ParentToolUseID,
-			}
-			if err := m.store.AddConversationEvent(ctx, convEvent); err != nil {
-				return err
-			}
+	switch ev.Kind {
+	case AssembledSessionCreated:
+		// Store system event
+		convEvent := &store.ConversationEvent{
+			SessionID:       sessionID,
+			ClaudeSessionID: claudeSessionID,
+			EventType:       store.EventTypeSystem,
+			Role:            ev.Role,
+			Content:         ev.Content,
+			ParentToolUseID: ev.ParentToolUseID,
+		}
+		if err := m.store.AddConversationEvent(ctx, convEvent); err != nil {
+			return err
+		}
 
-			// Publish conversation updated event
-			if m.eventBus != nil {
-				m.eventBus.Publish(bus.Event{
-					Type: bus.EventConversationUpdated,
-					Data: map[string]interface{}{
-						"session_id":         sessionID,
-						"claude_session_id":  claudeSessionID,
-						"event_type":         "system",
-						"subtype":            event.Subtype,
-						"content":            fmt.Sprintf("Session created with ID: %s", event.SessionID),
-						"content_type":       "system",
-						"parent_tool_use_id": event.ParentToolUseID,
-					},
-				})
-			}
-		case "init":
-			// Check if we need to populate the model
-			session, err := m.store.GetSession(ctx, sessionID)
-			if err != nil {
-				slog.Error("failed to get session for model update", "error", err)
-				return nil // Non-fatal, continue processing
-			}
+		// Publish conversation updated event
+		if m.eventBus != nil {
+			m.eventBus.Publish(bus.Event{
+				Type: bus.EventConversationUpdated,
+				Data: map[string]interface{}{
+					"session_id":         sessionID,
+					"claude_session_id":  claudeSessionID,
+					"content_hash":       convEvent.Digest(),
+					"event_type":         "system",
+					"subtype":            ev.Subtype,
+					"content":            ev.Content,
+					"content_type":       "system",
+					"parent_tool_use_id": ev.ParentToolUseID,
+				},
+			})
+		}
 
-			// Only update if model is empty and init event has a model
-			if session != nil && session.Model == "" && event.Model != "" {
-				// Store the full model ID
-				modelID := event.Model
-
-				// Extract simple model name from API format (case-insensitive)
-				var modelName string
-				lowerModel := strings.ToLower(event.Model)
-				if strings.Contains(lowerModel, "opus") {
-					modelName = "opus"
-				} else if strings.Contains(lowerModel, "sonnet") {
-					modelName = "sonnet"
-				} else if strings.Contains(lowerModel, "haiku") {
-					modelName = "haiku"
-				}
+	case AssembledModel:
+		// Check if we need to populate the model
+		session, err := m.store.GetSession(ctx, sessionID)
+		if err != nil {
+			slog.Error("failed to get session for model update", "error", err)
+			return nil // Non-fatal, continue processing
+		}
 
-				// Update session with both model ID and simplified name
-				if modelName != "" {
-					update := store.SessionUpdate{
-						Model:   &modelName,
-						ModelID: &modelID,
-					}
-					if err := m.store.UpdateSession(ctx, sessionID, update); err != nil {
-						slog.Error("failed to update session model from init event",
-							"session_id", sessionID,
-							"model", modelName,
-							"model_id", modelID,
-							"error", err)
-					} else {
-						slog.Info("populated session model from init event",
-							"session_id", sessionID,
-							"model", modelName,
-							"model_id", modelID)
-					}
+		// Only update if model is empty
+		if session != nil && session.Model == "" {
+			// Store the full model ID
+			modelID := ev.ModelID
+			modelName := ev.ModelName
+
+			// Update session with both model ID and simplified name
+			if modelName != "" {
+				update := store.SessionUpdate{
+					Model:   &modelName,
+					ModelID: &modelID,
+				}
+				if err := m.store.UpdateSession(ctx, sessionID, update); err != nil {
+					slog.Error("failed to update session model from init event",
+						"session_id", sessionID,
+						"model", modelName,
+						"model_id", modelID,
+						"error", err)
 				} else {
-					// Still store the model ID even if we don't recognize the format
-					update := store.SessionUpdate{
-						ModelID: &modelID,
-					}
-					if err := m.store.UpdateSession(ctx, sessionID, update); err != nil {
-						slog.Error("failed to update session model_id from init event",
-							"session_id", sessionID,
-							"model_id", modelID,
-							"error", err)
-					} else {
-						slog.Debug("stored unrecognized model format in init event",
-							"session_id", sessionID,
-							"model_id", modelID)
-					}
+					slog.Info("populated session model from init event",
+						"session_id", sessionID,
+						"model", modelName,
+						"model_id", modelID)
+				}
+			} else {
+				// Still store the model ID even if we don't recognize the format
+				update := store.SessionUpdate{
+					ModelID: &modelID,
+				}
+				if err := m.store.UpdateSession(ctx, sessionID, update); err != nil {
+					slog.Error("failed to update session model_id from init event",
+						"session_id", sessionID,
+						"model_id", modelID,
+						"error", err)
+				} else {
+					slog.Debug("stored unrecognized model format in init event",
+						"session_id", sessionID,
+						"model_id", modelID)
 				}
 			}
-			// Don't store init event in conversation history - we only extract the model
-		}
-		// Other system events can be added as needed
-
-	case "assistant", "user":
-		// Messages contain the actual content
-		if event.Message != nil {
-			// Token usage is already processed at the top of this function
-			// Process each content block
-			for _, content := range event.Message.Content {
-				switch content.Type {
-				case "text":
-					// Text message
-					convEvent := &store.ConversationEvent{
-						SessionID:       sessionID,
-						ClaudeSessionID: claudeSessionID,
-						EventType:       store.EventTypeMessage,
-						Role:            event.Message.Role,
-						Content:         content.Text,
-						ParentToolUseID: event.ParentToolUseID,
-					}
-					if err := m.store.AddConversationEvent(ctx, convEvent); err != nil {
-						return err
-					}
+		}
+		// Don't store init event in conversation history - we only extract the model
 
-					// Update session activity timestamp for text messages
-					m.updateSessionActivity(ctx, sessionID)
-
-					// Publish conversation updated event
-					if m.eventBus != nil {
-						m.eventBus.Publish(bus.Event{
-							Type: bus.EventConversationUpdated,
-							Data: map[string]interface{}{
-								"session_id":         sessionID,
-								"claude_session_id":  claudeSessionID,
-								"event_type":         "message",
-								"role":               event.Message.Role,
-								"content":            content.Text,
-								"content_type":       "text",
-								"parent_tool_use_id": event.ParentToolUseID,
-							},
-						})
-					}
+	case AssembledMessage:
+		m.mu.RLock()
+		detector := m.languageDetector
+		m.mu.RUnlock()
 
-				case "tool_use":
-					// Tool call
-					inputJSON, err := json.Marshal(content.Input)
-					if err != nil {
-						return fmt.Errorf("failed to marshal tool input: %w", err)
-					}
+		// Text message
+		convEvent := &store.ConversationEvent{
+			SessionID:       sessionID,
+			ClaudeSessionID: claudeSessionID,
+			EventType:       store.EventTypeMessage,
+			Role:            ev.Role,
+			Content:         ev.Content,
+			ParentToolUseID: ev.ParentToolUseID,
+			Language:        eventLanguage(detector, ev.Language, ev.Content),
+		}
+		if err := m.store.AddConversationEvent(ctx, convEvent); err != nil {
+			return err
+		}
 
-					convEvent := &store.ConversationEvent{
-						SessionID:       sessionID,
-						ClaudeSessionID: claudeSessionID,
-						EventType:       store.EventTypeToolCall,
-						ToolID:          content.ID,
-						ToolName:        content.Name,
-						ToolInputJSON:   string(inputJSON),
-						ParentToolUseID: event.ParentToolUseID, // Capture from event level
-						// We don't know yet if this needs approval - that comes from HumanLayer API
-					}
-					if err := m.store.AddConversationEvent(ctx, convEvent); err != nil {
-						return err
-					}
+		// Update session activity timestamp for text messages
+		m.updateSessionActivity(ctx, sessionID)
 
-					// Update session activity timestamp for tool calls
-					m.updateSessionActivity(ctx, sessionID)
+		// Publish conversation updated event
+		if m.eventBus != nil {
+			m.eventBus.Publish(bus.Event{
+				Type: bus.EventConversationUpdated,
+				Data: map[string]interface{}{
+					"session_id":         sessionID,
+					"claude_session_id":  claudeSessionID,
+					"content_hash":       convEvent.Digest(),
+					"event_type":         "message",
+					"role":               ev.Role,
+					"content":            ev.Content,
+					"content_type":       "text",
+					"parent_tool_use_id": ev.ParentToolUseID,
+				},
+			})
+		}
 
-					// Publish conversation updated event
-					if m.eventBus != nil {
-						// Parse tool input for event data
-						var toolInput map[string]interface{}
-						if err := json.Unmarshal([]byte(string(inputJSON)), &toolInput); err != nil {
-							toolInput = nil // Don't include invalid JSON
-						}
+	case AssembledToolUse:
+		// Tool call
+		convEvent := &store.ConversationEvent{
+			SessionID:       sessionID,
+			ClaudeSessionID: claudeSessionID,
+			EventType:       store.EventTypeToolCall,
+			ToolID:          ev.ToolID,
+			ToolName:        ev.ToolName,
+			ToolInputJSON:   ev.ToolInputJSON,
+			ParentToolUseID: ev.ParentToolUseID, // Capture from event level
+			// We don't know yet if this needs approval - that comes from HumanLayer API
+		}
+		if err := m.store.AddConversationEvent(ctx, convEvent); err != nil {
+			return err
+		}
 
-						m.eventBus.Publish(bus.Event{
-							Type: bus.EventConversationUpdated,
-							Data: map[string]interface{}{
-								"session_id":         sessionID,
-								"claude_session_id":  claudeSessionID,
-								"event_type":         "tool_call",
-								"tool_id":            content.ID,
-								"tool_name":          content.Name,
-								"tool_input":         toolInput,
-								"parent_tool_use_id": event.ParentToolUseID,
-								"content_type":       "tool_use",
-							},
-						})
-					}
+		// Update session activity timestamp for tool calls
+		m.updateSessionActivity(ctx, sessionID)
 
-				case "tool_result":
-					// Tool result (in user message)
-					convEvent := &store.ConversationEvent{
-						SessionID:         sessionID,
-						ClaudeSessionID:   claudeSessionID,
-						EventType:         store.EventTypeToolResult,
-						Role:              "user",
-						ToolResultForID:   content.ToolUseID,
-						ToolResultContent: content.Content.Value,
-						ParentToolUseID:   event.ParentToolUseID,
-					}
-					if err := m.store.AddConversationEvent(ctx, convEvent); err != nil {
-						return err
-					}
+		// Publish conversation updated event
+		if m.eventBus != nil {
+			// Parse tool input for event data
+			var toolInput map[string]interface{}
+			if err := json.Unmarshal([]byte(ev.ToolInputJSON), &toolInput); err != nil {
+				toolInput = nil // Don't include invalid JSON
+			}
 
-					// Asynchronously capture file snapshot for Read tool results
-					if toolCall, err := m.store.GetToolCallByID(ctx, content.ToolUseID); err == nil && toolCall != nil && toolCall.ToolName == "Read" {
-						go m.captureFileSnapshot(ctx, sessionID, content.ToolUseID, toolCall.ToolInputJSON, content.Content.Value)
-					}
+			m.eventBus.Publish(bus.Event{
+				Type: bus.EventConversationUpdated,
+				Data: map[string]interface{}{
+					"session_id":         sessionID,
+					"claude_session_id":  claudeSessionID,
+					"content_hash":       convEvent.Digest(),
+					"event_type":         "tool_call",
+					"tool_id":            ev.ToolID,
+					"tool_name":          ev.ToolName,
+					"tool_input":         toolInput,
+					"parent_tool_use_id": ev.ParentToolUseID,
+					"content_type":       "tool_use",
+				},
+			})
+		}
 
-					// Update session activity timestamp for tool results
-					m.updateSessionActivity(ctx, sessionID)
-
-					// Publish conversation updated event
-					if m.eventBus != nil {
-						m.eventBus.Publish(bus.Event{
-							Type: bus.EventConversationUpdated,
-							Data: map[string]interface{}{
-								"session_id":          sessionID,
-								"claude_session_id":   claudeSessionID,
-								"event_type":          "tool_result",
-								"tool_result_for_id":  content.ToolUseID,
-								"tool_result_content": content.Content.Value,
-								"content_type":        "tool_result",
-								"parent_tool_use_id":  event.ParentToolUseID,
-							},
-						})
-					}
+	case AssembledToolResult:
+		_, cacheHit := m.cachedToolUses.LoadAndDelete(ev.ToolResultForID)
 
-					// Mark the corresponding tool call as completed
-					if err := m.store.MarkToolCallCompleted(ctx, content.ToolUseID, sessionID); err != nil {
-						slog.Error("failed to mark tool call as completed",
-							"tool_id", content.ToolUseID,
-							"session_id", sessionID,
-							"error", err)
-						// Continue anyway - this is not fatal
-					}
+		// Tool result (in user message)
+		convEvent := &store.ConversationEvent{
+			SessionID:         sessionID,
+			ClaudeSessionID:   claudeSessionID,
+			EventType:         store.EventTypeToolResult,
+			Role:              ev.Role,
+			ToolResultForID:   ev.ToolResultForID,
+			ToolResultContent: ev.ToolResultContent,
+			ToolResultJSON:    ev.ToolResultJSON,
+			ParentToolUseID:   ev.ParentToolUseID,
+			ToolCacheHit:      cacheHit,
+		}
+		if ev.ToolResultIsError {
+			convEvent.ToolError = ev.ToolResultContent
+		}
+		if err := m.store.AddConversationEvent(ctx, convEvent); err != nil {
+			return err
+		}
 
-				case "thinking":
-					// Thinking message
-					convEvent := &store.ConversationEvent{
-						SessionID:       sessionID,
-						ClaudeSessionID: claudeSessionID,
-						EventType:       store.EventTypeThinking,
-						Role:            event.Message.Role,
-						Content:         content.Thinking,
-						ParentToolUseID: event.ParentToolUseID,
-					}
-					if err := m.store.AddConversationEvent(ctx, convEvent); err != nil {
-						return err
-					}
+		toolCall, err := m.store.GetToolCallByID(ctx, ev.ToolResultForID)
+		if err != nil {
+			toolCall = nil
+		}
 
-					// Update session activity timestamp for thinking messages
-					m.updateSessionActivity(ctx, sessionID)
-
-					// Publish conversation updated event
-					if m.eventBus != nil {
-						m.eventBus.Publish(bus.Event{
-							Type: bus.EventConversationUpdated,
-							Data: map[string]interface{}{
-								"session_id":         sessionID,
-								"claude_session_id":  claudeSessionID,
-								"event_type":         "thinking",
-								"role":               event.Message.Role,
-								"content":            content.Thinking,
-								"content_type":       "thinking",
-								"parent_tool_use_id": event.ParentToolUseID,
-							},
-						})
-					}
-				}
-			}
+		// Remember fresh, successful results of cacheable tools
+		m.mu.RLock()
+		cache := m.toolCache
+		m.mu.RUnlock()
+		if cache != nil && toolCall != nil && !cacheHit && !ev.ToolResultIsError {
+			cache.Store(toolCall.ToolName, toolCall.ToolInputJSON, ev.ToolResultContent)
+		}
+
+		// Tool execution and approval time is excluded from generation time,
+		// so the next turn starts once the result is handed back to the model
+		if ev.ParentToolUseID == "" {
+			m.turnStarts.Store(sessionID, time.Now())
+		}
+
+		// Asynchronously capture file snapshot for Read tool results
+		if toolCall != nil && toolCall.ToolName == "Read" {
+			go m.captureFileSnapshot(ctx, sessionID, ev.ToolResultForID, toolCall.ToolInputJSON, ev.ToolResultContent)
+		}
+
+		// Update session activity timestamp for tool results
+		m.updateSessionActivity(ctx, sessionID)
+
+		// Publish conversation updated event
+		if m.eventBus != nil {
+			m.eventBus.Publish(bus.Event{
+				Type: bus.EventConversationUpdated,
+				Data: map[string]interface{}{
+					"session_id":          sessionID,
+					"claude_session_id":   claudeSessionID,
+					"content_hash":        convEvent.Digest(),
+					"event_type":          "tool_result",
+					"tool_result_for_id":  ev.ToolResultForID,
+					"tool_result_content": ev.ToolResultContent,
+					"content_type":        "tool_result",
+					"parent_tool_use_id":  ev.ParentToolUseID,
+				},
+			})
+		}
+
+		// Mark the corresponding tool call as completed
+		if err := m.store.MarkToolCallCompleted(ctx, ev.ToolResultForID, sessionID); err != nil {
+			slog.Error("failed to mark tool call as completed",
+				"tool_id", ev.ToolResultForID,
+				"session_id", sessionID,
+				"error", err)
+			// Continue anyway - this is not fatal
+		}
+
+	case AssembledThinking:
+		// Thinking message
+		convEvent := &store.ConversationEvent{
+			SessionID:       sessionID,
+			ClaudeSessionID: claudeSessionID,
+			EventType:       store.EventTypeThinking,
+			Role:            ev.Role,
+			Content:         ev.Content,
+			ParentToolUseID: ev.ParentToolUseID,
+		}
+		if err := m.store.AddConversationEvent(ctx, convEvent); err != nil {
+			return err
+		}
+
+		// Update session activity timestamp for thinking messages
+		m.updateSessionActivity(ctx, sessionID)
+
+		// Publish conversation updated event
+		if m.eventBus != nil {
+			m.eventBus.Publish(bus.Event{
+				Type: bus.EventConversationUpdated,
+				Data: map[string]interface{}{
+					"session_id":         sessionID,
+					"claude_session_id":  claudeSessionID,
+					"content_hash":       convEvent.Digest(),
+					"event_type":         "thinking",
+					"role":               ev.Role,
+					"content":            ev.Content,
+					"content_type":       "thinking",
+					"parent_tool_use_id": ev.ParentToolUseID,
+				},
+			})
 		}
 
-	case "result":
+	case AssembledResult:
 		// Session completion
+		result := ev.Result
 		status := store.SessionStatusCompleted
-		if event.IsError {
+		if result.IsError {
 			status = store.SessionStatusFailed
 		}
 
@@ -1295,24 +1699,23 @@ func (m *Manager) processStreamEvent(ctx context.Context, sessionID string, clau
 			Status:         &status,
 			CompletedAt:    &now,
 			LastActivityAt: &now,
-			CostUSD:        &event.CostUSD,
-			DurationMS:     &event.DurationMS,
+			CostUSD:        &result.CostUSD,
+			DurationMS:     &result.DurationMS,
 		}
 
 		// Process usage data from result event
-		if event.Usage != nil {
-			usage := event.Usage
+		if result.Usage != nil {
 			// Skip updating token counts from result events - they appear to accumulate incorrectly
 			// Result events show cumulative cache reads across the entire session (bug)
 			// We only trust token counts from individual assistant messages
 			slog.Debug("Skipping result event token update due to API bug",
 				"session_id", sessionID,
-				"cache_read_tokens", usage.CacheReadInputTokens,
+				"cache_read_tokens", result.Usage.CacheReadInputTokens,
 				"reason", "result events report cumulative cache reads")
 		}
 
-		if event.Error != "" {
-			update.ErrorMessage = &event.Error
+		if result.Error != "" {
+			update.ErrorMessage = &result.Error
 		}
 
 		return m.store.UpdateSession(ctx, sessionID, update)
@@ -1321,6 +1724,34 @@ func (m *Manager) processStreamEvent(ctx context.Context, sessionID string, clau
 	return nil
 }
 
+// recordTurnUsage stores output tokens and generation time for the current turn
+func (m *Manager) recordTurnUsage(ctx context.Context, sessionID, messageID string, outputTokens int) {
+	if messageID == "" {
+		return
+	}
+	startVal, ok := m.turnStarts.Load(sessionID)
+	if !ok {
+		return
+	}
+	start, ok := startVal.(time.Time)
+	if !ok {
+		return
+	}
+
+	turn := &store.TurnUsage{
+		SessionID:    sessionID,
+		MessageID:    messageID,
+		OutputTokens: outputTokens,
+		GenerationMS: time.Since(start).Milliseconds(),
+	}
+	if err := m.store.RecordTurnUsage(ctx, turn); err != nil {
+		slog.Error("failed to record turn usage",
+			"session_id", sessionID,
+			"message_id", messageID,
+			"error", err)
+	}
+}
+
 // captureFileSnapshot captures full file content for Read tool results
 func (m *Manager) captureFileSnapshot(ctx context.Context, sessionID, toolID, toolInputJSON, toolResultContent string) {
 	// Parse tool input to get file path
@@ -1446,6 +1877,11 @@ func (m *Manager) ContinueSession(ctx context.Context, req ContinueSessionConfig
 		return nil, fmt.Errorf("cannot continue session with status %s (must be completed, interrupted, running, or failed)", parentSession.Status)
 	}
 
+	// Imported sessions have no live Claude session to resume
+	if parentSession.Imported {
+		return nil, fmt.Errorf("cannot continue imported session (no live claude session to resume)")
+	}
+
 	// Validate parent session has claude_session_id (needed for resume)
 	if parentSession.ClaudeSessionID == "" {
 		return nil, fmt.Errorf("parent session missing claude_session_id (cannot resume)")
@@ -1613,6 +2049,10 @@ func (m *Manager) ContinueSession(ctx context.Context, req ContinueSessionConfig
 	// Store session in database with parent reference
 	dbSession := store.NewSessionFromConfig(sessionID, runID, config)
 	dbSession.ParentSessionID = req.ParentSessionID
+	dbSession.Owner = parentSession.Owner
+	dbSession.BypassToolCache = parentSession.BypassToolCache
+	dbSession.ToolQuota = parentSession.ToolQuota
+	dbSession.Priority = parentSession.Priority
 	dbSession.Summary = CalculateSummary(req.Query)
 	// Inherit auto-accept setting from parent
 	dbSession.AutoAcceptEdits = parentSession.AutoAcceptEdits
@@ -1783,8 +2223,18 @@ func (m *Manager) ContinueSession(ctx context.Context, req ContinueSessionConfig
 		"proxy_base_url", dbSession.ProxyBaseURL,
 		"proxy_model", dbSession.ProxyModelOverride)
 
+	// Wait for a slot if a concurrency cap is configured
+	queuedAt, err := m.acquireLaunchSlot(ctx, sessionID, runID, dbSession.Owner, dbSession.Priority)
+	if err != nil {
+		if !errors.Is(err, errLaunchCancelled) {
+			m.updateSessionStatus(ctx, sessionID, StatusFailed, err.Error())
+		}
+		return nil, err
+	}
+
 	claudeSession, err := client.Launch(config)
 	if err != nil {
+		m.releaseLaunchSlot(sessionID)
 		slog.Error("failed to resume Claude session from failed parent",
 			"session_id", sessionID,
 			"parent_session_id", req.ParentSessionID,
@@ -1809,6 +2259,8 @@ func (m *Manager) ContinueSession(ctx context.Context, req ContinueSessionConfig
 	update := store.SessionUpdate{
 		Status:         &statusRunning,
 		LastActivityAt: &now,
+		QueuedAt:       &queuedAt,
+		StartedAt:      &now,
 	}
 	if err := m.store.UpdateSession(ctx, sessionID, update); err != nil {
 		slog.Error("failed to update session status to running", "error", err)
@@ -1921,6 +2373,60 @@ func (m *Manager) InterruptSession(ctx context.Context, sessionID string) error
 	return nil
 }
 
+// CancelSession stops a started session for good. Unlike an interrupt, which
+// lets Claude wind down and leaves the session resumable, the process is
+// killed and the session is marked cancelled.
+func (m *Manager) CancelSession(ctx context.Context, sessionID string) error {
+	session, err := m.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	oldStatus := session.Status
+
+	// Record the cancellation before killing the process, so the monitor
+	// sees it and doesn't report the kill as a failure
+	status := string(StatusCancelled)
+	if err := m.store.UpdateSession(ctx, sessionID, store.SessionUpdate{Status: &status}); err != nil {
+		return fmt.Errorf("failed to update session status: %w", err)
+	}
+
+	m.mu.Lock()
+	claudeSession, exists := m.activeProcesses[sessionID]
+	m.mu.Unlock()
+	if exists {
+		if err := claudeSession.Kill(); err != nil {
+			// The session is still running, so put its status back
+			if err := m.store.UpdateSession(ctx, sessionID, store.SessionUpdate{Status: &oldStatus}); err != nil {
+				slog.Error("failed to restore session status after failed cancel",
+					"session_id", sessionID,
+					"error", err)
+			}
+			return fmt.Errorf("failed to kill Claude session: %w", err)
+		}
+	}
+
+	now := time.Now()
+	if err := m.store.UpdateSession(ctx, sessionID, store.SessionUpdate{CompletedAt: &now, LastActivityAt: &now}); err != nil {
+		slog.Error("failed to record session cancellation time",
+			"session_id", sessionID,
+			"error", err)
+	}
+
+	if m.eventBus != nil {
+		m.eventBus.Publish(bus.Event{
+			Type: bus.EventSessionStatusChanged,
+			Data: map[string]interface{}{
+				"session_id": sessionID,
+				"run_id":     session.RunID,
+				"old_status": oldStatus,
+				"new_status": string(StatusCancelled),
+			},
+		})
+	}
+
+	return nil
+}
+
 // launchDraftWithConfig launches a draft session using the existing launch flow
 func (m *Manager) launchDraftWithConfig(ctx context.Context, sessionID, runID string, config LaunchSessionConfig) error {
 	// Get Claude client (will attempt initialization if needed)
@@ -2004,8 +2510,18 @@ func (m *Manager) launchDraftWithConfig(ctx context.Context, sessionID, runID st
 		"query", claudeConfig.Query,
 		"working_dir", claudeConfig.WorkingDir)
 
+	// Wait for a slot if a concurrency cap is configured
+	queuedAt, err := m.acquireLaunchSlot(ctx, sessionID, runID, config.Owner, config.Priority)
+	if err != nil {
+		if !errors.Is(err, errLaunchCancelled) {
+			m.updateSessionStatus(ctx, sessionID, StatusFailed, err.Error())
+		}
+		return err
+	}
+
 	claudeSession, err := client.Launch(claudeConfig)
 	if err != nil {
+		m.releaseLaunchSlot(sessionID)
 		slog.Error("failed to launch Claude session from draft",
 			"session_id", sessionID,
 			"error", err)
@@ -2027,6 +2543,8 @@ func (m *Manager) launchDraftWithConfig(ctx context.Context, sessionID, runID st
 	update := store.SessionUpdate{
 		Status:         &statusRunning,
 		LastActivityAt: &now,
+		QueuedAt:       &queuedAt,
+		StartedAt:      &now,
 	}
 	if err := m.store.UpdateSession(ctx, sessionID, update); err != nil {
 		slog.Error("failed to update session status to running", "error", err)
@@ -2254,6 +2772,9 @@ func (m *Manager) LaunchDraftSession(ctx context.Context, sessionID string, prom
 		ProxyBaseURL:               sess.ProxyBaseURL,
 		ProxyModelOverride:         sess.ProxyModelOverride,
 		ProxyAPIKey:                sess.ProxyAPIKey,
+		Owner:                      sess.Owner,
+		BypassToolCache:            sess.BypassToolCache,
+		Priority:                   sess.Priority,
 	}
 
 	// If dangerously skip permissions has an expiry, calculate the timeout
@@ -2273,7 +2794,7 @@ func (m *Manager) LaunchDraftSession(ctx context.Context, sessionID string, prom
 // injectQueryAsFirstEvent adds the user's query as the first conversation event
 func (m *Manager) injectQueryAsFirstEvent(ctx context.Context, sessionID, claudeSessionID, query string) error {
 	// Check if we already have a user message as the first event (deduplication)
-	events, err := m.store.GetConversation(ctx, claudeSessionID)
+	events, err := m.store.GetConversation(ctx, claudeSessionID, store.ConversationPage{})
 	if err == nil && len(events) > 0 && events[0].Role == "user" {
 		return nil // Query already injected
 	}
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
+	"github.com/humanlayer/humanlayer/hld/bus"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMonitorSession_BudgetExceeded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = testStore.Close() }()
+
+	ctx := context.Background()
+	eventBus := bus.NewEventBus()
+	sub := eventBus.Subscribe(ctx, bus.EventFilter{
+		Types: []bus.EventType{bus.EventSessionBudgetExceeded, bus.EventSessionStatusChanged},
+	})
+	manager, err := NewManager(eventBus, testStore, "")
+	require.NoError(t, err)
+
+	budget, spent := 1.0, 1.25
+	require.NoError(t, testStore.CreateSession(ctx, &store.Session{
+		ID:             "sess-1",
+		RunID:          "run-1",
+		Query:          "explain",
+		Status:         store.SessionStatusRunning,
+		MaxCostUSD:     &budget,
+		CreatedAt:      time.Now(),
+		LastActivityAt: time.Now(),
+	}))
+	require.NoError(t, testStore.UpdateSession(ctx, "sess-1", store.SessionUpdate{CostUSD: &spent}))
+
+	assistantText := func(text string) claudecode.StreamEvent {
+		return claudecode.StreamEvent{
+			Type:      "assistant",
+			SessionID: "claude-1",
+			Message: &claudecode.Message{
+				ID:      "msg_1",
+				Role:    "assistant",
+				Content: []claudecode.Content{{Type: "text", Text: text}},
+			},
+		}
+	}
+	events := make(chan claudecode.StreamEvent, 2)
+	events <- assistantText("Spending more.")
+	events <- assistantText("Still spending.")
+	close(events)
+
+	claudeSession := NewMockClaudeSession(ctrl)
+	claudeSession.EXPECT().GetEvents().Return(events).AnyTimes()
+	// Only the first refused event stops the session
+	claudeSession.EXPECT().Interrupt().Return(nil).Times(1)
+	claudeSession.EXPECT().Wait().Return(nil, errors.New("signal: interrupt"))
+	manager.activeProcesses["sess-1"] = claudeSession
+
+	manager.monitorSession(ctx, "sess-1", "run-1", claudeSession, time.Now(), claudecode.SessionConfig{})
+
+	sess, err := testStore.GetSession(ctx, "sess-1")
+	require.NoError(t, err)
+	assert.Equal(t, store.SessionStatusFailed, sess.Status, "the interrupted process isn't reported as interrupted")
+	assert.Equal(t, "session cost budget exceeded: spent $1.25 of its $1.00 budget", sess.ErrorMessage)
+	assert.NotNil(t, sess.CompletedAt)
+
+	conversation, err := testStore.GetConversation(ctx, "claude-1", store.ConversationPage{})
+	require.NoError(t, err)
+	assert.Empty(t, conversation)
+
+	var published []bus.Event
+	for len(published) < 2 {
+		select {
+		case event := <-sub.Channel:
+			published = append(published, event)
+		case <-time.After(time.Second):
+			t.Fatalf("expected 2 events, got %d", len(published))
+		}
+	}
+	assert.Equal(t, bus.EventSessionBudgetExceeded, published[0].Type)
+	assert.Equal(t, "sess-1", published[0].Data["session_id"])
+	assert.Equal(t, 1.25, published[0].Data["cost_usd"])
+	assert.Equal(t, 1.0, published[0].Data["max_cost_usd"])
+	assert.Equal(t, bus.EventSessionStatusChanged, published[1].Type)
+	assert.Equal(t, string(StatusFailed), published[1].Data["new_status"])
+}
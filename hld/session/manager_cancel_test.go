@@ -0,0 +1,81 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCancelSession(t *testing.T) {
+	setup := func(t *testing.T) (*Manager, store.ConversationStore, *MockClaudeSession) {
+		t.Helper()
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+
+		testStore, err := store.NewSQLiteStore(":memory:")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = testStore.Close() })
+
+		manager, err := NewManager(nil, testStore, "")
+		require.NoError(t, err)
+		require.NoError(t, testStore.CreateSession(context.Background(), &store.Session{
+			ID:             "sess-1",
+			RunID:          "run-1",
+			Query:          "explain",
+			Status:         store.SessionStatusRunning,
+			CreatedAt:      time.Now(),
+			LastActivityAt: time.Now(),
+		}))
+
+		claudeSession := NewMockClaudeSession(ctrl)
+		manager.activeProcesses["sess-1"] = claudeSession
+		return manager, testStore, claudeSession
+	}
+
+	t.Run("kills the process once and keeps the cancelled status", func(t *testing.T) {
+		manager, testStore, claudeSession := setup(t)
+		ctx := context.Background()
+
+		claudeSession.EXPECT().Kill().Return(nil).Times(1)
+		require.NoError(t, manager.CancelSession(ctx, "sess-1"))
+
+		sess, err := testStore.GetSession(ctx, "sess-1")
+		require.NoError(t, err)
+		assert.Equal(t, store.SessionStatusCancelled, sess.Status)
+		assert.NotNil(t, sess.CompletedAt)
+
+		// The killed process exits with an error, which isn't a failure
+		events := make(chan claudecode.StreamEvent)
+		close(events)
+		claudeSession.EXPECT().GetEvents().Return(events).AnyTimes()
+		claudeSession.EXPECT().Wait().Return(nil, errors.New("signal: killed"))
+		manager.monitorSession(ctx, "sess-1", "run-1", claudeSession, time.Now(), claudecode.SessionConfig{})
+
+		sess, err = testStore.GetSession(ctx, "sess-1")
+		require.NoError(t, err)
+		assert.Equal(t, store.SessionStatusCancelled, sess.Status)
+		assert.Empty(t, sess.ErrorMessage)
+	})
+
+	t.Run("restores the status if the process can't be killed", func(t *testing.T) {
+		manager, testStore, claudeSession := setup(t)
+		ctx := context.Background()
+
+		claudeSession.EXPECT().Kill().Return(errors.New("operation not permitted")).Times(1)
+		err := manager.CancelSession(ctx, "sess-1")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to kill Claude session")
+
+		sess, err := testStore.GetSession(ctx, "sess-1")
+		require.NoError(t, err)
+		assert.Equal(t, store.SessionStatusRunning, sess.Status)
+		assert.Nil(t, sess.CompletedAt)
+	})
+}
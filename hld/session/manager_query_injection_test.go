@@ -35,7 +35,7 @@ func TestQueryInjection(t *testing.T) {
 
 		// Mock expectations - check for existing user message first
 		mockStore.EXPECT().
-			GetConversation(gomock.Any(), claudeSessionID).
+			GetConversation(gomock.Any(), claudeSessionID, gomock.Any()).
 			Return([]*store.ConversationEvent{}, nil)
 
 		// Expect AddConversationEvent to be called with the query
@@ -75,7 +75,7 @@ func TestQueryInjection(t *testing.T) {
 		query := "Test query"
 
 		mockStore.EXPECT().
-			GetConversation(gomock.Any(), claudeSessionID).
+			GetConversation(gomock.Any(), claudeSessionID, gomock.Any()).
 			Return([]*store.ConversationEvent{}, nil)
 
 		mockStore.EXPECT().
@@ -104,7 +104,7 @@ func TestQueryInjection(t *testing.T) {
 			Content: "Some existing user message",
 		}
 		mockStore.EXPECT().
-			GetConversation(gomock.Any(), claudeSessionID).
+			GetConversation(gomock.Any(), claudeSessionID, gomock.Any()).
 			Return([]*store.ConversationEvent{existingEvent}, nil)
 
 		// AddConversationEvent should NOT be called due to deduplication
@@ -194,7 +194,7 @@ func TestQueryInjectionRaceCondition(t *testing.T) {
 
 		// Expect GetConversationEvents and AddConversationEvent for each session
 		mockStore.EXPECT().
-			GetConversation(gomock.Any(), claudeSessionID).
+			GetConversation(gomock.Any(), claudeSessionID, gomock.Any()).
 			Return([]*store.ConversationEvent{}, nil)
 
 		mockStore.EXPECT().
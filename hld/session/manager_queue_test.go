@@ -0,0 +1,150 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queueFailingStore fails every attempt to mark a session queued
+type queueFailingStore struct {
+	store.ConversationStore
+}
+
+func (s queueFailingStore) UpdateSession(ctx context.Context, sessionID string, updates store.SessionUpdate) error {
+	if updates.Status != nil && *updates.Status == store.SessionStatusQueued {
+		return errors.New("disk full")
+	}
+	return s.ConversationStore.UpdateSession(ctx, sessionID, updates)
+}
+
+func TestAcquireLaunchSlot(t *testing.T) {
+	setupWith := func(t *testing.T, wrap func(store.ConversationStore) store.ConversationStore) (*Manager, store.ConversationStore, *LaunchScheduler) {
+		t.Helper()
+		testStore, err := store.NewSQLiteStore(":memory:")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = testStore.Close() })
+
+		manager, err := NewManager(nil, wrap(testStore), "")
+		require.NoError(t, err)
+		scheduler := NewLaunchScheduler(1, SchedulingFair)
+		manager.SetLaunchScheduler(scheduler)
+		require.NoError(t, testStore.CreateSession(context.Background(), &store.Session{
+			ID:             "sess-1",
+			RunID:          "run-1",
+			Query:          "explain",
+			Status:         store.SessionStatusStarting,
+			CreatedAt:      time.Now(),
+			LastActivityAt: time.Now(),
+		}))
+		return manager, testStore, scheduler
+	}
+	setup := func(t *testing.T) (*Manager, store.ConversationStore, *LaunchScheduler) {
+		return setupWith(t, func(s store.ConversationStore) store.ConversationStore { return s })
+	}
+
+	// acquireInBackground waits until the launch is queued
+	acquireInBackground := func(t *testing.T, manager *Manager, testStore store.ConversationStore) <-chan error {
+		t.Helper()
+		result := make(chan error, 1)
+		go func() {
+			_, err := manager.acquireLaunchSlot(context.Background(), "sess-1", "run-1", "alice", 0)
+			result <- err
+		}()
+		require.Eventually(t, func() bool {
+			sess, err := testStore.GetSession(context.Background(), "sess-1")
+			return err == nil && sess.Status == store.SessionStatusQueued
+		}, time.Second, time.Millisecond)
+		return result
+	}
+
+	t.Run("starts right away when a slot is free", func(t *testing.T) {
+		manager, testStore, scheduler := setup(t)
+		ctx := context.Background()
+
+		_, err := manager.acquireLaunchSlot(ctx, "sess-1", "run-1", "alice", 0)
+		require.NoError(t, err)
+		sess, err := testStore.GetSession(ctx, "sess-1")
+		require.NoError(t, err)
+		assert.Equal(t, store.SessionStatusStarting, sess.Status)
+
+		manager.releaseLaunchSlot("sess-1")
+		assert.Equal(t, 0, scheduler.Status().Running)
+	})
+
+	t.Run("queues the session until a slot is free", func(t *testing.T) {
+		manager, testStore, scheduler := setup(t)
+		ctx := context.Background()
+		release, err := scheduler.Acquire(ctx, "bob", 0)
+		require.NoError(t, err)
+
+		result := acquireInBackground(t, manager, testStore)
+		queued, err := testStore.ListQueuedSessions(ctx)
+		require.NoError(t, err)
+		require.Len(t, queued, 1)
+		assert.Equal(t, "sess-1", queued[0].ID)
+
+		release()
+		require.NoError(t, <-result)
+		sess, err := testStore.GetSession(ctx, "sess-1")
+		require.NoError(t, err)
+		assert.Equal(t, store.SessionStatusStarting, sess.Status)
+		assert.Equal(t, 1, scheduler.Status().Active["alice"])
+	})
+
+	t.Run("gives the slot back when cancelled while queued", func(t *testing.T) {
+		manager, testStore, scheduler := setup(t)
+		ctx := context.Background()
+		release, err := scheduler.Acquire(ctx, "bob", 0)
+		require.NoError(t, err)
+
+		result := acquireInBackground(t, manager, testStore)
+		require.NoError(t, manager.CancelSession(ctx, "sess-1"))
+
+		release()
+		assert.ErrorIs(t, <-result, errLaunchCancelled)
+		sess, err := testStore.GetSession(ctx, "sess-1")
+		require.NoError(t, err)
+		assert.Equal(t, store.SessionStatusCancelled, sess.Status)
+		assert.Equal(t, 0, scheduler.Status().Running)
+	})
+
+	t.Run("gives the slot back when deleted while queued", func(t *testing.T) {
+		manager, testStore, scheduler := setup(t)
+		ctx := context.Background()
+		release, err := scheduler.Acquire(ctx, "bob", 0)
+		require.NoError(t, err)
+
+		result := acquireInBackground(t, manager, testStore)
+		require.NoError(t, testStore.HardDeleteSession(ctx, "sess-1"))
+
+		release()
+		assert.ErrorIs(t, <-result, errLaunchCancelled)
+		assert.Equal(t, 0, scheduler.Status().Running)
+	})
+
+	t.Run("fails when the session can't be marked queued", func(t *testing.T) {
+		manager, testStore, scheduler := setupWith(t, func(s store.ConversationStore) store.ConversationStore {
+			return queueFailingStore{s}
+		})
+		ctx := context.Background()
+		release, err := scheduler.Acquire(ctx, "bob", 0)
+		require.NoError(t, err)
+		defer release()
+
+		// The launch fails without waiting for the slot
+		_, err = manager.acquireLaunchSlot(ctx, "sess-1", "run-1", "alice", 0)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, errLaunchCancelled)
+		assert.Contains(t, err.Error(), "disk full")
+		sess, err := testStore.GetSession(ctx, "sess-1")
+		require.NoError(t, err)
+		assert.Equal(t, store.SessionStatusStarting, sess.Status)
+		assert.Empty(t, scheduler.Status().Queued)
+	})
+}
@@ -313,7 +313,7 @@ func TestSessionManagerWithStore(t *testing.T) {
 		}
 
 		// Verify conversation was stored
-		conversation, err := testStore.GetConversation(ctx, "01JGK7MWS9Z8XQYV3H2BCDEFGH")
+		conversation, err := testStore.GetConversation(ctx, "01JGK7MWS9Z8XQYV3H2BCDEFGH", store.ConversationPage{})
 		if err != nil {
 			t.Fatalf("failed to get conversation: %v", err)
 		}
@@ -453,7 +453,7 @@ func TestSessionManagerWithStore(t *testing.T) {
 		}
 
 		// Verify all sessions in database
-		sessions, err := testStore.ListSessions(ctx)
+		sessions, err := testStore.ListSessions(ctx, store.ListSessionsFilter{})
 		if err != nil {
 			t.Fatalf("failed to list sessions: %v", err)
 		}
@@ -543,7 +543,7 @@ func TestSessionManagerEventProcessing(t *testing.T) {
 		}
 
 		// Verify it was stored correctly
-		events, err := testStore.GetConversation(ctx, claudeSessionID)
+		events, err := testStore.GetConversation(ctx, claudeSessionID, store.ConversationPage{})
 		if err != nil {
 			t.Fatalf("failed to get conversation: %v", err)
 		}
@@ -613,7 +613,7 @@ func TestSessionManagerEventProcessing(t *testing.T) {
 		}
 
 		// Verify all content was stored
-		events, err := testStore.GetConversation(ctx, claudeSessionID)
+		events, err := testStore.GetConversation(ctx, claudeSessionID, store.ConversationPage{})
 		if err != nil {
 			t.Fatalf("failed to get conversation: %v", err)
 		}
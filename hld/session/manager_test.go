@@ -55,7 +55,7 @@ func TestListSessions(t *testing.T) {
 	manager, _ := NewManager(nil, mockStore, "")
 
 	// Test empty list
-	mockStore.EXPECT().ListSessions(gomock.Any()).Return([]*store.Session{}, nil)
+	mockStore.EXPECT().ListSessions(gomock.Any(), gomock.Any()).Return([]*store.Session{}, nil)
 
 	sessions := manager.ListSessions()
 	if len(sessions) != 0 {
@@ -72,7 +72,7 @@ func TestListSessions(t *testing.T) {
 			CreatedAt: time.Now(),
 		},
 	}
-	mockStore.EXPECT().ListSessions(gomock.Any()).Return(dbSessions, nil)
+	mockStore.EXPECT().ListSessions(gomock.Any(), gomock.Any()).Return(dbSessions, nil)
 
 	sessions = manager.ListSessions()
 	if len(sessions) != 1 {
@@ -294,6 +294,38 @@ func TestContinueSession_ValidatesClaudeSessionID(t *testing.T) {
 	}
 }
 
+func TestContinueSession_RejectsImportedSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := store.NewMockConversationStore(ctrl)
+	manager, _ := NewManager(nil, mockStore, "")
+
+	parentSession := &store.Session{
+		ID:              "parent-1",
+		RunID:           "run-1",
+		ClaudeSessionID: "imported-parent-1",
+		Status:          store.SessionStatusCompleted,
+		Query:           "original query",
+		WorkingDir:      "/tmp",
+		CreatedAt:       time.Now(),
+		Imported:        true,
+	}
+	mockStore.EXPECT().GetSession(gomock.Any(), "parent-1").Return(parentSession, nil)
+
+	req := ContinueSessionConfig{
+		ParentSessionID: "parent-1",
+		Query:           "continue this",
+	}
+	_, err := manager.ContinueSession(context.Background(), req)
+	if err == nil {
+		t.Fatal("Expected error for imported parent session")
+	}
+	if err.Error() != "cannot continue imported session (no live claude session to resume)" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
 func TestLaunchSession_SetsMCPEnvironment(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -0,0 +1,93 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMonitorSession_TruncatedStream(t *testing.T) {
+	runStream := func(t *testing.T, chunks []claudecode.StreamEvent, result *claudecode.Result, waitErr error) []*store.ConversationEvent {
+		t.Helper()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testStore, err := store.NewSQLiteStore(":memory:")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = testStore.Close() })
+
+		manager, err := NewManager(nil, testStore, "")
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		require.NoError(t, testStore.CreateSession(ctx, &store.Session{
+			ID:             "sess-1",
+			RunID:          "run-1",
+			Query:          "explain",
+			Status:         store.SessionStatusRunning,
+			CreatedAt:      time.Now(),
+			LastActivityAt: time.Now(),
+		}))
+
+		events := make(chan claudecode.StreamEvent, len(chunks))
+		for _, chunk := range chunks {
+			events <- chunk
+		}
+		close(events)
+
+		claudeSession := NewMockClaudeSession(ctrl)
+		claudeSession.EXPECT().GetEvents().Return(events).AnyTimes()
+		claudeSession.EXPECT().Wait().Return(result, waitErr)
+
+		manager.monitorSession(ctx, "sess-1", "run-1", claudeSession, time.Now(), claudecode.SessionConfig{})
+
+		conversation, err := testStore.GetConversation(ctx, "claude-1", store.ConversationPage{})
+		require.NoError(t, err)
+		return conversation
+	}
+
+	assistantText := func(text string) claudecode.StreamEvent {
+		return claudecode.StreamEvent{
+			Type:      "assistant",
+			SessionID: "claude-1",
+			Message: &claudecode.Message{
+				ID:      "msg_1",
+				Role:    "assistant",
+				Content: []claudecode.Content{{Type: "text", Text: text}},
+			},
+		}
+	}
+
+	t.Run("stream cut off before result", func(t *testing.T) {
+		conversation := runStream(t, []claudecode.StreamEvent{
+			assistantText("The first part is complete."),
+			assistantText("The second part was cut off mid-"),
+		}, nil, errors.New("connection reset by peer"))
+
+		require.Len(t, conversation, 2)
+		assert.False(t, conversation[0].Truncated, "earlier output was followed by more and is whole")
+		last := conversation[1]
+		assert.True(t, last.Truncated)
+		assert.False(t, last.IsCompleted)
+		assert.Equal(t, "The second part was cut off mid-", last.Content)
+	})
+
+	t.Run("stream with result", func(t *testing.T) {
+		conversation := runStream(t, []claudecode.StreamEvent{
+			assistantText("All done."),
+			{Type: "result", SessionID: "claude-1", Result: "All done."},
+		}, &claudecode.Result{Result: "All done."}, nil)
+
+		require.NotEmpty(t, conversation)
+		for _, event := range conversation {
+			assert.False(t, event.Truncated)
+		}
+	})
+}
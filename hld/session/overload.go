@@ -0,0 +1,199 @@
+package session
+
+import (
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// overloadBaseBackoff is the first backoff applied when the provider
+	// reports overload without a Retry-After hint
+	overloadBaseBackoff = 5 * time.Second
+	// DefaultOverloadMaxBackoff caps the backoff applied after repeated overloads
+	DefaultOverloadMaxBackoff = 5 * time.Minute
+	// maxOverloadHistory is the number of finished overload periods kept
+	maxOverloadHistory = 50
+)
+
+// OverloadPeriod is a span of time during which the provider was overloaded
+type OverloadPeriod struct {
+	Start   time.Time  `json:"start"`
+	End     *time.Time `json:"end,omitempty"` // Nil while the overload is ongoing
+	Reports int        `json:"reports"`       // Overload responses seen during the period
+}
+
+// ProviderStatus is a point-in-time view of provider health
+type ProviderStatus struct {
+	Overloaded      bool             `json:"overloaded"`
+	RetryAt         *time.Time       `json:"retry_at,omitempty"` // When launches resume at full concurrency
+	Current         *OverloadPeriod  `json:"current,omitempty"`
+	RecentOverloads []OverloadPeriod `json:"recent_overloads"` // Finished periods, oldest first
+}
+
+// OverloadMonitor coordinates the daemon's response to provider overload.
+// While the provider is overloaded, launches are throttled system-wide; the
+// overload clears once the backoff window passes with no further reports.
+type OverloadMonitor struct {
+	mu          sync.Mutex
+	scheduler   *LaunchScheduler
+	throttle    int
+	maxBackoff  time.Duration
+	consecutive int
+	until       time.Time
+	current     *OverloadPeriod
+	history     []OverloadPeriod
+	timer       *time.Timer
+	now         func() time.Time
+}
+
+// NewOverloadMonitor creates a monitor that throttles scheduler to throttle
+// running sessions while the provider is overloaded. scheduler may be nil, in
+// which case overloads are only recorded.
+func NewOverloadMonitor(scheduler *LaunchScheduler, throttle int, maxBackoff time.Duration) *OverloadMonitor {
+	if throttle < 1 {
+		throttle = 1
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultOverloadMaxBackoff
+	}
+	return &OverloadMonitor{
+		scheduler:  scheduler,
+		throttle:   throttle,
+		maxBackoff: maxBackoff,
+		now:        time.Now,
+	}
+}
+
+// ReportOverload records an overload response and returns how long launches
+// are held back. A positive retryAfter from the provider is respected;
+// otherwise the delay backs off exponentially with jitter.
+func (o *OverloadMonitor) ReportOverload(retryAfter time.Duration) time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.consecutive++
+	delay := retryAfter
+	if delay <= 0 {
+		delay = overloadBaseBackoff << min(o.consecutive-1, 16)
+		if delay > o.maxBackoff {
+			delay = o.maxBackoff
+		}
+		// Equal jitter keeps at least half the backoff while spreading retries
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	} else if delay > o.maxBackoff {
+		delay = o.maxBackoff
+	}
+
+	now := o.now()
+	if until := now.Add(delay); until.After(o.until) {
+		o.until = until
+	}
+
+	if o.current == nil {
+		o.current = &OverloadPeriod{Start: now}
+		if o.scheduler != nil {
+			o.scheduler.SetThrottle(o.throttle)
+		}
+		slog.Warn("provider overloaded, throttling launches",
+			"throttle", o.throttle,
+			"retry_at", o.until)
+	}
+	o.current.Reports++
+
+	if o.timer != nil {
+		o.timer.Stop()
+	}
+	o.timer = time.AfterFunc(o.until.Sub(now), o.clear)
+
+	return delay
+}
+
+// ReportSuccess records a successful provider response, resetting the backoff
+// once any overload window has passed
+func (o *OverloadMonitor) ReportSuccess() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.current == nil {
+		o.consecutive = 0
+	}
+}
+
+// Status returns whether the provider is overloaded and recent overload periods
+func (o *OverloadMonitor) Status() ProviderStatus {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	status := ProviderStatus{
+		Overloaded:      o.current != nil,
+		RecentOverloads: append([]OverloadPeriod{}, o.history...),
+	}
+	if o.current != nil {
+		current := *o.current
+		until := o.until
+		status.Current = &current
+		status.RetryAt = &until
+	}
+	return status
+}
+
+// clear ends the current overload period once its backoff window has passed
+func (o *OverloadMonitor) clear() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.now()
+	if o.current == nil || now.Before(o.until) {
+		return
+	}
+
+	o.current.End = &now
+	o.history = append(o.history, *o.current)
+	if len(o.history) > maxOverloadHistory {
+		o.history = o.history[len(o.history)-maxOverloadHistory:]
+	}
+	slog.Info("provider overload cleared",
+		"duration", now.Sub(o.current.Start),
+		"reports", o.current.Reports)
+	o.current = nil
+	o.timer = nil
+
+	if o.scheduler != nil {
+		o.scheduler.SetThrottle(0)
+	}
+}
+
+// IsOverloadStatus reports whether an HTTP status code signals provider overload
+func IsOverloadStatus(code int) bool {
+	return code == 529
+}
+
+// IsOverloadError reports whether a provider error message signals overload
+func IsOverloadError(message string) bool {
+	return strings.Contains(strings.ToLower(message), "overloaded")
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP
+// date. It returns zero when the header is missing or invalid.
+func ParseRetryAfter(header string) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverloadMonitor(t *testing.T) {
+	t.Run("throttles launches until the overload clears", func(t *testing.T) {
+		scheduler := NewLaunchScheduler(0, SchedulingFair)
+		monitor := NewOverloadMonitor(scheduler, 1, time.Minute)
+
+		_, err := scheduler.Acquire(context.Background(), "alice", 0)
+		require.NoError(t, err)
+
+		delay := monitor.ReportOverload(200 * time.Millisecond)
+		assert.Equal(t, 200*time.Millisecond, delay, "Retry-After is respected")
+		assert.Equal(t, 1, scheduler.Status().Throttle)
+
+		status := monitor.Status()
+		require.True(t, status.Overloaded)
+		require.NotNil(t, status.RetryAt)
+		assert.Equal(t, 1, status.Current.Reports)
+
+		// A second launch waits behind the throttle
+		granted := make(chan string, 1)
+		queueLaunch(t, scheduler, "bob", 0, granted)
+
+		select {
+		case owner := <-granted:
+			assert.Equal(t, "bob", owner)
+		case <-time.After(time.Second):
+			t.Fatal("launch was not released after the overload cleared")
+		}
+
+		status = monitor.Status()
+		assert.False(t, status.Overloaded)
+		require.Len(t, status.RecentOverloads, 1)
+		assert.NotNil(t, status.RecentOverloads[0].End)
+		assert.Equal(t, 0, scheduler.Status().Throttle)
+	})
+
+	t.Run("backs off with jitter without a Retry-After hint", func(t *testing.T) {
+		monitor := NewOverloadMonitor(nil, 1, time.Minute)
+
+		first := monitor.ReportOverload(0)
+		assert.GreaterOrEqual(t, first, overloadBaseBackoff/2)
+		assert.LessOrEqual(t, first, overloadBaseBackoff)
+
+		second := monitor.ReportOverload(0)
+		assert.GreaterOrEqual(t, second, overloadBaseBackoff)
+		assert.LessOrEqual(t, second, 2*overloadBaseBackoff)
+
+		for i := 0; i < 10; i++ {
+			assert.LessOrEqual(t, monitor.ReportOverload(0), time.Minute)
+		}
+		assert.Equal(t, 12, monitor.Status().Current.Reports)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 30*time.Second, ParseRetryAfter("30"))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("soon"))
+
+	at := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	d := ParseRetryAfter(at)
+	assert.Greater(t, d, 55*time.Second)
+	assert.LessOrEqual(t, d, time.Minute)
+}
+
+func TestIsOverloadError(t *testing.T) {
+	assert.True(t, IsOverloadError(`API Error: 529 {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+	assert.False(t, IsOverloadError("exit status 1"))
+}
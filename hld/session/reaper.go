@@ -0,0 +1,147 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/bus"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// inactivityTimeoutMessage is the error recorded on reaped sessions
+const inactivityTimeoutMessage = "inactivity timeout"
+
+// SessionReaper fails running sessions that have gone quiet for longer than
+// InactivityTimeout, which happens when the Claude process dies without the
+// daemon noticing. A tool that legitimately runs longer than the timeout
+// without output is indistinguishable, so the timeout should be generous.
+type SessionReaper struct {
+	store             store.ConversationStore
+	eventBus          bus.EventBus
+	InactivityTimeout time.Duration
+	interval          time.Duration
+	now               func() time.Time
+}
+
+// NewSessionReaper creates a reaper that checks every interval. eventBus may
+// be nil.
+func NewSessionReaper(store store.ConversationStore, eventBus bus.EventBus, inactivityTimeout, interval time.Duration) *SessionReaper {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &SessionReaper{
+		store:             store,
+		eventBus:          eventBus,
+		InactivityTimeout: inactivityTimeout,
+		interval:          interval,
+		now:               time.Now,
+	}
+}
+
+// Run reaps stale sessions every interval until ctx is cancelled, then
+// returns ctx's error
+func (r *SessionReaper) Run(ctx context.Context) error {
+	slog.Info("starting session reaper",
+		"inactivity_timeout", r.InactivityTimeout,
+		"interval", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	// Do an initial pass immediately
+	r.ReapOnce(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("session reaper shutting down")
+			return ctx.Err()
+		case <-ticker.C:
+			r.ReapOnce(ctx)
+		}
+	}
+}
+
+// ReapOnce fails every stale running session and returns how many it failed
+func (r *SessionReaper) ReapOnce(ctx context.Context) int {
+	if r.store == nil || r.InactivityTimeout <= 0 {
+		return 0
+	}
+
+	sessions, err := r.store.ListSessions(ctx, store.ListSessionsFilter{
+		Status: []string{store.SessionStatusRunning},
+	})
+	if err != nil {
+		slog.Error("failed to list running sessions", "error", err)
+		return 0
+	}
+
+	count := 0
+	for _, sess := range sessions {
+		if !r.stale(sess) {
+			continue
+		}
+		reaped, err := r.reap(ctx, sess.ID)
+		if err != nil {
+			slog.Error("failed to fail inactive session", "session_id", sess.ID, "error", err)
+			// Continue with other sessions
+			continue
+		}
+		if reaped {
+			count++
+		}
+	}
+
+	if count > 0 {
+		slog.Info("failed inactive sessions", "count", count, "inactivity_timeout", r.InactivityTimeout)
+	}
+	return count
+}
+
+func (r *SessionReaper) stale(sess *store.Session) bool {
+	return sess.Status == store.SessionStatusRunning && r.now().Sub(sess.LastActivityAt) > r.InactivityTimeout
+}
+
+// reap fails a session unless it has become active since it was listed
+func (r *SessionReaper) reap(ctx context.Context, sessionID string) (bool, error) {
+	// Re-read to narrow the window in which activity since the listing is missed
+	sess, err := r.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	if !r.stale(sess) {
+		return false, nil
+	}
+
+	failed := store.SessionStatusFailed
+	errorMsg := inactivityTimeoutMessage
+	now := r.now()
+	if err := r.store.UpdateSession(ctx, sessionID, store.SessionUpdate{
+		Status:       &failed,
+		CompletedAt:  &now,
+		ErrorMessage: &errorMsg,
+	}); err != nil {
+		return false, err
+	}
+
+	slog.Warn("failed session after inactivity",
+		"session_id", sessionID,
+		"last_activity_at", sess.LastActivityAt,
+		"inactivity_timeout", r.InactivityTimeout)
+
+	if r.eventBus != nil {
+		r.eventBus.Publish(bus.Event{
+			Type:      bus.EventSessionStatusChanged,
+			Timestamp: now,
+			Data: map[string]interface{}{
+				"session_id":    sessionID,
+				"run_id":        sess.RunID,
+				"old_status":    store.SessionStatusRunning,
+				"new_status":    store.SessionStatusFailed,
+				"error_message": errorMsg,
+			},
+		})
+	}
+	return true, nil
+}
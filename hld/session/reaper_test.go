@@ -0,0 +1,130 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore records the status updates made through it
+type countingStore struct {
+	store.ConversationStore
+	mu       sync.Mutex
+	statuses map[string][]string
+}
+
+func (s *countingStore) UpdateSession(ctx context.Context, sessionID string, update store.SessionUpdate) error {
+	if update.Status != nil {
+		s.mu.Lock()
+		s.statuses[sessionID] = append(s.statuses[sessionID], *update.Status)
+		s.mu.Unlock()
+	}
+	return s.ConversationStore.UpdateSession(ctx, sessionID, update)
+}
+
+func TestSessionReaper(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	base := time.Now()
+	for _, s := range []struct {
+		id           string
+		status       string
+		lastActivity time.Time
+	}{
+		{"quiet", store.SessionStatusRunning, base},
+		{"busy", store.SessionStatusRunning, base.Add(50 * time.Minute)},
+		{"waiting", store.SessionStatusWaitingInput, base},
+		{"done", store.SessionStatusCompleted, base},
+	} {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &store.Session{
+			ID: s.id, RunID: "run-" + s.id, Query: "q", Status: s.status,
+			CreatedAt: base, LastActivityAt: s.lastActivity,
+		}))
+	}
+
+	counting := &countingStore{ConversationStore: sqliteStore, statuses: map[string][]string{}}
+	reaper := NewSessionReaper(counting, nil, time.Hour, time.Minute)
+	var mu sync.Mutex
+	clock := base
+	reaper.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return clock
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		clock = clock.Add(d)
+		mu.Unlock()
+	}
+
+	advance(30 * time.Minute)
+	assert.Equal(t, 0, reaper.ReapOnce(ctx), "nothing is stale yet")
+
+	advance(31 * time.Minute)
+	assert.Equal(t, 1, reaper.ReapOnce(ctx))
+	assert.Equal(t, 0, reaper.ReapOnce(ctx), "a reaped session isn't reaped again")
+
+	quiet, err := sqliteStore.GetSession(ctx, "quiet")
+	require.NoError(t, err)
+	assert.Equal(t, store.SessionStatusFailed, quiet.Status)
+	assert.Equal(t, "inactivity timeout", quiet.ErrorMessage)
+	assert.NotNil(t, quiet.CompletedAt)
+
+	// The busy session goes stale later; other statuses never do
+	advance(time.Hour)
+	assert.Equal(t, 1, reaper.ReapOnce(ctx))
+	assert.Equal(t, 0, reaper.ReapOnce(ctx))
+
+	assert.Equal(t, map[string][]string{
+		"quiet": {store.SessionStatusFailed},
+		"busy":  {store.SessionStatusFailed},
+	}, counting.statuses)
+}
+
+func TestSessionReaperRun(t *testing.T) {
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	now := time.Now()
+	require.NoError(t, sqliteStore.CreateSession(context.Background(), &store.Session{
+		ID: "quiet", RunID: "run-quiet", Query: "q", Status: store.SessionStatusRunning,
+		CreatedAt: now, LastActivityAt: now,
+	}))
+
+	counting := &countingStore{ConversationStore: sqliteStore, statuses: map[string][]string{}}
+	reaper := NewSessionReaper(counting, nil, time.Hour, 5*time.Millisecond)
+	reaper.now = func() time.Time { return now.Add(2 * time.Hour) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- reaper.Run(ctx) }()
+
+	// Let several ticks pass before stopping
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		assert.True(t, errors.Is(err, context.Canceled))
+	case <-time.After(2 * time.Second):
+		t.Fatal("reaper did not stop on cancellation")
+	}
+
+	counting.mu.Lock()
+	defer counting.mu.Unlock()
+	assert.Equal(t, []string{store.SessionStatusFailed}, counting.statuses["quiet"], "exactly one transition across ticks")
+}
+
+func TestSessionReaperDisabled(t *testing.T) {
+	reaper := NewSessionReaper(nil, nil, time.Hour, 0)
+	assert.Equal(t, 0, reaper.ReapOnce(context.Background()))
+}
@@ -0,0 +1,228 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// DefaultOwner is used for launches that don't name an owner. It matches the
+// identity the RPC layer assigns to local socket connections.
+const DefaultOwner = "local"
+
+// SchedulingPolicy controls the order queued launches of equal priority are
+// granted a slot in
+type SchedulingPolicy string
+
+const (
+	// SchedulingFIFO grants slots strictly in arrival order
+	SchedulingFIFO SchedulingPolicy = "fifo"
+	// SchedulingFair grants the next slot to the queued owner with the fewest
+	// active sessions, breaking ties by arrival order. An owner flooding the
+	// queue therefore can't starve others waiting behind it.
+	SchedulingFair SchedulingPolicy = "fair"
+)
+
+// ParseSchedulingPolicy converts a config value to a SchedulingPolicy.
+// An empty value selects the fair policy.
+func ParseSchedulingPolicy(value string) (SchedulingPolicy, error) {
+	switch SchedulingPolicy(value) {
+	case "", SchedulingFair:
+		return SchedulingFair, nil
+	case SchedulingFIFO:
+		return SchedulingFIFO, nil
+	default:
+		return "", fmt.Errorf("unknown scheduling policy %q", value)
+	}
+}
+
+// SchedulerStatus is a point-in-time view of the launch scheduler
+type SchedulerStatus struct {
+	MaxConcurrent int              `json:"max_concurrent"`     // 0 means unlimited
+	Throttle      int              `json:"throttle,omitempty"` // Temporary lower cap, e.g. during provider overload
+	Policy        SchedulingPolicy `json:"policy"`
+	Running       int              `json:"running"`
+	Active        map[string]int   `json:"active"` // owner -> running sessions
+	Queued        map[string]int   `json:"queued"` // owner -> launches waiting for a slot
+}
+
+// launchWaiter is a launch blocked waiting for a slot
+type launchWaiter struct {
+	owner    string
+	priority int
+	ready    chan struct{}
+}
+
+// LaunchScheduler caps the number of concurrently running sessions and
+// decides which queued launch gets the next free slot. Higher priority
+// launches always go first; the policy orders launches of equal priority.
+type LaunchScheduler struct {
+	mu            sync.Mutex
+	maxConcurrent int
+	throttle      int
+	policy        SchedulingPolicy
+	running       int
+	active        map[string]int
+	waiters       []*launchWaiter
+}
+
+// NewLaunchScheduler creates a scheduler allowing maxConcurrent running
+// sessions. A maxConcurrent of zero doesn't cap launches but still allows them
+// to be throttled.
+func NewLaunchScheduler(maxConcurrent int, policy SchedulingPolicy) *LaunchScheduler {
+	if maxConcurrent < 0 {
+		maxConcurrent = 0
+	}
+	return &LaunchScheduler{
+		maxConcurrent: maxConcurrent,
+		policy:        policy,
+		active:        make(map[string]int),
+	}
+}
+
+// Acquire blocks until owner is granted a slot or ctx is done. The returned
+// release function frees the slot and is safe to call more than once.
+func (s *LaunchScheduler) Acquire(ctx context.Context, owner string, priority int) (func(), error) {
+	return s.AcquireNotify(ctx, owner, priority, nil)
+}
+
+// AcquireNotify is Acquire, calling queued before it blocks when no slot is
+// free. queued may be nil.
+func (s *LaunchScheduler) AcquireNotify(ctx context.Context, owner string, priority int, queued func()) (func(), error) {
+	if owner == "" {
+		owner = DefaultOwner
+	}
+
+	s.mu.Lock()
+	if s.running < s.limitLocked() && len(s.waiters) == 0 {
+		s.grantLocked(owner)
+		s.mu.Unlock()
+		return s.releaseFunc(owner), nil
+	}
+	w := &launchWaiter{owner: owner, priority: priority, ready: make(chan struct{})}
+	s.waiters = append(s.waiters, w)
+	s.mu.Unlock()
+
+	if queued != nil {
+		queued()
+	}
+
+	select {
+	case <-w.ready:
+		return s.releaseFunc(owner), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-w.ready:
+			// Granted while we were giving up - hand the slot back
+			s.mu.Unlock()
+			s.release(owner)
+		default:
+			s.removeWaiterLocked(w)
+			s.mu.Unlock()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// SetThrottle temporarily lowers the number of running sessions to limit,
+// without stopping sessions already running. A limit of zero lifts the throttle.
+func (s *LaunchScheduler) SetThrottle(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit < 0 {
+		limit = 0
+	}
+	s.throttle = limit
+	s.dispatchLocked()
+}
+
+// Status returns the current running and queued counts per owner
+func (s *LaunchScheduler) Status() SchedulerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := SchedulerStatus{
+		MaxConcurrent: s.maxConcurrent,
+		Throttle:      s.throttle,
+		Policy:        s.policy,
+		Running:       s.running,
+		Active:        make(map[string]int, len(s.active)),
+		Queued:        make(map[string]int),
+	}
+	for owner, count := range s.active {
+		status.Active[owner] = count
+	}
+	for _, w := range s.waiters {
+		status.Queued[w.owner]++
+	}
+	return status
+}
+
+func (s *LaunchScheduler) releaseFunc(owner string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { s.release(owner) })
+	}
+}
+
+func (s *LaunchScheduler) release(owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running--
+	s.active[owner]--
+	if s.active[owner] <= 0 {
+		delete(s.active, owner)
+	}
+	s.dispatchLocked()
+}
+
+// limitLocked returns the number of sessions currently allowed to run
+func (s *LaunchScheduler) limitLocked() int {
+	limit := s.maxConcurrent
+	if limit == 0 {
+		limit = math.MaxInt
+	}
+	if s.throttle > 0 && s.throttle < limit {
+		limit = s.throttle
+	}
+	return limit
+}
+
+func (s *LaunchScheduler) grantLocked(owner string) {
+	s.running++
+	s.active[owner]++
+}
+
+// dispatchLocked hands free slots to queued launches according to the policy
+func (s *LaunchScheduler) dispatchLocked() {
+	for s.running < s.limitLocked() && len(s.waiters) > 0 {
+		idx := 0
+		for i, w := range s.waiters {
+			next := s.waiters[idx]
+			switch {
+			case w.priority != next.priority:
+				if w.priority > next.priority {
+					idx = i
+				}
+			case s.policy == SchedulingFair && s.active[w.owner] < s.active[next.owner]:
+				idx = i
+			}
+		}
+		w := s.waiters[idx]
+		s.waiters = append(s.waiters[:idx], s.waiters[idx+1:]...)
+		s.grantLocked(w.owner)
+		close(w.ready)
+	}
+}
+
+func (s *LaunchScheduler) removeWaiterLocked(target *launchWaiter) {
+	for i, w := range s.waiters {
+		if w == target {
+			s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queueLaunch starts an Acquire for owner in the background and waits until
+// it is queued, so arrival order is deterministic
+func queueLaunch(t *testing.T, s *LaunchScheduler, owner string, priority int, granted chan<- string) {
+	t.Helper()
+	before := s.Status().Queued[owner]
+	go func() {
+		if _, err := s.Acquire(context.Background(), owner, priority); err == nil {
+			granted <- owner
+		}
+	}()
+	require.Eventually(t, func() bool { return s.Status().Queued[owner] == before+1 }, time.Second, time.Millisecond)
+}
+
+func TestLaunchScheduler(t *testing.T) {
+	t.Run("fair policy schedules a second owner behind a flood", func(t *testing.T) {
+		s := NewLaunchScheduler(2, SchedulingFair)
+
+		releaseA1, err := s.Acquire(context.Background(), "alice", 0)
+		require.NoError(t, err)
+		_, err = s.Acquire(context.Background(), "alice", 0)
+		require.NoError(t, err)
+
+		granted := make(chan string, 10)
+		for i := 0; i < 5; i++ {
+			queueLaunch(t, s, "alice", 0, granted)
+		}
+		queueLaunch(t, s, "bob", 0, granted)
+
+		status := s.Status()
+		assert.Equal(t, 2, status.Active["alice"])
+		assert.Equal(t, 5, status.Queued["alice"])
+		assert.Equal(t, 1, status.Queued["bob"])
+
+		// The first free slot goes to bob even though alice queued first
+		releaseA1()
+		select {
+		case owner := <-granted:
+			assert.Equal(t, "bob", owner)
+		case <-time.After(time.Second):
+			t.Fatal("no launch was scheduled")
+		}
+		assert.Equal(t, 1, s.Status().Active["bob"])
+	})
+
+	t.Run("fifo policy schedules in arrival order", func(t *testing.T) {
+		s := NewLaunchScheduler(1, SchedulingFIFO)
+
+		release, err := s.Acquire(context.Background(), "alice", 0)
+		require.NoError(t, err)
+
+		granted := make(chan string, 10)
+		queueLaunch(t, s, "alice", 0, granted)
+		queueLaunch(t, s, "bob", 0, granted)
+
+		release()
+		select {
+		case owner := <-granted:
+			assert.Equal(t, "alice", owner)
+		case <-time.After(time.Second):
+			t.Fatal("no launch was scheduled")
+		}
+	})
+
+	t.Run("higher priority launches go first", func(t *testing.T) {
+		s := NewLaunchScheduler(1, SchedulingFair)
+
+		release, err := s.Acquire(context.Background(), "alice", 0)
+		require.NoError(t, err)
+
+		granted := make(chan string, 10)
+		queueLaunch(t, s, "p1", 1, granted)
+		queueLaunch(t, s, "p5", 5, granted)
+		queueLaunch(t, s, "p3", 3, granted)
+
+		release()
+		for _, want := range []string{"p5", "p3", "p1"} {
+			select {
+			case owner := <-granted:
+				assert.Equal(t, want, owner)
+				// Free the slot for the next launch
+				s.release(owner)
+			case <-time.After(time.Second):
+				t.Fatal("no launch was scheduled")
+			}
+		}
+	})
+
+	t.Run("release is idempotent and empty owner uses default", func(t *testing.T) {
+		s := NewLaunchScheduler(1, SchedulingFair)
+
+		release, err := s.Acquire(context.Background(), "", 0)
+		require.NoError(t, err)
+		assert.Equal(t, 1, s.Status().Active[DefaultOwner])
+
+		release()
+		release()
+		status := s.Status()
+		assert.Equal(t, 0, status.Running)
+		assert.Empty(t, status.Active)
+	})
+
+	t.Run("cancelled waiter leaves the queue", func(t *testing.T) {
+		s := NewLaunchScheduler(1, SchedulingFair)
+		_, err := s.Acquire(context.Background(), "alice", 0)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = s.Acquire(ctx, "bob", 0)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Empty(t, s.Status().Queued)
+	})
+}
+
+func TestParseSchedulingPolicy(t *testing.T) {
+	policy, err := ParseSchedulingPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, SchedulingFair, policy)
+
+	policy, err = ParseSchedulingPolicy("fifo")
+	require.NoError(t, err)
+	assert.Equal(t, SchedulingFIFO, policy)
+
+	_, err = ParseSchedulingPolicy("lottery")
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package session
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+
+	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
+)
+
+// BuiltinTools are the tools Claude Code provides without any MCP server
+var BuiltinTools = []string{
+	"Bash",
+	"BashOutput",
+	"Edit",
+	"ExitPlanMode",
+	"Glob",
+	"Grep",
+	"KillShell",
+	"MultiEdit",
+	"NotebookEdit",
+	"Read",
+	"SlashCommand",
+	"Task",
+	"TodoWrite",
+	"WebFetch",
+	"WebSearch",
+	"Write",
+}
+
+// codelayerMCPServer is the MCP server the daemon injects into every session
+const codelayerMCPServer = "codelayer"
+
+// Tool sources
+const (
+	ToolSourceBuiltin = "builtin"
+	ToolSourceMCP     = "mcp"
+)
+
+// ToolInfo describes a tool, or an MCP server's tools, a session can be
+// allowed to use
+type ToolInfo struct {
+	Name      string `json:"name"`             // Tool name, or mcp__<server> for all of a server's tools
+	Source    string `json:"source"`           // ToolSourceBuiltin or ToolSourceMCP
+	Available bool   `json:"available"`        // False if the MCP server can't be started
+	Reason    string `json:"reason,omitempty"` // Why an MCP server is unavailable
+}
+
+// UnavailableToolsError is returned when a launch allows tools that don't
+// exist or whose MCP server can't be started
+type UnavailableToolsError struct {
+	Missing   []string          // Allowed tools that no built-in or configured MCP server provides
+	Unhealthy map[string]string // Allowed tool -> why its MCP server is unavailable
+}
+
+func (e *UnavailableToolsError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "unknown allowed tools: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unhealthy) > 0 {
+		tools := make([]string, 0, len(e.Unhealthy))
+		for tool := range e.Unhealthy {
+			tools = append(tools, tool)
+		}
+		sort.Strings(tools)
+		for i, tool := range tools {
+			tools[i] = fmt.Sprintf("%s (%s)", tool, e.Unhealthy[tool])
+		}
+		parts = append(parts, "unavailable allowed tools: "+strings.Join(tools, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ListTools returns the built-in tools followed by one entry per MCP server
+// in mcpConfig, including the daemon's own. MCP servers are checked without
+// being started, so their individual tools aren't listed.
+func ListTools(mcpConfig *claudecode.MCPConfig) []ToolInfo {
+	tools := make([]ToolInfo, 0, len(BuiltinTools)+1)
+	for _, name := range BuiltinTools {
+		tools = append(tools, ToolInfo{Name: name, Source: ToolSourceBuiltin, Available: true})
+	}
+
+	servers := map[string]string{codelayerMCPServer: ""}
+	if mcpConfig != nil {
+		for name, server := range mcpConfig.MCPServers {
+			if name != codelayerMCPServer {
+				servers[name] = checkMCPServer(server)
+			}
+		}
+	}
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tools = append(tools, ToolInfo{
+			Name:      "mcp__" + name,
+			Source:    ToolSourceMCP,
+			Available: servers[name] == "",
+			Reason:    servers[name],
+		})
+	}
+	return tools
+}
+
+// CheckToolAvailability verifies that every allowed tool is a built-in tool
+// or belongs to a configured MCP server that can be started. Permission rules
+// such as Bash(git:*) are checked by their tool name.
+func CheckToolAvailability(allowedTools []string, mcpConfig *claudecode.MCPConfig) error {
+	if len(allowedTools) == 0 {
+		return nil
+	}
+
+	available := make(map[string]ToolInfo)
+	for _, tool := range ListTools(mcpConfig) {
+		available[tool.Name] = tool
+	}
+
+	var unavailable UnavailableToolsError
+	for _, allowed := range allowedTools {
+		name := allowed
+		if i := strings.Index(name, "("); i >= 0 {
+			name = name[:i]
+		}
+		name = strings.TrimSpace(name)
+		if strings.HasPrefix(name, "mcp__") {
+			// mcp__<server> and mcp__<server>__<tool> both need the server
+			name = "mcp__" + strings.SplitN(strings.TrimPrefix(name, "mcp__"), "__", 2)[0]
+		}
+
+		tool, ok := available[name]
+		switch {
+		case !ok:
+			unavailable.Missing = append(unavailable.Missing, allowed)
+		case !tool.Available:
+			if unavailable.Unhealthy == nil {
+				unavailable.Unhealthy = make(map[string]string)
+			}
+			unavailable.Unhealthy[allowed] = tool.Reason
+		}
+	}
+
+	if len(unavailable.Missing) > 0 || len(unavailable.Unhealthy) > 0 {
+		return &unavailable
+	}
+	return nil
+}
+
+// checkMCPServer returns why an MCP server can't be started, or "" if it
+// looks usable: a stdio server's command must be on PATH and an HTTP
+// server's URL must be valid
+func checkMCPServer(server claudecode.MCPServer) string {
+	if server.Type == "http" || server.URL != "" {
+		u, err := url.Parse(server.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Sprintf("invalid server URL %q", server.URL)
+		}
+		return ""
+	}
+	if server.Command == "" {
+		return "no command configured"
+	}
+	if _, err := exec.LookPath(server.Command); err != nil {
+		return fmt.Sprintf("command %q not found", server.Command)
+	}
+	return ""
+}
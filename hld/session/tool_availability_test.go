@@ -0,0 +1,76 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckToolAvailability(t *testing.T) {
+	mcpConfig := &claudecode.MCPConfig{
+		MCPServers: map[string]claudecode.MCPServer{
+			"shell":  {Command: "sh"},
+			"broken": {Command: "definitely-not-a-real-mcp-server-binary"},
+			"docs":   {Type: "http", URL: "https://docs.example.com/mcp"},
+			"badurl": {Type: "http", URL: "not a url"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		allowed       []string
+		wantMissing   []string
+		wantUnhealthy []string
+	}{
+		{name: "no allowed tools", allowed: nil},
+		{
+			name:    "builtin tools and permission rules",
+			allowed: []string{"Read", "Edit", "Bash(git status:*)", "WebFetch(domain:example.com)"},
+		},
+		{
+			name:    "tools of configured and injected MCP servers",
+			allowed: []string{"mcp__shell", "mcp__docs__search", "mcp__codelayer__request_permission"},
+		},
+		{
+			name:        "unknown tools",
+			allowed:     []string{"Read", "Frobnicate", "mcp__nope__tool"},
+			wantMissing: []string{"Frobnicate", "mcp__nope__tool"},
+		},
+		{
+			name:          "servers that can't start",
+			allowed:       []string{"mcp__broken__run", "mcp__badurl"},
+			wantUnhealthy: []string{"mcp__broken__run", "mcp__badurl"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckToolAvailability(tt.allowed, mcpConfig)
+			if tt.wantMissing == nil && tt.wantUnhealthy == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var unavailable *UnavailableToolsError
+			require.True(t, errors.As(err, &unavailable), "expected UnavailableToolsError, got %v", err)
+			assert.Equal(t, tt.wantMissing, unavailable.Missing)
+			assert.Len(t, unavailable.Unhealthy, len(tt.wantUnhealthy))
+			for _, tool := range tt.wantUnhealthy {
+				assert.Contains(t, unavailable.Unhealthy, tool)
+				assert.Contains(t, err.Error(), tool)
+			}
+		})
+	}
+}
+
+func TestListToolsWithoutMCPConfig(t *testing.T) {
+	tools := ListTools(nil)
+	require.Len(t, tools, len(BuiltinTools)+1)
+	last := tools[len(tools)-1]
+	assert.Equal(t, "mcp__codelayer", last.Name)
+	assert.Equal(t, ToolSourceMCP, last.Source)
+	assert.True(t, last.Available)
+}
@@ -0,0 +1,106 @@
+package session
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// DefaultToolCacheTTL is how long a cached tool result stays valid when no TTL
+// is configured
+const DefaultToolCacheTTL = 10 * time.Minute
+
+// toolCacheEntry is a cached tool result
+type toolCacheEntry struct {
+	result   string
+	storedAt time.Time
+}
+
+// ToolResultCache remembers results of tools marked cacheable so identical
+// calls within the TTL can be answered without re-executing the tool. Only
+// tools known to be pure (same input, same output) should be marked cacheable.
+type ToolResultCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	tools   map[string]bool
+	entries map[string]toolCacheEntry
+	now     func() time.Time
+}
+
+// NewToolResultCache creates a cache for the given tool names. A ttl of zero
+// uses DefaultToolCacheTTL.
+func NewToolResultCache(tools []string, ttl time.Duration) *ToolResultCache {
+	if ttl <= 0 {
+		ttl = DefaultToolCacheTTL
+	}
+	c := &ToolResultCache{
+		ttl:     ttl,
+		tools:   make(map[string]bool, len(tools)),
+		entries: make(map[string]toolCacheEntry),
+		now:     time.Now,
+	}
+	for _, tool := range tools {
+		c.tools[tool] = true
+	}
+	return c
+}
+
+// Cacheable reports whether results of toolName may be cached
+func (c *ToolResultCache) Cacheable(toolName string) bool {
+	return c.tools[toolName]
+}
+
+// Lookup returns the cached result for a call, if one is still fresh
+func (c *ToolResultCache) Lookup(toolName, inputJSON string) (string, bool) {
+	if !c.Cacheable(toolName) {
+		return "", false
+	}
+	key := toolCacheKey(toolName, inputJSON)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if c.now().Sub(entry.storedAt) > c.ttl {
+		delete(c.entries, key)
+		return "", false
+	}
+	return entry.result, true
+}
+
+// Store records the result of a call. Calls to non-cacheable tools are ignored.
+func (c *ToolResultCache) Store(toolName, inputJSON, result string) {
+	if !c.Cacheable(toolName) {
+		return
+	}
+	key := toolCacheKey(toolName, inputJSON)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictExpiredLocked()
+	c.entries[key] = toolCacheEntry{result: result, storedAt: c.now()}
+}
+
+func (c *ToolResultCache) evictExpiredLocked() {
+	now := c.now()
+	for key, entry := range c.entries {
+		if now.Sub(entry.storedAt) > c.ttl {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// toolCacheKey builds the cache key for a call. The input is re-encoded so
+// that key order and whitespace differences between the stream and the
+// permission prompt don't produce different keys.
+func toolCacheKey(toolName, inputJSON string) string {
+	var input interface{}
+	if err := json.Unmarshal([]byte(inputJSON), &input); err == nil {
+		if canonical, err := json.Marshal(input); err == nil {
+			inputJSON = string(canonical)
+		}
+	}
+	return toolName + "\x00" + inputJSON
+}
@@ -0,0 +1,101 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolResultCache(t *testing.T) {
+	t.Run("hits ignore input key order and whitespace", func(t *testing.T) {
+		c := NewToolResultCache([]string{"Glob"}, time.Minute)
+		c.Store("Glob", `{"pattern": "*.go", "path": "/src"}`, "a.go")
+
+		result, ok := c.Lookup("Glob", `{"path":"/src","pattern":"*.go"}`)
+		assert.True(t, ok)
+		assert.Equal(t, "a.go", result)
+
+		_, ok = c.Lookup("Glob", `{"path":"/src","pattern":"*.md"}`)
+		assert.False(t, ok)
+	})
+
+	t.Run("non-cacheable tools are never cached", func(t *testing.T) {
+		c := NewToolResultCache([]string{"Glob"}, time.Minute)
+		c.Store("Bash", `{"command":"date"}`, "Mon")
+
+		_, ok := c.Lookup("Bash", `{"command":"date"}`)
+		assert.False(t, ok)
+	})
+
+	t.Run("entries expire after the ttl", func(t *testing.T) {
+		now := time.Now()
+		c := NewToolResultCache([]string{"Glob"}, time.Minute)
+		c.now = func() time.Time { return now }
+		c.Store("Glob", `{}`, "a.go")
+
+		now = now.Add(2 * time.Minute)
+		_, ok := c.Lookup("Glob", `{}`)
+		assert.False(t, ok)
+	})
+}
+
+func TestManagerToolResultCache(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	manager, err := NewManager(nil, sqliteStore, "")
+	require.NoError(t, err)
+	manager.SetToolResultCache(NewToolResultCache([]string{"Glob"}, time.Minute))
+
+	for _, sess := range []*store.Session{
+		{ID: "sess-cache", RunID: "run-cache", ClaudeSessionID: "claude-cache", Status: store.SessionStatusRunning},
+		{ID: "sess-bypass", RunID: "run-bypass", ClaudeSessionID: "claude-bypass", Status: store.SessionStatusRunning, BypassToolCache: true},
+	} {
+		require.NoError(t, sqliteStore.CreateSession(ctx, sess))
+	}
+
+	runTool := func(toolID, result string) {
+		require.NoError(t, manager.applyAssembledEvent(ctx, "sess-cache", "claude-cache", AssembledEvent{
+			Kind: AssembledToolUse, Role: "assistant", ToolID: toolID, ToolName: "Glob", ToolInputJSON: `{"pattern":"*.go"}`,
+		}))
+		require.NoError(t, manager.applyAssembledEvent(ctx, "sess-cache", "claude-cache", AssembledEvent{
+			Kind: AssembledToolResult, Role: "user", ToolResultForID: toolID, ToolResultContent: result,
+		}))
+	}
+
+	input := json.RawMessage(`{"pattern":"*.go"}`)
+	_, ok := manager.CachedToolResult(ctx, "sess-cache", "tool-1", "Glob", input)
+	require.False(t, ok, "nothing is cached before the first call")
+	runTool("tool-1", "a.go")
+
+	result, ok := manager.CachedToolResult(ctx, "sess-cache", "tool-2", "Glob", input)
+	require.True(t, ok)
+	assert.Equal(t, "a.go", result)
+	runTool("tool-2", "Not re-executed: cached result a.go")
+
+	_, ok = manager.CachedToolResult(ctx, "sess-bypass", "tool-3", "Glob", input)
+	assert.False(t, ok, "sessions launched with bypass always execute")
+
+	events, err := sqliteStore.GetSessionConversation(ctx, "sess-cache", store.ConversationPage{})
+	require.NoError(t, err)
+	var hits []bool
+	for _, event := range events {
+		if event.EventType == store.EventTypeToolResult {
+			hits = append(hits, event.ToolCacheHit)
+		}
+	}
+	assert.Equal(t, []bool{false, true}, hits)
+
+	// The deny message Claude reports for a cache hit must not replace the
+	// cached result
+	result, ok = manager.CachedToolResult(ctx, "sess-cache", "tool-4", "Glob", input)
+	require.True(t, ok)
+	assert.Equal(t, "a.go", result)
+}
@@ -0,0 +1,231 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/humanlayer/humanlayer/hld/bus"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// What happens to a session once a tool call exceeds its quota
+const (
+	// QuotaExceededDeny denies the call and lets the session continue, so the
+	// agent can finish with other tools
+	QuotaExceededDeny = "deny"
+	// QuotaExceededStop denies the call and interrupts the session
+	QuotaExceededStop = "stop"
+)
+
+// ToolQuota bounds the number of tool calls a session may make. A zero limit
+// is unlimited, so the zero value preserves the default behavior.
+type ToolQuota struct {
+	MaxToolCalls int            `json:"max_tool_calls,omitempty"` // Across all tools
+	PerTool      map[string]int `json:"per_tool,omitempty"`       // Tool name -> max calls of that tool
+	OnExceeded   string         `json:"on_exceeded,omitempty"`    // QuotaExceededDeny (default) or QuotaExceededStop
+}
+
+// Validate rejects negative limits and unknown actions
+func (q *ToolQuota) Validate() error {
+	if q.MaxToolCalls < 0 {
+		return fmt.Errorf("max_tool_calls must not be negative")
+	}
+	for tool, limit := range q.PerTool {
+		if limit < 0 {
+			return fmt.Errorf("per_tool limit for %s must not be negative", tool)
+		}
+	}
+	switch q.OnExceeded {
+	case "", QuotaExceededDeny, QuotaExceededStop:
+		return nil
+	default:
+		return fmt.Errorf("unknown on_exceeded %q (must be %s or %s)", q.OnExceeded, QuotaExceededDeny, QuotaExceededStop)
+	}
+}
+
+// IsUnlimited reports whether the quota sets no limits at all
+func (q *ToolQuota) IsUnlimited() bool {
+	if q == nil {
+		return true
+	}
+	if q.MaxToolCalls > 0 {
+		return false
+	}
+	for _, limit := range q.PerTool {
+		if limit > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseToolQuota decodes a session's stored quota, returning nil if it has none
+func ParseToolQuota(encoded string) (*ToolQuota, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	var quota ToolQuota
+	if err := json.Unmarshal([]byte(encoded), &quota); err != nil {
+		return nil, fmt.Errorf("invalid tool quota: %w", err)
+	}
+	return &quota, nil
+}
+
+// encodeToolQuota encodes a quota for storage. Unlimited quotas are stored empty.
+func encodeToolQuota(quota *ToolQuota) (string, error) {
+	if quota.IsUnlimited() {
+		return "", nil
+	}
+	encoded, err := json.Marshal(quota)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode tool quota: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// ToolQuotaLimit is the usage of a single quota limit
+type ToolQuotaLimit struct {
+	Limit     int `json:"limit"`
+	Used      int `json:"used"`
+	Remaining int `json:"remaining"`
+}
+
+// ToolQuotaStatus is the remaining quota of a session
+type ToolQuotaStatus struct {
+	Total      *ToolQuotaLimit           `json:"total,omitempty"`    // Set when max_tool_calls is limited
+	PerTool    map[string]ToolQuotaLimit `json:"per_tool,omitempty"` // Limited tools only
+	OnExceeded string                    `json:"on_exceeded"`
+}
+
+// ComputeToolQuotaStatus tallies a session's approvals against its quota.
+// Every call that reached the permission check counts except denied ones,
+// which never ran.
+func ComputeToolQuotaStatus(quota *ToolQuota, approvals []*store.Approval) *ToolQuotaStatus {
+	if quota.IsUnlimited() {
+		return nil
+	}
+
+	total := 0
+	perTool := make(map[string]int)
+	for _, a := range approvals {
+		if a.Status == store.ApprovalStatusLocalDenied {
+			continue
+		}
+		total++
+		perTool[a.ToolName]++
+	}
+
+	limit := func(allowed, used int) ToolQuotaLimit {
+		return ToolQuotaLimit{Limit: allowed, Used: used, Remaining: max(0, allowed-used)}
+	}
+
+	status := &ToolQuotaStatus{OnExceeded: quota.OnExceeded}
+	if status.OnExceeded == "" {
+		status.OnExceeded = QuotaExceededDeny
+	}
+	if quota.MaxToolCalls > 0 {
+		l := limit(quota.MaxToolCalls, total)
+		status.Total = &l
+	}
+	for tool, allowed := range quota.PerTool {
+		if allowed <= 0 {
+			continue
+		}
+		if status.PerTool == nil {
+			status.PerTool = make(map[string]ToolQuotaLimit)
+		}
+		status.PerTool[tool] = limit(allowed, perTool[tool])
+	}
+	return status
+}
+
+// exceededBy returns a feedback message if one more call of toolName would
+// exceed the quota, or "" if the call is within it
+func (s *ToolQuotaStatus) exceededBy(toolName string) string {
+	if s == nil {
+		return ""
+	}
+	if l, ok := s.PerTool[toolName]; ok && l.Remaining == 0 {
+		return fmt.Sprintf("Tool call denied: this session's quota of %d %s calls has been used. Finish the task without calling %s again.", l.Limit, toolName, toolName)
+	}
+	if s.Total != nil && s.Total.Remaining == 0 {
+		return fmt.Sprintf("Tool call denied: this session's quota of %d tool calls has been used. Finish the task without calling more tools.", s.Total.Limit)
+	}
+	return ""
+}
+
+// CheckToolQuota decides whether a tool call that is about to run fits within
+// the session's quota. When it doesn't, it returns the feedback message to
+// deny the call with, records the attempt and, if the quota says so, stops the
+// session. Sessions without a quota always pass.
+func (m *Manager) CheckToolQuota(ctx context.Context, sessionID, toolUseID, toolName string) (string, bool) {
+	sess, err := m.store.GetSession(ctx, sessionID)
+	if err != nil || sess.ToolQuota == "" {
+		return "", false
+	}
+	quota, err := ParseToolQuota(sess.ToolQuota)
+	if err != nil {
+		slog.Error("ignoring unreadable tool quota", "session_id", sessionID, "error", err)
+		return "", false
+	}
+
+	approvals, err := m.store.GetSessionApprovals(ctx, sessionID)
+	if err != nil {
+		// Fail open: a quota is a guard against runaway use, not a permission
+		slog.Error("failed to count tool calls for quota", "session_id", sessionID, "error", err)
+		return "", false
+	}
+	message := ComputeToolQuotaStatus(quota, approvals).exceededBy(toolName)
+	if message == "" {
+		return "", false
+	}
+
+	slog.Info("tool call exceeds quota",
+		"session_id", sessionID,
+		"tool_use_id", toolUseID,
+		"tool_name", toolName,
+		"on_exceeded", quota.OnExceeded)
+	m.recordQuotaDenial(ctx, sess, toolUseID, toolName, message)
+
+	if quota.OnExceeded == QuotaExceededStop {
+		// Interrupt after the denial has been returned to the agent
+		go func() {
+			if err := m.InterruptSession(context.Background(), sessionID); err != nil {
+				slog.Error("failed to stop session after tool quota exceeded", "session_id", sessionID, "error", err)
+			}
+		}()
+	}
+	return message, true
+}
+
+// recordQuotaDenial stores a quota-denied attempt in the conversation and
+// publishes it
+func (m *Manager) recordQuotaDenial(ctx context.Context, sess *store.Session, toolUseID, toolName, message string) {
+	if sess.ClaudeSessionID != "" {
+		event := &store.ConversationEvent{
+			SessionID:       sess.ID,
+			ClaudeSessionID: sess.ClaudeSessionID,
+			EventType:       store.EventTypeSystem,
+			Role:            "system",
+			Content:         message,
+		}
+		if err := m.store.AddConversationEvent(ctx, event); err != nil {
+			slog.Error("failed to record quota denial", "session_id", sess.ID, "error", err)
+		}
+	}
+
+	if m.eventBus != nil {
+		m.eventBus.Publish(bus.Event{
+			Type: bus.EventToolQuotaExceeded,
+			Data: map[string]interface{}{
+				"session_id":  sess.ID,
+				"run_id":      sess.RunID,
+				"tool_name":   toolName,
+				"tool_use_id": toolUseID,
+				"message":     message,
+			},
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/bus"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolQuota(t *testing.T) {
+	approvals := func(statuses map[string][]store.ApprovalStatus) []*store.Approval {
+		var out []*store.Approval
+		for tool, list := range statuses {
+			for _, status := range list {
+				out = append(out, &store.Approval{ToolName: tool, Status: status})
+			}
+		}
+		return out
+	}
+
+	t.Run("unlimited by default", func(t *testing.T) {
+		assert.True(t, (*ToolQuota)(nil).IsUnlimited())
+		assert.True(t, (&ToolQuota{PerTool: map[string]int{"Bash": 0}}).IsUnlimited())
+		assert.Nil(t, ComputeToolQuotaStatus(&ToolQuota{}, nil))
+
+		encoded, err := encodeToolQuota(&ToolQuota{OnExceeded: QuotaExceededStop})
+		require.NoError(t, err)
+		assert.Empty(t, encoded)
+	})
+
+	t.Run("denied calls don't count", func(t *testing.T) {
+		status := ComputeToolQuotaStatus(&ToolQuota{MaxToolCalls: 5, PerTool: map[string]int{"Bash": 2}}, approvals(map[string][]store.ApprovalStatus{
+			"Bash": {store.ApprovalStatusLocalApproved, store.ApprovalStatusLocalDenied},
+			"Read": {store.ApprovalStatusLocalPending},
+		}))
+		require.NotNil(t, status)
+		assert.Equal(t, &ToolQuotaLimit{Limit: 5, Used: 2, Remaining: 3}, status.Total)
+		assert.Equal(t, ToolQuotaLimit{Limit: 2, Used: 1, Remaining: 1}, status.PerTool["Bash"])
+		assert.Equal(t, QuotaExceededDeny, status.OnExceeded)
+		assert.Empty(t, status.exceededBy("Bash"))
+	})
+
+	t.Run("per-tool and overall limits", func(t *testing.T) {
+		status := ComputeToolQuotaStatus(&ToolQuota{MaxToolCalls: 3, PerTool: map[string]int{"Bash": 1}}, approvals(map[string][]store.ApprovalStatus{
+			"Bash": {store.ApprovalStatusLocalApproved},
+		}))
+		assert.Contains(t, status.exceededBy("Bash"), "quota of 1 Bash calls")
+		assert.Empty(t, status.exceededBy("Read"))
+
+		status = ComputeToolQuotaStatus(&ToolQuota{MaxToolCalls: 1}, approvals(map[string][]store.ApprovalStatus{
+			"Read": {store.ApprovalStatusLocalApproved},
+		}))
+		assert.Contains(t, status.exceededBy("Grep"), "quota of 1 tool calls")
+	})
+
+	t.Run("rejects invalid quotas", func(t *testing.T) {
+		assert.Error(t, (&ToolQuota{MaxToolCalls: -1}).Validate())
+		assert.Error(t, (&ToolQuota{PerTool: map[string]int{"Bash": -1}}).Validate())
+		assert.Error(t, (&ToolQuota{OnExceeded: "explode"}).Validate())
+		assert.NoError(t, (&ToolQuota{MaxToolCalls: 1, OnExceeded: QuotaExceededStop}).Validate())
+	})
+}
+
+func TestManagerCheckToolQuota(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	eventBus := bus.NewEventBus()
+	manager, err := NewManager(eventBus, sqliteStore, "")
+	require.NoError(t, err)
+
+	encoded, err := encodeToolQuota(&ToolQuota{PerTool: map[string]int{"Bash": 1}})
+	require.NoError(t, err)
+	for _, sess := range []*store.Session{
+		{ID: "limited", RunID: "run-limited", ClaudeSessionID: "claude-limited", ToolQuota: encoded},
+		{ID: "unlimited", RunID: "run-unlimited", ClaudeSessionID: "claude-unlimited"},
+	} {
+		sess.Status = store.SessionStatusRunning
+		sess.CreatedAt = time.Now()
+		sess.LastActivityAt = time.Now()
+		require.NoError(t, sqliteStore.CreateSession(ctx, sess))
+		require.NoError(t, sqliteStore.CreateApproval(ctx, &store.Approval{
+			ID:        "approval-" + sess.ID,
+			RunID:     sess.RunID,
+			SessionID: sess.ID,
+			Status:    store.ApprovalStatusLocalApproved,
+			CreatedAt: time.Now(),
+			ToolName:  "Bash",
+			ToolInput: []byte(`{}`),
+		}))
+	}
+
+	sub := eventBus.Subscribe(ctx, bus.EventFilter{Types: []bus.EventType{bus.EventToolQuotaExceeded}})
+
+	message, denied := manager.CheckToolQuota(ctx, "limited", "tu-2", "Bash")
+	assert.True(t, denied)
+	assert.Contains(t, message, "quota of 1 Bash calls")
+
+	_, denied = manager.CheckToolQuota(ctx, "limited", "tu-3", "Read")
+	assert.False(t, denied, "other tools aren't limited")
+
+	_, denied = manager.CheckToolQuota(ctx, "unlimited", "tu-4", "Bash")
+	assert.False(t, denied, "sessions without a quota are unlimited")
+
+	// The denied attempt is recorded in the conversation and published
+	events, err := sqliteStore.GetConversation(ctx, "claude-limited", store.ConversationPage{})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, store.EventTypeSystem, events[0].EventType)
+	assert.Equal(t, message, events[0].Content)
+
+	select {
+	case event := <-sub.Channel:
+		assert.Equal(t, "limited", event.Data["session_id"])
+		assert.Equal(t, "tu-2", event.Data["tool_use_id"])
+	case <-time.After(time.Second):
+		t.Fatal("expected a tool_quota_exceeded event")
+	}
+}
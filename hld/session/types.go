@@ -2,6 +2,7 @@ package session
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
@@ -17,7 +18,8 @@ type ApprovalReconciler interface {
 type Status string
 
 const (
-	StatusDraft        Status = "draft" // Session in configuration state
+	StatusDraft        Status = "draft"  // Session in configuration state
+	StatusQueued       Status = "queued" // Session is waiting for a launch slot
 	StatusStarting     Status = "starting"
 	StatusRunning      Status = "running"
 	StatusCompleted    Status = "completed"
@@ -26,6 +28,7 @@ const (
 	StatusInterrupted  Status = "interrupted"   // Session was interrupted but can be resumed
 	StatusWaitingInput Status = "waiting_input" // Session is waiting for tool approval input
 	StatusDiscarded    Status = "discarded"     // Draft session was discarded by the user
+	StatusCancelled    Status = "cancelled"     // Session was stopped by the user and can't be resumed
 )
 
 // Session represents a Claude Code session managed by the daemon
@@ -67,17 +70,22 @@ type Info struct {
 	ProxyBaseURL                        string             `json:"proxy_base_url,omitempty"`
 	ProxyModelOverride                  string             `json:"proxy_model_override,omitempty"`
 	ProxyAPIKey                         string             `json:"proxy_api_key,omitempty"`
+	Imported                            bool               `json:"imported,omitempty"`
 }
 
 // LaunchSessionConfig contains the configuration for launching a new session
 type LaunchSessionConfig struct {
 	claudecode.SessionConfig
 	// Daemon-level settings that don't get passed to Claude Code
-	Title                             string // Session title (optional)
-	AutoAcceptEdits                   bool   // Auto-accept edit tools
-	DangerouslySkipPermissions        bool   // Whether to auto-approve all tools
-	DangerouslySkipPermissionsTimeout *int64 // Optional timeout in milliseconds
-	CreateDirectoryIfNotExists        bool   // Create working directory if it doesn't exist
+	Title                             string     // Session title (optional)
+	AutoAcceptEdits                   bool       // Auto-accept edit tools
+	DangerouslySkipPermissions        bool       // Whether to auto-approve all tools
+	DangerouslySkipPermissionsTimeout *int64     // Optional timeout in milliseconds
+	CreateDirectoryIfNotExists        bool       // Create working directory if it doesn't exist
+	Owner                             string     // Owner the session is launched for (defaults to DefaultOwner)
+	BypassToolCache                   bool       // Always execute tools, ignoring cached results
+	ToolQuota                         *ToolQuota // Optional limit on tool calls, nil for unlimited
+	Priority                          int        // Launch priority when waiting for a slot; higher starts first
 	// Proxy configuration
 	ProxyEnabled       bool   // Whether proxy is enabled
 	ProxyBaseURL       string // Proxy base URL
@@ -131,6 +139,9 @@ type SessionManager interface {
 	// InterruptSession interrupts a running session
 	InterruptSession(ctx context.Context, sessionID string) error
 
+	// CancelSession stops a started session for good
+	CancelSession(ctx context.Context, sessionID string) error
+
 	// LaunchDraftSession launches a draft session by transitioning it to running state
 	LaunchDraftSession(ctx context.Context, sessionID string, prompt string, createDirectoryIfNotExists bool) error
 
@@ -157,6 +168,12 @@ type SessionManager interface {
 
 	// GetClaudeVersion returns the Claude binary version if available
 	GetClaudeVersion() (string, error)
+
+	// CachedToolResult returns a cached result for a cacheable tool call
+	CachedToolResult(ctx context.Context, sessionID, toolUseID, toolName string, input json.RawMessage) (string, bool)
+
+	// CheckToolQuota returns a denial message for a tool call that exceeds the session's quota
+	CheckToolQuota(ctx context.Context, sessionID, toolUseID, toolName string) (string, bool)
 }
 
 // ReadToolResult represents the JSON structure of a Read tool result
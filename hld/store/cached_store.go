@@ -0,0 +1,232 @@
+package store
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// CachedConversationStore is a ConversationStore that serves GetSession from
+// a bounded in-memory cache. Entries are evicted least recently used first
+// once the cache is full and re-fetched once they are older than the TTL.
+// Writes that go through the cache invalidate the session they touch; writes
+// made directly against the underlying store are only picked up after the TTL.
+type CachedConversationStore struct {
+	ConversationStore
+
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+	reads   map[string]*sessionRead // GetSession calls reading through, by session ID
+}
+
+// sessionRead tracks the GetSession calls reading a session from the
+// underlying store. Invalidating the session bumps generation, so a read
+// that started before the invalidation doesn't cache what it read.
+type sessionRead struct {
+	readers    int
+	generation uint64
+}
+
+// cachedSession is the value held by each element of CachedConversationStore.order
+type cachedSession struct {
+	sessionID string
+	session   *Session
+	fetchedAt time.Time
+}
+
+// NewCachedConversationStore wraps store with a GetSession cache holding up
+// to capacity sessions for at most ttl each. A capacity below one disables
+// caching; a ttl of zero keeps entries until they are evicted or invalidated.
+func NewCachedConversationStore(store ConversationStore, capacity int, ttl time.Duration) *CachedConversationStore {
+	return &CachedConversationStore{
+		ConversationStore: store,
+		capacity:          capacity,
+		ttl:               ttl,
+		now:               time.Now,
+		order:             list.New(),
+		entries:           make(map[string]*list.Element),
+		reads:             make(map[string]*sessionRead),
+	}
+}
+
+// GetSession returns the cached session when it is fresh and reads through to
+// the underlying store otherwise. Callers get their own copy, so modifying
+// the result doesn't change what later callers see.
+func (c *CachedConversationStore) GetSession(ctx context.Context, sessionID string) (*Session, error) {
+	if session, ok := c.lookup(sessionID); ok {
+		return session, nil
+	}
+
+	generation := c.beginRead(sessionID)
+	session, err := c.ConversationStore.GetSession(ctx, sessionID)
+	if err != nil {
+		c.endRead(sessionID, generation, nil)
+		return nil, err
+	}
+	c.endRead(sessionID, generation, session)
+	copied := *session
+	return &copied, nil
+}
+
+// beginRead registers a read of sessionID from the underlying store and
+// returns the session's current generation
+func (c *CachedConversationStore) beginRead(sessionID string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	read, ok := c.reads[sessionID]
+	if !ok {
+		read = &sessionRead{}
+		c.reads[sessionID] = read
+	}
+	read.readers++
+	return read.generation
+}
+
+// endRead finishes a read started by beginRead and caches session unless it
+// is nil or the session was invalidated while it was being read
+func (c *CachedConversationStore) endRead(sessionID string, generation uint64, session *Session) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	read := c.reads[sessionID]
+	read.readers--
+	if read.readers == 0 {
+		delete(c.reads, sessionID)
+	}
+	if session != nil && read.generation == generation {
+		c.insertLocked(sessionID, session)
+	}
+}
+
+// lookup returns a copy of the cached session, dropping it if it has expired
+func (c *CachedConversationStore) lookup(sessionID string) (*Session, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[sessionID]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cachedSession)
+	if c.ttl > 0 && c.now().Sub(entry.fetchedAt) >= c.ttl {
+		c.removeElement(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	copied := *entry.session
+	return &copied, true
+}
+
+// insertLocked caches session, evicting the least recently used entries to
+// make room. Callers must hold c.mu.
+func (c *CachedConversationStore) insertLocked(sessionID string, session *Session) {
+	if c.capacity < 1 {
+		return
+	}
+	copied := *session
+
+	if elem, ok := c.entries[sessionID]; ok {
+		entry := elem.Value.(*cachedSession)
+		entry.session = &copied
+		entry.fetchedAt = c.now()
+		c.order.MoveToFront(elem)
+		return
+	}
+	for c.order.Len() >= c.capacity {
+		c.removeElement(c.order.Back())
+	}
+	c.entries[sessionID] = c.order.PushFront(&cachedSession{
+		sessionID: sessionID,
+		session:   &copied,
+		fetchedAt: c.now(),
+	})
+}
+
+// removeElement drops elem from the cache. Callers must hold c.mu.
+func (c *CachedConversationStore) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cachedSession).sessionID)
+}
+
+// Invalidate drops sessionID from the cache so the next GetSession reads it
+// from the underlying store. Reads already in flight don't cache their result.
+func (c *CachedConversationStore) Invalidate(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[sessionID]; ok {
+		c.removeElement(elem)
+	}
+	if read, ok := c.reads[sessionID]; ok {
+		read.generation++
+	}
+}
+
+// Purge empties the cache
+func (c *CachedConversationStore) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	for _, read := range c.reads {
+		read.generation++
+	}
+}
+
+// Unwrap returns the underlying store
+func (c *CachedConversationStore) Unwrap() ConversationStore {
+	return c.ConversationStore
+}
+
+// UpdateSession updates the session and invalidates its cache entry
+func (c *CachedConversationStore) UpdateSession(ctx context.Context, sessionID string, updates SessionUpdate) error {
+	defer c.Invalidate(sessionID)
+	return c.ConversationStore.UpdateSession(ctx, sessionID, updates)
+}
+
+// HardDeleteSession deletes the session and invalidates its cache entry
+func (c *CachedConversationStore) HardDeleteSession(ctx context.Context, sessionID string) error {
+	defer c.Invalidate(sessionID)
+	return c.ConversationStore.HardDeleteSession(ctx, sessionID)
+}
+
+// DeleteSessionData deletes the session's data and invalidates its cache entry
+func (c *CachedConversationStore) DeleteSessionData(ctx context.Context, sessionID string) error {
+	defer c.Invalidate(sessionID)
+	return c.ConversationStore.DeleteSessionData(ctx, sessionID)
+}
+
+// SoftDeleteSession hides the session and invalidates its cache entry
+func (c *CachedConversationStore) SoftDeleteSession(ctx context.Context, sessionID string) error {
+	defer c.Invalidate(sessionID)
+	return c.ConversationStore.SoftDeleteSession(ctx, sessionID)
+}
+
+// RestoreSession restores the session and invalidates its cache entry
+func (c *CachedConversationStore) RestoreSession(ctx context.Context, sessionID string) error {
+	defer c.Invalidate(sessionID)
+	return c.ConversationStore.RestoreSession(ctx, sessionID)
+}
+
+// RestoreArchivedSession restores the archived session and invalidates its
+// cache entry
+func (c *CachedConversationStore) RestoreArchivedSession(ctx context.Context, sessionID string) error {
+	defer c.Invalidate(sessionID)
+	return c.ConversationStore.RestoreArchivedSession(ctx, sessionID)
+}
+
+// ArchiveSession archives the session and invalidates its cache entry
+func (c *CachedConversationStore) ArchiveSession(ctx context.Context, sessionID string) error {
+	defer c.Invalidate(sessionID)
+	return c.ConversationStore.ArchiveSession(ctx, sessionID)
+}
+
+// VacuumOldEvents can delete any number of sessions, so it empties the cache
+func (c *CachedConversationStore) VacuumOldEvents(ctx context.Context, olderThan time.Time, keepTerminalSessions bool) (int64, int64, error) {
+	defer c.Purge()
+	return c.ConversationStore.VacuumOldEvents(ctx, olderThan, keepTerminalSessions)
+}
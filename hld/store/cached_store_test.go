@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore counts the GetSession calls that reach the underlying store.
+// afterGet, if set, runs once a call has read the session.
+type countingStore struct {
+	ConversationStore
+	gets     map[string]int
+	afterGet func()
+}
+
+func (s *countingStore) GetSession(ctx context.Context, sessionID string) (*Session, error) {
+	s.gets[sessionID]++
+	session, err := s.ConversationStore.GetSession(ctx, sessionID)
+	if s.afterGet != nil {
+		s.afterGet()
+	}
+	return session, err
+}
+
+func newCachedTestStore(t *testing.T, capacity int, ttl time.Duration, sessionIDs ...string) (*CachedConversationStore, *countingStore) {
+	t.Helper()
+	sqliteStore, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqliteStore.Close() })
+
+	ctx := context.Background()
+	for _, id := range sessionIDs {
+		require.NoError(t, sqliteStore.CreateSession(ctx, &Session{
+			ID:        id,
+			RunID:     "run-" + id,
+			Query:     "query " + id,
+			Status:    SessionStatusRunning,
+			CreatedAt: time.Now(),
+		}))
+	}
+
+	counting := &countingStore{ConversationStore: sqliteStore, gets: make(map[string]int)}
+	return NewCachedConversationStore(counting, capacity, ttl), counting
+}
+
+func TestCachedConversationStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("hit avoids the store", func(t *testing.T) {
+		cached, counting := newCachedTestStore(t, 10, time.Minute, "s1")
+
+		first, err := cached.GetSession(ctx, "s1")
+		require.NoError(t, err)
+		first.Query = "modified by caller"
+
+		second, err := cached.GetSession(ctx, "s1")
+		require.NoError(t, err)
+		assert.Equal(t, "query s1", second.Query)
+		assert.Equal(t, 1, counting.gets["s1"])
+	})
+
+	t.Run("evicted entry misses", func(t *testing.T) {
+		cached, counting := newCachedTestStore(t, 2, time.Minute, "s1", "s2", "s3")
+
+		for _, id := range []string{"s1", "s2", "s1", "s3"} {
+			_, err := cached.GetSession(ctx, id)
+			require.NoError(t, err)
+		}
+		// s2 was least recently used when s3 arrived
+		for _, id := range []string{"s1", "s3", "s2"} {
+			_, err := cached.GetSession(ctx, id)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 1, counting.gets["s1"])
+		assert.Equal(t, 2, counting.gets["s2"])
+		assert.Equal(t, 1, counting.gets["s3"])
+	})
+
+	t.Run("expired entry is re-fetched", func(t *testing.T) {
+		cached, counting := newCachedTestStore(t, 10, time.Minute, "s1")
+		now := time.Now()
+		cached.now = func() time.Time { return now }
+
+		_, err := cached.GetSession(ctx, "s1")
+		require.NoError(t, err)
+		now = now.Add(59 * time.Second)
+		_, err = cached.GetSession(ctx, "s1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, counting.gets["s1"])
+
+		now = now.Add(time.Second)
+		_, err = cached.GetSession(ctx, "s1")
+		require.NoError(t, err)
+		assert.Equal(t, 2, counting.gets["s1"])
+	})
+
+	t.Run("writes invalidate", func(t *testing.T) {
+		cached, counting := newCachedTestStore(t, 10, time.Minute, "s1")
+
+		_, err := cached.GetSession(ctx, "s1")
+		require.NoError(t, err)
+		completed := SessionStatusCompleted
+		require.NoError(t, cached.UpdateSession(ctx, "s1", SessionUpdate{Status: &completed}))
+
+		session, err := cached.GetSession(ctx, "s1")
+		require.NoError(t, err)
+		assert.Equal(t, SessionStatusCompleted, session.Status)
+		assert.Equal(t, 2, counting.gets["s1"])
+
+		require.NoError(t, cached.SoftDeleteSession(ctx, "s1"))
+		_, err = cached.GetSession(ctx, "s1")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("read overtaken by a write isn't cached", func(t *testing.T) {
+		cached, counting := newCachedTestStore(t, 10, time.Minute, "s1")
+		completed := SessionStatusCompleted
+		counting.afterGet = func() {
+			// The update lands after the read but before it is cached
+			counting.afterGet = nil
+			require.NoError(t, cached.UpdateSession(ctx, "s1", SessionUpdate{Status: &completed}))
+		}
+
+		stale, err := cached.GetSession(ctx, "s1")
+		require.NoError(t, err)
+		assert.Equal(t, SessionStatusRunning, stale.Status)
+
+		session, err := cached.GetSession(ctx, "s1")
+		require.NoError(t, err)
+		assert.Equal(t, SessionStatusCompleted, session.Status)
+		assert.Equal(t, 2, counting.gets["s1"])
+	})
+}
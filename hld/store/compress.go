@@ -0,0 +1,79 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// gzipMagic starts every gzip stream. No UTF-8 text can start with it,
+	// since 0x8b is a continuation byte, so compressed values are told apart
+	// from plaintext without a marker of their own.
+	gzipMagic = "\x1f\x8b"
+
+	// compressMinBytes is the smallest value worth compressing. gzip adds
+	// about 20 bytes of framing, which short values don't win back.
+	compressMinBytes = 256
+)
+
+// compressValue gzips a value for storage. Values too short to shrink, or
+// that don't, are returned unchanged.
+func compressValue(value string) (string, error) {
+	if len(value) < compressMinBytes {
+		return value, nil
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(value)); err != nil {
+		return "", fmt.Errorf("failed to compress value: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress value: %w", err)
+	}
+	if buf.Len() >= len(value) {
+		return value, nil
+	}
+	return buf.String(), nil
+}
+
+// decompressValue reverses compressValue. Values that aren't compressed are
+// returned unchanged, so rows written by a store without compression, or
+// before it was enabled, read the same.
+func decompressValue(value string) (string, error) {
+	if !isCompressed(value) {
+		return value, nil
+	}
+	r, err := gzip.NewReader(strings.NewReader(value))
+	if err != nil {
+		return "", fmt.Errorf("malformed compressed value: %w", err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// isCompressed reports whether a value was written by compressValue
+func isCompressed(value string) bool {
+	return strings.HasPrefix(value, gzipMagic)
+}
+
+// columnValue returns a value to bind for a column. Compressed values are
+// bound as bytes so SQLite stores them as BLOBs rather than as malformed
+// TEXT.
+func columnValue(value string) interface{} {
+	if isCompressed(value) {
+		return []byte(value)
+	}
+	return value
+}
+
+// compressibleFields lists the event fields large enough to be worth
+// compressing when the store compresses event content
+func (e *ConversationEvent) compressibleFields() []*string {
+	return []*string{&e.Content, &e.ToolInputJSON, &e.ToolResultJSON}
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/humanlayer/humanlayer/hld/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// compressTestJSON returns about 10 kB of tool output shaped JSON
+func compressTestJSON(t *testing.T) string {
+	t.Helper()
+	type entry struct {
+		Path  string `json:"path"`
+		Size  int    `json:"size"`
+		Mode  string `json:"mode"`
+		IsDir bool   `json:"is_dir"`
+	}
+	var entries []entry
+	for i := 0; i < 130; i++ {
+		entries = append(entries, entry{Path: fmt.Sprintf("src/pkg/module%03d/file.go", i), Size: i * 37, Mode: "-rw-r--r--"})
+	}
+	data, err := json.Marshal(map[string]interface{}{"entries": entries})
+	require.NoError(t, err)
+	require.Greater(t, len(data), 10_000)
+	return string(data)
+}
+
+func TestCompressEventContent(t *testing.T) {
+	ctx := context.Background()
+	payload := compressTestJSON(t)
+	opts := DefaultSQLiteOptions()
+	opts.CompressEventContent = true
+
+	t.Run("round trip and savings", func(t *testing.T) {
+		dbPath := testutil.DatabasePath(t, "compressed")
+		s, err := NewSQLiteStoreWithOptions(dbPath, opts)
+		require.NoError(t, err)
+		createEncryptionTestSession(t, s, "sess-1")
+		event := &ConversationEvent{
+			SessionID: "sess-1", ClaudeSessionID: "claude-sess-1",
+			EventType: EventTypeToolCall, ToolID: "t1", ToolName: "Bash",
+			Content: payload, ToolInputJSON: payload, ToolResultJSON: payload,
+		}
+		require.NoError(t, s.AddConversationEvent(ctx, event))
+		require.NoError(t, s.AddConversationEvent(ctx, &ConversationEvent{
+			SessionID: "sess-1", ClaudeSessionID: "claude-sess-1",
+			EventType: EventTypeMessage, Role: "user", Content: "short",
+		}))
+
+		var contentType string
+		var contentBytes, inputBytes, resultBytes int
+		require.NoError(t, s.db.QueryRow(`
+			SELECT typeof(content), length(CAST(content AS BLOB)),
+				length(CAST(tool_input_json AS BLOB)), length(CAST(tool_result_json AS BLOB))
+			FROM conversation_events WHERE id = ?
+		`, event.ID).Scan(&contentType, &contentBytes, &inputBytes, &resultBytes))
+		assert.Equal(t, "blob", contentType)
+		for _, stored := range []int{contentBytes, inputBytes, resultBytes} {
+			savings := 1 - float64(stored)/float64(len(payload))
+			assert.Greater(t, savings, 0.6, "stored %d of %d bytes", stored, len(payload))
+		}
+		require.NoError(t, s.db.QueryRow(`SELECT typeof(content) FROM conversation_events WHERE content = 'short'`).Scan(&contentType))
+		assert.Equal(t, "text", contentType, "short values are stored as-is")
+
+		got, err := s.GetConversation(ctx, "claude-sess-1", ConversationPage{})
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		assert.Equal(t, payload, got[0].Content)
+		assert.Equal(t, payload, got[0].ToolInputJSON)
+		assert.Equal(t, payload, got[0].ToolResultJSON)
+		require.NoError(t, s.Close())
+
+		// A store opened without the flag still reads the compressed rows
+		plain, err := NewSQLiteStore(dbPath)
+		require.NoError(t, err)
+		defer func() { _ = plain.Close() }()
+		read, err := plain.GetEventByPermalink(ctx, event.Permalink)
+		require.NoError(t, err)
+		assert.Equal(t, payload, read.Content)
+		assert.Equal(t, payload, read.ToolInputJSON)
+		assert.Equal(t, payload, read.ToolResultJSON)
+	})
+
+	t.Run("with encryption", func(t *testing.T) {
+		dbPath := testutil.DatabasePath(t, "compressed-encrypted")
+		plain, err := NewSQLiteStoreWithOptions(dbPath, opts)
+		require.NoError(t, err)
+		createEncryptionTestSession(t, plain, "sess-1")
+		require.NoError(t, plain.AddConversationEvent(ctx, &ConversationEvent{
+			SessionID: "sess-1", ClaudeSessionID: "claude-sess-1",
+			EventType: EventTypeMessage, Role: "assistant", Content: payload,
+		}))
+		require.NoError(t, plain.Close())
+
+		// Encrypting existing rows keeps compressed values readable
+		s, err := NewEncryptedSQLiteStore(dbPath, "correct horse")
+		require.NoError(t, err)
+		defer func() { _ = s.Close() }()
+		assertStoredEncrypted(t, s)
+		got, err := s.GetConversation(ctx, "claude-sess-1", ConversationPage{})
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.Equal(t, payload, got[0].Content)
+	})
+}
+
+func TestCompressValue(t *testing.T) {
+	long := strings.Repeat("abc", compressMinBytes)
+	compressed, err := compressValue(long)
+	require.NoError(t, err)
+	assert.True(t, isCompressed(compressed))
+	decompressed, err := decompressValue(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, long, decompressed)
+
+	for _, value := range []string{"", "short", "héllo wörld"} {
+		stored, err := compressValue(value)
+		require.NoError(t, err)
+		assert.Equal(t, value, stored)
+		read, err := decompressValue(value)
+		require.NoError(t, err)
+		assert.Equal(t, value, read)
+	}
+
+	_, err = decompressValue(gzipMagic + "not gzip")
+	assert.Error(t, err)
+}
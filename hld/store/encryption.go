@@ -0,0 +1,371 @@
+package store
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+const (
+	// encryptedPrefix marks a column value written by fieldCipher. Values
+	// without it are plaintext, either from before encryption was enabled or
+	// from a store opened without a key.
+	encryptedPrefix = "enc1:"
+
+	// keyCheckPlaintext is encrypted with the store key on first use so later
+	// opens can tell a wrong key from a right one
+	keyCheckPlaintext = "humanlayer-store-key-check"
+
+	// storeKeyIterations is the PBKDF2 work factor for deriving the store key
+	// from the configured passphrase. It costs roughly 100ms once per open.
+	storeKeyIterations = 200_000
+
+	// encryptBatchSize is the number of plaintext values rewritten per
+	// transaction when encrypting an existing database
+	encryptBatchSize = 500
+)
+
+// ErrInvalidStoreKey is returned when opening an encrypted store with the wrong key
+var ErrInvalidStoreKey = errors.New("invalid database key")
+
+// ErrStoreKeyRequired is returned when opening an encrypted store without a key
+var ErrStoreKeyRequired = errors.New("database is encrypted; a database key is required")
+
+// fieldCipher encrypts individual column values with AES-256-GCM
+type fieldCipher struct {
+	aead cipher.AEAD
+}
+
+// newFieldCipher creates a cipher from a 32-byte key
+func newFieldCipher(key []byte) (*fieldCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return &fieldCipher{aead: aead}, nil
+}
+
+// encrypt seals a value. Empty values are stored as-is so NULL and empty
+// checks in queries keep working.
+func (c *fieldCipher) encrypt(plaintext string) (string, error) {
+	if c == nil || plaintext == "" {
+		return plaintext, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt opens a value written by encrypt. Plaintext values are returned unchanged.
+func (c *fieldCipher) decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	if c == nil {
+		return "", ErrStoreKeyRequired
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// encryptEvent returns a copy of the event with its sensitive fields ready
+// for storage: compressed first, if the store compresses, then encrypted
+func (s *SQLiteStore) encryptEvent(event *ConversationEvent) (*ConversationEvent, error) {
+	sealed := *event
+	if s.compress {
+		for _, field := range sealed.compressibleFields() {
+			var err error
+			if *field, err = compressValue(*field); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, field := range sealed.sensitiveFields() {
+		var err error
+		if *field, err = s.cipher.encrypt(*field); err != nil {
+			return nil, err
+		}
+	}
+	return &sealed, nil
+}
+
+// decryptEvent decrypts and decompresses a scanned event's sensitive fields
+// in place
+func (s *SQLiteStore) decryptEvent(event *ConversationEvent) error {
+	for _, field := range event.sensitiveFields() {
+		var err error
+		if *field, err = s.cipher.decrypt(*field); err != nil {
+			return err
+		}
+	}
+	for _, field := range event.compressibleFields() {
+		var err error
+		if *field, err = decompressValue(*field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sensitiveFields lists the event fields holding conversation content, which
+// are encrypted at rest when the store has a key
+func (e *ConversationEvent) sensitiveFields() []*string {
+	return []*string{&e.Content, &e.ToolInputJSON, &e.ToolResultContent, &e.ToolResultJSON, &e.ToolError}
+}
+
+// checkEncryption refuses to open an encrypted store without a key
+func (s *SQLiteStore) checkEncryption() error {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM store_encryption`).Scan(&count); err != nil {
+		return fmt.Errorf("failed to check store encryption: %w", err)
+	}
+	if count > 0 {
+		return ErrStoreKeyRequired
+	}
+	return nil
+}
+
+// enableEncryption derives the store key from passphrase and installs the
+// cipher. On first use it records a salt and key check; later opens verify
+// the passphrase against the check.
+func (s *SQLiteStore) enableEncryption(passphrase string) error {
+	var salt []byte
+	var keyCheck string
+	err := s.db.QueryRow(`SELECT salt, key_check FROM store_encryption WHERE id = 1`).Scan(&salt, &keyCheck)
+	firstUse := err == sql.ErrNoRows
+	if err != nil && !firstUse {
+		return fmt.Errorf("failed to read store encryption settings: %w", err)
+	}
+	if firstUse {
+		salt = make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+	}
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, storeKeyIterations, 32)
+	if err != nil {
+		return fmt.Errorf("failed to derive store key: %w", err)
+	}
+	c, err := newFieldCipher(key)
+	if err != nil {
+		return err
+	}
+
+	if !firstUse {
+		check, err := c.decrypt(keyCheck)
+		if err != nil || check != keyCheckPlaintext {
+			return ErrInvalidStoreKey
+		}
+		s.cipher = c
+		return nil
+	}
+
+	keyCheck, err = c.encrypt(keyCheckPlaintext)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`
+		INSERT INTO store_encryption (id, salt, key_check) VALUES (1, ?, ?)
+	`, salt, keyCheck); err != nil {
+		return fmt.Errorf("failed to record store encryption settings: %w", err)
+	}
+	s.cipher = c
+	return nil
+}
+
+// sensitiveFields lists the session fields holding prompts, results and
+// credentials, which are encrypted at rest when the store has a key. The
+// query, title and summary stay in plaintext for session search.
+func (session *Session) sensitiveFields() []*string {
+	return []*string{&session.SystemPrompt, &session.AppendSystemPrompt, &session.CustomInstructions,
+		&session.ResultContent, &session.ProxyAPIKey}
+}
+
+// encryptSession returns a copy of the session with its sensitive fields
+// encrypted for storage
+func (s *SQLiteStore) encryptSession(session *Session) (*Session, error) {
+	sealed := *session
+	for _, field := range sealed.sensitiveFields() {
+		var err error
+		if *field, err = s.cipher.encrypt(*field); err != nil {
+			return nil, err
+		}
+	}
+	return &sealed, nil
+}
+
+// decryptSession decrypts a scanned session's sensitive fields in place
+func (s *SQLiteStore) decryptSession(session *Session) error {
+	for _, field := range session.sensitiveFields() {
+		var err error
+		if *field, err = s.cipher.decrypt(*field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decryptApproval sets a scanned approval's tool input and comment from
+// their stored values
+func (s *SQLiteStore) decryptApproval(approval *Approval, toolInput, comment string) error {
+	toolInput, err := s.cipher.decrypt(toolInput)
+	if err != nil {
+		return err
+	}
+	if approval.Comment, err = s.cipher.decrypt(comment); err != nil {
+		return err
+	}
+	approval.ToolInput = json.RawMessage(toolInput)
+	return nil
+}
+
+// encryptedColumns lists every column encrypted at rest, by table. Archive
+// tables hold copies of their tables' rows and are listed alongside them.
+var encryptedColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"conversation_events", eventPayloadColumns},
+	{"archived_conversation_events", eventPayloadColumns},
+	{"sessions", sessionSecretColumns},
+	{"archived_sessions", sessionSecretColumns},
+	{"approvals", approvalSecretColumns},
+	{"archived_approvals", approvalSecretColumns},
+	{"file_snapshots", []string{"content"}},
+	{"archived_file_snapshots", []string{"content"}},
+	{"raw_events", []string{"event_json"}},
+	{"archived_raw_events", []string{"event_json"}},
+	{"webhooks", []string{"secret"}},
+}
+
+var (
+	eventPayloadColumns   = []string{"content", "tool_input_json", "tool_result_content", "tool_result_json", "tool_error"}
+	sessionSecretColumns  = []string{"system_prompt", "append_system_prompt", "custom_instructions", "result_content", "proxy_api_key"}
+	approvalSecretColumns = []string{"tool_input", "comment"}
+)
+
+// encryptExistingData rewrites the encrypted columns' plaintext values,
+// which is how a database created without a key is migrated. It is safe to
+// interrupt: already encrypted values are skipped on the next run. Once
+// anything was rewritten the database is vacuumed, since the plaintext
+// would otherwise survive in free pages until they are reused.
+func (s *SQLiteStore) encryptExistingData(ctx context.Context) (int, error) {
+	total := 0
+	for _, t := range encryptedColumns {
+		for _, column := range t.columns {
+			for {
+				n, err := s.encryptColumnBatch(ctx, t.table, column)
+				if err != nil {
+					return total, err
+				}
+				total += n
+				if n < encryptBatchSize {
+					break
+				}
+			}
+		}
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	slog.Info("encrypted existing data", "values", total)
+
+	// The search index still holds the plaintext words of rewritten events.
+	// Rebuilding drops its segments and re-reads the encrypted content.
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO conversation_events_fts(conversation_events_fts) VALUES ('rebuild')`); err != nil {
+		return total, fmt.Errorf("failed to rebuild conversation_events_fts: %w", err)
+	}
+
+	// VACUUM rebuilds the file without the old pages. With WAL the rewritten
+	// pages also sit in the log until a checkpoint copies and truncates it.
+	if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+		return total, fmt.Errorf("failed to vacuum after encrypting: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return total, fmt.Errorf("failed to checkpoint after encrypting: %w", err)
+	}
+	return total, nil
+}
+
+// encryptColumnBatch encrypts up to encryptBatchSize plaintext values of
+// column in one transaction
+func (s *SQLiteStore) encryptColumnBatch(ctx context.Context, table, column string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT rowid, %[1]s FROM %[2]s
+		WHERE %[1]s IS NOT NULL AND %[1]s != '' AND substr(%[1]s, 1, %[3]d) != '%[4]s'
+		LIMIT ?
+	`, column, table, len(encryptedPrefix), encryptedPrefix), encryptBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query plaintext %s.%s: %w", table, column, err)
+	}
+	type plaintextValue struct {
+		rowID int64
+		value string
+	}
+	var pending []plaintextValue
+	for rows.Next() {
+		var v plaintextValue
+		if err := rows.Scan(&v.rowID, &v.value); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to scan plaintext %s.%s: %w", table, column, err)
+		}
+		pending = append(pending, v)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, v := range pending {
+		// Compressed event payloads are encrypted as they are, which is
+		// the order encryptEvent applies and decryptEvent reverses
+		sealed, err := s.cipher.encrypt(v.value)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", table, column), sealed, v.rowID); err != nil {
+			return 0, fmt.Errorf("failed to encrypt %s.%s row %d: %w", table, column, v.rowID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit encrypted %s.%s: %w", table, column, err)
+	}
+	return len(pending), nil
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createEncryptionTestSession(t testing.TB, s *SQLiteStore, id string) {
+	t.Helper()
+	require.NoError(t, s.CreateSession(context.Background(), &Session{
+		ID:              id,
+		RunID:           "run-" + id,
+		ClaudeSessionID: "claude-" + id,
+		Query:           "test",
+		Status:          SessionStatusRunning,
+		CreatedAt:       time.Now(),
+		LastActivityAt:  time.Now(),
+	}))
+}
+
+func addEncryptionTestEvents(t testing.TB, s *SQLiteStore, sessionID string) {
+	t.Helper()
+	ctx := context.Background()
+	for _, event := range []*ConversationEvent{
+		{EventType: EventTypeMessage, Role: "user", Content: "my password is hunter2"},
+		{EventType: EventTypeToolCall, ToolID: "t1", ToolName: "Bash", ToolInputJSON: `{"command":"cat secrets.txt"}`},
+		{EventType: EventTypeToolResult, ToolResultForID: "t1", ToolResultContent: "API_KEY=sk-123",
+			ToolResultJSON: `"API_KEY=sk-123"`, ToolError: "API_KEY=sk-123"},
+	} {
+		event.SessionID = sessionID
+		event.ClaudeSessionID = "claude-" + sessionID
+		require.NoError(t, s.AddConversationEvent(ctx, event))
+	}
+}
+
+// assertStoredEncrypted checks that no payload column holds plaintext
+func assertStoredEncrypted(t *testing.T, s *SQLiteStore) {
+	t.Helper()
+	rows, err := s.db.Query(`SELECT COALESCE(content, ''), COALESCE(tool_input_json, ''), COALESCE(tool_result_content, ''),
+		COALESCE(tool_result_json, ''), COALESCE(tool_error, '') FROM conversation_events`)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var content, input, result, resultJSON, toolError string
+		require.NoError(t, rows.Scan(&content, &input, &result, &resultJSON, &toolError))
+		for _, value := range []string{content, input, result, resultJSON, toolError} {
+			if value != "" {
+				assert.True(t, strings.HasPrefix(value, encryptedPrefix), "stored in plaintext: %q", value)
+			}
+		}
+	}
+	require.NoError(t, rows.Err())
+}
+
+func assertEventsReadable(t *testing.T, s *SQLiteStore, sessionID string) {
+	t.Helper()
+	events, err := s.GetSessionConversation(context.Background(), sessionID, ConversationPage{})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "my password is hunter2", events[0].Content)
+	assert.Equal(t, `{"command":"cat secrets.txt"}`, events[1].ToolInputJSON)
+	assert.Equal(t, "API_KEY=sk-123", events[2].ToolResultContent)
+	assert.Equal(t, `"API_KEY=sk-123"`, events[2].ToolResultJSON)
+	assert.Equal(t, "API_KEY=sk-123", events[2].ToolError)
+	assert.Equal(t, len("API_KEY=sk-123"), events[2].ToolResultBytes, "sizes are measured before encryption")
+
+	toolCall, err := s.GetToolCallByID(context.Background(), "t1")
+	require.NoError(t, err)
+	require.NotNil(t, toolCall)
+	assert.Equal(t, `{"command":"cat secrets.txt"}`, toolCall.ToolInputJSON)
+}
+
+func TestEncryptedStore(t *testing.T) {
+	t.Run("round trips events", func(t *testing.T) {
+		dbPath := testutil.DatabasePath(t, "encrypted")
+		s, err := NewEncryptedSQLiteStore(dbPath, "correct horse")
+		require.NoError(t, err)
+		createEncryptionTestSession(t, s, "sess-1")
+		addEncryptionTestEvents(t, s, "sess-1")
+
+		assertStoredEncrypted(t, s)
+		assertEventsReadable(t, s, "sess-1")
+		require.NoError(t, s.Close())
+
+		// Reopening with the same key reads the events back
+		s, err = NewEncryptedSQLiteStore(dbPath, "correct horse")
+		require.NoError(t, err)
+		assertEventsReadable(t, s, "sess-1")
+		require.NoError(t, s.Close())
+
+		_, err = NewEncryptedSQLiteStore(dbPath, "wrong horse")
+		assert.ErrorIs(t, err, ErrInvalidStoreKey)
+
+		_, err = NewSQLiteStore(dbPath)
+		assert.ErrorIs(t, err, ErrStoreKeyRequired)
+	})
+
+	t.Run("encrypts an existing plaintext database", func(t *testing.T) {
+		dbPath := testutil.DatabasePath(t, "plaintext")
+		s, err := NewSQLiteStore(dbPath)
+		require.NoError(t, err)
+		createEncryptionTestSession(t, s, "sess-1")
+		addEncryptionTestEvents(t, s, "sess-1")
+		require.NoError(t, s.Close())
+
+		s, err = NewEncryptedSQLiteStore(dbPath, "correct horse")
+		require.NoError(t, err)
+		defer func() { _ = s.Close() }()
+
+		assertStoredEncrypted(t, s)
+		assertEventsReadable(t, s, "sess-1")
+	})
+
+	t.Run("leaves no plaintext in the database files", func(t *testing.T) {
+		const marker = "plaintextmarker7f3a"
+		ctx := context.Background()
+		dbPath := testutil.DatabasePath(t, "marker")
+		s, err := NewSQLiteStore(dbPath)
+		require.NoError(t, err)
+		require.NoError(t, s.CreateSession(ctx, &Session{
+			ID: "sess-1", RunID: "run-1", ClaudeSessionID: "claude-sess-1", Query: "q",
+			SystemPrompt: marker, ProxyAPIKey: marker, Status: SessionStatusCompleted,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+		require.NoError(t, s.UpdateSession(ctx, "sess-1", SessionUpdate{ResultContent: stringPtr(marker)}))
+		require.NoError(t, s.AddConversationEvent(ctx, &ConversationEvent{
+			SessionID: "sess-1", ClaudeSessionID: "claude-sess-1", EventType: EventTypeMessage, Role: "user", Content: marker,
+		}))
+		require.NoError(t, s.CreateApproval(ctx, &Approval{
+			ID: "appr-1", RunID: "run-1", SessionID: "sess-1", Status: ApprovalStatusLocalPending, CreatedAt: time.Now(),
+			ToolName: "Bash", ToolInput: []byte(`{"command":"` + marker + `"}`), Comment: marker,
+		}))
+		require.NoError(t, s.CreateFileSnapshot(ctx, &FileSnapshot{ToolID: "t1", SessionID: "sess-1", FilePath: "a.txt", Content: marker}))
+		require.NoError(t, s.StoreRawEvent(ctx, "sess-1", `{"text":"`+marker+`"}`))
+		require.NoError(t, s.CreateWebhook(ctx, &Webhook{ID: "wh-1", URL: "https://example.com/hook", Secret: marker, Events: []string{"session.completed"}}))
+		require.NoError(t, s.Close())
+
+		s, err = NewEncryptedSQLiteStore(dbPath, "correct horse")
+		require.NoError(t, err)
+		sess, err := s.GetSession(ctx, "sess-1")
+		require.NoError(t, err)
+		assert.Equal(t, marker, sess.SystemPrompt)
+		assert.Equal(t, marker, sess.ResultContent)
+		assert.Equal(t, marker, sess.ProxyAPIKey)
+		approval, err := s.GetApproval(ctx, "appr-1")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"command":"`+marker+`"}`, string(approval.ToolInput))
+		assert.Equal(t, marker, approval.Comment)
+		snapshots, err := s.GetFileSnapshots(ctx, "sess-1")
+		require.NoError(t, err)
+		require.Len(t, snapshots, 1)
+		assert.Equal(t, marker, snapshots[0].Content)
+		webhooks, err := s.ListWebhooks(ctx)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, marker, webhooks[0].Secret)
+
+		// Values written with the key are encrypted too
+		require.NoError(t, s.CreateFileSnapshot(ctx, &FileSnapshot{ToolID: "t2", SessionID: "sess-1", FilePath: "b.txt", Content: marker}))
+		require.NoError(t, s.StoreRawEvent(ctx, "sess-1", marker))
+		require.NoError(t, s.CreateWebhook(ctx, &Webhook{ID: "wh-2", URL: "https://example.com/hook", Secret: marker, Events: []string{"session.failed"}}))
+		require.NoError(t, s.Close())
+
+		for _, path := range []string{dbPath, dbPath + "-wal"} {
+			data, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			require.NoError(t, err)
+			assert.NotContains(t, string(data), marker, path)
+		}
+	})
+
+	t.Run("rejects an empty key", func(t *testing.T) {
+		_, err := NewEncryptedSQLiteStore(":memory:", "")
+		assert.Error(t, err)
+	})
+}
+
+// BenchmarkAddConversationEvent measures the write overhead of encryption
+func BenchmarkAddConversationEvent(b *testing.B) {
+	for _, passphrase := range []string{"", "correct horse"} {
+		name := "plaintext"
+		if passphrase != "" {
+			name = "encrypted"
+		}
+		b.Run(name, func(b *testing.B) {
+			var s *SQLiteStore
+			var err error
+			if passphrase == "" {
+				s, err = NewSQLiteStore(":memory:")
+			} else {
+				s, err = NewEncryptedSQLiteStore(":memory:", passphrase)
+			}
+			require.NoError(b, err)
+			defer func() { _ = s.Close() }()
+			createEncryptionTestSession(b, s, "sess-1")
+
+			content := strings.Repeat("x", 4096)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				require.NoError(b, s.AddConversationEvent(context.Background(), &ConversationEvent{
+					SessionID:         "sess-1",
+					ClaudeSessionID:   "claude-sess-1",
+					EventType:         EventTypeToolResult,
+					ToolResultForID:   fmt.Sprintf("t%d", i),
+					ToolResultContent: content,
+				}))
+			}
+		})
+	}
+}
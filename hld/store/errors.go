@@ -15,6 +15,10 @@ var (
 
 	// ErrInvalidStatus is returned when an invalid status is provided
 	ErrInvalidStatus = errors.New("invalid status")
+
+	// ErrBudgetExceeded is returned when adding an event to a session whose
+	// cost has passed its MaxCostUSD
+	ErrBudgetExceeded = errors.New("session cost budget exceeded")
 )
 
 // NotFoundError wraps ErrNotFound with additional context
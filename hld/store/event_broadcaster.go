@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// eventSubscriberBuffer is how many events a subscriber can fall behind by
+// before its stream is ended
+const eventSubscriberBuffer = 100
+
+// eventSubscriber is one SubscribeToSession caller
+type eventSubscriber struct {
+	ch     chan *ConversationEvent
+	closed chan struct{}
+}
+
+// eventBroadcaster fans stored conversation events out to the subscribers of
+// their session
+type eventBroadcaster struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*eventSubscriber]struct{}
+}
+
+func newEventBroadcaster() *eventBroadcaster {
+	return &eventBroadcaster{subscribers: make(map[string]map[*eventSubscriber]struct{})}
+}
+
+// subscribe registers a subscriber for sessionID that is removed when ctx is done
+func (b *eventBroadcaster) subscribe(ctx context.Context, sessionID string) *eventSubscriber {
+	sub := &eventSubscriber{
+		ch:     make(chan *ConversationEvent, eventSubscriberBuffer),
+		closed: make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	if b.subscribers[sessionID] == nil {
+		b.subscribers[sessionID] = make(map[*eventSubscriber]struct{})
+	}
+	b.subscribers[sessionID][sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			b.unsubscribe(sessionID, sub)
+		case <-sub.closed:
+		}
+	}()
+	return sub
+}
+
+// publish sends a copy of event to its session's subscribers. A subscriber
+// that has fallen too far behind is closed rather than skipped, so every
+// stream is gap free and a client that sees it end can catch up with
+// GetConversation.
+func (b *eventBroadcaster) publish(event *ConversationEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers[event.SessionID] {
+		delivered := *event
+		select {
+		case sub.ch <- &delivered:
+		default:
+			slog.Warn("closing conversation stream for slow subscriber",
+				"session_id", event.SessionID,
+				"sequence", event.Sequence,
+			)
+			b.removeLocked(event.SessionID, sub)
+		}
+	}
+}
+
+// closeSession ends every stream for sessionID
+func (b *eventBroadcaster) closeSession(sessionID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers[sessionID] {
+		b.removeLocked(sessionID, sub)
+	}
+}
+
+func (b *eventBroadcaster) unsubscribe(sessionID string, sub *eventSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeLocked(sessionID, sub)
+}
+
+func (b *eventBroadcaster) removeLocked(sessionID string, sub *eventSubscriber) {
+	subs, ok := b.subscribers[sessionID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subscribers, sessionID)
+	}
+	close(sub.ch)
+	close(sub.closed)
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeToSession(t *testing.T) {
+	setup := func(t *testing.T) *SQLiteStore {
+		t.Helper()
+		// A file database, since concurrent writers each get their own
+		// connection and every connection to :memory: is a separate database
+		s, err := NewSQLiteStore(testutil.DatabasePath(t, "subscribe"))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = s.Close() })
+		require.NoError(t, s.CreateSession(context.Background(), &Session{
+			ID:             "sess-1",
+			RunID:          "run-1",
+			Query:          "test",
+			Status:         SessionStatusRunning,
+			CreatedAt:      time.Now(),
+			LastActivityAt: time.Now(),
+		}))
+		return s
+	}
+	addMessage := func(t *testing.T, s *SQLiteStore) {
+		require.NoError(t, s.AddConversationEvent(context.Background(), &ConversationEvent{
+			SessionID:       "sess-1",
+			ClaudeSessionID: "claude-1",
+			EventType:       EventTypeMessage,
+			Role:            "assistant",
+			Content:         "working",
+		}))
+	}
+
+	t.Run("delivers concurrent writes in sequence order", func(t *testing.T) {
+		s := setup(t)
+		ctx := context.Background()
+		events, err := s.SubscribeToSession(ctx, "sess-1")
+		require.NoError(t, err)
+
+		const writers, perWriter = 4, 20
+		var wg sync.WaitGroup
+		for range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range perWriter {
+					addMessage(t, s)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// A terminal status ends the stream after the buffered events
+		completed := SessionStatusCompleted
+		require.NoError(t, s.UpdateSession(ctx, "sess-1", SessionUpdate{Status: &completed}))
+
+		var sequences []int
+		for event := range events {
+			assert.Equal(t, "working", event.Content)
+			sequences = append(sequences, event.Sequence)
+		}
+		require.Len(t, sequences, writers*perWriter)
+		for i, seq := range sequences {
+			assert.Equal(t, i+1, seq)
+		}
+	})
+
+	t.Run("cancelling the context closes the channel", func(t *testing.T) {
+		s := setup(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		events, err := s.SubscribeToSession(ctx, "sess-1")
+		require.NoError(t, err)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for range events {
+			}
+		}()
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("subscriber was not unblocked by cancellation")
+		}
+		// Later writes are unaffected
+		addMessage(t, s)
+	})
+
+	t.Run("finished session", func(t *testing.T) {
+		s := setup(t)
+		ctx := context.Background()
+		completed := SessionStatusCompleted
+		require.NoError(t, s.UpdateSession(ctx, "sess-1", SessionUpdate{Status: &completed}))
+
+		events, err := s.SubscribeToSession(ctx, "sess-1")
+		require.NoError(t, err)
+		_, ok := <-events
+		assert.False(t, ok, "stream of a finished session is already closed")
+	})
+
+	t.Run("slow subscriber is closed rather than skipped", func(t *testing.T) {
+		s := setup(t)
+		events, err := s.SubscribeToSession(context.Background(), "sess-1")
+		require.NoError(t, err)
+
+		for range eventSubscriberBuffer + 1 {
+			addMessage(t, s)
+		}
+
+		var sequences []int
+		for event := range events {
+			sequences = append(sequences, event.Sequence)
+		}
+		require.Len(t, sequences, eventSubscriberBuffer)
+		assert.Equal(t, eventSubscriberBuffer, sequences[len(sequences)-1])
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		s := setup(t)
+		_, err := s.SubscribeToSession(context.Background(), "missing")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFiles holds schema changes written as plain SQL. Each file is
+// named NNN_description.sql, where NNN is its schema_version, sharing one
+// numbering with the migrations in applyMigrations. Those run first, so new
+// schema changes that need no Go logic belong here; ones that do, such as
+// adding a column only if it is missing, still go in applyMigrations.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// sqlMigration is one parsed migration file
+type sqlMigration struct {
+	version     int
+	description string
+	name        string
+	sql         string
+}
+
+// ApplyMigrations applies the embedded SQL migrations that db hasn't
+// recorded in schema_version, in version order. Each file runs in its own
+// transaction together with its schema_version row, so a failing file
+// leaves no trace and a second run only retries what is left.
+func ApplyMigrations(ctx context.Context, db *sql.DB) error {
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+	return applySQLMigrations(ctx, db, files)
+}
+
+// applySQLMigrations applies the migration files in fsys that db lacks
+func applySQLMigrations(ctx context.Context, db *sql.DB, fsys fs.FS) error {
+	migrations, err := loadSQLMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			description TEXT
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	applied := map[int]bool{}
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_version`)
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		slog.Info(fmt.Sprintf("Applying migration %d: %s", m.version, m.description))
+		if err := applySQLMigration(ctx, db, m); err != nil {
+			return err
+		}
+		slog.Info(fmt.Sprintf("Migration %d applied successfully", m.version))
+	}
+	return nil
+}
+
+func applySQLMigration(ctx context.Context, db *sql.DB, m sqlMigration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return fmt.Errorf("migration %s failed: %w", m.name, err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO schema_version (version, description)
+		VALUES (?, ?)
+	`, m.version, m.description)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+	return nil
+}
+
+// loadSQLMigrations reads and orders the migration files in fsys
+func loadSQLMigrations(fsys fs.FS) ([]sqlMigration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	var migrations []sqlMigration
+	seen := map[int]string{}
+	for _, name := range names {
+		number, rest, _ := strings.Cut(strings.TrimSuffix(path.Base(name), ".sql"), "_")
+		version, err := strconv.Atoi(number)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s must be named NNN_description.sql", name)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		migrations = append(migrations, sqlMigration{
+			version:     version,
+			description: strings.ReplaceAll(rest, "_", " "),
+			name:        name,
+			sql:         string(content),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
@@ -0,0 +1,5 @@
+-- Tool results are joined to their calls by tool ID in session metrics and
+-- tool output stats
+CREATE INDEX IF NOT EXISTS idx_conversation_tool_result_for
+	ON conversation_events(session_id, tool_result_for_id)
+	WHERE tool_result_for_id IS NOT NULL;
@@ -0,0 +1,10 @@
+-- Reviewer notes attached to conversation events
+CREATE TABLE IF NOT EXISTS annotations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_id INTEGER NOT NULL,
+	author TEXT NOT NULL,
+	note TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (event_id) REFERENCES conversation_events(id)
+);
+CREATE INDEX IF NOT EXISTS idx_annotations_event ON annotations(event_id);
@@ -0,0 +1,9 @@
+-- Webhook registrations notified of session lifecycle events. events is a
+-- JSON array of event names such as "session.completed".
+CREATE TABLE IF NOT EXISTS webhooks (
+	id TEXT PRIMARY KEY,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySQLMigrations(t *testing.T) {
+	ctx := context.Background()
+	openDB := func(t *testing.T) *sql.DB {
+		t.Helper()
+		db, err := sql.Open("sqlite3", ":memory:")
+		require.NoError(t, err)
+		// Every connection to :memory: is a separate database
+		db.SetMaxOpenConns(1)
+		t.Cleanup(func() { _ = db.Close() })
+		return db
+	}
+	versions := func(t *testing.T, db *sql.DB) []int {
+		t.Helper()
+		rows, err := db.Query(`SELECT version FROM schema_version ORDER BY version`)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		var out []int
+		for rows.Next() {
+			var v int
+			require.NoError(t, rows.Scan(&v))
+			out = append(out, v)
+		}
+		return out
+	}
+	tableExists := func(t *testing.T, db *sql.DB, name string) bool {
+		t.Helper()
+		var n int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&n))
+		return n > 0
+	}
+
+	first := &fstest.MapFile{Data: []byte(`CREATE TABLE widgets (id INTEGER PRIMARY KEY);`)}
+	second := &fstest.MapFile{Data: []byte(`
+		ALTER TABLE widgets ADD COLUMN name TEXT;
+		CREATE TABLE gadgets (id INTEGER PRIMARY KEY);
+	`)}
+
+	t.Run("fresh database gets every migration", func(t *testing.T) {
+		db := openDB(t)
+		files := fstest.MapFS{"002_add_gadgets.sql": second, "001_add_widgets.sql": first}
+		require.NoError(t, applySQLMigrations(ctx, db, files))
+		assert.Equal(t, []int{1, 2}, versions(t, db))
+		assert.True(t, tableExists(t, db, "gadgets"))
+
+		var description string
+		require.NoError(t, db.QueryRow(`SELECT description FROM schema_version WHERE version = 2`).Scan(&description))
+		assert.Equal(t, "add gadgets", description)
+
+		// The files aren't idempotent on their own, so a rerun must skip them
+		require.NoError(t, applySQLMigrations(ctx, db, files))
+		assert.Equal(t, []int{1, 2}, versions(t, db))
+	})
+
+	t.Run("partially migrated database gets the remainder", func(t *testing.T) {
+		db := openDB(t)
+		require.NoError(t, applySQLMigrations(ctx, db, fstest.MapFS{"001_add_widgets.sql": first}))
+		assert.Equal(t, []int{1}, versions(t, db))
+		assert.False(t, tableExists(t, db, "gadgets"))
+
+		require.NoError(t, applySQLMigrations(ctx, db, fstest.MapFS{"001_add_widgets.sql": first, "002_add_gadgets.sql": second}))
+		assert.Equal(t, []int{1, 2}, versions(t, db))
+		assert.True(t, tableExists(t, db, "gadgets"))
+	})
+
+	t.Run("bad file rolls back", func(t *testing.T) {
+		db := openDB(t)
+		bad := &fstest.MapFile{Data: []byte(`
+			CREATE TABLE gizmos (id INTEGER PRIMARY KEY);
+			ALTER TABLE missing ADD COLUMN name TEXT;
+		`)}
+		err := applySQLMigrations(ctx, db, fstest.MapFS{"001_add_widgets.sql": first, "002_add_gizmos.sql": bad})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "002_add_gizmos.sql")
+
+		assert.Equal(t, []int{1}, versions(t, db), "earlier migrations stay applied")
+		assert.False(t, tableExists(t, db, "gizmos"), "the failed file's statements are undone")
+
+		// Fixed, the remainder applies
+		require.NoError(t, applySQLMigrations(ctx, db, fstest.MapFS{"001_add_widgets.sql": first, "002_add_gadgets.sql": second}))
+		assert.Equal(t, []int{1, 2}, versions(t, db))
+	})
+
+	t.Run("invalid file names", func(t *testing.T) {
+		db := openDB(t)
+		assert.Error(t, applySQLMigrations(ctx, db, fstest.MapFS{"widgets.sql": first}))
+		assert.Error(t, applySQLMigrations(ctx, db, fstest.MapFS{"001_a.sql": first, "1_b.sql": second}))
+	})
+}
+
+func TestApplyMigrationsEmbedded(t *testing.T) {
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	files, err := fs.Sub(migrationFiles, "migrations")
+	require.NoError(t, err)
+	migrations, err := loadSQLMigrations(files)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	last := migrations[len(migrations)-1].version
+
+	var recorded int
+	require.NoError(t, s.db.QueryRow(`SELECT COUNT(*) FROM schema_version WHERE version = ?`, last).Scan(&recorded))
+	assert.Equal(t, 1, recorded, "opening a store applies the embedded migrations")
+
+	require.NoError(t, ApplyMigrations(context.Background(), s.db), "running again is a no-op")
+}
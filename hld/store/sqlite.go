@@ -2,15 +2,20 @@ package store
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
 	_ "github.com/mattn/go-sqlite3"
@@ -19,6 +24,21 @@ import (
 // SQLiteStore implements ConversationStore using SQLite
 type SQLiteStore struct {
 	db *sql.DB
+
+	// auditMu serializes audit log appends so the hash chain stays linear
+	auditMu sync.Mutex
+
+	// cipher encrypts conversation event payloads and the other columns in
+	// encryptedColumns, nil when the store was opened without a key
+	cipher *fieldCipher
+
+	// compress gzips large conversation event payloads before writing them
+	compress bool
+
+	// eventMu serializes conversation event appends so subscribers receive
+	// events in sequence order
+	eventMu sync.Mutex
+	events  *eventBroadcaster
 }
 
 // GetDB returns the underlying database connection for testing purposes
@@ -26,8 +46,70 @@ func (s *SQLiteStore) GetDB() *sql.DB {
 	return s.db
 }
 
+// SQLiteOptions tunes the SQLite connection. Busy timeout and cache size are
+// per-connection settings, so they are passed to the driver and applied to
+// every connection the pool opens rather than set once after opening.
+type SQLiteOptions struct {
+	// WALMode enables write-ahead logging, letting reads run alongside a
+	// write instead of failing with "database is locked"
+	WALMode bool
+	// BusyTimeoutMS is how long a connection waits for a lock before giving
+	// up with "database is locked"
+	BusyTimeoutMS int
+	// CacheSize is passed to PRAGMA cache_size: pages when positive, KiB
+	// when negative. Zero keeps SQLite's default.
+	CacheSize int
+	// CompressEventContent gzips large event content, tool input and tool
+	// result JSON before writing them. Compressed values are read back
+	// whether or not it is set, but are left out of full-text search.
+	CompressEventContent bool
+}
+
+// DefaultSQLiteOptions returns the options NewSQLiteStore opens databases with
+func DefaultSQLiteOptions() SQLiteOptions {
+	return SQLiteOptions{WALMode: true, BusyTimeoutMS: 5000}
+}
+
+// dsn returns the driver data source name for dbPath with the options applied
+func (o SQLiteOptions) dsn(dbPath string) string {
+	// Incremental auto-vacuum only takes effect on new databases; see
+	// IncrementalVacuum for existing ones
+	params := fmt.Sprintf("_busy_timeout=%d&_auto_vacuum=incremental", o.BusyTimeoutMS)
+	if o.CacheSize != 0 {
+		params += fmt.Sprintf("&_cache_size=%d", o.CacheSize)
+	}
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	return dbPath + separator + params
+}
+
 // NewSQLiteStore creates a new SQLite-backed store
 func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
+	return openSQLiteStore(dbPath, "", DefaultSQLiteOptions())
+}
+
+// NewSQLiteStoreWithOptions creates a SQLite-backed store with the given
+// connection options
+func NewSQLiteStoreWithOptions(dbPath string, opts SQLiteOptions) (*SQLiteStore, error) {
+	return openSQLiteStore(dbPath, "", opts)
+}
+
+// NewEncryptedSQLiteStore creates a SQLite-backed store whose conversation
+// event content, tool inputs and tool results, session prompts, results and
+// proxy keys, approval inputs, file snapshots and raw events are encrypted
+// with a key derived from passphrase. Opening an existing plaintext database
+// encrypts it in place; the same passphrase must be used on every later open.
+func NewEncryptedSQLiteStore(dbPath string, passphrase string) (*SQLiteStore, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("database key cannot be empty")
+	}
+	return openSQLiteStore(dbPath, passphrase, DefaultSQLiteOptions())
+}
+
+// openSQLiteStore opens the store, enabling encryption when passphrase is set
+func openSQLiteStore(dbPath string, passphrase string, opts SQLiteOptions) (*SQLiteStore, error) {
 	// Ensure directory exists (skip for in-memory databases)
 	if dbPath != ":memory:" {
 		dbDir := filepath.Dir(dbPath)
@@ -36,23 +118,39 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		}
 	}
 
-	// Open database
-	db, err := sql.Open("sqlite3", dbPath)
+	// Open database. With a key, deleted and overwritten content is zeroed
+	// rather than left readable in free pages.
+	dsn := opts.dsn(dbPath)
+	if passphrase != "" {
+		dsn += "&_secure_delete=true"
+	}
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	if dbPath == ":memory:" {
+		// Every connection to :memory: gets its own empty database, so the
+		// pool must never open a second one
+		db.SetMaxOpenConns(1)
+	}
 
-	// Enable foreign keys and WAL mode for better concurrency
+	// Enable foreign keys and, unless disabled, WAL mode for better
+	// concurrency. The journal mode is stored in the database file, so it
+	// only needs setting once.
 	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
 	}
-	if _, err := db.Exec("PRAGMA journal_mode = WAL"); err != nil {
+	journalMode := "DELETE"
+	if opts.WALMode {
+		journalMode = "WAL"
+	}
+	if _, err := db.Exec("PRAGMA journal_mode = " + journalMode); err != nil {
 		_ = db.Close()
-		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
+		return nil, fmt.Errorf("failed to set journal mode: %w", err)
 	}
 
-	store := &SQLiteStore{db: db}
+	store := &SQLiteStore{db: db, compress: opts.CompressEventContent, events: newEventBroadcaster()}
 
 	// Initialize schema
 	if err := store.initSchema(); err != nil {
@@ -65,6 +163,15 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		_ = db.Close()
 		return nil, fmt.Errorf("failed to apply migrations: %w", err)
 	}
+	if err := ApplyMigrations(context.Background(), db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	if err := store.syncArchiveTables(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to sync archive tables: %w", err)
+	}
 
 	// Validate schema is in expected state
 	if err := store.validateSchema(); err != nil {
@@ -72,6 +179,22 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("schema validation failed: %w", err)
 	}
 
+	if passphrase == "" {
+		if err := store.checkEncryption(); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	} else {
+		if err := store.enableEncryption(passphrase); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		if _, err := store.encryptExistingData(context.Background()); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to encrypt existing data: %w", err)
+		}
+	}
+
 	slog.Info("SQLite store initialized", "path", dbPath)
 	return store, nil
 }
@@ -1117,1263 +1240,4131 @@ func (s *SQLiteStore) applyMigrations() error {
 		slog.Info("Migration 22 applied successfully")
 	}
 
-	return nil
-}
+	// Migration 23: Add audit_log table for mutating RPC calls
+	if currentVersion < 23 {
+		slog.Info("Applying migration 23: Add audit_log table")
 
-// validateSchema ensures the database schema is in the expected state
-func (s *SQLiteStore) validateSchema() error {
-	// Validate user_settings table exists
-	var userSettingsExists int
-	err := s.db.QueryRow(`
-		SELECT COUNT(*) FROM sqlite_master
-		WHERE type='table' AND name='user_settings'
-	`).Scan(&userSettingsExists)
-	if err != nil {
-		return fmt.Errorf("failed to validate user_settings table: %w", err)
-	}
-	if userSettingsExists == 0 {
-		return fmt.Errorf("schema validation failed: user_settings table missing")
+		_, err := s.db.Exec(`
+			CREATE TABLE IF NOT EXISTS audit_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				identity TEXT NOT NULL,
+				method TEXT NOT NULL,
+				session_id TEXT,
+				outcome TEXT NOT NULL DEFAULT 'pending',
+				error_message TEXT,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				completed_at TIMESTAMP,
+				prev_hash TEXT,
+				entry_hash TEXT NOT NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+			CREATE INDEX IF NOT EXISTS idx_audit_log_session ON audit_log(session_id);
+			CREATE INDEX IF NOT EXISTS idx_audit_log_method ON audit_log(method);
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to create audit_log table: %w", err)
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (23, 'Add audit_log table for mutating RPC calls')
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to record migration 23: %w", err)
+		}
+
+		slog.Info("Migration 23 applied successfully")
 	}
 
-	// Validate additional_directories column exists
-	var additionalDirsExists int
-	err = s.db.QueryRow(`
-		SELECT COUNT(*) FROM pragma_table_info('sessions')
-		WHERE name = 'additional_directories'
-	`).Scan(&additionalDirsExists)
-	if err != nil {
-		return fmt.Errorf("failed to validate additional_directories column: %w", err)
+	// Migration 24: Add stable permalink IDs to conversation events
+	if currentVersion < 24 {
+		slog.Info("Applying migration 24: Add permalink to conversation_events")
+
+		var columnExists int
+		err = s.db.QueryRow(`
+			SELECT COUNT(*) FROM pragma_table_info('conversation_events')
+			WHERE name = 'permalink'
+		`).Scan(&columnExists)
+		if err != nil {
+			return fmt.Errorf("failed to check permalink column: %w", err)
+		}
+
+		if columnExists == 0 {
+			_, err = s.db.Exec(`ALTER TABLE conversation_events ADD COLUMN permalink TEXT`)
+			if err != nil {
+				return fmt.Errorf("failed to add permalink column: %w", err)
+			}
+		}
+
+		// Backfill existing events so every event is linkable
+		_, err = s.db.Exec(`
+			UPDATE conversation_events
+			SET permalink = 'evt_' || lower(hex(randomblob(12)))
+			WHERE permalink IS NULL OR permalink = ''
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to backfill permalinks: %w", err)
+		}
+
+		_, err = s.db.Exec(`
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_conversation_events_permalink
+			ON conversation_events(permalink)
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to create permalink index: %w", err)
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (24, 'Add permalink to conversation_events')
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to record migration 24: %w", err)
+		}
+
+		slog.Info("Migration 24 applied successfully")
 	}
-	if additionalDirsExists == 0 {
-		return fmt.Errorf("schema validation failed: additional_directories column missing")
+
+	// Migration 25: Add session_turns table for throughput metrics
+	if currentVersion < 25 {
+		slog.Info("Applying migration 25: Add session_turns table")
+
+		_, err := s.db.Exec(`
+			CREATE TABLE IF NOT EXISTS session_turns (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				session_id TEXT NOT NULL,
+				message_id TEXT NOT NULL,
+				output_tokens INTEGER NOT NULL DEFAULT 0,
+				generation_ms INTEGER NOT NULL DEFAULT 0,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(session_id, message_id),
+				FOREIGN KEY (session_id) REFERENCES sessions(id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_session_turns_session ON session_turns(session_id);
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to create session_turns table: %w", err)
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (25, 'Add session_turns table for throughput metrics')
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to record migration 25: %w", err)
+		}
+
+		slog.Info("Migration 25 applied successfully")
 	}
 
-	// Validate schema version is at least 19
-	var currentVersion int
-	err = s.db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&currentVersion)
-	if err != nil {
-		return fmt.Errorf("failed to get schema version: %w", err)
+	// Migration 26: Add imported flag for sessions loaded from external transcripts
+	if currentVersion < 26 {
+		slog.Info("Applying migration 26: Adding imported column to sessions table")
+
+		_, err := s.db.Exec(`
+			ALTER TABLE sessions ADD COLUMN imported BOOLEAN DEFAULT 0
+		`)
+		if err != nil {
+			// Check if column already exists (for idempotency)
+			var columnCount int
+			err = s.db.QueryRow(`
+				SELECT COUNT(*) FROM pragma_table_info('sessions')
+				WHERE name = 'imported'
+			`).Scan(&columnCount)
+			if err != nil {
+				return fmt.Errorf("failed to check for imported column: %w", err)
+			}
+			if columnCount == 0 {
+				return fmt.Errorf("failed to add imported column: %w", err)
+			}
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 26, "Add imported flag for sessions loaded from external transcripts")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 26: %w", err)
+		}
+
+		slog.Info("Migration 26 applied successfully")
 	}
-	if currentVersion < 19 {
-		return fmt.Errorf("schema validation failed: version %d is less than required 19", currentVersion)
+
+	// Migration 27: Add owner column for per-owner launch scheduling
+	if currentVersion < 27 {
+		slog.Info("Applying migration 27: Adding owner column to sessions table")
+
+		_, err := s.db.Exec(`
+			ALTER TABLE sessions ADD COLUMN owner TEXT
+		`)
+		if err != nil {
+			// Check if column already exists (for idempotency)
+			var columnCount int
+			err = s.db.QueryRow(`
+				SELECT COUNT(*) FROM pragma_table_info('sessions')
+				WHERE name = 'owner'
+			`).Scan(&columnCount)
+			if err != nil {
+				return fmt.Errorf("failed to check for owner column: %w", err)
+			}
+			if columnCount == 0 {
+				return fmt.Errorf("failed to add owner column: %w", err)
+			}
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 27, "Add owner column for per-owner launch scheduling")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 27: %w", err)
+		}
+
+		slog.Info("Migration 27 applied successfully")
 	}
 
-	slog.Info("Schema validation successful",
-		"version", currentVersion,
-		"user_settings_table", "present",
-		"additional_directories_column", "present")
+	// Migration 28: Add tool result size accounting to conversation_events
+	if currentVersion < 28 {
+		slog.Info("Applying migration 28: Add tool result size columns")
 
-	return nil
-}
+		for _, column := range []string{"tool_result_bytes", "tool_result_tokens"} {
+			var columnCount int
+			err := s.db.QueryRow(`
+				SELECT COUNT(*) FROM pragma_table_info('conversation_events')
+				WHERE name = ?
+			`, column).Scan(&columnCount)
+			if err != nil {
+				return fmt.Errorf("failed to check for %s column: %w", column, err)
+			}
+			if columnCount > 0 {
+				continue
+			}
+			if _, err := s.db.Exec("ALTER TABLE conversation_events ADD COLUMN " + column + " INTEGER"); err != nil {
+				return fmt.Errorf("failed to add %s column: %w", column, err)
+			}
+		}
 
-// Close closes the database connection
-func (s *SQLiteStore) Close() error {
-	return s.db.Close()
-}
+		// Backfill existing results using the same estimate as new inserts
+		_, err := s.db.Exec(`
+			UPDATE conversation_events
+			SET tool_result_bytes = length(CAST(tool_result_content AS BLOB)),
+				tool_result_tokens = (length(CAST(tool_result_content AS BLOB)) + 3) / 4
+			WHERE event_type = 'tool_result' AND tool_result_content IS NOT NULL
+				AND tool_result_bytes IS NULL
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to backfill tool result sizes: %w", err)
+		}
 
-// CreateSession creates a new session
-func (s *SQLiteStore) CreateSession(ctx context.Context, session *Session) error {
-	query := `
-		INSERT INTO sessions (
-			id, run_id, claude_session_id, parent_session_id,
-			query, summary, title, model, model_id, working_dir, max_turns, system_prompt, append_system_prompt, custom_instructions,
-			permission_prompt_tool, allowed_tools, disallowed_tools,
-			status, created_at, last_activity_at, auto_accept_edits, archived, dangerously_skip_permissions, dangerously_skip_permissions_expires_at,
-			dangerously_skip_permissions_timeout_ms,
-			proxy_enabled, proxy_base_url, proxy_model_override, proxy_api_key, additional_directories, editor_state
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (28, 'Add tool result size accounting to conversation_events')
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to record migration 28: %w", err)
+		}
 
-	_, err := s.db.ExecContext(ctx, query,
-		session.ID, session.RunID, session.ClaudeSessionID, session.ParentSessionID,
-		session.Query, session.Summary, session.Title, session.Model, session.ModelID, session.WorkingDir, session.MaxTurns,
-		session.SystemPrompt, session.AppendSystemPrompt, session.CustomInstructions,
-		session.PermissionPromptTool, session.AllowedTools, session.DisallowedTools,
-		session.Status, session.CreatedAt, session.LastActivityAt, session.AutoAcceptEdits, session.Archived,
-		session.DangerouslySkipPermissions, session.DangerouslySkipPermissionsExpiresAt,
-		session.DangerouslySkipPermissionsTimeoutMs,
-		session.ProxyEnabled, session.ProxyBaseURL, session.ProxyModelOverride, session.ProxyAPIKey,
-		session.AdditionalDirectories, session.EditorState,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+		slog.Info("Migration 28 applied successfully")
 	}
-	return nil
-}
 
-// UpdateSession updates session fields
-func (s *SQLiteStore) UpdateSession(ctx context.Context, sessionID string, updates SessionUpdate) error {
-	query := `UPDATE sessions SET`
-	args := []interface{}{}
-	setParts := []string{}
+	// Migration 29: Add language metadata to conversation_events
+	if currentVersion < 29 {
+		slog.Info("Applying migration 29: Adding language column to conversation_events")
 
-	if updates.LastActivityAt != nil {
-		setParts = append(setParts, "last_activity_at = ?")
-		args = append(args, *updates.LastActivityAt)
+		_, err := s.db.Exec(`
+			ALTER TABLE conversation_events ADD COLUMN language TEXT
+		`)
+		if err != nil {
+			// Check if column already exists (for idempotency)
+			var columnCount int
+			err = s.db.QueryRow(`
+				SELECT COUNT(*) FROM pragma_table_info('conversation_events')
+				WHERE name = 'language'
+			`).Scan(&columnCount)
+			if err != nil {
+				return fmt.Errorf("failed to check for language column: %w", err)
+			}
+			if columnCount == 0 {
+				return fmt.Errorf("failed to add language column: %w", err)
+			}
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 29, "Add language metadata to conversation_events")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 29: %w", err)
+		}
+
+		slog.Info("Migration 29 applied successfully")
 	}
-	if updates.ClaudeSessionID != nil {
-		setParts = append(setParts, "claude_session_id = ?")
-		args = append(args, *updates.ClaudeSessionID)
-	}
-	if updates.Query != nil {
-		setParts = append(setParts, "query = ?")
-		args = append(args, *updates.Query)
-	}
-	if updates.Status != nil {
-		setParts = append(setParts, "status = ?")
-		args = append(args, *updates.Status)
+
+	// Migration 30: Add bypass_tool_cache to sessions and tool_cache_hit to conversation_events
+	if currentVersion < 30 {
+		slog.Info("Applying migration 30: Adding tool result cache columns")
+
+		for _, column := range []struct{ table, name string }{
+			{"sessions", "bypass_tool_cache"},
+			{"conversation_events", "tool_cache_hit"},
+		} {
+			var columnCount int
+			err := s.db.QueryRow(`
+				SELECT COUNT(*) FROM pragma_table_info(?)
+				WHERE name = ?
+			`, column.table, column.name).Scan(&columnCount)
+			if err != nil {
+				return fmt.Errorf("failed to check for %s column: %w", column.name, err)
+			}
+			if columnCount > 0 {
+				continue
+			}
+			if _, err := s.db.Exec("ALTER TABLE " + column.table + " ADD COLUMN " + column.name + " BOOLEAN DEFAULT 0"); err != nil {
+				return fmt.Errorf("failed to add %s column: %w", column.name, err)
+			}
+		}
+
+		// Record migration
+		_, err := s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 30, "Add tool result cache columns")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 30: %w", err)
+		}
+
+		slog.Info("Migration 30 applied successfully")
 	}
-	if updates.CompletedAt != nil {
-		setParts = append(setParts, "completed_at = ?")
-		args = append(args, *updates.CompletedAt)
+
+	// Migration 31: Add audit_retention table anchoring the pruned audit chain
+	if currentVersion < 31 {
+		slog.Info("Applying migration 31: Add audit_retention table")
+
+		_, err := s.db.Exec(`
+			CREATE TABLE IF NOT EXISTS audit_retention (
+				id INTEGER PRIMARY KEY CHECK (id = 1),
+				pruned_through_id INTEGER NOT NULL,
+				anchor_hash TEXT NOT NULL,
+				pruned_at TIMESTAMP NOT NULL
+			)
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to create audit_retention table: %w", err)
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (31, 'Add audit_retention table for audit log pruning')
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to record migration 31: %w", err)
+		}
+
+		slog.Info("Migration 31 applied successfully")
 	}
-	if updates.CostUSD != nil {
-		setParts = append(setParts, "cost_usd = ?")
-		args = append(args, *updates.CostUSD)
+
+	// Migration 32: Add store_encryption table holding the key salt and check
+	if currentVersion < 32 {
+		slog.Info("Applying migration 32: Add store_encryption table")
+
+		_, err := s.db.Exec(`
+			CREATE TABLE IF NOT EXISTS store_encryption (
+				id INTEGER PRIMARY KEY CHECK (id = 1),
+				salt BLOB NOT NULL,
+				key_check TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to create store_encryption table: %w", err)
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (32, 'Add store_encryption table for encryption at rest')
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to record migration 32: %w", err)
+		}
+
+		slog.Info("Migration 32 applied successfully")
 	}
-	if updates.InputTokens != nil {
-		setParts = append(setParts, "input_tokens = ?")
-		args = append(args, *updates.InputTokens)
+
+	// Migration 33: Add content_hash to conversation_events for import deduplication
+	if currentVersion < 33 {
+		slog.Info("Applying migration 33: Add content_hash to conversation_events")
+
+		var columnCount int
+		err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM pragma_table_info('conversation_events')
+			WHERE name = 'content_hash'
+		`).Scan(&columnCount)
+		if err != nil {
+			return fmt.Errorf("failed to check for content_hash column: %w", err)
+		}
+		if columnCount == 0 {
+			if _, err := s.db.Exec(`ALTER TABLE conversation_events ADD COLUMN content_hash TEXT`); err != nil {
+				return fmt.Errorf("failed to add content_hash column: %w", err)
+			}
+		}
+
+		_, err = s.db.Exec(`
+			CREATE INDEX IF NOT EXISTS idx_conversation_content_hash
+			ON conversation_events(content_hash)
+			WHERE content_hash IS NOT NULL
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to create content_hash index: %w", err)
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 33, "Add content_hash to conversation_events for import deduplication")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 33: %w", err)
+		}
+
+		slog.Info("Migration 33 applied successfully")
 	}
-	if updates.OutputTokens != nil {
-		setParts = append(setParts, "output_tokens = ?")
-		args = append(args, *updates.OutputTokens)
+
+	// Migration 34: Add truncated flag to conversation_events
+	if currentVersion < 34 {
+		slog.Info("Applying migration 34: Add truncated to conversation_events")
+
+		var columnCount int
+		err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM pragma_table_info('conversation_events')
+			WHERE name = 'truncated'
+		`).Scan(&columnCount)
+		if err != nil {
+			return fmt.Errorf("failed to check for truncated column: %w", err)
+		}
+		if columnCount == 0 {
+			if _, err := s.db.Exec(`ALTER TABLE conversation_events ADD COLUMN truncated BOOLEAN DEFAULT 0`); err != nil {
+				return fmt.Errorf("failed to add truncated column: %w", err)
+			}
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 34, "Add truncated flag to conversation_events")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 34: %w", err)
+		}
+
+		slog.Info("Migration 34 applied successfully")
 	}
-	if updates.CacheCreationInputTokens != nil {
-		setParts = append(setParts, "cache_creation_input_tokens = ?")
-		args = append(args, *updates.CacheCreationInputTokens)
+
+	// Migration 35: Add tool_quota to sessions
+	if currentVersion < 35 {
+		slog.Info("Applying migration 35: Add tool_quota to sessions")
+
+		var columnCount int
+		err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM pragma_table_info('sessions')
+			WHERE name = 'tool_quota'
+		`).Scan(&columnCount)
+		if err != nil {
+			return fmt.Errorf("failed to check for tool_quota column: %w", err)
+		}
+		if columnCount == 0 {
+			if _, err := s.db.Exec(`ALTER TABLE sessions ADD COLUMN tool_quota TEXT`); err != nil {
+				return fmt.Errorf("failed to add tool_quota column: %w", err)
+			}
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 35, "Add tool_quota to sessions")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 35: %w", err)
+		}
+
+		slog.Info("Migration 35 applied successfully")
 	}
-	if updates.CacheReadInputTokens != nil {
-		setParts = append(setParts, "cache_read_input_tokens = ?")
-		args = append(args, *updates.CacheReadInputTokens)
+
+	// Migration 36: Add attachments table referencing blobs in the attachment store
+	if currentVersion < 36 {
+		slog.Info("Applying migration 36: Add attachments table")
+
+		_, err := s.db.Exec(`
+			CREATE TABLE IF NOT EXISTS attachments (
+				id TEXT PRIMARY KEY,
+				session_id TEXT NOT NULL,
+				name TEXT NOT NULL,
+				content_type TEXT NOT NULL DEFAULT '',
+				size_bytes INTEGER NOT NULL,
+				sha256 TEXT NOT NULL,
+				backend TEXT NOT NULL,
+				storage_key TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (session_id) REFERENCES sessions(id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_attachments_session ON attachments(session_id, created_at);
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to create attachments table: %w", err)
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (36, 'Add attachments table referencing blobs in the attachment store')
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to record migration 36: %w", err)
+		}
+
+		slog.Info("Migration 36 applied successfully")
 	}
-	if updates.EffectiveContextTokens != nil {
-		setParts = append(setParts, "effective_context_tokens = ?")
-		args = append(args, *updates.EffectiveContextTokens)
+
+	// Migration 37: Add launch timing columns to sessions
+	if currentVersion < 37 {
+		slog.Info("Applying migration 37: Add launch timing to sessions")
+
+		for _, column := range []string{"queued_at", "started_at", "first_event_at"} {
+			var columnCount int
+			err := s.db.QueryRow(`
+				SELECT COUNT(*) FROM pragma_table_info('sessions')
+				WHERE name = ?
+			`, column).Scan(&columnCount)
+			if err != nil {
+				return fmt.Errorf("failed to check for %s column: %w", column, err)
+			}
+			if columnCount == 0 {
+				if _, err := s.db.Exec(`ALTER TABLE sessions ADD COLUMN ` + column + ` TIMESTAMP`); err != nil {
+					return fmt.Errorf("failed to add %s column: %w", column, err)
+				}
+			}
+		}
+
+		// Record migration
+		_, err := s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 37, "Add launch timing to sessions")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 37: %w", err)
+		}
+
+		slog.Info("Migration 37 applied successfully")
 	}
-	if updates.DurationMS != nil {
-		setParts = append(setParts, "duration_ms = ?")
-		args = append(args, *updates.DurationMS)
+
+	// Migration 38: Add tool result JSON and error columns to conversation_events
+	if currentVersion < 38 {
+		slog.Info("Applying migration 38: Add tool result JSON and error to conversation_events")
+
+		for _, column := range []string{"tool_result_json", "tool_error"} {
+			var columnCount int
+			err := s.db.QueryRow(`
+				SELECT COUNT(*) FROM pragma_table_info('conversation_events')
+				WHERE name = ?
+			`, column).Scan(&columnCount)
+			if err != nil {
+				return fmt.Errorf("failed to check for %s column: %w", column, err)
+			}
+			if columnCount == 0 {
+				if _, err := s.db.Exec(`ALTER TABLE conversation_events ADD COLUMN ` + column + ` TEXT`); err != nil {
+					return fmt.Errorf("failed to add %s column: %w", column, err)
+				}
+			}
+		}
+
+		// Record migration
+		_, err := s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 38, "Add tool result JSON and error to conversation_events")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 38: %w", err)
+		}
+
+		slog.Info("Migration 38 applied successfully")
 	}
-	if updates.NumTurns != nil {
-		setParts = append(setParts, "num_turns = ?")
-		args = append(args, *updates.NumTurns)
+
+	// Migration 39: Add session_tags table for free-form session metadata
+	if currentVersion < 39 {
+		slog.Info("Applying migration 39: Add session_tags table")
+
+		_, err := s.db.Exec(`
+			CREATE TABLE IF NOT EXISTS session_tags (
+				session_id TEXT NOT NULL,
+				key TEXT NOT NULL,
+				value TEXT NOT NULL,
+				PRIMARY KEY (session_id, key),
+				FOREIGN KEY (session_id) REFERENCES sessions(id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_session_tags_key_value ON session_tags(key, value);
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to create session_tags table: %w", err)
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 39, "Add session_tags table for free-form session metadata")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 39: %w", err)
+		}
+
+		slog.Info("Migration 39 applied successfully")
 	}
-	if updates.ResultContent != nil {
-		setParts = append(setParts, "result_content = ?")
-		args = append(args, *updates.ResultContent)
+
+	// Migration 40: Add full-text search over conversation event content
+	if currentVersion < 40 {
+		slog.Info("Applying migration 40: Add conversation_events_fts search index")
+
+		if err := s.createEventSearchIndex(); err != nil {
+			return err
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 40, "Add conversation_events_fts full-text index kept in sync by triggers")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 40: %w", err)
+		}
+
+		slog.Info("Migration 40 applied successfully")
 	}
-	if updates.ErrorMessage != nil {
-		setParts = append(setParts, "error_message = ?")
-		args = append(args, *updates.ErrorMessage)
+
+	// Migrations 41 and 42 are SQL files applied by ApplyMigrations
+
+	// Migration 43: Add max_cost_usd budget column to sessions. It stays
+	// here rather than in a SQL file because ADD COLUMN can't be made
+	// idempotent in SQL.
+	if currentVersion < 43 {
+		slog.Info("Applying migration 43: Add max_cost_usd to sessions")
+
+		var columnCount int
+		err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM pragma_table_info('sessions')
+			WHERE name = 'max_cost_usd'
+		`).Scan(&columnCount)
+		if err != nil {
+			return fmt.Errorf("failed to check for max_cost_usd column: %w", err)
+		}
+		if columnCount == 0 {
+			if _, err := s.db.Exec(`ALTER TABLE sessions ADD COLUMN max_cost_usd REAL`); err != nil {
+				return fmt.Errorf("failed to add max_cost_usd column: %w", err)
+			}
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 43, "Add max_cost_usd budget column to sessions")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 43: %w", err)
+		}
+
+		slog.Info("Migration 43 applied successfully")
 	}
-	if updates.Summary != nil {
-		setParts = append(setParts, "summary = ?")
+
+	// Migration 44 is a SQL file applied by ApplyMigrations
+
+	// Migration 45: Record who decided each approval
+	if currentVersion < 45 {
+		slog.Info("Applying migration 45: Add responded_by to approvals")
+
+		var columnCount int
+		err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM pragma_table_info('approvals')
+			WHERE name = 'responded_by'
+		`).Scan(&columnCount)
+		if err != nil {
+			return fmt.Errorf("failed to check for responded_by column: %w", err)
+		}
+		if columnCount == 0 {
+			if _, err := s.db.Exec(`ALTER TABLE approvals ADD COLUMN responded_by TEXT`); err != nil {
+				return fmt.Errorf("failed to add responded_by column: %w", err)
+			}
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 45, "Add responded_by to approvals")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 45: %w", err)
+		}
+
+		slog.Info("Migration 45 applied successfully")
+	}
+
+	// Migration 46: Add deleted_at to sessions for soft deletes
+	if currentVersion < 46 {
+		slog.Info("Applying migration 46: Add deleted_at to sessions")
+
+		var columnCount int
+		err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM pragma_table_info('sessions')
+			WHERE name = 'deleted_at'
+		`).Scan(&columnCount)
+		if err != nil {
+			return fmt.Errorf("failed to check for deleted_at column: %w", err)
+		}
+		if columnCount == 0 {
+			if _, err := s.db.Exec(`ALTER TABLE sessions ADD COLUMN deleted_at TIMESTAMP`); err != nil {
+				return fmt.Errorf("failed to add deleted_at column: %w", err)
+			}
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 46, "Add deleted_at to sessions for soft deletes")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 46: %w", err)
+		}
+
+		slog.Info("Migration 46 applied successfully")
+	}
+
+	// Migration 47: Add launch priority to sessions
+	if currentVersion < 47 {
+		slog.Info("Applying migration 47: Add priority to sessions")
+
+		var columnCount int
+		err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM pragma_table_info('sessions')
+			WHERE name = 'priority'
+		`).Scan(&columnCount)
+		if err != nil {
+			return fmt.Errorf("failed to check for priority column: %w", err)
+		}
+		if columnCount == 0 {
+			if _, err := s.db.Exec(`ALTER TABLE sessions ADD COLUMN priority INTEGER NOT NULL DEFAULT 0`); err != nil {
+				return fmt.Errorf("failed to add priority column: %w", err)
+			}
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 47, "Add priority to sessions")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 47: %w", err)
+		}
+
+		slog.Info("Migration 47 applied successfully")
+	}
+
+	// Migration 48: Index sessions by model for per-model listings
+	if currentVersion < 48 {
+		slog.Info("Applying migration 48: Add model index to sessions")
+
+		_, err := s.db.Exec(`
+			CREATE INDEX IF NOT EXISTS idx_sessions_model
+			ON sessions(model)
+		`)
+		if err != nil {
+			return fmt.Errorf("migration 48 failed to create model index: %w", err)
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 48, "Add model index to sessions")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 48: %w", err)
+		}
+
+		slog.Info("Migration 48 applied successfully")
+	}
+
+	// Migration 49: Add idempotency_key to conversation_events so retried appends are no-ops
+	if currentVersion < 49 {
+		slog.Info("Applying migration 49: Add idempotency_key to conversation_events")
+
+		var columnExists int
+		err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM pragma_table_info('conversation_events')
+			WHERE name = 'idempotency_key'
+		`).Scan(&columnExists)
+		if err != nil {
+			return fmt.Errorf("failed to check for idempotency_key column: %w", err)
+		}
+		if columnExists == 0 {
+			if _, err := s.db.Exec(`ALTER TABLE conversation_events ADD COLUMN idempotency_key TEXT`); err != nil {
+				return fmt.Errorf("failed to add idempotency_key column: %w", err)
+			}
+		}
+
+		// Events without a key are NULL, which never conflict
+		_, err = s.db.Exec(`
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_conversation_idempotency_key
+			ON conversation_events(session_id, idempotency_key)
+			WHERE idempotency_key IS NOT NULL
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to create idempotency_key index: %w", err)
+		}
+
+		// Record migration
+		_, err = s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 49, "Add idempotency_key to conversation_events")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 49: %w", err)
+		}
+
+		slog.Info("Migration 49 applied successfully")
+	}
+
+	// Migration 50: Add archive tables for sessions moved out of the main tables
+	if currentVersion < 50 {
+		slog.Info("Applying migration 50: Add archived_sessions and archived_conversation_events")
+
+		// The archive tables copy their tables' columns
+		if err := s.syncArchiveTables(); err != nil {
+			return fmt.Errorf("migration 50 failed to create archive tables: %w", err)
+		}
+		for _, stmt := range []string{
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_archived_sessions_id ON archived_sessions(id)`,
+			`CREATE INDEX IF NOT EXISTS idx_archived_conversation_events_session ON archived_conversation_events(session_id)`,
+		} {
+			if _, err := s.db.Exec(stmt); err != nil {
+				return fmt.Errorf("migration 50 failed to create archive index: %w", err)
+			}
+		}
+
+		// Record migration
+		_, err := s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 50, "Add archived_sessions and archived_conversation_events")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 50: %w", err)
+		}
+
+		slog.Info("Migration 50 applied successfully")
+	}
+
+	// Migration 51: Index archived events for permalink and conversation reads
+	if currentVersion < 51 {
+		slog.Info("Applying migration 51: Index archived_conversation_events by permalink and Claude session")
+
+		for _, stmt := range []string{
+			`CREATE INDEX IF NOT EXISTS idx_archived_conversation_events_permalink ON archived_conversation_events(permalink)`,
+			`CREATE INDEX IF NOT EXISTS idx_archived_conversation_events_claude ON archived_conversation_events(claude_session_id, sequence)`,
+		} {
+			if _, err := s.db.Exec(stmt); err != nil {
+				return fmt.Errorf("migration 51 failed to create archive index: %w", err)
+			}
+		}
+
+		// Record migration
+		_, err := s.db.Exec(`
+			INSERT INTO schema_version (version, description)
+			VALUES (?, ?)
+		`, 51, "Index archived_conversation_events by permalink and Claude session")
+		if err != nil {
+			return fmt.Errorf("failed to record migration 51: %w", err)
+		}
+
+		slog.Info("Migration 51 applied successfully")
+	}
+
+	// Migration 52: Chain audit outcomes with completion records
+	if currentVersion < 52 {
+		slog.Info("Applying migration 52: Chain audit outcomes with completion records")
+
+		if err := s.addAuditCompletionRecords(); err != nil {
+			return fmt.Errorf("migration 52 failed: %w", err)
+		}
+
+		slog.Info("Migration 52 applied successfully")
+	}
+
+	return nil
+}
+
+// addAuditCompletionRecords adds the completes_id column to audit_log and
+// appends a completion record for every entry already completed, so their
+// outcomes are covered by the chain too
+func (s *SQLiteStore) addAuditCompletionRecords() error {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Re-running the migration, e.g. after its version record was lost, keeps
+	// the column and records it already added
+	var columnExists int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM pragma_table_info('audit_log') WHERE name = 'completes_id'
+	`).Scan(&columnExists); err != nil {
+		return fmt.Errorf("failed to check for completes_id column: %w", err)
+	}
+	if columnExists == 0 {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE audit_log ADD COLUMN completes_id INTEGER`); err != nil {
+			return fmt.Errorf("failed to add completes_id column: %w", err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, identity, method, session_id, outcome, error_message, completed_at
+		FROM audit_log
+		WHERE outcome != ? AND completed_at IS NOT NULL AND completes_id IS NULL
+			AND id NOT IN (SELECT completes_id FROM audit_log WHERE completes_id IS NOT NULL)
+		ORDER BY id ASC
+	`, AuditOutcomePending)
+	if err != nil {
+		return fmt.Errorf("failed to query completed audit entries: %w", err)
+	}
+	var completed []*AuditEntry
+	for rows.Next() {
+		entry := &AuditEntry{}
+		var sessionID, errorMessage sql.NullString
+		var completedAt time.Time
+		if err := rows.Scan(&entry.ID, &entry.Identity, &entry.Method, &sessionID,
+			&entry.Outcome, &errorMessage, &completedAt); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.SessionID = sessionID.String
+		entry.ErrorMessage = errorMessage.String
+		entry.CompletedAt = &completedAt
+		completed = append(completed, entry)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read completed audit entries: %w", err)
+	}
+
+	for _, entry := range completed {
+		if err := appendAuditCompletion(ctx, tx, entry); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO schema_version (version, description)
+		VALUES (?, ?)
+	`, 52, "Chain audit outcomes with completion records"); err != nil {
+		return fmt.Errorf("failed to record migration 52: %w", err)
+	}
+	return tx.Commit()
+}
+
+// validateSchema ensures the database schema is in the expected state
+func (s *SQLiteStore) validateSchema() error {
+	// Validate user_settings table exists
+	var userSettingsExists int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type='table' AND name='user_settings'
+	`).Scan(&userSettingsExists)
+	if err != nil {
+		return fmt.Errorf("failed to validate user_settings table: %w", err)
+	}
+	if userSettingsExists == 0 {
+		return fmt.Errorf("schema validation failed: user_settings table missing")
+	}
+
+	// Validate additional_directories column exists
+	var additionalDirsExists int
+	err = s.db.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info('sessions')
+		WHERE name = 'additional_directories'
+	`).Scan(&additionalDirsExists)
+	if err != nil {
+		return fmt.Errorf("failed to validate additional_directories column: %w", err)
+	}
+	if additionalDirsExists == 0 {
+		return fmt.Errorf("schema validation failed: additional_directories column missing")
+	}
+
+	// Validate schema version is at least 19
+	var currentVersion int
+	err = s.db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&currentVersion)
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+	if currentVersion < 19 {
+		return fmt.Errorf("schema validation failed: version %d is less than required 19", currentVersion)
+	}
+
+	// Validate this build can maintain the conversation search index
+	if err := s.checkEventSearchIndex(); err != nil {
+		return fmt.Errorf("schema validation failed: %w", err)
+	}
+
+	slog.Info("Schema validation successful",
+		"version", currentVersion,
+		"user_settings_table", "present",
+		"additional_directories_column", "present")
+
+	return nil
+}
+
+// Close closes the database connection
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Ping runs SELECT 1 against the database
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	var one int
+	if err := s.db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// CreateSession creates a new session
+func (s *SQLiteStore) CreateSession(ctx context.Context, session *Session) error {
+	return s.insertSession(ctx, s.db, session)
+}
+
+// insertSession writes a new session row through db, which may be a
+// transaction
+func (s *SQLiteStore) insertSession(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}, session *Session) error {
+	session, err := s.encryptSession(session)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO sessions (
+			id, run_id, claude_session_id, parent_session_id,
+			query, summary, title, model, model_id, working_dir, max_turns, system_prompt, append_system_prompt, custom_instructions,
+			permission_prompt_tool, allowed_tools, disallowed_tools,
+			status, created_at, last_activity_at, auto_accept_edits, archived, dangerously_skip_permissions, dangerously_skip_permissions_expires_at,
+			dangerously_skip_permissions_timeout_ms,
+			proxy_enabled, proxy_base_url, proxy_model_override, proxy_api_key, additional_directories, editor_state, imported, owner, bypass_tool_cache, tool_quota, queued_at, started_at, first_event_at,
+			max_cost_usd, priority
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = db.ExecContext(ctx, query,
+		session.ID, session.RunID, session.ClaudeSessionID, session.ParentSessionID,
+		session.Query, session.Summary, session.Title, session.Model, session.ModelID, session.WorkingDir, session.MaxTurns,
+		session.SystemPrompt, session.AppendSystemPrompt, session.CustomInstructions,
+		session.PermissionPromptTool, session.AllowedTools, session.DisallowedTools,
+		session.Status, session.CreatedAt, session.LastActivityAt, session.AutoAcceptEdits, session.Archived,
+		session.DangerouslySkipPermissions, session.DangerouslySkipPermissionsExpiresAt,
+		session.DangerouslySkipPermissionsTimeoutMs,
+		session.ProxyEnabled, session.ProxyBaseURL, session.ProxyModelOverride, session.ProxyAPIKey,
+		session.AdditionalDirectories, session.EditorState, session.Imported, session.Owner, session.BypassToolCache, session.ToolQuota, session.QueuedAt, session.StartedAt, session.FirstEventAt,
+		session.MaxCostUSD, session.Priority,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// UpdateSession updates session fields
+func (s *SQLiteStore) UpdateSession(ctx context.Context, sessionID string, updates SessionUpdate) error {
+	query := `UPDATE sessions SET`
+	args := []interface{}{}
+	setParts := []string{}
+
+	if updates.LastActivityAt != nil {
+		setParts = append(setParts, "last_activity_at = ?")
+		args = append(args, *updates.LastActivityAt)
+	}
+	if updates.ClaudeSessionID != nil {
+		setParts = append(setParts, "claude_session_id = ?")
+		args = append(args, *updates.ClaudeSessionID)
+	}
+	if updates.Query != nil {
+		setParts = append(setParts, "query = ?")
+		args = append(args, *updates.Query)
+	}
+	if updates.Status != nil {
+		setParts = append(setParts, "status = ?")
+		args = append(args, *updates.Status)
+	}
+	if updates.CompletedAt != nil {
+		setParts = append(setParts, "completed_at = ?")
+		args = append(args, *updates.CompletedAt)
+	}
+	if updates.QueuedAt != nil {
+		setParts = append(setParts, "queued_at = ?")
+		args = append(args, *updates.QueuedAt)
+	}
+	if updates.StartedAt != nil {
+		setParts = append(setParts, "started_at = ?")
+		args = append(args, *updates.StartedAt)
+	}
+	if updates.FirstEventAt != nil {
+		setParts = append(setParts, "first_event_at = ?")
+		args = append(args, *updates.FirstEventAt)
+	}
+	if updates.MaxCostUSD != nil {
+		setParts = append(setParts, "max_cost_usd = ?")
+		if *updates.MaxCostUSD != nil {
+			args = append(args, **updates.MaxCostUSD)
+		} else {
+			args = append(args, nil)
+		}
+	}
+	if updates.CostUSD != nil {
+		setParts = append(setParts, "cost_usd = ?")
+		args = append(args, *updates.CostUSD)
+	}
+	if updates.InputTokens != nil {
+		setParts = append(setParts, "input_tokens = ?")
+		args = append(args, *updates.InputTokens)
+	}
+	if updates.OutputTokens != nil {
+		setParts = append(setParts, "output_tokens = ?")
+		args = append(args, *updates.OutputTokens)
+	}
+	if updates.CacheCreationInputTokens != nil {
+		setParts = append(setParts, "cache_creation_input_tokens = ?")
+		args = append(args, *updates.CacheCreationInputTokens)
+	}
+	if updates.CacheReadInputTokens != nil {
+		setParts = append(setParts, "cache_read_input_tokens = ?")
+		args = append(args, *updates.CacheReadInputTokens)
+	}
+	if updates.EffectiveContextTokens != nil {
+		setParts = append(setParts, "effective_context_tokens = ?")
+		args = append(args, *updates.EffectiveContextTokens)
+	}
+	if updates.DurationMS != nil {
+		setParts = append(setParts, "duration_ms = ?")
+		args = append(args, *updates.DurationMS)
+	}
+	if updates.NumTurns != nil {
+		setParts = append(setParts, "num_turns = ?")
+		args = append(args, *updates.NumTurns)
+	}
+	if updates.ResultContent != nil {
+		resultContent, err := s.cipher.encrypt(*updates.ResultContent)
+		if err != nil {
+			return err
+		}
+		setParts = append(setParts, "result_content = ?")
+		args = append(args, resultContent)
+	}
+	if updates.ErrorMessage != nil {
+		setParts = append(setParts, "error_message = ?")
+		args = append(args, *updates.ErrorMessage)
+	}
+	if updates.Summary != nil {
+		setParts = append(setParts, "summary = ?")
 		args = append(args, *updates.Summary)
 	}
-	if updates.Title != nil {
-		setParts = append(setParts, "title = ?")
-		args = append(args, *updates.Title)
+	if updates.Title != nil {
+		setParts = append(setParts, "title = ?")
+		args = append(args, *updates.Title)
+	}
+	if updates.AutoAcceptEdits != nil {
+		setParts = append(setParts, "auto_accept_edits = ?")
+		args = append(args, *updates.AutoAcceptEdits)
+	}
+	if updates.DangerouslySkipPermissions != nil {
+		setParts = append(setParts, "dangerously_skip_permissions = ?")
+		args = append(args, *updates.DangerouslySkipPermissions)
+	}
+	if updates.DangerouslySkipPermissionsExpiresAt != nil {
+		setParts = append(setParts, "dangerously_skip_permissions_expires_at = ?")
+		if *updates.DangerouslySkipPermissionsExpiresAt != nil {
+			args = append(args, **updates.DangerouslySkipPermissionsExpiresAt)
+		} else {
+			args = append(args, nil)
+		}
+	}
+	if updates.DangerouslySkipPermissionsTimeoutMs != nil {
+		setParts = append(setParts, "dangerously_skip_permissions_timeout_ms = ?")
+		args = append(args, *updates.DangerouslySkipPermissionsTimeoutMs)
+	}
+	if updates.Model != nil {
+		setParts = append(setParts, "model = ?")
+		args = append(args, *updates.Model)
+	}
+	if updates.ModelID != nil {
+		setParts = append(setParts, "model_id = ?")
+		args = append(args, *updates.ModelID)
+	}
+	if updates.Archived != nil {
+		setParts = append(setParts, "archived = ?")
+		args = append(args, *updates.Archived)
+	}
+	// Handle proxy field updates
+	if updates.ProxyEnabled != nil {
+		setParts = append(setParts, "proxy_enabled = ?")
+		args = append(args, *updates.ProxyEnabled)
+	}
+	if updates.ProxyBaseURL != nil {
+		setParts = append(setParts, "proxy_base_url = ?")
+		args = append(args, *updates.ProxyBaseURL)
+	}
+	if updates.ProxyModelOverride != nil {
+		setParts = append(setParts, "proxy_model_override = ?")
+		args = append(args, *updates.ProxyModelOverride)
+	}
+	if updates.ProxyAPIKey != nil {
+		proxyAPIKey, err := s.cipher.encrypt(*updates.ProxyAPIKey)
+		if err != nil {
+			return err
+		}
+		setParts = append(setParts, "proxy_api_key = ?")
+		args = append(args, proxyAPIKey)
+	}
+	if updates.AdditionalDirectories != nil {
+		setParts = append(setParts, "additional_directories = ?")
+		args = append(args, *updates.AdditionalDirectories)
+	}
+	if updates.WorkingDir != nil {
+		setParts = append(setParts, "working_dir = ?")
+		args = append(args, *updates.WorkingDir)
+	}
+	if updates.EditorState != nil {
+		setParts = append(setParts, "editor_state = ?")
+		args = append(args, *updates.EditorState)
+	}
+
+	if len(setParts) == 0 {
+		// No fields to update is OK - this is a no-op
+		return nil
+	}
+
+	query += " " + strings.Join(setParts, ", ")
+
+	query += " WHERE id = ?"
+	args = append(args, sessionID)
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	if updates.Status != nil && IsTerminalSessionStatus(*updates.Status) {
+		s.events.closeSession(sessionID)
+	}
+	return nil
+}
+
+// HardDeleteSession permanently deletes a session from the database
+func (s *SQLiteStore) HardDeleteSession(ctx context.Context, sessionID string) error {
+	query := `DELETE FROM sessions WHERE id = ?`
+	result, err := s.db.ExecContext(ctx, query, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	s.events.closeSession(sessionID)
+	return nil
+}
+
+// removableSessionWhere matches the sessions s that eviction and retention
+// may delete: those in a terminal status without pending approvals, that no
+// other session was continued from, so child conversations keep their
+// history. Soft-deleted sessions still take up storage, so they match too;
+// callers look them up with GetSessionIncludingDeleted. Its arguments come
+// from removableSessionArgs.
+const removableSessionWhere = `s.status IN (?, ?, ?, ?, ?)
+			AND NOT EXISTS (SELECT 1 FROM sessions c WHERE c.parent_session_id = s.id)
+			AND NOT EXISTS (SELECT 1 FROM approvals a WHERE a.session_id = s.id AND a.status = ?)`
+
+// removableSessionArgs returns the arguments of removableSessionWhere
+func removableSessionArgs() []interface{} {
+	return []interface{}{
+		SessionStatusCompleted, SessionStatusFailed, SessionStatusInterrupted, SessionStatusDiscarded, SessionStatusCancelled,
+		ApprovalStatusLocalPending,
+	}
+}
+
+// GetEvictableSessionIDs returns the IDs of sessions that should be evicted to
+// bring the total session count down to maxSessions, least recently active
+// first. Every stored session is counted, but only those matching
+// removableSessionWhere are candidates.
+func (s *SQLiteStore) GetEvictableSessionIDs(ctx context.Context, maxSessions int) ([]string, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sessions").Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count sessions: %w", err)
+	}
+
+	excess := total - maxSessions
+	if excess <= 0 {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT s.id FROM sessions s
+		WHERE `+removableSessionWhere+`
+		ORDER BY s.last_activity_at ASC
+		LIMIT ?
+	`, append(removableSessionArgs(), excess)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query evictable sessions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan session id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetExpiredSessionIDs returns the IDs of sessions matching
+// removableSessionWhere that were last active before olderThan, least
+// recently active first
+func (s *SQLiteStore) GetExpiredSessionIDs(ctx context.Context, olderThan time.Time) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT s.id FROM sessions s
+		WHERE `+removableSessionWhere+`
+			AND s.last_activity_at < ?
+		ORDER BY s.last_activity_at ASC
+	`, append(removableSessionArgs(), olderThan)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired sessions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan session id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// IncrementalVacuum returns the database file's free pages to the
+// filesystem. Databases are created in incremental auto-vacuum mode, and
+// older ones are converted by a full VACUUM the first time this runs.
+func (s *SQLiteStore) IncrementalVacuum(ctx context.Context) error {
+	// The mode comes from the connection's DSN, so the conversion must run
+	// on the connection whose mode was checked
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	var mode int
+	if err := conn.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		return fmt.Errorf("failed to get auto vacuum mode: %w", err)
+	}
+	if mode == 0 {
+		if _, err := conn.ExecContext(ctx, "VACUUM"); err != nil {
+			return fmt.Errorf("failed to vacuum database: %w", err)
+		}
+		return nil
+	}
+
+	// incremental_vacuum frees a page per step, so it must be read to the
+	// end rather than executed
+	rows, err := conn.QueryContext(ctx, "PRAGMA incremental_vacuum")
+	if err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	return nil
+}
+
+// DeleteSessionData permanently deletes a session together with its
+// conversation events, approvals, MCP servers, raw events and file snapshots
+func (s *SQLiteStore) DeleteSessionData(ctx context.Context, sessionID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := deleteSessionRows(ctx, tx, sessionID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.events.closeSession(sessionID)
+	return nil
+}
+
+// deleteSessionRows deletes a session and every row that references it
+func deleteSessionRows(ctx context.Context, tx *sql.Tx, sessionID string) error {
+	// Annotations reference the session's events rather than the session
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM annotations
+		WHERE event_id IN (SELECT id FROM conversation_events WHERE session_id = ?)
+	`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete from annotations: %w", err)
+	}
+	for _, table := range []string{"conversation_events", "approvals", "mcp_servers", "raw_events", "file_snapshots", "session_turns", "attachments", "session_tags"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE session_id = ?", sessionID); err != nil {
+			return fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return &NotFoundError{Type: "session", ID: sessionID}
+	}
+	return nil
+}
+
+// ArchiveSession moves a terminal session and all of its data to the
+// archive tables in one transaction, out of the way of queries on active
+// sessions. RestoreArchivedSession moves it back. Sessions continued from
+// can't be archived, since their continuations still read their history.
+func (s *SQLiteStore) ArchiveSession(ctx context.Context, sessionID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var status string
+	var continued bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, EXISTS (SELECT 1 FROM sessions c WHERE c.parent_session_id = s.id)
+		FROM sessions s WHERE id = ?
+	`, sessionID).Scan(&status, &continued)
+	if err == sql.ErrNoRows {
+		return &NotFoundError{Type: "session", ID: sessionID}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	if !IsTerminalSessionStatus(status) {
+		return fmt.Errorf("cannot archive session %s while it is %s", sessionID, status)
+	}
+	if continued {
+		return fmt.Errorf("cannot archive session %s while sessions continued from it remain", sessionID)
+	}
+
+	for _, t := range archiveTables {
+		if err := copySessionRows(ctx, tx, t.table, t.archive, t.table, t.sessionRows, sessionID); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", t.table, err)
+		}
+	}
+	if err := deleteSessionRows(ctx, tx, sessionID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.events.closeSession(sessionID)
+	return nil
+}
+
+// RestoreArchivedSession moves a session archived by ArchiveSession and all
+// of its data back to the main tables in one transaction
+func (s *SQLiteStore) RestoreArchivedSession(ctx context.Context, sessionID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var archived bool
+	if err := tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM archived_sessions WHERE id = ?)", sessionID).Scan(&archived); err != nil {
+		return fmt.Errorf("failed to get archived session: %w", err)
+	}
+	if !archived {
+		return &NotFoundError{Type: "archived session", ID: sessionID}
+	}
+
+	// Rows are copied in archiveTables order, so sessions exist before the
+	// rows referencing them and events before their annotations
+	for _, t := range archiveTables {
+		if err := copySessionRows(ctx, tx, t.table, t.table, t.archive, t.sessionRows, sessionID); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", t.table, err)
+		}
+	}
+	for _, t := range archiveTables {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+t.archive+" WHERE "+t.sessionRows, sessionID); err != nil {
+			return fmt.Errorf("failed to delete from %s: %w", t.archive, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// copySessionRows copies a session's rows of table, selected by
+// sessionRows, from one of table and its archive table to the other.
+// Only table's columns are copied, so archive-only columns are left behind.
+func copySessionRows(ctx context.Context, tx *sql.Tx, table, to, from, sessionRows, sessionID string) error {
+	columns, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+	list := strings.Join(columns, ", ")
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO "+to+" ("+list+") SELECT "+list+" FROM "+from+" WHERE "+sessionRows, sessionID)
+	return err
+}
+
+// tableColumns returns the names of a table's columns in order
+func tableColumns(ctx context.Context, q interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}, table string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, "SELECT name FROM pragma_table_info(?) ORDER BY cid", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s columns: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan %s column: %w", table, err)
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+// archiveTables lists every table holding a session's data, the archive
+// table ArchiveSession moves its rows to, and the condition selecting a
+// session's rows in either. Annotations reference the session's events
+// rather than the session, so they are selected through conversation_events
+// and listed after it.
+var archiveTables = []struct{ table, archive, sessionRows string }{
+	{"sessions", "archived_sessions", "id = ?"},
+	{"conversation_events", "archived_conversation_events", "session_id = ?"},
+	{"annotations", "archived_annotations", "event_id IN (SELECT id FROM conversation_events WHERE session_id = ?)"},
+	{"approvals", "archived_approvals", "session_id = ?"},
+	{"mcp_servers", "archived_mcp_servers", "session_id = ?"},
+	{"raw_events", "archived_raw_events", "session_id = ?"},
+	{"file_snapshots", "archived_file_snapshots", "session_id = ?"},
+	{"session_turns", "archived_session_turns", "session_id = ?"},
+	{"attachments", "archived_attachments", "session_id = ?"},
+	{"session_tags", "archived_session_tags", "session_id = ?"},
+}
+
+// syncArchiveTables gives each archive table every column of its table,
+// creating it if need be, so migrations adding columns need not repeat them
+// for the archive. Columns keep their declared types, which the driver
+// relies on to read timestamps, but none of their constraints.
+func (s *SQLiteStore) syncArchiveTables() error {
+	ctx := context.Background()
+	for _, t := range archiveTables {
+		archived, err := tableColumns(ctx, s.db, t.archive)
+		if err != nil {
+			return err
+		}
+		if len(archived) == 0 {
+			// Tables need a column to be created with. archived_at
+			// records when each row was archived.
+			if _, err := s.db.Exec("CREATE TABLE " + t.archive + " (archived_at DATETIME DEFAULT CURRENT_TIMESTAMP)"); err != nil {
+				return fmt.Errorf("failed to create %s: %w", t.archive, err)
+			}
+		}
+		have := make(map[string]bool, len(archived))
+		for _, name := range archived {
+			have[name] = true
+		}
+
+		rows, err := s.db.Query("SELECT name, type FROM pragma_table_info(?) ORDER BY cid", t.table)
+		if err != nil {
+			return fmt.Errorf("failed to get %s columns: %w", t.table, err)
+		}
+		var missing [][2]string
+		for rows.Next() {
+			var name, declaredType string
+			if err := rows.Scan(&name, &declaredType); err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("failed to scan %s column: %w", t.table, err)
+			}
+			if !have[name] {
+				missing = append(missing, [2]string{name, declaredType})
+			}
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, column := range missing {
+			if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", t.archive, column[0], column[1])); err != nil {
+				return fmt.Errorf("failed to add %s to %s: %w", column[0], t.archive, err)
+			}
+		}
+	}
+	return nil
+}
+
+// vacuumBatchSize is how many events VacuumOldEvents deletes per
+// transaction, so that no single write holds the database for long
+const vacuumBatchSize = 1000
+
+// vacuumableSessions selects the sessions VacuumOldEvents applies to:
+// terminal sessions last active before the cutoff that no session was
+// continued from, so child conversations keep their history
+const vacuumableSessions = `
+	SELECT s.id FROM sessions s
+	WHERE s.status IN (?, ?, ?, ?, ?)
+		AND s.last_activity_at < ?
+		AND NOT EXISTS (SELECT 1 FROM sessions c WHERE c.parent_session_id = s.id)`
+
+// VacuumOldEvents deletes old sessions' events in batches of
+// vacuumBatchSize, then deletes the sessions unless keepTerminalSessions
+func (s *SQLiteStore) VacuumOldEvents(ctx context.Context, olderThan time.Time, keepTerminalSessions bool) (int64, int64, error) {
+	args := []interface{}{
+		SessionStatusCompleted, SessionStatusFailed, SessionStatusInterrupted, SessionStatusDiscarded, SessionStatusCancelled,
+		olderThan,
+	}
+
+	var deletedEvents int64
+	for {
+		n, err := s.vacuumEventBatch(ctx, args)
+		if err != nil {
+			return deletedEvents, 0, err
+		}
+		deletedEvents += n
+		if n < vacuumBatchSize {
+			break
+		}
+	}
+	if keepTerminalSessions {
+		return deletedEvents, 0, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, vacuumableSessions, args...)
+	if err != nil {
+		return deletedEvents, 0, fmt.Errorf("failed to query old sessions: %w", err)
+	}
+	var sessionIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return deletedEvents, 0, fmt.Errorf("failed to scan session id: %w", err)
+		}
+		sessionIDs = append(sessionIDs, id)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return deletedEvents, 0, fmt.Errorf("failed to query old sessions: %w", err)
+	}
+
+	var deletedSessions int64
+	for _, id := range sessionIDs {
+		if err := s.DeleteSessionData(ctx, id); err != nil {
+			return deletedEvents, deletedSessions, fmt.Errorf("failed to delete session %s: %w", id, err)
+		}
+		deletedSessions++
+	}
+	return deletedEvents, deletedSessions, nil
+}
+
+// vacuumEventBatch deletes up to vacuumBatchSize events of the sessions
+// selected by vacuumableSessions with args, along with their annotations
+func (s *SQLiteStore) vacuumEventBatch(ctx context.Context, args []interface{}) (int64, error) {
+	query := `SELECT id FROM conversation_events WHERE session_id IN (` + vacuumableSessions + `) LIMIT ?`
+	rows, err := s.db.QueryContext(ctx, query, append(args, vacuumBatchSize)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query old events: %w", err)
+	}
+	var ids []interface{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to scan event id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query old events: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	if _, err := tx.ExecContext(ctx, "DELETE FROM annotations WHERE event_id IN ("+placeholders+")", ids...); err != nil {
+		return 0, fmt.Errorf("failed to delete from annotations: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM conversation_events WHERE id IN ("+placeholders+")", ids...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old events: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return deleted, nil
+}
+
+// ForkSession creates fork and copies the source session's events up to
+// atSequence into it in one transaction
+func (s *SQLiteStore) ForkSession(ctx context.Context, sourceSessionID string, atSequence int, fork *Session) (int, error) {
+	if atSequence < 0 {
+		return 0, fmt.Errorf("invalid fork sequence: %d", atSequence)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM sessions WHERE id = ?)", sourceSessionID).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check source session: %w", err)
+	}
+	if !exists {
+		return 0, &NotFoundError{Type: "session", ID: sourceSessionID}
+	}
+
+	if err := s.insertSession(ctx, tx, fork); err != nil {
+		return 0, err
+	}
+
+	// Copies get their own permalinks, generated like newEventPermalink's,
+	// and no content hash, so imports still dedupe against the source only
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO conversation_events (
+			session_id, claude_session_id, sequence, event_type, created_at,
+			role, content,
+			tool_id, tool_name, tool_input_json, parent_tool_use_id,
+			tool_result_for_id, tool_result_content, tool_result_bytes, tool_result_tokens,
+			is_completed, approval_status, approval_id, permalink, language, tool_cache_hit,
+			truncated, tool_result_json, tool_error
+		)
+		SELECT ?, ?, sequence, event_type, created_at,
+			role, content,
+			tool_id, tool_name, tool_input_json, parent_tool_use_id,
+			tool_result_for_id, tool_result_content, tool_result_bytes, tool_result_tokens,
+			is_completed, approval_status, approval_id, 'evt_' || lower(hex(randomblob(12))), language, tool_cache_hit,
+			truncated, tool_result_json, tool_error
+		FROM conversation_events
+		WHERE session_id = ? AND sequence <= ?
+		ORDER BY sequence, id
+	`, fork.ID, fork.ClaudeSessionID, sourceSessionID, atSequence)
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy conversation events: %w", err)
+	}
+	copied, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(copied), nil
+}
+
+// GetSession retrieves a session by ID
+func (s *SQLiteStore) GetSession(ctx context.Context, sessionID string) (*Session, error) {
+	query := `
+		SELECT id, run_id, claude_session_id, parent_session_id,
+			query, summary, title, model, model_id, working_dir, max_turns, system_prompt, append_system_prompt, custom_instructions,
+			permission_prompt_tool, allowed_tools, disallowed_tools,
+			status, created_at, last_activity_at, completed_at,
+			cost_usd, input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens, effective_context_tokens,
+			duration_ms, num_turns, result_content, error_message, auto_accept_edits, archived,
+			dangerously_skip_permissions, dangerously_skip_permissions_expires_at, dangerously_skip_permissions_timeout_ms,
+			proxy_enabled, proxy_base_url, proxy_model_override, proxy_api_key, additional_directories, editor_state, imported, owner, bypass_tool_cache, tool_quota, queued_at, started_at, first_event_at, max_cost_usd, priority
+		FROM sessions WHERE id = ? AND deleted_at IS NULL
+	`
+
+	var session Session
+	var claudeSessionID, parentSessionID, summary, title, model, modelID, workingDir, systemPrompt, appendSystemPrompt, customInstructions sql.NullString
+	var permissionPromptTool, allowedTools, disallowedTools sql.NullString
+	var completedAt sql.NullTime
+	var costUSD sql.NullFloat64
+	var inputTokens, outputTokens, cacheCreationInputTokens, cacheReadInputTokens, effectiveContextTokens sql.NullInt64
+	var durationMS, numTurns sql.NullInt64
+	var resultContent, errorMessage sql.NullString
+	var archived sql.NullBool
+	var dangerouslySkipPermissionsExpiresAt sql.NullTime
+	var dangerouslySkipPermissionsTimeoutMs sql.NullInt64
+	var proxyEnabled sql.NullBool
+	var proxyBaseURL, proxyModelOverride, proxyAPIKey sql.NullString
+	var additionalDirectories sql.NullString
+	var editorState sql.NullString
+	var imported sql.NullBool
+	var owner sql.NullString
+	var bypassToolCache sql.NullBool
+	var toolQuota sql.NullString
+	var queuedAt sql.NullTime
+	var startedAt sql.NullTime
+	var firstEventAt sql.NullTime
+	var maxCostUSD sql.NullFloat64
+
+	err := s.db.QueryRowContext(ctx, query, sessionID).Scan(
+		&session.ID, &session.RunID, &claudeSessionID, &parentSessionID,
+		&session.Query, &summary, &title, &model, &modelID, &workingDir, &session.MaxTurns,
+		&systemPrompt, &appendSystemPrompt, &customInstructions,
+		&permissionPromptTool, &allowedTools, &disallowedTools,
+		&session.Status, &session.CreatedAt, &session.LastActivityAt, &completedAt,
+		&costUSD, &inputTokens, &outputTokens, &cacheCreationInputTokens, &cacheReadInputTokens, &effectiveContextTokens,
+		&durationMS, &numTurns, &resultContent, &errorMessage, &session.AutoAcceptEdits,
+		&archived, &session.DangerouslySkipPermissions, &dangerouslySkipPermissionsExpiresAt, &dangerouslySkipPermissionsTimeoutMs,
+		&proxyEnabled, &proxyBaseURL, &proxyModelOverride, &proxyAPIKey, &additionalDirectories, &editorState, &imported, &owner, &bypassToolCache, &toolQuota, &queuedAt, &startedAt, &firstEventAt, &maxCostUSD, &session.Priority,
+	)
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Type: "session", ID: sessionID}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Handle nullable fields
+	session.ClaudeSessionID = claudeSessionID.String
+	session.ParentSessionID = parentSessionID.String
+	session.Summary = summary.String
+	session.Title = title.String
+	session.Model = model.String
+	session.ModelID = modelID.String
+	session.WorkingDir = workingDir.String
+	session.SystemPrompt = systemPrompt.String
+	session.AppendSystemPrompt = appendSystemPrompt.String
+	session.CustomInstructions = customInstructions.String
+	session.PermissionPromptTool = permissionPromptTool.String
+	session.AllowedTools = allowedTools.String
+	session.DisallowedTools = disallowedTools.String
+	session.ResultContent = resultContent.String
+	session.ErrorMessage = errorMessage.String
+	if completedAt.Valid {
+		session.CompletedAt = &completedAt.Time
+	}
+	if costUSD.Valid {
+		session.CostUSD = &costUSD.Float64
+	}
+	if inputTokens.Valid {
+		tokens := int(inputTokens.Int64)
+		session.InputTokens = &tokens
+	}
+	if outputTokens.Valid {
+		tokens := int(outputTokens.Int64)
+		session.OutputTokens = &tokens
+	}
+	if cacheCreationInputTokens.Valid {
+		tokens := int(cacheCreationInputTokens.Int64)
+		session.CacheCreationInputTokens = &tokens
+	}
+	if cacheReadInputTokens.Valid {
+		tokens := int(cacheReadInputTokens.Int64)
+		session.CacheReadInputTokens = &tokens
+	}
+	if effectiveContextTokens.Valid {
+		tokens := int(effectiveContextTokens.Int64)
+		session.EffectiveContextTokens = &tokens
+	}
+	if durationMS.Valid {
+		duration := int(durationMS.Int64)
+		session.DurationMS = &duration
+	}
+	if numTurns.Valid {
+		turns := int(numTurns.Int64)
+		session.NumTurns = &turns
+	}
+
+	// Handle archived field - default to false if NULL
+	session.Archived = archived.Valid && archived.Bool
+
+	// Handle dangerously skip permissions expires at
+	if dangerouslySkipPermissionsExpiresAt.Valid {
+		session.DangerouslySkipPermissionsExpiresAt = &dangerouslySkipPermissionsExpiresAt.Time
+	}
+
+	// Handle dangerously skip permissions timeout ms
+	if dangerouslySkipPermissionsTimeoutMs.Valid {
+		session.DangerouslySkipPermissionsTimeoutMs = &dangerouslySkipPermissionsTimeoutMs.Int64
+	}
+
+	// Handle proxy fields
+	session.ProxyEnabled = proxyEnabled.Valid && proxyEnabled.Bool
+	session.ProxyBaseURL = proxyBaseURL.String
+	session.ProxyModelOverride = proxyModelOverride.String
+	session.ProxyAPIKey = proxyAPIKey.String
+
+	// Handle additional directories
+	session.AdditionalDirectories = additionalDirectories.String
+
+	// Handle editor state
+	if editorState.Valid {
+		session.EditorState = &editorState.String
+	}
+
+	// Handle imported flag
+	session.Imported = imported.Valid && imported.Bool
+
+	// Handle owner
+	if owner.Valid {
+		session.Owner = owner.String
+	}
+
+	// Handle bypass_tool_cache
+	session.BypassToolCache = bypassToolCache.Valid && bypassToolCache.Bool
+
+	// Handle tool_quota
+	session.ToolQuota = toolQuota.String
+
+	if queuedAt.Valid {
+		session.QueuedAt = &queuedAt.Time
+	}
+
+	if startedAt.Valid {
+		session.StartedAt = &startedAt.Time
+	}
+
+	if firstEventAt.Valid {
+		session.FirstEventAt = &firstEventAt.Time
+	}
+
+	if maxCostUSD.Valid {
+		session.MaxCostUSD = &maxCostUSD.Float64
+	}
+
+	if err := s.decryptSession(&session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// GetSessionByRunID retrieves a session by its run_id
+func (s *SQLiteStore) GetSessionByRunID(ctx context.Context, runID string) (*Session, error) {
+	query := `
+		SELECT id, run_id, claude_session_id, parent_session_id,
+			query, summary, title, model, model_id, working_dir, max_turns, system_prompt, append_system_prompt, custom_instructions,
+			permission_prompt_tool, allowed_tools, disallowed_tools,
+			status, created_at, last_activity_at, completed_at,
+			cost_usd, input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens, effective_context_tokens,
+			duration_ms, num_turns, result_content, error_message, auto_accept_edits, archived,
+			dangerously_skip_permissions, dangerously_skip_permissions_expires_at, dangerously_skip_permissions_timeout_ms,
+			proxy_enabled, proxy_base_url, proxy_model_override, proxy_api_key, additional_directories, editor_state, imported, owner, bypass_tool_cache, tool_quota, queued_at, started_at, first_event_at, max_cost_usd, priority
+		FROM sessions
+		WHERE run_id = ?
+	`
+
+	var session Session
+	var claudeSessionID, parentSessionID, summary, title, model, modelID, workingDir, systemPrompt, appendSystemPrompt, customInstructions sql.NullString
+	var permissionPromptTool, allowedTools, disallowedTools sql.NullString
+	var completedAt sql.NullTime
+	var costUSD sql.NullFloat64
+	var inputTokens, outputTokens, cacheCreationInputTokens, cacheReadInputTokens, effectiveContextTokens sql.NullInt64
+	var durationMS, numTurns sql.NullInt64
+	var resultContent, errorMessage sql.NullString
+	var archived sql.NullBool
+	var dangerouslySkipPermissionsExpiresAt sql.NullTime
+	var dangerouslySkipPermissionsTimeoutMs sql.NullInt64
+	var proxyEnabled sql.NullBool
+	var proxyBaseURL, proxyModelOverride, proxyAPIKey sql.NullString
+	var additionalDirectories sql.NullString
+	var editorState sql.NullString
+	var imported sql.NullBool
+	var owner sql.NullString
+	var bypassToolCache sql.NullBool
+	var toolQuota sql.NullString
+	var queuedAt sql.NullTime
+	var startedAt sql.NullTime
+	var firstEventAt sql.NullTime
+	var maxCostUSD sql.NullFloat64
+
+	err := s.db.QueryRowContext(ctx, query, runID).Scan(
+		&session.ID, &session.RunID, &claudeSessionID, &parentSessionID,
+		&session.Query, &summary, &title, &model, &modelID, &workingDir, &session.MaxTurns,
+		&systemPrompt, &appendSystemPrompt, &customInstructions,
+		&permissionPromptTool, &allowedTools, &disallowedTools,
+		&session.Status, &session.CreatedAt, &session.LastActivityAt, &completedAt,
+		&costUSD, &inputTokens, &outputTokens, &cacheCreationInputTokens, &cacheReadInputTokens, &effectiveContextTokens,
+		&durationMS, &numTurns, &resultContent, &errorMessage, &session.AutoAcceptEdits,
+		&archived, &session.DangerouslySkipPermissions, &dangerouslySkipPermissionsExpiresAt, &dangerouslySkipPermissionsTimeoutMs,
+		&proxyEnabled, &proxyBaseURL, &proxyModelOverride, &proxyAPIKey, &additionalDirectories, &editorState, &imported, &owner, &bypassToolCache, &toolQuota, &queuedAt, &startedAt, &firstEventAt, &maxCostUSD, &session.Priority,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil // No session found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session by run_id: %w", err)
+	}
+
+	// Convert nullable fields
+	session.ClaudeSessionID = claudeSessionID.String
+	session.ParentSessionID = parentSessionID.String
+	session.Summary = summary.String
+	session.Title = title.String
+	session.Model = model.String
+	session.ModelID = modelID.String
+	session.WorkingDir = workingDir.String
+	session.SystemPrompt = systemPrompt.String
+	session.AppendSystemPrompt = appendSystemPrompt.String
+	session.CustomInstructions = customInstructions.String
+	session.PermissionPromptTool = permissionPromptTool.String
+	session.AllowedTools = allowedTools.String
+	session.DisallowedTools = disallowedTools.String
+	session.ResultContent = resultContent.String
+	session.ErrorMessage = errorMessage.String
+	if completedAt.Valid {
+		session.CompletedAt = &completedAt.Time
+	}
+	if costUSD.Valid {
+		session.CostUSD = &costUSD.Float64
+	}
+	if inputTokens.Valid {
+		tokens := int(inputTokens.Int64)
+		session.InputTokens = &tokens
+	}
+	if outputTokens.Valid {
+		tokens := int(outputTokens.Int64)
+		session.OutputTokens = &tokens
+	}
+	if cacheCreationInputTokens.Valid {
+		tokens := int(cacheCreationInputTokens.Int64)
+		session.CacheCreationInputTokens = &tokens
+	}
+	if cacheReadInputTokens.Valid {
+		tokens := int(cacheReadInputTokens.Int64)
+		session.CacheReadInputTokens = &tokens
+	}
+	if effectiveContextTokens.Valid {
+		tokens := int(effectiveContextTokens.Int64)
+		session.EffectiveContextTokens = &tokens
+	}
+	if durationMS.Valid {
+		duration := int(durationMS.Int64)
+		session.DurationMS = &duration
+	}
+	if numTurns.Valid {
+		turns := int(numTurns.Int64)
+		session.NumTurns = &turns
+	}
+
+	// Handle archived field - default to false if NULL
+	session.Archived = archived.Valid && archived.Bool
+
+	// Handle dangerously skip permissions expires at
+	if dangerouslySkipPermissionsExpiresAt.Valid {
+		session.DangerouslySkipPermissionsExpiresAt = &dangerouslySkipPermissionsExpiresAt.Time
+	}
+
+	// Handle dangerously skip permissions timeout ms
+	if dangerouslySkipPermissionsTimeoutMs.Valid {
+		session.DangerouslySkipPermissionsTimeoutMs = &dangerouslySkipPermissionsTimeoutMs.Int64
+	}
+
+	// Handle proxy fields
+	session.ProxyEnabled = proxyEnabled.Valid && proxyEnabled.Bool
+	session.ProxyBaseURL = proxyBaseURL.String
+	session.ProxyModelOverride = proxyModelOverride.String
+	session.ProxyAPIKey = proxyAPIKey.String
+
+	// Handle additional directories
+	session.AdditionalDirectories = additionalDirectories.String
+
+	// Handle editor state
+	if editorState.Valid {
+		session.EditorState = &editorState.String
+	}
+
+	// Handle imported flag
+	session.Imported = imported.Valid && imported.Bool
+
+	// Handle owner
+	if owner.Valid {
+		session.Owner = owner.String
+	}
+
+	// Handle bypass_tool_cache
+	session.BypassToolCache = bypassToolCache.Valid && bypassToolCache.Bool
+
+	// Handle tool_quota
+	session.ToolQuota = toolQuota.String
+
+	if queuedAt.Valid {
+		session.QueuedAt = &queuedAt.Time
+	}
+
+	if startedAt.Valid {
+		session.StartedAt = &startedAt.Time
+	}
+
+	if firstEventAt.Valid {
+		session.FirstEventAt = &firstEventAt.Time
+	}
+
+	if maxCostUSD.Valid {
+		session.MaxCostUSD = &maxCostUSD.Float64
+	}
+
+	if err := s.decryptSession(&session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// GetSessionIncludingDeleted retrieves a session by ID even if it is
+// soft-deleted, for the paths that delete or evict sessions
+func (s *SQLiteStore) GetSessionIncludingDeleted(ctx context.Context, sessionID string) (*Session, error) {
+	sessions, err := s.listSessions(ctx, ListSessionsFilter{IDs: []string{sessionID}, IncludeDeleted: true}, "id")
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, &NotFoundError{Type: "session", ID: sessionID}
+	}
+	return sessions[0], nil
+}
+
+// SoftDeleteSession hides a finished session from GetSession and session
+// listings without removing any of its data. Sessions that are still
+// active can't be soft-deleted, since the daemon looks them up as they run.
+func (s *SQLiteStore) SoftDeleteSession(ctx context.Context, sessionID string) error {
+	session, err := s.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if !IsTerminalSessionStatus(session.Status) {
+		return fmt.Errorf("cannot soft-delete session %s while it is %s", sessionID, session.Status)
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE sessions SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL",
+		time.Now(), sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to soft-delete session: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return &NotFoundError{Type: "session", ID: sessionID}
+	}
+	return nil
+}
+
+// RestoreSession makes a soft-deleted session visible again
+func (s *SQLiteStore) RestoreSession(ctx context.Context, sessionID string) error {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE sessions SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL",
+		sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to restore session: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return &NotFoundError{Type: "session", ID: sessionID}
+	}
+	return nil
+}
+
+// GetSessionsByRunID retrieves every session with the given run_id, oldest first
+func (s *SQLiteStore) GetSessionsByRunID(ctx context.Context, runID string) ([]*Session, error) {
+	return s.listSessions(ctx, ListSessionsFilter{RunID: runID}, "created_at, id")
+}
+
+// ListSessions retrieves all sessions
+func (s *SQLiteStore) ListSessions(ctx context.Context, filter ListSessionsFilter) ([]*Session, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = SessionSortLastActivity
+	}
+	column, ok := sessionSortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown session sort %q", filter.SortBy)
+	}
+	direction := "DESC"
+	if filter.SortAscending {
+		direction = "ASC"
+	}
+	// Ties are broken by ID so pages don't overlap
+	return s.listSessions(ctx, filter, column+" "+direction+", id "+direction)
+}
+
+// sessionSortColumns are the SQL expressions ListSessions orders by for each
+// SessionSort value. Missing costs and token counts sort as zero.
+var sessionSortColumns = map[string]string{
+	SessionSortCreatedAt:    "created_at",
+	SessionSortLastActivity: "last_activity_at",
+	SessionSortCost:         "COALESCE(cost_usd, 0)",
+	SessionSortTotalTokens: "(COALESCE(input_tokens, 0) + COALESCE(output_tokens, 0) + " +
+		"COALESCE(cache_creation_input_tokens, 0) + COALESCE(cache_read_input_tokens, 0))",
+}
+
+// GetSessionsByModel retrieves the most recently created sessions using model
+func (s *SQLiteStore) GetSessionsByModel(ctx context.Context, model string, limit int) ([]*Session, error) {
+	if model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	return s.listSessions(ctx, ListSessionsFilter{Model: model, Limit: limit}, "created_at DESC, id")
+}
+
+// ListQueuedSessions retrieves the sessions waiting for a launch slot in the
+// order the launch scheduler grants them
+func (s *SQLiteStore) ListQueuedSessions(ctx context.Context) ([]*Session, error) {
+	filter := ListSessionsFilter{Status: []string{SessionStatusQueued}}
+	return s.listSessions(ctx, filter, "priority DESC, created_at ASC, id ASC")
+}
+
+// sessionListColumns are the sessions columns listSessions scans
+const sessionListColumns = `
+			id, run_id, claude_session_id, parent_session_id,
+			query, summary, title, model, model_id, working_dir, max_turns, system_prompt, append_system_prompt, custom_instructions,
+			permission_prompt_tool, allowed_tools, disallowed_tools,
+			status, created_at, last_activity_at, completed_at,
+			cost_usd, input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens, effective_context_tokens,
+			duration_ms, num_turns, result_content, error_message, auto_accept_edits, archived,
+			dangerously_skip_permissions, dangerously_skip_permissions_expires_at, dangerously_skip_permissions_timeout_ms,
+			proxy_enabled, proxy_base_url, proxy_model_override, proxy_api_key, additional_directories, editor_state, imported, owner, bypass_tool_cache, tool_quota, queued_at, started_at, first_event_at, max_cost_usd, priority, deleted_at`
+
+// sessionsQuery builds the query selecting columns from the sessions
+// matching filter, leaving off ordering and limits
+func sessionsQuery(columns string, filter ListSessionsFilter) (string, []interface{}) {
+	from := "sessions"
+	if filter.IncludeArchived {
+		// Aliased so filters that refer to sessions.id apply to both
+		from = `(
+			SELECT ` + sessionListColumns + ` FROM sessions
+			UNION ALL
+			SELECT ` + sessionListColumns + ` FROM archived_sessions
+		) AS sessions`
+	}
+	query := `
+		SELECT ` + columns + `
+		FROM ` + from + `
+		WHERE 1 = 1
+	`
+	var args []interface{}
+	if !filter.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+	if len(filter.Status) > 0 {
+		query += " AND status IN (?" + strings.Repeat(", ?", len(filter.Status)-1) + ")"
+		for _, status := range filter.Status {
+			args = append(args, status)
+		}
+	}
+	if filter.ModelPrefix != "" {
+		// substr rather than LIKE, so the prefix needs no escaping
+		query += " AND substr(model, 1, length(?)) = ?"
+		args = append(args, filter.ModelPrefix, filter.ModelPrefix)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		query += " AND created_at > ?"
+		args = append(args, filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, filter.CreatedBefore)
+	}
+	if filter.Model != "" {
+		query += " AND model = ?"
+		args = append(args, filter.Model)
+	}
+	if filter.WorkingDirPrefix != "" {
+		query += " AND substr(working_dir, 1, length(?)) = ?"
+		args = append(args, filter.WorkingDirPrefix, filter.WorkingDirPrefix)
+	}
+	if filter.RunID != "" {
+		query += " AND run_id = ?"
+		args = append(args, filter.RunID)
+	}
+	if len(filter.IDs) > 0 {
+		query += " AND id IN (?" + strings.Repeat(", ?", len(filter.IDs)-1) + ")"
+		for _, id := range filter.IDs {
+			args = append(args, id)
+		}
+	}
+	tagKeys := make([]string, 0, len(filter.TagFilters))
+	for key := range filter.TagFilters {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	for _, key := range tagKeys {
+		query += " AND EXISTS (SELECT 1 FROM session_tags t WHERE t.session_id = sessions.id AND t.key = ? AND t.value = ?)"
+		args = append(args, key, filter.TagFilters[key])
+	}
+	return query, args
+}
+
+// CountSessions counts the sessions ListSessions returns for filter, ignoring
+// its Limit and Offset
+func (s *SQLiteStore) CountSessions(ctx context.Context, filter ListSessionsFilter) (int, error) {
+	query, args := sessionsQuery("COUNT(*)", filter)
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count sessions: %w", err)
+	}
+	return count, nil
+}
+
+// listSessions retrieves the sessions matching filter in the given order
+func (s *SQLiteStore) listSessions(ctx context.Context, filter ListSessionsFilter, orderBy string) ([]*Session, error) {
+	query, args := sessionsQuery(sessionListColumns, filter)
+	query += " ORDER BY " + orderBy
+	if filter.Limit > 0 || filter.Offset > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, sqlLimit(filter.Limit), filter.Offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sessions []*Session
+	for rows.Next() {
+		var session Session
+		var claudeSessionID, parentSessionID, summary, title, model, modelID, workingDir, systemPrompt, appendSystemPrompt, customInstructions sql.NullString
+		var permissionPromptTool, allowedTools, disallowedTools sql.NullString
+		var completedAt sql.NullTime
+		var costUSD sql.NullFloat64
+		var inputTokens, outputTokens, cacheCreationInputTokens, cacheReadInputTokens, effectiveContextTokens sql.NullInt64
+		var durationMS, numTurns sql.NullInt64
+		var resultContent, errorMessage sql.NullString
+		var archived sql.NullBool
+		var dangerouslySkipPermissionsExpiresAt sql.NullTime
+		var dangerouslySkipPermissionsTimeoutMs sql.NullInt64
+		var proxyEnabled sql.NullBool
+		var proxyBaseURL, proxyModelOverride, proxyAPIKey sql.NullString
+		var additionalDirectories sql.NullString
+		var editorState sql.NullString
+		var imported sql.NullBool
+		var owner sql.NullString
+		var bypassToolCache sql.NullBool
+		var toolQuota sql.NullString
+		var queuedAt sql.NullTime
+		var startedAt sql.NullTime
+		var firstEventAt sql.NullTime
+		var maxCostUSD sql.NullFloat64
+		var deletedAt sql.NullTime
+
+		err := rows.Scan(
+			&session.ID, &session.RunID, &claudeSessionID, &parentSessionID,
+			&session.Query, &summary, &title, &model, &modelID, &workingDir, &session.MaxTurns,
+			&systemPrompt, &appendSystemPrompt, &customInstructions,
+			&permissionPromptTool, &allowedTools, &disallowedTools,
+			&session.Status, &session.CreatedAt, &session.LastActivityAt, &completedAt,
+			&costUSD, &inputTokens, &outputTokens, &cacheCreationInputTokens, &cacheReadInputTokens, &effectiveContextTokens,
+			&durationMS, &numTurns, &resultContent, &errorMessage, &session.AutoAcceptEdits,
+			&archived, &session.DangerouslySkipPermissions, &dangerouslySkipPermissionsExpiresAt, &dangerouslySkipPermissionsTimeoutMs,
+			&proxyEnabled, &proxyBaseURL, &proxyModelOverride, &proxyAPIKey, &additionalDirectories, &editorState, &imported, &owner, &bypassToolCache, &toolQuota, &queuedAt, &startedAt, &firstEventAt, &maxCostUSD, &session.Priority, &deletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+
+		// Handle nullable fields
+		session.ClaudeSessionID = claudeSessionID.String
+		session.ParentSessionID = parentSessionID.String
+		session.Summary = summary.String
+		session.Title = title.String
+		session.Model = model.String
+		session.ModelID = modelID.String
+		session.WorkingDir = workingDir.String
+		session.SystemPrompt = systemPrompt.String
+		session.AppendSystemPrompt = appendSystemPrompt.String
+		session.CustomInstructions = customInstructions.String
+		session.PermissionPromptTool = permissionPromptTool.String
+		session.AllowedTools = allowedTools.String
+		session.DisallowedTools = disallowedTools.String
+		session.ResultContent = resultContent.String
+		session.ErrorMessage = errorMessage.String
+		if completedAt.Valid {
+			session.CompletedAt = &completedAt.Time
+		}
+		if costUSD.Valid {
+			session.CostUSD = &costUSD.Float64
+		}
+		if inputTokens.Valid {
+			tokens := int(inputTokens.Int64)
+			session.InputTokens = &tokens
+		}
+		if outputTokens.Valid {
+			tokens := int(outputTokens.Int64)
+			session.OutputTokens = &tokens
+		}
+		if cacheCreationInputTokens.Valid {
+			tokens := int(cacheCreationInputTokens.Int64)
+			session.CacheCreationInputTokens = &tokens
+		}
+		if cacheReadInputTokens.Valid {
+			tokens := int(cacheReadInputTokens.Int64)
+			session.CacheReadInputTokens = &tokens
+		}
+		if effectiveContextTokens.Valid {
+			tokens := int(effectiveContextTokens.Int64)
+			session.EffectiveContextTokens = &tokens
+		}
+		if durationMS.Valid {
+			duration := int(durationMS.Int64)
+			session.DurationMS = &duration
+		}
+		if numTurns.Valid {
+			turns := int(numTurns.Int64)
+			session.NumTurns = &turns
+		}
+		session.ResultContent = resultContent.String
+		session.ErrorMessage = errorMessage.String
+
+		// Handle archived field - default to false if NULL
+		session.Archived = archived.Valid && archived.Bool
+
+		// Handle dangerously skip permissions expires at
+		if dangerouslySkipPermissionsExpiresAt.Valid {
+			session.DangerouslySkipPermissionsExpiresAt = &dangerouslySkipPermissionsExpiresAt.Time
+		}
+
+		// Handle dangerously skip permissions timeout ms
+		if dangerouslySkipPermissionsTimeoutMs.Valid {
+			session.DangerouslySkipPermissionsTimeoutMs = &dangerouslySkipPermissionsTimeoutMs.Int64
+		}
+
+		// Handle proxy fields
+		session.ProxyEnabled = proxyEnabled.Valid && proxyEnabled.Bool
+		session.ProxyBaseURL = proxyBaseURL.String
+		session.ProxyModelOverride = proxyModelOverride.String
+		session.ProxyAPIKey = proxyAPIKey.String
+
+		// Handle additional directories
+		session.AdditionalDirectories = additionalDirectories.String
+
+		// Handle editor state
+		if editorState.Valid {
+			session.EditorState = &editorState.String
+		}
+
+		// Handle imported flag
+		session.Imported = imported.Valid && imported.Bool
+
+		// Handle owner
+		if owner.Valid {
+			session.Owner = owner.String
+		}
+
+		// Handle bypass_tool_cache
+		session.BypassToolCache = bypassToolCache.Valid && bypassToolCache.Bool
+
+		// Handle tool_quota
+		session.ToolQuota = toolQuota.String
+
+		if queuedAt.Valid {
+			session.QueuedAt = &queuedAt.Time
+		}
+
+		if startedAt.Valid {
+			session.StartedAt = &startedAt.Time
+		}
+
+		if firstEventAt.Valid {
+			session.FirstEventAt = &firstEventAt.Time
+		}
+
+		if maxCostUSD.Valid {
+			session.MaxCostUSD = &maxCostUSD.Float64
+		}
+
+		if deletedAt.Valid {
+			session.DeletedAt = &deletedAt.Time
+		}
+
+		if err := s.decryptSession(&session); err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, nil
+}
+
+// SearchSessionsByTitle searches for sessions by title using SQL LIKE
+func (s *SQLiteStore) SearchSessionsByTitle(ctx context.Context, query string, limit int) ([]*Session, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	// Build the SQL query with LIKE pattern
+	// Use % wildcards for substring matching anywhere in title
+	// Filter to only leaf sessions (sessions with no children)
+	// Filter to only "normal" sessions (not archived, not draft, not discarded)
+	sqlQuery := `
+		SELECT id, run_id, claude_session_id, parent_session_id,
+			query, summary, title, model, model_id, working_dir, max_turns, system_prompt, append_system_prompt, custom_instructions,
+			permission_prompt_tool, allowed_tools, disallowed_tools,
+			status, created_at, last_activity_at, completed_at,
+			cost_usd, input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens, effective_context_tokens,
+			duration_ms, num_turns, result_content, error_message, auto_accept_edits, archived,
+			dangerously_skip_permissions, dangerously_skip_permissions_expires_at, dangerously_skip_permissions_timeout_ms,
+			proxy_enabled, proxy_base_url, proxy_model_override, proxy_api_key, additional_directories, editor_state, imported, owner, bypass_tool_cache, tool_quota, queued_at, started_at, first_event_at, max_cost_usd, priority
+		FROM sessions
+		WHERE 1=1
+		AND NOT EXISTS (
+			SELECT 1 FROM sessions children
+			WHERE children.parent_session_id = sessions.id
+		)
+		AND (archived IS NULL OR archived = 0)
+		AND status != 'draft'
+		AND status != 'discarded'`
+
+	var args []interface{}
+
+	// Search across title, summary, and query fields to match UI display logic
+	// UI shows: title || summary || query, so search should match this behavior
+	if query != "" {
+		sqlQuery += " AND (title LIKE ? OR summary LIKE ? OR query LIKE ?)"
+		args = append(args, "%"+query+"%", "%"+query+"%", "%"+query+"%")
+	}
+
+	// Order by last activity and limit
+	// Look at 20 most recent sessions for performance
+	sqlQuery += `
+		ORDER BY last_activity_at DESC
+		LIMIT 20`
+
+	// Then apply the user's limit in Go after fetching
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search sessions query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sessions []*Session
+	for rows.Next() {
+		var session Session
+		var claudeSessionID, parentSessionID, summary, title, model, modelID, workingDir, systemPrompt, appendSystemPrompt, customInstructions sql.NullString
+		var permissionPromptTool, allowedTools, disallowedTools sql.NullString
+		var completedAt sql.NullTime
+		var costUSD sql.NullFloat64
+		var inputTokens, outputTokens, cacheCreationInputTokens, cacheReadInputTokens, effectiveContextTokens sql.NullInt64
+		var durationMS, numTurns sql.NullInt64
+		var resultContent, errorMessage sql.NullString
+		var archived sql.NullBool
+		var dangerouslySkipPermissionsExpiresAt sql.NullTime
+		var dangerouslySkipPermissionsTimeoutMs sql.NullInt64
+		var proxyEnabled sql.NullBool
+		var proxyBaseURL, proxyModelOverride, proxyAPIKey sql.NullString
+		var additionalDirectories sql.NullString
+		var editorState sql.NullString
+		var imported sql.NullBool
+		var owner sql.NullString
+		var bypassToolCache sql.NullBool
+		var toolQuota sql.NullString
+		var queuedAt sql.NullTime
+		var startedAt sql.NullTime
+		var firstEventAt sql.NullTime
+		var maxCostUSD sql.NullFloat64
+
+		err := rows.Scan(
+			&session.ID, &session.RunID, &claudeSessionID, &parentSessionID,
+			&session.Query, &summary, &title, &model, &modelID, &workingDir, &session.MaxTurns,
+			&systemPrompt, &appendSystemPrompt, &customInstructions,
+			&permissionPromptTool, &allowedTools, &disallowedTools,
+			&session.Status, &session.CreatedAt, &session.LastActivityAt, &completedAt,
+			&costUSD, &inputTokens, &outputTokens, &cacheCreationInputTokens, &cacheReadInputTokens, &effectiveContextTokens,
+			&durationMS, &numTurns, &resultContent, &errorMessage, &session.AutoAcceptEdits,
+			&archived, &session.DangerouslySkipPermissions, &dangerouslySkipPermissionsExpiresAt, &dangerouslySkipPermissionsTimeoutMs,
+			&proxyEnabled, &proxyBaseURL, &proxyModelOverride, &proxyAPIKey, &additionalDirectories, &editorState, &imported, &owner, &bypassToolCache, &toolQuota, &queuedAt, &startedAt, &firstEventAt, &maxCostUSD, &session.Priority,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+
+		// Handle nullable fields
+		session.ClaudeSessionID = claudeSessionID.String
+		session.ParentSessionID = parentSessionID.String
+		session.Summary = summary.String
+		session.Title = title.String
+		session.Model = model.String
+		session.ModelID = modelID.String
+		session.WorkingDir = workingDir.String
+		session.SystemPrompt = systemPrompt.String
+		session.AppendSystemPrompt = appendSystemPrompt.String
+		session.CustomInstructions = customInstructions.String
+		session.PermissionPromptTool = permissionPromptTool.String
+		session.AllowedTools = allowedTools.String
+		session.DisallowedTools = disallowedTools.String
+		session.ResultContent = resultContent.String
+		session.ErrorMessage = errorMessage.String
+		if completedAt.Valid {
+			session.CompletedAt = &completedAt.Time
+		}
+		if costUSD.Valid {
+			session.CostUSD = &costUSD.Float64
+		}
+		if inputTokens.Valid {
+			tokens := int(inputTokens.Int64)
+			session.InputTokens = &tokens
+		}
+		if outputTokens.Valid {
+			tokens := int(outputTokens.Int64)
+			session.OutputTokens = &tokens
+		}
+		if cacheCreationInputTokens.Valid {
+			tokens := int(cacheCreationInputTokens.Int64)
+			session.CacheCreationInputTokens = &tokens
+		}
+		if cacheReadInputTokens.Valid {
+			tokens := int(cacheReadInputTokens.Int64)
+			session.CacheReadInputTokens = &tokens
+		}
+		if effectiveContextTokens.Valid {
+			tokens := int(effectiveContextTokens.Int64)
+			session.EffectiveContextTokens = &tokens
+		}
+		if durationMS.Valid {
+			duration := int(durationMS.Int64)
+			session.DurationMS = &duration
+		}
+		if numTurns.Valid {
+			turns := int(numTurns.Int64)
+			session.NumTurns = &turns
+		}
+		session.ResultContent = resultContent.String
+		session.ErrorMessage = errorMessage.String
+
+		// Handle archived field - default to false if NULL
+		session.Archived = archived.Valid && archived.Bool
+
+		// Handle dangerously skip permissions expires at
+		if dangerouslySkipPermissionsExpiresAt.Valid {
+			session.DangerouslySkipPermissionsExpiresAt = &dangerouslySkipPermissionsExpiresAt.Time
+		}
+
+		// Handle dangerously skip permissions timeout ms
+		if dangerouslySkipPermissionsTimeoutMs.Valid {
+			session.DangerouslySkipPermissionsTimeoutMs = &dangerouslySkipPermissionsTimeoutMs.Int64
+		}
+
+		// Handle proxy fields
+		session.ProxyEnabled = proxyEnabled.Valid && proxyEnabled.Bool
+		session.ProxyBaseURL = proxyBaseURL.String
+		session.ProxyModelOverride = proxyModelOverride.String
+		session.ProxyAPIKey = proxyAPIKey.String
+
+		// Handle additional directories
+		session.AdditionalDirectories = additionalDirectories.String
+
+		// Handle editor state
+		if editorState.Valid {
+			session.EditorState = &editorState.String
+		}
+
+		// Handle imported flag
+		session.Imported = imported.Valid && imported.Bool
+
+		// Handle owner
+		if owner.Valid {
+			session.Owner = owner.String
+		}
+
+		// Handle bypass_tool_cache
+		session.BypassToolCache = bypassToolCache.Valid && bypassToolCache.Bool
+
+		// Handle tool_quota
+		session.ToolQuota = toolQuota.String
+
+		if queuedAt.Valid {
+			session.QueuedAt = &queuedAt.Time
+		}
+
+		if startedAt.Valid {
+			session.StartedAt = &startedAt.Time
+		}
+
+		if firstEventAt.Valid {
+			session.FirstEventAt = &firstEventAt.Time
+		}
+
+		if maxCostUSD.Valid {
+			session.MaxCostUSD = &maxCostUSD.Float64
+		}
+
+		if err := s.decryptSession(&session); err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, &session)
 	}
-	if updates.AutoAcceptEdits != nil {
-		setParts = append(setParts, "auto_accept_edits = ?")
-		args = append(args, *updates.AutoAcceptEdits)
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration: %w", err)
 	}
-	if updates.DangerouslySkipPermissions != nil {
-		setParts = append(setParts, "dangerously_skip_permissions = ?")
-		args = append(args, *updates.DangerouslySkipPermissions)
+
+	// Apply the user's requested limit
+	if len(sessions) > limit {
+		sessions = sessions[:limit]
 	}
-	if updates.DangerouslySkipPermissionsExpiresAt != nil {
-		setParts = append(setParts, "dangerously_skip_permissions_expires_at = ?")
-		if *updates.DangerouslySkipPermissionsExpiresAt != nil {
-			args = append(args, **updates.DangerouslySkipPermissionsExpiresAt)
-		} else {
-			args = append(args, nil)
+
+	return sessions, nil
+}
+
+// GetExpiredDangerousPermissionsSessions returns sessions where dangerous permissions have expired
+func (s *SQLiteStore) GetExpiredDangerousPermissionsSessions(ctx context.Context) ([]*Session, error) {
+	now := time.Now()
+	query := `
+		SELECT id, run_id, claude_session_id, parent_session_id,
+			query, summary, title, model, model_id, working_dir, max_turns, system_prompt, append_system_prompt, custom_instructions,
+			permission_prompt_tool, allowed_tools, disallowed_tools,
+			status, created_at, last_activity_at, completed_at,
+			cost_usd, input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens, effective_context_tokens,
+		duration_ms, num_turns, result_content, error_message, auto_accept_edits, archived,
+			dangerously_skip_permissions, dangerously_skip_permissions_expires_at, dangerously_skip_permissions_timeout_ms,
+			proxy_enabled, proxy_base_url, proxy_model_override, proxy_api_key, additional_directories, editor_state, imported, owner, bypass_tool_cache, tool_quota, queued_at, started_at, first_event_at, max_cost_usd, priority
+		FROM sessions
+		WHERE dangerously_skip_permissions = 1
+			AND dangerously_skip_permissions_expires_at IS NOT NULL
+			AND dangerously_skip_permissions_expires_at < ?
+			AND status IN ('running', 'waiting_input', 'starting')
+		ORDER BY dangerously_skip_permissions_expires_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired dangerous skip permissions sessions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sessions []*Session
+	for rows.Next() {
+		var session Session
+		var claudeSessionID, parentSessionID, summary, title, model, modelID, workingDir, systemPrompt, appendSystemPrompt, customInstructions sql.NullString
+		var permissionPromptTool, allowedTools, disallowedTools sql.NullString
+		var completedAt sql.NullTime
+		var costUSD sql.NullFloat64
+		var inputTokens, outputTokens, cacheCreationInputTokens, cacheReadInputTokens, effectiveContextTokens sql.NullInt64
+		var durationMS, numTurns sql.NullInt64
+		var resultContent, errorMessage sql.NullString
+		var archived sql.NullBool
+		var dangerouslySkipPermissionsExpiresAt sql.NullTime
+		var dangerouslySkipPermissionsTimeoutMs sql.NullInt64
+		var proxyEnabled sql.NullBool
+		var proxyBaseURL, proxyModelOverride, proxyAPIKey sql.NullString
+		var additionalDirectories sql.NullString
+		var editorState sql.NullString
+		var imported sql.NullBool
+		var owner sql.NullString
+		var bypassToolCache sql.NullBool
+		var toolQuota sql.NullString
+		var queuedAt sql.NullTime
+		var startedAt sql.NullTime
+		var firstEventAt sql.NullTime
+		var maxCostUSD sql.NullFloat64
+
+		err := rows.Scan(
+			&session.ID, &session.RunID, &claudeSessionID, &parentSessionID,
+			&session.Query, &summary, &title, &model, &modelID, &workingDir, &session.MaxTurns,
+			&systemPrompt, &appendSystemPrompt, &customInstructions,
+			&permissionPromptTool, &allowedTools, &disallowedTools,
+			&session.Status, &session.CreatedAt, &session.LastActivityAt, &completedAt,
+			&costUSD, &inputTokens, &outputTokens, &cacheCreationInputTokens, &cacheReadInputTokens, &effectiveContextTokens,
+			&durationMS, &numTurns, &resultContent, &errorMessage, &session.AutoAcceptEdits,
+			&archived, &session.DangerouslySkipPermissions, &dangerouslySkipPermissionsExpiresAt, &dangerouslySkipPermissionsTimeoutMs,
+			&proxyEnabled, &proxyBaseURL, &proxyModelOverride, &proxyAPIKey, &additionalDirectories, &editorState, &imported, &owner, &bypassToolCache, &toolQuota, &queuedAt, &startedAt, &firstEventAt, &maxCostUSD, &session.Priority,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+
+		// Handle nullable fields
+		session.ClaudeSessionID = claudeSessionID.String
+		session.ParentSessionID = parentSessionID.String
+		session.Summary = summary.String
+		session.Title = title.String
+		session.Model = model.String
+		session.ModelID = modelID.String
+		session.WorkingDir = workingDir.String
+		session.SystemPrompt = systemPrompt.String
+		session.AppendSystemPrompt = appendSystemPrompt.String
+		session.CustomInstructions = customInstructions.String
+		session.PermissionPromptTool = permissionPromptTool.String
+		session.AllowedTools = allowedTools.String
+		session.DisallowedTools = disallowedTools.String
+		session.ResultContent = resultContent.String
+		session.ErrorMessage = errorMessage.String
+		if completedAt.Valid {
+			session.CompletedAt = &completedAt.Time
+		}
+		if costUSD.Valid {
+			session.CostUSD = &costUSD.Float64
+		}
+		if inputTokens.Valid {
+			tokens := int(inputTokens.Int64)
+			session.InputTokens = &tokens
+		}
+		if outputTokens.Valid {
+			tokens := int(outputTokens.Int64)
+			session.OutputTokens = &tokens
+		}
+		if cacheCreationInputTokens.Valid {
+			tokens := int(cacheCreationInputTokens.Int64)
+			session.CacheCreationInputTokens = &tokens
+		}
+		if cacheReadInputTokens.Valid {
+			tokens := int(cacheReadInputTokens.Int64)
+			session.CacheReadInputTokens = &tokens
 		}
-	}
-	if updates.DangerouslySkipPermissionsTimeoutMs != nil {
-		setParts = append(setParts, "dangerously_skip_permissions_timeout_ms = ?")
-		args = append(args, *updates.DangerouslySkipPermissionsTimeoutMs)
-	}
-	if updates.Model != nil {
-		setParts = append(setParts, "model = ?")
-		args = append(args, *updates.Model)
-	}
-	if updates.ModelID != nil {
-		setParts = append(setParts, "model_id = ?")
-		args = append(args, *updates.ModelID)
-	}
-	if updates.Archived != nil {
-		setParts = append(setParts, "archived = ?")
-		args = append(args, *updates.Archived)
-	}
-	// Handle proxy field updates
-	if updates.ProxyEnabled != nil {
-		setParts = append(setParts, "proxy_enabled = ?")
-		args = append(args, *updates.ProxyEnabled)
-	}
-	if updates.ProxyBaseURL != nil {
-		setParts = append(setParts, "proxy_base_url = ?")
-		args = append(args, *updates.ProxyBaseURL)
-	}
-	if updates.ProxyModelOverride != nil {
-		setParts = append(setParts, "proxy_model_override = ?")
-		args = append(args, *updates.ProxyModelOverride)
-	}
-	if updates.ProxyAPIKey != nil {
-		setParts = append(setParts, "proxy_api_key = ?")
-		args = append(args, *updates.ProxyAPIKey)
-	}
-	if updates.AdditionalDirectories != nil {
-		setParts = append(setParts, "additional_directories = ?")
-		args = append(args, *updates.AdditionalDirectories)
-	}
-	if updates.WorkingDir != nil {
-		setParts = append(setParts, "working_dir = ?")
-		args = append(args, *updates.WorkingDir)
-	}
-	if updates.EditorState != nil {
-		setParts = append(setParts, "editor_state = ?")
-		args = append(args, *updates.EditorState)
-	}
+		if effectiveContextTokens.Valid {
+			tokens := int(effectiveContextTokens.Int64)
+			session.EffectiveContextTokens = &tokens
+		}
+		if durationMS.Valid {
+			duration := int(durationMS.Int64)
+			session.DurationMS = &duration
+		}
+		if numTurns.Valid {
+			turns := int(numTurns.Int64)
+			session.NumTurns = &turns
+		}
+		session.ResultContent = resultContent.String
+		session.ErrorMessage = errorMessage.String
 
-	if len(setParts) == 0 {
-		// No fields to update is OK - this is a no-op
-		return nil
-	}
+		// Handle archived field - default to false if NULL
+		session.Archived = archived.Valid && archived.Bool
 
-	query += " " + strings.Join(setParts, ", ")
+		// Handle dangerously skip permissions expires at
+		if dangerouslySkipPermissionsExpiresAt.Valid {
+			session.DangerouslySkipPermissionsExpiresAt = &dangerouslySkipPermissionsExpiresAt.Time
+		}
 
-	query += " WHERE id = ?"
-	args = append(args, sessionID)
+		// Handle dangerously skip permissions timeout ms
+		if dangerouslySkipPermissionsTimeoutMs.Valid {
+			session.DangerouslySkipPermissionsTimeoutMs = &dangerouslySkipPermissionsTimeoutMs.Int64
+		}
 
-	result, err := s.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		return fmt.Errorf("failed to update session: %w", err)
-	}
+		// Handle proxy fields
+		session.ProxyEnabled = proxyEnabled.Valid && proxyEnabled.Bool
+		session.ProxyBaseURL = proxyBaseURL.String
+		session.ProxyModelOverride = proxyModelOverride.String
+		session.ProxyAPIKey = proxyAPIKey.String
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
+		// Handle additional directories
+		session.AdditionalDirectories = additionalDirectories.String
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("session not found: %s", sessionID)
-	}
+		// Handle editor state
+		if editorState.Valid {
+			session.EditorState = &editorState.String
+		}
 
-	return nil
-}
+		// Handle imported flag
+		session.Imported = imported.Valid && imported.Bool
 
-// HardDeleteSession permanently deletes a session from the database
-func (s *SQLiteStore) HardDeleteSession(ctx context.Context, sessionID string) error {
-	query := `DELETE FROM sessions WHERE id = ?`
-	result, err := s.db.ExecContext(ctx, query, sessionID)
-	if err != nil {
-		return fmt.Errorf("failed to delete session: %w", err)
-	}
+		// Handle owner
+		if owner.Valid {
+			session.Owner = owner.String
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		// Handle bypass_tool_cache
+		session.BypassToolCache = bypassToolCache.Valid && bypassToolCache.Bool
+
+		// Handle tool_quota
+		session.ToolQuota = toolQuota.String
+
+		if queuedAt.Valid {
+			session.QueuedAt = &queuedAt.Time
+		}
+
+		if startedAt.Valid {
+			session.StartedAt = &startedAt.Time
+		}
+
+		if firstEventAt.Valid {
+			session.FirstEventAt = &firstEventAt.Time
+		}
+
+		if maxCostUSD.Valid {
+			session.MaxCostUSD = &maxCostUSD.Float64
+		}
+
+		if err := s.decryptSession(&session); err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, &session)
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	return nil
+	return sessions, nil
 }
 
-// GetSession retrieves a session by ID
-func (s *SQLiteStore) GetSession(ctx context.Context, sessionID string) (*Session, error) {
+// GetRecentWorkingDirs retrieves recently used working directories
+func (s *SQLiteStore) GetRecentWorkingDirs(ctx context.Context, limit int) ([]RecentPath, error) {
+	if limit <= 0 {
+		limit = 20 // Default to 20 recent paths
+	}
+
 	query := `
-		SELECT id, run_id, claude_session_id, parent_session_id,
-			query, summary, title, model, model_id, working_dir, max_turns, system_prompt, append_system_prompt, custom_instructions,
-			permission_prompt_tool, allowed_tools, disallowed_tools,
-			status, created_at, last_activity_at, completed_at,
-			cost_usd, input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens, effective_context_tokens,
-			duration_ms, num_turns, result_content, error_message, auto_accept_edits, archived,
-			dangerously_skip_permissions, dangerously_skip_permissions_expires_at, dangerously_skip_permissions_timeout_ms,
-			proxy_enabled, proxy_base_url, proxy_model_override, proxy_api_key, additional_directories, editor_state
-		FROM sessions WHERE id = ?
+		SELECT
+			working_dir as path,
+			datetime(MAX(last_activity_at)) as last_used,
+			COUNT(*) as usage_count
+		FROM sessions
+		WHERE working_dir IS NOT NULL
+			AND working_dir != ''
+			AND working_dir != '.'
+		GROUP BY working_dir
+		ORDER BY MAX(last_activity_at) DESC
+		LIMIT ?
 	`
 
-	var session Session
-	var claudeSessionID, parentSessionID, summary, title, model, modelID, workingDir, systemPrompt, appendSystemPrompt, customInstructions sql.NullString
-	var permissionPromptTool, allowedTools, disallowedTools sql.NullString
-	var completedAt sql.NullTime
-	var costUSD sql.NullFloat64
-	var inputTokens, outputTokens, cacheCreationInputTokens, cacheReadInputTokens, effectiveContextTokens sql.NullInt64
-	var durationMS, numTurns sql.NullInt64
-	var resultContent, errorMessage sql.NullString
-	var archived sql.NullBool
-	var dangerouslySkipPermissionsExpiresAt sql.NullTime
-	var dangerouslySkipPermissionsTimeoutMs sql.NullInt64
-	var proxyEnabled sql.NullBool
-	var proxyBaseURL, proxyModelOverride, proxyAPIKey sql.NullString
-	var additionalDirectories sql.NullString
-	var editorState sql.NullString
-
-	err := s.db.QueryRowContext(ctx, query, sessionID).Scan(
-		&session.ID, &session.RunID, &claudeSessionID, &parentSessionID,
-		&session.Query, &summary, &title, &model, &modelID, &workingDir, &session.MaxTurns,
-		&systemPrompt, &appendSystemPrompt, &customInstructions,
-		&permissionPromptTool, &allowedTools, &disallowedTools,
-		&session.Status, &session.CreatedAt, &session.LastActivityAt, &completedAt,
-		&costUSD, &inputTokens, &outputTokens, &cacheCreationInputTokens, &cacheReadInputTokens, &effectiveContextTokens,
-		&durationMS, &numTurns, &resultContent, &errorMessage, &session.AutoAcceptEdits,
-		&archived, &session.DangerouslySkipPermissions, &dangerouslySkipPermissionsExpiresAt, &dangerouslySkipPermissionsTimeoutMs,
-		&proxyEnabled, &proxyBaseURL, &proxyModelOverride, &proxyAPIKey, &additionalDirectories, &editorState,
-	)
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("session not found: %s", sessionID)
-	}
+	rows, err := s.db.QueryContext(ctx, query, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get session: %w", err)
+		return nil, fmt.Errorf("query recent paths: %w", err)
 	}
+	defer func() { _ = rows.Close() }()
 
-	// Handle nullable fields
-	session.ClaudeSessionID = claudeSessionID.String
-	session.ParentSessionID = parentSessionID.String
-	session.Summary = summary.String
-	session.Title = title.String
-	session.Model = model.String
-	session.ModelID = modelID.String
-	session.WorkingDir = workingDir.String
-	session.SystemPrompt = systemPrompt.String
-	session.AppendSystemPrompt = appendSystemPrompt.String
-	session.CustomInstructions = customInstructions.String
-	session.PermissionPromptTool = permissionPromptTool.String
-	session.AllowedTools = allowedTools.String
-	session.DisallowedTools = disallowedTools.String
-	session.ResultContent = resultContent.String
-	session.ErrorMessage = errorMessage.String
-	if completedAt.Valid {
-		session.CompletedAt = &completedAt.Time
-	}
-	if costUSD.Valid {
-		session.CostUSD = &costUSD.Float64
-	}
-	if inputTokens.Valid {
-		tokens := int(inputTokens.Int64)
-		session.InputTokens = &tokens
-	}
-	if outputTokens.Valid {
-		tokens := int(outputTokens.Int64)
-		session.OutputTokens = &tokens
-	}
-	if cacheCreationInputTokens.Valid {
-		tokens := int(cacheCreationInputTokens.Int64)
-		session.CacheCreationInputTokens = &tokens
-	}
-	if cacheReadInputTokens.Valid {
-		tokens := int(cacheReadInputTokens.Int64)
-		session.CacheReadInputTokens = &tokens
-	}
-	if effectiveContextTokens.Valid {
-		tokens := int(effectiveContextTokens.Int64)
-		session.EffectiveContextTokens = &tokens
-	}
-	if durationMS.Valid {
-		duration := int(durationMS.Int64)
-		session.DurationMS = &duration
+	var paths []RecentPath
+	for rows.Next() {
+		var p RecentPath
+		var lastUsedStr string
+		if err := rows.Scan(&p.Path, &lastUsedStr, &p.UsageCount); err != nil {
+			return nil, fmt.Errorf("scan recent path: %w", err)
+		}
+		// Parse the datetime string from SQLite
+		if t, err := time.Parse("2006-01-02 15:04:05", lastUsedStr); err == nil {
+			p.LastUsed = t
+		}
+		paths = append(paths, p)
 	}
-	if numTurns.Valid {
-		turns := int(numTurns.Int64)
-		session.NumTurns = &turns
+
+	return paths, rows.Err()
+}
+
+// GetUserSettings retrieves the user settings from the database
+func (s *SQLiteStore) GetUserSettings(ctx context.Context) (*UserSettings, error) {
+	var settings UserSettings
+	err := s.db.QueryRowContext(ctx, `
+		SELECT advanced_providers, opt_in_telemetry, created_at, updated_at
+		FROM user_settings WHERE id = 1
+	`).Scan(&settings.AdvancedProviders, &settings.OptInTelemetry, &settings.CreatedAt, &settings.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		// Return defaults if not found (for backwards compatibility)
+		return &UserSettings{
+			AdvancedProviders: false,
+			CreatedAt:         time.Now(),
+			UpdatedAt:         time.Now(),
+		}, nil
 	}
+	return &settings, err
+}
 
-	// Handle archived field - default to false if NULL
-	session.Archived = archived.Valid && archived.Bool
+// UpdateUserSettings updates the user settings in the database
+func (s *SQLiteStore) UpdateUserSettings(ctx context.Context, settings UserSettings) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE user_settings
+		SET advanced_providers = ?, opt_in_telemetry = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = 1
+	`, settings.AdvancedProviders, settings.OptInTelemetry)
+	return err
+}
 
-	// Handle dangerously skip permissions expires at
-	if dangerouslySkipPermissionsExpiresAt.Valid {
-		session.DangerouslySkipPermissionsExpiresAt = &dangerouslySkipPermissionsExpiresAt.Time
+// eventInsertColumns are the conversation_events columns written for a new
+// event, in the order eventInsertArgs returns their values
+const eventInsertColumns = `
+			session_id, claude_session_id, sequence, event_type,
+			role, content,
+			tool_id, tool_name, tool_input_json, parent_tool_use_id,
+			tool_result_for_id, tool_result_content, tool_result_bytes, tool_result_tokens,
+			is_completed, approval_status, approval_id, permalink, language, tool_cache_hit,
+			content_hash, tool_result_json, tool_error, idempotency_key`
+
+// eventInsertColumnCount is the number of columns in eventInsertColumns
+const eventInsertColumnCount = 24
+
+// eventInsertArgs fills in the derived fields of an event about to be
+// stored, its permalink and tool result size, and returns the values for
+// eventInsertColumns with content encrypted if the store is
+func (s *SQLiteStore) eventInsertArgs(event *ConversationEvent) ([]interface{}, error) {
+	if event.Permalink == "" {
+		permalink, err := newEventPermalink()
+		if err != nil {
+			return nil, err
+		}
+		event.Permalink = permalink
 	}
 
-	// Handle dangerously skip permissions timeout ms
-	if dangerouslySkipPermissionsTimeoutMs.Valid {
-		session.DangerouslySkipPermissionsTimeoutMs = &dangerouslySkipPermissionsTimeoutMs.Int64
+	if event.EventType == EventTypeToolResult {
+		event.ToolResultBytes = len(event.ToolResultContent)
+		event.ToolResultTokens = EstimateTokens(event.ToolResultContent)
 	}
 
-	// Handle proxy fields
-	session.ProxyEnabled = proxyEnabled.Valid && proxyEnabled.Bool
-	session.ProxyBaseURL = proxyBaseURL.String
-	session.ProxyModelOverride = proxyModelOverride.String
-	session.ProxyAPIKey = proxyAPIKey.String
-
-	// Handle additional directories
-	session.AdditionalDirectories = additionalDirectories.String
+	sealed, err := s.encryptEvent(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt conversation event: %w", err)
+	}
 
-	// Handle editor state
-	if editorState.Valid {
-		session.EditorState = &editorState.String
+	var contentHash sql.NullString
+	if event.ContentHash != "" {
+		contentHash = sql.NullString{String: event.ContentHash, Valid: true}
+	}
+	var idempotencyKey sql.NullString
+	if event.IdempotencyKey != "" {
+		idempotencyKey = sql.NullString{String: event.IdempotencyKey, Valid: true}
 	}
 
-	return &session, nil
+	return []interface{}{
+		event.SessionID, event.ClaudeSessionID, event.Sequence, event.EventType,
+		event.Role, columnValue(sealed.Content),
+		event.ToolID, event.ToolName, columnValue(sealed.ToolInputJSON), event.ParentToolUseID,
+		event.ToolResultForID, sealed.ToolResultContent, event.ToolResultBytes, event.ToolResultTokens,
+		event.IsCompleted, event.ApprovalStatus, event.ApprovalID, event.Permalink, event.Language, event.ToolCacheHit,
+		contentHash, columnValue(sealed.ToolResultJSON), sealed.ToolError, idempotencyKey,
+	}, nil
 }
 
-// GetSessionByRunID retrieves a session by its run_id
-func (s *SQLiteStore) GetSessionByRunID(ctx context.Context, runID string) (*Session, error) {
+// nextEventSequence returns the sequence number after the highest stored
+// for a Claude session. Callers hold eventMu and a transaction, so no other
+// append can take the same number.
+func nextEventSequence(ctx context.Context, tx *sql.Tx, claudeSessionID string) (int, error) {
+	var maxSeq sql.NullInt64
+	err := tx.QueryRowContext(ctx,
+		"SELECT MAX(sequence) FROM conversation_events WHERE claude_session_id = ?",
+		claudeSessionID,
+	).Scan(&maxSeq)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get max sequence: %w", err)
+	}
+	return int(maxSeq.Int64) + 1, nil
+}
+
+// findEventByIdempotencyKey returns the session's event with the given
+// idempotency key, or nil if it has none
+func (s *SQLiteStore) findEventByIdempotencyKey(ctx context.Context, tx *sql.Tx, sessionID, key string) (*ConversationEvent, error) {
 	query := `
-		SELECT id, run_id, claude_session_id, parent_session_id,
-			query, summary, title, model, model_id, working_dir, max_turns, system_prompt, append_system_prompt, custom_instructions,
-			permission_prompt_tool, allowed_tools, disallowed_tools,
-			status, created_at, last_activity_at, completed_at,
-			cost_usd, input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens, effective_context_tokens,
-			duration_ms, num_turns, result_content, error_message, auto_accept_edits, archived,
-			dangerously_skip_permissions, dangerously_skip_permissions_expires_at, dangerously_skip_permissions_timeout_ms,
-			proxy_enabled, proxy_base_url, proxy_model_override, proxy_api_key, additional_directories, editor_state
-		FROM sessions
-		WHERE run_id = ?
+		SELECT id, session_id, claude_session_id, sequence, event_type, created_at,
+			role, content,
+			tool_id, tool_name, tool_input_json, parent_tool_use_id,
+			tool_result_for_id, tool_result_content,
+			COALESCE(tool_result_bytes, 0), COALESCE(tool_result_tokens, 0),
+			is_completed, approval_status, approval_id, COALESCE(permalink, ''), COALESCE(language, ''), COALESCE(tool_cache_hit, 0),
+			COALESCE(truncated, 0),
+			COALESCE(tool_result_json, ''), COALESCE(tool_error, ''), idempotency_key
+		FROM conversation_events
+		WHERE session_id = ? AND idempotency_key = ?
 	`
 
-	var session Session
-	var claudeSessionID, parentSessionID, summary, title, model, modelID, workingDir, systemPrompt, appendSystemPrompt, customInstructions sql.NullString
-	var permissionPromptTool, allowedTools, disallowedTools sql.NullString
-	var completedAt sql.NullTime
-	var costUSD sql.NullFloat64
-	var inputTokens, outputTokens, cacheCreationInputTokens, cacheReadInputTokens, effectiveContextTokens sql.NullInt64
-	var durationMS, numTurns sql.NullInt64
-	var resultContent, errorMessage sql.NullString
-	var archived sql.NullBool
-	var dangerouslySkipPermissionsExpiresAt sql.NullTime
-	var dangerouslySkipPermissionsTimeoutMs sql.NullInt64
-	var proxyEnabled sql.NullBool
-	var proxyBaseURL, proxyModelOverride, proxyAPIKey sql.NullString
-	var additionalDirectories sql.NullString
-	var editorState sql.NullString
-
-	err := s.db.QueryRowContext(ctx, query, runID).Scan(
-		&session.ID, &session.RunID, &claudeSessionID, &parentSessionID,
-		&session.Query, &summary, &title, &model, &modelID, &workingDir, &session.MaxTurns,
-		&systemPrompt, &appendSystemPrompt, &customInstructions,
-		&permissionPromptTool, &allowedTools, &disallowedTools,
-		&session.Status, &session.CreatedAt, &session.LastActivityAt, &completedAt,
-		&costUSD, &inputTokens, &outputTokens, &cacheCreationInputTokens, &cacheReadInputTokens, &effectiveContextTokens,
-		&durationMS, &numTurns, &resultContent, &errorMessage, &session.AutoAcceptEdits,
-		&archived, &session.DangerouslySkipPermissions, &dangerouslySkipPermissionsExpiresAt, &dangerouslySkipPermissionsTimeoutMs,
-		&proxyEnabled, &proxyBaseURL, &proxyModelOverride, &proxyAPIKey, &additionalDirectories, &editorState,
+	event := &ConversationEvent{}
+	err := tx.QueryRowContext(ctx, query, sessionID, key).Scan(
+		&event.ID, &event.SessionID, &event.ClaudeSessionID,
+		&event.Sequence, &event.EventType, &event.CreatedAt,
+		&event.Role, &event.Content,
+		&event.ToolID, &event.ToolName, &event.ToolInputJSON, &event.ParentToolUseID,
+		&event.ToolResultForID, &event.ToolResultContent,
+		&event.ToolResultBytes, &event.ToolResultTokens,
+		&event.IsCompleted, &event.ApprovalStatus, &event.ApprovalID, &event.Permalink, &event.Language, &event.ToolCacheHit,
+		&event.Truncated,
+		&event.ToolResultJSON, &event.ToolError, &event.IdempotencyKey,
 	)
 	if err == sql.ErrNoRows {
-		return nil, nil // No session found
+		return nil, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get session by run_id: %w", err)
+		return nil, fmt.Errorf("failed to find event by idempotency key: %w", err)
 	}
 
-	// Convert nullable fields
-	session.ClaudeSessionID = claudeSessionID.String
-	session.ParentSessionID = parentSessionID.String
-	session.Summary = summary.String
-	session.Title = title.String
-	session.Model = model.String
-	session.ModelID = modelID.String
-	session.WorkingDir = workingDir.String
-	session.SystemPrompt = systemPrompt.String
-	session.AppendSystemPrompt = appendSystemPrompt.String
-	session.CustomInstructions = customInstructions.String
-	session.PermissionPromptTool = permissionPromptTool.String
-	session.AllowedTools = allowedTools.String
-	session.DisallowedTools = disallowedTools.String
-	session.ResultContent = resultContent.String
-	session.ErrorMessage = errorMessage.String
-	if completedAt.Valid {
-		session.CompletedAt = &completedAt.Time
-	}
-	if costUSD.Valid {
-		session.CostUSD = &costUSD.Float64
-	}
-	if inputTokens.Valid {
-		tokens := int(inputTokens.Int64)
-		session.InputTokens = &tokens
-	}
-	if outputTokens.Valid {
-		tokens := int(outputTokens.Int64)
-		session.OutputTokens = &tokens
-	}
-	if cacheCreationInputTokens.Valid {
-		tokens := int(cacheCreationInputTokens.Int64)
-		session.CacheCreationInputTokens = &tokens
-	}
-	if cacheReadInputTokens.Valid {
-		tokens := int(cacheReadInputTokens.Int64)
-		session.CacheReadInputTokens = &tokens
-	}
-	if effectiveContextTokens.Valid {
-		tokens := int(effectiveContextTokens.Int64)
-		session.EffectiveContextTokens = &tokens
-	}
-	if durationMS.Valid {
-		duration := int(durationMS.Int64)
-		session.DurationMS = &duration
-	}
-	if numTurns.Valid {
-		turns := int(numTurns.Int64)
-		session.NumTurns = &turns
+	if err := s.decryptEvent(event); err != nil {
+		return nil, err
 	}
+	return event, nil
+}
 
-	// Handle archived field - default to false if NULL
-	session.Archived = archived.Valid && archived.Bool
+// AddConversationEvent adds a new conversation event. If the event has an
+// idempotency key the session already has, nothing is stored and the event
+// is filled in from the stored one.
+func (s *SQLiteStore) AddConversationEvent(ctx context.Context, event *ConversationEvent) error {
+	s.eventMu.Lock()
+	defer s.eventMu.Unlock()
 
-	// Handle dangerously skip permissions expires at
-	if dangerouslySkipPermissionsExpiresAt.Valid {
-		session.DangerouslySkipPermissionsExpiresAt = &dangerouslySkipPermissionsExpiresAt.Time
+	// Use a transaction to avoid race conditions with sequence numbers
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	// Handle dangerously skip permissions timeout ms
-	if dangerouslySkipPermissionsTimeoutMs.Valid {
-		session.DangerouslySkipPermissionsTimeoutMs = &dangerouslySkipPermissionsTimeoutMs.Int64
+	if event.IdempotencyKey != "" {
+		existing, err := s.findEventByIdempotencyKey(ctx, tx, event.SessionID, event.IdempotencyKey)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			*event = *existing
+			return nil
+		}
 	}
 
-	// Handle proxy fields
-	session.ProxyEnabled = proxyEnabled.Valid && proxyEnabled.Bool
-	session.ProxyBaseURL = proxyBaseURL.String
-	session.ProxyModelOverride = proxyModelOverride.String
-	session.ProxyAPIKey = proxyAPIKey.String
-
-	// Handle additional directories
-	session.AdditionalDirectories = additionalDirectories.String
-
-	// Handle editor state
-	if editorState.Valid {
-		session.EditorState = &editorState.String
+	// Get next sequence number for this claude session within the transaction
+	event.Sequence, err = nextEventSequence(ctx, tx, event.ClaudeSessionID)
+	if err != nil {
+		return err
 	}
 
-	return &session, nil
-}
+	args, err := s.eventInsertArgs(event)
+	if err != nil {
+		return err
+	}
 
-// ListSessions retrieves all sessions
-func (s *SQLiteStore) ListSessions(ctx context.Context) ([]*Session, error) {
 	query := `
-		SELECT id, run_id, claude_session_id, parent_session_id,
-			query, summary, title, model, model_id, working_dir, max_turns, system_prompt, append_system_prompt, custom_instructions,
-			permission_prompt_tool, allowed_tools, disallowed_tools,
-			status, created_at, last_activity_at, completed_at,
-			cost_usd, input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens, effective_context_tokens,
-		duration_ms, num_turns, result_content, error_message, auto_accept_edits, archived,
-			dangerously_skip_permissions, dangerously_skip_permissions_expires_at, dangerously_skip_permissions_timeout_ms,
-			proxy_enabled, proxy_base_url, proxy_model_override, proxy_api_key, additional_directories, editor_state
-		FROM sessions
-		ORDER BY last_activity_at DESC
+		INSERT INTO conversation_events (` + eventInsertColumns + `
+		)
+		SELECT ?` + strings.Repeat(", ?", eventInsertColumnCount-1) + `
+		WHERE NOT EXISTS (
+			SELECT 1 FROM sessions WHERE id = ? AND cost_usd > max_cost_usd
+		)
 	`
 
-	rows, err := s.db.QueryContext(ctx, query)
+	result, err := tx.ExecContext(ctx, query, append(args, event.SessionID)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list sessions: %w", err)
+		return fmt.Errorf("failed to add conversation event: %w", err)
+	}
+	// The budget is checked by the insert itself, so no cost update can
+	// land between the check and the write
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("session %s: %w", event.SessionID, ErrBudgetExceeded)
 	}
-	defer func() { _ = rows.Close() }()
 
-	var sessions []*Session
-	for rows.Next() {
-		var session Session
-		var claudeSessionID, parentSessionID, summary, title, model, modelID, workingDir, systemPrompt, appendSystemPrompt, customInstructions sql.NullString
-		var permissionPromptTool, allowedTools, disallowedTools sql.NullString
-		var completedAt sql.NullTime
-		var costUSD sql.NullFloat64
-		var inputTokens, outputTokens, cacheCreationInputTokens, cacheReadInputTokens, effectiveContextTokens sql.NullInt64
-		var durationMS, numTurns sql.NullInt64
-		var resultContent, errorMessage sql.NullString
-		var archived sql.NullBool
-		var dangerouslySkipPermissionsExpiresAt sql.NullTime
-		var dangerouslySkipPermissionsTimeoutMs sql.NullInt64
-		var proxyEnabled sql.NullBool
-		var proxyBaseURL, proxyModelOverride, proxyAPIKey sql.NullString
-		var additionalDirectories sql.NullString
-		var editorState sql.NullString
+	id, err := result.LastInsertId()
+	if err == nil {
+		event.ID = id
+	}
 
-		err := rows.Scan(
-			&session.ID, &session.RunID, &claudeSessionID, &parentSessionID,
-			&session.Query, &summary, &title, &model, &modelID, &workingDir, &session.MaxTurns,
-			&systemPrompt, &appendSystemPrompt, &customInstructions,
-			&permissionPromptTool, &allowedTools, &disallowedTools,
-			&session.Status, &session.CreatedAt, &session.LastActivityAt, &completedAt,
-			&costUSD, &inputTokens, &outputTokens, &cacheCreationInputTokens, &cacheReadInputTokens, &effectiveContextTokens,
-			&durationMS, &numTurns, &resultContent, &errorMessage, &session.AutoAcceptEdits,
-			&archived, &session.DangerouslySkipPermissions, &dangerouslySkipPermissionsExpiresAt, &dangerouslySkipPermissionsTimeoutMs,
-			&proxyEnabled, &proxyBaseURL, &proxyModelOverride, &proxyAPIKey, &additionalDirectories, &editorState,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan session: %w", err)
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.events.publish(event)
+	return nil
+}
+
+// appendBatchSize is the most events AppendConversationEvents writes per
+// INSERT statement, keeping each well under SQLite's bound parameter limit
+const appendBatchSize = 500
+
+// AppendConversationEvents stores a batch of one Claude session's events in
+// one transaction, assigning them consecutive sequence numbers. Each group
+// of up to appendBatchSize events is written with a single multi-row
+// INSERT, so a batch costs a round trip rather than one per event. Events
+// with an idempotency key already stored for the session, or used by an
+// earlier event in the batch, are not stored again; like
+// AddConversationEvent, they are filled in from the event holding the key.
+func (s *SQLiteStore) AppendConversationEvents(ctx context.Context, events []*ConversationEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	sessionID, claudeSessionID := events[0].SessionID, events[0].ClaudeSessionID
+	if sessionID == "" {
+		return fmt.Errorf("events must have a session ID")
+	}
+	for _, event := range events[1:] {
+		if event.SessionID != sessionID || event.ClaudeSessionID != claudeSessionID {
+			return fmt.Errorf("all events in a batch must belong to the same session")
 		}
+	}
 
-		// Handle nullable fields
-		session.ClaudeSessionID = claudeSessionID.String
-		session.ParentSessionID = parentSessionID.String
-		session.Summary = summary.String
-		session.Title = title.String
-		session.Model = model.String
-		session.ModelID = modelID.String
-		session.WorkingDir = workingDir.String
-		session.SystemPrompt = systemPrompt.String
-		session.AppendSystemPrompt = appendSystemPrompt.String
-		session.CustomInstructions = customInstructions.String
-		session.PermissionPromptTool = permissionPromptTool.String
-		session.AllowedTools = allowedTools.String
-		session.DisallowedTools = disallowedTools.String
-		session.ResultContent = resultContent.String
-		session.ErrorMessage = errorMessage.String
-		if completedAt.Valid {
-			session.CompletedAt = &completedAt.Time
-		}
-		if costUSD.Valid {
-			session.CostUSD = &costUSD.Float64
+	s.eventMu.Lock()
+	defer s.eventMu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var fresh []*ConversationEvent
+	// repeats pairs each event whose key an earlier event in the batch
+	// holds with that event, which only has its ID once the batch commits
+	var repeats [][2]*ConversationEvent
+	byKey := make(map[string]*ConversationEvent)
+	for _, event := range events {
+		if event.IdempotencyKey == "" {
+			fresh = append(fresh, event)
+			continue
 		}
-		if inputTokens.Valid {
-			tokens := int(inputTokens.Int64)
-			session.InputTokens = &tokens
+		if first, ok := byKey[event.IdempotencyKey]; ok {
+			repeats = append(repeats, [2]*ConversationEvent{event, first})
+			continue
 		}
-		if outputTokens.Valid {
-			tokens := int(outputTokens.Int64)
-			session.OutputTokens = &tokens
+		existing, err := s.findEventByIdempotencyKey(ctx, tx, sessionID, event.IdempotencyKey)
+		if err != nil {
+			return err
 		}
-		if cacheCreationInputTokens.Valid {
-			tokens := int(cacheCreationInputTokens.Int64)
-			session.CacheCreationInputTokens = &tokens
+		if existing != nil {
+			*event = *existing
+			continue
 		}
-		if cacheReadInputTokens.Valid {
-			tokens := int(cacheReadInputTokens.Int64)
-			session.CacheReadInputTokens = &tokens
+		byKey[event.IdempotencyKey] = event
+		fresh = append(fresh, event)
+	}
+
+	next, err := nextEventSequence(ctx, tx, claudeSessionID)
+	if err != nil {
+		return err
+	}
+
+	// IDs are only set once the batch commits
+	ids := make([]int64, len(fresh))
+	for start := 0; start < len(fresh); start += appendBatchSize {
+		chunk := fresh[start:min(start+appendBatchSize, len(fresh))]
+
+		row := "(?" + strings.Repeat(", ?", eventInsertColumnCount-1) + ")"
+		args := make([]interface{}, 0, len(chunk)*eventInsertColumnCount+1)
+		for i, event := range chunk {
+			event.Sequence = next + start + i
+			eventArgs, err := s.eventInsertArgs(event)
+			if err != nil {
+				return err
+			}
+			args = append(args, eventArgs...)
 		}
-		if effectiveContextTokens.Valid {
-			tokens := int(effectiveContextTokens.Int64)
-			session.EffectiveContextTokens = &tokens
+		args = append(args, sessionID)
+
+		// Selecting from VALUES lets the budget check guard the whole batch
+		query := `
+			INSERT INTO conversation_events (` + eventInsertColumns + `
+			)
+			SELECT * FROM (VALUES ` + row + strings.Repeat(", "+row, len(chunk)-1) + `)
+			WHERE NOT EXISTS (
+				SELECT 1 FROM sessions WHERE id = ? AND cost_usd > max_cost_usd
+			)
+		`
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to append conversation events: %w", err)
 		}
-		if durationMS.Valid {
-			duration := int(durationMS.Int64)
-			session.DurationMS = &duration
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
 		}
-		if numTurns.Valid {
-			turns := int(numTurns.Int64)
-			session.NumTurns = &turns
+		if rowsAffected == 0 {
+			return fmt.Errorf("session %s: %w", sessionID, ErrBudgetExceeded)
 		}
-		session.ResultContent = resultContent.String
-		session.ErrorMessage = errorMessage.String
-
-		// Handle archived field - default to false if NULL
-		session.Archived = archived.Valid && archived.Bool
 
-		// Handle dangerously skip permissions expires at
-		if dangerouslySkipPermissionsExpiresAt.Valid {
-			session.DangerouslySkipPermissionsExpiresAt = &dangerouslySkipPermissionsExpiresAt.Time
+		// Rows inserted by one statement while eventMu is held get
+		// consecutive IDs ending at the last insert ID
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert ID: %w", err)
 		}
-
-		// Handle dangerously skip permissions timeout ms
-		if dangerouslySkipPermissionsTimeoutMs.Valid {
-			session.DangerouslySkipPermissionsTimeoutMs = &dangerouslySkipPermissionsTimeoutMs.Int64
+		for i := range chunk {
+			ids[start+i] = lastID - int64(len(chunk)-1-i)
 		}
+	}
 
-		// Handle proxy fields
-		session.ProxyEnabled = proxyEnabled.Valid && proxyEnabled.Bool
-		session.ProxyBaseURL = proxyBaseURL.String
-		session.ProxyModelOverride = proxyModelOverride.String
-		session.ProxyAPIKey = proxyAPIKey.String
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for i, event := range fresh {
+		event.ID = ids[i]
+		s.events.publish(event)
+	}
+	for _, pair := range repeats {
+		*pair[0] = *pair[1]
+	}
+	return nil
+}
 
-		// Handle additional directories
-		session.AdditionalDirectories = additionalDirectories.String
+// FindEventContentHashes returns the session holding each of the given content hashes
+func (s *SQLiteStore) FindEventContentHashes(ctx context.Context, hashes []string) (map[string]string, error) {
+	found := make(map[string]string)
+	if len(hashes) == 0 {
+		return found, nil
+	}
 
-		// Handle editor state
-		if editorState.Valid {
-			session.EditorState = &editorState.String
-		}
+	query := `SELECT content_hash, session_id FROM conversation_events WHERE content_hash IN (?` +
+		strings.Repeat(", ?", len(hashes)-1) + `)`
+	args := make([]interface{}, len(hashes))
+	for i, hash := range hashes {
+		args[i] = hash
+	}
 
-		sessions = append(sessions, &session)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find content hashes: %w", err)
 	}
+	defer func() { _ = rows.Close() }()
 
-	return sessions, nil
+	for rows.Next() {
+		var hash, sessionID string
+		if err := rows.Scan(&hash, &sessionID); err != nil {
+			return nil, fmt.Errorf("failed to scan content hash: %w", err)
+		}
+		found[hash] = sessionID
+	}
+	return found, rows.Err()
 }
 
-// SearchSessionsByTitle searches for sessions by title using SQL LIKE
-func (s *SQLiteStore) SearchSessionsByTitle(ctx context.Context, query string, limit int) ([]*Session, error) {
-	if limit <= 0 {
-		limit = 10
+// GetLastEventContentHash returns the content hash of a session's latest event
+func (s *SQLiteStore) GetLastEventContentHash(ctx context.Context, sessionID string) (string, error) {
+	var hash sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT content_hash FROM conversation_events
+		WHERE session_id = ?
+		ORDER BY sequence DESC, id DESC
+		LIMIT 1
+	`, sessionID).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
 	}
-	if limit > 50 {
-		limit = 50
+	if err != nil {
+		return "", fmt.Errorf("failed to get last content hash: %w", err)
 	}
+	return hash.String, nil
+}
 
-	// Build the SQL query with LIKE pattern
-	// Use % wildcards for substring matching anywhere in title
-	// Filter to only leaf sessions (sessions with no children)
-	// Filter to only "normal" sessions (not archived, not draft, not discarded)
-	sqlQuery := `
-		SELECT id, run_id, claude_session_id, parent_session_id,
-			query, summary, title, model, model_id, working_dir, max_turns, system_prompt, append_system_prompt, custom_instructions,
-			permission_prompt_tool, allowed_tools, disallowed_tools,
-			status, created_at, last_activity_at, completed_at,
-			cost_usd, input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens, effective_context_tokens,
-			duration_ms, num_turns, result_content, error_message, auto_accept_edits, archived,
-			dangerously_skip_permissions, dangerously_skip_permissions_expires_at, dangerously_skip_permissions_timeout_ms,
-			proxy_enabled, proxy_base_url, proxy_model_override, proxy_api_key, additional_directories, editor_state
-		FROM sessions
-		WHERE 1=1
-		AND NOT EXISTS (
-			SELECT 1 FROM sessions children
-			WHERE children.parent_session_id = sessions.id
-		)
-		AND (archived IS NULL OR archived = 0)
-		AND status != 'draft'
-		AND status != 'discarded'`
+// MarkEventTruncated flags an event whose content was cut off
+func (s *SQLiteStore) MarkEventTruncated(ctx context.Context, eventID int64) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE conversation_events
+		SET truncated = 1, is_completed = 0
+		WHERE id = ?
+	`, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to mark event truncated: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return &NotFoundError{Type: "conversation event", ID: fmt.Sprint(eventID)}
+	}
+	return nil
+}
 
-	var args []interface{}
+// sqlLimit converts a page limit to a LIMIT argument. SQLite treats a
+// negative limit as no limit.
+func sqlLimit(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
 
-	// Search across title, summary, and query fields to match UI display logic
-	// UI shows: title || summary || query, so search should match this behavior
-	if query != "" {
-		sqlQuery += " AND (title LIKE ? OR summary LIKE ? OR query LIKE ?)"
-		args = append(args, "%"+query+"%", "%"+query+"%", "%"+query+"%")
+// GetConversation retrieves a page of events for a Claude session
+func (s *SQLiteStore) GetConversation(ctx context.Context, claudeSessionID string, page ConversationPage) ([]*ConversationEvent, error) {
+	events, err := s.getConversation(ctx, "conversation_events", claudeSessionID, page)
+	if err != nil || len(events) > 0 {
+		return events, err
 	}
+	// A Claude session's events are archived together, so an empty page
+	// may belong to an archived session
+	return s.getConversation(ctx, "archived_conversation_events", claudeSessionID, page)
+}
 
-	// Order by last activity and limit
-	// Look at 20 most recent sessions for performance
-	sqlQuery += `
-		ORDER BY last_activity_at DESC
-		LIMIT 20`
+func (s *SQLiteStore) getConversation(ctx context.Context, table, claudeSessionID string, page ConversationPage) ([]*ConversationEvent, error) {
+	query := `
+		SELECT id, session_id, claude_session_id, sequence, event_type, created_at,
+			role, content,
+			tool_id, tool_name, tool_input_json, parent_tool_use_id,
+			tool_result_for_id, tool_result_content,
+			COALESCE(tool_result_bytes, 0), COALESCE(tool_result_tokens, 0),
+			is_completed, approval_status, approval_id, COALESCE(permalink, ''), COALESCE(language, ''), COALESCE(tool_cache_hit, 0),
+			COALESCE(truncated, 0),
+			COALESCE(tool_result_json, ''), COALESCE(tool_error, '')
+		FROM ` + table + `
+		WHERE claude_session_id = ? AND sequence > ?
+		ORDER BY sequence
+		LIMIT ?
+	`
 
-	// Then apply the user's limit in Go after fetching
-	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	rows, err := s.db.QueryContext(ctx, query, claudeSessionID, page.AfterSequence, sqlLimit(page.Limit))
 	if err != nil {
-		return nil, fmt.Errorf("search sessions query: %w", err)
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
-	var sessions []*Session
-	for rows.Next() {
-		var session Session
-		var claudeSessionID, parentSessionID, summary, title, model, modelID, workingDir, systemPrompt, appendSystemPrompt, customInstructions sql.NullString
-		var permissionPromptTool, allowedTools, disallowedTools sql.NullString
-		var completedAt sql.NullTime
-		var costUSD sql.NullFloat64
-		var inputTokens, outputTokens, cacheCreationInputTokens, cacheReadInputTokens, effectiveContextTokens sql.NullInt64
-		var durationMS, numTurns sql.NullInt64
-		var resultContent, errorMessage sql.NullString
-		var archived sql.NullBool
-		var dangerouslySkipPermissionsExpiresAt sql.NullTime
-		var dangerouslySkipPermissionsTimeoutMs sql.NullInt64
-		var proxyEnabled sql.NullBool
-		var proxyBaseURL, proxyModelOverride, proxyAPIKey sql.NullString
-		var additionalDirectories sql.NullString
-		var editorState sql.NullString
-
-		err := rows.Scan(
-			&session.ID, &session.RunID, &claudeSessionID, &parentSessionID,
-			&session.Query, &summary, &title, &model, &modelID, &workingDir, &session.MaxTurns,
-			&systemPrompt, &appendSystemPrompt, &customInstructions,
-			&permissionPromptTool, &allowedTools, &disallowedTools,
-			&session.Status, &session.CreatedAt, &session.LastActivityAt, &completedAt,
-			&costUSD, &inputTokens, &outputTokens, &cacheCreationInputTokens, &cacheReadInputTokens, &effectiveContextTokens,
-			&durationMS, &numTurns, &resultContent, &errorMessage, &session.AutoAcceptEdits,
-			&archived, &session.DangerouslySkipPermissions, &dangerouslySkipPermissionsExpiresAt, &dangerouslySkipPermissionsTimeoutMs,
-			&proxyEnabled, &proxyBaseURL, &proxyModelOverride, &proxyAPIKey, &additionalDirectories, &editorState,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan session: %w", err)
-		}
-
-		// Handle nullable fields
-		session.ClaudeSessionID = claudeSessionID.String
-		session.ParentSessionID = parentSessionID.String
-		session.Summary = summary.String
-		session.Title = title.String
-		session.Model = model.String
-		session.ModelID = modelID.String
-		session.WorkingDir = workingDir.String
-		session.SystemPrompt = systemPrompt.String
-		session.AppendSystemPrompt = appendSystemPrompt.String
-		session.CustomInstructions = customInstructions.String
-		session.PermissionPromptTool = permissionPromptTool.String
-		session.AllowedTools = allowedTools.String
-		session.DisallowedTools = disallowedTools.String
-		session.ResultContent = resultContent.String
-		session.ErrorMessage = errorMessage.String
-		if completedAt.Valid {
-			session.CompletedAt = &completedAt.Time
-		}
-		if costUSD.Valid {
-			session.CostUSD = &costUSD.Float64
-		}
-		if inputTokens.Valid {
-			tokens := int(inputTokens.Int64)
-			session.InputTokens = &tokens
-		}
-		if outputTokens.Valid {
-			tokens := int(outputTokens.Int64)
-			session.OutputTokens = &tokens
-		}
-		if cacheCreationInputTokens.Valid {
-			tokens := int(cacheCreationInputTokens.Int64)
-			session.CacheCreationInputTokens = &tokens
-		}
-		if cacheReadInputTokens.Valid {
-			tokens := int(cacheReadInputTokens.Int64)
-			session.CacheReadInputTokens = &tokens
-		}
-		if effectiveContextTokens.Valid {
-			tokens := int(effectiveContextTokens.Int64)
-			session.EffectiveContextTokens = &tokens
-		}
-		if durationMS.Valid {
-			duration := int(durationMS.Int64)
-			session.DurationMS = &duration
+	var events []*ConversationEvent
+	for rows.Next() {
+		event := &ConversationEvent{}
+		err := rows.Scan(
+			&event.ID, &event.SessionID, &event.ClaudeSessionID,
+			&event.Sequence, &event.EventType, &event.CreatedAt,
+			&event.Role, &event.Content,
+			&event.ToolID, &event.ToolName, &event.ToolInputJSON, &event.ParentToolUseID,
+			&event.ToolResultForID, &event.ToolResultContent,
+			&event.ToolResultBytes, &event.ToolResultTokens,
+			&event.IsCompleted, &event.ApprovalStatus, &event.ApprovalID, &event.Permalink, &event.Language, &event.ToolCacheHit,
+			&event.Truncated,
+			&event.ToolResultJSON, &event.ToolError,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
 		}
-		if numTurns.Valid {
-			turns := int(numTurns.Int64)
-			session.NumTurns = &turns
+		if err := s.decryptEvent(event); err != nil {
+			return nil, err
 		}
-		session.ResultContent = resultContent.String
-		session.ErrorMessage = errorMessage.String
+		events = append(events, event)
+	}
 
-		// Handle archived field - default to false if NULL
-		session.Archived = archived.Valid && archived.Bool
+	return events, nil
+}
 
-		// Handle dangerously skip permissions expires at
-		if dangerouslySkipPermissionsExpiresAt.Valid {
-			session.DangerouslySkipPermissionsExpiresAt = &dangerouslySkipPermissionsExpiresAt.Time
+// sessionClaudeSessionIDs returns the Claude session IDs of a session and
+// its parent chain, oldest first
+func (s *SQLiteStore) sessionClaudeSessionIDs(ctx context.Context, sessionID string) ([]string, error) {
+	// Walk up the parent chain to get all related claude session IDs
+	claudeSessionIDs := []string{}
+	currentID := sessionID
+	isFirstSession := true
+
+	for currentID != "" {
+		var claudeSessionID sql.NullString
+		var parentID sql.NullString
+
+		err := s.db.QueryRowContext(ctx,
+			"SELECT claude_session_id, parent_session_id FROM sessions WHERE id = ?",
+			currentID,
+		).Scan(&claudeSessionID, &parentID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				// If the requested session doesn't exist, return error
+				if isFirstSession {
+					return nil, &NotFoundError{Type: "session", ID: sessionID}
+				}
+				// Otherwise, parent not found, just stop walking
+				break
+			}
+			return nil, fmt.Errorf("failed to get session: %w", err)
 		}
+		isFirstSession = false
 
-		// Handle dangerously skip permissions timeout ms
-		if dangerouslySkipPermissionsTimeoutMs.Valid {
-			session.DangerouslySkipPermissionsTimeoutMs = &dangerouslySkipPermissionsTimeoutMs.Int64
+		// Add claude session ID if present (in reverse order for chronological events)
+		if claudeSessionID.Valid && claudeSessionID.String != "" {
+			claudeSessionIDs = append([]string{claudeSessionID.String}, claudeSessionIDs...)
 		}
 
-		// Handle proxy fields
-		session.ProxyEnabled = proxyEnabled.Valid && proxyEnabled.Bool
-		session.ProxyBaseURL = proxyBaseURL.String
-		session.ProxyModelOverride = proxyModelOverride.String
-		session.ProxyAPIKey = proxyAPIKey.String
+		// Move to parent
+		if parentID.Valid {
+			currentID = parentID.String
+		} else {
+			currentID = ""
+		}
+	}
 
-		// Handle additional directories
-		session.AdditionalDirectories = additionalDirectories.String
+	return claudeSessionIDs, nil
+}
 
-		// Handle editor state
-		if editorState.Valid {
-			session.EditorState = &editorState.String
-		}
+// CountConversation counts a Claude session's events
+func (s *SQLiteStore) CountConversation(ctx context.Context, claudeSessionID string) (int, error) {
+	// Like GetConversation, counts archived events when there are no live ones
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(
+			NULLIF((SELECT COUNT(*) FROM conversation_events WHERE claude_session_id = ?), 0),
+			(SELECT COUNT(*) FROM archived_conversation_events WHERE claude_session_id = ?))
+	`, claudeSessionID, claudeSessionID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count conversation: %w", err)
+	}
+	return count, nil
+}
 
-		sessions = append(sessions, &session)
+// CountSessionConversation counts a session's events including those of its
+// parent chain
+func (s *SQLiteStore) CountSessionConversation(ctx context.Context, sessionID string) (int, error) {
+	claudeSessionIDs, err := s.sessionClaudeSessionIDs(ctx, sessionID)
+	if err != nil || len(claudeSessionIDs) == 0 {
+		return 0, err
+	}
+	placeholders := make([]string, len(claudeSessionIDs))
+	args := make([]interface{}, len(claudeSessionIDs))
+	for i, id := range claudeSessionIDs {
+		placeholders[i] = "?"
+		args[i] = id
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration: %w", err)
+	var count int
+	err = s.db.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT COUNT(*) FROM conversation_events WHERE claude_session_id IN (%s)",
+		strings.Join(placeholders, ","),
+	), args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count conversation: %w", err)
 	}
+	return count, nil
+}
 
-	// Apply the user's requested limit
-	if len(sessions) > limit {
-		sessions = sessions[:limit]
+// GetSessionConversation retrieves a page of events for a session including parent history
+func (s *SQLiteStore) GetSessionConversation(ctx context.Context, sessionID string, page ConversationPage) ([]*ConversationEvent, error) {
+	claudeSessionIDs, err := s.sessionClaudeSessionIDs(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(claudeSessionIDs) == 0 {
+		// No claude sessions yet, return empty
+		return []*ConversationEvent{}, nil
 	}
 
-	return sessions, nil
-}
+	// Get all events for all claude session IDs in chronological order
+	placeholders := make([]string, len(claudeSessionIDs))
+	args := make([]interface{}, len(claudeSessionIDs))
+	for i, id := range claudeSessionIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
 
-// GetExpiredDangerousPermissionsSessions returns sessions where dangerous permissions have expired
-func (s *SQLiteStore) GetExpiredDangerousPermissionsSessions(ctx context.Context) ([]*Session, error) {
-	now := time.Now()
-	query := `
-		SELECT id, run_id, claude_session_id, parent_session_id,
-			query, summary, title, model, model_id, working_dir, max_turns, system_prompt, append_system_prompt, custom_instructions,
-			permission_prompt_tool, allowed_tools, disallowed_tools,
-			status, created_at, last_activity_at, completed_at,
-			cost_usd, input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens, effective_context_tokens,
-		duration_ms, num_turns, result_content, error_message, auto_accept_edits, archived,
-			dangerously_skip_permissions, dangerously_skip_permissions_expires_at, dangerously_skip_permissions_timeout_ms,
-			proxy_enabled, proxy_base_url, proxy_model_override, proxy_api_key, additional_directories, editor_state
-		FROM sessions
-		WHERE dangerously_skip_permissions = 1
-			AND dangerously_skip_permissions_expires_at IS NOT NULL
-			AND dangerously_skip_permissions_expires_at < ?
-			AND status IN ('running', 'waiting_input', 'starting')
-		ORDER BY dangerously_skip_permissions_expires_at ASC
-	`
+	// Build query that orders by the position in the claude session ID list first
+	// This ensures parent events come before child events
+	orderCases := make([]string, len(claudeSessionIDs))
+	for i := range claudeSessionIDs {
+		orderCases[i] = fmt.Sprintf("WHEN claude_session_id = ? THEN %d", i)
+		args = append(args, claudeSessionIDs[i])
+	}
 
-	rows, err := s.db.QueryContext(ctx, query, now)
+	query := fmt.Sprintf(`
+		SELECT id, session_id, claude_session_id, sequence, event_type, created_at,
+			role, content,
+			tool_id, tool_name, tool_input_json, parent_tool_use_id,
+			tool_result_for_id, tool_result_content,
+			COALESCE(tool_result_bytes, 0), COALESCE(tool_result_tokens, 0),
+			is_completed, approval_status, approval_id, COALESCE(permalink, ''), COALESCE(language, ''), COALESCE(tool_cache_hit, 0),
+			COALESCE(truncated, 0),
+			COALESCE(tool_result_json, ''), COALESCE(tool_error, '')
+		FROM conversation_events
+		WHERE claude_session_id IN (%s)
+		ORDER BY
+			CASE %s END,
+			sequence
+		LIMIT ? OFFSET ?
+	`, strings.Join(placeholders, ","), strings.Join(orderCases, " "))
+	args = append(args, sqlLimit(page.Limit), page.AfterSequence)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query expired dangerous skip permissions sessions: %w", err)
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
-	var sessions []*Session
+	var events []*ConversationEvent
 	for rows.Next() {
-		var session Session
-		var claudeSessionID, parentSessionID, summary, title, model, modelID, workingDir, systemPrompt, appendSystemPrompt, customInstructions sql.NullString
-		var permissionPromptTool, allowedTools, disallowedTools sql.NullString
-		var completedAt sql.NullTime
-		var costUSD sql.NullFloat64
-		var inputTokens, outputTokens, cacheCreationInputTokens, cacheReadInputTokens, effectiveContextTokens sql.NullInt64
-		var durationMS, numTurns sql.NullInt64
-		var resultContent, errorMessage sql.NullString
-		var archived sql.NullBool
-		var dangerouslySkipPermissionsExpiresAt sql.NullTime
-		var dangerouslySkipPermissionsTimeoutMs sql.NullInt64
-		var proxyEnabled sql.NullBool
-		var proxyBaseURL, proxyModelOverride, proxyAPIKey sql.NullString
-		var additionalDirectories sql.NullString
-		var editorState sql.NullString
-
+		event := &ConversationEvent{}
 		err := rows.Scan(
-			&session.ID, &session.RunID, &claudeSessionID, &parentSessionID,
-			&session.Query, &summary, &title, &model, &modelID, &workingDir, &session.MaxTurns,
-			&systemPrompt, &appendSystemPrompt, &customInstructions,
-			&permissionPromptTool, &allowedTools, &disallowedTools,
-			&session.Status, &session.CreatedAt, &session.LastActivityAt, &completedAt,
-			&costUSD, &inputTokens, &outputTokens, &cacheCreationInputTokens, &cacheReadInputTokens, &effectiveContextTokens,
-			&durationMS, &numTurns, &resultContent, &errorMessage, &session.AutoAcceptEdits,
-			&archived, &session.DangerouslySkipPermissions, &dangerouslySkipPermissionsExpiresAt, &dangerouslySkipPermissionsTimeoutMs,
-			&proxyEnabled, &proxyBaseURL, &proxyModelOverride, &proxyAPIKey, &additionalDirectories, &editorState,
+			&event.ID, &event.SessionID, &event.ClaudeSessionID,
+			&event.Sequence, &event.EventType, &event.CreatedAt,
+			&event.Role, &event.Content,
+			&event.ToolID, &event.ToolName, &event.ToolInputJSON, &event.ParentToolUseID,
+			&event.ToolResultForID, &event.ToolResultContent,
+			&event.ToolResultBytes, &event.ToolResultTokens,
+			&event.IsCompleted, &event.ApprovalStatus, &event.ApprovalID, &event.Permalink, &event.Language, &event.ToolCacheHit,
+			&event.Truncated,
+			&event.ToolResultJSON, &event.ToolError,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan session: %w", err)
-		}
-
-		// Handle nullable fields
-		session.ClaudeSessionID = claudeSessionID.String
-		session.ParentSessionID = parentSessionID.String
-		session.Summary = summary.String
-		session.Title = title.String
-		session.Model = model.String
-		session.ModelID = modelID.String
-		session.WorkingDir = workingDir.String
-		session.SystemPrompt = systemPrompt.String
-		session.AppendSystemPrompt = appendSystemPrompt.String
-		session.CustomInstructions = customInstructions.String
-		session.PermissionPromptTool = permissionPromptTool.String
-		session.AllowedTools = allowedTools.String
-		session.DisallowedTools = disallowedTools.String
-		session.ResultContent = resultContent.String
-		session.ErrorMessage = errorMessage.String
-		if completedAt.Valid {
-			session.CompletedAt = &completedAt.Time
-		}
-		if costUSD.Valid {
-			session.CostUSD = &costUSD.Float64
-		}
-		if inputTokens.Valid {
-			tokens := int(inputTokens.Int64)
-			session.InputTokens = &tokens
-		}
-		if outputTokens.Valid {
-			tokens := int(outputTokens.Int64)
-			session.OutputTokens = &tokens
-		}
-		if cacheCreationInputTokens.Valid {
-			tokens := int(cacheCreationInputTokens.Int64)
-			session.CacheCreationInputTokens = &tokens
-		}
-		if cacheReadInputTokens.Valid {
-			tokens := int(cacheReadInputTokens.Int64)
-			session.CacheReadInputTokens = &tokens
-		}
-		if effectiveContextTokens.Valid {
-			tokens := int(effectiveContextTokens.Int64)
-			session.EffectiveContextTokens = &tokens
-		}
-		if durationMS.Valid {
-			duration := int(durationMS.Int64)
-			session.DurationMS = &duration
+			return nil, fmt.Errorf("failed to scan event: %w", err)
 		}
-		if numTurns.Valid {
-			turns := int(numTurns.Int64)
-			session.NumTurns = &turns
+		if err := s.decryptEvent(event); err != nil {
+			return nil, err
 		}
-		session.ResultContent = resultContent.String
-		session.ErrorMessage = errorMessage.String
+		events = append(events, event)
+	}
 
-		// Handle archived field - default to false if NULL
-		session.Archived = archived.Valid && archived.Bool
+	return events, nil
+}
+
+// GetConversationRange retrieves a session's events between two sequence numbers
+func (s *SQLiteStore) GetConversationRange(ctx context.Context, sessionID string, fromSeq, toSeq int) ([]*ConversationEvent, error) {
+	if fromSeq > toSeq {
+		return nil, fmt.Errorf("invalid sequence range: from %d is after to %d", fromSeq, toSeq)
+	}
+
+	query := `
+		SELECT id, session_id, claude_session_id, sequence, event_type, created_at,
+			role, content,
+			tool_id, tool_name, tool_input_json, parent_tool_use_id,
+			tool_result_for_id, tool_result_content,
+			COALESCE(tool_result_bytes, 0), COALESCE(tool_result_tokens, 0),
+			is_completed, approval_status, approval_id, COALESCE(permalink, ''), COALESCE(language, ''), COALESCE(tool_cache_hit, 0),
+			COALESCE(truncated, 0),
+			COALESCE(tool_result_json, ''), COALESCE(tool_error, '')
+		FROM conversation_events
+		WHERE session_id = ? AND sequence BETWEEN ? AND ?
+		ORDER BY sequence, id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, sessionID, fromSeq, toSeq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation range: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
 
-		// Handle dangerously skip permissions expires at
-		if dangerouslySkipPermissionsExpiresAt.Valid {
-			session.DangerouslySkipPermissionsExpiresAt = &dangerouslySkipPermissionsExpiresAt.Time
+	events := []*ConversationEvent{}
+	for rows.Next() {
+		event := &ConversationEvent{}
+		err := rows.Scan(
+			&event.ID, &event.SessionID, &event.ClaudeSessionID,
+			&event.Sequence, &event.EventType, &event.CreatedAt,
+			&event.Role, &event.Content,
+			&event.ToolID, &event.ToolName, &event.ToolInputJSON, &event.ParentToolUseID,
+			&event.ToolResultForID, &event.ToolResultContent,
+			&event.ToolResultBytes, &event.ToolResultTokens,
+			&event.IsCompleted, &event.ApprovalStatus, &event.ApprovalID, &event.Permalink, &event.Language, &event.ToolCacheHit,
+			&event.Truncated,
+			&event.ToolResultJSON, &event.ToolError,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
 		}
-
-		// Handle dangerously skip permissions timeout ms
-		if dangerouslySkipPermissionsTimeoutMs.Valid {
-			session.DangerouslySkipPermissionsTimeoutMs = &dangerouslySkipPermissionsTimeoutMs.Int64
+		if err := s.decryptEvent(event); err != nil {
+			return nil, err
 		}
+		events = append(events, event)
+	}
 
-		// Handle proxy fields
-		session.ProxyEnabled = proxyEnabled.Valid && proxyEnabled.Bool
-		session.ProxyBaseURL = proxyBaseURL.String
-		session.ProxyModelOverride = proxyModelOverride.String
-		session.ProxyAPIKey = proxyAPIKey.String
-
-		// Handle additional directories
-		session.AdditionalDirectories = additionalDirectories.String
+	return events, nil
+}
 
-		// Handle editor state
-		if editorState.Valid {
-			session.EditorState = &editorState.String
-		}
+// SubscribeToSession streams the conversation events added for a session
+// until it reaches a terminal status
+func (s *SQLiteStore) SubscribeToSession(ctx context.Context, sessionID string) (<-chan *ConversationEvent, error) {
+	sub := s.events.subscribe(ctx, sessionID)
 
-		sessions = append(sessions, &session)
+	// Check the status after subscribing, so a session finishing in between
+	// still closes the stream
+	session, err := s.GetSession(ctx, sessionID)
+	if err != nil {
+		s.events.unsubscribe(sessionID, sub)
+		return nil, err
+	}
+	if IsTerminalSessionStatus(session.Status) {
+		s.events.unsubscribe(sessionID, sub)
 	}
+	return sub.ch, nil
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+// RecordTurnUsage stores usage for a model turn, replacing any earlier report
+// for the same message
+func (s *SQLiteStore) RecordTurnUsage(ctx context.Context, turn *TurnUsage) error {
+	createdAt := turn.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO session_turns (session_id, message_id, output_tokens, generation_ms, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(session_id, message_id) DO UPDATE SET
+			output_tokens = excluded.output_tokens,
+			generation_ms = excluded.generation_ms
+	`, turn.SessionID, turn.MessageID, turn.OutputTokens, turn.GenerationMS, createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to record turn usage: %w", err)
 	}
+	return nil
+}
 
-	return sessions, nil
+// GetSessionThroughput aggregates recorded turn usage for a session
+func (s *SQLiteStore) GetSessionThroughput(ctx context.Context, sessionID string) (*SessionThroughput, error) {
+	var t SessionThroughput
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(generation_ms), 0)
+		FROM session_turns
+		WHERE session_id = ?
+	`, sessionID).Scan(&t.Turns, &t.OutputTokens, &t.GenerationMS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session throughput: %w", err)
+	}
+	return &t, nil
 }
 
-// GetRecentWorkingDirs retrieves recently used working directories
-func (s *SQLiteStore) GetRecentWorkingDirs(ctx context.Context, limit int) ([]RecentPath, error) {
-	if limit <= 0 {
-		limit = 20 // Default to 20 recent paths
+// GetSessionTurns returns the recorded turns for a session in the order
+// they were first reported
+func (s *SQLiteStore) GetSessionTurns(ctx context.Context, sessionID string) ([]*TurnUsage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT session_id, message_id, output_tokens, generation_ms, created_at
+		FROM session_turns
+		WHERE session_id = ?
+		ORDER BY id ASC
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session turns: %w", err)
 	}
+	defer func() { _ = rows.Close() }()
 
-	query := `
+	var turns []*TurnUsage
+	for rows.Next() {
+		turn := &TurnUsage{}
+		if err := rows.Scan(&turn.SessionID, &turn.MessageID, &turn.OutputTokens, &turn.GenerationMS, &turn.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session turn: %w", err)
+		}
+		turns = append(turns, turn)
+	}
+	return turns, rows.Err()
+}
+
+// GetConversationMetrics measures the size of a session's conversation. Counts
+// are aggregated in SQL; words need the text, so message content is scanned
+// but never returned.
+func (s *SQLiteStore) GetConversationMetrics(ctx context.Context, sessionID string) (*ConversationMetrics, error) {
+	metrics := &ConversationMetrics{}
+	err := s.db.QueryRowContext(ctx, `
 		SELECT
-			working_dir as path,
-			datetime(MAX(last_activity_at)) as last_used,
-			COUNT(*) as usage_count
-		FROM sessions
-		WHERE working_dir IS NOT NULL
-			AND working_dir != ''
-			AND working_dir != '.'
-		GROUP BY working_dir
-		ORDER BY MAX(last_activity_at) DESC
-		LIMIT ?
-	`
+			COALESCE(SUM(event_type = 'message'), 0),
+			COALESCE(SUM(event_type = 'message' AND role = 'user'), 0),
+			COALESCE(SUM(event_type = 'tool_call'), 0)
+		FROM conversation_events
+		WHERE session_id = ?
+	`, sessionID).Scan(&metrics.Messages, &metrics.Turns, &metrics.ToolCalls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count conversation events: %w", err)
+	}
 
-	rows, err := s.db.QueryContext(ctx, query, limit)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT COALESCE(content, '') FROM conversation_events
+		WHERE session_id = ? AND event_type = 'message'
+	`, sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("query recent paths: %w", err)
+		return nil, fmt.Errorf("failed to get message content: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
-	var paths []RecentPath
 	for rows.Next() {
-		var p RecentPath
-		var lastUsedStr string
-		if err := rows.Scan(&p.Path, &lastUsedStr, &p.UsageCount); err != nil {
-			return nil, fmt.Errorf("scan recent path: %w", err)
+		var content string
+		if err := rows.Scan(&content); err != nil {
+			return nil, fmt.Errorf("failed to scan message content: %w", err)
 		}
-		// Parse the datetime string from SQLite
-		if t, err := time.Parse("2006-01-02 15:04:05", lastUsedStr); err == nil {
-			p.LastUsed = t
+		if content, err = s.cipher.decrypt(content); err != nil {
+			return nil, err
 		}
-		paths = append(paths, p)
+		if content, err = decompressValue(content); err != nil {
+			return nil, err
+		}
+		metrics.Characters += utf8.RuneCountInString(content)
+		metrics.Words += len(strings.Fields(content))
 	}
-
-	return paths, rows.Err()
+	return metrics, rows.Err()
 }
 
-// GetUserSettings retrieves the user settings from the database
-func (s *SQLiteStore) GetUserSettings(ctx context.Context) (*UserSettings, error) {
-	var settings UserSettings
-	err := s.db.QueryRowContext(ctx, `
-		SELECT advanced_providers, opt_in_telemetry, created_at, updated_at
-		FROM user_settings WHERE id = 1
-	`).Scan(&settings.AdvancedProviders, &settings.OptInTelemetry, &settings.CreatedAt, &settings.UpdatedAt)
-
-	if err == sql.ErrNoRows {
-		// Return defaults if not found (for backwards compatibility)
-		return &UserSettings{
-			AdvancedProviders: false,
-			CreatedAt:         time.Now(),
-			UpdatedAt:         time.Now(),
-		}, nil
+// GetConversationStats counts a session's events by type and by hour in one
+// grouped query. Event timestamps are stored in UTC.
+func (s *SQLiteStore) GetConversationStats(ctx context.Context, sessionID string) (*ConversationStats, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT strftime('%Y-%m-%d %H', created_at) AS hour, event_type,
+			COUNT(*), COALESCE(SUM(tool_result_tokens), 0)
+		FROM conversation_events
+		WHERE session_id = ?
+		GROUP BY hour, event_type
+		ORDER BY hour
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation stats: %w", err)
 	}
-	return &settings, err
-}
+	defer func() { _ = rows.Close() }()
 
-// UpdateUserSettings updates the user settings in the database
-func (s *SQLiteStore) UpdateUserSettings(ctx context.Context, settings UserSettings) error {
-	_, err := s.db.ExecContext(ctx, `
-		UPDATE user_settings
-		SET advanced_providers = ?, opt_in_telemetry = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = 1
-	`, settings.AdvancedProviders, settings.OptInTelemetry)
-	return err
+	stats := &ConversationStats{ByType: map[string]int{}}
+	for rows.Next() {
+		var hour, eventType string
+		var count, tokens int
+		if err := rows.Scan(&hour, &eventType, &count, &tokens); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation stats: %w", err)
+		}
+		start, err := time.Parse("2006-01-02 15", hour)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse event hour %q: %w", hour, err)
+		}
+
+		stats.ByType[eventType] += count
+		if n := len(stats.ByHour); n == 0 || !stats.ByHour[n-1].Hour.Equal(start) {
+			stats.ByHour = append(stats.ByHour, EventHourBucket{Hour: start})
+		}
+		bucket := &stats.ByHour[len(stats.ByHour)-1]
+		bucket.EventCount += count
+		bucket.TokenCount += tokens
+	}
+	return stats, rows.Err()
 }
 
-// AddConversationEvent adds a new conversation event
-func (s *SQLiteStore) AddConversationEvent(ctx context.Context, event *ConversationEvent) error {
-	// Use a transaction to avoid race conditions with sequence numbers
-	tx, err := s.db.BeginTx(ctx, nil)
+// CountEventsByType counts a session's events by event type with a single
+// grouped query
+func (s *SQLiteStore) CountEventsByType(ctx context.Context, sessionID string) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT event_type, COUNT(*)
+		FROM conversation_events
+		WHERE session_id = ?
+		GROUP BY event_type
+	`, sessionID)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to count events by type: %w", err)
 	}
-	defer func() { _ = tx.Rollback() }()
+	defer func() { _ = rows.Close() }()
 
-	// Get next sequence number for this claude session within the transaction
-	var maxSeq sql.NullInt64
-	err = tx.QueryRowContext(ctx,
-		"SELECT MAX(sequence) FROM conversation_events WHERE claude_session_id = ?",
-		event.ClaudeSessionID,
-	).Scan(&maxSeq)
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("failed to get max sequence: %w", err)
+	counts := make(map[string]int)
+	for rows.Next() {
+		var eventType string
+		var count int
+		if err := rows.Scan(&eventType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan event count: %w", err)
+		}
+		counts[eventType] = count
 	}
+	return counts, rows.Err()
+}
 
-	event.Sequence = int(maxSeq.Int64) + 1
+// GetSessionMetrics counts a session's messages and tool calls and averages
+// the time between each tool call and its result
+func (s *SQLiteStore) GetSessionMetrics(ctx context.Context, sessionID string) (*SessionMetrics, error) {
+	metrics := &SessionMetrics{ToolCallsByName: map[string]int{}}
+	var costUSD sql.NullFloat64
+	err := s.db.QueryRowContext(ctx, `SELECT cost_usd FROM sessions WHERE id = ?`, sessionID).Scan(&costUSD)
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Type: "session", ID: sessionID}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session cost: %w", err)
+	}
+	metrics.CostUSD = costUSD.Float64
 
-	query := `
-		INSERT INTO conversation_events (
-			session_id, claude_session_id, sequence, event_type,
-			role, content,
-			tool_id, tool_name, tool_input_json, parent_tool_use_id,
-			tool_result_for_id, tool_result_content,
-			is_completed, approval_status, approval_id
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
+	err = s.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(event_type = 'message'), 0),
+			COALESCE(SUM(event_type = 'tool_call'), 0)
+		FROM conversation_events
+		WHERE session_id = ?
+	`, sessionID).Scan(&metrics.Messages, &metrics.ToolCalls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count conversation events: %w", err)
+	}
 
-	result, err := tx.ExecContext(ctx, query,
-		event.SessionID, event.ClaudeSessionID, event.Sequence, event.EventType,
-		event.Role, event.Content,
-		event.ToolID, event.ToolName, event.ToolInputJSON, event.ParentToolUseID,
-		event.ToolResultForID, event.ToolResultContent,
-		event.IsCompleted, event.ApprovalStatus, event.ApprovalID,
-	)
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+			COALESCE(AVG((julianday(r.created_at) - julianday(c.created_at)) * 86400000), 0)
+		FROM conversation_events c
+		JOIN conversation_events r
+			ON r.event_type = 'tool_result' AND r.tool_result_for_id = c.tool_id AND r.session_id = c.session_id
+		WHERE c.session_id = ? AND c.event_type = 'tool_call' AND c.tool_id IS NOT NULL
+	`, sessionID).Scan(&metrics.TimedToolCalls, &metrics.AvgToolLatencyMS)
 	if err != nil {
-		return fmt.Errorf("failed to add conversation event: %w", err)
+		return nil, fmt.Errorf("failed to time tool calls: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err == nil {
-		event.ID = id
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT COALESCE(tool_name, 'unknown'), COUNT(*)
+		FROM conversation_events
+		WHERE session_id = ? AND event_type = 'tool_call'
+		GROUP BY 1
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tool calls by name: %w", err)
 	}
+	defer func() { _ = rows.Close() }()
 
-	return tx.Commit()
+	for rows.Next() {
+		var name string
+		var calls int
+		if err := rows.Scan(&name, &calls); err != nil {
+			return nil, fmt.Errorf("failed to scan tool call count: %w", err)
+		}
+		metrics.ToolCallsByName[name] = calls
+	}
+	return metrics, rows.Err()
 }
 
-// GetConversation retrieves all events for a Claude session
-func (s *SQLiteStore) GetConversation(ctx context.Context, claudeSessionID string) ([]*ConversationEvent, error) {
+// GetToolOutputStats aggregates tool result sizes by the name of the tool
+// that produced them
+func (s *SQLiteStore) GetToolOutputStats(ctx context.Context, sessionID string) ([]*ToolOutputStats, error) {
 	query := `
-		SELECT id, session_id, claude_session_id, sequence, event_type, created_at,
-			role, content,
-			tool_id, tool_name, tool_input_json, parent_tool_use_id,
-			tool_result_for_id, tool_result_content,
-			is_completed, approval_status, approval_id
-		FROM conversation_events
-		WHERE claude_session_id = ?
-		ORDER BY sequence
+		SELECT COALESCE(c.tool_name, 'unknown') AS name, COUNT(*),
+			COALESCE(SUM(r.tool_result_bytes), 0), COALESCE(SUM(r.tool_result_tokens), 0),
+			COALESCE(MAX(r.tool_result_tokens), 0)
+		FROM conversation_events r
+		LEFT JOIN conversation_events c
+			ON c.event_type = 'tool_call' AND c.tool_id = r.tool_result_for_id AND c.session_id = r.session_id
+		WHERE r.event_type = 'tool_result'
 	`
+	var args []interface{}
+	if sessionID != "" {
+		query += " AND r.session_id = ?"
+		args = append(args, sessionID)
+	}
+	query += " GROUP BY name ORDER BY 4 DESC, name"
 
-	rows, err := s.db.QueryContext(ctx, query, claudeSessionID)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get conversation: %w", err)
+		return nil, fmt.Errorf("failed to get tool output stats: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
-	var events []*ConversationEvent
+	var stats []*ToolOutputStats
 	for rows.Next() {
-		event := &ConversationEvent{}
-		err := rows.Scan(
-			&event.ID, &event.SessionID, &event.ClaudeSessionID,
-			&event.Sequence, &event.EventType, &event.CreatedAt,
-			&event.Role, &event.Content,
-			&event.ToolID, &event.ToolName, &event.ToolInputJSON, &event.ParentToolUseID,
-			&event.ToolResultForID, &event.ToolResultContent,
-			&event.IsCompleted, &event.ApprovalStatus, &event.ApprovalID,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan event: %w", err)
+		st := &ToolOutputStats{}
+		if err := rows.Scan(&st.ToolName, &st.Results, &st.Bytes, &st.Tokens, &st.MaxTokens); err != nil {
+			return nil, fmt.Errorf("failed to scan tool output stats: %w", err)
 		}
-		events = append(events, event)
+		stats = append(stats, st)
 	}
-
-	return events, nil
+	return stats, rows.Err()
 }
 
-// GetSessionConversation retrieves all events for a session including parent history
-func (s *SQLiteStore) GetSessionConversation(ctx context.Context, sessionID string) ([]*ConversationEvent, error) {
-	// Walk up the parent chain to get all related claude session IDs
-	claudeSessionIDs := []string{}
-	currentID := sessionID
-	isFirstSession := true
-
-	for currentID != "" {
-		var claudeSessionID sql.NullString
-		var parentID sql.NullString
+// GetSessionUsage returns per-session usage in one aggregate query, counting
+// tool calls with a join rather than a query per session
+func (s *SQLiteStore) GetSessionUsage(ctx context.Context, filter SessionUsageFilter) ([]*SessionUsage, error) {
+	query := `
+		SELECT s.id, s.status, COALESCE(s.model, ''), s.created_at,
+			s.cost_usd, s.duration_ms, s.num_turns, COUNT(e.id)
+		FROM sessions s
+		LEFT JOIN conversation_events e
+			ON e.session_id = s.id AND e.event_type = 'tool_call'
+		WHERE 1 = 1
+	`
+	var args []interface{}
+	if !filter.Since.IsZero() {
+		query += " AND s.created_at >= ?"
+		args = append(args, filter.Since)
+	}
+	if filter.Status != "" {
+		query += " AND s.status = ?"
+		args = append(args, filter.Status)
+	}
+	if filter.Model != "" {
+		query += " AND s.model = ?"
+		args = append(args, filter.Model)
+	}
+	if filter.Owner != "" {
+		query += " AND s.owner = ?"
+		args = append(args, filter.Owner)
+	}
+	query += " GROUP BY s.id ORDER BY s.created_at, s.id"
 
-		err := s.db.QueryRowContext(ctx,
-			"SELECT claude_session_id, parent_session_id FROM sessions WHERE id = ?",
-			currentID,
-		).Scan(&claudeSessionID, &parentID)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				// If the requested session doesn't exist, return error
-				if isFirstSession {
-					return nil, fmt.Errorf("session not found: %s", sessionID)
-				}
-				// Otherwise, parent not found, just stop walking
-				break
-			}
-			return nil, fmt.Errorf("failed to get session: %w", err)
-		}
-		isFirstSession = false
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session usage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
 
-		// Add claude session ID if present (in reverse order for chronological events)
-		if claudeSessionID.Valid && claudeSessionID.String != "" {
-			claudeSessionIDs = append([]string{claudeSessionID.String}, claudeSessionIDs...)
+	var usage []*SessionUsage
+	for rows.Next() {
+		u := &SessionUsage{}
+		var cost sql.NullFloat64
+		var duration, turns sql.NullInt64
+		if err := rows.Scan(&u.SessionID, &u.Status, &u.Model, &u.CreatedAt,
+			&cost, &duration, &turns, &u.ToolCalls); err != nil {
+			return nil, fmt.Errorf("failed to scan session usage: %w", err)
 		}
-
-		// Move to parent
-		if parentID.Valid {
-			currentID = parentID.String
-		} else {
-			currentID = ""
+		if cost.Valid {
+			u.CostUSD = &cost.Float64
+		}
+		if duration.Valid {
+			d := int(duration.Int64)
+			u.DurationMS = &d
+		}
+		if turns.Valid {
+			t := int(turns.Int64)
+			u.Turns = &t
 		}
+		usage = append(usage, u)
 	}
+	return usage, rows.Err()
+}
 
-	if len(claudeSessionIDs) == 0 {
-		// No claude sessions yet, return empty
-		return []*ConversationEvent{}, nil
+// AggregateCostByRun totals the cost of a run's sessions, broken down by model
+func (s *SQLiteStore) AggregateCostByRun(ctx context.Context, runID string) (RunCostSummary, error) {
+	models, err := s.aggregateCostByModel(ctx, "run_id = ?", runID)
+	if err != nil {
+		return RunCostSummary{}, err
+	}
+	if len(models) == 0 {
+		return RunCostSummary{}, &NotFoundError{Type: "run", ID: runID}
 	}
 
-	// Get all events for all claude session IDs in chronological order
-	placeholders := make([]string, len(claudeSessionIDs))
-	args := make([]interface{}, len(claudeSessionIDs))
-	for i, id := range claudeSessionIDs {
-		placeholders[i] = "?"
-		args[i] = id
+	summary := RunCostSummary{RunID: runID, Models: models}
+	for _, m := range models {
+		summary.SessionCount += m.SessionCount
+		summary.CostUSD += m.CostUSD
+		summary.PromptTokens += m.PromptTokens
+		summary.CompletionTokens += m.CompletionTokens
 	}
+	return summary, nil
+}
 
-	// Build query that orders by the position in the claude session ID list first
-	// This ensures parent events come before child events
-	orderCases := make([]string, len(claudeSessionIDs))
-	for i := range claudeSessionIDs {
-		orderCases[i] = fmt.Sprintf("WHEN claude_session_id = ? THEN %d", i)
-		args = append(args, claudeSessionIDs[i])
+// GetSessionCostStats averages session cost in SQL
+func (s *SQLiteStore) GetSessionCostStats(ctx context.Context, runID string) (SessionCostStats, error) {
+	var stats SessionCostStats
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(cost_usd), COALESCE(AVG(cost_usd), 0)
+		FROM sessions
+		WHERE ? = '' OR run_id = ?
+	`, runID, runID).Scan(&stats.Count, &stats.MeanCostUSD)
+	if err != nil {
+		return SessionCostStats{}, fmt.Errorf("failed to get session cost stats: %w", err)
 	}
+	return stats, nil
+}
 
-	query := fmt.Sprintf(`
+// AggregateCostByModel totals session cost per model since the given time
+func (s *SQLiteStore) AggregateCostByModel(ctx context.Context, since time.Time) ([]ModelCostSummary, error) {
+	if since.IsZero() {
+		return s.aggregateCostByModel(ctx, "1 = 1")
+	}
+	return s.aggregateCostByModel(ctx, "created_at >= ?", since)
+}
+
+// aggregateCostByModel sums the cost and tokens of the sessions matching where
+// in a single grouped query
+func (s *SQLiteStore) aggregateCostByModel(ctx context.Context, where string, args ...interface{}) ([]ModelCostSummary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT COALESCE(model, '') AS model_name, COUNT(*),
+			COALESCE(SUM(cost_usd), 0),
+			COALESCE(SUM(COALESCE(input_tokens, 0) + COALESCE(cache_creation_input_tokens, 0) + COALESCE(cache_read_input_tokens, 0)), 0),
+			COALESCE(SUM(output_tokens), 0)
+		FROM sessions
+		WHERE `+where+`
+		GROUP BY model_name
+		ORDER BY 3 DESC, model_name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate session cost: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var models []ModelCostSummary
+	for rows.Next() {
+		var m ModelCostSummary
+		if err := rows.Scan(&m.Model, &m.SessionCount, &m.CostUSD, &m.PromptTokens, &m.CompletionTokens); err != nil {
+			return nil, fmt.Errorf("failed to scan cost summary: %w", err)
+		}
+		models = append(models, m)
+	}
+	return models, rows.Err()
+}
+
+// GetEventByPermalink retrieves a conversation event by its permalink ID
+func (s *SQLiteStore) GetEventByPermalink(ctx context.Context, permalink string) (*ConversationEvent, error) {
+	// Permalinks outlive ArchiveSession, so archived events are looked up
+	// when no live one matches
+	event, err := s.getEventByPermalink(ctx, "conversation_events", permalink)
+	var notFound *NotFoundError
+	if errors.As(err, &notFound) {
+		return s.getEventByPermalink(ctx, "archived_conversation_events", permalink)
+	}
+	return event, err
+}
+
+func (s *SQLiteStore) getEventByPermalink(ctx context.Context, table, permalink string) (*ConversationEvent, error) {
+	query := `
 		SELECT id, session_id, claude_session_id, sequence, event_type, created_at,
 			role, content,
 			tool_id, tool_name, tool_input_json, parent_tool_use_id,
 			tool_result_for_id, tool_result_content,
-			is_completed, approval_status, approval_id
-		FROM conversation_events
-		WHERE claude_session_id IN (%s)
-		ORDER BY
-			CASE %s END,
-			sequence
-	`, strings.Join(placeholders, ","), strings.Join(orderCases, " "))
+			COALESCE(tool_result_bytes, 0), COALESCE(tool_result_tokens, 0),
+			is_completed, approval_status, approval_id, permalink, COALESCE(language, ''), COALESCE(tool_cache_hit, 0),
+			COALESCE(truncated, 0),
+			COALESCE(tool_result_json, ''), COALESCE(tool_error, '')
+		FROM ` + table + `
+		WHERE permalink = ?
+	`
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	event := &ConversationEvent{}
+	err := s.db.QueryRowContext(ctx, query, permalink).Scan(
+		&event.ID, &event.SessionID, &event.ClaudeSessionID,
+		&event.Sequence, &event.EventType, &event.CreatedAt,
+		&event.Role, &event.Content,
+		&event.ToolID, &event.ToolName, &event.ToolInputJSON, &event.ParentToolUseID,
+		&event.ToolResultForID, &event.ToolResultContent,
+		&event.ToolResultBytes, &event.ToolResultTokens,
+		&event.IsCompleted, &event.ApprovalStatus, &event.ApprovalID, &event.Permalink, &event.Language, &event.ToolCacheHit,
+		&event.Truncated,
+		&event.ToolResultJSON, &event.ToolError,
+	)
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Type: "event", ID: permalink}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get conversation: %w", err)
+		return nil, fmt.Errorf("failed to get event by permalink: %w", err)
 	}
-	defer func() { _ = rows.Close() }()
 
-	var events []*ConversationEvent
-	for rows.Next() {
-		event := &ConversationEvent{}
-		err := rows.Scan(
-			&event.ID, &event.SessionID, &event.ClaudeSessionID,
-			&event.Sequence, &event.EventType, &event.CreatedAt,
-			&event.Role, &event.Content,
-			&event.ToolID, &event.ToolName, &event.ToolInputJSON, &event.ParentToolUseID,
-			&event.ToolResultForID, &event.ToolResultContent,
-			&event.IsCompleted, &event.ApprovalStatus, &event.ApprovalID,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan event: %w", err)
-		}
-		events = append(events, event)
+	if err := s.decryptEvent(event); err != nil {
+		return nil, err
 	}
 
-	return events, nil
+	return event, nil
+}
+
+// newEventPermalink generates an opaque, URL-safe permalink ID for an event
+func newEventPermalink() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate permalink: %w", err)
+	}
+	return "evt_" + hex.EncodeToString(b), nil
 }
 
 // GetPendingToolCall finds the most recent uncompleted tool call for a given session and tool name
@@ -2410,6 +5401,10 @@ func (s *SQLiteStore) GetPendingToolCall(ctx context.Context, sessionID string,
 		return nil, fmt.Errorf("failed to get pending tool call: %w", err)
 	}
 
+	if err := s.decryptEvent(event); err != nil {
+		return nil, err
+	}
+
 	return event, nil
 }
 
@@ -2448,6 +5443,10 @@ func (s *SQLiteStore) GetUncorrelatedPendingToolCall(ctx context.Context, sessio
 		return nil, fmt.Errorf("failed to get uncorrelated pending tool call: %w", err)
 	}
 
+	if err := s.decryptEvent(event); err != nil {
+		return nil, err
+	}
+
 	return event, nil
 }
 
@@ -2486,6 +5485,9 @@ func (s *SQLiteStore) GetPendingToolCalls(ctx context.Context, sessionID string)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan event: %w", err)
 		}
+		if err := s.decryptEvent(event); err != nil {
+			return nil, err
+		}
 		events = append(events, event)
 	}
 
@@ -2522,6 +5524,10 @@ func (s *SQLiteStore) GetToolCallByID(ctx context.Context, toolID string) (*Conv
 		return nil, fmt.Errorf("failed to get tool call by ID: %w", err)
 	}
 
+	if err := s.decryptEvent(event); err != nil {
+		return nil, err
+	}
+
 	return event, nil
 }
 
@@ -2729,7 +5735,11 @@ func (s *SQLiteStore) StoreRawEvent(ctx context.Context, sessionID string, event
 		VALUES (?, ?)
 	`
 
-	_, err := s.db.ExecContext(ctx, query, sessionID, eventJSON)
+	eventJSON, err := s.cipher.encrypt(eventJSON)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, query, sessionID, eventJSON)
 	if err != nil {
 		return fmt.Errorf("failed to store raw event: %w", err)
 	}
@@ -2750,9 +5760,17 @@ func (s *SQLiteStore) CreateApproval(ctx context.Context, approval *Approval) er
 		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.ExecContext(ctx, query,
+	toolInput, err := s.cipher.encrypt(string(approval.ToolInput))
+	if err != nil {
+		return err
+	}
+	comment, err := s.cipher.encrypt(approval.Comment)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, query,
 		approval.ID, approval.RunID, approval.SessionID, approval.ToolUseID, approval.Status.String(), approval.CreatedAt,
-		approval.ToolName, string(approval.ToolInput), approval.Comment,
+		approval.ToolName, toolInput, comment,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create approval: %w", err)
@@ -2764,7 +5782,7 @@ func (s *SQLiteStore) CreateApproval(ctx context.Context, approval *Approval) er
 func (s *SQLiteStore) GetApproval(ctx context.Context, id string) (*Approval, error) {
 	query := `
 		SELECT id, run_id, session_id, tool_use_id, status, created_at, responded_at,
-			tool_name, tool_input, comment
+			tool_name, tool_input, comment, COALESCE(responded_by, '')
 		FROM approvals WHERE id = ?
 	`
 
@@ -2778,7 +5796,7 @@ func (s *SQLiteStore) GetApproval(ctx context.Context, id string) (*Approval, er
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&approval.ID, &approval.RunID, &approval.SessionID, &toolUseID, &statusStr,
 		&approval.CreatedAt, &respondedAt,
-		&approval.ToolName, &toolInputStr, &comment,
+		&approval.ToolName, &toolInputStr, &comment, &approval.RespondedBy,
 	)
 	if err == sql.ErrNoRows {
 		return nil, &NotFoundError{Type: "approval", ID: id}
@@ -2800,8 +5818,9 @@ func (s *SQLiteStore) GetApproval(ctx context.Context, id string) (*Approval, er
 	if respondedAt.Valid {
 		approval.RespondedAt = &respondedAt.Time
 	}
-	approval.Comment = comment.String
-	approval.ToolInput = json.RawMessage(toolInputStr)
+	if err := s.decryptApproval(&approval, toolInputStr, comment.String); err != nil {
+		return nil, err
+	}
 
 	return &approval, nil
 }
@@ -2810,16 +5829,44 @@ func (s *SQLiteStore) GetApproval(ctx context.Context, id string) (*Approval, er
 func (s *SQLiteStore) GetPendingApprovals(ctx context.Context, sessionID string) ([]*Approval, error) {
 	query := `
 		SELECT id, run_id, session_id, tool_use_id, status, created_at, responded_at,
-			tool_name, tool_input, comment
+			tool_name, tool_input, comment, COALESCE(responded_by, '')
 		FROM approvals
 		WHERE session_id = ? AND status = ?
 		ORDER BY created_at ASC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, sessionID, ApprovalStatusLocalPending.String())
+	approvals, err := s.queryApprovals(ctx, query, sessionID, ApprovalStatusLocalPending.String())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pending approvals: %w", err)
 	}
+	return approvals, nil
+}
+
+// GetSessionApprovals retrieves every approval for a session, decided or not,
+// oldest first
+func (s *SQLiteStore) GetSessionApprovals(ctx context.Context, sessionID string) ([]*Approval, error) {
+	query := `
+		SELECT id, run_id, session_id, tool_use_id, status, created_at, responded_at,
+			tool_name, tool_input, comment, COALESCE(responded_by, '')
+		FROM approvals
+		WHERE session_id = ?
+		ORDER BY created_at ASC
+	`
+
+	approvals, err := s.queryApprovals(ctx, query, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session approvals: %w", err)
+	}
+	return approvals, nil
+}
+
+// queryApprovals runs an approvals query selecting the columns used by
+// GetPendingApprovals and scans the results
+func (s *SQLiteStore) queryApprovals(ctx context.Context, query string, args ...interface{}) ([]*Approval, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer func() { _ = rows.Close() }()
 
 	var approvals []*Approval
@@ -2834,7 +5881,7 @@ func (s *SQLiteStore) GetPendingApprovals(ctx context.Context, sessionID string)
 		err := rows.Scan(
 			&approval.ID, &approval.RunID, &approval.SessionID, &toolUseID, &statusStr,
 			&approval.CreatedAt, &respondedAt,
-			&approval.ToolName, &toolInputStr, &comment,
+			&approval.ToolName, &toolInputStr, &comment, &approval.RespondedBy,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan approval: %w", err)
@@ -2853,17 +5900,19 @@ func (s *SQLiteStore) GetPendingApprovals(ctx context.Context, sessionID string)
 		if respondedAt.Valid {
 			approval.RespondedAt = &respondedAt.Time
 		}
-		approval.Comment = comment.String
-		approval.ToolInput = json.RawMessage(toolInputStr)
+		if err := s.decryptApproval(&approval, toolInputStr, comment.String); err != nil {
+			return nil, err
+		}
 
 		approvals = append(approvals, &approval)
 	}
 
-	return approvals, nil
+	return approvals, rows.Err()
 }
 
-// UpdateApprovalResponse updates the status and comment of an approval
-func (s *SQLiteStore) UpdateApprovalResponse(ctx context.Context, id string, status ApprovalStatus, comment string) error {
+// UpdateApprovalResponse records the decision on an approval: its status,
+// comment and who decided
+func (s *SQLiteStore) UpdateApprovalResponse(ctx context.Context, id string, status ApprovalStatus, comment, respondedBy string) error {
 	// Validate status
 	if !status.IsValid() {
 		return fmt.Errorf("invalid approval status: %s", status)
@@ -2882,11 +5931,15 @@ func (s *SQLiteStore) UpdateApprovalResponse(ctx context.Context, id string, sta
 
 	query := `
 		UPDATE approvals
-		SET status = ?, comment = ?, responded_at = CURRENT_TIMESTAMP
+		SET status = ?, comment = ?, responded_by = NULLIF(?, ''), responded_at = CURRENT_TIMESTAMP
 		WHERE id = ? AND status = ?
 	`
 
-	result, err := s.db.ExecContext(ctx, query, status.String(), comment, id, ApprovalStatusLocalPending.String())
+	comment, err = s.cipher.encrypt(comment)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.ExecContext(ctx, query, status.String(), comment, respondedBy, id, ApprovalStatusLocalPending.String())
 	if err != nil {
 		return fmt.Errorf("failed to update approval response: %w", err)
 	}
@@ -2965,11 +6018,15 @@ func MCPServersFromConfig(sessionID string, config map[string]claudecode.MCPServ
 
 // CreateFileSnapshot stores a new file snapshot
 func (s *SQLiteStore) CreateFileSnapshot(ctx context.Context, snapshot *FileSnapshot) error {
-	_, err := s.db.ExecContext(ctx, `
+	content, err := s.cipher.encrypt(snapshot.Content)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
 		INSERT INTO file_snapshots (
 			tool_id, session_id, file_path, content
 		) VALUES (?, ?, ?, ?)
-	`, snapshot.ToolID, snapshot.SessionID, snapshot.FilePath, snapshot.Content)
+	`, snapshot.ToolID, snapshot.SessionID, snapshot.FilePath, content)
 	return err
 }
 
@@ -2988,16 +6045,257 @@ func (s *SQLiteStore) GetFileSnapshots(ctx context.Context, sessionID string) ([
 
 	var snapshots []FileSnapshot
 	for rows.Next() {
-		var s FileSnapshot
-		if err := rows.Scan(&s.ID, &s.ToolID, &s.SessionID, &s.FilePath,
-			&s.Content, &s.CreatedAt); err != nil {
+		var snapshot FileSnapshot
+		if err := rows.Scan(&snapshot.ID, &snapshot.ToolID, &snapshot.SessionID, &snapshot.FilePath,
+			&snapshot.Content, &snapshot.CreatedAt); err != nil {
+			return nil, err
+		}
+		if snapshot.Content, err = s.cipher.decrypt(snapshot.Content); err != nil {
 			return nil, err
 		}
-		snapshots = append(snapshots, s)
+		snapshots = append(snapshots, snapshot)
 	}
 	return snapshots, rows.Err()
 }
 
+// SetSessionTags adds or replaces the given tags on a session. Tags with an
+// empty value are removed.
+func (s *SQLiteStore) SetSessionTags(ctx context.Context, sessionID string, tags map[string]string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM sessions WHERE id = ?", sessionID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check session: %w", err)
+	}
+	if exists == 0 {
+		return &NotFoundError{Type: "session", ID: sessionID}
+	}
+
+	for key, value := range tags {
+		if value == "" {
+			_, err = tx.ExecContext(ctx, "DELETE FROM session_tags WHERE session_id = ? AND key = ?", sessionID, key)
+		} else {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO session_tags (session_id, key, value) VALUES (?, ?, ?)
+				ON CONFLICT (session_id, key) DO UPDATE SET value = excluded.value
+			`, sessionID, key, value)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set tag %s: %w", key, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSessionTags returns a session's tags
+func (s *SQLiteStore) GetSessionTags(ctx context.Context, sessionID string) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT key, value FROM session_tags WHERE session_id = ?", sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session tags: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	tags := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan session tag: %w", err)
+		}
+		tags[key] = value
+	}
+	return tags, rows.Err()
+}
+
+// AddAnnotation attaches a note to a conversation event
+func (s *SQLiteStore) AddAnnotation(ctx context.Context, eventID int64, author, note string) (*Annotation, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM conversation_events WHERE id = ?)", eventID,
+	).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check event: %w", err)
+	}
+	if !exists {
+		return nil, &NotFoundError{Type: "conversation event", ID: fmt.Sprint(eventID)}
+	}
+
+	annotation := &Annotation{EventID: eventID, Author: author, Note: note, CreatedAt: time.Now()}
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO annotations (event_id, author, note, created_at) VALUES (?, ?, ?, ?)
+	`, eventID, author, note, annotation.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add annotation: %w", err)
+	}
+	annotation.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotation id: %w", err)
+	}
+	return annotation, nil
+}
+
+// GetAnnotationsForSession retrieves the annotations on a session's events
+func (s *SQLiteStore) GetAnnotationsForSession(ctx context.Context, sessionID string) ([]*Annotation, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT a.id, a.event_id, a.author, a.note, a.created_at
+		FROM annotations a
+		JOIN conversation_events e ON e.id = a.event_id
+		WHERE e.session_id = ?
+		ORDER BY a.created_at, a.id
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	annotations := []*Annotation{}
+	for rows.Next() {
+		var a Annotation
+		if err := rows.Scan(&a.ID, &a.EventID, &a.Author, &a.Note, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan annotation: %w", err)
+		}
+		annotations = append(annotations, &a)
+	}
+	return annotations, rows.Err()
+}
+
+// CreateAttachment records an attachment whose blob has been stored
+func (s *SQLiteStore) CreateAttachment(ctx context.Context, attachment *Attachment) error {
+	if attachment.CreatedAt.IsZero() {
+		attachment.CreatedAt = time.Now()
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO attachments (
+			id, session_id, name, content_type, size_bytes, sha256, backend, storage_key, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, attachment.ID, attachment.SessionID, attachment.Name, attachment.ContentType,
+		attachment.SizeBytes, attachment.SHA256, attachment.Backend, attachment.StorageKey, attachment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+	return nil
+}
+
+const attachmentColumns = `id, session_id, name, content_type, size_bytes, sha256, backend, storage_key, created_at`
+
+func scanAttachment(scanner interface{ Scan(...interface{}) error }) (*Attachment, error) {
+	var a Attachment
+	if err := scanner.Scan(&a.ID, &a.SessionID, &a.Name, &a.ContentType, &a.SizeBytes,
+		&a.SHA256, &a.Backend, &a.StorageKey, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetAttachment retrieves an attachment's reference
+func (s *SQLiteStore) GetAttachment(ctx context.Context, id string) (*Attachment, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+attachmentColumns+` FROM attachments WHERE id = ?`, id)
+	attachment, err := scanAttachment(row)
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Type: "attachment", ID: id}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+// ListSessionAttachments retrieves a session's attachments, oldest first
+func (s *SQLiteStore) ListSessionAttachments(ctx context.Context, sessionID string) ([]*Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM attachments
+		WHERE session_id = ?
+		ORDER BY created_at, id
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var attachments []*Attachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// CreateWebhook registers a webhook
+func (s *SQLiteStore) CreateWebhook(ctx context.Context, webhook *Webhook) error {
+	if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = time.Now()
+	}
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook events: %w", err)
+	}
+	secret, err := s.cipher.encrypt(webhook.Secret)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO webhooks (id, url, secret, events, created_at) VALUES (?, ?, ?, ?, ?)
+	`, webhook.ID, webhook.URL, secret, string(events), webhook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+// DeleteWebhook removes a webhook registration
+func (s *SQLiteStore) DeleteWebhook(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return &NotFoundError{Type: "webhook", ID: id}
+	}
+	return nil
+}
+
+// ListWebhooks retrieves every webhook registration, oldest first
+func (s *SQLiteStore) ListWebhooks(ctx context.Context) ([]*Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, url, secret, events, created_at
+		FROM webhooks
+		ORDER BY created_at, id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var webhooks []*Webhook
+	for rows.Next() {
+		var w Webhook
+		var events string
+		if err := rows.Scan(&w.ID, &w.URL, &w.Secret, &events, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		if w.Secret, err = s.cipher.decrypt(w.Secret); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(events), &w.Events); err != nil {
+			return nil, fmt.Errorf("failed to decode events of webhook %s: %w", w.ID, err)
+		}
+		webhooks = append(webhooks, &w)
+	}
+	return webhooks, rows.Err()
+}
+
 // GetSessionCount returns the total number of sessions
 func (s *SQLiteStore) GetSessionCount(ctx context.Context) (int, error) {
 	var count int
@@ -0,0 +1,396 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// computeAuditHash derives the tamper-evident hash for an audit entry.
+// Only fields that never change after insert are covered so that completing
+// an entry does not break the chain. The outcome is chained separately by the
+// entry's completion record; see computeAuditCompletionHash.
+func computeAuditHash(entry *AuditEntry) string {
+	h := sha256.New()
+	for _, part := range []string{
+		entry.PrevHash,
+		entry.Identity,
+		entry.Method,
+		entry.SessionID,
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// computeAuditCompletionHash derives the hash of the completion record that
+// CompleteAuditEntry appends to the chain for the entry completesID. record
+// holds the completed entry's outcome, with PrevHash set to the hash it
+// follows in the chain.
+func computeAuditCompletionHash(record *AuditEntry, completesID int64) string {
+	var completedAt string
+	if record.CompletedAt != nil {
+		completedAt = record.CompletedAt.UTC().Format(time.RFC3339Nano)
+	}
+	h := sha256.New()
+	for _, part := range []string{
+		record.PrevHash,
+		"completion",
+		strconv.FormatInt(completesID, 10),
+		record.Identity,
+		record.Method,
+		record.SessionID,
+		record.Outcome,
+		record.ErrorMessage,
+		completedAt,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// appendAuditCompletion chains a completion record for entry, which must
+// already hold its final outcome, onto the end of the audit log
+func appendAuditCompletion(ctx context.Context, tx *sql.Tx, entry *AuditEntry) error {
+	var prevHash sql.NullString
+	err := tx.QueryRowContext(ctx, `
+		SELECT entry_hash FROM audit_log ORDER BY id DESC LIMIT 1
+	`).Scan(&prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get previous audit hash: %w", err)
+	}
+
+	record := *entry
+	record.PrevHash = prevHash.String
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_log (
+			identity, method, session_id, outcome, error_message,
+			created_at, completed_at, prev_hash, entry_hash, completes_id
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, record.Identity, record.Method, record.SessionID, record.Outcome, record.ErrorMessage,
+		*record.CompletedAt, *record.CompletedAt, record.PrevHash, computeAuditCompletionHash(&record, entry.ID), entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit completion record: %w", err)
+	}
+	return nil
+}
+
+// CreateAuditEntry appends a new entry to the audit log, chaining it to the
+// previous entry's hash. The entry's ID, CreatedAt, PrevHash and EntryHash are
+// populated on success.
+func (s *SQLiteStore) CreateAuditEntry(ctx context.Context, entry *AuditEntry) error {
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var prevHash sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT entry_hash FROM audit_log ORDER BY id DESC LIMIT 1
+	`).Scan(&prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get previous audit hash: %w", err)
+	}
+
+	if entry.Outcome == "" {
+		entry.Outcome = AuditOutcomePending
+	}
+	entry.CreatedAt = time.Now().UTC()
+	entry.PrevHash = prevHash.String
+	entry.EntryHash = computeAuditHash(entry)
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO audit_log (
+			identity, method, session_id, outcome, error_message,
+			created_at, prev_hash, entry_hash
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, entry.Identity, entry.Method, entry.SessionID, entry.Outcome, entry.ErrorMessage,
+		entry.CreatedAt, entry.PrevHash, entry.EntryHash)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get audit entry id: %w", err)
+	}
+	entry.ID = id
+
+	return tx.Commit()
+}
+
+// CompleteAuditEntry records the final outcome of a previously created entry
+// and appends a completion record chaining that outcome, so editing it later
+// fails VerifyAuditChain
+func (s *SQLiteStore) CompleteAuditEntry(ctx context.Context, id int64, outcome string, errorMessage string) error {
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	entry := &AuditEntry{ID: id}
+	var sessionID sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT identity, method, session_id FROM audit_log
+		WHERE id = ? AND outcome = ? AND completes_id IS NULL
+	`, id, AuditOutcomePending).Scan(&entry.Identity, &entry.Method, &sessionID)
+	if err == sql.ErrNoRows {
+		return &NotFoundError{Type: "audit entry", ID: fmt.Sprintf("%d", id)}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get audit entry: %w", err)
+	}
+	entry.SessionID = sessionID.String
+
+	completedAt := time.Now().UTC()
+	entry.Outcome = outcome
+	entry.ErrorMessage = errorMessage
+	entry.CompletedAt = &completedAt
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE audit_log
+		SET outcome = ?, error_message = ?, completed_at = ?
+		WHERE id = ?
+	`, outcome, errorMessage, completedAt, id); err != nil {
+		return fmt.Errorf("failed to complete audit entry: %w", err)
+	}
+	if err := appendAuditCompletion(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListAuditEntries returns audit entries matching the filter, newest first.
+// Completion records are folded into the entries they complete.
+func (s *SQLiteStore) ListAuditEntries(ctx context.Context, filter AuditLogFilter) ([]*AuditEntry, error) {
+	conditions := []string{"completes_id IS NULL"}
+	var args []interface{}
+
+	if filter.Method != "" {
+		conditions = append(conditions, "method = ?")
+		args = append(args, filter.Method)
+	}
+	if filter.SessionID != "" {
+		conditions = append(conditions, "session_id = ?")
+		args = append(args, filter.SessionID)
+	}
+	if filter.Identity != "" {
+		conditions = append(conditions, "identity = ?")
+		args = append(args, filter.Identity)
+	}
+	if filter.Outcome != "" {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, filter.Outcome)
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+	if filter.BeforeID > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, filter.BeforeID)
+	}
+
+	query := `
+		SELECT id, identity, method, session_id, outcome, error_message,
+			created_at, completed_at, prev_hash, entry_hash
+		FROM audit_log
+	`
+	query += " WHERE " + strings.Join(conditions, " AND ")
+	query += " ORDER BY id DESC"
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	query += " LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var entries []*AuditEntry
+	for rows.Next() {
+		entry := &AuditEntry{}
+		var sessionID, errorMessage, prevHash sql.NullString
+		var completedAt sql.NullTime
+		if err := rows.Scan(&entry.ID, &entry.Identity, &entry.Method, &sessionID,
+			&entry.Outcome, &errorMessage, &entry.CreatedAt, &completedAt,
+			&prevHash, &entry.EntryHash); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.SessionID = sessionID.String
+		entry.ErrorMessage = errorMessage.String
+		entry.PrevHash = prevHash.String
+		if completedAt.Valid {
+			entry.CompletedAt = &completedAt.Time
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// PruneAuditEntries deletes audit entries created before the cutoff and
+// returns the number removed. The hash of the last pruned entry is kept as an
+// anchor so the remaining entries still verify as an unbroken chain.
+func (s *SQLiteStore) PruneAuditEntries(ctx context.Context, before time.Time) (int64, error) {
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var lastID int64
+	var anchorHash string
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, entry_hash FROM audit_log
+		WHERE created_at < ?
+		ORDER BY id DESC LIMIT 1
+	`, before.UTC()).Scan(&lastID, &anchorHash)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find audit entries to prune: %w", err)
+	}
+
+	// Completion records go with the entries but aren't counted
+	var pruned int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM audit_log WHERE id <= ? AND completes_id IS NULL
+	`, lastID).Scan(&pruned)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count audit entries to prune: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM audit_log WHERE id <= ?`, lastID); err != nil {
+		return 0, fmt.Errorf("failed to prune audit entries: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_retention (id, pruned_through_id, anchor_hash, pruned_at)
+		VALUES (1, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			pruned_through_id = excluded.pruned_through_id,
+			anchor_hash = excluded.anchor_hash,
+			pruned_at = excluded.pruned_at
+	`, lastID, anchorHash, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to record audit retention anchor: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit audit prune: %w", err)
+	}
+	return pruned, nil
+}
+
+// VerifyAuditChain walks the full audit log in insertion order and checks
+// that every entry's hash matches its contents and links to its predecessor,
+// and that each completed entry's outcome matches its chained completion
+// record. If older entries have been pruned, the chain is checked from the
+// recorded anchor. It returns the ID of the first entry that fails
+// verification, or 0.
+func (s *SQLiteStore) VerifyAuditChain(ctx context.Context) (int64, error) {
+	var anchor sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT anchor_hash FROM audit_retention WHERE id = 1
+	`).Scan(&anchor)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get audit retention anchor: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, identity, method, session_id, outcome, error_message,
+			created_at, completed_at, prev_hash, entry_hash, completes_id
+		FROM audit_log
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	// Entries whose completion record hasn't been reached yet
+	uncompleted := make(map[int64]*AuditEntry)
+	expectedPrev := anchor.String
+	for rows.Next() {
+		entry := &AuditEntry{}
+		var sessionID, errorMessage, prevHash sql.NullString
+		var completedAt sql.NullTime
+		var completesID sql.NullInt64
+		if err := rows.Scan(&entry.ID, &entry.Identity, &entry.Method, &sessionID,
+			&entry.Outcome, &errorMessage, &entry.CreatedAt, &completedAt,
+			&prevHash, &entry.EntryHash, &completesID); err != nil {
+			return 0, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.SessionID = sessionID.String
+		entry.ErrorMessage = errorMessage.String
+		entry.PrevHash = prevHash.String
+		if completedAt.Valid {
+			entry.CompletedAt = &completedAt.Time
+		}
+		if entry.PrevHash != expectedPrev {
+			return entry.ID, nil
+		}
+		expectedPrev = entry.EntryHash
+
+		if !completesID.Valid {
+			if computeAuditHash(entry) != entry.EntryHash {
+				return entry.ID, nil
+			}
+			uncompleted[entry.ID] = entry
+			continue
+		}
+
+		if computeAuditCompletionHash(entry, completesID.Int64) != entry.EntryHash {
+			return entry.ID, nil
+		}
+		// The completed entry is missing if it was pruned
+		completed, ok := uncompleted[completesID.Int64]
+		if !ok {
+			continue
+		}
+		delete(uncompleted, completed.ID)
+		if completed.Outcome != entry.Outcome || completed.ErrorMessage != entry.ErrorMessage ||
+			completed.CompletedAt == nil || entry.CompletedAt == nil || !completed.CompletedAt.Equal(*entry.CompletedAt) {
+			return completed.ID, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// An entry can only leave pending through CompleteAuditEntry
+	var firstBad int64
+	for id, entry := range uncompleted {
+		if (entry.Outcome != AuditOutcomePending || entry.CompletedAt != nil) && (firstBad == 0 || id < firstBad) {
+			firstBad = id
+		}
+	}
+	return firstBad, nil
+}
@@ -0,0 +1,222 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	dbPath := testutil.DatabasePath(t, "sqlite-audit")
+	store, err := NewSQLiteStore(dbPath)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+
+	first := &AuditEntry{Identity: "local", Method: "launchSession"}
+	require.NoError(t, store.CreateAuditEntry(ctx, first))
+	second := &AuditEntry{Identity: "local", Method: "interruptSession", SessionID: "sess-1"}
+	require.NoError(t, store.CreateAuditEntry(ctx, second))
+
+	t.Run("chains hashes", func(t *testing.T) {
+		assert.Empty(t, first.PrevHash)
+		assert.NotEmpty(t, first.EntryHash)
+		assert.Equal(t, first.EntryHash, second.PrevHash)
+
+		badID, err := store.VerifyAuditChain(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, badID)
+	})
+
+	t.Run("completes entries", func(t *testing.T) {
+		require.NoError(t, store.CompleteAuditEntry(ctx, first.ID, AuditOutcomeSuccess, ""))
+		require.NoError(t, store.CompleteAuditEntry(ctx, second.ID, AuditOutcomeError, "boom"))
+
+		// Completed entries are immutable
+		err := store.CompleteAuditEntry(ctx, first.ID, AuditOutcomeError, "again")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		entries, err := store.ListAuditEntries(ctx, AuditLogFilter{})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, second.ID, entries[0].ID, "newest first")
+		assert.Equal(t, AuditOutcomeError, entries[0].Outcome)
+		assert.Equal(t, "boom", entries[0].ErrorMessage)
+		assert.NotNil(t, entries[0].CompletedAt)
+		assert.Equal(t, AuditOutcomeSuccess, entries[1].Outcome)
+
+		// Completion must not break the chain
+		badID, err := store.VerifyAuditChain(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, badID)
+	})
+
+	t.Run("filters entries", func(t *testing.T) {
+		entries, err := store.ListAuditEntries(ctx, AuditLogFilter{SessionID: "sess-1"})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "interruptSession", entries[0].Method)
+
+		entries, err = store.ListAuditEntries(ctx, AuditLogFilter{Method: "launchSession", Outcome: AuditOutcomeSuccess})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		future := time.Now().Add(time.Hour)
+		entries, err = store.ListAuditEntries(ctx, AuditLogFilter{Since: &future})
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("detects an edited outcome", func(t *testing.T) {
+		_, err := store.db.Exec(`UPDATE audit_log SET outcome = ?, error_message = '' WHERE id = ?`, AuditOutcomeSuccess, second.ID)
+		require.NoError(t, err)
+		defer func() {
+			_, err := store.db.Exec(`UPDATE audit_log SET outcome = ?, error_message = 'boom' WHERE id = ?`, AuditOutcomeError, second.ID)
+			require.NoError(t, err)
+		}()
+
+		badID, err := store.VerifyAuditChain(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, second.ID, badID)
+	})
+
+	t.Run("detects an outcome set without completing", func(t *testing.T) {
+		third := &AuditEntry{Identity: "local", Method: "deleteSession"}
+		require.NoError(t, store.CreateAuditEntry(ctx, third))
+		_, err := store.db.Exec(`UPDATE audit_log SET outcome = ? WHERE id = ?`, AuditOutcomeSuccess, third.ID)
+		require.NoError(t, err)
+
+		badID, err := store.VerifyAuditChain(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, third.ID, badID)
+
+		_, err = store.db.Exec(`UPDATE audit_log SET outcome = ? WHERE id = ?`, AuditOutcomePending, third.ID)
+		require.NoError(t, err)
+		badID, err = store.VerifyAuditChain(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, badID)
+	})
+
+	t.Run("detects tampering", func(t *testing.T) {
+		_, err := store.db.Exec(`UPDATE audit_log SET method = 'getConversation' WHERE id = ?`, first.ID)
+		require.NoError(t, err)
+
+		badID, err := store.VerifyAuditChain(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, badID)
+	})
+}
+
+func TestAuditCompletionMigration(t *testing.T) {
+	dbPath := testutil.DatabasePath(t, "sqlite-audit-migration")
+	store, err := NewSQLiteStore(dbPath)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	entry := &AuditEntry{Identity: "local", Method: "launchSession"}
+	require.NoError(t, store.CreateAuditEntry(ctx, entry))
+
+	// Complete the entry the way the log did before completion records
+	_, err = store.db.Exec(`UPDATE audit_log SET outcome = ?, completed_at = ? WHERE id = ?`, AuditOutcomeSuccess, time.Now().UTC(), entry.ID)
+	require.NoError(t, err)
+	_, err = store.db.Exec(`ALTER TABLE audit_log DROP COLUMN completes_id`)
+	require.NoError(t, err)
+	_, err = store.db.Exec(`DELETE FROM schema_version WHERE version = 52`)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	store, err = NewSQLiteStore(dbPath)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	badID, err := store.VerifyAuditChain(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, badID)
+	entries, err := store.ListAuditEntries(ctx, AuditLogFilter{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "completion records aren't listed")
+
+	_, err = store.db.Exec(`UPDATE audit_log SET outcome = ? WHERE id = ?`, AuditOutcomeError, entry.ID)
+	require.NoError(t, err)
+	badID, err = store.VerifyAuditChain(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, entry.ID, badID)
+}
+
+func TestAuditRetention(t *testing.T) {
+	dbPath := testutil.DatabasePath(t, "sqlite-audit-retention")
+	store, err := NewSQLiteStore(dbPath)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateSession(ctx, &Session{
+		ID:             "sess-1",
+		RunID:          "run-1",
+		Query:          "test",
+		Status:         SessionStatusCompleted,
+		CreatedAt:      time.Now(),
+		LastActivityAt: time.Now(),
+	}))
+
+	var entries []*AuditEntry
+	for _, method := range []string{"launchSession", "interruptSession", "continueSession", "archiveSession"} {
+		entry := &AuditEntry{Identity: "local", Method: method, SessionID: "sess-1"}
+		require.NoError(t, store.CreateAuditEntry(ctx, entry))
+		entries = append(entries, entry)
+	}
+
+	t.Run("survives conversation pruning", func(t *testing.T) {
+		require.NoError(t, store.DeleteSessionData(ctx, "sess-1"))
+
+		remaining, err := store.ListAuditEntries(ctx, AuditLogFilter{SessionID: "sess-1"})
+		require.NoError(t, err)
+		assert.Len(t, remaining, len(entries))
+	})
+
+	t.Run("pages with before id", func(t *testing.T) {
+		page, err := store.ListAuditEntries(ctx, AuditLogFilter{BeforeID: entries[2].ID, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		assert.Equal(t, entries[1].ID, page[0].ID)
+	})
+
+	t.Run("prunes old entries and keeps the chain verifiable", func(t *testing.T) {
+		// Age the first two entries past the cutoff
+		old := time.Now().Add(-48 * time.Hour).UTC()
+		_, err := store.db.Exec(`UPDATE audit_log SET created_at = ? WHERE id <= ?`, old, entries[1].ID)
+		require.NoError(t, err)
+
+		pruned, err := store.PruneAuditEntries(ctx, time.Now().Add(-24*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), pruned)
+
+		remaining, err := store.ListAuditEntries(ctx, AuditLogFilter{})
+		require.NoError(t, err)
+		require.Len(t, remaining, 2)
+		assert.Equal(t, entries[2].ID, remaining[1].ID)
+
+		badID, err := store.VerifyAuditChain(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, badID)
+
+		// Nothing left to prune
+		pruned, err = store.PruneAuditEntries(ctx, time.Now().Add(-24*time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, pruned)
+
+		// New entries still chain onto the survivors
+		next := &AuditEntry{Identity: "local", Method: "launchSession"}
+		require.NoError(t, store.CreateAuditEntry(ctx, next))
+		assert.Equal(t, entries[3].EntryHash, next.PrevHash)
+		badID, err = store.VerifyAuditChain(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, badID)
+	})
+}
@@ -94,7 +94,7 @@ func TestSQLiteStoreIntegration(t *testing.T) {
 		}
 
 		// List sessions
-		sessions, err := store.ListSessions(ctx)
+		sessions, err := store.ListSessions(ctx, ListSessionsFilter{})
 		if err != nil {
 			t.Fatalf("failed to list sessions: %v", err)
 		}
@@ -173,7 +173,7 @@ func TestSQLiteStoreIntegration(t *testing.T) {
 		}
 
 		// Get conversation by claude session ID
-		conversation, err := store.GetConversation(ctx, claudeSessionID)
+		conversation, err := store.GetConversation(ctx, claudeSessionID, ConversationPage{})
 		if err != nil {
 			t.Fatalf("failed to get conversation: %v", err)
 		}
@@ -190,7 +190,7 @@ func TestSQLiteStoreIntegration(t *testing.T) {
 		}
 
 		// Get conversation by session ID
-		sessionConv, err := store.GetSessionConversation(ctx, sessionID)
+		sessionConv, err := store.GetSessionConversation(ctx, sessionID, ConversationPage{})
 		if err != nil {
 			t.Fatalf("failed to get session conversation: %v", err)
 		}
@@ -458,7 +458,7 @@ func TestSQLiteStoreIntegration(t *testing.T) {
 		}
 
 		// Verify all events were stored
-		events, err := store.GetConversation(ctx, claudeSessionID)
+		events, err := store.GetConversation(ctx, claudeSessionID, ConversationPage{})
 		if err != nil {
 			t.Fatalf("failed to get conversation: %v", err)
 		}
@@ -509,7 +509,7 @@ func TestSQLiteStoreIntegration(t *testing.T) {
 
 		// Query all events
 		start = time.Now()
-		events, err := store.GetConversation(ctx, claudeSessionID)
+		events, err := store.GetConversation(ctx, claudeSessionID, ConversationPage{})
 		if err != nil {
 			t.Fatalf("failed to get conversation: %v", err)
 		}
@@ -623,7 +623,7 @@ func TestSQLiteStorePersistence(t *testing.T) {
 		t.Errorf("expected query %s, got %s", session.Query, retrieved.Query)
 	}
 
-	events, err := store2.GetConversation(ctx, session.ClaudeSessionID)
+	events, err := store2.GetConversation(ctx, session.ClaudeSessionID, ConversationPage{})
 	if err != nil {
 		t.Fatalf("failed to get conversation: %v", err)
 	}
@@ -788,7 +788,7 @@ func TestSQLiteStoreWithMockSession(t *testing.T) {
 	}
 
 	// Verify the complete conversation
-	conversation, err := store.GetConversation(ctx, claudeSessionID)
+	conversation, err := store.GetConversation(ctx, claudeSessionID, ConversationPage{})
 	if err != nil {
 		t.Fatalf("failed to get conversation: %v", err)
 	}
@@ -0,0 +1,270 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// eventSearchTriggers keep conversation_events_fts in step with
+// conversation_events for each full-text module. FTS5 is told the old content
+// to remove; FTS4 reads it back from the content table, so its deletes must
+// run before the row changes.
+var eventSearchTriggers = map[string]string{
+	"fts5": `
+		CREATE TRIGGER IF NOT EXISTS conversation_events_fts_insert AFTER INSERT ON conversation_events BEGIN
+			INSERT INTO conversation_events_fts(rowid, content) VALUES (new.id, new.content);
+		END;
+		CREATE TRIGGER IF NOT EXISTS conversation_events_fts_delete AFTER DELETE ON conversation_events BEGIN
+			INSERT INTO conversation_events_fts(conversation_events_fts, rowid, content) VALUES ('delete', old.id, old.content);
+		END;
+		CREATE TRIGGER IF NOT EXISTS conversation_events_fts_update AFTER UPDATE OF content ON conversation_events BEGIN
+			INSERT INTO conversation_events_fts(conversation_events_fts, rowid, content) VALUES ('delete', old.id, old.content);
+			INSERT INTO conversation_events_fts(rowid, content) VALUES (new.id, new.content);
+		END;
+	`,
+	"fts4": `
+		CREATE TRIGGER IF NOT EXISTS conversation_events_fts_insert AFTER INSERT ON conversation_events BEGIN
+			INSERT INTO conversation_events_fts(docid, content) VALUES (new.id, new.content);
+		END;
+		CREATE TRIGGER IF NOT EXISTS conversation_events_fts_delete BEFORE DELETE ON conversation_events BEGIN
+			DELETE FROM conversation_events_fts WHERE docid = old.id;
+		END;
+		CREATE TRIGGER IF NOT EXISTS conversation_events_fts_before_update BEFORE UPDATE OF content ON conversation_events BEGIN
+			DELETE FROM conversation_events_fts WHERE docid = old.id;
+		END;
+		CREATE TRIGGER IF NOT EXISTS conversation_events_fts_update AFTER UPDATE OF content ON conversation_events BEGIN
+			INSERT INTO conversation_events_fts(docid, content) VALUES (new.id, new.content);
+		END;
+	`,
+}
+
+// eventSearchModule picks the full-text module for the events index. FTS5 is
+// only compiled into go-sqlite3 with the sqlite_fts5 build tag; without it
+// the index falls back to FTS4, which is always available.
+func (s *SQLiteStore) eventSearchModule() (string, error) {
+	var fts5 bool
+	if err := s.db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil {
+		return "", fmt.Errorf("failed to check for fts5: %w", err)
+	}
+	if fts5 {
+		return "fts5", nil
+	}
+	return "fts4", nil
+}
+
+// createEventSearchIndex creates the external-content full-text index over
+// conversation event content, its sync triggers, and indexes existing events
+func (s *SQLiteStore) createEventSearchIndex() error {
+	module, err := s.eventSearchModule()
+	if err != nil {
+		return err
+	}
+
+	table := `CREATE VIRTUAL TABLE IF NOT EXISTS conversation_events_fts USING fts5(content, content='conversation_events', content_rowid='id')`
+	if module == "fts4" {
+		table = `CREATE VIRTUAL TABLE IF NOT EXISTS conversation_events_fts USING fts4(content, content='conversation_events')`
+	}
+	if _, err := s.db.Exec(table); err != nil {
+		return fmt.Errorf("failed to create conversation_events_fts table: %w", err)
+	}
+	if _, err := s.db.Exec(eventSearchTriggers[module]); err != nil {
+		return fmt.Errorf("failed to create conversation_events_fts triggers: %w", err)
+	}
+	if _, err := s.db.Exec(`INSERT INTO conversation_events_fts(conversation_events_fts) VALUES ('rebuild')`); err != nil {
+		return fmt.Errorf("failed to build conversation_events_fts: %w", err)
+	}
+	return nil
+}
+
+// checkEventSearchIndex refuses a database whose events index was created
+// with FTS5 when this binary lacks it, since every conversation event insert
+// would otherwise fail in the index trigger
+func (s *SQLiteStore) checkEventSearchIndex() error {
+	var definition string
+	err := s.db.QueryRow(`
+		SELECT COALESCE(sql, '') FROM sqlite_master
+		WHERE type = 'table' AND name = 'conversation_events_fts'
+	`).Scan(&definition)
+	if err != nil || !strings.Contains(strings.ToLower(definition), "using fts5") {
+		return nil
+	}
+	module, err := s.eventSearchModule()
+	if err != nil {
+		return err
+	}
+	if module != "fts5" {
+		return fmt.Errorf("database search index requires fts5: build with -tags sqlite_fts5")
+	}
+	return nil
+}
+
+// ftsMatchQuery turns free text into a MATCH expression that can only match
+// words and phrases. Each word, or double-quoted phrase, becomes a quoted
+// string of just its letters and digits, so operators such as OR, NEAR, *, ^
+// and column filters are searched for as text instead of being interpreted.
+// Both tokenizers split on punctuation anyway, so nothing searchable is lost.
+// An empty result means nothing can match.
+func ftsMatchQuery(query string) string {
+	var terms []string
+	for i, part := range strings.Split(query, `"`) {
+		// Odd parts sit between a pair of quotes
+		words := []string{part}
+		if i%2 == 0 {
+			words = strings.Fields(part)
+		}
+		for _, word := range words {
+			tokens := strings.FieldsFunc(word, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+			if len(tokens) == 0 {
+				continue
+			}
+			terms = append(terms, `"`+strings.Join(tokens, " ")+`"`)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// SearchEvents finds conversation events whose content matches query
+func (s *SQLiteStore) SearchEvents(ctx context.Context, query string, filter SearchFilter) ([]*ConversationEvent, error) {
+	if s.cipher != nil {
+		return nil, fmt.Errorf("full-text search is unavailable when conversation content is encrypted")
+	}
+
+	match := ftsMatchQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	sqlQuery := `
+		SELECT e.id, e.session_id, e.claude_session_id, e.sequence, e.event_type, e.created_at,
+			e.role, e.content,
+			e.tool_id, e.tool_name, e.tool_input_json, e.parent_tool_use_id,
+			e.tool_result_for_id, e.tool_result_content,
+			COALESCE(e.tool_result_bytes, 0), COALESCE(e.tool_result_tokens, 0),
+			e.is_completed, e.approval_status, e.approval_id, COALESCE(e.permalink, ''), COALESCE(e.language, ''), COALESCE(e.tool_cache_hit, 0),
+			COALESCE(e.truncated, 0),
+			COALESCE(e.tool_result_json, ''), COALESCE(e.tool_error, '')
+		FROM conversation_events_fts
+		JOIN conversation_events e ON e.id = conversation_events_fts.rowid
+		WHERE conversation_events_fts MATCH ?`
+	args := []interface{}{match}
+
+	if filter.SessionID != "" {
+		sqlQuery += " AND e.session_id = ?"
+		args = append(args, filter.SessionID)
+	}
+	if len(filter.EventTypes) > 0 {
+		sqlQuery += " AND e.event_type IN (?" + strings.Repeat(", ?", len(filter.EventTypes)-1) + ")"
+		for _, eventType := range filter.EventTypes {
+			args = append(args, eventType)
+		}
+	}
+	if !filter.Since.IsZero() {
+		sqlQuery += " AND e.created_at >= ?"
+		args = append(args, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		sqlQuery += " AND e.created_at < ?"
+		args = append(args, filter.Until)
+	}
+	sqlQuery += " ORDER BY e.created_at DESC, e.id DESC LIMIT ?"
+	args = append(args, sqlLimit(filter.Limit))
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search events: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var events []*ConversationEvent
+	for rows.Next() {
+		event := &ConversationEvent{}
+		err := rows.Scan(
+			&event.ID, &event.SessionID, &event.ClaudeSessionID,
+			&event.Sequence, &event.EventType, &event.CreatedAt,
+			&event.Role, &event.Content,
+			&event.ToolID, &event.ToolName, &event.ToolInputJSON, &event.ParentToolUseID,
+			&event.ToolResultForID, &event.ToolResultContent,
+			&event.ToolResultBytes, &event.ToolResultTokens,
+			&event.IsCompleted, &event.ApprovalStatus, &event.ApprovalID, &event.Permalink, &event.Language, &event.ToolCacheHit,
+			&event.Truncated,
+			&event.ToolResultJSON, &event.ToolError,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		// Matched content is plaintext, but the other payloads may be compressed
+		if err := s.decryptEvent(event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// ListToolCalls returns the tool call events matching filter, most recent
+// first. Soft-deleted sessions' calls are left out.
+func (s *SQLiteStore) ListToolCalls(ctx context.Context, filter ToolCallFilter) ([]*ToolCallSummary, error) {
+	query := `
+		SELECT e.id, e.session_id, e.claude_session_id, e.sequence, e.event_type, e.created_at,
+			e.role, e.content,
+			e.tool_id, e.tool_name, e.tool_input_json, e.parent_tool_use_id,
+			e.tool_result_for_id, e.tool_result_content,
+			COALESCE(e.tool_result_bytes, 0), COALESCE(e.tool_result_tokens, 0),
+			e.is_completed, e.approval_status, e.approval_id, COALESCE(e.permalink, ''), COALESCE(e.language, ''), COALESCE(e.tool_cache_hit, 0),
+			COALESCE(e.truncated, 0),
+			COALESCE(e.tool_result_json, ''), COALESCE(e.tool_error, ''),
+			s.run_id
+		FROM conversation_events e
+		JOIN sessions s ON s.id = e.session_id
+		WHERE e.event_type = ? AND s.deleted_at IS NULL`
+	args := []interface{}{EventTypeToolCall}
+
+	if filter.ToolName != "" {
+		query += " AND e.tool_name = ?"
+		args = append(args, filter.ToolName)
+	}
+	if filter.RunID != "" {
+		query += " AND s.run_id = ?"
+		args = append(args, filter.RunID)
+	}
+	if !filter.Since.IsZero() {
+		query += " AND e.created_at >= ?"
+		args = append(args, filter.Since)
+	}
+	query += " ORDER BY e.created_at DESC, e.id DESC LIMIT ?"
+	args = append(args, sqlLimit(filter.Limit))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tool calls: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var calls []*ToolCallSummary
+	for rows.Next() {
+		call := &ToolCallSummary{}
+		event := &call.ConversationEvent
+		err := rows.Scan(
+			&event.ID, &event.SessionID, &event.ClaudeSessionID,
+			&event.Sequence, &event.EventType, &event.CreatedAt,
+			&event.Role, &event.Content,
+			&event.ToolID, &event.ToolName, &event.ToolInputJSON, &event.ParentToolUseID,
+			&event.ToolResultForID, &event.ToolResultContent,
+			&event.ToolResultBytes, &event.ToolResultTokens,
+			&event.IsCompleted, &event.ApprovalStatus, &event.ApprovalID, &event.Permalink, &event.Language, &event.ToolCacheHit,
+			&event.Truncated,
+			&event.ToolResultJSON, &event.ToolError,
+			&call.SessionRunID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tool call: %w", err)
+		}
+		if err := s.decryptEvent(event); err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+	return calls, rows.Err()
+}
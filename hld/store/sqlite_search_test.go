@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFTSMatchQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"words", "database migration", `"database" "migration"`},
+		{"phrase", `fix "flaky test" today`, `"fix" "flaky test" "today"`},
+		{"operators are text", "cats OR dogs NOT birds", `"cats" "OR" "dogs" "NOT" "birds"`},
+		{"syntax characters", `content:secret* NEAR(a b) -x ^y`, `"content secret" "NEAR a" "b" "x" "y"`},
+		{"unbalanced quote", `say "hello`, `"say" "hello"`},
+		{"punctuation only", `* " ( -`, ""},
+		{"empty", "   ", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ftsMatchQuery(tt.query))
+		})
+	}
+}
+
+func TestSearchEvents(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	for _, id := range []string{"sess-1", "sess-2"} {
+		require.NoError(t, s.CreateSession(ctx, &Session{
+			ID: id, RunID: "run-" + id, Query: "q", Status: SessionStatusRunning,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+	}
+	add := func(sessionID, eventType, content string) *ConversationEvent {
+		event := &ConversationEvent{
+			SessionID:       sessionID,
+			ClaudeSessionID: "claude-" + sessionID,
+			EventType:       eventType,
+			Role:            "assistant",
+			Content:         content,
+		}
+		require.NoError(t, s.AddConversationEvent(ctx, event))
+		return event
+	}
+	contents := func(events []*ConversationEvent) []string {
+		var out []string
+		for _, e := range events {
+			out = append(out, e.Content)
+		}
+		return out
+	}
+
+	add("sess-1", EventTypeMessage, "The database migration finished cleanly")
+	add("sess-1", EventTypeMessage, "Running the migration against the staging database")
+	add("sess-1", EventTypeThinking, "Maybe the flaky test is a race")
+	add("sess-2", EventTypeMessage, "Cats OR dogs: which pets are allowed?")
+	add("sess-2", EventTypeMessage, "The test is flaky on CI")
+
+	t.Run("every word must match", func(t *testing.T) {
+		events, err := s.SearchEvents(ctx, "database migration", SearchFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"Running the migration against the staging database",
+			"The database migration finished cleanly",
+		}, contents(events), "most recent first")
+	})
+
+	t.Run("quoted phrase", func(t *testing.T) {
+		events, err := s.SearchEvents(ctx, `"flaky test"`, SearchFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Maybe the flaky test is a race"}, contents(events))
+
+		events, err = s.SearchEvents(ctx, "flaky test", SearchFilter{})
+		require.NoError(t, err)
+		assert.Len(t, events, 2)
+	})
+
+	t.Run("special characters are escaped", func(t *testing.T) {
+		for _, query := range []string{`cats OR`, `"cats OR dogs`, `dogs:`, `pets*`, `(cats dogs)`, `-cats`, `cats: ^pets`} {
+			events, err := s.SearchEvents(ctx, query, SearchFilter{})
+			require.NoError(t, err, query)
+			assert.Equal(t, []string{"Cats OR dogs: which pets are allowed?"}, contents(events), query)
+		}
+
+		// As operators these would match; as text the words are missing
+		for _, query := range []string{`NEAR(cats dogs)`, `content:cats`, `cats AND birds`, `pet*`} {
+			events, err := s.SearchEvents(ctx, query, SearchFilter{})
+			require.NoError(t, err, query)
+			assert.Empty(t, events, query)
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		events, err := s.SearchEvents(ctx, "kubernetes", SearchFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, events)
+
+		events, err = s.SearchEvents(ctx, `"* ( )"`, SearchFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		events, err := s.SearchEvents(ctx, "flaky", SearchFilter{SessionID: "sess-2"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"The test is flaky on CI"}, contents(events))
+
+		events, err = s.SearchEvents(ctx, "flaky", SearchFilter{EventTypes: []string{EventTypeThinking}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Maybe the flaky test is a race"}, contents(events))
+
+		events, err = s.SearchEvents(ctx, "the", SearchFilter{Limit: 2})
+		require.NoError(t, err)
+		assert.Len(t, events, 2)
+
+		events, err = s.SearchEvents(ctx, "the", SearchFilter{Since: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		assert.Empty(t, events)
+
+		events, err = s.SearchEvents(ctx, "the", SearchFilter{Until: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		assert.Len(t, events, 4)
+	})
+
+	t.Run("index follows updates and deletes", func(t *testing.T) {
+		_, err := s.db.Exec(`UPDATE conversation_events SET content = 'Rewritten reply' WHERE content LIKE 'Cats%'`)
+		require.NoError(t, err)
+		events, err := s.SearchEvents(ctx, "cats", SearchFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, events)
+		events, err = s.SearchEvents(ctx, "rewritten", SearchFilter{})
+		require.NoError(t, err)
+		assert.Len(t, events, 1)
+
+		require.NoError(t, s.DeleteSessionData(ctx, "sess-2"))
+		events, err = s.SearchEvents(ctx, "rewritten flaky", SearchFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+}
+
+func TestSearchEventsIndexesExistingEvents(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	require.NoError(t, s.CreateSession(ctx, &Session{
+		ID: "sess-1", RunID: "run-1", Query: "q", Status: SessionStatusRunning,
+		CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+	require.NoError(t, s.AddConversationEvent(ctx, &ConversationEvent{
+		SessionID: "sess-1", ClaudeSessionID: "claude-1", EventType: EventTypeMessage, Role: "user", Content: "hello from before the index",
+	}))
+
+	// Recreate the index as migration 40 does on a database that has events
+	_, err = s.db.Exec(`
+		DROP TRIGGER conversation_events_fts_insert;
+		DROP TABLE conversation_events_fts;
+	`)
+	require.NoError(t, err)
+	require.NoError(t, s.createEventSearchIndex())
+
+	events, err := s.SearchEvents(ctx, "before index", SearchFilter{})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestSearchEventsEncryptedStore(t *testing.T) {
+	s, err := NewEncryptedSQLiteStore(":memory:", "passphrase")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	_, err = s.SearchEvents(context.Background(), "anything", SearchFilter{})
+	assert.Error(t, err, "the index only holds ciphertext")
+}
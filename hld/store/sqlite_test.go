@@ -2,11 +2,19 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	claudecode "github.com/humanlayer/humanlayer/claudecode-go"
 	"github.com/humanlayer/humanlayer/hld/internal/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -106,7 +114,7 @@ func TestSQLiteStore(t *testing.T) {
 		}
 
 		// Get conversation
-		retrieved, err := store.GetConversation(ctx, claudeSessionID)
+		retrieved, err := store.GetConversation(ctx, claudeSessionID, ConversationPage{})
 		require.NoError(t, err)
 		require.Len(t, retrieved, 4)
 
@@ -162,7 +170,7 @@ func TestSQLiteStore(t *testing.T) {
 
 	t.Run("CorrelateApproval", func(t *testing.T) {
 		// First, let's verify the tool call exists
-		conv, err := store.GetConversation(ctx, claudeSessionID)
+		conv, err := store.GetConversation(ctx, claudeSessionID, ConversationPage{})
 		require.NoError(t, err)
 
 		// Find the calculate tool call
@@ -181,7 +189,7 @@ func TestSQLiteStore(t *testing.T) {
 		require.NoError(t, err)
 
 		// Check if the correlation worked by looking at the conversation again
-		conv2, err := store.GetConversation(ctx, claudeSessionID)
+		conv2, err := store.GetConversation(ctx, claudeSessionID, ConversationPage{})
 		require.NoError(t, err)
 
 		for _, event := range conv2 {
@@ -192,7 +200,7 @@ func TestSQLiteStore(t *testing.T) {
 		}
 
 		// Check the conversation shows the pending approval
-		conv3, err := store.GetConversation(ctx, claudeSessionID)
+		conv3, err := store.GetConversation(ctx, claudeSessionID, ConversationPage{})
 		require.NoError(t, err)
 
 		var pendingCount int
@@ -226,7 +234,7 @@ func TestSQLiteStore(t *testing.T) {
 		require.Nil(t, pendingTool, "Tool call should no longer be pending after completion")
 
 		// Verify in conversation that it's marked as completed
-		conv, err := store.GetConversation(ctx, claudeSessionID)
+		conv, err := store.GetConversation(ctx, claudeSessionID, ConversationPage{})
 		require.NoError(t, err)
 
 		for _, event := range conv {
@@ -252,7 +260,7 @@ func TestSQLiteStore(t *testing.T) {
 		require.NoError(t, err)
 
 		// List all sessions
-		sessions, err := store.ListSessions(ctx)
+		sessions, err := store.ListSessions(ctx, ListSessionsFilter{})
 		require.NoError(t, err)
 		require.Len(t, sessions, 2)
 	})
@@ -452,7 +460,7 @@ func TestGetSessionConversationWithParentChain(t *testing.T) {
 
 	t.Run("GetSessionConversation_IncludesFullHistory", func(t *testing.T) {
 		// Get conversation for grandchild - should include all parent events
-		events, err := store.GetSessionConversation(ctx, "grandchild-1")
+		events, err := store.GetSessionConversation(ctx, "grandchild-1", ConversationPage{})
 		require.NoError(t, err)
 		require.Len(t, events, 6) // 2 from parent + 2 from child + 2 from grandchild
 
@@ -485,28 +493,28 @@ func TestGetSessionConversationWithParentChain(t *testing.T) {
 		require.NoError(t, err)
 
 		// Should return empty events
-		events, err := store.GetSessionConversation(ctx, "new-session")
+		events, err := store.GetSessionConversation(ctx, "new-session", ConversationPage{})
 		require.NoError(t, err)
 		require.Len(t, events, 0)
 	})
 
 	t.Run("GetSessionConversation_NonExistentSession", func(t *testing.T) {
 		// Should return error for non-existent session
-		_, err := store.GetSessionConversation(ctx, "does-not-exist")
+		_, err := store.GetSessionConversation(ctx, "does-not-exist", ConversationPage{})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "session not found: does-not-exist")
 	})
 
 	t.Run("GetSessionConversation_NoParent", func(t *testing.T) {
 		// Get conversation for parent session (no parents)
-		events, err := store.GetSessionConversation(ctx, "parent-1")
+		events, err := store.GetSessionConversation(ctx, "parent-1", ConversationPage{})
 		require.NoError(t, err)
 		require.Len(t, events, 2) // Only parent's events
 	})
 
 	t.Run("GetSessionConversation_MiddleOfChain", func(t *testing.T) {
 		// Get conversation for child (middle of chain)
-		events, err := store.GetSessionConversation(ctx, "child-1")
+		events, err := store.GetSessionConversation(ctx, "child-1", ConversationPage{})
 		require.NoError(t, err)
 		require.Len(t, events, 4) // Parent's 2 + child's 2
 
@@ -1005,3 +1013,1588 @@ func TestSearchSessionsByTitle(t *testing.T) {
 		require.Equal(t, "title-only-sess", results[2].ID)
 	})
 }
+
+func TestEventPermalinks(t *testing.T) {
+	dbPath := testutil.DatabasePath(t, "sqlite-permalink")
+	store, err := NewSQLiteStore(dbPath)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+
+	err = store.CreateSession(ctx, &Session{
+		ID:              "sess-permalink",
+		RunID:           "run-permalink",
+		ClaudeSessionID: "claude-permalink",
+		Query:           "Test query",
+		Status:          SessionStatusRunning,
+		CreatedAt:       time.Now(),
+		LastActivityAt:  time.Now(),
+	})
+	require.NoError(t, err)
+
+	var events []*ConversationEvent
+	for i := 0; i < 3; i++ {
+		event := &ConversationEvent{
+			SessionID:       "sess-permalink",
+			ClaudeSessionID: "claude-permalink",
+			EventType:       EventTypeMessage,
+			Role:            "assistant",
+			Content:         "message",
+		}
+		if i == 1 {
+			event.Language = "fr"
+		}
+		require.NoError(t, store.AddConversationEvent(ctx, event))
+		require.NotEmpty(t, event.Permalink)
+		events = append(events, event)
+	}
+	require.NotEqual(t, events[0].Permalink, events[1].Permalink)
+
+	t.Run("resolves permalink", func(t *testing.T) {
+		event, err := store.GetEventByPermalink(ctx, events[1].Permalink)
+		require.NoError(t, err)
+		require.Equal(t, events[1].ID, event.ID)
+		require.Equal(t, 2, event.Sequence)
+		require.Equal(t, "sess-permalink", event.SessionID)
+		require.Equal(t, "fr", event.Language)
+	})
+
+	t.Run("conversation includes permalinks", func(t *testing.T) {
+		conversation, err := store.GetSessionConversation(ctx, "sess-permalink", ConversationPage{})
+		require.NoError(t, err)
+		require.Len(t, conversation, 3)
+		for i, event := range conversation {
+			require.Equal(t, events[i].Permalink, event.Permalink)
+			require.Equal(t, events[i].Language, event.Language)
+		}
+	})
+
+	t.Run("permalink survives archival", func(t *testing.T) {
+		archived := true
+		require.NoError(t, store.UpdateSession(ctx, "sess-permalink", SessionUpdate{Archived: &archived}))
+		event, err := store.GetEventByPermalink(ctx, events[0].Permalink)
+		require.NoError(t, err)
+		require.Equal(t, events[0].ID, event.ID)
+	})
+
+	t.Run("unknown permalink", func(t *testing.T) {
+		_, err := store.GetEventByPermalink(ctx, "evt_missing")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestSessionThroughput(t *testing.T) {
+	dbPath := testutil.DatabasePath(t, "sqlite-throughput")
+	store, err := NewSQLiteStore(dbPath)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+
+	err = store.CreateSession(ctx, &Session{
+		ID:             "sess-tps",
+		RunID:          "run-tps",
+		Query:          "Test query",
+		Status:         SessionStatusRunning,
+		CreatedAt:      time.Now(),
+		LastActivityAt: time.Now(),
+	})
+	require.NoError(t, err)
+
+	throughput, err := store.GetSessionThroughput(ctx, "sess-tps")
+	require.NoError(t, err)
+	_, ok := throughput.TokensPerSecond()
+	require.False(t, ok, "no turns recorded yet")
+
+	// Repeated reports for the same message replace the earlier one
+	require.NoError(t, store.RecordTurnUsage(ctx, &TurnUsage{SessionID: "sess-tps", MessageID: "msg_1", OutputTokens: 10, GenerationMS: 500}))
+	require.NoError(t, store.RecordTurnUsage(ctx, &TurnUsage{SessionID: "sess-tps", MessageID: "msg_1", OutputTokens: 100, GenerationMS: 2000}))
+	require.NoError(t, store.RecordTurnUsage(ctx, &TurnUsage{SessionID: "sess-tps", MessageID: "msg_2", OutputTokens: 200, GenerationMS: 4000}))
+
+	throughput, err = store.GetSessionThroughput(ctx, "sess-tps")
+	require.NoError(t, err)
+	require.Equal(t, 2, throughput.Turns)
+	require.Equal(t, 300, throughput.OutputTokens)
+	require.Equal(t, int64(6000), throughput.GenerationMS)
+
+	tps, ok := throughput.TokensPerSecond()
+	require.True(t, ok)
+	require.Equal(t, 50.0, tps)
+}
+
+func TestToolOutputStats(t *testing.T) {
+	dbPath := testutil.DatabasePath(t, "sqlite-tool-output")
+	store, err := NewSQLiteStore(dbPath)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+
+	for _, id := range []string{"sess-a", "sess-b"} {
+		err = store.CreateSession(ctx, &Session{
+			ID:              id,
+			RunID:           "run-" + id,
+			ClaudeSessionID: "claude-" + id,
+			Query:           "Test query",
+			Status:          SessionStatusRunning,
+			CreatedAt:       time.Now(),
+			LastActivityAt:  time.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	addResult := func(sessionID, toolID, toolName, content string) {
+		t.Helper()
+		require.NoError(t, store.AddConversationEvent(ctx, &ConversationEvent{
+			SessionID: sessionID, ClaudeSessionID: "claude-" + sessionID,
+			EventType: EventTypeToolCall, ToolID: toolID, ToolName: toolName,
+		}))
+		result := &ConversationEvent{
+			SessionID: sessionID, ClaudeSessionID: "claude-" + sessionID,
+			EventType: EventTypeToolResult, ToolResultForID: toolID, ToolResultContent: content,
+		}
+		require.NoError(t, store.AddConversationEvent(ctx, result))
+		require.Equal(t, len(content), result.ToolResultBytes)
+		require.Equal(t, EstimateTokens(content), result.ToolResultTokens)
+	}
+
+	addResult("sess-a", "t1", "Bash", strings.Repeat("x", 4000))
+	addResult("sess-a", "t2", "Bash", strings.Repeat("x", 400))
+	addResult("sess-a", "t3", "Read", strings.Repeat("x", 800))
+	addResult("sess-b", "t4", "Read", strings.Repeat("x", 8000))
+
+	// Sizes round-trip through reads
+	events, err := store.GetConversation(ctx, "claude-sess-a", ConversationPage{})
+	require.NoError(t, err)
+	require.Equal(t, 4000, events[1].ToolResultBytes)
+	require.Equal(t, 1000, events[1].ToolResultTokens)
+
+	stats, err := store.GetToolOutputStats(ctx, "sess-a")
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	require.Equal(t, "Bash", stats[0].ToolName)
+	require.Equal(t, 2, stats[0].Results)
+	require.Equal(t, int64(4400), stats[0].Bytes)
+	require.Equal(t, int64(1100), stats[0].Tokens)
+	require.Equal(t, 1000, stats[0].MaxTokens)
+	require.Equal(t, "Read", stats[1].ToolName)
+
+	// Across all sessions Read now dominates
+	stats, err = store.GetToolOutputStats(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, "Read", stats[0].ToolName)
+	require.Equal(t, int64(2200), stats[0].Tokens)
+}
+
+func TestConversationEventDigest(t *testing.T) {
+	event := &ConversationEvent{
+		ID:            7,
+		SessionID:     "sess-1",
+		Sequence:      3,
+		EventType:     EventTypeToolCall,
+		CreatedAt:     time.Now(),
+		ToolID:        "toolu_1",
+		ToolName:      "Bash",
+		ToolInputJSON: `{"command":"ls"}`,
+	}
+	digest := event.Digest()
+	// Pinned so a change to the hashed fields or encoding, which would
+	// invalidate every client cache, is deliberate
+	require.Equal(t, "4622ba09b4442ed8059704c42502bcc2b750428325edae7db5811f86807f1a53", digest)
+
+	// Storage and tracking fields don't affect it
+	moved := *event
+	moved.ID, moved.SessionID, moved.Sequence = 99, "sess-2", 1
+	moved.CreatedAt = time.Now().Add(time.Hour)
+	moved.IsCompleted, moved.ApprovalStatus, moved.Permalink = true, "approved", "p1"
+	require.Equal(t, digest, moved.Digest())
+
+	// Content changes do, including moving text between fields
+	edited := *event
+	edited.ToolInputJSON = `{"command":"ls -la"}`
+	require.NotEqual(t, digest, edited.Digest())
+	a := &ConversationEvent{EventType: EventTypeMessage, Role: "user", Content: "ab"}
+	b := &ConversationEvent{EventType: EventTypeMessage, Role: "userab"}
+	require.NotEqual(t, a.Digest(), b.Digest())
+}
+
+func TestConversationPages(t *testing.T) {
+	dbPath := testutil.DatabasePath(t, "sqlite-pages")
+	store, err := NewSQLiteStore(dbPath)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+
+	// A parent and a resumed child, each with its own Claude session and so
+	// its own sequence numbers starting at 1
+	for _, sess := range []*Session{
+		{ID: "parent-pg", RunID: "run-parent-pg", ClaudeSessionID: "claude-parent-pg"},
+		{ID: "child-pg", RunID: "run-child-pg", ClaudeSessionID: "claude-child-pg", ParentSessionID: "parent-pg"},
+	} {
+		sess.Query, sess.Status = "Test query", SessionStatusCompleted
+		sess.CreatedAt, sess.LastActivityAt = time.Now(), time.Now()
+		require.NoError(t, store.CreateSession(ctx, sess))
+		for i := 1; i <= 4; i++ {
+			require.NoError(t, store.AddConversationEvent(ctx, &ConversationEvent{
+				SessionID:       sess.ID,
+				ClaudeSessionID: sess.ClaudeSessionID,
+				EventType:       EventTypeMessage,
+				Role:            "assistant",
+				Content:         fmt.Sprintf("%s %d", sess.ID, i),
+			}))
+		}
+	}
+
+	contents := func(events []*ConversationEvent) []string {
+		out := make([]string, len(events))
+		for i, event := range events {
+			out[i] = event.Content
+		}
+		return out
+	}
+
+	t.Run("claude session pages by sequence", func(t *testing.T) {
+		events, err := store.GetConversation(ctx, "claude-child-pg", ConversationPage{Limit: 3})
+		require.NoError(t, err)
+		require.Equal(t, []string{"child-pg 1", "child-pg 2", "child-pg 3"}, contents(events))
+
+		events, err = store.GetConversation(ctx, "claude-child-pg", ConversationPage{AfterSequence: 3, Limit: 3})
+		require.NoError(t, err)
+		require.Equal(t, []string{"child-pg 4"}, contents(events))
+
+		events, err = store.GetConversation(ctx, "claude-child-pg", ConversationPage{AfterSequence: 4})
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		count, err := store.CountConversation(ctx, "claude-child-pg")
+		require.NoError(t, err)
+		require.Equal(t, 4, count)
+	})
+
+	t.Run("session history pages by position across the chain", func(t *testing.T) {
+		all, err := store.GetSessionConversation(ctx, "child-pg", ConversationPage{})
+		require.NoError(t, err)
+		require.Len(t, all, 8)
+
+		events, err := store.GetSessionConversation(ctx, "child-pg", ConversationPage{AfterSequence: 3, Limit: 3})
+		require.NoError(t, err)
+		require.Equal(t, []string{"parent-pg 4", "child-pg 1", "child-pg 2"}, contents(events))
+
+		events, err = store.GetSessionConversation(ctx, "child-pg", ConversationPage{AfterSequence: 6})
+		require.NoError(t, err)
+		require.Equal(t, []string{"child-pg 3", "child-pg 4"}, contents(events))
+
+		count, err := store.CountSessionConversation(ctx, "child-pg")
+		require.NoError(t, err)
+		require.Equal(t, 8, count)
+		_, err = store.CountSessionConversation(ctx, "missing-pg")
+		var notFound *NotFoundError
+		require.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("range includes both bounds of the session's own events", func(t *testing.T) {
+		events, err := store.GetConversationRange(ctx, "child-pg", 2, 3)
+		require.NoError(t, err)
+		require.Equal(t, []string{"child-pg 2", "child-pg 3"}, contents(events))
+
+		events, err = store.GetConversationRange(ctx, "child-pg", 4, 4)
+		require.NoError(t, err)
+		require.Equal(t, []string{"child-pg 4"}, contents(events))
+
+		events, err = store.GetConversationRange(ctx, "child-pg", 0, 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"child-pg 1", "child-pg 2", "child-pg 3", "child-pg 4"}, contents(events))
+	})
+
+	t.Run("range outside the conversation is empty", func(t *testing.T) {
+		events, err := store.GetConversationRange(ctx, "child-pg", 5, 10)
+		require.NoError(t, err)
+		require.NotNil(t, events)
+		require.Empty(t, events)
+
+		events, err = store.GetConversationRange(ctx, "missing-pg", 1, 4)
+		require.NoError(t, err)
+		require.Empty(t, events)
+	})
+
+	t.Run("reversed range is an error", func(t *testing.T) {
+		_, err := store.GetConversationRange(ctx, "child-pg", 3, 2)
+		require.Error(t, err)
+	})
+}
+
+func TestAggregateCost(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	floatPtr := func(v float64) *float64 { return &v }
+	intPtr := func(v int) *int { return &v }
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, sess := range []struct {
+		id, runID, model string
+		createdAt        time.Time
+		update           SessionUpdate
+	}{
+		{"a", "run-1", "sonnet", time.Now(), SessionUpdate{CostUSD: floatPtr(1.5), InputTokens: intPtr(100), CacheReadInputTokens: intPtr(50), OutputTokens: intPtr(20)}},
+		{"b", "run-2", "opus", time.Now(), SessionUpdate{CostUSD: floatPtr(4), InputTokens: intPtr(300), OutputTokens: intPtr(80)}},
+		{"c", "run-3", "sonnet", old, SessionUpdate{CostUSD: floatPtr(0.5), InputTokens: intPtr(10), OutputTokens: intPtr(5)}},
+		// Still running, nothing reported yet
+		{"d", "run-4", "", time.Now(), SessionUpdate{}},
+	} {
+		require.NoError(t, s.CreateSession(ctx, &Session{
+			ID: sess.id, RunID: sess.runID, Model: sess.model, Query: "q",
+			Status: SessionStatusCompleted, CreatedAt: sess.createdAt, LastActivityAt: sess.createdAt,
+		}))
+		require.NoError(t, s.UpdateSession(ctx, sess.id, sess.update))
+	}
+
+	t.Run("by run", func(t *testing.T) {
+		summary, err := s.AggregateCostByRun(ctx, "run-1")
+		require.NoError(t, err)
+		assert.Equal(t, RunCostSummary{
+			RunID: "run-1", SessionCount: 1, CostUSD: 1.5, PromptTokens: 150, CompletionTokens: 20,
+			Models: []ModelCostSummary{{Model: "sonnet", SessionCount: 1, CostUSD: 1.5, PromptTokens: 150, CompletionTokens: 20}},
+		}, summary)
+
+		_, err = s.AggregateCostByRun(ctx, "run-missing")
+		var notFound *NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("by model", func(t *testing.T) {
+		models, err := s.AggregateCostByModel(ctx, time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, []ModelCostSummary{
+			{Model: "opus", SessionCount: 1, CostUSD: 4, PromptTokens: 300, CompletionTokens: 80},
+			{Model: "sonnet", SessionCount: 2, CostUSD: 2, PromptTokens: 160, CompletionTokens: 25},
+			{Model: "", SessionCount: 1},
+		}, models)
+
+		recent, err := s.AggregateCostByModel(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		require.Len(t, recent, 3)
+		assert.Equal(t, ModelCostSummary{Model: "sonnet", SessionCount: 1, CostUSD: 1.5, PromptTokens: 150, CompletionTokens: 20}, recent[1])
+	})
+}
+
+func TestSessionTags(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	for _, id := range []string{"sess-1", "sess-2", "sess-3"} {
+		require.NoError(t, s.CreateSession(ctx, &Session{
+			ID: id, RunID: "run-" + id, Query: "q", Status: SessionStatusCompleted,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+	}
+
+	require.NoError(t, s.SetSessionTags(ctx, "sess-1", map[string]string{"env": "prod", "project": "billing", "user": "sam"}))
+	require.NoError(t, s.SetSessionTags(ctx, "sess-2", map[string]string{"env": "prod", "project": "search"}))
+	require.NoError(t, s.SetSessionTags(ctx, "sess-3", map[string]string{"env": "staging", "project": "billing"}))
+
+	t.Run("upserts and removes", func(t *testing.T) {
+		require.NoError(t, s.SetSessionTags(ctx, "sess-1", map[string]string{"user": "", "team": "payments"}))
+		tags, err := s.GetSessionTags(ctx, "sess-1")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"env": "prod", "project": "billing", "team": "payments"}, tags)
+
+		tags, err = s.GetSessionTags(ctx, "sess-unknown")
+		require.NoError(t, err)
+		assert.Empty(t, tags)
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		err := s.SetSessionTags(ctx, "sess-unknown", map[string]string{"env": "prod"})
+		var notFound *NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("filters list by all tags", func(t *testing.T) {
+		ids := func(filter ListSessionsFilter) []string {
+			sessions, err := s.ListSessions(ctx, filter)
+			require.NoError(t, err)
+			var ids []string
+			for _, sess := range sessions {
+				ids = append(ids, sess.ID)
+			}
+			sort.Strings(ids)
+			return ids
+		}
+		assert.Equal(t, []string{"sess-1", "sess-2"}, ids(ListSessionsFilter{TagFilters: map[string]string{"env": "prod"}}))
+		assert.Equal(t, []string{"sess-1"}, ids(ListSessionsFilter{TagFilters: map[string]string{"env": "prod", "project": "billing"}}))
+		assert.Empty(t, ids(ListSessionsFilter{TagFilters: map[string]string{"env": "dev"}}))
+	})
+
+	t.Run("deleted with the session", func(t *testing.T) {
+		require.NoError(t, s.DeleteSessionData(ctx, "sess-2"))
+		var count int
+		require.NoError(t, s.db.QueryRow("SELECT COUNT(*) FROM session_tags WHERE session_id = 'sess-2'").Scan(&count))
+		assert.Zero(t, count)
+	})
+}
+
+func TestListSessionsByIDs(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	for _, id := range []string{"sess-1", "sess-2", "sess-3"} {
+		require.NoError(t, s.CreateSession(ctx, &Session{
+			ID: id, RunID: "run-" + id, Query: "q", Status: SessionStatusCompleted,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+	}
+
+	sessions, err := s.ListSessions(ctx, ListSessionsFilter{IDs: []string{"sess-3", "sess-1", "sess-missing"}})
+	require.NoError(t, err)
+	var ids []string
+	for _, sess := range sessions {
+		ids = append(ids, sess.ID)
+	}
+	sort.Strings(ids)
+	assert.Equal(t, []string{"sess-1", "sess-3"}, ids)
+}
+
+func TestGetSessionMetrics(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	for _, id := range []string{"sess-1", "sess-idle"} {
+		require.NoError(t, s.CreateSession(ctx, &Session{
+			ID: id, RunID: "run-" + id, Query: "q", Status: SessionStatusRunning,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+	}
+	cost := 0.42
+	require.NoError(t, s.UpdateSession(ctx, "sess-1", SessionUpdate{CostUSD: &cost}))
+
+	start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.Local)
+	for _, e := range []struct {
+		event  ConversationEvent
+		offset time.Duration
+	}{
+		{ConversationEvent{EventType: EventTypeMessage, Role: "user", Content: "hi"}, 0},
+		{ConversationEvent{EventType: EventTypeToolCall, ToolID: "t1", ToolName: "Bash"}, time.Second},
+		{ConversationEvent{EventType: EventTypeToolResult, ToolResultForID: "t1", ToolResultContent: "ok"}, 3 * time.Second},
+		{ConversationEvent{EventType: EventTypeToolCall, ToolID: "t2", ToolName: "Bash"}, 4 * time.Second},
+		{ConversationEvent{EventType: EventTypeToolResult, ToolResultForID: "t2", ToolResultContent: "ok"}, 10 * time.Second},
+		{ConversationEvent{EventType: EventTypeToolCall, ToolID: "t3", ToolName: "Read"}, 11 * time.Second},
+		{ConversationEvent{EventType: EventTypeMessage, Role: "assistant", Content: "done"}, 12 * time.Second},
+	} {
+		event := e.event
+		event.SessionID = "sess-1"
+		event.ClaudeSessionID = "claude-1"
+		require.NoError(t, s.AddConversationEvent(ctx, &event))
+		_, err := s.db.Exec(`UPDATE conversation_events SET created_at = ? WHERE id = ?`, start.Add(e.offset), event.ID)
+		require.NoError(t, err)
+	}
+
+	metrics, err := s.GetSessionMetrics(ctx, "sess-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, metrics.Messages)
+	assert.Equal(t, 3, metrics.ToolCalls)
+	assert.Equal(t, map[string]int{"Bash": 2, "Read": 1}, metrics.ToolCallsByName)
+	assert.Equal(t, 2, metrics.TimedToolCalls, "the unanswered Read call isn't timed")
+	assert.InDelta(t, 4000, metrics.AvgToolLatencyMS, 1)
+	assert.Equal(t, 0.42, metrics.CostUSD)
+
+	metrics, err = s.GetSessionMetrics(ctx, "sess-idle")
+	require.NoError(t, err)
+	assert.Equal(t, &SessionMetrics{ToolCallsByName: map[string]int{}}, metrics)
+
+	_, err = s.GetSessionMetrics(ctx, "missing")
+	var notFound *NotFoundError
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestCountEventsByType(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	for _, id := range []string{"sess-1", "sess-2"} {
+		require.NoError(t, s.CreateSession(ctx, &Session{
+			ID: id, RunID: "run-" + id, Query: "q", Status: SessionStatusRunning,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+	}
+	for _, e := range []ConversationEvent{
+		{SessionID: "sess-1", EventType: EventTypeMessage, Role: "user", Content: "hi"},
+		{SessionID: "sess-1", EventType: EventTypeToolCall, ToolID: "t1", ToolName: "Bash"},
+		{SessionID: "sess-1", EventType: EventTypeToolResult, ToolResultForID: "t1", ToolResultContent: "ok"},
+		{SessionID: "sess-1", EventType: EventTypeToolCall, ToolID: "t2", ToolName: "Read"},
+		{SessionID: "sess-1", EventType: EventTypeMessage, Role: "assistant", Content: "done"},
+		{SessionID: "sess-1", EventType: EventTypeMessage, Role: "user", Content: "thanks"},
+		{SessionID: "sess-2", EventType: EventTypeMessage, Role: "user", Content: "other"},
+	} {
+		event := e
+		event.ClaudeSessionID = "claude-" + e.SessionID
+		require.NoError(t, s.AddConversationEvent(ctx, &event))
+	}
+
+	counts, err := s.CountEventsByType(ctx, "sess-1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{EventTypeMessage: 3, EventTypeToolCall: 2, EventTypeToolResult: 1}, counts,
+		"types the session has no events of are left out")
+
+	counts, err = s.CountEventsByType(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+}
+
+func TestGetExpiredSessionIDs(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, sess := range []struct {
+		id, status, parent string
+		lastActivity       time.Time
+	}{
+		{id: "old-failed", status: SessionStatusFailed, lastActivity: old.Add(-time.Hour)},
+		{id: "old-completed", status: SessionStatusCompleted, lastActivity: old},
+		{id: "old-cancelled", status: SessionStatusCancelled, lastActivity: old.Add(time.Minute)},
+		{id: "soft-deleted", status: SessionStatusInterrupted, lastActivity: old.Add(2 * time.Minute)},
+		{id: "recent", status: SessionStatusCompleted, lastActivity: time.Now()},
+		{id: "running", status: SessionStatusRunning, lastActivity: old},
+		{id: "pending-approval", status: SessionStatusCompleted, lastActivity: old},
+		{id: "parent", status: SessionStatusCompleted, lastActivity: old},
+		{id: "child", status: SessionStatusCompleted, parent: "parent", lastActivity: time.Now()},
+	} {
+		require.NoError(t, s.CreateSession(ctx, &Session{
+			ID: sess.id, RunID: "run-" + sess.id, Query: "q", Status: sess.status, ParentSessionID: sess.parent,
+			CreatedAt: sess.lastActivity, LastActivityAt: sess.lastActivity,
+		}))
+	}
+	require.NoError(t, s.CreateApproval(ctx, &Approval{
+		ID: "appr", RunID: "run-pending-approval", SessionID: "pending-approval", Status: ApprovalStatusLocalPending,
+		CreatedAt: time.Now(), ToolName: "Bash", ToolInput: json.RawMessage(`{}`),
+	}))
+	require.NoError(t, s.SoftDeleteSession(ctx, "soft-deleted"))
+
+	ids, err := s.GetExpiredSessionIDs(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old-failed", "old-completed", "old-cancelled", "soft-deleted"}, ids, "oldest first")
+}
+
+func TestIncrementalVacuum(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	s, err := NewSQLiteStore(dbPath)
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	autoVacuum := func() int {
+		var mode int
+		require.NoError(t, s.db.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&mode))
+		return mode
+	}
+	assert.Equal(t, 2, autoVacuum(), "new databases use incremental auto vacuum")
+
+	require.NoError(t, s.CreateSession(ctx, &Session{
+		ID: "sess", RunID: "run-sess", Query: "q", Status: SessionStatusCompleted,
+		CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+	for range 200 {
+		require.NoError(t, s.AddConversationEvent(ctx, &ConversationEvent{
+			SessionID: "sess", ClaudeSessionID: "claude-sess", EventType: EventTypeMessage,
+			Role: "assistant", Content: strings.Repeat("x", 4096),
+		}))
+	}
+	require.NoError(t, s.DeleteSessionData(ctx, "sess"))
+
+	freePages := func() int {
+		var n int
+		require.NoError(t, s.db.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&n))
+		return n
+	}
+	require.Positive(t, freePages())
+	require.NoError(t, s.IncrementalVacuum(ctx))
+	assert.Zero(t, freePages())
+}
+
+func TestAnnotations(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	eventIDs := map[string]int64{}
+	for _, id := range []string{"sess-1", "sess-2"} {
+		require.NoError(t, s.CreateSession(ctx, &Session{
+			ID: id, RunID: "run-" + id, ClaudeSessionID: "claude-" + id, Query: "q", Status: SessionStatusCompleted,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+		event := &ConversationEvent{
+			SessionID: id, ClaudeSessionID: "claude-" + id,
+			EventType: EventTypeMessage, Role: "assistant", Content: "hello",
+		}
+		require.NoError(t, s.AddConversationEvent(ctx, event))
+		eventIDs[id] = event.ID
+	}
+
+	first, err := s.AddAnnotation(ctx, eventIDs["sess-1"], "sam", "Looks wrong")
+	require.NoError(t, err)
+	assert.NotZero(t, first.ID)
+	assert.Equal(t, eventIDs["sess-1"], first.EventID)
+	assert.False(t, first.CreatedAt.IsZero())
+	_, err = s.AddAnnotation(ctx, eventIDs["sess-1"], "alex", "Agreed")
+	require.NoError(t, err)
+	_, err = s.AddAnnotation(ctx, eventIDs["sess-2"], "sam", "Fine")
+	require.NoError(t, err)
+
+	annotations, err := s.GetAnnotationsForSession(ctx, "sess-1")
+	require.NoError(t, err)
+	require.Len(t, annotations, 2)
+	assert.Equal(t, []string{"Looks wrong", "Agreed"}, []string{annotations[0].Note, annotations[1].Note})
+	assert.Equal(t, "alex", annotations[1].Author)
+
+	t.Run("unknown event", func(t *testing.T) {
+		_, err := s.AddAnnotation(ctx, 9999, "sam", "Missing")
+		var notFound *NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("deleted with the session", func(t *testing.T) {
+		require.NoError(t, s.DeleteSessionData(ctx, "sess-1"))
+		annotations, err := s.GetAnnotationsForSession(ctx, "sess-1")
+		require.NoError(t, err)
+		assert.Empty(t, annotations)
+
+		var remaining int
+		require.NoError(t, s.db.QueryRow(`SELECT COUNT(*) FROM annotations`).Scan(&remaining))
+		assert.Equal(t, 1, remaining)
+	})
+}
+
+func TestSessionBudget(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(testutil.DatabasePath(t, "sqlite-budget"))
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	budget := 1.0
+	require.NoError(t, s.CreateSession(ctx, &Session{
+		ID: "sess-1", RunID: "run-sess-1", ClaudeSessionID: "claude-1", Query: "q", Status: SessionStatusRunning,
+		CreatedAt: time.Now(), LastActivityAt: time.Now(), MaxCostUSD: &budget,
+	}))
+	sess, err := s.GetSession(ctx, "sess-1")
+	require.NoError(t, err)
+	require.NotNil(t, sess.MaxCostUSD)
+	assert.Equal(t, budget, *sess.MaxCostUSD)
+
+	// Each turn's cost is recorded before its next event arrives
+	add := func(cost float64) error {
+		require.NoError(t, s.UpdateSession(ctx, "sess-1", SessionUpdate{CostUSD: &cost}))
+		return s.AddConversationEvent(ctx, &ConversationEvent{
+			SessionID: "sess-1", ClaudeSessionID: "claude-1",
+			EventType: EventTypeMessage, Role: "assistant", Content: fmt.Sprintf("cost %.2f", cost),
+		})
+	}
+	require.NoError(t, add(0.4))
+	require.NoError(t, add(0.9))
+	require.NoError(t, add(1.0), "reaching the cap exactly is within budget")
+	err = add(1.2)
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.ErrorIs(t, add(1.3), ErrBudgetExceeded)
+
+	events, err := s.GetSessionConversation(ctx, "sess-1", ConversationPage{})
+	require.NoError(t, err)
+	require.Len(t, events, 3, "refused events aren't stored")
+	assert.Equal(t, "cost 1.00", events[2].Content)
+
+	t.Run("concurrent appends over budget all fail", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make([]error, 10)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = s.AddConversationEvent(ctx, &ConversationEvent{
+					SessionID: "sess-1", ClaudeSessionID: "claude-1",
+					EventType: EventTypeMessage, Role: "assistant", Content: "late",
+				})
+			}(i)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			assert.ErrorIs(t, err, ErrBudgetExceeded)
+		}
+	})
+
+	t.Run("raising or removing the cap allows events again", func(t *testing.T) {
+		raised := 2.0
+		raisedPtr := &raised
+		require.NoError(t, s.UpdateSession(ctx, "sess-1", SessionUpdate{MaxCostUSD: &raisedPtr}))
+		require.NoError(t, add(1.5))
+		assert.ErrorIs(t, add(2.5), ErrBudgetExceeded)
+
+		var none *float64
+		require.NoError(t, s.UpdateSession(ctx, "sess-1", SessionUpdate{MaxCostUSD: &none}))
+		require.NoError(t, add(10))
+		sess, err := s.GetSession(ctx, "sess-1")
+		require.NoError(t, err)
+		assert.Nil(t, sess.MaxCostUSD)
+	})
+}
+
+func TestForkSession(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	require.NoError(t, s.CreateSession(ctx, &Session{
+		ID: "source", RunID: "run-source", ClaudeSessionID: "claude-source", Query: "q", Status: SessionStatusCompleted,
+		CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, s.AddConversationEvent(ctx, &ConversationEvent{
+			SessionID: "source", ClaudeSessionID: "claude-source",
+			EventType: EventTypeMessage, Role: "assistant", Content: fmt.Sprintf("event %d", i),
+		}))
+	}
+	source, err := s.GetConversationRange(ctx, "source", 0, 100)
+	require.NoError(t, err)
+
+	fork := func(id string, atSequence int) (int, error) {
+		return s.ForkSession(ctx, "source", atSequence, &Session{
+			ID: id, RunID: "run-" + id, ClaudeSessionID: "claude-" + id, Query: "q", Status: SessionStatusRunning,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		})
+	}
+
+	copied, err := fork("fork-3", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 3, copied)
+	events, err := s.GetSessionConversation(ctx, "fork-3", ConversationPage{})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	for i, event := range events {
+		assert.Equal(t, source[i].Sequence, event.Sequence)
+		assert.Equal(t, source[i].Content, event.Content)
+		assert.Equal(t, "claude-fork-3", event.ClaudeSessionID)
+		assert.NotEqual(t, source[i].Permalink, event.Permalink)
+		assert.NotEmpty(t, event.Permalink)
+	}
+
+	// New events continue the fork's sequence
+	require.NoError(t, s.AddConversationEvent(ctx, &ConversationEvent{
+		SessionID: "fork-3", ClaudeSessionID: "claude-fork-3",
+		EventType: EventTypeMessage, Role: "user", Content: "alternative",
+	}))
+	events, err = s.GetConversationRange(ctx, "fork-3", 4, 4)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "alternative", events[0].Content)
+
+	after, err := s.GetConversationRange(ctx, "source", 0, 100)
+	require.NoError(t, err)
+	assert.Equal(t, source, after, "the source is unchanged")
+
+	copied, err = fork("fork-0", 0)
+	require.NoError(t, err)
+	assert.Zero(t, copied)
+	events, err = s.GetSessionConversation(ctx, "fork-0", ConversationPage{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	t.Run("unknown source creates nothing", func(t *testing.T) {
+		_, err := s.ForkSession(ctx, "missing", 3, &Session{
+			ID: "fork-missing", RunID: "run-fork-missing", Query: "q", Status: SessionStatusRunning,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		})
+		var notFound *NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		_, err = s.GetSession(ctx, "fork-missing")
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
+
+func TestWebhooks(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	base := time.Now().Add(-time.Hour)
+	require.NoError(t, s.CreateWebhook(ctx, &Webhook{
+		ID: "wh-2", URL: "https://example.com/b", Secret: "b", Events: []string{"session.failed"}, CreatedAt: base.Add(time.Minute),
+	}))
+	require.NoError(t, s.CreateWebhook(ctx, &Webhook{
+		ID: "wh-1", URL: "https://example.com/a", Secret: "a", Events: []string{"session.completed", "session.failed"}, CreatedAt: base,
+	}))
+
+	webhooks, err := s.ListWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+	assert.Equal(t, "wh-1", webhooks[0].ID, "oldest first")
+	assert.Equal(t, "https://example.com/a", webhooks[0].URL)
+	assert.Equal(t, "a", webhooks[0].Secret)
+	assert.Equal(t, []string{"session.completed", "session.failed"}, webhooks[0].Events)
+	assert.Equal(t, "wh-2", webhooks[1].ID)
+
+	require.NoError(t, s.DeleteWebhook(ctx, "wh-1"))
+	webhooks, err = s.ListWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, "wh-2", webhooks[0].ID)
+
+	var notFound *NotFoundError
+	assert.ErrorAs(t, s.DeleteWebhook(ctx, "wh-1"), &notFound)
+}
+
+func TestPing(t *testing.T) {
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, s.Ping(context.Background()))
+
+	require.NoError(t, s.Close())
+	assert.Error(t, s.Ping(context.Background()))
+}
+
+func TestVacuumOldEvents(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cutoff := now.Add(-24 * time.Hour)
+
+	// seed creates the sessions and returns the store with 200 old and 10
+	// recent events in terminal sessions
+	seed := func(t *testing.T) *SQLiteStore {
+		s, err := NewSQLiteStore(":memory:")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = s.Close() })
+
+		for _, sess := range []struct {
+			id, status, parent string
+			lastActivity       time.Time
+			events             int
+		}{
+			{"old", SessionStatusCompleted, "", now.Add(-48 * time.Hour), 200},
+			{"recent", SessionStatusCompleted, "", now.Add(-time.Hour), 10},
+			{"old-running", SessionStatusRunning, "", now.Add(-48 * time.Hour), 3},
+			{"old-parent", SessionStatusCompleted, "", now.Add(-72 * time.Hour), 2},
+			{"child", SessionStatusCompleted, "old-parent", now.Add(-time.Hour), 1},
+		} {
+			require.NoError(t, s.CreateSession(ctx, &Session{
+				ID: sess.id, RunID: "run-" + sess.id, ClaudeSessionID: "claude-" + sess.id, ParentSessionID: sess.parent,
+				Query: "q", Status: sess.status, CreatedAt: sess.lastActivity, LastActivityAt: sess.lastActivity,
+			}))
+			for i := 0; i < sess.events; i++ {
+				require.NoError(t, s.AddConversationEvent(ctx, &ConversationEvent{
+					SessionID: sess.id, ClaudeSessionID: "claude-" + sess.id,
+					EventType: EventTypeMessage, Role: "assistant", Content: fmt.Sprintf("event %d", i),
+				}))
+			}
+			lastActivity := sess.lastActivity
+			require.NoError(t, s.UpdateSession(ctx, sess.id, SessionUpdate{LastActivityAt: &lastActivity}))
+		}
+
+		events, err := s.GetConversationRange(ctx, "old", 1, 1)
+		require.NoError(t, err)
+		_, err = s.AddAnnotation(ctx, events[0].ID, "sam", "gone soon")
+		require.NoError(t, err)
+		return s
+	}
+
+	countEvents := func(t *testing.T, s *SQLiteStore, sessionID string) int {
+		var n int
+		require.NoError(t, s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM conversation_events WHERE session_id = ?", sessionID).Scan(&n))
+		return n
+	}
+
+	t.Run("keep sessions", func(t *testing.T) {
+		s := seed(t)
+		deletedEvents, deletedSessions, err := s.VacuumOldEvents(ctx, cutoff, true)
+		require.NoError(t, err)
+		assert.Equal(t, int64(200), deletedEvents)
+		assert.Equal(t, int64(0), deletedSessions)
+
+		assert.Equal(t, 0, countEvents(t, s, "old"))
+		assert.Equal(t, 10, countEvents(t, s, "recent"))
+		assert.Equal(t, 3, countEvents(t, s, "old-running"), "live sessions are never vacuumed")
+		assert.Equal(t, 2, countEvents(t, s, "old-parent"), "parents keep their children's history")
+
+		_, err = s.GetSession(ctx, "old")
+		assert.NoError(t, err)
+		annotations, err := s.GetAnnotationsForSession(ctx, "old")
+		require.NoError(t, err)
+		assert.Empty(t, annotations)
+
+		// Nothing is left to vacuum
+		deletedEvents, _, err = s.VacuumOldEvents(ctx, cutoff, true)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), deletedEvents)
+	})
+
+	t.Run("delete sessions", func(t *testing.T) {
+		s := seed(t)
+		deletedEvents, deletedSessions, err := s.VacuumOldEvents(ctx, cutoff, false)
+		require.NoError(t, err)
+		assert.Equal(t, int64(200), deletedEvents)
+		assert.Equal(t, int64(1), deletedSessions)
+
+		var notFound *NotFoundError
+		_, err = s.GetSession(ctx, "old")
+		assert.ErrorAs(t, err, &notFound)
+		for _, id := range []string{"recent", "old-running", "old-parent", "child"} {
+			_, err := s.GetSession(ctx, id)
+			assert.NoError(t, err, id)
+		}
+		assert.Equal(t, 10, countEvents(t, s, "recent"))
+	})
+}
+
+func TestGetSessionCostStats(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	stats, err := s.GetSessionCostStats(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, SessionCostStats{}, stats)
+
+	one, two := 1.0, 2.0
+	for id, cost := range map[string]*float64{"a": &one, "b": &two, "c": nil} {
+		require.NoError(t, s.CreateSession(ctx, &Session{
+			ID: id, RunID: "run-" + id, Query: "q", Status: SessionStatusCompleted,
+			CreatedAt: time.Now(), LastActivityAt: time.Now(),
+		}))
+		if cost != nil {
+			require.NoError(t, s.UpdateSession(ctx, id, SessionUpdate{CostUSD: cost}))
+		}
+	}
+
+	stats, err = s.GetSessionCostStats(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, SessionCostStats{Count: 2, MeanCostUSD: 1.5}, stats, "sessions without a cost are left out")
+
+	stats, err = s.GetSessionCostStats(ctx, "run-b")
+	require.NoError(t, err)
+	assert.Equal(t, SessionCostStats{Count: 1, MeanCostUSD: 2}, stats)
+}
+
+func TestAppendConversationEvents(t *testing.T) {
+	ctx := context.Background()
+	newEvents := func(sessionID string, n int) []*ConversationEvent {
+		events := make([]*ConversationEvent, n)
+		for i := range events {
+			events[i] = &ConversationEvent{
+				SessionID: sessionID, ClaudeSessionID: "claude-" + sessionID,
+				EventType: EventTypeToolCall, ToolID: fmt.Sprintf("tool-%d", i), ToolName: "Read",
+				ToolInputJSON: `{"file":"a.go"}`,
+			}
+		}
+		return events
+	}
+
+	t.Run("continues the sequence", func(t *testing.T) {
+		s, err := NewSQLiteStore(":memory:")
+		require.NoError(t, err)
+		defer func() { _ = s.Close() }()
+		createEncryptionTestSession(t, s, "sess-1")
+
+		require.NoError(t, s.AddConversationEvent(ctx, &ConversationEvent{
+			SessionID: "sess-1", ClaudeSessionID: "claude-sess-1",
+			EventType: EventTypeMessage, Role: "user", Content: "go",
+		}))
+		// More than one INSERT's worth, to cover the chunking
+		events := newEvents("sess-1", appendBatchSize+20)
+		require.NoError(t, s.AppendConversationEvents(ctx, events))
+		require.NoError(t, s.AddConversationEvent(ctx, &ConversationEvent{
+			SessionID: "sess-1", ClaudeSessionID: "claude-sess-1",
+			EventType: EventTypeMessage, Role: "assistant", Content: "done",
+		}))
+
+		stored, err := s.GetConversation(ctx, "claude-sess-1", ConversationPage{})
+		require.NoError(t, err)
+		require.Len(t, stored, len(events)+2)
+		for i, e := range stored {
+			assert.Equal(t, i+1, e.Sequence)
+		}
+		for i, e := range events {
+			assert.Equal(t, i+2, e.Sequence)
+			assert.Equal(t, stored[i+1].ID, e.ID, "IDs match the stored rows")
+			assert.Equal(t, fmt.Sprintf("tool-%d", i), stored[i+1].ToolID)
+			assert.NotEmpty(t, e.Permalink)
+		}
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		s, err := NewEncryptedSQLiteStore(":memory:", "correct horse")
+		require.NoError(t, err)
+		defer func() { _ = s.Close() }()
+		createEncryptionTestSession(t, s, "sess-1")
+
+		require.NoError(t, s.AppendConversationEvents(ctx, newEvents("sess-1", 3)))
+		stored, err := s.GetConversation(ctx, "claude-sess-1", ConversationPage{})
+		require.NoError(t, err)
+		require.Len(t, stored, 3)
+		assert.Equal(t, `{"file":"a.go"}`, stored[0].ToolInputJSON)
+
+		var raw string
+		require.NoError(t, s.db.QueryRow("SELECT tool_input_json FROM conversation_events WHERE id = ?", stored[0].ID).Scan(&raw))
+		assert.NotContains(t, raw, "a.go")
+	})
+
+	t.Run("rejects mixed sessions", func(t *testing.T) {
+		s, err := NewSQLiteStore(":memory:")
+		require.NoError(t, err)
+		defer func() { _ = s.Close() }()
+		createEncryptionTestSession(t, s, "sess-1")
+		createEncryptionTestSession(t, s, "sess-2")
+
+		events := append(newEvents("sess-1", 2), newEvents("sess-2", 1)...)
+		assert.EqualError(t, s.AppendConversationEvents(ctx, events), "all events in a batch must belong to the same session")
+		assert.EqualError(t, s.AppendConversationEvents(ctx, newEvents("", 1)), "events must have a session ID")
+		assert.NoError(t, s.AppendConversationEvents(ctx, nil))
+
+		stored, err := s.GetConversation(ctx, "claude-sess-1", ConversationPage{})
+		require.NoError(t, err)
+		assert.Empty(t, stored)
+	})
+
+	t.Run("over budget stores nothing", func(t *testing.T) {
+		s, err := NewSQLiteStore(":memory:")
+		require.NoError(t, err)
+		defer func() { _ = s.Close() }()
+		createEncryptionTestSession(t, s, "sess-1")
+		maxCost, cost := 1.0, 2.0
+		maxCostPtr := &maxCost
+		require.NoError(t, s.UpdateSession(ctx, "sess-1", SessionUpdate{MaxCostUSD: &maxCostPtr, CostUSD: &cost}))
+
+		err = s.AppendConversationEvents(ctx, newEvents("sess-1", appendBatchSize+1))
+		assert.ErrorIs(t, err, ErrBudgetExceeded)
+		stored, err := s.GetConversation(ctx, "claude-sess-1", ConversationPage{})
+		require.NoError(t, err)
+		assert.Empty(t, stored)
+	})
+}
+
+func TestIdempotentConversationEvents(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+	createEncryptionTestSession(t, s, "sess-1")
+	createEncryptionTestSession(t, s, "sess-2")
+	newEvent := func(sessionID, key, content string) *ConversationEvent {
+		return &ConversationEvent{
+			SessionID: sessionID, ClaudeSessionID: "claude-" + sessionID, IdempotencyKey: key,
+			EventType: EventTypeMessage, Role: "assistant", Content: content,
+		}
+	}
+	countRows := func(t *testing.T, key string) int {
+		t.Helper()
+		var n int
+		require.NoError(t, s.db.QueryRow("SELECT COUNT(*) FROM conversation_events WHERE idempotency_key = ?", key).Scan(&n))
+		return n
+	}
+
+	first := newEvent("sess-1", "retry-1", "hello")
+	require.NoError(t, s.AddConversationEvent(ctx, first))
+	retry := newEvent("sess-1", "retry-1", "hello again")
+	require.NoError(t, s.AddConversationEvent(ctx, retry))
+	assert.Equal(t, 1, countRows(t, "retry-1"))
+	assert.Equal(t, first.ID, retry.ID)
+	assert.Equal(t, first.Sequence, retry.Sequence)
+	assert.Equal(t, "hello", retry.Content, "filled in from the stored event")
+
+	t.Run("keys are per session", func(t *testing.T) {
+		other := newEvent("sess-2", "retry-1", "hello")
+		require.NoError(t, s.AddConversationEvent(ctx, other))
+		assert.NotEqual(t, first.ID, other.ID)
+		assert.Equal(t, 2, countRows(t, "retry-1"))
+	})
+
+	t.Run("events without a key never conflict", func(t *testing.T) {
+		require.NoError(t, s.AddConversationEvent(ctx, newEvent("sess-1", "", "a")))
+		require.NoError(t, s.AddConversationEvent(ctx, newEvent("sess-1", "", "a")))
+		var n int
+		require.NoError(t, s.db.QueryRow("SELECT COUNT(*) FROM conversation_events WHERE session_id = 'sess-1' AND idempotency_key IS NULL").Scan(&n))
+		assert.Equal(t, 2, n)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		events := []*ConversationEvent{
+			newEvent("sess-1", "retry-1", "stored before"),
+			newEvent("sess-1", "batch-1", "new"),
+			newEvent("sess-1", "batch-1", "repeated in the batch"),
+			newEvent("sess-1", "", "no key"),
+		}
+		require.NoError(t, s.AppendConversationEvents(ctx, events))
+		assert.Equal(t, first.ID, events[0].ID)
+		assert.Equal(t, 1, countRows(t, "batch-1"))
+		assert.Equal(t, events[1].ID, events[2].ID)
+		assert.Equal(t, "new", events[2].Content)
+		assert.Equal(t, events[1].Sequence+1, events[3].Sequence, "skipped events take no sequence number")
+
+		stored, err := s.GetConversation(ctx, "claude-sess-1", ConversationPage{})
+		require.NoError(t, err)
+		assert.Len(t, stored, 5)
+	})
+}
+
+// BenchmarkAppendConversationEvents compares storing 100 events one at a
+// time with storing them as one batch
+func BenchmarkAppendConversationEvents(b *testing.B) {
+	newEvents := func(n int) []*ConversationEvent {
+		events := make([]*ConversationEvent, n)
+		for i := range events {
+			events[i] = &ConversationEvent{
+				SessionID: "sess-1", ClaudeSessionID: "claude-sess-1",
+				EventType: EventTypeToolCall, ToolID: fmt.Sprintf("tool-%d", i), ToolName: "Read",
+				ToolInputJSON: `{"file":"a.go"}`,
+			}
+		}
+		return events
+	}
+	open := func(b *testing.B) *SQLiteStore {
+		s, err := NewSQLiteStore(filepath.Join(b.TempDir(), "bench.db"))
+		require.NoError(b, err)
+		b.Cleanup(func() { _ = s.Close() })
+		createEncryptionTestSession(b, s, "sess-1")
+		return s
+	}
+
+	b.Run("individual", func(b *testing.B) {
+		s := open(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, event := range newEvents(100) {
+				require.NoError(b, s.AddConversationEvent(context.Background(), event))
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		s := open(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, s.AppendConversationEvents(context.Background(), newEvents(100)))
+		}
+	})
+}
+
+func TestGetConversationUsesClaudeSessionIndex(t *testing.T) {
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	rows, err := s.db.Query(`EXPLAIN QUERY PLAN
+		SELECT * FROM conversation_events
+		WHERE claude_session_id = ? AND sequence > ?
+		ORDER BY sequence`, "claude-1", 0)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+
+	var plan []string
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		require.NoError(t, rows.Scan(&id, &parent, &notused, &detail))
+		plan = append(plan, detail)
+	}
+	require.NoError(t, rows.Err())
+	assert.Contains(t, strings.Join(plan, "\n"), "SEARCH conversation_events USING INDEX idx_conversation_claude_session")
+}
+
+// BenchmarkGetConversationLookup measures fetching one Claude session's
+// events from tables of increasing size, with and without the
+// claude_session_id index.
+func BenchmarkGetConversationLookup(b *testing.B) {
+	for _, rows := range []int{10_000, 100_000, 1_000_000} {
+		for _, indexed := range []bool{true, false} {
+			name := fmt.Sprintf("rows=%d/indexed=%t", rows, indexed)
+			b.Run(name, func(b *testing.B) {
+				s, err := NewSQLiteStore(filepath.Join(b.TempDir(), "bench.db"))
+				require.NoError(b, err)
+				defer func() { _ = s.Close() }()
+				createEncryptionTestSession(b, s, "sess-1")
+
+				// Spread the rows over 1000 Claude sessions of equal size
+				_, err = s.db.Exec(`
+					WITH RECURSIVE n(i) AS (SELECT 0 UNION ALL SELECT i + 1 FROM n WHERE i + 1 < ?)
+					INSERT INTO conversation_events (session_id, claude_session_id, sequence, event_type, role, content,
+						tool_id, tool_name, tool_input_json, parent_tool_use_id, tool_result_for_id, tool_result_content,
+						approval_status, approval_id)
+					SELECT 'sess-1', 'claude-' || (i % 1000), i / 1000 + 1, 'message', 'assistant', 'hello',
+						'', '', '', '', '', '', '', ''
+					FROM n`, rows)
+				require.NoError(b, err)
+				if !indexed {
+					_, err = s.db.Exec(`DROP INDEX idx_conversation_claude_session`)
+					require.NoError(b, err)
+				}
+
+				ctx := context.Background()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					events, err := s.GetConversation(ctx, fmt.Sprintf("claude-%d", i%1000), ConversationPage{})
+					require.NoError(b, err)
+					require.Len(b, events, rows/1000)
+				}
+			})
+		}
+	}
+}
+
+func TestCreateSessionRunIDIsUnique(t *testing.T) {
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "runid.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	// Two racing inserts for one run; the unique index lets only one through
+	errs := make(chan error, 2)
+	for _, id := range []string{"sess-a", "sess-b"} {
+		go func(id string) {
+			errs <- s.CreateSession(context.Background(), &Session{
+				ID: id, RunID: "run-1", ClaudeSessionID: "claude-" + id, Query: "q",
+				Status: SessionStatusRunning, CreatedAt: time.Now(), LastActivityAt: time.Now(),
+			})
+		}(id)
+	}
+	var failed int
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			failed++
+			assert.Contains(t, err.Error(), "UNIQUE constraint failed: sessions.run_id")
+		}
+	}
+	assert.Equal(t, 1, failed)
+
+	sessions, err := s.GetSessionsByRunID(context.Background(), "run-1")
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
+
+func TestSoftDeleteSession(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	for _, session := range []*Session{
+		{ID: "done", RunID: "run-done", Status: SessionStatusCompleted},
+		{ID: "kept", RunID: "run-kept", Status: SessionStatusCompleted},
+		{ID: "live", RunID: "run-live", Status: SessionStatusRunning},
+	} {
+		session.Query, session.CreatedAt, session.LastActivityAt = "q", time.Now(), time.Now()
+		require.NoError(t, s.CreateSession(ctx, session))
+	}
+	require.NoError(t, s.AddConversationEvent(ctx, &ConversationEvent{
+		SessionID: "done", ClaudeSessionID: "claude-done", EventType: EventTypeMessage, Role: "user", Content: "hi",
+	}))
+
+	require.NoError(t, s.SoftDeleteSession(ctx, "done"))
+
+	var notFound *NotFoundError
+	_, err = s.GetSession(ctx, "done")
+	assert.ErrorAs(t, err, &notFound)
+	listed, err := s.ListSessions(ctx, ListSessionsFilter{})
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	byRun, err := s.GetSessionsByRunID(ctx, "run-done")
+	require.NoError(t, err)
+	assert.Empty(t, byRun)
+
+	all, err := s.ListSessions(ctx, ListSessionsFilter{IncludeDeleted: true})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	for _, session := range all {
+		assert.Equal(t, session.ID == "done", session.DeletedAt != nil, session.ID)
+	}
+
+	// The data stays until the session is restored
+	events, err := s.GetConversation(ctx, "claude-done", ConversationPage{})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+	require.NoError(t, s.RestoreSession(ctx, "done"))
+	restored, err := s.GetSession(ctx, "done")
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+
+	t.Run("errors", func(t *testing.T) {
+		assert.EqualError(t, s.SoftDeleteSession(ctx, "live"), "cannot soft-delete session live while it is running")
+		assert.ErrorAs(t, s.SoftDeleteSession(ctx, "missing"), &notFound)
+		assert.ErrorAs(t, s.RestoreSession(ctx, "kept"), &notFound, "only soft-deleted sessions can be restored")
+		require.NoError(t, s.SoftDeleteSession(ctx, "kept"))
+		assert.ErrorAs(t, s.SoftDeleteSession(ctx, "kept"), &notFound, "already soft-deleted")
+	})
+}
+
+func TestArchiveSession(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	for _, session := range []*Session{
+		{ID: "done", RunID: "run-done", ClaudeSessionID: "claude-done", Status: SessionStatusCompleted, Model: "opus"},
+		{ID: "live", RunID: "run-live", Status: SessionStatusRunning},
+		{ID: "parent", RunID: "run-parent", Status: SessionStatusCompleted},
+		{ID: "child", RunID: "run-child", ParentSessionID: "parent", Status: SessionStatusCompleted},
+	} {
+		session.Query, session.CreatedAt, session.LastActivityAt = "q", time.Now(), time.Now()
+		require.NoError(t, s.CreateSession(ctx, session))
+	}
+	for _, content := range []string{"hi", "hello"} {
+		require.NoError(t, s.AddConversationEvent(ctx, &ConversationEvent{
+			SessionID: "done", ClaudeSessionID: "claude-done", EventType: EventTypeMessage, Role: "user", Content: content,
+		}))
+	}
+	require.NoError(t, s.SetSessionTags(ctx, "done", map[string]string{"team": "infra"}))
+	require.NoError(t, s.CreateApproval(ctx, &Approval{
+		ID: "appr-done", RunID: "run-done", SessionID: "done", Status: ApprovalStatusApproved,
+		CreatedAt: time.Now(), ToolName: "Bash", ToolInput: json.RawMessage(`{}`),
+	}))
+	require.NoError(t, s.CreateAttachment(ctx, &Attachment{
+		ID: "att-done", SessionID: "done", Name: "a.txt", ContentType: "text/plain", Backend: "local", StorageKey: "a",
+	}))
+	events, err := s.GetConversation(ctx, "claude-done", ConversationPage{})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	_, err = s.AddAnnotation(ctx, events[0].ID, "reviewer", "looks right")
+	require.NoError(t, err)
+	count := func(t *testing.T, table, sessionColumn string) int {
+		t.Helper()
+		var n int
+		require.NoError(t, s.db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE "+sessionColumn+" = 'done'").Scan(&n))
+		return n
+	}
+	archivedData := map[string][2]string{
+		"conversation_events": {"archived_conversation_events", "session_id"},
+		"approvals":           {"archived_approvals", "session_id"},
+		"attachments":         {"archived_attachments", "session_id"},
+		"session_tags":        {"archived_session_tags", "session_id"},
+	}
+
+	require.NoError(t, s.ArchiveSession(ctx, "done"))
+	assert.Equal(t, 0, count(t, "sessions", "id"))
+	assert.Equal(t, 1, count(t, "archived_sessions", "id"))
+	for table, archive := range archivedData {
+		assert.Equal(t, 0, count(t, table, archive[1]), table)
+		assert.Positive(t, count(t, archive[0], archive[1]), archive[0])
+	}
+	var annotations int
+	require.NoError(t, s.db.QueryRow("SELECT COUNT(*) FROM archived_annotations WHERE event_id = ?", events[0].ID).Scan(&annotations))
+	assert.Equal(t, 1, annotations)
+
+	// Archived events are still found by permalink and Claude session
+	byPermalink, err := s.GetEventByPermalink(ctx, events[1].Permalink)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", byPermalink.Content)
+	archivedEvents, err := s.GetConversation(ctx, "claude-done", ConversationPage{})
+	require.NoError(t, err)
+	assert.Len(t, archivedEvents, 2)
+	total, err := s.CountConversation(ctx, "claude-done")
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+
+	var notFound *NotFoundError
+	_, err = s.GetSession(ctx, "done")
+	assert.ErrorAs(t, err, &notFound)
+	listed, err := s.ListSessions(ctx, ListSessionsFilter{})
+	require.NoError(t, err)
+	assert.Len(t, listed, 3)
+
+	all, err := s.ListSessions(ctx, ListSessionsFilter{IncludeArchived: true})
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+	archived, err := s.ListSessions(ctx, ListSessionsFilter{IncludeArchived: true, Model: "opus"})
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	assert.Equal(t, "done", archived[0].ID)
+	assert.Equal(t, SessionStatusCompleted, archived[0].Status)
+	assert.Equal(t, "claude-done", archived[0].ClaudeSessionID)
+
+	t.Run("restores a session with its data", func(t *testing.T) {
+		require.NoError(t, s.RestoreArchivedSession(ctx, "done"))
+		assert.Equal(t, 0, count(t, "archived_sessions", "id"))
+		for table, archive := range archivedData {
+			assert.Positive(t, count(t, table, archive[1]), table)
+			assert.Equal(t, 0, count(t, archive[0], archive[1]), archive[0])
+		}
+		restored, err := s.GetSession(ctx, "done")
+		require.NoError(t, err)
+		assert.Equal(t, "opus", restored.Model)
+		restoredAnnotations, err := s.GetAnnotationsForSession(ctx, "done")
+		require.NoError(t, err)
+		assert.Len(t, restoredAnnotations, 1)
+		tags, err := s.GetSessionTags(ctx, "done")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"team": "infra"}, tags)
+
+		assert.ErrorAs(t, s.RestoreArchivedSession(ctx, "done"), &notFound)
+		require.NoError(t, s.ArchiveSession(ctx, "done"))
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		assert.ErrorAs(t, s.ArchiveSession(ctx, "done"), &notFound)
+		assert.EqualError(t, s.ArchiveSession(ctx, "live"), "cannot archive session live while it is running")
+		assert.EqualError(t, s.ArchiveSession(ctx, "parent"), "cannot archive session parent while sessions continued from it remain")
+		require.NoError(t, s.ArchiveSession(ctx, "child"))
+		assert.NoError(t, s.ArchiveSession(ctx, "parent"))
+	})
+
+	t.Run("archive tables follow new columns", func(t *testing.T) {
+		_, err := s.db.Exec("ALTER TABLE sessions ADD COLUMN later_column TEXT")
+		require.NoError(t, err)
+		require.NoError(t, s.syncArchiveTables())
+		columns, err := tableColumns(ctx, s.db, "archived_sessions")
+		require.NoError(t, err)
+		assert.Contains(t, columns, "later_column")
+	})
+}
+
+func TestListQueuedSessions(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	base := time.Now().Add(-time.Hour)
+	for i, session := range []*Session{
+		{ID: "low", Priority: 1, Status: SessionStatusQueued},
+		{ID: "high", Priority: 5, Status: SessionStatusQueued},
+		{ID: "mid", Priority: 3, Status: SessionStatusQueued},
+		{ID: "mid-later", Priority: 3, Status: SessionStatusQueued},
+		{ID: "starting", Priority: 9, Status: SessionStatusStarting},
+		{ID: "running", Priority: 9, Status: SessionStatusRunning},
+	} {
+		session.RunID, session.Query = "run-"+session.ID, "q"
+		session.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		session.LastActivityAt = session.CreatedAt
+		require.NoError(t, s.CreateSession(ctx, session))
+	}
+
+	queued, err := s.ListQueuedSessions(ctx)
+	require.NoError(t, err)
+	var ids []string
+	for _, session := range queued {
+		ids = append(ids, session.ID)
+	}
+	assert.Equal(t, []string{"high", "mid", "mid-later", "low"}, ids)
+	assert.Equal(t, 5, queued[0].Priority)
+
+	got, err := s.GetSession(ctx, "mid")
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Priority)
+}
+
+func TestSQLiteOptions(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "daemon.db")
+	opts := SQLiteOptions{WALMode: true, BusyTimeoutMS: 1000, CacheSize: -4096}
+
+	writer, err := NewSQLiteStoreWithOptions(dbPath, opts)
+	require.NoError(t, err)
+	defer func() { _ = writer.Close() }()
+	reader, err := NewSQLiteStoreWithOptions(dbPath, opts)
+	require.NoError(t, err)
+	defer func() { _ = reader.Close() }()
+
+	// The settings reach every pooled connection, not just the first
+	conns := make([]*sql.Conn, 3)
+	for i := range conns {
+		conns[i], err = reader.GetDB().Conn(ctx)
+		require.NoError(t, err)
+	}
+	for _, conn := range conns {
+		var journalMode string
+		var busyTimeout, cacheSize int
+		require.NoError(t, conn.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journalMode))
+		require.NoError(t, conn.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busyTimeout))
+		require.NoError(t, conn.QueryRowContext(ctx, "PRAGMA cache_size").Scan(&cacheSize))
+		assert.Equal(t, "wal", journalMode)
+		assert.Equal(t, 1000, busyTimeout)
+		assert.Equal(t, -4096, cacheSize)
+		require.NoError(t, conn.Close())
+	}
+
+	require.NoError(t, writer.CreateSession(ctx, &Session{
+		ID: "sess-1", RunID: "run-1", ClaudeSessionID: "claude-1", Query: "q", Status: SessionStatusRunning,
+		CreatedAt: time.Now(), LastActivityAt: time.Now(),
+	}))
+
+	// Write from one store while reading from the other
+	deadline := time.Now().Add(200 * time.Millisecond)
+	writeErr := make(chan error, 1)
+	go func() {
+		for time.Now().Before(deadline) {
+			if err := writer.AddConversationEvent(ctx, &ConversationEvent{
+				SessionID: "sess-1", ClaudeSessionID: "claude-1",
+				EventType: EventTypeMessage, Role: "assistant", Content: "working",
+			}); err != nil {
+				writeErr <- err
+				return
+			}
+		}
+		writeErr <- nil
+	}()
+	reads := 0
+	for time.Now().Before(deadline) {
+		_, err := reader.GetSessionConversation(ctx, "sess-1", ConversationPage{})
+		require.NoError(t, err)
+		reads++
+	}
+	require.NoError(t, <-writeErr)
+	assert.Positive(t, reads)
+
+	events, err := reader.GetSessionConversation(ctx, "sess-1", ConversationPage{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, events)
+}
+
+func TestGetSessionsByModel(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	now := time.Now()
+	for i, session := range []*Session{
+		{ID: "old-sonnet", Model: "sonnet"},
+		{ID: "new-sonnet", Model: "sonnet"},
+		{ID: "opus", Model: "opus"},
+	} {
+		session.RunID, session.Query, session.Status = "run-"+session.ID, "q", SessionStatusCompleted
+		session.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+		session.LastActivityAt = session.CreatedAt
+		require.NoError(t, s.CreateSession(ctx, session))
+	}
+
+	sessions, err := s.GetSessionsByModel(ctx, "sonnet", 0)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "new-sonnet", sessions[0].ID)
+	assert.Equal(t, "old-sonnet", sessions[1].ID)
+
+	sessions, err = s.GetSessionsByModel(ctx, "sonnet", 1)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "new-sonnet", sessions[0].ID)
+
+	sessions, err = s.GetSessionsByModel(ctx, "haiku", 10)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	var id, parent, notused int
+	var plan string
+	require.NoError(t, s.db.QueryRow(`EXPLAIN QUERY PLAN SELECT * FROM sessions WHERE model = ?`, "sonnet").
+		Scan(&id, &parent, &notused, &plan))
+	assert.Contains(t, plan, "USING INDEX idx_sessions_model")
+}
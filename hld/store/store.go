@@ -2,6 +2,8 @@ package store
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"time"
 
@@ -16,15 +18,142 @@ type ConversationStore interface {
 	HardDeleteSession(ctx context.Context, sessionID string) error
 	GetSession(ctx context.Context, sessionID string) (*Session, error)
 	GetSessionByRunID(ctx context.Context, runID string) (*Session, error)
-	ListSessions(ctx context.Context) ([]*Session, error)
+	// GetSessionsByRunID returns every session with the given run ID, oldest
+	// first. run_id is unique in the sessions table today, so this returns at
+	// most one session.
+	GetSessionsByRunID(ctx context.Context, runID string) ([]*Session, error)
+	// ListSessions returns the sessions matching filter in its sort order,
+	// most recently active first by default. The zero filter returns every
+	// session.
+	ListSessions(ctx context.Context, filter ListSessionsFilter) ([]*Session, error)
+	// CountSessions counts the sessions matching filter, ignoring its Limit
+	// and Offset
+	CountSessions(ctx context.Context, filter ListSessionsFilter) (int, error)
 	SearchSessionsByTitle(ctx context.Context, query string, limit int) ([]*Session, error)
+	// SearchEvents finds conversation events whose content matches every
+	// word of query, most recent first. Double-quoted parts of query match
+	// as phrases; other search syntax is treated as plain text.
+	SearchEvents(ctx context.Context, query string, filter SearchFilter) ([]*ConversationEvent, error)
+	// ListToolCalls returns tool call events across sessions, most recent
+	// first, with the run each belongs to
+	ListToolCalls(ctx context.Context, filter ToolCallFilter) ([]*ToolCallSummary, error)
 	// GetExpiredDangerousPermissionsSessions returns sessions where dangerous permissions have expired
 	GetExpiredDangerousPermissionsSessions(ctx context.Context) ([]*Session, error)
+	// GetEvictableSessionIDs returns the IDs of removable sessions that
+	// exceed maxSessions, least recently active first. Removable sessions
+	// are terminal, including soft-deleted ones, without pending approvals
+	// or continuations.
+	GetEvictableSessionIDs(ctx context.Context, maxSessions int) ([]string, error)
+	// GetExpiredSessionIDs returns the IDs of removable sessions, as for
+	// GetEvictableSessionIDs, last active before olderThan, least recently
+	// active first
+	GetExpiredSessionIDs(ctx context.Context, olderThan time.Time) ([]string, error)
+	// IncrementalVacuum returns free pages in the database file to the
+	// filesystem, after deletions have left them unused
+	IncrementalVacuum(ctx context.Context) error
+	// ForkSession creates fork and copies into it the source session's own
+	// events with sequence numbers up to atSequence, keeping their sequence
+	// numbers, in one transaction. It returns the number of events copied.
+	ForkSession(ctx context.Context, sourceSessionID string, atSequence int, fork *Session) (int, error)
+	// DeleteSessionData permanently deletes a session and all rows that reference it
+	DeleteSessionData(ctx context.Context, sessionID string) error
+	// GetSessionIncludingDeleted is GetSession for sessions that may be
+	// soft-deleted
+	GetSessionIncludingDeleted(ctx context.Context, sessionID string) (*Session, error)
+	// SoftDeleteSession hides a terminal session from GetSession and listings
+	// without removing its data; RestoreSession undoes it
+	SoftDeleteSession(ctx context.Context, sessionID string) error
+	RestoreSession(ctx context.Context, sessionID string) error
+	// ArchiveSession moves a terminal session and all of its data to the
+	// archive tables. Archived sessions are only returned by ListSessions
+	// with IncludeArchived, though GetConversation and GetEventByPermalink
+	// still find their events.
+	ArchiveSession(ctx context.Context, sessionID string) error
+	// RestoreArchivedSession moves an archived session and its data back
+	RestoreArchivedSession(ctx context.Context, sessionID string) error
+	// ListQueuedSessions returns the sessions waiting for a launch slot,
+	// highest priority first and oldest first within a priority
+	ListQueuedSessions(ctx context.Context) ([]*Session, error)
+	// VacuumOldEvents deletes the conversation events of terminal sessions
+	// last active before olderThan, and then those sessions too unless
+	// keepTerminalSessions is set. It returns how many of each it deleted.
+	VacuumOldEvents(ctx context.Context, olderThan time.Time, keepTerminalSessions bool) (deletedEvents int64, deletedSessions int64, err error)
 
 	// Conversation operations
+	// AddConversationEvent stores an event, assigning its sequence number.
+	// It fails with ErrBudgetExceeded if the event's session has spent more
+	// than its MaxCostUSD.
 	AddConversationEvent(ctx context.Context, event *ConversationEvent) error
-	GetConversation(ctx context.Context, claudeSessionID string) ([]*ConversationEvent, error)
-	GetSessionConversation(ctx context.Context, sessionID string) ([]*ConversationEvent, error)
+	// AppendConversationEvents stores events belonging to one session in
+	// one transaction, assigning consecutive sequence numbers. Either all
+	// are stored or none are, and it fails with ErrBudgetExceeded like
+	// AddConversationEvent.
+	AppendConversationEvents(ctx context.Context, events []*ConversationEvent) error
+	// GetConversation returns a Claude session's events in sequence order,
+	// from the archive tables if the session was archived. The zero page
+	// returns them all.
+	GetConversation(ctx context.Context, claudeSessionID string, page ConversationPage) ([]*ConversationEvent, error)
+	// GetSessionConversation returns a session's events including those of
+	// its parent chain, oldest first. The zero page returns them all.
+	GetSessionConversation(ctx context.Context, sessionID string, page ConversationPage) ([]*ConversationEvent, error)
+	// CountConversation and CountSessionConversation count the events
+	// GetConversation and GetSessionConversation return for the zero page
+	CountConversation(ctx context.Context, claudeSessionID string) (int, error)
+	CountSessionConversation(ctx context.Context, sessionID string) (int, error)
+	// GetConversationRange returns a session's own events with sequence
+	// numbers from fromSeq to toSeq inclusive, in sequence order. Bounds past
+	// either end of the conversation return what is in range, possibly
+	// nothing; fromSeq greater than toSeq is an error.
+	GetConversationRange(ctx context.Context, sessionID string, fromSeq, toSeq int) ([]*ConversationEvent, error)
+	// SubscribeToSession streams the conversation events stored for a session
+	// from now on, in sequence order. The channel is closed when the session
+	// reaches a terminal status, is deleted, or ctx is done. A subscriber that
+	// falls too far behind also has its channel closed rather than missing
+	// events.
+	SubscribeToSession(ctx context.Context, sessionID string) (<-chan *ConversationEvent, error)
+	GetEventByPermalink(ctx context.Context, permalink string) (*ConversationEvent, error)
+	// FindEventContentHashes returns the session holding each of the given
+	// content hashes, omitting hashes not stored in any session
+	FindEventContentHashes(ctx context.Context, hashes []string) (map[string]string, error)
+	// GetLastEventContentHash returns the content hash of a session's latest
+	// event, or "" if it has none
+	GetLastEventContentHash(ctx context.Context, sessionID string) (string, error)
+	// MarkEventTruncated flags an event whose content was cut off by an
+	// incomplete provider stream
+	MarkEventTruncated(ctx context.Context, eventID int64) error
+
+	// Turn metrics operations
+	RecordTurnUsage(ctx context.Context, turn *TurnUsage) error
+	GetSessionThroughput(ctx context.Context, sessionID string) (*SessionThroughput, error)
+	GetSessionTurns(ctx context.Context, sessionID string) ([]*TurnUsage, error)
+	// GetToolOutputStats aggregates tool result sizes by tool name, largest first.
+	// An empty sessionID aggregates across all sessions.
+	GetToolOutputStats(ctx context.Context, sessionID string) ([]*ToolOutputStats, error)
+	// GetConversationMetrics measures the size of a session's conversation
+	GetConversationMetrics(ctx context.Context, sessionID string) (*ConversationMetrics, error)
+	// GetConversationStats counts a session's events by type and by hour
+	GetConversationStats(ctx context.Context, sessionID string) (*ConversationStats, error)
+	// CountEventsByType counts a session's own events by event type. Types
+	// the session has no events of are left out.
+	CountEventsByType(ctx context.Context, sessionID string) (map[string]int, error)
+	// GetSessionMetrics counts a session's messages and tool calls and times
+	// its tool calls
+	GetSessionMetrics(ctx context.Context, sessionID string) (*SessionMetrics, error)
+	// GetSessionUsage returns cost, duration, turns and tool calls for each
+	// session matching filter, oldest first
+	GetSessionUsage(ctx context.Context, filter SessionUsageFilter) ([]*SessionUsage, error)
+	// AggregateCostByRun totals the cost and tokens of a run's sessions
+	AggregateCostByRun(ctx context.Context, runID string) (RunCostSummary, error)
+	// AggregateCostByModel totals the cost and tokens of sessions created at
+	// or after since for each model, most expensive first. A zero since
+	// includes every session.
+	AggregateCostByModel(ctx context.Context, since time.Time) ([]ModelCostSummary, error)
+	// GetSessionsByModel returns up to limit sessions using model, newest
+	// first. A limit of 0 returns them all.
+	GetSessionsByModel(ctx context.Context, model string, limit int) ([]*Session, error)
+	// GetSessionCostStats counts the sessions of a run that have a recorded
+	// cost and averages it. An empty runID covers every session.
+	GetSessionCostStats(ctx context.Context, runID string) (SessionCostStats, error)
 
 	// Tool call operations
 	GetPendingToolCall(ctx context.Context, sessionID string, toolName string) (*ConversationEvent, error)
@@ -47,11 +176,38 @@ type ConversationStore interface {
 	CreateApproval(ctx context.Context, approval *Approval) error
 	GetApproval(ctx context.Context, id string) (*Approval, error)
 	GetPendingApprovals(ctx context.Context, sessionID string) ([]*Approval, error)
-	UpdateApprovalResponse(ctx context.Context, id string, status ApprovalStatus, comment string) error
+	GetSessionApprovals(ctx context.Context, sessionID string) ([]*Approval, error)
+	UpdateApprovalResponse(ctx context.Context, id string, status ApprovalStatus, comment, respondedBy string) error
 
 	// File snapshot operations
 	CreateFileSnapshot(ctx context.Context, snapshot *FileSnapshot) error
 	GetFileSnapshots(ctx context.Context, sessionID string) ([]FileSnapshot, error)
+
+	// Attachment operations. Only references are stored here; the content
+	// lives in the attachment blob store.
+	CreateAttachment(ctx context.Context, attachment *Attachment) error
+	GetAttachment(ctx context.Context, id string) (*Attachment, error)
+	ListSessionAttachments(ctx context.Context, sessionID string) ([]*Attachment, error)
+
+	// Session tag operations. Tags are free-form key/value metadata.
+	SetSessionTags(ctx context.Context, sessionID string, tags map[string]string) error
+	GetSessionTags(ctx context.Context, sessionID string) (map[string]string, error)
+
+	// Annotation operations. Annotations are reviewer notes on conversation
+	// events.
+	// AddAnnotation fails with a NotFoundError if the event doesn't exist.
+	AddAnnotation(ctx context.Context, eventID int64, author, note string) (*Annotation, error)
+	// GetAnnotationsForSession returns the annotations on a session's own
+	// events, oldest first
+	GetAnnotationsForSession(ctx context.Context, sessionID string) ([]*Annotation, error)
+
+	// Webhook operations
+	CreateWebhook(ctx context.Context, webhook *Webhook) error
+	// DeleteWebhook fails with a NotFoundError if the webhook doesn't exist
+	DeleteWebhook(ctx context.Context, id string) error
+	// ListWebhooks returns every registered webhook, oldest first
+	ListWebhooks(ctx context.Context) ([]*Webhook, error)
+
 	// Recent paths operations
 	GetRecentWorkingDirs(ctx context.Context, limit int) ([]RecentPath, error)
 
@@ -59,7 +215,15 @@ type ConversationStore interface {
 	GetUserSettings(ctx context.Context) (*UserSettings, error)
 	UpdateUserSettings(ctx context.Context, settings UserSettings) error
 
+	// Audit log operations
+	CreateAuditEntry(ctx context.Context, entry *AuditEntry) error
+	CompleteAuditEntry(ctx context.Context, id int64, outcome string, errorMessage string) error
+	ListAuditEntries(ctx context.Context, filter AuditLogFilter) ([]*AuditEntry, error)
+	PruneAuditEntries(ctx context.Context, before time.Time) (int64, error)
+
 	// Database lifecycle
+	// Ping checks that the database answers a trivial query
+	Ping(ctx context.Context) error
 	Close() error
 }
 
@@ -119,6 +283,38 @@ type Session struct {
 
 	// Editor state for draft sessions (JSON blob)
 	EditorState *string `db:"editor_state"`
+
+	// Imported sessions were loaded from an external transcript and have no
+	// live Claude session behind them, so they cannot be resumed
+	Imported bool `db:"imported"`
+
+	// Owner the session was launched on behalf of, used for fair scheduling
+	Owner string `db:"owner"`
+
+	// BypassToolCache makes every tool call execute even when a cached
+	// result is available
+	BypassToolCache bool `db:"bypass_tool_cache"`
+
+	// ToolQuota is the JSON-encoded tool call quota, empty for unlimited
+	ToolQuota string `db:"tool_quota"`
+
+	// Launch timing: when the session started waiting for a launch slot,
+	// when its Claude process started, and when the first assistant output
+	// arrived. Unset for sessions that haven't reached each point.
+	QueuedAt     *time.Time `db:"queued_at"`
+	StartedAt    *time.Time `db:"started_at"`
+	FirstEventAt *time.Time `db:"first_event_at"`
+
+	// MaxCostUSD caps CostUSD. Once CostUSD exceeds it, AddConversationEvent
+	// refuses the session's events with ErrBudgetExceeded. Nil for no cap.
+	MaxCostUSD *float64 `db:"max_cost_usd"`
+
+	// Priority orders launches waiting for a slot; higher starts first
+	Priority int `db:"priority"`
+
+	// DeletedAt is set while the session is soft-deleted. Only listings
+	// with IncludeDeleted return such sessions.
+	DeletedAt *time.Time `db:"deleted_at"`
 }
 
 // SessionUpdate contains fields that can be updated
@@ -157,6 +353,58 @@ type SessionUpdate struct {
 	WorkingDir *string `db:"working_dir"`
 	// Editor state field (JSON blob)
 	EditorState *string `db:"editor_state"`
+	// Launch timing fields
+	QueuedAt     *time.Time `db:"queued_at"`
+	StartedAt    *time.Time `db:"started_at"`
+	FirstEventAt *time.Time `db:"first_event_at"`
+	// Budget field; set to a nil *float64 to remove the cap
+	MaxCostUSD **float64 `db:"max_cost_usd"`
+}
+
+// SearchFilter narrows SearchEvents. Zero fields don't filter.
+type SearchFilter struct {
+	SessionID  string
+	EventTypes []string  // Any of these event types
+	Since      time.Time // Created at or after
+	Until      time.Time // Created before
+	Limit      int       // Zero returns every match
+}
+
+// ToolCallFilter narrows ListToolCalls. Zero fields don't filter.
+type ToolCallFilter struct {
+	ToolName string
+	RunID    string
+	Since    time.Time // Created at or after
+	Limit    int       // Zero returns every match
+}
+
+// ToolCallSummary is a tool call event with the run ID of its session
+type ToolCallSummary struct {
+	ConversationEvent
+	SessionRunID string
+}
+
+// ListSessionsFilter selects sessions. Zero fields don't filter.
+type ListSessionsFilter struct {
+	Status           []string // Any of these statuses
+	ModelPrefix      string   // Models starting with this, such as "claude-sonnet"
+	Model            string   // Exactly this model
+	WorkingDirPrefix string   // Working directories starting with this
+	CreatedAfter     time.Time
+	CreatedBefore    time.Time
+	RunID            string
+	TagFilters       map[string]string // Sessions with all of these tags
+	IDs              []string          // Any of these session IDs
+
+	IncludeDeleted  bool // Also return soft-deleted sessions
+	IncludeArchived bool // Also return sessions moved to the archive by ArchiveSession
+
+	// SortBy is one of SessionSorts, ordering by last activity if empty.
+	// Sessions come largest or most recent first unless SortAscending is set.
+	SortBy        string
+	SortAscending bool
+	Limit         int // At most this many sessions, 0 for all
+	Offset        int // Sessions to skip before the first one returned
 }
 
 // ConversationEvent represents a single event in a conversation
@@ -181,11 +429,202 @@ type ConversationEvent struct {
 	// Tool result fields
 	ToolResultForID   string
 	ToolResultContent string
+	ToolResultBytes   int // Size of ToolResultContent, computed on insert
+	ToolResultTokens  int // Estimated tokens in ToolResultContent, computed on insert
+	// ToolResultJSON is the result as the provider sent it, which keeps
+	// structure (such as image or multi-part results) that ToolResultContent
+	// flattens to text
+	ToolResultJSON string
+	// ToolError is the error the tool reported, empty if it succeeded
+	ToolError string
 
 	// Tool call tracking
 	IsCompleted    bool   // TRUE when tool result received
 	ApprovalStatus string // NULL, 'pending', 'approved', 'denied'
 	ApprovalID     string // HumanLayer approval ID when correlated
+
+	// Permalink is a stable, opaque ID for deep-linking to this event
+	Permalink string
+
+	// Language is a declared or detected language tag (e.g. "es"), empty if unknown
+	Language string
+
+	// ToolCacheHit is set on tool results served from the tool result cache
+	// rather than by executing the tool
+	ToolCacheHit bool
+
+	// ContentHash chains this event's content to the events before it. It is
+	// set on imported events so re-imports can be deduplicated.
+	ContentHash string
+
+	// Truncated is set when the provider stream ended before the turn this
+	// event belongs to finished, so its content may be partial
+	Truncated bool
+
+	// IdempotencyKey is an optional caller-supplied key, unique within a
+	// session. Adding an event whose key the session already has stores
+	// nothing and fills the event in from the stored one instead.
+	IdempotencyKey string
+}
+
+// ConversationPage selects a page of a conversation. The zero value selects
+// every event.
+type ConversationPage struct {
+	// AfterSequence skips events up to and including this sequence number.
+	// A session's history spans the Claude sessions of its parent chain,
+	// which each number their events from 1, so for GetSessionConversation
+	// it counts events from the start of the history instead.
+	AfterSequence int
+	Limit         int // Maximum events to return, or 0 for no limit
+}
+
+// Digest returns a hash of the event's own content: its type, role, content
+// and tool fields. IDs, sequence numbers, timestamps and tracking state are
+// left out, so the digest only changes when the content does and is the same
+// for an event wherever and whenever it is computed. Unlike ContentHash it
+// doesn't depend on earlier events.
+func (e *ConversationEvent) Digest() string {
+	// Encoded as a JSON array so field boundaries can't be confused
+	content, _ := json.Marshal([]string{
+		e.EventType,
+		e.Role,
+		e.Content,
+		e.ToolID,
+		e.ToolName,
+		e.ToolInputJSON,
+		e.ParentToolUseID,
+		e.ToolResultForID,
+		e.ToolResultContent,
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// TurnUsage records output tokens and active generation time for one model turn
+type TurnUsage struct {
+	SessionID    string
+	MessageID    string // Provider message ID; repeated reports for the same message replace earlier ones
+	OutputTokens int
+	GenerationMS int64     // Time from the turn's input to the latest output, excluding tool execution and approvals
+	CreatedAt    time.Time // When the turn was first reported; defaults to now on insert
+}
+
+// SessionThroughput aggregates turn usage for a session
+type SessionThroughput struct {
+	Turns        int
+	OutputTokens int
+	GenerationMS int64
+}
+
+// TokensPerSecond returns output tokens per second of generation time, or
+// false if the session has no measured turns yet
+func (t *SessionThroughput) TokensPerSecond() (float64, bool) {
+	if t == nil || t.Turns == 0 || t.GenerationMS <= 0 {
+		return 0, false
+	}
+	return float64(t.OutputTokens) / (float64(t.GenerationMS) / 1000), true
+}
+
+// ToolOutputStats aggregates the size of results returned by one tool
+type ToolOutputStats struct {
+	ToolName  string
+	Results   int
+	Bytes     int64
+	Tokens    int64 // Estimated, see EstimateTokens
+	MaxTokens int   // Largest single result
+}
+
+// ConversationMetrics sizes a conversation without its content. Characters
+// and words cover user and assistant messages only; thinking, tool inputs and
+// tool results are left out since they aren't read like prose.
+type ConversationMetrics struct {
+	Messages   int
+	Turns      int // User messages, each of which starts a turn
+	ToolCalls  int
+	Characters int
+	Words      int
+}
+
+// ConversationStats counts a session's events by type and by the hour they
+// were recorded in
+type ConversationStats struct {
+	ByType map[string]int
+	ByHour []EventHourBucket // Only hours with events, oldest first
+}
+
+// EventHourBucket counts the events recorded in one hour
+type EventHourBucket struct {
+	Hour       time.Time // Start of the hour, in UTC
+	EventCount int
+	TokenCount int // Estimated tool result tokens, see EstimateTokens
+}
+
+// SessionMetrics summarizes a session's activity. Tool call latency is the
+// time from a call to its result, matched by tool ID; event timestamps have
+// one-second resolution, so it is only meaningful for slower tools.
+type SessionMetrics struct {
+	Messages         int
+	ToolCalls        int
+	ToolCallsByName  map[string]int
+	TimedToolCalls   int     // Calls with a result, which the average covers
+	AvgToolLatencyMS float64 // Zero without timed calls
+	CostUSD          float64
+}
+
+// SessionUsageFilter selects sessions for GetSessionUsage. Zero values match
+// everything.
+type SessionUsageFilter struct {
+	Since  time.Time // Created at or after
+	Status string
+	Model  string
+	Owner  string
+}
+
+// ModelCostSummary totals the cost of the sessions using one model. Prompt
+// tokens include cache reads and writes; completion tokens are output tokens.
+type ModelCostSummary struct {
+	Model            string // Empty for sessions without a recorded model
+	SessionCount     int
+	CostUSD          float64
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// SessionCostStats summarises the recorded costs of a set of sessions.
+// Sessions without a cost are left out.
+type SessionCostStats struct {
+	Count       int
+	MeanCostUSD float64
+}
+
+// RunCostSummary totals the cost of a run's sessions
+type RunCostSummary struct {
+	RunID            string
+	SessionCount     int
+	CostUSD          float64
+	PromptTokens     int64
+	CompletionTokens int64
+	Models           []ModelCostSummary
+}
+
+// SessionUsage is the size of one session's run. Metrics the session hasn't
+// reported, such as the duration of one still running, are nil.
+type SessionUsage struct {
+	SessionID  string
+	Status     string
+	Model      string
+	CreatedAt  time.Time
+	CostUSD    *float64
+	DurationMS *int
+	Turns      *int
+	ToolCalls  int
+}
+
+// EstimateTokens approximates the number of model tokens in text using the
+// common ~4 bytes per token heuristic. It is meant for relative comparisons,
+// not billing.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
 }
 
 // FileSnapshot represents a snapshot of file content at Read time
@@ -198,6 +637,39 @@ type FileSnapshot struct {
 	CreatedAt time.Time
 }
 
+// Attachment references a file attached to a session. The content is kept in
+// the blob store named by Backend under StorageKey.
+type Attachment struct {
+	ID          string
+	SessionID   string
+	Name        string
+	ContentType string
+	SizeBytes   int64
+	SHA256      string
+	Backend     string
+	StorageKey  string
+	CreatedAt   time.Time
+}
+
+// Annotation is a note a reviewer attached to a conversation event
+type Annotation struct {
+	ID        int64
+	EventID   int64
+	Author    string
+	Note      string
+	CreatedAt time.Time
+}
+
+// Webhook is an external URL notified of session lifecycle events. Deliveries
+// are signed with Secret.
+type Webhook struct {
+	ID        string
+	URL       string
+	Secret    string
+	Events    []string // Event names such as "session.completed"
+	CreatedAt time.Time
+}
+
 // MCPServer represents an MCP server configuration
 type MCPServer struct {
 	ID        int64
@@ -245,6 +717,7 @@ type Approval struct {
 	ToolName    string          `json:"tool_name"`
 	ToolInput   json.RawMessage `json:"tool_input"`
 	Comment     string          `json:"comment,omitempty"`
+	RespondedBy string          `json:"responded_by,omitempty"` // Identity of whoever decided, empty if unknown
 }
 
 // EventType constants
@@ -256,6 +729,39 @@ const (
 	EventTypeThinking   = "thinking"
 )
 
+// AuditEntry records a single state-changing RPC call
+type AuditEntry struct {
+	ID           int64
+	Identity     string // Who made the call (token identity or transport peer)
+	Method       string // RPC method name
+	SessionID    string // Session affected by the call, if known
+	Outcome      string // 'pending', 'success', 'error'
+	ErrorMessage string
+	CreatedAt    time.Time
+	CompletedAt  *time.Time
+	PrevHash     string // EntryHash of the preceding entry, empty for the first entry
+	EntryHash    string // SHA-256 over PrevHash and the immutable fields of this entry
+}
+
+// AuditLogFilter narrows the entries returned by ListAuditEntries
+type AuditLogFilter struct {
+	Method    string
+	SessionID string
+	Identity  string
+	Outcome   string
+	Since     *time.Time
+	Until     *time.Time
+	BeforeID  int64 // Only entries with a lower ID, for paging newest to oldest
+	Limit     int
+}
+
+// AuditOutcome constants
+const (
+	AuditOutcomePending = "pending"
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeError   = "error"
+)
+
 // RecentPath represents a recently used working directory
 type RecentPath struct {
 	Path       string    `json:"path"`
@@ -274,6 +780,7 @@ const (
 // SessionStatus constants
 const (
 	SessionStatusDraft        = "draft"
+	SessionStatusQueued       = "queued" // Session is waiting for a launch slot
 	SessionStatusStarting     = "starting"
 	SessionStatusRunning      = "running"
 	SessionStatusCompleted    = "completed"
@@ -282,8 +789,38 @@ const (
 	SessionStatusInterrupting = "interrupting" // Session received interrupt signal and is shutting down
 	SessionStatusInterrupted  = "interrupted"  // Session was interrupted but can be resumed
 	SessionStatusDiscarded    = "discarded"    // Draft session was discarded by the user
+	SessionStatusCancelled    = "cancelled"    // Session was stopped by the user and can't be resumed
 )
 
+// Session sort orders for ListSessionsFilter.SortBy
+const (
+	SessionSortCreatedAt    = "created_at"
+	SessionSortLastActivity = "last_activity_at"
+	SessionSortCost         = "cost_usd"
+	SessionSortTotalTokens  = "total_tokens" // Input, output and cache tokens
+)
+
+// SessionSorts lists every session sort order
+var SessionSorts = []string{SessionSortCreatedAt, SessionSortLastActivity, SessionSortCost, SessionSortTotalTokens}
+
+// SessionStatuses lists every session status
+var SessionStatuses = []string{
+	SessionStatusDraft, SessionStatusQueued, SessionStatusStarting, SessionStatusRunning, SessionStatusCompleted,
+	SessionStatusFailed, SessionStatusWaitingInput, SessionStatusInterrupting, SessionStatusInterrupted,
+	SessionStatusDiscarded, SessionStatusCancelled,
+}
+
+// IsTerminalSessionStatus reports whether a session can no longer change
+// status. Resuming an interrupted session starts a new session.
+func IsTerminalSessionStatus(status string) bool {
+	switch status {
+	case SessionStatusCompleted, SessionStatusFailed,
+		SessionStatusInterrupted, SessionStatusDiscarded, SessionStatusCancelled:
+		return true
+	}
+	return false
+}
+
 // Helper functions for converting between store types and Claude types
 
 // NewSessionFromConfig creates a Session from Claude SessionConfig
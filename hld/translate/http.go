@@ -0,0 +1,84 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPProvider calls a LibreTranslate-compatible endpoint, which accepts a
+// batch of texts and detects their source language
+type HTTPProvider struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewHTTPProvider creates a provider posting to endpoint, such as
+// https://translate.example.com/translate. A nil client uses one with a 30s
+// timeout.
+func NewHTTPProvider(endpoint, apiKey string, client *http.Client) *HTTPProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &HTTPProvider{endpoint: endpoint, apiKey: apiKey, client: client}
+}
+
+type translateRequest struct {
+	Q      []string `json:"q"`
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+	APIKey string   `json:"api_key,omitempty"`
+}
+
+type translateResponse struct {
+	TranslatedText []string `json:"translatedText"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// Translate implements Provider
+func (p *HTTPProvider) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	body, err := json.Marshal(translateRequest{
+		Q:      texts,
+		Source: "auto",
+		Target: target,
+		Format: "text",
+		APIKey: p.apiKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("translation request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read translation response: %w", err)
+	}
+	var result translateResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("translation provider returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+		}
+		return nil, fmt.Errorf("invalid translation response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("translation provider returned %s: %s", resp.Status, result.Error)
+	}
+	return result.TranslatedText, nil
+}
@@ -0,0 +1,144 @@
+// Package translate translates conversation text through a configured
+// provider, leaving code untouched and caching results per event.
+package translate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Provider translates text into a target language
+type Provider interface {
+	// Translate returns the translation of each text, in order. Target is a
+	// language tag such as "en".
+	Translate(ctx context.Context, texts []string, target string) ([]string, error)
+}
+
+// DefaultCacheSize is the number of translations kept when no size is given
+const DefaultCacheSize = 10000
+
+// codePattern matches fenced code blocks and inline code spans, which are
+// passed through untranslated
+var codePattern = regexp.MustCompile("(?s)```.*?(?:```|$)|`[^`\n]+`")
+
+type cacheKey struct {
+	eventID int64
+	target  string
+	hash    string // Of the source text, so edited events are retranslated
+}
+
+// Translator translates event content, caching translations per event
+type Translator struct {
+	provider Provider
+	maxSize  int
+
+	mu    sync.Mutex
+	cache map[cacheKey]string
+	order []cacheKey // Insertion order, oldest first, for eviction
+}
+
+// New creates a translator for provider that caches up to maxSize
+// translations, or DefaultCacheSize if maxSize is zero
+func New(provider Provider, maxSize int) *Translator {
+	if maxSize <= 0 {
+		maxSize = DefaultCacheSize
+	}
+	return &Translator{
+		provider: provider,
+		maxSize:  maxSize,
+		cache:    make(map[cacheKey]string),
+	}
+}
+
+// TranslateEvent translates an event's text into target. Code blocks and
+// inline code are left as they are.
+func (t *Translator) TranslateEvent(ctx context.Context, eventID int64, text, target string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return text, nil
+	}
+	sum := sha256.Sum256([]byte(text))
+	key := cacheKey{eventID: eventID, target: strings.ToLower(target), hash: hex.EncodeToString(sum[:])}
+
+	t.mu.Lock()
+	cached, ok := t.cache[key]
+	t.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	translated, err := t.translate(ctx, text, target)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.cache[key]; !ok {
+		if len(t.order) >= t.maxSize {
+			delete(t.cache, t.order[0])
+			t.order = t.order[1:]
+		}
+		t.order = append(t.order, key)
+	}
+	t.cache[key] = translated
+	return translated, nil
+}
+
+// translate sends the prose between code spans to the provider in one call
+// and reassembles the text around the untouched code
+func (t *Translator) translate(ctx context.Context, text, target string) (string, error) {
+	type segment struct {
+		text  string
+		prose bool
+	}
+	var segments []segment
+	last := 0
+	for _, loc := range codePattern.FindAllStringIndex(text, -1) {
+		if loc[0] > last {
+			segments = append(segments, segment{text[last:loc[0]], true})
+		}
+		segments = append(segments, segment{text[loc[0]:loc[1]], false})
+		last = loc[1]
+	}
+	if last < len(text) {
+		segments = append(segments, segment{text[last:], true})
+	}
+
+	var prose []string
+	for _, seg := range segments {
+		if seg.prose && strings.TrimSpace(seg.text) != "" {
+			prose = append(prose, seg.text)
+		}
+	}
+	if len(prose) == 0 {
+		return text, nil
+	}
+
+	translated, err := t.provider.Translate(ctx, prose, target)
+	if err != nil {
+		return "", fmt.Errorf("translation failed: %w", err)
+	}
+	if len(translated) != len(prose) {
+		return "", fmt.Errorf("translation failed: provider returned %d texts for %d", len(translated), len(prose))
+	}
+
+	var b strings.Builder
+	next := 0
+	for _, seg := range segments {
+		if !seg.prose || strings.TrimSpace(seg.text) == "" {
+			b.WriteString(seg.text)
+			continue
+		}
+		// Providers tend to trim; keep the original spacing around code
+		lead := seg.text[:len(seg.text)-len(strings.TrimLeft(seg.text, " \t\n"))]
+		trail := seg.text[len(strings.TrimRight(seg.text, " \t\n")):]
+		b.WriteString(lead + strings.TrimSpace(translated[next]) + trail)
+		next++
+	}
+	return b.String(), nil
+}
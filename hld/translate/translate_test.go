@@ -0,0 +1,121 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// upperProvider "translates" by upper-casing, counting calls
+type upperProvider struct {
+	calls int
+	texts []string
+}
+
+func (p *upperProvider) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	p.calls++
+	p.texts = append(p.texts, texts...)
+	out := make([]string, len(texts))
+	for i, text := range texts {
+		out[i] = strings.ToUpper(strings.TrimSpace(text))
+	}
+	return out, nil
+}
+
+func TestTranslateEventKeepsCode(t *testing.T) {
+	provider := &upperProvider{}
+	translator := New(provider, 0)
+
+	text := "run this:\n```go\nfmt.Println(\"hola\")\n```\nthen call `make test` please"
+	got, err := translator.TranslateEvent(context.Background(), 1, text, "en")
+	if err != nil {
+		t.Fatalf("TranslateEvent: %v", err)
+	}
+	want := "RUN THIS:\n```go\nfmt.Println(\"hola\")\n```\nTHEN CALL `make test` PLEASE"
+	if got != want {
+		t.Errorf("TranslateEvent() =\n%q\nwant\n%q", got, want)
+	}
+	if provider.calls != 1 {
+		t.Errorf("provider called %d times, want one batched call", provider.calls)
+	}
+	for _, sent := range provider.texts {
+		if strings.Contains(sent, "Println") || strings.Contains(sent, "make test") {
+			t.Errorf("code sent to provider: %q", sent)
+		}
+	}
+}
+
+func TestTranslateEventCache(t *testing.T) {
+	provider := &upperProvider{}
+	translator := New(provider, 2)
+	ctx := context.Background()
+
+	translate := func(id int64, text, target string) {
+		t.Helper()
+		if _, err := translator.TranslateEvent(ctx, id, text, target); err != nil {
+			t.Fatalf("TranslateEvent: %v", err)
+		}
+	}
+
+	translate(1, "hola", "en")
+	translate(1, "hola", "en")
+	if provider.calls != 1 {
+		t.Fatalf("repeat read called provider %d times, want 1", provider.calls)
+	}
+
+	translate(1, "hola", "fr")
+	translate(1, "hola mundo", "en") // Edited event
+	if provider.calls != 3 {
+		t.Errorf("provider called %d times, want new target and edit translated", provider.calls)
+	}
+
+	// The cache holds two entries, so the first one was evicted
+	translate(1, "hola", "en")
+	if provider.calls != 4 {
+		t.Errorf("provider called %d times, want evicted entry retranslated", provider.calls)
+	}
+
+	// Code-only text never reaches the provider
+	translate(2, "```\nls\n```", "en")
+	if provider.calls != 4 {
+		t.Errorf("code-only text sent to provider")
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req translateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.APIKey != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(translateResponse{Error: "invalid API key"})
+			return
+		}
+		out := make([]string, len(req.Q))
+		for i, q := range req.Q {
+			out[i] = req.Target + ":" + q
+		}
+		_ = json.NewEncoder(w).Encode(translateResponse{TranslatedText: out})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	got, err := NewHTTPProvider(server.URL, "secret", nil).Translate(ctx, []string{"hola", "adiós"}, "en")
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+	if len(got) != 2 || got[0] != "en:hola" || got[1] != "en:adiós" {
+		t.Errorf("Translate() = %v", got)
+	}
+
+	_, err = NewHTTPProvider(server.URL, "wrong", nil).Translate(ctx, []string{"hola"}, "en")
+	if err == nil || !strings.Contains(err.Error(), "invalid API key") {
+		t.Errorf("Translate() error = %v, want provider error", err)
+	}
+}
@@ -0,0 +1,204 @@
+// Package webhook notifies external systems of session lifecycle events by
+// POSTing signed JSON payloads to registered URLs.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/bus"
+	"github.com/humanlayer/humanlayer/hld/store"
+)
+
+// SignatureHeader carries the hex-encoded HMAC-SHA256 of the request body,
+// keyed with the webhook's secret
+const SignatureHeader = "X-HumanLayer-Signature"
+
+// EventHeader carries the payload's event name
+const EventHeader = "X-HumanLayer-Event"
+
+// EventName returns the webhook event name for a session entering status,
+// such as "session.completed"
+func EventName(status string) string {
+	return "session." + status
+}
+
+// EventNames lists every event a webhook can subscribe to
+func EventNames() []string {
+	statuses := []string{
+		store.SessionStatusQueued,
+		store.SessionStatusStarting,
+		store.SessionStatusRunning,
+		store.SessionStatusCompleted,
+		store.SessionStatusFailed,
+		store.SessionStatusWaitingInput,
+		store.SessionStatusInterrupting,
+		store.SessionStatusInterrupted,
+		store.SessionStatusDiscarded,
+		store.SessionStatusCancelled,
+	}
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = EventName(status)
+	}
+	return names
+}
+
+// Payload is the JSON body delivered to webhooks
+type Payload struct {
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+	SessionID string    `json:"session_id"`
+	RunID     string    `json:"run_id,omitempty"`
+	OldStatus string    `json:"old_status,omitempty"`
+	NewStatus string    `json:"new_status"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Sign returns the value of SignatureHeader for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookDispatcher delivers session status changes from the event bus to
+// the webhooks registered for them. Deliveries are best effort: a failed
+// delivery is logged and not retried.
+type WebhookDispatcher struct {
+	store    store.ConversationStore
+	eventBus bus.EventBus
+	client   *http.Client
+	wg       sync.WaitGroup
+}
+
+// NewWebhookDispatcher creates a dispatcher. A nil client uses one with a
+// 10s timeout.
+func NewWebhookDispatcher(store store.ConversationStore, eventBus bus.EventBus, client *http.Client) *WebhookDispatcher {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &WebhookDispatcher{store: store, eventBus: eventBus, client: client}
+}
+
+// Run delivers events until ctx is cancelled, then waits for deliveries in
+// flight and returns ctx's error
+func (d *WebhookDispatcher) Run(ctx context.Context) error {
+	sub := d.eventBus.Subscribe(ctx, bus.EventFilter{
+		Types: []bus.EventType{bus.EventSessionStatusChanged},
+	})
+	defer d.wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-sub.Channel:
+			if !ok {
+				return ctx.Err()
+			}
+			d.Dispatch(ctx, event)
+		}
+	}
+}
+
+// Dispatch starts delivering event to each webhook registered for it
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, event bus.Event) {
+	payload, ok := payloadFor(event)
+	if !ok {
+		return
+	}
+
+	webhooks, err := d.store.ListWebhooks(ctx)
+	if err != nil {
+		slog.Error("failed to list webhooks", "error", err)
+		return
+	}
+
+	var body []byte
+	for _, webhook := range webhooks {
+		if !slices.Contains(webhook.Events, payload.Event) {
+			continue
+		}
+		if body == nil {
+			if body, err = json.Marshal(payload); err != nil {
+				slog.Error("failed to encode webhook payload", "error", err)
+				return
+			}
+		}
+		d.wg.Add(1)
+		go func(webhook *store.Webhook) {
+			defer d.wg.Done()
+			if err := d.deliver(ctx, webhook, payload.Event, body); err != nil {
+				slog.Warn("webhook delivery failed",
+					"webhook_id", webhook.ID,
+					"event", payload.Event,
+					"session_id", payload.SessionID,
+					"error", err)
+			}
+		}(webhook)
+	}
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, webhook *store.Webhook, event string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// Drain so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// payloadFor converts a status change event, reporting false for events
+// that don't change the status, such as token count updates
+func payloadFor(event bus.Event) (Payload, bool) {
+	if event.Type != bus.EventSessionStatusChanged {
+		return Payload{}, false
+	}
+	str := func(key string) string {
+		value, _ := event.Data[key].(string)
+		return value
+	}
+	newStatus := str("new_status")
+	if newStatus == "" || newStatus == str("old_status") {
+		return Payload{}, false
+	}
+	timestamp := event.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	return Payload{
+		Event:     EventName(newStatus),
+		Timestamp: timestamp,
+		SessionID: str("session_id"),
+		RunID:     str("run_id"),
+		OldStatus: str("old_status"),
+		NewStatus: newStatus,
+		Error:     str("error_message"),
+	}, true
+}
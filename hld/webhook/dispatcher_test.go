@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/humanlayer/humanlayer/hld/bus"
+	"github.com/humanlayer/humanlayer/hld/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// delivery is a request received by the test server
+type delivery struct {
+	body      []byte
+	signature string
+	event     string
+}
+
+// receiver collects the deliveries made to an httptest server
+type receiver struct {
+	mu         sync.Mutex
+	deliveries []delivery
+	received   chan struct{}
+}
+
+func newReceiver(t *testing.T, status int) (*receiver, *httptest.Server) {
+	r := &receiver{received: make(chan struct{}, 10)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		r.mu.Lock()
+		r.deliveries = append(r.deliveries, delivery{
+			body:      body,
+			signature: req.Header.Get(SignatureHeader),
+			event:     req.Header.Get(EventHeader),
+		})
+		r.mu.Unlock()
+		w.WriteHeader(status)
+		r.received <- struct{}{}
+	}))
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+func (r *receiver) wait(t *testing.T) delivery {
+	t.Helper()
+	select {
+	case <-r.received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deliveries[len(r.deliveries)-1]
+}
+
+func statusChanged(sessionID, oldStatus, newStatus string) bus.Event {
+	return bus.Event{
+		Type:      bus.EventSessionStatusChanged,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"session_id": sessionID,
+			"run_id":     "run-" + sessionID,
+			"old_status": oldStatus,
+			"new_status": newStatus,
+		},
+	}
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	completed, completedServer := newReceiver(t, http.StatusOK)
+	failed, failedServer := newReceiver(t, http.StatusInternalServerError)
+	require.NoError(t, sqliteStore.CreateWebhook(ctx, &store.Webhook{
+		ID: "wh-completed", URL: completedServer.URL, Secret: "s3cret", Events: []string{EventName(store.SessionStatusCompleted)},
+	}))
+	require.NoError(t, sqliteStore.CreateWebhook(ctx, &store.Webhook{
+		ID: "wh-failed", URL: failedServer.URL, Secret: "other", Events: []string{EventName(store.SessionStatusFailed)},
+	}))
+
+	eventBus := bus.NewEventBus()
+	dispatcher := NewWebhookDispatcher(sqliteStore, eventBus, nil)
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- dispatcher.Run(runCtx) }()
+	// Wait for the dispatcher's subscription before publishing
+	require.Eventually(t, func() bool { return eventBus.GetSubscriberCount() == 1 }, time.Second, time.Millisecond)
+
+	eventBus.Publish(statusChanged("sess-1", store.SessionStatusRunning, store.SessionStatusCompleted))
+	got := completed.wait(t)
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(got.body)
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), got.signature, "signed with the webhook's secret")
+	assert.Equal(t, "session.completed", got.event)
+
+	var payload Payload
+	require.NoError(t, json.Unmarshal(got.body, &payload))
+	assert.Equal(t, "session.completed", payload.Event)
+	assert.Equal(t, "sess-1", payload.SessionID)
+	assert.Equal(t, "run-sess-1", payload.RunID)
+	assert.Equal(t, store.SessionStatusRunning, payload.OldStatus)
+	assert.Equal(t, store.SessionStatusCompleted, payload.NewStatus)
+
+	// A failing receiver doesn't stop later deliveries
+	eventBus.Publish(statusChanged("sess-2", store.SessionStatusRunning, store.SessionStatusFailed))
+	got = failed.wait(t)
+	assert.Equal(t, Sign("other", got.body), got.signature)
+	eventBus.Publish(statusChanged("sess-3", store.SessionStatusRunning, store.SessionStatusCompleted))
+	completed.wait(t)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.True(t, errors.Is(err, context.Canceled))
+	case <-time.After(2 * time.Second):
+		t.Fatal("dispatcher did not stop on cancellation")
+	}
+
+	completed.mu.Lock()
+	assert.Len(t, completed.deliveries, 2)
+	completed.mu.Unlock()
+	failed.mu.Lock()
+	assert.Len(t, failed.deliveries, 1, "only subscribed events are delivered")
+	failed.mu.Unlock()
+}
+
+func TestWebhookDispatcherSkipsNonTransitions(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := store.NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = sqliteStore.Close() }()
+
+	running, server := newReceiver(t, http.StatusOK)
+	require.NoError(t, sqliteStore.CreateWebhook(ctx, &store.Webhook{
+		ID: "wh-running", URL: server.URL, Secret: "s3cret", Events: []string{EventName(store.SessionStatusRunning)},
+	}))
+
+	dispatcher := NewWebhookDispatcher(sqliteStore, bus.NewEventBus(), nil)
+	// Token count updates are published as status changes to the same status
+	dispatcher.Dispatch(ctx, statusChanged("sess-1", store.SessionStatusRunning, store.SessionStatusRunning))
+	dispatcher.Dispatch(ctx, bus.Event{Type: bus.EventConversationUpdated, Data: map[string]interface{}{"new_status": "running"}})
+	dispatcher.Dispatch(ctx, statusChanged("sess-1", store.SessionStatusStarting, store.SessionStatusRunning))
+	dispatcher.wg.Wait()
+
+	running.mu.Lock()
+	defer running.mu.Unlock()
+	assert.Len(t, running.deliveries, 1)
+}